@@ -0,0 +1,130 @@
+-- Migración para unificar el cálculo de costos con internal/costing
+-- Cantidad a 4 decimales, cada parcial a 2 decimales y los totales como suma de parciales redondeados.
+-- ROUND de PostgreSQL redondea la mitad alejándose de cero, igual que costing.Redondear.
+
+-- Parcial de cada recurso del APU
+ALTER TABLE partida_recursos DROP COLUMN IF EXISTS parcial;
+ALTER TABLE partida_recursos ADD COLUMN parcial DECIMAL(15,4)
+    GENERATED ALWAYS AS (ROUND(ROUND(cantidad, 4) * precio, 2)) STORED;
+
+-- Costo unitario de la partida: suma de los parciales redondeados
+CREATE OR REPLACE FUNCTION calcular_costo_partida(partida_uuid UUID)
+RETURNS DECIMAL(15,4) AS $$
+DECLARE
+    total DECIMAL(15,4) := 0;
+BEGIN
+    SELECT COALESCE(SUM(ROUND(ROUND(cantidad, 4) * precio, 2)), 0)
+    INTO total
+    FROM partida_recursos
+    WHERE partida_id = partida_uuid;
+    
+    RETURN total;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Costo directo: suma de metrado × costo unitario, cada parcial a 2 decimales
+CREATE OR REPLACE FUNCTION calcular_costo_total_proyecto(proyecto_uuid UUID)
+RETURNS DECIMAL(15,4) AS $$
+DECLARE
+    total DECIMAL(15,4) := 0;
+BEGIN
+    SELECT COALESCE(SUM(ROUND(mp.metrado * p.costo_total, 2)), 0)
+    INTO total
+    FROM metrados_partidas mp
+    JOIN partidas p ON p.codigo = mp.partida_codigo AND p.proyecto_id = mp.proyecto_id
+    WHERE mp.proyecto_id = proyecto_uuid;
+    
+    RETURN total;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION obtener_resumen_proyecto(proyecto_uuid UUID)
+RETURNS TABLE(
+    total_partidas BIGINT,
+    costo_directo DECIMAL(15,4),
+    partidas_con_metrado BIGINT,
+    partidas_sin_metrado BIGINT
+) AS $$
+BEGIN
+    RETURN QUERY
+    SELECT 
+        COUNT(p.id) as total_partidas,
+        COALESCE(SUM(ROUND(mp.metrado * p.costo_total, 2)), 0) as costo_directo,
+        COUNT(mp.id) as partidas_con_metrado,
+        COUNT(p.id) - COUNT(mp.id) as partidas_sin_metrado
+    FROM partidas p
+    LEFT JOIN metrados_partidas mp ON p.codigo = mp.partida_codigo AND p.proyecto_id = mp.proyecto_id
+    WHERE p.proyecto_id = proyecto_uuid;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE VIEW vista_metrados_completos AS
+SELECT 
+    mp.id,
+    mp.proyecto_id,
+    mp.partida_codigo,
+    mp.metrado,
+    mp.unidad as metrado_unidad,
+    mp.observaciones,
+    p.descripcion as partida_descripcion,
+    p.unidad as partida_unidad,
+    p.costo_total as costo_unitario,
+    ROUND(mp.metrado * p.costo_total, 2) as costo_total_partida,
+    pr.nombre as proyecto_nombre,
+    mp.created_at,
+    mp.updated_at
+FROM metrados_partidas mp
+LEFT JOIN partidas p ON p.codigo = mp.partida_codigo AND p.proyecto_id = mp.proyecto_id
+LEFT JOIN proyectos pr ON mp.proyecto_id = pr.id;
+
+-- Costos por tipo de recurso como suma de los parciales redondeados
+CREATE OR REPLACE VIEW vista_partidas_completas AS
+SELECT 
+    p.id,
+    p.codigo,
+    p.descripcion,
+    p.unidad,
+    p.rendimiento,
+    p.costo_total,
+    pr.nombre as proyecto_nombre,
+    COALESCE(mo.total, 0) as costo_mano_obra,
+    COALESCE(mat.total, 0) as costo_materiales,
+    COALESCE(eq.total, 0) as costo_equipos,
+    COALESCE(sub.total, 0) as costo_subcontratos
+FROM partidas p
+LEFT JOIN proyectos pr ON p.proyecto_id = pr.id
+LEFT JOIN (
+    SELECT pr.partida_id, SUM(pr.parcial) as total
+    FROM partida_recursos pr
+    JOIN recursos r ON pr.recurso_id = r.id
+    JOIN tipos_recurso tr ON r.tipo_recurso_id = tr.id
+    WHERE tr.nombre = 'mano_obra'
+    GROUP BY pr.partida_id
+) mo ON p.id = mo.partida_id
+LEFT JOIN (
+    SELECT pr.partida_id, SUM(pr.parcial) as total
+    FROM partida_recursos pr
+    JOIN recursos r ON pr.recurso_id = r.id
+    JOIN tipos_recurso tr ON r.tipo_recurso_id = tr.id
+    WHERE tr.nombre = 'materiales'
+    GROUP BY pr.partida_id
+) mat ON p.id = mat.partida_id
+LEFT JOIN (
+    SELECT pr.partida_id, SUM(pr.parcial) as total
+    FROM partida_recursos pr
+    JOIN recursos r ON pr.recurso_id = r.id
+    JOIN tipos_recurso tr ON r.tipo_recurso_id = tr.id
+    WHERE tr.nombre = 'equipos'
+    GROUP BY pr.partida_id
+) eq ON p.id = eq.partida_id
+LEFT JOIN (
+    SELECT pr.partida_id, SUM(pr.parcial) as total
+    FROM partida_recursos pr
+    JOIN recursos r ON pr.recurso_id = r.id
+    JOIN tipos_recurso tr ON r.tipo_recurso_id = tr.id
+    WHERE tr.nombre = 'subcontratos'
+    GROUP BY pr.partida_id
+) sub ON p.id = sub.partida_id;
+
+-- Recalcular los costos unitarios guardados con las reglas nuevas
+UPDATE partidas SET costo_total = calcular_costo_partida(id);
@@ -0,0 +1,44 @@
+-- Migración para el análisis de costo horario de equipos
+-- Cada análisis corresponde a un recurso de equipo del catálogo (codigo_recurso) y calcula su costo
+-- por hora-máquina: posesión (depreciación, intereses, seguros) más operación (mantenimiento,
+-- combustible, lubricantes, neumáticos y operador). Combustible, lubricante y operador pueden
+-- referirse a recursos del catálogo para tomar su precio de la lista de precios o del catálogo, de
+-- modo que el costo horario se actualiza cuando esos precios cambian.
+
+CREATE TABLE IF NOT EXISTS analisis_equipos (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    organizacion_id UUID REFERENCES organizaciones(id) ON DELETE CASCADE, -- NULL: análisis global
+    codigo_recurso VARCHAR(50) NOT NULL,
+    descripcion TEXT NOT NULL,
+    potencia_hp DECIMAL(10,2),
+    valor_adquisicion DECIMAL(15,2) NOT NULL CHECK (valor_adquisicion > 0),
+    vida_economica_anios DECIMAL(6,2) NOT NULL CHECK (vida_economica_anios > 0),
+    horas_anuales DECIMAL(8,2) NOT NULL DEFAULT 2000 CHECK (horas_anuales > 0),
+    porcentaje_rescate DECIMAL(6,2) NOT NULL DEFAULT 20 CHECK (porcentaje_rescate BETWEEN 0 AND 100),
+    tasa_interes_anual DECIMAL(6,2) NOT NULL DEFAULT 0 CHECK (tasa_interes_anual >= 0),
+    porcentaje_seguros_anual DECIMAL(6,2) NOT NULL DEFAULT 0 CHECK (porcentaje_seguros_anual >= 0),
+    porcentaje_mantenimiento DECIMAL(6,2) NOT NULL DEFAULT 0 CHECK (porcentaje_mantenimiento >= 0),
+    valor_neumaticos DECIMAL(15,2) NOT NULL DEFAULT 0 CHECK (valor_neumaticos >= 0),
+    vida_neumaticos_horas DECIMAL(10,2) CHECK (vida_neumaticos_horas > 0),
+    codigo_combustible VARCHAR(50),
+    consumo_combustible DECIMAL(10,4) NOT NULL DEFAULT 0 CHECK (consumo_combustible >= 0), -- gal/h
+    precio_combustible DECIMAL(15,4) NOT NULL DEFAULT 0,
+    codigo_lubricante VARCHAR(50),
+    consumo_lubricante DECIMAL(10,4) NOT NULL DEFAULT 0 CHECK (consumo_lubricante >= 0), -- gal/h
+    precio_lubricante DECIMAL(15,4) NOT NULL DEFAULT 0,
+    codigo_operador VARCHAR(50),
+    horas_operador DECIMAL(10,4) NOT NULL DEFAULT 0 CHECK (horas_operador >= 0), -- hh por hora-máquina
+    precio_operador DECIMAL(15,4) NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (valor_neumaticos = 0 OR vida_neumaticos_horas IS NOT NULL)
+);
+
+-- Un análisis por equipo en cada organización (y uno global)
+CREATE UNIQUE INDEX IF NOT EXISTS idx_analisis_equipos_unico ON analisis_equipos (
+    (COALESCE(organizacion_id, '00000000-0000-0000-0000-000000000000'::uuid)), codigo_recurso
+);
+
+DROP TRIGGER IF EXISTS update_analisis_equipos_updated_at ON analisis_equipos;
+CREATE TRIGGER update_analisis_equipos_updated_at BEFORE UPDATE ON analisis_equipos
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
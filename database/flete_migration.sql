@@ -0,0 +1,126 @@
+-- Migración para desperdicio de materiales y flete terrestre
+-- El desperdicio es un porcentaje opcional sobre la cantidad neta de cada recurso del APU: la cantidad
+-- que se valoriza es la neta aumentada en ese porcentaje, redondeada a 4 decimales como en internal/costing.
+-- El flete se calcula con una ruta (distancia y tarifa por tonelada-km) y el peso unitario de cada
+-- material; según el modo del proyecto, se suma al precio del material o se agrega como subcontrato.
+
+ALTER TABLE partida_recursos ADD COLUMN IF NOT EXISTS desperdicio DECIMAL(6,2)
+    CHECK (desperdicio IS NULL OR desperdicio >= 0);
+
+-- Parcial de cada recurso del APU con su desperdicio
+ALTER TABLE partida_recursos DROP COLUMN IF EXISTS parcial CASCADE;
+ALTER TABLE partida_recursos ADD COLUMN parcial DECIMAL(15,4)
+    GENERATED ALWAYS AS (ROUND(ROUND(cantidad * (1 + COALESCE(desperdicio, 0) / 100), 4) * precio, 2)) STORED;
+
+-- La vista usa parcial y CASCADE la elimina con la columna; se vuelve a crear igual que en costing_migration.sql
+CREATE OR REPLACE VIEW vista_partidas_completas AS
+SELECT
+    p.id,
+    p.codigo,
+    p.descripcion,
+    p.unidad,
+    p.rendimiento,
+    p.costo_total,
+    pr.nombre as proyecto_nombre,
+    COALESCE(mo.total, 0) as costo_mano_obra,
+    COALESCE(mat.total, 0) as costo_materiales,
+    COALESCE(eq.total, 0) as costo_equipos,
+    COALESCE(sub.total, 0) as costo_subcontratos
+FROM partidas p
+LEFT JOIN proyectos pr ON p.proyecto_id = pr.id
+LEFT JOIN (
+    SELECT pr.partida_id, SUM(pr.parcial) as total
+    FROM partida_recursos pr
+    JOIN recursos r ON pr.recurso_id = r.id
+    JOIN tipos_recurso tr ON r.tipo_recurso_id = tr.id
+    WHERE tr.nombre = 'mano_obra'
+    GROUP BY pr.partida_id
+) mo ON p.id = mo.partida_id
+LEFT JOIN (
+    SELECT pr.partida_id, SUM(pr.parcial) as total
+    FROM partida_recursos pr
+    JOIN recursos r ON pr.recurso_id = r.id
+    JOIN tipos_recurso tr ON r.tipo_recurso_id = tr.id
+    WHERE tr.nombre = 'materiales'
+    GROUP BY pr.partida_id
+) mat ON p.id = mat.partida_id
+LEFT JOIN (
+    SELECT pr.partida_id, SUM(pr.parcial) as total
+    FROM partida_recursos pr
+    JOIN recursos r ON pr.recurso_id = r.id
+    JOIN tipos_recurso tr ON r.tipo_recurso_id = tr.id
+    WHERE tr.nombre = 'equipos'
+    GROUP BY pr.partida_id
+) eq ON p.id = eq.partida_id
+LEFT JOIN (
+    SELECT pr.partida_id, SUM(pr.parcial) as total
+    FROM partida_recursos pr
+    JOIN recursos r ON pr.recurso_id = r.id
+    JOIN tipos_recurso tr ON r.tipo_recurso_id = tr.id
+    WHERE tr.nombre = 'subcontratos'
+    GROUP BY pr.partida_id
+) sub ON p.id = sub.partida_id;
+
+-- Costo unitario de la partida: suma de los parciales redondeados
+CREATE OR REPLACE FUNCTION calcular_costo_partida(partida_uuid UUID)
+RETURNS DECIMAL(15,4) AS $$
+DECLARE
+    total DECIMAL(15,4) := 0;
+BEGIN
+    SELECT COALESCE(SUM(ROUND(ROUND(cantidad * (1 + COALESCE(desperdicio, 0) / 100), 4) * precio, 2)), 0)
+    INTO total
+    FROM partida_recursos
+    WHERE partida_id = partida_uuid;
+
+    RETURN total;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Rutas de transporte de materiales a la obra
+CREATE TABLE IF NOT EXISTS rutas_flete (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    organizacion_id UUID REFERENCES organizaciones(id) ON DELETE CASCADE, -- NULL: ruta global
+    nombre VARCHAR(255) NOT NULL,
+    origen VARCHAR(255) NOT NULL,
+    destino VARCHAR(255) NOT NULL,
+    distancia_km DECIMAL(10,2) NOT NULL CHECK (distancia_km > 0),
+    tarifa_tonelada_km DECIMAL(15,4) NOT NULL CHECK (tarifa_tonelada_km >= 0),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_rutas_flete_unico ON rutas_flete (
+    (COALESCE(organizacion_id, '00000000-0000-0000-0000-000000000000'::uuid)), nombre
+);
+
+DROP TRIGGER IF EXISTS update_rutas_flete_updated_at ON rutas_flete;
+CREATE TRIGGER update_rutas_flete_updated_at BEFORE UPDATE ON rutas_flete
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Peso en kg de una unidad de cada material del catálogo (una bolsa de cemento, un m3 de arena)
+CREATE TABLE IF NOT EXISTS pesos_unitarios (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    organizacion_id UUID REFERENCES organizaciones(id) ON DELETE CASCADE, -- NULL: peso global
+    codigo_recurso VARCHAR(50) NOT NULL,
+    peso_kg DECIMAL(12,4) NOT NULL CHECK (peso_kg > 0),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Un peso por material en cada organización (y uno global)
+CREATE UNIQUE INDEX IF NOT EXISTS idx_pesos_unitarios_unico ON pesos_unitarios (
+    (COALESCE(organizacion_id, '00000000-0000-0000-0000-000000000000'::uuid)), codigo_recurso
+);
+
+DROP TRIGGER IF EXISTS update_pesos_unitarios_updated_at ON pesos_unitarios;
+CREATE TRIGGER update_pesos_unitarios_updated_at BEFORE UPDATE ON pesos_unitarios
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Ruta y modo de flete del proyecto: 'precio' suma el flete al precio de cada material y
+-- 'subcontrato' agrega una línea de flete terrestre en el APU de cada partida
+ALTER TABLE proyectos ADD COLUMN IF NOT EXISTS ruta_flete_id UUID REFERENCES rutas_flete(id) ON DELETE SET NULL;
+ALTER TABLE proyectos ADD COLUMN IF NOT EXISTS modo_flete VARCHAR(20) NOT NULL DEFAULT 'precio'
+    CHECK (modo_flete IN ('precio', 'subcontrato'));
+
+-- Recalcular los costos unitarios guardados con el desperdicio
+UPDATE partidas SET costo_total = calcular_costo_partida(id);
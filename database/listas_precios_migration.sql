@@ -0,0 +1,34 @@
+-- Migración para listas de precios regionales con vigencia
+-- Cada organización mantiene listas por región y período (p. ej. "Lima 2025-T1") con el precio de
+-- cada recurso. El proyecto tiene asignada una lista y la operación "repreciar" copia sus precios a
+-- partida_recursos.precio; recursos.precio_base se mantiene como precio de referencia global.
+
+CREATE TABLE IF NOT EXISTS listas_precios (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    organizacion_id UUID NOT NULL REFERENCES organizaciones(id) ON DELETE CASCADE,
+    nombre VARCHAR(255) NOT NULL,
+    region VARCHAR(100) NOT NULL,
+    vigente_desde DATE NOT NULL,
+    vigente_hasta DATE, -- NULL: sin fecha de término
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (vigente_hasta IS NULL OR vigente_hasta >= vigente_desde)
+);
+
+CREATE TABLE IF NOT EXISTS listas_precios_recursos (
+    lista_precios_id UUID NOT NULL REFERENCES listas_precios(id) ON DELETE CASCADE,
+    recurso_id UUID NOT NULL REFERENCES recursos(id) ON DELETE CASCADE,
+    precio DECIMAL(15,4) NOT NULL CHECK (precio >= 0),
+    moneda VARCHAR(3), -- NULL: la moneda del proyecto, como en partida_recursos
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (lista_precios_id, recurso_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_listas_precios_organizacion ON listas_precios(organizacion_id, region);
+
+-- Lista asignada al proyecto; al borrar la lista el proyecto queda sin lista
+ALTER TABLE proyectos ADD COLUMN IF NOT EXISTS lista_precios_id UUID REFERENCES listas_precios(id) ON DELETE SET NULL;
+
+DROP TRIGGER IF EXISTS update_listas_precios_updated_at ON listas_precios;
+CREATE TRIGGER update_listas_precios_updated_at BEFORE UPDATE ON listas_precios
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
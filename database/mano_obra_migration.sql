@@ -0,0 +1,39 @@
+-- Migración para el cálculo del costo de mano de obra del régimen de construcción civil
+-- Cada juego de parámetros corresponde a un año del convenio CAPECO-FTCCP: jornal básico, BUC y
+-- leyes sociales por categoría, más movilidad, overol y horas extra comunes a todas. El costo hora-hombre
+-- resultante puede aplicarse al catálogo de recursos (admin) o a una lista de precios de la organización.
+
+CREATE TABLE IF NOT EXISTS parametros_mano_obra (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    organizacion_id UUID REFERENCES organizaciones(id) ON DELETE CASCADE, -- NULL: parámetros globales
+    anio INTEGER NOT NULL CHECK (anio BETWEEN 2000 AND 2100),
+    descripcion VARCHAR(255),
+    vigente_desde DATE NOT NULL,
+    horas_jornada DECIMAL(6,2) NOT NULL DEFAULT 8 CHECK (horas_jornada > 0),
+    movilidad_diaria DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (movilidad_diaria >= 0),
+    overol_diario DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (overol_diario >= 0),
+    horas_extra_diarias DECIMAL(6,2) NOT NULL DEFAULT 0 CHECK (horas_extra_diarias >= 0),
+    porcentaje_sobretasa_extra DECIMAL(6,2) NOT NULL DEFAULT 25 CHECK (porcentaje_sobretasa_extra >= 0),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Un juego de parámetros por año en cada organización (y uno global)
+CREATE UNIQUE INDEX IF NOT EXISTS idx_parametros_mano_obra_unico ON parametros_mano_obra (
+    (COALESCE(organizacion_id, '00000000-0000-0000-0000-000000000000'::uuid)), anio
+);
+
+CREATE TABLE IF NOT EXISTS parametros_mano_obra_categorias (
+    parametros_mano_obra_id UUID NOT NULL REFERENCES parametros_mano_obra(id) ON DELETE CASCADE,
+    categoria VARCHAR(50) NOT NULL, -- OPERARIO, OFICIAL, PEÓN...
+    codigo_recurso VARCHAR(50), -- recurso hh del catálogo que recibe el costo calculado
+    jornal_basico DECIMAL(10,2) NOT NULL CHECK (jornal_basico > 0),
+    porcentaje_buc DECIMAL(6,2) NOT NULL DEFAULT 0 CHECK (porcentaje_buc >= 0),
+    porcentaje_leyes_sociales DECIMAL(6,2) NOT NULL DEFAULT 0 CHECK (porcentaje_leyes_sociales >= 0),
+    orden INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (parametros_mano_obra_id, categoria)
+);
+
+DROP TRIGGER IF EXISTS update_parametros_mano_obra_updated_at ON parametros_mano_obra;
+CREATE TRIGGER update_parametros_mano_obra_updated_at BEFORE UPDATE ON parametros_mano_obra
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -35,7 +35,7 @@ SELECT
     p.descripcion as partida_descripcion,
     p.unidad as partida_unidad,
     p.costo_total as costo_unitario,
-    (mp.metrado * p.costo_total) as costo_total_partida,
+    ROUND(mp.metrado * p.costo_total, 2) as costo_total_partida,
     pr.nombre as proyecto_nombre,
     mp.created_at,
     mp.updated_at
@@ -49,7 +49,7 @@ RETURNS DECIMAL(15,4) AS $$
 DECLARE
     total DECIMAL(15,4) := 0;
 BEGIN
-    SELECT COALESCE(SUM(mp.metrado * p.costo_total), 0)
+    SELECT COALESCE(SUM(ROUND(mp.metrado * p.costo_total, 2)), 0)
     INTO total
     FROM metrados_partidas mp
     JOIN partidas p ON p.codigo = mp.partida_codigo AND p.proyecto_id = mp.proyecto_id
@@ -71,7 +71,7 @@ BEGIN
     RETURN QUERY
     SELECT 
         COUNT(p.id) as total_partidas,
-        COALESCE(SUM(mp.metrado * p.costo_total), 0) as costo_directo,
+        COALESCE(SUM(ROUND(mp.metrado * p.costo_total, 2)), 0) as costo_directo,
         COUNT(mp.id) as partidas_con_metrado,
         COUNT(p.id) - COUNT(mp.id) as partidas_sin_metrado
     FROM partidas p
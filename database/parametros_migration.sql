@@ -0,0 +1,11 @@
+-- Migración para parámetros de cálculo por organización, proyecto y presupuesto
+-- JSON con las claves de models.ParametrosCalculo; las ausentes se heredan del nivel superior:
+-- valores por defecto → organización → proyecto o presupuesto.
+-- La moneda del proyecto y del presupuesto se guarda en su columna moneda, no en el JSON.
+-- Las funciones SQL de costing_migration.sql no leen estos parámetros y redondean siempre a 4 y 2
+-- decimales: partidas.costo_total y los costos de vista_metrados_completos no siguen los decimales
+-- configurados. Los totales de la API se calculan en Go (services.CalculoService) con los efectivos.
+
+ALTER TABLE organizaciones ADD COLUMN IF NOT EXISTS parametros JSONB DEFAULT '{}';
+ALTER TABLE proyectos ADD COLUMN IF NOT EXISTS parametros JSONB DEFAULT '{}';
+ALTER TABLE presupuestos ADD COLUMN IF NOT EXISTS parametros JSONB DEFAULT '{}';
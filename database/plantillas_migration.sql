@@ -0,0 +1,28 @@
+-- Migración para plantillas de reportes Excel por organización
+-- Cada organización puede definir colores, fuentes, formato numérico, logo y datos de encabezado/pie
+
+CREATE TABLE IF NOT EXISTS plantillas_excel (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    organizacion_id UUID NOT NULL UNIQUE REFERENCES organizaciones(id) ON DELETE CASCADE,
+    color_titulo VARCHAR(7),
+    color_cabecera VARCHAR(7),
+    color_partida VARCHAR(7),
+    color_seccion VARCHAR(7),
+    color_total VARCHAR(7),
+    fuente VARCHAR(100),
+    tamano_fuente DECIMAL(4,1) DEFAULT 0,
+    formato_numero VARCHAR(50),
+    empresa VARCHAR(255),
+    ruc VARCHAR(11),
+    ingeniero VARCHAR(255),
+    cip VARCHAR(20),
+    logo BYTEA,
+    logo_extension VARCHAR(10),
+    anchos_columna JSONB DEFAULT '{}',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Trigger para actualizar timestamp
+CREATE TRIGGER update_plantillas_excel_updated_at BEFORE UPDATE ON plantillas_excel
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
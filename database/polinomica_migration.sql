@@ -0,0 +1,7 @@
+-- Migración para la fórmula polinómica de reajuste
+-- Cada recurso se asocia a un índice unificado de precios (INEI)
+
+ALTER TABLE recursos ADD COLUMN IF NOT EXISTS indice_unificado VARCHAR(2);
+
+-- Índice para agrupar insumos por índice unificado
+CREATE INDEX IF NOT EXISTS idx_recursos_indice_unificado ON recursos(indice_unificado);
@@ -105,7 +105,7 @@ CREATE TABLE partida_recursos (
     cantidad DECIMAL(15,6) NOT NULL DEFAULT 0,
     precio DECIMAL(15,4) NOT NULL DEFAULT 0,
     cuadrilla DECIMAL(15,6) DEFAULT NULL,
-    parcial DECIMAL(15,4) GENERATED ALWAYS AS (cantidad * precio) STORED,
+    parcial DECIMAL(15,4) GENERATED ALWAYS AS (ROUND(ROUND(cantidad, 4) * precio, 2)) STORED,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     UNIQUE(partida_id, recurso_id)
@@ -167,13 +167,13 @@ CREATE TRIGGER update_recursos_updated_at BEFORE UPDATE ON recursos
 CREATE TRIGGER update_partida_recursos_updated_at BEFORE UPDATE ON partida_recursos
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
--- Función para calcular costo total de partida
+-- Función para calcular costo total de partida (reglas de redondeo de internal/costing)
 CREATE OR REPLACE FUNCTION calcular_costo_partida(partida_uuid UUID)
 RETURNS DECIMAL(15,4) AS $$
 DECLARE
     total DECIMAL(15,4) := 0;
 BEGIN
-    SELECT COALESCE(SUM(cantidad * precio), 0)
+    SELECT COALESCE(SUM(ROUND(ROUND(cantidad, 4) * precio, 2)), 0)
     INTO total
     FROM partida_recursos
     WHERE partida_id = partida_uuid;
@@ -221,7 +221,7 @@ SELECT
 FROM partidas p
 LEFT JOIN proyectos pr ON p.proyecto_id = pr.id
 LEFT JOIN (
-    SELECT pr.partida_id, SUM(pr.cantidad * pr.precio) as total
+    SELECT pr.partida_id, SUM(pr.parcial) as total
     FROM partida_recursos pr
     JOIN recursos r ON pr.recurso_id = r.id
     JOIN tipos_recurso tr ON r.tipo_recurso_id = tr.id
@@ -229,7 +229,7 @@ LEFT JOIN (
     GROUP BY pr.partida_id
 ) mo ON p.id = mo.partida_id
 LEFT JOIN (
-    SELECT pr.partida_id, SUM(pr.cantidad * pr.precio) as total
+    SELECT pr.partida_id, SUM(pr.parcial) as total
     FROM partida_recursos pr
     JOIN recursos r ON pr.recurso_id = r.id
     JOIN tipos_recurso tr ON r.tipo_recurso_id = tr.id
@@ -237,7 +237,7 @@ LEFT JOIN (
     GROUP BY pr.partida_id
 ) mat ON p.id = mat.partida_id
 LEFT JOIN (
-    SELECT pr.partida_id, SUM(pr.cantidad * pr.precio) as total
+    SELECT pr.partida_id, SUM(pr.parcial) as total
     FROM partida_recursos pr
     JOIN recursos r ON pr.recurso_id = r.id
     JOIN tipos_recurso tr ON r.tipo_recurso_id = tr.id
@@ -245,7 +245,7 @@ LEFT JOIN (
     GROUP BY pr.partida_id
 ) eq ON p.id = eq.partida_id
 LEFT JOIN (
-    SELECT pr.partida_id, SUM(pr.cantidad * pr.precio) as total
+    SELECT pr.partida_id, SUM(pr.parcial) as total
     FROM partida_recursos pr
     JOIN recursos r ON pr.recurso_id = r.id
     JOIN tipos_recurso tr ON r.tipo_recurso_id = tr.id
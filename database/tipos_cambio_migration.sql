@@ -0,0 +1,25 @@
+-- Migración para presupuestos multimoneda
+-- Cada precio de partida_recursos puede estar en otra moneda; NULL es la moneda del proyecto.
+-- Los reportes convierten esos precios con la tabla tipos_cambio, a la fecha de referencia del proyecto
+-- (parámetro fecha_tipo_cambio). Las funciones SQL de costing_migration.sql no convierten monedas: los totales
+-- de la API (costo total, resumen, insumos) se calculan en Go con services.CalculoService.
+
+ALTER TABLE partida_recursos ADD COLUMN IF NOT EXISTS moneda VARCHAR(3);
+
+CREATE TABLE IF NOT EXISTS tipos_cambio (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    organizacion_id UUID REFERENCES organizaciones(id) ON DELETE CASCADE, -- NULL: tasa global
+    moneda_origen VARCHAR(3) NOT NULL,
+    moneda_destino VARCHAR(3) NOT NULL,
+    tasa DECIMAL(18,6) NOT NULL CHECK (tasa > 0), -- unidades de destino por unidad de origen
+    fecha_vigencia DATE NOT NULL,
+    fuente VARCHAR(100),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (moneda_origen <> moneda_destino)
+);
+
+-- Una tasa por par de monedas y fecha en cada organización (y una global)
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tipos_cambio_unico ON tipos_cambio (
+    (COALESCE(organizacion_id, '00000000-0000-0000-0000-000000000000'::uuid)),
+    moneda_origen, moneda_destino, fecha_vigencia
+);
@@ -0,0 +1,162 @@
+// Package costing es la única fuente de los cálculos de costos del presupuesto: parcial de cada
+// recurso, subtotal de sección, costo unitario de la partida, parcial en el presupuesto, subtotal de
+// los títulos y total. Los handlers, los generadores de reportes y las funciones SQL
+// (database/costing_migration.sql) aplican estas mismas reglas para que los totales cuadren al céntimo.
+//
+// Los montos son Decimal, así que las sumas son exactas. Reglas de redondeo por defecto, como en S10:
+//   - la cantidad de cada recurso se redondea a 4 decimales antes de multiplicarla por el precio;
+//   - con desperdicio, la cantidad es la neta aumentada en ese porcentaje, redondeada igual;
+//   - cada parcial (recurso o partida) se redondea a 2 decimales;
+//   - subtotales, costos unitarios y totales son sumas de parciales ya redondeados;
+//   - el redondeo es "mitad hacia arriba" (alejándose de cero), igual que ROUND de PostgreSQL;
+//   - un precio en otra moneda se convierte con el tipo de cambio y se redondea a 4 decimales;
+//   - el flete por unidad de material (peso × costo por tonelada) se redondea a 4 decimales, como un precio.
+//
+// Los decimales y el modo de cada etapa se configuran con Reglas.
+package costing
+
+// Decimales de cada etapa del cálculo con las reglas por defecto
+const (
+	DecimalesCantidad = 4
+	DecimalesParcial  = 2
+	DecimalesPrecio   = 4
+)
+
+// ModoRedondeo indica qué hacer con los dígitos que sobran
+type ModoRedondeo int
+
+const (
+	MitadArriba ModoRedondeo = iota // la mitad se aleja de cero: 2.345 → 2.35 (S10, ROUND de PostgreSQL)
+	MitadPar                        // la mitad va al par: 2.345 → 2.34
+	Truncar                         // se descartan: 2.349 → 2.34
+)
+
+// Redondeo es la regla de una etapa del cálculo
+type Redondeo struct {
+	Decimales int32
+	Modo      ModoRedondeo
+}
+
+// Reglas son las reglas de redondeo de cada etapa del cálculo
+type Reglas struct {
+	Cantidad Redondeo // cantidad del recurso antes de multiplicarla por el precio
+	Parcial  Redondeo // parciales de recursos y partidas, y montos derivados (GG, utilidad, IGV)
+	Precio   Redondeo // precio convertido a la moneda del presupuesto
+}
+
+// ReglasS10 son las reglas por defecto, compatibles con S10
+var ReglasS10 = Reglas{
+	Cantidad: Redondeo{Decimales: DecimalesCantidad, Modo: MitadArriba},
+	Parcial:  Redondeo{Decimales: DecimalesParcial, Modo: MitadArriba},
+	Precio:   Redondeo{Decimales: DecimalesPrecio, Modo: MitadArriba},
+}
+
+// Recurso es lo que interviene en el costo de un recurso del APU
+type Recurso struct {
+	Cantidad    Decimal
+	Precio      Decimal
+	Desperdicio Decimal // porcentaje sobre la cantidad; cero si no hay
+}
+
+// ParcialRecurso es el costo de un recurso en el APU: cantidad (redondeada) por precio, redondeado
+func (r Reglas) ParcialRecurso(cantidad, precio Decimal) Decimal {
+	return cantidad.Redondear(r.Cantidad).Multiplicar(precio).Redondear(r.Parcial)
+}
+
+// CantidadConDesperdicio es la cantidad neta aumentada en el porcentaje de desperdicio, redondeada
+// como cantidad: 100 ladrillos con 5 % son 105. Sin desperdicio devuelve la cantidad tal cual.
+func (r Reglas) CantidadConDesperdicio(cantidad, porcentaje Decimal) Decimal {
+	if porcentaje.EsCero() {
+		return cantidad
+	}
+	cien := NuevoDecimal(100, 0)
+	return cantidad.Multiplicar(cien.Sumar(porcentaje)).Dividir(cien, r.Cantidad)
+}
+
+// Toneladas es el peso en toneladas de una cantidad de recurso que pesa pesoKg por unidad, redondeado
+// como cantidad
+func (r Reglas) Toneladas(cantidad, pesoKg Decimal) Decimal {
+	return cantidad.Multiplicar(pesoKg).Dividir(NuevoDecimal(1000, 0), r.Cantidad)
+}
+
+// FleteUnitario es el flete de una unidad de recurso que pesa pesoKg, con el costo por tonelada de la
+// ruta, redondeado como precio
+func (r Reglas) FleteUnitario(pesoKg, costoTonelada Decimal) Decimal {
+	return pesoKg.Multiplicar(costoTonelada).Dividir(NuevoDecimal(1000, 0), r.Precio)
+}
+
+// Subtotal es la suma de los parciales de los recursos: el subtotal de una sección del APU
+func (r Reglas) Subtotal(recursos []Recurso) Decimal {
+	total := Decimal{}
+	for _, recurso := range recursos {
+		cantidad := r.CantidadConDesperdicio(recurso.Cantidad, recurso.Desperdicio)
+		total = total.Sumar(r.ParcialRecurso(cantidad, recurso.Precio))
+	}
+	return total
+}
+
+// Sumar suma parciales ya redondeados: secciones en el costo unitario, partidas en los títulos y en
+// el costo directo. Se redondea por si algún sumando viene con más decimales.
+func (r Reglas) Sumar(montos ...Decimal) Decimal {
+	total := Decimal{}
+	for _, monto := range montos {
+		total = total.Sumar(monto)
+	}
+	return total.Redondear(r.Parcial)
+}
+
+// ParcialPartida es el costo de la partida en el presupuesto: metrado por costo unitario, redondeado
+func (r Reglas) ParcialPartida(metrado, costoUnitario Decimal) Decimal {
+	return metrado.Multiplicar(costoUnitario).Redondear(r.Parcial)
+}
+
+// CantidadCuadrilla es la cantidad de un recurso de mano de obra o equipo deducida de la cuadrilla:
+// cuadrilla × horas de la jornada / rendimiento diario, redondeada como cantidad
+func (r Reglas) CantidadCuadrilla(cuadrilla, horasJornada, rendimiento float64) Decimal {
+	if rendimiento <= 0 {
+		return Decimal{}
+	}
+	horas := DecimalDesdeFloat(cuadrilla).Multiplicar(DecimalDesdeFloat(horasJornada))
+	return horas.Dividir(DecimalDesdeFloat(rendimiento), r.Cantidad)
+}
+
+// Convertir lleva un precio a otra moneda con el tipo de cambio (unidades de destino por unidad de
+// origen), redondeado como precio
+func (r Reglas) Convertir(precio, tasa Decimal) Decimal {
+	return precio.Multiplicar(tasa).Redondear(r.Precio)
+}
+
+// Porcentaje aplica un porcentaje a un monto (gastos generales, utilidad, IGV), redondeado
+func (r Reglas) Porcentaje(monto Decimal, porcentaje float64) Decimal {
+	return monto.Multiplicar(DecimalDesdeFloat(porcentaje)).Dividir(NuevoDecimal(100, 0), r.Parcial)
+}
+
+// ParcialRecurso aplica ReglasS10.ParcialRecurso
+func ParcialRecurso(cantidad, precio Decimal) Decimal {
+	return ReglasS10.ParcialRecurso(cantidad, precio)
+}
+
+// CantidadConDesperdicio aplica ReglasS10.CantidadConDesperdicio
+func CantidadConDesperdicio(cantidad, porcentaje Decimal) Decimal {
+	return ReglasS10.CantidadConDesperdicio(cantidad, porcentaje)
+}
+
+// Subtotal aplica ReglasS10.Subtotal
+func Subtotal(recursos []Recurso) Decimal {
+	return ReglasS10.Subtotal(recursos)
+}
+
+// Sumar aplica ReglasS10.Sumar
+func Sumar(montos ...Decimal) Decimal {
+	return ReglasS10.Sumar(montos...)
+}
+
+// ParcialPartida aplica ReglasS10.ParcialPartida
+func ParcialPartida(metrado, costoUnitario Decimal) Decimal {
+	return ReglasS10.ParcialPartida(metrado, costoUnitario)
+}
+
+// Porcentaje aplica ReglasS10.Porcentaje
+func Porcentaje(monto Decimal, porcentaje float64) Decimal {
+	return ReglasS10.Porcentaje(monto, porcentaje)
+}
@@ -0,0 +1,366 @@
+package costing
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Decimal es un número decimal exacto: coeficiente entero por 10^-escala. Se usa para precios,
+// cantidades, parciales y totales, de modo que las sumas cuadren con las columnas DECIMAL de la base
+// de datos y con S10. El valor cero de Decimal es 0 y los métodos nunca modifican al receptor.
+//
+// En JSON se escribe como número exacto (12.5, no "12.5") y se lee de números o de cadenas; en SQL se
+// escanea de NUMERIC/DECIMAL sin pasar por float64.
+type Decimal struct {
+	coef   *big.Int // nil vale cero
+	escala int32    // dígitos después del punto
+}
+
+var diez = big.NewInt(10)
+
+// MaxEscala acota los decimales de un Decimal leído de un texto y los de un redondeo. Sin ese tope un
+// exponente como 1e2000000000 obligaría a calcular 10^2000000000.
+const MaxEscala = 64
+
+// NuevoDecimal devuelve coef × 10^-escala: NuevoDecimal(1250, 2) es 12.50
+func NuevoDecimal(coef int64, escala int32) Decimal {
+	if escala < 0 {
+		return Decimal{coef: new(big.Int).Mul(big.NewInt(coef), potencia10(-escala))}
+	}
+	return Decimal{coef: big.NewInt(coef), escala: escala}
+}
+
+// DecimalDesdeFloat convierte un float64 por su representación decimal más corta, así 0.1 es
+// exactamente 0.1. Es exacto para cualquier valor que venga de un texto con hasta 15 dígitos
+// significativos (metrados, porcentajes, celdas de Excel). NaN e infinito dan cero.
+func DecimalDesdeFloat(valor float64) Decimal {
+	if math.IsNaN(valor) || math.IsInf(valor, 0) || valor == 0 {
+		return Decimal{}
+	}
+	d, _ := ParsearDecimal(strconv.FormatFloat(valor, 'f', -1, 64))
+	return d
+}
+
+// ParsearDecimal lee un número en notación decimal, con signo y exponente opcionales ("-12.50", "1e3").
+// La escala resultante debe estar entre -MaxEscala y MaxEscala.
+func ParsearDecimal(texto string) (Decimal, error) {
+	s := strings.TrimSpace(texto)
+	if s == "" {
+		return Decimal{}, fmt.Errorf("número vacío")
+	}
+
+	exponente := 0
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		e, err := strconv.Atoi(s[i+1:])
+		if err != nil {
+			return Decimal{}, fmt.Errorf("número inválido %q", texto)
+		}
+		exponente = e
+		s = s[:i]
+	}
+
+	entero, fraccion, _ := strings.Cut(s, ".")
+	digitos := entero + fraccion
+	if d := strings.TrimLeft(digitos, "+-"); d == "" || len(digitos)-len(d) > 1 || strings.ContainsAny(d, "+-") {
+		return Decimal{}, fmt.Errorf("número inválido %q", texto)
+	}
+	coef, ok := new(big.Int).SetString(digitos, 10)
+	if !ok {
+		return Decimal{}, fmt.Errorf("número inválido %q", texto)
+	}
+
+	escala := int64(len(fraccion)) - int64(exponente)
+	if escala > MaxEscala || escala < -MaxEscala {
+		return Decimal{}, fmt.Errorf("número fuera de rango %q", texto)
+	}
+	if escala < 0 {
+		return Decimal{coef: coef.Mul(coef, potencia10(int32(-escala)))}, nil
+	}
+	return Decimal{coef: coef, escala: int32(escala)}, nil
+}
+
+// DebeParsear es ParsearDecimal para constantes del código; entra en pánico si el texto es inválido
+func DebeParsear(texto string) Decimal {
+	d, err := ParsearDecimal(texto)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func potencia10(n int32) *big.Int {
+	return new(big.Int).Exp(diez, big.NewInt(int64(n)), nil)
+}
+
+func (d Decimal) coeficiente() *big.Int {
+	if d.coef == nil {
+		return new(big.Int)
+	}
+	return d.coef
+}
+
+// conEscala devuelve el coeficiente de d expresado con una escala mayor o igual a la suya
+func (d Decimal) conEscala(escala int32) *big.Int {
+	coef := new(big.Int).Set(d.coeficiente())
+	if escala > d.escala {
+		coef.Mul(coef, potencia10(escala-d.escala))
+	}
+	return coef
+}
+
+func alinear(a, b Decimal) (*big.Int, *big.Int, int32) {
+	escala := a.escala
+	if b.escala > escala {
+		escala = b.escala
+	}
+	return a.conEscala(escala), b.conEscala(escala), escala
+}
+
+// Sumar devuelve d + otro
+func (d Decimal) Sumar(otro Decimal) Decimal {
+	x, y, escala := alinear(d, otro)
+	return Decimal{coef: x.Add(x, y), escala: escala}
+}
+
+// Restar devuelve d - otro
+func (d Decimal) Restar(otro Decimal) Decimal {
+	x, y, escala := alinear(d, otro)
+	return Decimal{coef: x.Sub(x, y), escala: escala}
+}
+
+// Multiplicar devuelve d × otro, sin redondear
+func (d Decimal) Multiplicar(otro Decimal) Decimal {
+	return Decimal{coef: new(big.Int).Mul(d.coeficiente(), otro.coeficiente()), escala: d.escala + otro.escala}
+}
+
+// Dividir devuelve d / otro redondeado según la regla indicada; dividir entre cero da cero
+func (d Decimal) Dividir(otro Decimal, redondeo Redondeo) Decimal {
+	if otro.EsCero() {
+		return Decimal{}
+	}
+	// d/otro = (coef_d × 10^escala_otro) / (coef_otro × 10^escala_d)
+	numerador := new(big.Int).Mul(d.coeficiente(), potencia10(otro.escala))
+	denominador := new(big.Int).Mul(otro.coeficiente(), potencia10(d.escala))
+	return redondearCociente(numerador, denominador, redondeo)
+}
+
+// Neg devuelve -d
+func (d Decimal) Neg() Decimal {
+	return Decimal{coef: new(big.Int).Neg(d.coeficiente()), escala: d.escala}
+}
+
+// Abs devuelve |d|
+func (d Decimal) Abs() Decimal {
+	return Decimal{coef: new(big.Int).Abs(d.coeficiente()), escala: d.escala}
+}
+
+// Signo devuelve -1, 0 o 1
+func (d Decimal) Signo() int {
+	return d.coeficiente().Sign()
+}
+
+// EsCero indica si d vale cero
+func (d Decimal) EsCero() bool {
+	return d.Signo() == 0
+}
+
+// Cmp devuelve -1, 0 o 1 según d sea menor, igual o mayor que otro
+func (d Decimal) Cmp(otro Decimal) int {
+	x, y, _ := alinear(d, otro)
+	return x.Cmp(y)
+}
+
+// Igual indica si ambos valen lo mismo, aunque tengan distinta escala (1.50 y 1.5)
+func (d Decimal) Igual(otro Decimal) bool {
+	return d.Cmp(otro) == 0
+}
+
+// Redondear lleva d a los decimales de la regla; si ya tiene esos o menos lo devuelve igual. Los
+// decimales de la regla se acotan entre 0 y MaxEscala.
+func (d Decimal) Redondear(redondeo Redondeo) Decimal {
+	if d.escala <= redondeo.Decimales {
+		return d
+	}
+	return redondearCociente(d.coeficiente(), potencia10(d.escala), redondeo)
+}
+
+// redondearCociente devuelve numerador/denominador con los decimales y el modo de la regla
+func redondearCociente(numerador, denominador *big.Int, redondeo Redondeo) Decimal {
+	escala := redondeo.Decimales
+	if escala < 0 {
+		escala = 0
+	} else if escala > MaxEscala {
+		escala = MaxEscala
+	}
+	n := new(big.Int).Mul(numerador, potencia10(escala))
+	cociente, resto := new(big.Int).QuoRem(n, denominador, new(big.Int))
+	if resto.Sign() == 0 {
+		return Decimal{coef: cociente, escala: escala}
+	}
+
+	// Se compara el doble del resto con el divisor para saber si se pasó de la mitad
+	comparacion := new(big.Int).Abs(new(big.Int).Lsh(resto, 1)).Cmp(new(big.Int).Abs(denominador))
+	alejar := false
+	switch redondeo.Modo {
+	case MitadArriba:
+		alejar = comparacion >= 0
+	case MitadPar:
+		alejar = comparacion > 0 || (comparacion == 0 && cociente.Bit(0) == 1)
+	case Truncar:
+		alejar = false
+	}
+	if alejar {
+		if n.Sign() != denominador.Sign() {
+			cociente.Sub(cociente, big.NewInt(1))
+		} else {
+			cociente.Add(cociente, big.NewInt(1))
+		}
+	}
+	return Decimal{coef: cociente, escala: escala}
+}
+
+// Float64 devuelve el float64 más cercano; solo para presentar (Excel, PDF, gráficos)
+func (d Decimal) Float64() float64 {
+	f, _ := strconv.ParseFloat(d.String(), 64)
+	return f
+}
+
+// String devuelve el valor exacto sin ceros sobrantes a la derecha: "12.5", "-0.0035", "0"
+func (d Decimal) String() string {
+	s := d.texto()
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	return s
+}
+
+// StringFijo devuelve el valor con exactamente los decimales indicados (como mucho MaxEscala),
+// redondeando mitad hacia arriba
+func (d Decimal) StringFijo(decimales int32) string {
+	if decimales > MaxEscala {
+		decimales = MaxEscala
+	}
+	r := d.Redondear(Redondeo{Decimales: decimales, Modo: MitadArriba})
+	if r.escala < decimales {
+		r = Decimal{coef: r.conEscala(decimales), escala: decimales}
+	}
+	return r.texto()
+}
+
+// texto escribe el coeficiente con el punto decimal según la escala
+func (d Decimal) texto() string {
+	coef := d.coeficiente()
+	digitos := new(big.Int).Abs(coef).String()
+	signo := ""
+	if coef.Sign() < 0 {
+		signo = "-"
+	}
+	if d.escala <= 0 {
+		return signo + digitos
+	}
+	escala := int(d.escala)
+	if len(digitos) <= escala {
+		digitos = strings.Repeat("0", escala-len(digitos)+1) + digitos
+	}
+	return signo + digitos[:len(digitos)-escala] + "." + digitos[len(digitos)-escala:]
+}
+
+// Format permite usar Decimal con los verbos de fmt: %v y %s dan el valor exacto y %.2f lo redondea
+// mitad hacia arriba, sin pasar por float64
+func (d Decimal) Format(estado fmt.State, verbo rune) {
+	switch verbo {
+	case 'v', 's':
+		rellenar(estado, d.String())
+	case 'f', 'F':
+		decimales, ok := estado.Precision()
+		if !ok {
+			decimales = 6
+		}
+		s := d.StringFijo(int32(decimales))
+		if estado.Flag('+') && d.Signo() >= 0 {
+			s = "+" + s
+		}
+		rellenar(estado, s)
+	default:
+		fmt.Fprintf(estado, fmt.FormatString(estado, verbo), d.Float64())
+	}
+}
+
+// rellenar aplica el ancho y las banderas '-' y '0' del verbo
+func rellenar(estado fmt.State, s string) {
+	ancho, ok := estado.Width()
+	if !ok || len(s) >= ancho {
+		io.WriteString(estado, s)
+		return
+	}
+	faltan := ancho - len(s)
+	switch {
+	case estado.Flag('-'):
+		s += strings.Repeat(" ", faltan)
+	case estado.Flag('0'):
+		signo := ""
+		if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+			signo, s = s[:1], s[1:]
+		}
+		s = signo + strings.Repeat("0", faltan) + s
+	default:
+		s = strings.Repeat(" ", faltan) + s
+	}
+	io.WriteString(estado, s)
+}
+
+// MarshalJSON escribe el valor como número JSON exacto
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalJSON acepta números (12.5), cadenas ("12.5") y null (cero)
+func (d *Decimal) UnmarshalJSON(datos []byte) error {
+	s := string(datos)
+	if s == "null" {
+		*d = Decimal{}
+		return nil
+	}
+	if sinComillas, err := strconv.Unquote(s); err == nil {
+		s = sinComillas
+	}
+	valor, err := ParsearDecimal(s)
+	if err != nil {
+		return err
+	}
+	*d = valor
+	return nil
+}
+
+// Value guarda el valor exacto como texto, que PostgreSQL convierte a NUMERIC sin pérdida
+func (d Decimal) Value() (driver.Value, error) {
+	return d.String(), nil
+}
+
+// Scan lee NUMERIC/DECIMAL (que lib/pq entrega como texto), enteros y float64; NULL es cero
+func (d *Decimal) Scan(valor interface{}) error {
+	switch v := valor.(type) {
+	case nil:
+		*d = Decimal{}
+	case []byte:
+		return d.Scan(string(v))
+	case string:
+		parseado, err := ParsearDecimal(v)
+		if err != nil {
+			return err
+		}
+		*d = parseado
+	case int64:
+		*d = NuevoDecimal(v, 0)
+	case float64:
+		*d = DecimalDesdeFloat(v)
+	default:
+		return fmt.Errorf("no se puede convertir %T a Decimal", valor)
+	}
+	return nil
+}
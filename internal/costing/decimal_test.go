@@ -0,0 +1,167 @@
+package costing
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestParsearDecimal(t *testing.T) {
+	casos := []struct {
+		texto string
+		valor string
+	}{
+		{"12.50", "12.5"},
+		{"-12.50", "-12.5"},
+		{"+3", "3"},
+		{" 0.0035 ", "0.0035"},
+		{".5", "0.5"},
+		{"7.", "7"},
+		{"1e3", "1000"},
+		{"1.25E-2", "0.0125"},
+		{"-4.5e1", "-45"},
+		{"0", "0"},
+		{"1e64", "1" + fmt.Sprintf("%064d", 0)},
+		{"1e-64", "0." + fmt.Sprintf("%063d", 0) + "1"},
+	}
+	for _, caso := range casos {
+		d, err := ParsearDecimal(caso.texto)
+		if err != nil {
+			t.Errorf("ParsearDecimal(%q): %v", caso.texto, err)
+			continue
+		}
+		if d.String() != caso.valor {
+			t.Errorf("ParsearDecimal(%q) = %s, se esperaba %s", caso.texto, d, caso.valor)
+		}
+	}
+}
+
+func TestParsearDecimalInvalido(t *testing.T) {
+	for _, texto := range []string{"", "  ", "abc", "1.2.3", "--1", "1-2", "+-1", "1e", "1ex", ".", "e5"} {
+		if d, err := ParsearDecimal(texto); err == nil {
+			t.Errorf("ParsearDecimal(%q) = %s, se esperaba un error", texto, d)
+		}
+	}
+}
+
+func TestParsearDecimalFueraDeRango(t *testing.T) {
+	textos := []string{
+		"1e2000000000",
+		"1e-2000000000",
+		"1e65",
+		"1e-65",
+		"1.5e66",
+		"0." + fmt.Sprintf("%065d", 1),
+		"1e99999999999999999999",
+	}
+	for _, texto := range textos {
+		if d, err := ParsearDecimal(texto); err == nil {
+			t.Errorf("ParsearDecimal(%q) = %s, se esperaba un error", texto, d)
+		}
+	}
+
+	var d Decimal
+	if err := json.Unmarshal([]byte(`1e2000000000`), &d); err == nil {
+		t.Errorf("UnmarshalJSON aceptó un exponente fuera de rango")
+	}
+	if err := json.Unmarshal([]byte(`"-1e-2000000000"`), &d); err == nil {
+		t.Errorf("UnmarshalJSON aceptó un exponente fuera de rango en una cadena")
+	}
+}
+
+func TestRedondear(t *testing.T) {
+	casos := []struct {
+		valor     string
+		decimales int32
+		modo      ModoRedondeo
+		esperado  string
+	}{
+		{"2.345", 2, MitadArriba, "2.35"},
+		{"-2.345", 2, MitadArriba, "-2.35"},
+		{"2.344", 2, MitadArriba, "2.34"},
+		{"2.3450001", 2, MitadArriba, "2.35"},
+		{"0.005", 2, MitadArriba, "0.01"},
+		{"2.345", 2, MitadPar, "2.34"},
+		{"2.355", 2, MitadPar, "2.36"},
+		{"-2.345", 2, MitadPar, "-2.34"},
+		{"-2.355", 2, MitadPar, "-2.36"},
+		{"2.3451", 2, MitadPar, "2.35"},
+		{"2.349", 2, Truncar, "2.34"},
+		{"-2.349", 2, Truncar, "-2.34"},
+		{"2.5", 0, MitadArriba, "3"},
+		{"2.5", 0, MitadPar, "2"},
+		{"3.5", 0, MitadPar, "4"},
+		{"12.5", 4, MitadArriba, "12.5"},
+		{"1.23456", -2, MitadArriba, "1"},
+	}
+	for _, caso := range casos {
+		d := DebeParsear(caso.valor).Redondear(Redondeo{Decimales: caso.decimales, Modo: caso.modo})
+		if d.String() != caso.esperado {
+			t.Errorf("Redondear(%s, %d, %d) = %s, se esperaba %s", caso.valor, caso.decimales, caso.modo, d, caso.esperado)
+		}
+	}
+}
+
+func TestRedondearAcotaLaEscala(t *testing.T) {
+	// Un redondeo con más decimales que MaxEscala se queda en MaxEscala en vez de calcular 10^n
+	d := DebeParsear("1").Dividir(NuevoDecimal(3, 0), Redondeo{Decimales: 2000000000, Modo: MitadArriba})
+	if d.escala != MaxEscala {
+		t.Fatalf("Dividir con 2000000000 decimales dio escala %d, se esperaba %d", d.escala, MaxEscala)
+	}
+	if s := NuevoDecimal(1, 0).StringFijo(2000000000); len(s) != 2+MaxEscala {
+		t.Errorf("StringFijo con 2000000000 decimales dio %d caracteres, se esperaban %d", len(s), 2+MaxEscala)
+	}
+}
+
+func TestDividir(t *testing.T) {
+	casos := []struct {
+		a, b     string
+		esperado string
+	}{
+		{"10", "3", "3.3333"},
+		{"20", "3", "6.6667"},
+		{"-20", "3", "-6.6667"},
+		{"1", "-8", "-0.125"},
+		{"5", "0", "0"},
+	}
+	for _, caso := range casos {
+		d := DebeParsear(caso.a).Dividir(DebeParsear(caso.b), Redondeo{Decimales: 4, Modo: MitadArriba})
+		if d.String() != caso.esperado {
+			t.Errorf("%s / %s = %s, se esperaba %s", caso.a, caso.b, d, caso.esperado)
+		}
+	}
+}
+
+func TestFormatYJSON(t *testing.T) {
+	d := DebeParsear("1234.565")
+	if s := fmt.Sprintf("%.2f", d); s != "1234.57" {
+		t.Errorf("%%.2f = %s, se esperaba 1234.57", s)
+	}
+	if s := fmt.Sprintf("%+.1f", DebeParsear("0.25")); s != "+0.3" {
+		t.Errorf("%%+.1f = %s, se esperaba +0.3", s)
+	}
+	if s := fmt.Sprintf("%08.2f", DebeParsear("-1.5")); s != "-0001.50" {
+		t.Errorf("%%08.2f = %s, se esperaba -0001.50", s)
+	}
+
+	datos, err := json.Marshal(struct{ Monto Decimal }{DebeParsear("12.50")})
+	if err != nil || string(datos) != `{"Monto":12.5}` {
+		t.Errorf("MarshalJSON = %s, %v", datos, err)
+	}
+	var leido struct{ A, B, C Decimal }
+	if err := json.Unmarshal([]byte(`{"A":0.1,"B":"2.25","C":null}`), &leido); err != nil {
+		t.Fatalf("UnmarshalJSON: %v", err)
+	}
+	if leido.A.String() != "0.1" || leido.B.String() != "2.25" || !leido.C.EsCero() {
+		t.Errorf("UnmarshalJSON = %s, %s, %s", leido.A, leido.B, leido.C)
+	}
+}
+
+func TestDecimalDesdeFloat(t *testing.T) {
+	if d := DecimalDesdeFloat(0.1).Sumar(DecimalDesdeFloat(0.2)); d.String() != "0.3" {
+		t.Errorf("0.1 + 0.2 = %s, se esperaba 0.3", d)
+	}
+	if d := DecimalDesdeFloat(1e-300); !d.EsCero() {
+		t.Errorf("DecimalDesdeFloat(1e-300) = %s, se esperaba 0", d)
+	}
+}
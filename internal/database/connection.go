@@ -109,7 +109,7 @@ func (db *DB) RunMigrations() error {
 		cantidad DECIMAL(15,6) NOT NULL DEFAULT 0,
 		precio DECIMAL(15,4) NOT NULL DEFAULT 0,
 		cuadrilla DECIMAL(15,6) DEFAULT NULL,
-		parcial DECIMAL(15,4) GENERATED ALWAYS AS (cantidad * precio) STORED,
+		parcial DECIMAL(15,4) GENERATED ALWAYS AS (ROUND(ROUND(cantidad, 4) * precio, 2)) STORED,
 		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 		UNIQUE(partida_id, recurso_id)
@@ -0,0 +1,202 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"goexcel/internal/models"
+)
+
+// EquipoRepository maneja los análisis de costo horario de equipos. Los análisis sin organización son
+// globales y los de una organización prevalecen sobre ellos para el mismo equipo.
+type EquipoRepository struct {
+	db *sql.DB
+}
+
+// NewEquipoRepository crea una nueva instancia del repositorio de análisis de equipos
+func NewEquipoRepository(db *sql.DB) *EquipoRepository {
+	return &EquipoRepository{db: db}
+}
+
+const columnasAnalisisEquipo = `
+	id, organizacion_id, codigo_recurso, descripcion, potencia_hp,
+	valor_adquisicion, vida_economica_anios, horas_anuales, porcentaje_rescate,
+	tasa_interes_anual, porcentaje_seguros_anual, porcentaje_mantenimiento,
+	valor_neumaticos, vida_neumaticos_horas,
+	COALESCE(codigo_combustible, ''), consumo_combustible, precio_combustible,
+	COALESCE(codigo_lubricante, ''), consumo_lubricante, precio_lubricante,
+	COALESCE(codigo_operador, ''), horas_operador, precio_operador,
+	created_at, updated_at`
+
+func escanearAnalisisEquipo(scanner interface{ Scan(...interface{}) error }) (*models.AnalisisEquipo, error) {
+	var a models.AnalisisEquipo
+	err := scanner.Scan(
+		&a.ID, &a.OrganizacionID, &a.CodigoRecurso, &a.Descripcion, &a.PotenciaHP,
+		&a.ValorAdquisicion, &a.VidaEconomicaAnios, &a.HorasAnuales, &a.PorcentajeRescate,
+		&a.TasaInteresAnual, &a.PorcentajeSegurosAnual, &a.PorcentajeMantenimiento,
+		&a.ValorNeumaticos, &a.VidaNeumaticosHoras,
+		&a.Combustible.Codigo, &a.Combustible.Cantidad, &a.Combustible.Precio,
+		&a.Lubricante.Codigo, &a.Lubricante.Cantidad, &a.Lubricante.Precio,
+		&a.Operador.Codigo, &a.Operador.Cantidad, &a.Operador.Precio,
+		&a.CreatedAt, &a.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r *EquipoRepository) consultar(query string, args ...interface{}) ([]models.AnalisisEquipo, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando análisis de equipos: %v", err)
+	}
+	defer rows.Close()
+
+	analisis := []models.AnalisisEquipo{}
+	for rows.Next() {
+		a, err := escanearAnalisisEquipo(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error escaneando análisis de equipo: %v", err)
+		}
+		analisis = append(analisis, *a)
+	}
+
+	return analisis, rows.Err()
+}
+
+// Listar obtiene los análisis de una organización junto con los globales; sin organización, solo los globales
+func (r *EquipoRepository) Listar(organizacionID *uuid.UUID) ([]models.AnalisisEquipo, error) {
+	query := `SELECT ` + columnasAnalisisEquipo + `
+		FROM analisis_equipos
+		WHERE organizacion_id IS NULL OR organizacion_id = $1
+		ORDER BY codigo_recurso, (organizacion_id IS NULL)`
+	return r.consultar(query, organizacionID)
+}
+
+// Efectivos obtiene un análisis por equipo para la organización: el suyo o, si no tiene, el global
+func (r *EquipoRepository) Efectivos(organizacionID *uuid.UUID) ([]models.AnalisisEquipo, error) {
+	query := `SELECT DISTINCT ON (codigo_recurso) ` + columnasAnalisisEquipo + `
+		FROM analisis_equipos
+		WHERE organizacion_id IS NULL OR organizacion_id = $1
+		ORDER BY codigo_recurso, (organizacion_id IS NULL)`
+	return r.consultar(query, organizacionID)
+}
+
+// EfectivosParaProyecto obtiene los análisis efectivos para la organización del proyecto (la suya o la
+// de su dueño)
+func (r *EquipoRepository) EfectivosParaProyecto(proyectoID uuid.UUID) ([]models.AnalisisEquipo, error) {
+	query := `SELECT DISTINCT ON (codigo_recurso) ` + columnasAnalisisEquipo + `
+		FROM analisis_equipos
+		WHERE organizacion_id IS NULL OR organizacion_id = (
+			SELECT COALESCE(p.organizacion_id, u.organizacion_id)
+			FROM proyectos p
+			LEFT JOIN usuarios u ON u.id = p.usuario_id
+			WHERE p.id = $1
+		)
+		ORDER BY codigo_recurso, (organizacion_id IS NULL)`
+	return r.consultar(query, proyectoID)
+}
+
+// ObtenerPorID obtiene un análisis; devuelve nil si no existe
+func (r *EquipoRepository) ObtenerPorID(id uuid.UUID) (*models.AnalisisEquipo, error) {
+	query := `SELECT ` + columnasAnalisisEquipo + ` FROM analisis_equipos WHERE id = $1`
+	return r.obtener(query, id)
+}
+
+// ObtenerPorCodigo obtiene el análisis del equipo en la organización (o el global, sin organización);
+// devuelve nil si no existe
+func (r *EquipoRepository) ObtenerPorCodigo(organizacionID *uuid.UUID, codigo string) (*models.AnalisisEquipo, error) {
+	query := `SELECT ` + columnasAnalisisEquipo + `
+		FROM analisis_equipos
+		WHERE organizacion_id IS NOT DISTINCT FROM $1 AND codigo_recurso = $2`
+	return r.obtener(query, organizacionID, codigo)
+}
+
+func (r *EquipoRepository) obtener(query string, args ...interface{}) (*models.AnalisisEquipo, error) {
+	a, err := escanearAnalisisEquipo(r.db.QueryRow(query, args...))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error obteniendo análisis de equipo: %v", err)
+	}
+	return a, nil
+}
+
+// Crear guarda un análisis nuevo
+func (r *EquipoRepository) Crear(a *models.AnalisisEquipo) error {
+	query := `
+		INSERT INTO analisis_equipos (
+			organizacion_id, codigo_recurso, descripcion, potencia_hp,
+			valor_adquisicion, vida_economica_anios, horas_anuales, porcentaje_rescate,
+			tasa_interes_anual, porcentaje_seguros_anual, porcentaje_mantenimiento,
+			valor_neumaticos, vida_neumaticos_horas,
+			codigo_combustible, consumo_combustible, precio_combustible,
+			codigo_lubricante, consumo_lubricante, precio_lubricante,
+			codigo_operador, horas_operador, precio_operador
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13,
+			NULLIF($14, ''), $15, $16, NULLIF($17, ''), $18, $19, NULLIF($20, ''), $21, $22)
+		RETURNING id, created_at, updated_at`
+
+	err := r.db.QueryRow(query,
+		a.OrganizacionID, a.CodigoRecurso, a.Descripcion, a.PotenciaHP,
+		a.ValorAdquisicion, a.VidaEconomicaAnios, a.HorasAnuales, a.PorcentajeRescate,
+		a.TasaInteresAnual, a.PorcentajeSegurosAnual, a.PorcentajeMantenimiento,
+		a.ValorNeumaticos, a.VidaNeumaticosHoras,
+		a.Combustible.Codigo, a.Combustible.Cantidad, a.Combustible.Precio,
+		a.Lubricante.Codigo, a.Lubricante.Cantidad, a.Lubricante.Precio,
+		a.Operador.Codigo, a.Operador.Cantidad, a.Operador.Precio,
+	).Scan(&a.ID, &a.CreatedAt, &a.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("error creando análisis de equipo %s: %v", a.CodigoRecurso, err)
+	}
+	return nil
+}
+
+// Actualizar reemplaza los datos de un análisis
+func (r *EquipoRepository) Actualizar(a *models.AnalisisEquipo) error {
+	query := `
+		UPDATE analisis_equipos SET
+			codigo_recurso = $2, descripcion = $3, potencia_hp = $4,
+			valor_adquisicion = $5, vida_economica_anios = $6, horas_anuales = $7, porcentaje_rescate = $8,
+			tasa_interes_anual = $9, porcentaje_seguros_anual = $10, porcentaje_mantenimiento = $11,
+			valor_neumaticos = $12, vida_neumaticos_horas = $13,
+			codigo_combustible = NULLIF($14, ''), consumo_combustible = $15, precio_combustible = $16,
+			codigo_lubricante = NULLIF($17, ''), consumo_lubricante = $18, precio_lubricante = $19,
+			codigo_operador = NULLIF($20, ''), horas_operador = $21, precio_operador = $22
+		WHERE id = $1
+		RETURNING updated_at`
+
+	err := r.db.QueryRow(query,
+		a.ID, a.CodigoRecurso, a.Descripcion, a.PotenciaHP,
+		a.ValorAdquisicion, a.VidaEconomicaAnios, a.HorasAnuales, a.PorcentajeRescate,
+		a.TasaInteresAnual, a.PorcentajeSegurosAnual, a.PorcentajeMantenimiento,
+		a.ValorNeumaticos, a.VidaNeumaticosHoras,
+		a.Combustible.Codigo, a.Combustible.Cantidad, a.Combustible.Precio,
+		a.Lubricante.Codigo, a.Lubricante.Cantidad, a.Lubricante.Precio,
+		a.Operador.Codigo, a.Operador.Cantidad, a.Operador.Precio,
+	).Scan(&a.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("análisis de equipo no encontrado")
+		}
+		return fmt.Errorf("error actualizando análisis de equipo: %v", err)
+	}
+	return nil
+}
+
+// Eliminar borra un análisis de la organización indicada; sin organización, uno global
+func (r *EquipoRepository) Eliminar(id uuid.UUID, organizacionID *uuid.UUID) error {
+	result, err := r.db.Exec(`
+		DELETE FROM analisis_equipos
+		WHERE id = $1 AND organizacion_id IS NOT DISTINCT FROM $2`, id, organizacionID)
+	if err != nil {
+		return fmt.Errorf("error eliminando análisis de equipo: %v", err)
+	}
+	if filas, _ := result.RowsAffected(); filas == 0 {
+		return fmt.Errorf("análisis de equipo no encontrado")
+	}
+	return nil
+}
@@ -0,0 +1,257 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"goexcel/internal/costing"
+	"goexcel/internal/models"
+)
+
+// FleteRepository maneja las rutas de flete, los pesos unitarios de materiales y el flete asignado a
+// cada proyecto. Rutas y pesos sin organización son globales; los de una organización prevalecen.
+type FleteRepository struct {
+	db *sql.DB
+}
+
+// NewFleteRepository crea una nueva instancia del repositorio de flete
+func NewFleteRepository(db *sql.DB) *FleteRepository {
+	return &FleteRepository{db: db}
+}
+
+const columnasRutaFlete = `
+	id, organizacion_id, nombre, origen, destino, distancia_km, tarifa_tonelada_km, created_at, updated_at`
+
+func escanearRutaFlete(scanner interface{ Scan(...interface{}) error }) (*models.RutaFlete, error) {
+	var ruta models.RutaFlete
+	err := scanner.Scan(
+		&ruta.ID, &ruta.OrganizacionID, &ruta.Nombre, &ruta.Origen, &ruta.Destino,
+		&ruta.DistanciaKm, &ruta.TarifaToneladaKm, &ruta.CreatedAt, &ruta.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &ruta, nil
+}
+
+// ListarRutas obtiene las rutas de una organización junto con las globales; sin organización, solo las globales
+func (r *FleteRepository) ListarRutas(organizacionID *uuid.UUID) ([]models.RutaFlete, error) {
+	query := `SELECT ` + columnasRutaFlete + `
+		FROM rutas_flete
+		WHERE organizacion_id IS NULL OR organizacion_id = $1
+		ORDER BY nombre, (organizacion_id IS NULL)`
+
+	rows, err := r.db.Query(query, organizacionID)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando rutas de flete: %v", err)
+	}
+	defer rows.Close()
+
+	rutas := []models.RutaFlete{}
+	for rows.Next() {
+		ruta, err := escanearRutaFlete(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error escaneando ruta de flete: %v", err)
+		}
+		rutas = append(rutas, *ruta)
+	}
+
+	return rutas, rows.Err()
+}
+
+// ObtenerRuta obtiene una ruta; devuelve nil si no existe
+func (r *FleteRepository) ObtenerRuta(id uuid.UUID) (*models.RutaFlete, error) {
+	ruta, err := escanearRutaFlete(r.db.QueryRow(`SELECT `+columnasRutaFlete+` FROM rutas_flete WHERE id = $1`, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error obteniendo ruta de flete: %v", err)
+	}
+	return ruta, nil
+}
+
+// CrearRuta guarda una ruta nueva
+func (r *FleteRepository) CrearRuta(ruta *models.RutaFlete) error {
+	query := `
+		INSERT INTO rutas_flete (organizacion_id, nombre, origen, destino, distancia_km, tarifa_tonelada_km)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, updated_at`
+
+	err := r.db.QueryRow(query,
+		ruta.OrganizacionID, ruta.Nombre, ruta.Origen, ruta.Destino, ruta.DistanciaKm, ruta.TarifaToneladaKm,
+	).Scan(&ruta.ID, &ruta.CreatedAt, &ruta.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("error creando ruta de flete %s: %v", ruta.Nombre, err)
+	}
+	return nil
+}
+
+// ActualizarRuta reemplaza los datos de una ruta
+func (r *FleteRepository) ActualizarRuta(ruta *models.RutaFlete) error {
+	query := `
+		UPDATE rutas_flete SET
+			nombre = $2, origen = $3, destino = $4, distancia_km = $5, tarifa_tonelada_km = $6
+		WHERE id = $1
+		RETURNING updated_at`
+
+	err := r.db.QueryRow(query,
+		ruta.ID, ruta.Nombre, ruta.Origen, ruta.Destino, ruta.DistanciaKm, ruta.TarifaToneladaKm,
+	).Scan(&ruta.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("ruta de flete no encontrada")
+		}
+		return fmt.Errorf("error actualizando ruta de flete: %v", err)
+	}
+	return nil
+}
+
+// EliminarRuta borra una ruta de la organización indicada; sin organización, una global. Los proyectos
+// que la usaban quedan sin flete.
+func (r *FleteRepository) EliminarRuta(id uuid.UUID, organizacionID *uuid.UUID) error {
+	result, err := r.db.Exec(`
+		DELETE FROM rutas_flete
+		WHERE id = $1 AND organizacion_id IS NOT DISTINCT FROM $2`, id, organizacionID)
+	if err != nil {
+		return fmt.Errorf("error eliminando ruta de flete: %v", err)
+	}
+	if filas, _ := result.RowsAffected(); filas == 0 {
+		return fmt.Errorf("ruta de flete no encontrada")
+	}
+	return nil
+}
+
+// ListarPesos obtiene los pesos unitarios de una organización junto con los globales; sin organización,
+// solo los globales
+func (r *FleteRepository) ListarPesos(organizacionID *uuid.UUID) ([]models.PesoUnitario, error) {
+	query := `
+		SELECT id, organizacion_id, codigo_recurso, peso_kg, created_at, updated_at
+		FROM pesos_unitarios
+		WHERE organizacion_id IS NULL OR organizacion_id = $1
+		ORDER BY codigo_recurso, (organizacion_id IS NULL)`
+
+	rows, err := r.db.Query(query, organizacionID)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando pesos unitarios: %v", err)
+	}
+	defer rows.Close()
+
+	pesos := []models.PesoUnitario{}
+	for rows.Next() {
+		var peso models.PesoUnitario
+		err := rows.Scan(&peso.ID, &peso.OrganizacionID, &peso.CodigoRecurso, &peso.PesoKg, &peso.CreatedAt, &peso.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("error escaneando peso unitario: %v", err)
+		}
+		pesos = append(pesos, peso)
+	}
+
+	return pesos, rows.Err()
+}
+
+// GuardarPesos crea o reemplaza los pesos de la organización (o los globales) en una transacción: se
+// guardan todos o ninguno
+func (r *FleteRepository) GuardarPesos(organizacionID *uuid.UUID, pesos []models.PesoUnitarioRequest) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error iniciando transacción: %v", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO pesos_unitarios (organizacion_id, codigo_recurso, peso_kg)
+		VALUES ($1, $2, $3)
+		ON CONFLICT ((COALESCE(organizacion_id, '00000000-0000-0000-0000-000000000000'::uuid)), codigo_recurso)
+		DO UPDATE SET peso_kg = EXCLUDED.peso_kg`
+	for _, peso := range pesos {
+		if _, err := tx.Exec(query, organizacionID, peso.CodigoRecurso, peso.PesoKg); err != nil {
+			return fmt.Errorf("error guardando peso unitario de %s: %v", peso.CodigoRecurso, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error confirmando pesos unitarios: %v", err)
+	}
+	return nil
+}
+
+// EliminarPeso borra el peso de un material de la organización indicada; sin organización, el global
+func (r *FleteRepository) EliminarPeso(organizacionID *uuid.UUID, codigo string) error {
+	result, err := r.db.Exec(`
+		DELETE FROM pesos_unitarios
+		WHERE organizacion_id IS NOT DISTINCT FROM $1 AND codigo_recurso = $2`, organizacionID, codigo)
+	if err != nil {
+		return fmt.Errorf("error eliminando peso unitario: %v", err)
+	}
+	if filas, _ := result.RowsAffected(); filas == 0 {
+		return fmt.Errorf("peso unitario no encontrado")
+	}
+	return nil
+}
+
+// PesosParaProyecto obtiene un peso por material para la organización del proyecto (la suya o la de su
+// dueño): el de la organización o, si no tiene, el global
+func (r *FleteRepository) PesosParaProyecto(proyectoID uuid.UUID) (map[string]costing.Decimal, error) {
+	query := `
+		SELECT DISTINCT ON (codigo_recurso) codigo_recurso, peso_kg
+		FROM pesos_unitarios
+		WHERE organizacion_id IS NULL OR organizacion_id = (
+			SELECT COALESCE(p.organizacion_id, u.organizacion_id)
+			FROM proyectos p
+			LEFT JOIN usuarios u ON u.id = p.usuario_id
+			WHERE p.id = $1
+		)
+		ORDER BY codigo_recurso, (organizacion_id IS NULL)`
+
+	rows, err := r.db.Query(query, proyectoID)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando pesos unitarios del proyecto: %v", err)
+	}
+	defer rows.Close()
+
+	pesos := make(map[string]costing.Decimal)
+	for rows.Next() {
+		var codigo string
+		var peso costing.Decimal
+		if err := rows.Scan(&codigo, &peso); err != nil {
+			return nil, fmt.Errorf("error escaneando peso unitario: %v", err)
+		}
+		pesos[codigo] = peso
+	}
+
+	return pesos, rows.Err()
+}
+
+// FleteDeProyecto obtiene la organización del proyecto (la suya o la de su dueño), la ruta asignada y
+// el modo de flete; organización y ruta pueden ser nil
+func (r *FleteRepository) FleteDeProyecto(proyectoID uuid.UUID) (*uuid.UUID, *uuid.UUID, string, error) {
+	query := `
+		SELECT COALESCE(p.organizacion_id, u.organizacion_id), p.ruta_flete_id, p.modo_flete
+		FROM proyectos p
+		LEFT JOIN usuarios u ON u.id = p.usuario_id
+		WHERE p.id = $1`
+
+	var organizacionID, rutaID *uuid.UUID
+	var modo string
+	if err := r.db.QueryRow(query, proyectoID).Scan(&organizacionID, &rutaID, &modo); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil, "", fmt.Errorf("proyecto no encontrado")
+		}
+		return nil, nil, "", fmt.Errorf("error obteniendo flete del proyecto: %v", err)
+	}
+	return organizacionID, rutaID, modo, nil
+}
+
+// AsignarAProyecto guarda la ruta (nil la quita) y el modo de flete del proyecto
+func (r *FleteRepository) AsignarAProyecto(proyectoID uuid.UUID, rutaID *uuid.UUID, modo string) error {
+	result, err := r.db.Exec(`UPDATE proyectos SET ruta_flete_id = $2, modo_flete = $3 WHERE id = $1`, proyectoID, rutaID, modo)
+	if err != nil {
+		return fmt.Errorf("error asignando flete: %v", err)
+	}
+	if filas, _ := result.RowsAffected(); filas == 0 {
+		return fmt.Errorf("proyecto no encontrado")
+	}
+	return nil
+}
@@ -0,0 +1,312 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"goexcel/internal/models"
+)
+
+// ListaPreciosRepository maneja las listas de precios de las organizaciones y su asignación a proyectos
+type ListaPreciosRepository struct {
+	db *sql.DB
+}
+
+// NewListaPreciosRepository crea una nueva instancia del repositorio de listas de precios
+func NewListaPreciosRepository(db *sql.DB) *ListaPreciosRepository {
+	return &ListaPreciosRepository{db: db}
+}
+
+const columnasListaPrecios = `
+	l.id, l.organizacion_id, l.nombre, l.region,
+	TO_CHAR(l.vigente_desde, 'YYYY-MM-DD'), TO_CHAR(l.vigente_hasta, 'YYYY-MM-DD'),
+	(SELECT COUNT(*) FROM listas_precios_recursos lr WHERE lr.lista_precios_id = l.id),
+	l.created_at, l.updated_at`
+
+func escanearListaPrecios(scanner interface{ Scan(...interface{}) error }) (*models.ListaPrecios, error) {
+	var lista models.ListaPrecios
+	var vigenteHasta sql.NullString
+	err := scanner.Scan(
+		&lista.ID, &lista.OrganizacionID, &lista.Nombre, &lista.Region,
+		&lista.VigenteDesde, &vigenteHasta, &lista.CantidadPrecios,
+		&lista.CreatedAt, &lista.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if vigenteHasta.Valid {
+		lista.VigenteHasta = &vigenteHasta.String
+	}
+	return &lista, nil
+}
+
+// Listar obtiene las listas de la organización, por región y de la más reciente a la más antigua
+func (r *ListaPreciosRepository) Listar(organizacionID uuid.UUID) ([]models.ListaPrecios, error) {
+	query := `SELECT ` + columnasListaPrecios + `
+		FROM listas_precios l
+		WHERE l.organizacion_id = $1
+		ORDER BY l.region, l.vigente_desde DESC, l.nombre`
+
+	rows, err := r.db.Query(query, organizacionID)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando listas de precios: %v", err)
+	}
+	defer rows.Close()
+
+	listas := []models.ListaPrecios{}
+	for rows.Next() {
+		lista, err := escanearListaPrecios(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error escaneando lista de precios: %v", err)
+		}
+		listas = append(listas, *lista)
+	}
+
+	return listas, rows.Err()
+}
+
+// ObtenerPorID obtiene la cabecera de una lista; devuelve nil si no existe
+func (r *ListaPreciosRepository) ObtenerPorID(id uuid.UUID) (*models.ListaPrecios, error) {
+	query := `SELECT ` + columnasListaPrecios + ` FROM listas_precios l WHERE l.id = $1`
+
+	lista, err := escanearListaPrecios(r.db.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error obteniendo lista de precios: %v", err)
+	}
+	return lista, nil
+}
+
+// ObtenerPrecios obtiene los precios de la lista ordenados por código de recurso
+func (r *ListaPreciosRepository) ObtenerPrecios(listaID uuid.UUID) ([]models.PrecioLista, error) {
+	query := `
+		SELECT r.id, r.codigo, r.descripcion, r.unidad, lr.precio, COALESCE(lr.moneda, '')
+		FROM listas_precios_recursos lr
+		JOIN recursos r ON r.id = lr.recurso_id
+		WHERE lr.lista_precios_id = $1
+		ORDER BY r.codigo`
+
+	rows, err := r.db.Query(query, listaID)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando precios de la lista: %v", err)
+	}
+	defer rows.Close()
+
+	var precios []models.PrecioLista
+	for rows.Next() {
+		var precio models.PrecioLista
+		if err := rows.Scan(&precio.RecursoID, &precio.Codigo, &precio.Descripcion, &precio.Unidad, &precio.Precio, &precio.Moneda); err != nil {
+			return nil, fmt.Errorf("error escaneando precio de la lista: %v", err)
+		}
+		precios = append(precios, precio)
+	}
+
+	return precios, rows.Err()
+}
+
+// Crear guarda una lista nueva con sus precios iniciales en una transacción
+func (r *ListaPreciosRepository) Crear(lista *models.ListaPrecios, precios []models.PrecioListaRequest) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error iniciando transacción: %v", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO listas_precios (organizacion_id, nombre, region, vigente_desde, vigente_hasta)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at`
+
+	err = tx.QueryRow(query, lista.OrganizacionID, lista.Nombre, lista.Region, lista.VigenteDesde, lista.VigenteHasta).
+		Scan(&lista.ID, &lista.CreatedAt, &lista.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("error creando lista de precios: %v", err)
+	}
+
+	if err := guardarPreciosLista(tx, lista.ID, precios); err != nil {
+		return err
+	}
+	lista.CantidadPrecios = len(precios)
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error confirmando lista de precios: %v", err)
+	}
+	return nil
+}
+
+// Actualizar modifica nombre, región y vigencia de la lista
+func (r *ListaPreciosRepository) Actualizar(lista *models.ListaPrecios) error {
+	query := `
+		UPDATE listas_precios
+		SET nombre = $2, region = $3, vigente_desde = $4, vigente_hasta = $5
+		WHERE id = $1
+		RETURNING updated_at`
+
+	err := r.db.QueryRow(query, lista.ID, lista.Nombre, lista.Region, lista.VigenteDesde, lista.VigenteHasta).Scan(&lista.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("lista de precios no encontrada")
+		}
+		return fmt.Errorf("error actualizando lista de precios: %v", err)
+	}
+	return nil
+}
+
+// Eliminar borra la lista y sus precios; los proyectos que la usaban quedan sin lista
+func (r *ListaPreciosRepository) Eliminar(id uuid.UUID) error {
+	if _, err := r.db.Exec(`DELETE FROM listas_precios WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("error eliminando lista de precios: %v", err)
+	}
+	return nil
+}
+
+// GuardarPrecios crea o reemplaza precios de la lista en una transacción: se guardan todos o ninguno
+func (r *ListaPreciosRepository) GuardarPrecios(listaID uuid.UUID, precios []models.PrecioListaRequest) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error iniciando transacción: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := guardarPreciosLista(tx, listaID, precios); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE listas_precios SET updated_at = CURRENT_TIMESTAMP WHERE id = $1`, listaID); err != nil {
+		return fmt.Errorf("error actualizando lista de precios: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error confirmando precios: %v", err)
+	}
+	return nil
+}
+
+// guardarPreciosLista busca cada recurso por código y guarda su precio; falla con los códigos que no
+// existen en el catálogo de recursos
+func guardarPreciosLista(tx *sql.Tx, listaID uuid.UUID, precios []models.PrecioListaRequest) error {
+	query := `
+		INSERT INTO listas_precios_recursos (lista_precios_id, recurso_id, precio, moneda)
+		SELECT $1, r.id, $3, NULLIF($4, '')
+		FROM recursos r
+		WHERE r.codigo = $2
+		ON CONFLICT (lista_precios_id, recurso_id) DO UPDATE SET
+			precio = EXCLUDED.precio,
+			moneda = EXCLUDED.moneda,
+			updated_at = CURRENT_TIMESTAMP`
+
+	var desconocidos []string
+	for _, precio := range precios {
+		result, err := tx.Exec(query, listaID, precio.Codigo, precio.Precio, precio.Moneda)
+		if err != nil {
+			return fmt.Errorf("error guardando precio de %s: %v", precio.Codigo, err)
+		}
+		if filas, _ := result.RowsAffected(); filas == 0 {
+			desconocidos = append(desconocidos, precio.Codigo)
+		}
+	}
+	if len(desconocidos) > 0 {
+		return fmt.Errorf("recursos no encontrados: %s", strings.Join(desconocidos, ", "))
+	}
+	return nil
+}
+
+// OrganizacionYListaDeProyecto obtiene la organización del proyecto (la suya o la de su dueño) y la
+// lista de precios asignada; ambas pueden ser nil
+func (r *ListaPreciosRepository) OrganizacionYListaDeProyecto(proyectoID uuid.UUID) (*uuid.UUID, *uuid.UUID, error) {
+	query := `
+		SELECT COALESCE(p.organizacion_id, u.organizacion_id), p.lista_precios_id
+		FROM proyectos p
+		LEFT JOIN usuarios u ON u.id = p.usuario_id
+		WHERE p.id = $1`
+
+	var organizacionID, listaID *uuid.UUID
+	if err := r.db.QueryRow(query, proyectoID).Scan(&organizacionID, &listaID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil, fmt.Errorf("proyecto no encontrado")
+		}
+		return nil, nil, fmt.Errorf("error obteniendo lista de precios del proyecto: %v", err)
+	}
+	return organizacionID, listaID, nil
+}
+
+// AsignarAProyecto asigna la lista al proyecto; nil la quita
+func (r *ListaPreciosRepository) AsignarAProyecto(proyectoID uuid.UUID, listaID *uuid.UUID) error {
+	result, err := r.db.Exec(`UPDATE proyectos SET lista_precios_id = $2 WHERE id = $1`, proyectoID, listaID)
+	if err != nil {
+		return fmt.Errorf("error asignando lista de precios: %v", err)
+	}
+	if filas, _ := result.RowsAffected(); filas == 0 {
+		return fmt.Errorf("proyecto no encontrado")
+	}
+	return nil
+}
+
+// UsosEnProyecto obtiene cada recurso usado en las partidas del proyecto con su precio en la lista
+func (r *ListaPreciosRepository) UsosEnProyecto(proyectoID, listaID uuid.UUID) ([]models.UsoRecurso, error) {
+	query := `
+		SELECT pr.id, p.id, p.codigo, p.descripcion, r.codigo, r.descripcion, r.unidad,
+			ROUND(pr.cantidad * (1 + COALESCE(pr.desperdicio, 0) / 100), 4), pr.precio, COALESCE(pr.moneda, ''), lr.precio, COALESCE(lr.moneda, '')
+		FROM partida_recursos pr
+		JOIN partidas p ON p.id = pr.partida_id
+		JOIN recursos r ON r.id = pr.recurso_id
+		LEFT JOIN listas_precios_recursos lr ON lr.recurso_id = pr.recurso_id AND lr.lista_precios_id = $2
+		WHERE p.proyecto_id = $1
+		ORDER BY p.codigo, r.codigo`
+
+	rows, err := r.db.Query(query, proyectoID, listaID)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando recursos del proyecto: %v", err)
+	}
+	defer rows.Close()
+
+	var usos []models.UsoRecurso
+	for rows.Next() {
+		var uso models.UsoRecurso
+		err := rows.Scan(
+			&uso.PartidaRecursoID, &uso.PartidaID, &uso.PartidaCodigo, &uso.PartidaDescripcion,
+			&uso.RecursoCodigo, &uso.Descripcion, &uso.Unidad,
+			&uso.Cantidad, &uso.Precio, &uso.Moneda, &uso.PrecioLista, &uso.MonedaLista,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error escaneando recurso del proyecto: %v", err)
+		}
+		usos = append(usos, uso)
+	}
+
+	return usos, rows.Err()
+}
+
+// AplicarPrecios actualiza los precios de partida_recursos y el costo unitario de las partidas
+// afectadas en una transacción: se guardan todos o ninguno
+func (r *ListaPreciosRepository) AplicarPrecios(actualizaciones []models.ActualizacionPrecio) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error iniciando transacción: %v", err)
+	}
+	defer tx.Rollback()
+
+	partidas := make(map[uuid.UUID]bool)
+	for _, actualizacion := range actualizaciones {
+		_, err := tx.Exec(`UPDATE partida_recursos SET precio = $2, moneda = NULLIF($3, '') WHERE id = $1`,
+			actualizacion.PartidaRecursoID, actualizacion.Precio, actualizacion.Moneda)
+		if err != nil {
+			return fmt.Errorf("error actualizando precio: %v", err)
+		}
+		partidas[actualizacion.PartidaID] = true
+	}
+
+	for partidaID := range partidas {
+		if _, err := tx.Exec(`UPDATE partidas SET costo_total = calcular_costo_partida(id) WHERE id = $1`, partidaID); err != nil {
+			return fmt.Errorf("error recalculando costo de la partida: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error confirmando precios: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,246 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"goexcel/internal/models"
+)
+
+// ManoObraRepository maneja los parámetros anuales del costo de mano de obra de construcción civil.
+// Los parámetros sin organización son globales y los de una organización prevalecen sobre ellos.
+type ManoObraRepository struct {
+	db *sql.DB
+}
+
+// NewManoObraRepository crea una nueva instancia del repositorio de parámetros de mano de obra
+func NewManoObraRepository(db *sql.DB) *ManoObraRepository {
+	return &ManoObraRepository{db: db}
+}
+
+const columnasParametrosManoObra = `
+	id, organizacion_id, anio, COALESCE(descripcion, ''), TO_CHAR(vigente_desde, 'YYYY-MM-DD'),
+	horas_jornada, movilidad_diaria, overol_diario, horas_extra_diarias, porcentaje_sobretasa_extra,
+	created_at, updated_at`
+
+func escanearParametrosManoObra(scanner interface{ Scan(...interface{}) error }) (*models.ParametrosManoObra, error) {
+	var p models.ParametrosManoObra
+	err := scanner.Scan(
+		&p.ID, &p.OrganizacionID, &p.Anio, &p.Descripcion, &p.VigenteDesde,
+		&p.HorasJornada, &p.MovilidadDiaria, &p.OverolDiario, &p.HorasExtraDiarias, &p.PorcentajeSobretasaExtra,
+		&p.CreatedAt, &p.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Listar obtiene los parámetros de una organización junto con los globales, del año más reciente al
+// más antiguo; sin organización, solo los globales
+func (r *ManoObraRepository) Listar(organizacionID *uuid.UUID) ([]models.ParametrosManoObra, error) {
+	query := `SELECT ` + columnasParametrosManoObra + `
+		FROM parametros_mano_obra
+		WHERE organizacion_id IS NULL OR organizacion_id = $1
+		ORDER BY anio DESC, (organizacion_id IS NULL)`
+
+	rows, err := r.db.Query(query, organizacionID)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando parámetros de mano de obra: %v", err)
+	}
+	defer rows.Close()
+
+	lista := []models.ParametrosManoObra{}
+	for rows.Next() {
+		p, err := escanearParametrosManoObra(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error escaneando parámetros de mano de obra: %v", err)
+		}
+		lista = append(lista, *p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range lista {
+		if lista[i].Categorias, err = r.categorias(lista[i].ID); err != nil {
+			return nil, err
+		}
+	}
+	return lista, nil
+}
+
+// ObtenerPorID obtiene un juego de parámetros con sus categorías; devuelve nil si no existe
+func (r *ManoObraRepository) ObtenerPorID(id uuid.UUID) (*models.ParametrosManoObra, error) {
+	query := `SELECT ` + columnasParametrosManoObra + ` FROM parametros_mano_obra WHERE id = $1`
+	return r.obtener(query, id)
+}
+
+// ObtenerPorAnio obtiene los parámetros del año de la organización (o los globales, sin organización);
+// devuelve nil si no existen
+func (r *ManoObraRepository) ObtenerPorAnio(organizacionID *uuid.UUID, anio int) (*models.ParametrosManoObra, error) {
+	query := `SELECT ` + columnasParametrosManoObra + `
+		FROM parametros_mano_obra
+		WHERE organizacion_id IS NOT DISTINCT FROM $1 AND anio = $2`
+	return r.obtener(query, organizacionID, anio)
+}
+
+// VigentesParaProyecto obtiene los últimos parámetros vigentes a la fecha para la organización del
+// proyecto (la suya o la de su dueño); si la organización no tiene, los globales. Devuelve nil si no hay.
+func (r *ManoObraRepository) VigentesParaProyecto(proyectoID uuid.UUID, fecha time.Time) (*models.ParametrosManoObra, error) {
+	query := `SELECT ` + columnasParametrosManoObra + `
+		FROM parametros_mano_obra
+		WHERE vigente_desde <= $2
+		  AND (organizacion_id IS NULL OR organizacion_id = (
+			SELECT COALESCE(p.organizacion_id, u.organizacion_id)
+			FROM proyectos p
+			LEFT JOIN usuarios u ON u.id = p.usuario_id
+			WHERE p.id = $1
+		  ))
+		ORDER BY (organizacion_id IS NULL), vigente_desde DESC
+		LIMIT 1`
+	return r.obtener(query, proyectoID, fecha.Format(models.FormatoFecha))
+}
+
+func (r *ManoObraRepository) obtener(query string, args ...interface{}) (*models.ParametrosManoObra, error) {
+	p, err := escanearParametrosManoObra(r.db.QueryRow(query, args...))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error obteniendo parámetros de mano de obra: %v", err)
+	}
+	if p.Categorias, err = r.categorias(p.ID); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (r *ManoObraRepository) categorias(parametrosID uuid.UUID) ([]models.CategoriaManoObra, error) {
+	query := `
+		SELECT categoria, COALESCE(codigo_recurso, ''), jornal_basico, porcentaje_buc, porcentaje_leyes_sociales
+		FROM parametros_mano_obra_categorias
+		WHERE parametros_mano_obra_id = $1
+		ORDER BY orden, categoria`
+
+	rows, err := r.db.Query(query, parametrosID)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando categorías de mano de obra: %v", err)
+	}
+	defer rows.Close()
+
+	categorias := []models.CategoriaManoObra{}
+	for rows.Next() {
+		var c models.CategoriaManoObra
+		if err := rows.Scan(&c.Categoria, &c.CodigoRecurso, &c.JornalBasico, &c.PorcentajeBUC, &c.PorcentajeLeyesSociales); err != nil {
+			return nil, fmt.Errorf("error escaneando categoría de mano de obra: %v", err)
+		}
+		categorias = append(categorias, c)
+	}
+
+	return categorias, rows.Err()
+}
+
+// Crear guarda un juego de parámetros nuevo con sus categorías en una transacción
+func (r *ManoObraRepository) Crear(p *models.ParametrosManoObra) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error iniciando transacción: %v", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO parametros_mano_obra (
+			organizacion_id, anio, descripcion, vigente_desde, horas_jornada,
+			movilidad_diaria, overol_diario, horas_extra_diarias, porcentaje_sobretasa_extra
+		) VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at, updated_at`
+
+	err = tx.QueryRow(query,
+		p.OrganizacionID, p.Anio, p.Descripcion, p.VigenteDesde, p.HorasJornada,
+		p.MovilidadDiaria, p.OverolDiario, p.HorasExtraDiarias, p.PorcentajeSobretasaExtra,
+	).Scan(&p.ID, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("error creando parámetros de mano de obra: %v", err)
+	}
+
+	if err := guardarCategoriasManoObra(tx, p.ID, p.Categorias); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error confirmando parámetros de mano de obra: %v", err)
+	}
+	return nil
+}
+
+// Actualizar reemplaza los valores y las categorías de un juego de parámetros en una transacción
+func (r *ManoObraRepository) Actualizar(p *models.ParametrosManoObra) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error iniciando transacción: %v", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE parametros_mano_obra SET
+			anio = $2, descripcion = NULLIF($3, ''), vigente_desde = $4, horas_jornada = $5,
+			movilidad_diaria = $6, overol_diario = $7, horas_extra_diarias = $8, porcentaje_sobretasa_extra = $9
+		WHERE id = $1
+		RETURNING updated_at`
+
+	err = tx.QueryRow(query,
+		p.ID, p.Anio, p.Descripcion, p.VigenteDesde, p.HorasJornada,
+		p.MovilidadDiaria, p.OverolDiario, p.HorasExtraDiarias, p.PorcentajeSobretasaExtra,
+	).Scan(&p.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("parámetros de mano de obra no encontrados")
+		}
+		return fmt.Errorf("error actualizando parámetros de mano de obra: %v", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM parametros_mano_obra_categorias WHERE parametros_mano_obra_id = $1`, p.ID); err != nil {
+		return fmt.Errorf("error eliminando categorías de mano de obra: %v", err)
+	}
+	if err := guardarCategoriasManoObra(tx, p.ID, p.Categorias); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error confirmando parámetros de mano de obra: %v", err)
+	}
+	return nil
+}
+
+// Eliminar borra un juego de parámetros de la organización indicada; sin organización, uno global
+func (r *ManoObraRepository) Eliminar(id uuid.UUID, organizacionID *uuid.UUID) error {
+	result, err := r.db.Exec(`
+		DELETE FROM parametros_mano_obra
+		WHERE id = $1 AND organizacion_id IS NOT DISTINCT FROM $2`, id, organizacionID)
+	if err != nil {
+		return fmt.Errorf("error eliminando parámetros de mano de obra: %v", err)
+	}
+	if filas, _ := result.RowsAffected(); filas == 0 {
+		return fmt.Errorf("parámetros de mano de obra no encontrados")
+	}
+	return nil
+}
+
+func guardarCategoriasManoObra(tx *sql.Tx, parametrosID uuid.UUID, categorias []models.CategoriaManoObra) error {
+	query := `
+		INSERT INTO parametros_mano_obra_categorias (
+			parametros_mano_obra_id, categoria, codigo_recurso, jornal_basico,
+			porcentaje_buc, porcentaje_leyes_sociales, orden
+		) VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6, $7)`
+
+	for i, c := range categorias {
+		_, err := tx.Exec(query, parametrosID, c.Categoria, c.CodigoRecurso, c.JornalBasico, c.PorcentajeBUC, c.PorcentajeLeyesSociales, i)
+		if err != nil {
+			return fmt.Errorf("error guardando categoría %s: %v", c.Categoria, err)
+		}
+	}
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/google/uuid"
+	"goexcel/internal/costing"
 	"goexcel/internal/models"
 )
 
@@ -195,40 +196,8 @@ func (r *MetradoRepository) EliminarMetrado(proyectoID uuid.UUID, partidaCodigo
 	return nil
 }
 
-// ObtenerResumenProyecto obtiene el resumen financiero de un proyecto
-func (r *MetradoRepository) ObtenerResumenProyecto(proyectoID uuid.UUID) (*models.ResumenProyecto, error) {
-	query := `SELECT * FROM obtener_resumen_proyecto($1)`
-
-	var resumen models.ResumenProyecto
-	err := r.db.QueryRow(query, proyectoID).Scan(
-		&resumen.TotalPartidas,
-		&resumen.CostoDirecto,
-		&resumen.PartidasConMetrado,
-		&resumen.PartidasSinMetrado,
-	)
-
-	if err != nil {
-		return nil, fmt.Errorf("error obteniendo resumen del proyecto: %v", err)
-	}
-
-	return &resumen, nil
-}
-
-// CalcularCostoTotalProyecto calcula el costo total del proyecto con metrados
-func (r *MetradoRepository) CalcularCostoTotalProyecto(proyectoID uuid.UUID) (float64, error) {
-	query := `SELECT calcular_costo_total_proyecto($1)`
-
-	var costoTotal float64
-	err := r.db.QueryRow(query, proyectoID).Scan(&costoTotal)
-	if err != nil {
-		return 0, fmt.Errorf("error calculando costo total del proyecto: %v", err)
-	}
-
-	return costoTotal, nil
-}
-
-// ObtenerMetradosSimples obtiene metrados en formato simple (mapa clave-valor)
-func (r *MetradoRepository) ObtenerMetradosSimples(proyectoID uuid.UUID) (map[string]float64, error) {
+// ObtenerMetradosSimples obtiene metrados en formato simple (mapa clave-valor), con el valor exacto de la BD
+func (r *MetradoRepository) ObtenerMetradosSimples(proyectoID uuid.UUID) (map[string]costing.Decimal, error) {
 	query := `SELECT partida_codigo, metrado FROM metrados_partidas WHERE proyecto_id = $1`
 
 	rows, err := r.db.Query(query, proyectoID)
@@ -237,10 +206,10 @@ func (r *MetradoRepository) ObtenerMetradosSimples(proyectoID uuid.UUID) (map[st
 	}
 	defer rows.Close()
 
-	metrados := make(map[string]float64)
+	metrados := make(map[string]costing.Decimal)
 	for rows.Next() {
 		var codigo string
-		var metrado float64
+		var metrado costing.Decimal
 		err := rows.Scan(&codigo, &metrado)
 		if err != nil {
 			return nil, fmt.Errorf("error escaneando metrado simple: %v", err)
@@ -0,0 +1,144 @@
+package repositories
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"goexcel/internal/models"
+)
+
+// ParametrosRepository maneja los parámetros de cálculo guardados en organizaciones, proyectos y
+// presupuestos. La moneda de proyectos y presupuestos vive en su columna moneda, no en el JSON.
+type ParametrosRepository struct {
+	db *sql.DB
+}
+
+// NewParametrosRepository crea una nueva instancia del repositorio de parámetros
+func NewParametrosRepository(db *sql.DB) *ParametrosRepository {
+	return &ParametrosRepository{db: db}
+}
+
+// ObtenerDeOrganizacion obtiene los parámetros declarados por la organización
+func (r *ParametrosRepository) ObtenerDeOrganizacion(organizacionID uuid.UUID) (*models.ParametrosCalculo, error) {
+	var datos []byte
+	err := r.db.QueryRow(`SELECT COALESCE(parametros, '{}') FROM organizaciones WHERE id = $1`, organizacionID).Scan(&datos)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("organización no encontrada")
+		}
+		return nil, fmt.Errorf("error obteniendo parámetros de la organización: %v", err)
+	}
+	return leerParametros(datos)
+}
+
+// GuardarDeOrganizacion reemplaza los parámetros declarados por la organización
+func (r *ParametrosRepository) GuardarDeOrganizacion(organizacionID uuid.UUID, parametros *models.ParametrosCalculo) error {
+	datos, err := json.Marshal(parametros)
+	if err != nil {
+		return fmt.Errorf("error serializando parámetros: %v", err)
+	}
+
+	result, err := r.db.Exec(`UPDATE organizaciones SET parametros = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1`, organizacionID, datos)
+	if err != nil {
+		return fmt.Errorf("error guardando parámetros de la organización: %v", err)
+	}
+	if filas, _ := result.RowsAffected(); filas == 0 {
+		return fmt.Errorf("organización no encontrada")
+	}
+	return nil
+}
+
+// ObtenerDeProyecto obtiene los parámetros declarados por el proyecto y por su organización, que es la
+// del proyecto o, si no tiene, la de su dueño
+func (r *ParametrosRepository) ObtenerDeProyecto(proyectoID uuid.UUID) (*models.ParametrosCalculo, *models.ParametrosCalculo, error) {
+	query := `
+		SELECT COALESCE(p.parametros, '{}'), COALESCE(p.moneda, ''), COALESCE(o.parametros, '{}')
+		FROM proyectos p
+		LEFT JOIN usuarios u ON u.id = p.usuario_id
+		LEFT JOIN organizaciones o ON o.id = COALESCE(p.organizacion_id, u.organizacion_id)
+		WHERE p.id = $1`
+
+	return r.obtenerConOrganizacion(query, proyectoID, "proyecto")
+}
+
+// GuardarDeProyecto reemplaza los parámetros declarados por el proyecto; la moneda, si viene, actualiza
+// la columna moneda
+func (r *ParametrosRepository) GuardarDeProyecto(proyectoID uuid.UUID, parametros *models.ParametrosCalculo) error {
+	return r.guardarConMoneda("proyectos", proyectoID, parametros, "proyecto")
+}
+
+// ObtenerDePresupuesto obtiene los parámetros declarados por el presupuesto y por su organización
+func (r *ParametrosRepository) ObtenerDePresupuesto(presupuestoID uuid.UUID) (*models.ParametrosCalculo, *models.ParametrosCalculo, error) {
+	query := `
+		SELECT COALESCE(p.parametros, '{}'), COALESCE(p.moneda, ''), COALESCE(o.parametros, '{}')
+		FROM presupuestos p
+		LEFT JOIN usuarios u ON u.id = p.usuario_id
+		LEFT JOIN organizaciones o ON o.id = COALESCE(p.organizacion_id, u.organizacion_id)
+		WHERE p.id = $1`
+
+	return r.obtenerConOrganizacion(query, presupuestoID, "presupuesto")
+}
+
+// GuardarDePresupuesto reemplaza los parámetros declarados por el presupuesto
+func (r *ParametrosRepository) GuardarDePresupuesto(presupuestoID uuid.UUID, parametros *models.ParametrosCalculo) error {
+	return r.guardarConMoneda("presupuestos", presupuestoID, parametros, "presupuesto")
+}
+
+func (r *ParametrosRepository) obtenerConOrganizacion(query string, id uuid.UUID, entidad string) (*models.ParametrosCalculo, *models.ParametrosCalculo, error) {
+	var propios, deOrganizacion []byte
+	var moneda string
+	if err := r.db.QueryRow(query, id).Scan(&propios, &moneda, &deOrganizacion); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil, fmt.Errorf("%s no encontrado", entidad)
+		}
+		return nil, nil, fmt.Errorf("error obteniendo parámetros del %s: %v", entidad, err)
+	}
+
+	parametros, err := leerParametros(propios)
+	if err != nil {
+		return nil, nil, err
+	}
+	if moneda != "" {
+		parametros.Moneda = &moneda
+	}
+
+	organizacion, err := leerParametros(deOrganizacion)
+	if err != nil {
+		return nil, nil, err
+	}
+	return parametros, organizacion, nil
+}
+
+func (r *ParametrosRepository) guardarConMoneda(tabla string, id uuid.UUID, parametros *models.ParametrosCalculo, entidad string) error {
+	sinMoneda := *parametros
+	sinMoneda.Moneda = nil
+	datos, err := json.Marshal(sinMoneda)
+	if err != nil {
+		return fmt.Errorf("error serializando parámetros: %v", err)
+	}
+
+	query := fmt.Sprintf(`
+		UPDATE %s
+		SET parametros = $2, moneda = COALESCE($3, moneda), updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1`, tabla)
+
+	result, err := r.db.Exec(query, id, datos, parametros.Moneda)
+	if err != nil {
+		return fmt.Errorf("error guardando parámetros del %s: %v", entidad, err)
+	}
+	if filas, _ := result.RowsAffected(); filas == 0 {
+		return fmt.Errorf("%s no encontrado", entidad)
+	}
+	return nil
+}
+
+// leerParametros interpreta la columna JSONB de parámetros
+func leerParametros(datos []byte) (*models.ParametrosCalculo, error) {
+	parametros := &models.ParametrosCalculo{}
+	if err := json.Unmarshal(datos, parametros); err != nil {
+		return nil, fmt.Errorf("error leyendo parámetros: %v", err)
+	}
+	return parametros, nil
+}
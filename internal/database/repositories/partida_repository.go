@@ -53,14 +53,15 @@ func (r *PartidaRepository) Create(partida *models.PartidaCreateRequest) (*model
 
 func (r *PartidaRepository) AddRecurso(req *models.PartidaRecursoCreateRequest) (*models.PartidaRecurso, error) {
 	query := `
-		INSERT INTO partida_recursos (partida_id, recurso_id, cantidad, precio, cuadrilla)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO partida_recursos (partida_id, recurso_id, cantidad, precio, cuadrilla, desperdicio)
+		VALUES ($1, $2, $3, $4, $5, $6)
 		ON CONFLICT (partida_id, recurso_id) DO UPDATE SET
 			cantidad = EXCLUDED.cantidad,
 			precio = EXCLUDED.precio,
 			cuadrilla = EXCLUDED.cuadrilla,
+			desperdicio = EXCLUDED.desperdicio,
 			updated_at = CURRENT_TIMESTAMP
-		RETURNING id, partida_id, recurso_id, cantidad, precio, cuadrilla, parcial, created_at, updated_at
+		RETURNING id, partida_id, recurso_id, cantidad, precio, cuadrilla, desperdicio, parcial, created_at, updated_at
 	`
 
 	var pr models.PartidaRecurso
@@ -71,6 +72,7 @@ func (r *PartidaRepository) AddRecurso(req *models.PartidaRecursoCreateRequest)
 		req.Cantidad,
 		req.Precio,
 		req.Cuadrilla,
+		req.Desperdicio,
 	).Scan(
 		&pr.ID,
 		&pr.PartidaID,
@@ -78,6 +80,7 @@ func (r *PartidaRepository) AddRecurso(req *models.PartidaRecursoCreateRequest)
 		&pr.Cantidad,
 		&pr.Precio,
 		&pr.Cuadrilla,
+		&pr.Desperdicio,
 		&pr.Parcial,
 		&pr.CreatedAt,
 		&pr.UpdatedAt,
@@ -0,0 +1,114 @@
+package repositories
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"goexcel/internal/models"
+)
+
+// PlantillaRepository maneja las operaciones de base de datos para plantillas Excel
+type PlantillaRepository struct {
+	db *sql.DB
+}
+
+// NewPlantillaRepository crea una nueva instancia del repositorio de plantillas
+func NewPlantillaRepository(db *sql.DB) *PlantillaRepository {
+	return &PlantillaRepository{db: db}
+}
+
+// ObtenerPorOrganizacion obtiene la plantilla de una organización; devuelve nil si no tiene una
+func (r *PlantillaRepository) ObtenerPorOrganizacion(organizacionID uuid.UUID) (*models.PlantillaExcel, error) {
+	query := `
+		SELECT id, organizacion_id,
+			COALESCE(color_titulo, ''), COALESCE(color_cabecera, ''), COALESCE(color_partida, ''),
+			COALESCE(color_seccion, ''), COALESCE(color_total, ''),
+			COALESCE(fuente, ''), COALESCE(tamano_fuente, 0), COALESCE(formato_numero, ''),
+			COALESCE(empresa, ''), COALESCE(ruc, ''), COALESCE(ingeniero, ''), COALESCE(cip, ''),
+			logo, COALESCE(logo_extension, ''), COALESCE(anchos_columna, '{}'),
+			created_at, updated_at
+		FROM plantillas_excel
+		WHERE organizacion_id = $1`
+
+	var plantilla models.PlantillaExcel
+	var anchos []byte
+	err := r.db.QueryRow(query, organizacionID).Scan(
+		&plantilla.ID, &plantilla.OrganizacionID,
+		&plantilla.ColorTitulo, &plantilla.ColorCabecera, &plantilla.ColorPartida,
+		&plantilla.ColorSeccion, &plantilla.ColorTotal,
+		&plantilla.Fuente, &plantilla.TamanoFuente, &plantilla.FormatoNumero,
+		&plantilla.Empresa, &plantilla.RUC, &plantilla.Ingeniero, &plantilla.CIP,
+		&plantilla.Logo, &plantilla.LogoExtension, &anchos,
+		&plantilla.CreatedAt, &plantilla.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error obteniendo plantilla: %v", err)
+	}
+
+	if err := json.Unmarshal(anchos, &plantilla.AnchosColumna); err != nil {
+		return nil, fmt.Errorf("error leyendo anchos de columna: %v", err)
+	}
+
+	return &plantilla, nil
+}
+
+// Guardar crea o reemplaza la plantilla de la organización
+func (r *PlantillaRepository) Guardar(plantilla *models.PlantillaExcel) error {
+	anchos, err := json.Marshal(plantilla.AnchosColumna)
+	if err != nil {
+		return fmt.Errorf("error serializando anchos de columna: %v", err)
+	}
+
+	query := `
+		INSERT INTO plantillas_excel (
+			organizacion_id, color_titulo, color_cabecera, color_partida, color_seccion, color_total,
+			fuente, tamano_fuente, formato_numero, empresa, ruc, ingeniero, cip,
+			logo, logo_extension, anchos_columna
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+		ON CONFLICT (organizacion_id)
+		DO UPDATE SET
+			color_titulo = EXCLUDED.color_titulo,
+			color_cabecera = EXCLUDED.color_cabecera,
+			color_partida = EXCLUDED.color_partida,
+			color_seccion = EXCLUDED.color_seccion,
+			color_total = EXCLUDED.color_total,
+			fuente = EXCLUDED.fuente,
+			tamano_fuente = EXCLUDED.tamano_fuente,
+			formato_numero = EXCLUDED.formato_numero,
+			empresa = EXCLUDED.empresa,
+			ruc = EXCLUDED.ruc,
+			ingeniero = EXCLUDED.ingeniero,
+			cip = EXCLUDED.cip,
+			logo = EXCLUDED.logo,
+			logo_extension = EXCLUDED.logo_extension,
+			anchos_columna = EXCLUDED.anchos_columna,
+			updated_at = CURRENT_TIMESTAMP
+		RETURNING id, created_at, updated_at`
+
+	err = r.db.QueryRow(query,
+		plantilla.OrganizacionID, plantilla.ColorTitulo, plantilla.ColorCabecera, plantilla.ColorPartida,
+		plantilla.ColorSeccion, plantilla.ColorTotal, plantilla.Fuente, plantilla.TamanoFuente,
+		plantilla.FormatoNumero, plantilla.Empresa, plantilla.RUC, plantilla.Ingeniero, plantilla.CIP,
+		plantilla.Logo, plantilla.LogoExtension, anchos,
+	).Scan(&plantilla.ID, &plantilla.CreatedAt, &plantilla.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("error guardando plantilla: %v", err)
+	}
+
+	return nil
+}
+
+// Eliminar borra la plantilla de la organización para volver a los estilos por defecto
+func (r *PlantillaRepository) Eliminar(organizacionID uuid.UUID) error {
+	_, err := r.db.Exec(`DELETE FROM plantillas_excel WHERE organizacion_id = $1`, organizacionID)
+	if err != nil {
+		return fmt.Errorf("error eliminando plantilla: %v", err)
+	}
+	return nil
+}
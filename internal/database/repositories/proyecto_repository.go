@@ -3,6 +3,7 @@ package repositories
 import (
 	"database/sql"
 	"fmt"
+	"strings"
 
 	"github.com/google/uuid"
 	"goexcel/internal/database"
@@ -387,6 +388,53 @@ func (r *ProyectoRepository) GetByIDWithLikeStatus(id uuid.UUID, usuarioID *uuid
 	return proyecto, nil
 }
 
+// Buscar devuelve hasta limit proyectos que cumplen el filtro, los más recientes primero
+func (r *ProyectoRepository) Buscar(filtro models.FiltroProyectos, limit int) ([]models.Proyecto, error) {
+	var condiciones []string
+	var args []interface{}
+	agregar := func(condicion string, valor interface{}) {
+		args = append(args, valor)
+		condiciones = append(condiciones, fmt.Sprintf(condicion, len(args)))
+	}
+
+	if filtro.OrganizacionID != nil {
+		agregar("p.organizacion_id = $%d", *filtro.OrganizacionID)
+	}
+	if filtro.UsuarioID != nil {
+		agregar("p.usuario_id = $%d", *filtro.UsuarioID)
+	}
+	if filtro.Visibility != "" {
+		agregar("p.visibility = $%d", filtro.Visibility)
+	}
+	if filtro.Desde != nil {
+		agregar("p.created_at >= $%d", *filtro.Desde)
+	}
+	if filtro.Hasta != nil {
+		agregar("p.created_at <= $%d", *filtro.Hasta)
+	}
+
+	where := ""
+	if len(condiciones) > 0 {
+		where = "WHERE " + strings.Join(condiciones, " AND ")
+	}
+	args = append(args, limit)
+
+	query := fmt.Sprintf(`
+		SELECT p.id, p.nombre, p.descripcion, p.ubicacion, p.cliente, p.fecha_inicio, p.fecha_fin,
+		       p.moneda, p.usuario_id, p.organizacion_id, p.visibility, p.template_categoria,
+		       p.imagen_portada, p.likes_count, p.vistas_count, p.created_at, p.updated_at,
+		       u.nombre as usuario_nombre, o.nombre as organizacion_nombre
+		FROM proyectos p
+		LEFT JOIN usuarios u ON p.usuario_id = u.id
+		LEFT JOIN organizaciones o ON p.organizacion_id = o.id
+		%s
+		ORDER BY p.created_at DESC
+		LIMIT $%d
+	`, where, len(args))
+
+	return r.queryProyectos(query, args...)
+}
+
 // Helper method para queries comunes
 func (r *ProyectoRepository) queryProyectos(query string, args ...interface{}) ([]models.Proyecto, error) {
 	rows, err := r.db.Query(query, args...)
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/google/uuid"
+	"goexcel/internal/costing"
 	"goexcel/internal/database"
 	"goexcel/internal/models"
 )
@@ -28,11 +29,11 @@ func (r *RecursoRepository) GetTipoRecursoByNombre(nombre string) (*models.TipoR
 		}
 		return nil, fmt.Errorf("error obteniendo tipo de recurso: %w", err)
 	}
-	
+
 	return &tr, nil
 }
 
-func (r *RecursoRepository) CreateOrGetRecurso(codigo, descripcion, unidad string, precioBase float64, tipoRecursoID uuid.UUID) (*models.Recurso, error) {
+func (r *RecursoRepository) CreateOrGetRecurso(codigo, descripcion, unidad string, precioBase costing.Decimal, tipoRecursoID uuid.UUID) (*models.Recurso, error) {
 	// Usar UPSERT para manejar duplicados
 	query := `
 		INSERT INTO recursos (codigo, descripcion, unidad, precio_base, tipo_recurso_id)
@@ -57,4 +58,92 @@ func (r *RecursoRepository) CreateOrGetRecurso(codigo, descripcion, unidad strin
 	}
 	
 	return &recurso, nil
-}
\ No newline at end of file
+}
+// ActualizarIndicesUnificados asigna el índice unificado a cada recurso identificado por su código
+func (r *RecursoRepository) ActualizarIndicesUnificados(asignaciones map[string]string) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("error iniciando transacción: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE recursos SET indice_unificado = $2, updated_at = CURRENT_TIMESTAMP WHERE codigo = $1`
+
+	actualizados := 0
+	for codigo, indice := range asignaciones {
+		var valor interface{}
+		if indice != "" {
+			valor = indice
+		}
+
+		result, err := tx.Exec(query, codigo, valor)
+		if err != nil {
+			return 0, fmt.Errorf("error actualizando índice del recurso %s: %w", codigo, err)
+		}
+
+		filas, _ := result.RowsAffected()
+		if filas == 0 {
+			return 0, fmt.Errorf("recurso no encontrado: %s", codigo)
+		}
+		actualizados++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("error confirmando transacción: %w", err)
+	}
+
+	return actualizados, nil
+}
+
+// ActualizarPreciosBase asigna el precio base del catálogo a cada recurso identificado por su código,
+// en una transacción: si algún código no existe no se actualiza ninguno
+func (r *RecursoRepository) ActualizarPreciosBase(precios []models.PrecioListaRequest) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("error iniciando transacción: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE recursos SET precio_base = $2, updated_at = CURRENT_TIMESTAMP WHERE codigo = $1`
+
+	actualizados := 0
+	for _, precio := range precios {
+		result, err := tx.Exec(query, precio.Codigo, precio.Precio)
+		if err != nil {
+			return 0, fmt.Errorf("error actualizando precio del recurso %s: %w", precio.Codigo, err)
+		}
+
+		filas, _ := result.RowsAffected()
+		if filas == 0 {
+			return 0, fmt.Errorf("recurso no encontrado: %s", precio.Codigo)
+		}
+		actualizados++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("error confirmando transacción: %w", err)
+	}
+
+	return actualizados, nil
+}
+
+// PreciosBase obtiene el precio base del catálogo de los recursos indicados por código; los códigos
+// que no existen no se incluyen
+func (r *RecursoRepository) PreciosBase(codigos []string) (map[string]costing.Decimal, error) {
+	query := `SELECT precio_base FROM recursos WHERE codigo = $1`
+
+	precios := make(map[string]costing.Decimal, len(codigos))
+	for _, codigo := range codigos {
+		var precio costing.Decimal
+		err := r.db.QueryRow(query, codigo).Scan(&precio)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error obteniendo precio del recurso %s: %v", codigo, err)
+		}
+		precios[codigo] = precio
+	}
+
+	return precios, nil
+}
@@ -0,0 +1,164 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"goexcel/internal/models"
+)
+
+// TipoCambioRepository maneja la tabla de tipos de cambio. Las tasas sin organización son globales y
+// las de una organización prevalecen sobre ellas.
+type TipoCambioRepository struct {
+	db *sql.DB
+}
+
+// NewTipoCambioRepository crea una nueva instancia del repositorio de tipos de cambio
+func NewTipoCambioRepository(db *sql.DB) *TipoCambioRepository {
+	return &TipoCambioRepository{db: db}
+}
+
+// Listar obtiene las tasas de una organización junto con las globales; sin organización, solo las globales
+func (r *TipoCambioRepository) Listar(organizacionID *uuid.UUID) ([]models.TipoCambio, error) {
+	query := `
+		SELECT id, organizacion_id, moneda_origen, moneda_destino, tasa,
+			TO_CHAR(fecha_vigencia, 'YYYY-MM-DD'), COALESCE(fuente, ''), created_at
+		FROM tipos_cambio
+		WHERE organizacion_id IS NULL OR organizacion_id = $1
+		ORDER BY moneda_origen, moneda_destino, fecha_vigencia DESC, (organizacion_id IS NULL)`
+
+	rows, err := r.db.Query(query, organizacionID)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando tipos de cambio: %v", err)
+	}
+	defer rows.Close()
+
+	tipos := []models.TipoCambio{}
+	for rows.Next() {
+		var tc models.TipoCambio
+		err := rows.Scan(
+			&tc.ID, &tc.OrganizacionID, &tc.MonedaOrigen, &tc.MonedaDestino, &tc.Tasa,
+			&tc.FechaVigencia, &tc.Fuente, &tc.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error escaneando tipo de cambio: %v", err)
+		}
+		tipos = append(tipos, tc)
+	}
+
+	return tipos, rows.Err()
+}
+
+// Guardar crea la tasa o reemplaza la del mismo par de monedas y fecha
+func (r *TipoCambioRepository) Guardar(tc *models.TipoCambio) error {
+	return guardarTipoCambio(r.db, tc)
+}
+
+// GuardarLote guarda varias tasas en una transacción: se guardan todas o ninguna
+func (r *TipoCambioRepository) GuardarLote(tipos []models.TipoCambio) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error iniciando transacción: %v", err)
+	}
+	defer tx.Rollback()
+
+	for i := range tipos {
+		if err := guardarTipoCambio(tx, &tipos[i]); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error confirmando tipos de cambio: %v", err)
+	}
+	return nil
+}
+
+// Eliminar borra una tasa de la organización indicada; sin organización, una global
+func (r *TipoCambioRepository) Eliminar(id uuid.UUID, organizacionID *uuid.UUID) error {
+	result, err := r.db.Exec(`
+		DELETE FROM tipos_cambio
+		WHERE id = $1 AND organizacion_id IS NOT DISTINCT FROM $2`, id, organizacionID)
+	if err != nil {
+		return fmt.Errorf("error eliminando tipo de cambio: %v", err)
+	}
+	if filas, _ := result.RowsAffected(); filas == 0 {
+		return fmt.Errorf("tipo de cambio no encontrado")
+	}
+	return nil
+}
+
+// VigentesParaProyecto obtiene, por cada par de monedas, la última tasa vigente a la fecha para la
+// organización del proyecto (la suya o la de su dueño); si la organización no tiene una, la global
+func (r *TipoCambioRepository) VigentesParaProyecto(proyectoID uuid.UUID, fecha time.Time) ([]models.TipoCambio, error) {
+	return r.vigentes("proyectos", proyectoID, fecha)
+}
+
+// VigentesParaPresupuesto es VigentesParaProyecto para la organización de un presupuesto jerárquico
+func (r *TipoCambioRepository) VigentesParaPresupuesto(presupuestoID uuid.UUID, fecha time.Time) ([]models.TipoCambio, error) {
+	return r.vigentes("presupuestos", presupuestoID, fecha)
+}
+
+// vigentes obtiene las tasas vigentes para la organización de la fila id de tabla (proyectos o
+// presupuestos), que tienen las mismas columnas de dueño y organización
+func (r *TipoCambioRepository) vigentes(tabla string, id uuid.UUID, fecha time.Time) ([]models.TipoCambio, error) {
+	query := fmt.Sprintf(`
+		SELECT DISTINCT ON (tc.moneda_origen, tc.moneda_destino)
+			tc.id, tc.organizacion_id, tc.moneda_origen, tc.moneda_destino, tc.tasa,
+			TO_CHAR(tc.fecha_vigencia, 'YYYY-MM-DD'), COALESCE(tc.fuente, ''), tc.created_at
+		FROM tipos_cambio tc
+		WHERE tc.fecha_vigencia <= $2
+		  AND (tc.organizacion_id IS NULL OR tc.organizacion_id = (
+			SELECT COALESCE(p.organizacion_id, u.organizacion_id)
+			FROM %s p
+			LEFT JOIN usuarios u ON u.id = p.usuario_id
+			WHERE p.id = $1
+		  ))
+		ORDER BY tc.moneda_origen, tc.moneda_destino, (tc.organizacion_id IS NULL), tc.fecha_vigencia DESC`, tabla)
+
+	rows, err := r.db.Query(query, id, fecha.Format(models.FormatoFecha))
+	if err != nil {
+		return nil, fmt.Errorf("error consultando tipos de cambio vigentes: %v", err)
+	}
+	defer rows.Close()
+
+	var tipos []models.TipoCambio
+	for rows.Next() {
+		var tc models.TipoCambio
+		err := rows.Scan(
+			&tc.ID, &tc.OrganizacionID, &tc.MonedaOrigen, &tc.MonedaDestino, &tc.Tasa,
+			&tc.FechaVigencia, &tc.Fuente, &tc.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error escaneando tipo de cambio: %v", err)
+		}
+		tipos = append(tipos, tc)
+	}
+
+	return tipos, rows.Err()
+}
+
+// ejecutor es lo común de *sql.DB y *sql.Tx que necesita guardarTipoCambio
+type ejecutor interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+func guardarTipoCambio(db ejecutor, tc *models.TipoCambio) error {
+	query := `
+		INSERT INTO tipos_cambio (organizacion_id, moneda_origen, moneda_destino, tasa, fecha_vigencia, fuente)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''))
+		ON CONFLICT ((COALESCE(organizacion_id, '00000000-0000-0000-0000-000000000000'::uuid)),
+			moneda_origen, moneda_destino, fecha_vigencia)
+		DO UPDATE SET tasa = EXCLUDED.tasa, fuente = EXCLUDED.fuente
+		RETURNING id, created_at`
+
+	err := db.QueryRow(query,
+		tc.OrganizacionID, tc.MonedaOrigen, tc.MonedaDestino, tc.Tasa, tc.FechaVigencia, tc.Fuente,
+	).Scan(&tc.ID, &tc.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error guardando tipo de cambio %s→%s del %s: %v", tc.MonedaOrigen, tc.MonedaDestino, tc.FechaVigencia, err)
+	}
+	return nil
+}
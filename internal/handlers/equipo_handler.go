@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"goexcel/internal/models"
+	"goexcel/internal/services"
+)
+
+// EquipoHandler maneja los análisis de costo horario de equipos. Bajo /organizations/{organizacion_id}
+// se gestionan los de la organización; bajo /admin, los globales.
+type EquipoHandler struct {
+	equipoSvc *services.EquipoService
+}
+
+// NewEquipoHandler crea una nueva instancia del handler de equipos
+func NewEquipoHandler(equipoSvc *services.EquipoService) *EquipoHandler {
+	return &EquipoHandler{equipoSvc: equipoSvc}
+}
+
+// ListarAnalisisEquipos devuelve los análisis de la organización junto con los globales
+func (h *EquipoHandler) ListarAnalisisEquipos(w http.ResponseWriter, r *http.Request) {
+	organizacionID, ok := organizacionOGlobal(w, r)
+	if !ok {
+		return
+	}
+
+	analisis, err := h.equipoSvc.Listar(organizacionID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error obteniendo análisis de equipos: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.AnalisisEquiposResponse{
+		Success: true,
+		Data:    analisis,
+	})
+}
+
+// ObtenerAnalisisEquipo devuelve un análisis con su costo horario; con ?lista_precios_id= los insumos
+// se valoran con esa lista de la organización y, sin ella, con el catálogo
+func (h *EquipoHandler) ObtenerAnalisisEquipo(w http.ResponseWriter, r *http.Request) {
+	organizacionID, id, ok := analisisEquipoDeRuta(w, r)
+	if !ok {
+		return
+	}
+
+	var listaID *uuid.UUID
+	if valor := r.URL.Query().Get("lista_precios_id"); valor != "" {
+		parsed, err := uuid.Parse(valor)
+		if err != nil {
+			http.Error(w, "lista_precios_id inválido", http.StatusBadRequest)
+			return
+		}
+		listaID = &parsed
+	}
+
+	a, err := h.equipoSvc.Obtener(id, organizacionID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	costo, err := h.equipoSvc.CostoHorario(a, organizacionID, listaID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	responderAnalisisEquipo(w, http.StatusOK, models.AnalisisEquipoResponse{
+		Success: true,
+		Data:    a,
+		Costo:   costo,
+	})
+}
+
+// CrearAnalisisEquipo registra el análisis de un equipo y devuelve su costo horario con el catálogo
+func (h *EquipoHandler) CrearAnalisisEquipo(w http.ResponseWriter, r *http.Request) {
+	organizacionID, ok := organizacionOGlobal(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.AnalisisEquipoRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Error decodificando JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	a, err := h.equipoSvc.Crear(req, organizacionID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	costo, err := h.equipoSvc.CostoHorario(a, organizacionID, nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("✅ Análisis de equipo %s registrado: %s por hm", a.CodigoRecurso, costo.CostoHorario)
+	responderAnalisisEquipo(w, http.StatusCreated, models.AnalisisEquipoResponse{
+		Success: true,
+		Message: "Análisis de equipo registrado exitosamente",
+		Data:    a,
+		Costo:   costo,
+	})
+}
+
+// ActualizarAnalisisEquipo reemplaza un análisis y devuelve su costo horario con el catálogo
+func (h *EquipoHandler) ActualizarAnalisisEquipo(w http.ResponseWriter, r *http.Request) {
+	organizacionID, id, ok := analisisEquipoDeRuta(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.AnalisisEquipoRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Error decodificando JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	a, err := h.equipoSvc.Actualizar(id, organizacionID, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	costo, err := h.equipoSvc.CostoHorario(a, organizacionID, nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	responderAnalisisEquipo(w, http.StatusOK, models.AnalisisEquipoResponse{
+		Success: true,
+		Message: "Análisis de equipo actualizado exitosamente",
+		Data:    a,
+		Costo:   costo,
+	})
+}
+
+// EliminarAnalisisEquipo borra un análisis
+func (h *EquipoHandler) EliminarAnalisisEquipo(w http.ResponseWriter, r *http.Request) {
+	organizacionID, id, ok := analisisEquipoDeRuta(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.equipoSvc.Eliminar(id, organizacionID); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	responderAnalisisEquipo(w, http.StatusOK, models.AnalisisEquipoResponse{
+		Success: true,
+		Message: "Análisis de equipo eliminado exitosamente",
+	})
+}
+
+// AplicarCostosEquipos recalcula todos los equipos con análisis y guarda su costo horario: en una lista
+// de precios de la organización o, bajo /admin, como precio base del catálogo de recursos
+func (h *EquipoHandler) AplicarCostosEquipos(w http.ResponseWriter, r *http.Request) {
+	organizacionID, ok := organizacionOGlobal(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.AplicarEquiposRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("Error decodificando JSON: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+	if organizacionID != nil && req.ListaPreciosID == nil {
+		http.Error(w, "Debe indicar lista_precios_id", http.StatusBadRequest)
+		return
+	}
+
+	costos, precios, err := h.equipoSvc.Aplicar(organizacionID, req.ListaPreciosID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	mensaje := "Costos horarios aplicados al catálogo de recursos"
+	if organizacionID != nil {
+		mensaje = "Costos horarios aplicados a la lista de precios"
+	}
+	log.Printf("✅ %s: %d equipos", mensaje, len(precios))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.AplicarEquiposResponse{
+		Success: true,
+		Message: mensaje,
+		Costos:  costos,
+		Precios: precios,
+	})
+}
+
+// analisisEquipoDeRuta valida la organización (nil bajo /admin) y el ID del análisis de la ruta
+func analisisEquipoDeRuta(w http.ResponseWriter, r *http.Request) (*uuid.UUID, uuid.UUID, bool) {
+	organizacionID, ok := organizacionOGlobal(w, r)
+	if !ok {
+		return nil, uuid.Nil, false
+	}
+
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "ID de análisis de equipo inválido", http.StatusBadRequest)
+		return nil, uuid.Nil, false
+	}
+	return organizacionID, id, true
+}
+
+func responderAnalisisEquipo(w http.ResponseWriter, status int, respuesta models.AnalisisEquipoResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(respuesta)
+}
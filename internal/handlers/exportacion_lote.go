@@ -0,0 +1,405 @@
+package handlers
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"goexcel/internal/auth"
+	"goexcel/internal/models"
+	"goexcel/internal/services"
+)
+
+const (
+	// maxProyectosLote limita los proyectos de una exportación por lotes
+	maxProyectosLote = 500
+	// maxTrabajadoresLote limita los proyectos que se generan a la vez; cada uno mantiene sus documentos en memoria
+	// hasta que se escriben en el ZIP
+	maxTrabajadoresLote = 4
+	// tiempoMaximoLote es el plazo para enviar el ZIP completo
+	tiempoMaximoLote = 30 * time.Minute
+)
+
+// resultadoLote es un proyecto ya generado, listo para agregarse al ZIP
+type resultadoLote struct {
+	indice     int
+	proyecto   models.ProyectoLote
+	documentos []services.Documento // documento de cada archivo de proyecto.Archivos
+}
+
+// ExportBatch genera los formatos pedidos de varios proyectos y los envía en un ZIP con un manifest.json.
+// Los proyectos se generan en paralelo con un número acotado de trabajadores y el ZIP se escribe a medida
+// que terminan; un proyecto que falla se informa en el manifiesto sin detener el lote. Las opciones de
+// presentación se leen de la query, igual que en la exportación individual.
+func (h *ProyectoHandler) ExportBatch(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if user == nil {
+		http.Error(w, "Usuario no autenticado", http.StatusUnauthorized)
+		return
+	}
+
+	var solicitud models.SolicitudExportacionLote
+	if err := json.NewDecoder(r.Body).Decode(&solicitud); err != nil {
+		http.Error(w, fmt.Sprintf("JSON inválido: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	formatos, err := h.validarFormatosLote(solicitud.Formatos)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	opciones, err := parseOpcionesExportacion(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ids, status, err := h.proyectosLote(solicitud, user)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	log.Printf("📤 Exportación por lotes: %d proyectos en %s", len(ids), strings.Join(formatos, ", "))
+
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(tiempoMaximoLote)); err != nil {
+		log.Printf("⚠️ No se pudo extender el plazo de escritura: %v", err)
+	}
+
+	ahora := time.Now()
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=exportacion_%s.zip", ahora.Format("20060102_150405")))
+
+	archivo := zip.NewWriter(w)
+	manifiesto := models.ManifiestoLote{
+		Generado:  ahora,
+		Formatos:  formatos,
+		Proyectos: make([]models.ProyectoLote, len(ids)),
+	}
+	for i, id := range ids {
+		manifiesto.Proyectos[i] = models.ProyectoLote{ID: id.String(), Archivos: []models.ArchivoLote{}, Errores: []string{"no generado"}}
+	}
+
+	// Si falla la escritura (el cliente se desconectó) se dejan de generar proyectos, pero se vacía
+	// el canal de resultados para que los trabajadores terminen
+	var errEscritura error
+	for resultado := range h.generarLote(r, ids, formatos, opciones, user) {
+		for i, doc := range resultado.documentos {
+			if errEscritura == nil {
+				errEscritura = escribirDocumentoZIP(archivo, &resultado.proyecto, i, doc, ahora)
+			}
+			doc.Close()
+		}
+
+		manifiesto.Proyectos[resultado.indice] = resultado.proyecto
+		if len(resultado.proyecto.Errores) > 0 {
+			manifiesto.Fallidos++
+		} else {
+			manifiesto.Exitosos++
+		}
+	}
+	if errEscritura != nil {
+		log.Printf("❌ Exportación por lotes interrumpida: %v", errEscritura)
+		return
+	}
+
+	contenido, _ := json.MarshalIndent(manifiesto, "", "  ")
+	if err := escribirEntradaZIP(archivo, "manifest.json", contenido, ahora); err != nil {
+		log.Printf("❌ Error escribiendo manifiesto: %v", err)
+		return
+	}
+	if err := archivo.Close(); err != nil {
+		log.Printf("❌ Error cerrando ZIP: %v", err)
+		return
+	}
+
+	log.Printf("✅ Exportación por lotes enviada: %d proyectos exitosos, %d con errores", manifiesto.Exitosos, manifiesto.Fallidos)
+}
+
+// validarFormatosLote comprueba que los formatos tengan renderizador y descarta los que generan el
+// mismo archivo (excel y xlsx)
+func (h *ProyectoHandler) validarFormatosLote(formatos []string) ([]string, error) {
+	if len(formatos) == 0 {
+		return nil, fmt.Errorf("indique al menos un formato")
+	}
+
+	var validos []string
+	extensiones := make(map[string]bool)
+	for _, formato := range formatos {
+		renderer, existe := h.renderers[formato]
+		if !existe {
+			return nil, fmt.Errorf("formato no soportado: %s", formato)
+		}
+		if extensiones[renderer.Extension()] {
+			continue
+		}
+		extensiones[renderer.Extension()] = true
+		validos = append(validos, formato)
+	}
+	return validos, nil
+}
+
+// proyectosLote resuelve los IDs a exportar. Un filtro de un usuario que no es admin se limita a su
+// organización o, si no tiene, a sus propios proyectos; los IDs explícitos se autorizan uno por uno.
+func (h *ProyectoHandler) proyectosLote(solicitud models.SolicitudExportacionLote, user *models.Usuario) ([]uuid.UUID, int, error) {
+	if (len(solicitud.ProyectoIDs) > 0) == (solicitud.Filtro != nil) {
+		return nil, http.StatusBadRequest, fmt.Errorf("indique project_ids o filtro, pero no ambos")
+	}
+
+	if len(solicitud.ProyectoIDs) > 0 {
+		if len(solicitud.ProyectoIDs) > maxProyectosLote {
+			return nil, http.StatusBadRequest, fmt.Errorf("se pueden exportar hasta %d proyectos por lote", maxProyectosLote)
+		}
+		return sinRepetir(solicitud.ProyectoIDs), http.StatusOK, nil
+	}
+
+	filtro := *solicitud.Filtro
+	if user.Rol != "admin" {
+		if user.OrganizacionID != nil {
+			if filtro.OrganizacionID != nil && *filtro.OrganizacionID != *user.OrganizacionID {
+				return nil, http.StatusForbidden, fmt.Errorf("no tiene permisos sobre esta organización")
+			}
+			filtro.OrganizacionID = user.OrganizacionID
+		} else {
+			filtro.UsuarioID = &user.ID
+		}
+	}
+
+	// Se pide uno más que el límite para rechazar el filtro en lugar de recortar el lote sin avisar
+	proyectos, err := h.proyectoRepo.Buscar(filtro, maxProyectosLote+1)
+	if err != nil {
+		log.Printf("❌ Error buscando proyectos: %v", err)
+		return nil, http.StatusInternalServerError, fmt.Errorf("error buscando proyectos")
+	}
+	if len(proyectos) == 0 {
+		return nil, http.StatusNotFound, fmt.Errorf("ningún proyecto cumple el filtro")
+	}
+	if len(proyectos) > maxProyectosLote {
+		return nil, http.StatusBadRequest, fmt.Errorf("el filtro selecciona más de %d proyectos; acótelo por fechas u organización", maxProyectosLote)
+	}
+
+	ids := make([]uuid.UUID, len(proyectos))
+	for i, proyecto := range proyectos {
+		ids[i] = proyecto.ID
+	}
+	return ids, http.StatusOK, nil
+}
+
+// sinRepetir devuelve los IDs sin duplicados, en el orden en que aparecen por primera vez, para que
+// cada proyecto se genere y se agregue al ZIP una sola vez
+func sinRepetir(ids []uuid.UUID) []uuid.UUID {
+	vistos := make(map[uuid.UUID]bool, len(ids))
+	unicos := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		if !vistos[id] {
+			vistos[id] = true
+			unicos = append(unicos, id)
+		}
+	}
+	return unicos
+}
+
+// generarLote reparte los proyectos entre los trabajadores y devuelve sus resultados a medida que
+// terminan; el canal se cierra al terminar todos o al cancelarse la solicitud
+func (h *ProyectoHandler) generarLote(r *http.Request, ids []uuid.UUID, formatos []string, opciones models.OpcionesExportacion, user *models.Usuario) <-chan resultadoLote {
+	trabajos := make(chan int)
+	resultados := make(chan resultadoLote)
+	plantillas := &plantillasLote{svc: h.plantillaSvc, porOrganizacion: make(map[uuid.UUID]*models.PlantillaExcel)}
+
+	trabajadores := min(maxTrabajadoresLote, runtime.NumCPU(), len(ids))
+	var wg sync.WaitGroup
+	for t := 0; t < trabajadores; t++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for indice := range trabajos {
+				resultado := h.generarProyectoLote(ids[indice], formatos, opciones, plantillas, user)
+				resultado.indice = indice
+				resultados <- resultado
+			}
+		}()
+	}
+
+	go func() {
+	repartir:
+		for indice := range ids {
+			select {
+			case trabajos <- indice:
+			case <-r.Context().Done():
+				break repartir
+			}
+		}
+		close(trabajos)
+		wg.Wait()
+		close(resultados)
+	}()
+
+	return resultados
+}
+
+// generarProyectoLote calcula el reporte del proyecto una vez y lo genera en cada formato
+func (h *ProyectoHandler) generarProyectoLote(id uuid.UUID, formatos []string, opciones models.OpcionesExportacion, plantillas *plantillasLote, user *models.Usuario) resultadoLote {
+	resultado := resultadoLote{proyecto: models.ProyectoLote{ID: id.String(), Archivos: []models.ArchivoLote{}}}
+	fallar := func(err error) resultadoLote {
+		log.Printf("⚠️ Proyecto %s no exportado: %v", id, err)
+		resultado.proyecto.Errores = append(resultado.proyecto.Errores, err.Error())
+		return resultado
+	}
+
+	proyecto, err := h.proyectoRepo.GetByID(id)
+	if err != nil {
+		return fallar(fmt.Errorf("proyecto no encontrado"))
+	}
+	resultado.proyecto.Nombre = proyecto.Nombre
+	if !puedeGestionarProyecto(user, proyecto) {
+		return fallar(fmt.Errorf("sin permisos sobre el proyecto"))
+	}
+
+	opciones.Proyecto = proyecto.Nombre
+	opciones.Plantilla = plantillas.obtener(proyecto.OrganizacionID)
+	opciones.Parametros = h.parametrosProyecto(id)
+
+	reporte, err := h.cargarReporte(proyecto, id.String(), opciones)
+	if err != nil {
+		return fallar(fmt.Errorf("error obteniendo datos: %v", err))
+	}
+	resultado.proyecto.CostoDirecto = reporte.Pie.CostoDirecto
+	resultado.proyecto.Total = reporte.Pie.Total
+
+	// Cada proyecto en su carpeta; el sufijo del ID evita choques entre proyectos con el mismo nombre
+	nombre := nombreArchivoLote(proyecto.Nombre)
+	carpeta := fmt.Sprintf("%s_%s", nombre, id.String()[:8])
+	for _, formato := range formatos {
+		renderer := h.renderers[formato]
+		doc, err := renderer.Renderizar(reporte)
+		if err != nil {
+			fallar(fmt.Errorf("%s: %v", formato, err))
+			continue
+		}
+
+		// Bytes y SHA256 se completan al escribir el documento en el ZIP
+		resultado.proyecto.Archivos = append(resultado.proyecto.Archivos, models.ArchivoLote{
+			Ruta:    carpeta + "/" + nombre + renderer.Extension(),
+			Formato: formato,
+		})
+		resultado.documentos = append(resultado.documentos, doc)
+	}
+
+	return resultado
+}
+
+// puedeGestionarProyecto permite al admin exportar o configurar cualquier proyecto y a los demás usuarios
+// los de su organización o los propios
+func puedeGestionarProyecto(user *models.Usuario, proyecto *models.Proyecto) bool {
+	if user.Rol == "admin" {
+		return true
+	}
+	if user.OrganizacionID != nil && proyecto.OrganizacionID != nil && *user.OrganizacionID == *proyecto.OrganizacionID {
+		return true
+	}
+	return proyecto.UsuarioID != nil && *proyecto.UsuarioID == user.ID
+}
+
+// plantillasLote carga la plantilla de cada organización una sola vez por lote, porque puede
+// requerir descargar el logo
+type plantillasLote struct {
+	svc             *services.PlantillaService
+	mu              sync.Mutex
+	porOrganizacion map[uuid.UUID]*models.PlantillaExcel
+}
+
+func (p *plantillasLote) obtener(organizacionID *uuid.UUID) *models.PlantillaExcel {
+	if organizacionID == nil {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if plantilla, existe := p.porOrganizacion[*organizacionID]; existe {
+		return plantilla
+	}
+
+	plantilla, err := p.svc.ObtenerParaExportar(organizacionID)
+	if err != nil {
+		log.Printf("⚠️ No se pudo cargar la plantilla de la organización %s: %v", organizacionID, err)
+	}
+	p.porOrganizacion[*organizacionID] = plantilla
+	return plantilla
+}
+
+// escribirEntradaZIP agrega un archivo al ZIP con la fecha de la exportación
+func escribirEntradaZIP(archivo *zip.Writer, ruta string, datos []byte, fecha time.Time) error {
+	entrada, err := archivo.CreateHeader(&zip.FileHeader{Name: ruta, Method: zip.Deflate, Modified: fecha})
+	if err != nil {
+		return fmt.Errorf("error agregando %s: %v", ruta, err)
+	}
+	if _, err := entrada.Write(datos); err != nil {
+		return fmt.Errorf("error escribiendo %s: %v", ruta, err)
+	}
+	return nil
+}
+
+// escribirDocumentoZIP escribe el documento directamente en su entrada del ZIP y completa en el manifiesto
+// su tamaño y huella. Un error del documento se informa en el proyecto (la entrada queda incompleta); solo
+// un error al escribir en la respuesta se devuelve, porque interrumpe el lote.
+func escribirDocumentoZIP(archivo *zip.Writer, proyecto *models.ProyectoLote, i int, doc services.Documento, fecha time.Time) error {
+	info := &proyecto.Archivos[i]
+	entrada, err := archivo.CreateHeader(&zip.FileHeader{Name: info.Ruta, Method: zip.Deflate, Modified: fecha})
+	if err != nil {
+		return fmt.Errorf("error agregando %s: %v", info.Ruta, err)
+	}
+
+	destino := &escrituraZIP{w: entrada}
+	huella := sha256.New()
+	escritos, err := doc.WriteTo(io.MultiWriter(destino, huella))
+	if destino.err != nil {
+		return fmt.Errorf("error escribiendo %s: %v", info.Ruta, destino.err)
+	}
+	if err != nil {
+		log.Printf("⚠️ Proyecto %s: %s incompleto: %v", proyecto.ID, info.Ruta, err)
+		proyecto.Errores = append(proyecto.Errores, fmt.Sprintf("%s: archivo incompleto: %v", info.Formato, err))
+	}
+
+	info.Bytes = escritos
+	info.SHA256 = hex.EncodeToString(huella.Sum(nil))
+	return nil
+}
+
+// escrituraZIP guarda el error de la entrada del ZIP para distinguirlo de un error del documento
+type escrituraZIP struct {
+	w   io.Writer
+	err error
+}
+
+func (e *escrituraZIP) Write(p []byte) (int, error) {
+	n, err := e.w.Write(p)
+	if err != nil && e.err == nil {
+		e.err = err
+	}
+	return n, err
+}
+
+// nombreArchivoLote quita del nombre del proyecto los caracteres no válidos en rutas de Windows y del ZIP
+func nombreArchivoLote(nombre string) string {
+	limpio := strings.Map(func(c rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, c) || c < ' ' {
+			return '_'
+		}
+		return c
+	}, strings.TrimSpace(nombre))
+	if limpio == "" {
+		return "proyecto"
+	}
+	return limpio
+}
@@ -0,0 +1,279 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"goexcel/internal/database/repositories"
+	"goexcel/internal/models"
+	"goexcel/internal/services"
+)
+
+// FleteHandler maneja las rutas de flete, los pesos unitarios de materiales y el flete de cada
+// proyecto. Bajo /organizations/{organizacion_id} se gestionan los de la organización; bajo /admin,
+// los globales.
+type FleteHandler struct {
+	fleteSvc     *services.FleteService
+	proyectoRepo *repositories.ProyectoRepository
+}
+
+// NewFleteHandler crea una nueva instancia del handler de flete
+func NewFleteHandler(fleteSvc *services.FleteService, proyectoRepo *repositories.ProyectoRepository) *FleteHandler {
+	return &FleteHandler{
+		fleteSvc:     fleteSvc,
+		proyectoRepo: proyectoRepo,
+	}
+}
+
+// ListarRutasFlete devuelve las rutas de la organización junto con las globales
+func (h *FleteHandler) ListarRutasFlete(w http.ResponseWriter, r *http.Request) {
+	organizacionID, ok := organizacionOGlobal(w, r)
+	if !ok {
+		return
+	}
+
+	rutas, err := h.fleteSvc.ListarRutas(organizacionID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error obteniendo rutas de flete: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.RutasFleteResponse{
+		Success: true,
+		Data:    rutas,
+	})
+}
+
+// ObtenerRutaFlete devuelve una ruta de la organización o global
+func (h *FleteHandler) ObtenerRutaFlete(w http.ResponseWriter, r *http.Request) {
+	organizacionID, id, ok := rutaFleteDeRuta(w, r)
+	if !ok {
+		return
+	}
+
+	ruta, err := h.fleteSvc.ObtenerRuta(id, organizacionID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	responderRutaFlete(w, http.StatusOK, models.RutaFleteResponse{Success: true, Data: ruta})
+}
+
+// CrearRutaFlete registra una ruta de flete
+func (h *FleteHandler) CrearRutaFlete(w http.ResponseWriter, r *http.Request) {
+	organizacionID, ok := organizacionOGlobal(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.RutaFleteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Error decodificando JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	ruta, err := h.fleteSvc.CrearRuta(req, organizacionID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	log.Printf("✅ Ruta de flete %s registrada: %s km", ruta.Nombre, ruta.DistanciaKm)
+	responderRutaFlete(w, http.StatusCreated, models.RutaFleteResponse{
+		Success: true,
+		Message: "Ruta de flete registrada exitosamente",
+		Data:    ruta,
+	})
+}
+
+// ActualizarRutaFlete reemplaza los datos de una ruta
+func (h *FleteHandler) ActualizarRutaFlete(w http.ResponseWriter, r *http.Request) {
+	organizacionID, id, ok := rutaFleteDeRuta(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.RutaFleteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Error decodificando JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	ruta, err := h.fleteSvc.ActualizarRuta(id, organizacionID, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	responderRutaFlete(w, http.StatusOK, models.RutaFleteResponse{
+		Success: true,
+		Message: "Ruta de flete actualizada exitosamente",
+		Data:    ruta,
+	})
+}
+
+// EliminarRutaFlete borra una ruta; los proyectos que la usaban quedan sin flete
+func (h *FleteHandler) EliminarRutaFlete(w http.ResponseWriter, r *http.Request) {
+	organizacionID, id, ok := rutaFleteDeRuta(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.fleteSvc.EliminarRuta(id, organizacionID); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	responderRutaFlete(w, http.StatusOK, models.RutaFleteResponse{
+		Success: true,
+		Message: "Ruta de flete eliminada exitosamente",
+	})
+}
+
+// ListarPesosUnitarios devuelve los pesos unitarios de la organización junto con los globales
+func (h *FleteHandler) ListarPesosUnitarios(w http.ResponseWriter, r *http.Request) {
+	organizacionID, ok := organizacionOGlobal(w, r)
+	if !ok {
+		return
+	}
+
+	pesos, err := h.fleteSvc.ListarPesos(organizacionID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error obteniendo pesos unitarios: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.PesosUnitariosResponse{
+		Success: true,
+		Data:    pesos,
+	})
+}
+
+// GuardarPesosUnitarios agrega o reemplaza pesos unitarios; los materiales que no vienen no cambian
+func (h *FleteHandler) GuardarPesosUnitarios(w http.ResponseWriter, r *http.Request) {
+	organizacionID, ok := organizacionOGlobal(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.GuardarPesosRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Error decodificando JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	pesos, err := h.fleteSvc.GuardarPesos(organizacionID, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	log.Printf("✅ %d pesos unitarios guardados", len(req.Pesos))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.PesosUnitariosResponse{
+		Success: true,
+		Message: "Pesos unitarios guardados exitosamente",
+		Data:    pesos,
+	})
+}
+
+// EliminarPesoUnitario borra el peso de un material
+func (h *FleteHandler) EliminarPesoUnitario(w http.ResponseWriter, r *http.Request) {
+	organizacionID, ok := organizacionOGlobal(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.fleteSvc.EliminarPeso(organizacionID, mux.Vars(r)["codigo"]); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.PesosUnitariosResponse{
+		Success: true,
+		Message: "Peso unitario eliminado exitosamente",
+	})
+}
+
+// ObtenerFleteProyecto devuelve la ruta y el modo de flete del proyecto
+func (h *FleteHandler) ObtenerFleteProyecto(w http.ResponseWriter, r *http.Request) {
+	proyectoID, ok := autorizarProyecto(w, r, h.proyectoRepo)
+	if !ok {
+		return
+	}
+
+	flete, err := h.fleteSvc.FleteDeProyecto(proyectoID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error obteniendo flete: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	respuesta := models.FleteProyectoResponse{Success: true, Data: flete}
+	if flete.Ruta == nil {
+		respuesta.Message = "El proyecto no tiene una ruta de flete asignada"
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(respuesta)
+}
+
+// AsignarFleteProyecto asigna al proyecto una ruta de su organización o global, o la quita con null,
+// y el modo de aplicar el flete. Se aplica al exportar el presupuesto; los precios guardados no cambian.
+func (h *FleteHandler) AsignarFleteProyecto(w http.ResponseWriter, r *http.Request) {
+	proyectoID, ok := autorizarProyecto(w, r, h.proyectoRepo)
+	if !ok {
+		return
+	}
+
+	var req models.AsignarFleteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Error decodificando JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	flete, err := h.fleteSvc.AsignarAProyecto(proyectoID, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	mensaje := "Ruta de flete quitada del proyecto"
+	if flete.Ruta != nil {
+		mensaje = "Ruta de flete asignada exitosamente"
+		log.Printf("✅ Ruta de flete %s asignada al proyecto %s (modo %s)", flete.Ruta.Nombre, proyectoID, flete.Modo)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.FleteProyectoResponse{
+		Success: true,
+		Message: mensaje,
+		Data:    flete,
+	})
+}
+
+// rutaFleteDeRuta valida la organización (nil bajo /admin) y el ID de la ruta de flete de la URL
+func rutaFleteDeRuta(w http.ResponseWriter, r *http.Request) (*uuid.UUID, uuid.UUID, bool) {
+	organizacionID, ok := organizacionOGlobal(w, r)
+	if !ok {
+		return nil, uuid.Nil, false
+	}
+
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "ID de ruta de flete inválido", http.StatusBadRequest)
+		return nil, uuid.Nil, false
+	}
+	return organizacionID, id, true
+}
+
+func responderRutaFlete(w http.ResponseWriter, status int, respuesta models.RutaFleteResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(respuesta)
+}
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+	"goexcel/internal/auth"
+	"goexcel/internal/models"
+)
+
+// maxTamanoImportacion limita el tamaño del libro de APU/Presupuesto subido
+const maxTamanoImportacion = 32 << 20 // 32 MB
+
+// PreviewImport lee un libro .xlsx de APU/Presupuesto y devuelve lo detectado sin guardarlo,
+// para que el usuario corrija partidas, títulos y metrados antes de confirmar
+func (h *ProyectoHandler) PreviewImport(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if user == nil {
+		http.Error(w, "Usuario no autenticado", http.StatusUnauthorized)
+		return
+	}
+
+	if err := r.ParseMultipartForm(maxTamanoImportacion); err != nil {
+		http.Error(w, fmt.Sprintf("Error leyendo formulario: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	archivo, cabecera, err := r.FormFile("archivo")
+	if err != nil {
+		http.Error(w, "Debe adjuntar el libro en el campo 'archivo'", http.StatusBadRequest)
+		return
+	}
+	defer archivo.Close()
+
+	opciones := models.OpcionesImportacion{Hoja: r.FormValue("hoja")}
+	for campo, destino := range map[string]**models.MapeoColumnas{
+		"mapeo_apu":         &opciones.MapeoAPU,
+		"mapeo_presupuesto": &opciones.MapeoPresupuesto,
+	} {
+		if valor := r.FormValue(campo); valor != "" {
+			if err := json.Unmarshal([]byte(valor), destino); err != nil {
+				http.Error(w, fmt.Sprintf("Error en %s: %v", campo, err), http.StatusBadRequest)
+				return
+			}
+		}
+	}
+
+	// La cantidad deducida de la cuadrilla usa la jornada y los decimales de la organización
+	parametros, err := h.parametrosSvc.EfectivosDeOrganizacion(user.OrganizacionID)
+	if err != nil {
+		log.Printf("⚠️ No se pudieron cargar los parámetros de la organización: %v", err)
+	}
+	opciones.Parametros = &parametros
+
+	log.Printf("📥 Previsualizando importación de %s", cabecera.Filename)
+
+	vista, err := h.importacionSvc.Importar(archivo, opciones)
+	if err != nil {
+		log.Printf("❌ Error importando %s: %v", cabecera.Filename, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	log.Printf("📊 Importación de %s: %d partidas, %d títulos, %d metrados, %d advertencias",
+		cabecera.Filename, len(vista.Partidas), len(vista.Titulos), len(vista.Metrados), len(vista.Advertencias))
+
+	response := map[string]interface{}{
+		"success": true,
+		"archivo": cabecera.Filename,
+		"data":    vista,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// ConfirmImport guarda como proyecto la vista previa corregida por el usuario: crea las partidas
+// como CreateProject y luego aplica las descripciones de títulos y los metrados leídos del libro
+func (h *ProyectoHandler) ConfirmImport(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if user == nil {
+		http.Error(w, "Usuario no autenticado", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.ConfirmarImportacionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Error parsing JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if req.Proyecto.Nombre == "" {
+		http.Error(w, "Nombre del proyecto es requerido", http.StatusBadRequest)
+		return
+	}
+	if len(req.Partidas) == 0 {
+		http.Error(w, "Al menos una partida es requerida", http.StatusBadRequest)
+		return
+	}
+	if err := validarPartidasImportadas(req.Partidas); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Proyecto.Parametros != nil {
+		if err := req.Proyecto.Parametros.Validar(); err != nil {
+			http.Error(w, fmt.Sprintf("Parámetros inválidos: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	partidasLegacy := h.convertToLegacyFormat(req.Partidas)
+	normalizedData, err := h.guardarProyecto(req.Proyecto, partidasLegacy, user)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	proyectoID := normalizedData.Proyecto.ID
+
+	// Los títulos se generan desde los códigos de partida; aquí toman la descripción del libro
+	if len(req.Titulos) > 0 {
+		titulos := make(map[string]string, len(req.Titulos))
+		for _, titulo := range req.Titulos {
+			if titulo.Codigo != "" && titulo.Descripcion != "" {
+				titulos[titulo.Codigo] = titulo.Descripcion
+			}
+		}
+		if err := h.hierarchySvc.ActualizarTitulosPersonalizados(proyectoID, titulos); err != nil {
+			log.Printf("⚠️ Proyecto %s creado, pero no se aplicaron los títulos importados: %v", proyectoID, err)
+		}
+	}
+
+	if len(req.Metrados) > 0 {
+		proyectoUUID, err := uuid.Parse(proyectoID)
+		if err == nil {
+			err = h.metradoRepo.ActualizarMetrados(proyectoUUID, req.Metrados)
+		}
+		if err != nil {
+			log.Printf("⚠️ Proyecto %s creado, pero no se guardaron los metrados importados: %v", proyectoID, err)
+		}
+	}
+
+	response := nuevaCreateProjectResponse(normalizedData, "Proyecto importado exitosamente")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// validarPartidasImportadas rechaza lo que la normalización no puede guardar, para que el usuario
+// lo corrija en la vista previa en lugar de obtener un error de base de datos
+func validarPartidasImportadas(partidas []models.PartidaRequest) error {
+	codigos := make(map[string]bool, len(partidas))
+	for i, partida := range partidas {
+		if partida.Codigo == "" || partida.Descripcion == "" {
+			return fmt.Errorf("la partida %d no tiene código o descripción", i+1)
+		}
+		if codigos[partida.Codigo] {
+			return fmt.Errorf("la partida %s está repetida", partida.Codigo)
+		}
+		codigos[partida.Codigo] = true
+
+		for _, grupo := range [][]models.RecursoRequest{partida.ManoObra, partida.Materiales, partida.Equipos, partida.Subcontratos} {
+			for _, recurso := range grupo {
+				if recurso.Codigo == "" || recurso.Descripcion == "" {
+					return fmt.Errorf("la partida %s tiene un recurso sin código o descripción", partida.Codigo)
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"goexcel/internal/database/repositories"
+	"goexcel/internal/models"
+	"goexcel/internal/services"
+)
+
+// InsumosHandler maneja las peticiones HTTP de la relación de insumos y la fórmula polinómica
+type InsumosHandler struct {
+	insumosSvc      *services.InsumosService
+	formulaSvc      *services.FormulaPolinomicaService
+	recursoRepo     *repositories.RecursoRepository
+	proyectoRepo    *repositories.ProyectoRepository
+	presupuestoRepo *repositories.PresupuestoRepository
+}
+
+// NewInsumosHandler crea una nueva instancia del handler de insumos
+func NewInsumosHandler(insumosSvc *services.InsumosService, formulaSvc *services.FormulaPolinomicaService, recursoRepo *repositories.RecursoRepository, proyectoRepo *repositories.ProyectoRepository, presupuestoRepo *repositories.PresupuestoRepository) *InsumosHandler {
+	return &InsumosHandler{
+		insumosSvc:      insumosSvc,
+		formulaSvc:      formulaSvc,
+		recursoRepo:     recursoRepo,
+		proyectoRepo:    proyectoRepo,
+		presupuestoRepo: presupuestoRepo,
+	}
+}
+
+// ObtenerInsumosProyecto obtiene la relación de insumos consolidada de un proyecto
+func (h *InsumosHandler) ObtenerInsumosProyecto(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	proyectoIDStr := vars["proyecto_id"]
+
+	proyectoID, err := uuid.Parse(proyectoIDStr)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ID de proyecto inválido: %v", err), http.StatusBadRequest)
+		return
+	}
+	if !h.autorizarLecturaProyecto(w, r, proyectoID) {
+		return
+	}
+
+	relacion, err := h.insumosSvc.ObtenerRelacionPorProyecto(proyectoID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error obteniendo insumos: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	h.responderRelacion(w, r, relacion, proyectoIDStr)
+}
+
+// ObtenerInsumosPresupuesto obtiene la relación de insumos consolidada de un presupuesto jerárquico
+func (h *InsumosHandler) ObtenerInsumosPresupuesto(w http.ResponseWriter, r *http.Request) {
+	presupuestoID, ok := autorizarPresupuesto(w, r, h.presupuestoRepo)
+	if !ok {
+		return
+	}
+
+	relacion, ok := h.relacionDePresupuesto(w, presupuestoID)
+	if !ok {
+		return
+	}
+
+	h.responderRelacion(w, r, relacion, presupuestoID.String())
+}
+
+// responderRelacion envía la relación en JSON o como CSV si se solicita format=csv
+func (h *InsumosHandler) responderRelacion(w http.ResponseWriter, r *http.Request, relacion *models.RelacionInsumos, id string) {
+	if r.URL.Query().Get("format") == "csv" {
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=insumos_%s.csv", id))
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		if err := h.insumosSvc.EscribirCSV(w, relacion); err != nil {
+			http.Error(w, fmt.Sprintf("Error generando CSV: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	message := "Relación de insumos obtenida exitosamente"
+	if !relacion.Conciliado {
+		message = "Relación de insumos no concilia con Σ metrado × costo unitario de las partidas"
+	}
+
+	response := models.RelacionInsumosResponse{
+		Success: true,
+		Message: message,
+		Data:    relacion,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// ObtenerFormulaProyecto calcula la fórmula polinómica de reajuste de un proyecto
+func (h *InsumosHandler) ObtenerFormulaProyecto(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	proyectoID, err := uuid.Parse(vars["proyecto_id"])
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ID de proyecto inválido: %v", err), http.StatusBadRequest)
+		return
+	}
+	if !h.autorizarLecturaProyecto(w, r, proyectoID) {
+		return
+	}
+
+	relacion, err := h.insumosSvc.ObtenerRelacionPorProyecto(proyectoID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error obteniendo insumos: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	h.responderFormula(w, relacion)
+}
+
+// ObtenerFormulaPresupuesto calcula la fórmula polinómica de reajuste de un presupuesto jerárquico
+func (h *InsumosHandler) ObtenerFormulaPresupuesto(w http.ResponseWriter, r *http.Request) {
+	presupuestoID, ok := autorizarPresupuesto(w, r, h.presupuestoRepo)
+	if !ok {
+		return
+	}
+
+	relacion, ok := h.relacionDePresupuesto(w, presupuestoID)
+	if !ok {
+		return
+	}
+
+	h.responderFormula(w, relacion)
+}
+
+// autorizarLecturaProyecto carga el proyecto y verifica que el usuario pueda verlo: la relación muestra
+// todos sus recursos y precios
+func (h *InsumosHandler) autorizarLecturaProyecto(w http.ResponseWriter, r *http.Request, proyectoID uuid.UUID) bool {
+	proyecto, err := h.proyectoRepo.GetByID(proyectoID)
+	if err != nil {
+		http.Error(w, "Proyecto no encontrado", http.StatusNotFound)
+		return false
+	}
+	return autorizarLectura(w, r, proyecto)
+}
+
+// relacionDePresupuesto consolida los insumos del presupuesto jerárquico; sin metrados responde 422
+func (h *InsumosHandler) relacionDePresupuesto(w http.ResponseWriter, presupuestoID uuid.UUID) (*models.RelacionInsumos, bool) {
+	relacion, err := h.insumosSvc.ObtenerRelacionPorPresupuesto(presupuestoID)
+	if errors.Is(err, services.ErrSinMetrados) {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return nil, false
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error obteniendo insumos: %v", err), http.StatusInternalServerError)
+		return nil, false
+	}
+	return relacion, true
+}
+
+func (h *InsumosHandler) responderFormula(w http.ResponseWriter, relacion *models.RelacionInsumos) {
+	formula, err := h.formulaSvc.Calcular(relacion)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error calculando fórmula polinómica: %v", err), http.StatusUnprocessableEntity)
+		return
+	}
+
+	response := models.FormulaPolinomicaResponse{
+		Success: true,
+		Message: fmt.Sprintf("Fórmula polinómica calculada con %d monomios", len(formula.Monomios)),
+		Data:    formula,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// ActualizarIndicesUnificados asigna índices unificados a recursos por código
+func (h *InsumosHandler) ActualizarIndicesUnificados(w http.ResponseWriter, r *http.Request) {
+	var req models.IndicesUnificadosRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("JSON inválido: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if len(req.Asignaciones) == 0 {
+		http.Error(w, "Debe indicar al menos una asignación", http.StatusBadRequest)
+		return
+	}
+
+	for codigo, indice := range req.Asignaciones {
+		if indice == "" {
+			continue
+		}
+		if err := services.ValidarIndiceUnificado(indice); err != nil {
+			http.Error(w, fmt.Sprintf("Recurso %s: %v", codigo, err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	actualizados, err := h.recursoRepo.ActualizarIndicesUnificados(req.Asignaciones)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error actualizando índices: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":      true,
+		"message":      "Índices unificados actualizados exitosamente",
+		"actualizados": actualizados,
+	})
+}
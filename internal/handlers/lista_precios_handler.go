@@ -0,0 +1,337 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"goexcel/internal/database/repositories"
+	"goexcel/internal/models"
+	"goexcel/internal/services"
+)
+
+// maxTamanoPreciosCSV limita el tamaño del CSV de precios subido
+const maxTamanoPreciosCSV = 10 << 20 // 10 MB
+
+// ListaPreciosHandler maneja las listas de precios de las organizaciones, su asignación a proyectos
+// y el reprecio de los proyectos. Al cambiar precios de una lista se recalcula el costo horario de
+// los equipos que consumen esos recursos.
+type ListaPreciosHandler struct {
+	listaPreciosSvc *services.ListaPreciosService
+	equipoSvc       *services.EquipoService
+	proyectoRepo    *repositories.ProyectoRepository
+}
+
+// NewListaPreciosHandler crea una nueva instancia del handler de listas de precios
+func NewListaPreciosHandler(listaPreciosSvc *services.ListaPreciosService, equipoSvc *services.EquipoService, proyectoRepo *repositories.ProyectoRepository) *ListaPreciosHandler {
+	return &ListaPreciosHandler{
+		listaPreciosSvc: listaPreciosSvc,
+		equipoSvc:       equipoSvc,
+		proyectoRepo:    proyectoRepo,
+	}
+}
+
+// ListarListasPrecios devuelve las listas de la organización, sin sus precios
+func (h *ListaPreciosHandler) ListarListasPrecios(w http.ResponseWriter, r *http.Request) {
+	organizacionID, ok := autorizarOrganizacion(w, r)
+	if !ok {
+		return
+	}
+
+	listas, err := h.listaPreciosSvc.Listar(organizacionID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error obteniendo listas de precios: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.ListasPreciosResponse{
+		Success: true,
+		Data:    listas,
+	})
+}
+
+// ObtenerListaPrecios devuelve una lista con sus precios
+func (h *ListaPreciosHandler) ObtenerListaPrecios(w http.ResponseWriter, r *http.Request) {
+	organizacionID, listaID, ok := listaDeRuta(w, r)
+	if !ok {
+		return
+	}
+
+	lista, err := h.listaPreciosSvc.Obtener(listaID, organizacionID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	responderListaPrecios(w, http.StatusOK, models.ListaPreciosResponse{Success: true, Data: lista})
+}
+
+// CrearListaPrecios crea una lista con sus precios iniciales
+func (h *ListaPreciosHandler) CrearListaPrecios(w http.ResponseWriter, r *http.Request) {
+	organizacionID, ok := autorizarOrganizacion(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.ListaPreciosRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Error decodificando JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	lista, err := h.listaPreciosSvc.Crear(req, organizacionID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	log.Printf("✅ Lista de precios creada: %s (%s, %d precios)", lista.Nombre, lista.Region, lista.CantidadPrecios)
+	responderListaPrecios(w, http.StatusCreated, models.ListaPreciosResponse{
+		Success: true,
+		Message: "Lista de precios creada exitosamente",
+		Data:    lista,
+	})
+}
+
+// ActualizarListaPrecios modifica nombre, región y vigencia de una lista
+func (h *ListaPreciosHandler) ActualizarListaPrecios(w http.ResponseWriter, r *http.Request) {
+	organizacionID, listaID, ok := listaDeRuta(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.ListaPreciosRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Error decodificando JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	lista, err := h.listaPreciosSvc.Actualizar(listaID, organizacionID, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	responderListaPrecios(w, http.StatusOK, models.ListaPreciosResponse{
+		Success: true,
+		Message: "Lista de precios actualizada exitosamente",
+		Data:    lista,
+	})
+}
+
+// EliminarListaPrecios borra una lista; los proyectos que la tenían asignada quedan sin lista
+func (h *ListaPreciosHandler) EliminarListaPrecios(w http.ResponseWriter, r *http.Request) {
+	organizacionID, listaID, ok := listaDeRuta(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.listaPreciosSvc.Eliminar(listaID, organizacionID); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	responderListaPrecios(w, http.StatusOK, models.ListaPreciosResponse{
+		Success: true,
+		Message: "Lista de precios eliminada exitosamente",
+	})
+}
+
+// GuardarPreciosLista crea o reemplaza precios de la lista, identificando los recursos por código
+func (h *ListaPreciosHandler) GuardarPreciosLista(w http.ResponseWriter, r *http.Request) {
+	organizacionID, listaID, ok := listaDeRuta(w, r)
+	if !ok {
+		return
+	}
+
+	var precios []models.PrecioListaRequest
+	if err := json.NewDecoder(r.Body).Decode(&precios); err != nil {
+		http.Error(w, fmt.Sprintf("Error decodificando JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	lista, err := h.listaPreciosSvc.GuardarPrecios(listaID, organizacionID, precios)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	codigos := make([]string, 0, len(precios))
+	for _, precio := range precios {
+		codigos = append(codigos, precio.Codigo)
+	}
+	h.recalcularEquipos(organizacionID, lista, codigos)
+
+	responderListaPrecios(w, http.StatusOK, models.ListaPreciosResponse{
+		Success: true,
+		Message: "Precios guardados exitosamente",
+		Data:    lista,
+	})
+}
+
+// ImportarPreciosLista carga los precios de un CSV adjunto en el campo 'archivo'; si una línea es
+// inválida no se guarda ninguno
+func (h *ListaPreciosHandler) ImportarPreciosLista(w http.ResponseWriter, r *http.Request) {
+	organizacionID, listaID, ok := listaDeRuta(w, r)
+	if !ok {
+		return
+	}
+
+	if err := r.ParseMultipartForm(maxTamanoPreciosCSV); err != nil {
+		http.Error(w, fmt.Sprintf("Error leyendo formulario: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	archivo, _, err := r.FormFile("archivo")
+	if err != nil {
+		http.Error(w, "Debe adjuntar el CSV de precios en el campo 'archivo'", http.StatusBadRequest)
+		return
+	}
+	defer archivo.Close()
+
+	lista, importados, err := h.listaPreciosSvc.ImportarCSV(archivo, listaID, organizacionID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	log.Printf("📥 %d precios importados a la lista %s", importados, lista.Nombre)
+	codigos := make([]string, 0, len(lista.Precios))
+	for _, precio := range lista.Precios {
+		codigos = append(codigos, precio.Codigo)
+	}
+	h.recalcularEquipos(organizacionID, lista, codigos)
+	responderListaPrecios(w, http.StatusOK, models.ListaPreciosResponse{
+		Success:    true,
+		Message:    "Precios importados exitosamente",
+		Data:       lista,
+		Importados: importados,
+	})
+}
+
+// ObtenerListaPreciosProyecto devuelve la lista asignada al proyecto
+func (h *ListaPreciosHandler) ObtenerListaPreciosProyecto(w http.ResponseWriter, r *http.Request) {
+	proyectoID, ok := autorizarProyecto(w, r, h.proyectoRepo)
+	if !ok {
+		return
+	}
+
+	lista, err := h.listaPreciosSvc.ListaDeProyecto(proyectoID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error obteniendo lista de precios: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	respuesta := models.ListaPreciosResponse{Success: true, Data: lista}
+	if lista == nil {
+		respuesta.Message = "El proyecto no tiene una lista de precios asignada"
+	}
+	responderListaPrecios(w, http.StatusOK, respuesta)
+}
+
+// AsignarListaPreciosProyecto asigna al proyecto una lista de su organización, o la quita con null.
+// Los precios del proyecto no cambian hasta repreciarlo.
+func (h *ListaPreciosHandler) AsignarListaPreciosProyecto(w http.ResponseWriter, r *http.Request) {
+	proyectoID, ok := autorizarProyecto(w, r, h.proyectoRepo)
+	if !ok {
+		return
+	}
+
+	var req models.AsignarListaPreciosRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Error decodificando JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	lista, err := h.listaPreciosSvc.AsignarAProyecto(proyectoID, req.ListaPreciosID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	mensaje := "Lista de precios quitada del proyecto"
+	if lista != nil {
+		mensaje = "Lista de precios asignada exitosamente"
+		log.Printf("✅ Lista de precios %s asignada al proyecto %s", lista.Nombre, proyectoID)
+	}
+	responderListaPrecios(w, http.StatusOK, models.ListaPreciosResponse{
+		Success: true,
+		Message: mensaje,
+		Data:    lista,
+	})
+}
+
+// RepreciarProyecto actualiza los precios de los recursos del proyecto con su lista de precios y
+// devuelve el informe de cambios. Con ?simular=true solo devuelve el informe.
+func (h *ListaPreciosHandler) RepreciarProyecto(w http.ResponseWriter, r *http.Request) {
+	proyectoID, ok := autorizarProyecto(w, r, h.proyectoRepo)
+	if !ok {
+		return
+	}
+	simular := r.URL.Query().Get("simular") == "true"
+
+	informe, err := h.listaPreciosSvc.Repreciar(proyectoID, simular)
+	if err != nil {
+		log.Printf("❌ Error repreciando proyecto %s: %v", proyectoID, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	mensaje := "Proyecto repreciado exitosamente"
+	if simular {
+		mensaje = "Simulación de reprecio: no se guardaron cambios"
+	} else {
+		// Las exportaciones deben leer los precios nuevos de la BD, no el JSON importado
+		originalJSONStore.eliminar(proyectoID.String())
+		log.Printf("✅ Proyecto %s repreciado con %s: %d recursos en %d partidas, costo directo %s → %s",
+			proyectoID, informe.ListaPrecios.Nombre, informe.RecursosActualizados, informe.PartidasAfectadas,
+			informe.CostoDirectoAnterior, informe.CostoDirectoNuevo)
+	}
+	if !informe.ListaVigente {
+		mensaje += " (la lista de precios no está vigente hoy)"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.ReprecioResponse{
+		Success: true,
+		Message: mensaje,
+		Data:    informe,
+	})
+}
+
+// recalcularEquipos actualiza en la lista el costo horario de los equipos que consumen los recursos cuyo
+// precio cambió; si falla, los precios guardados se mantienen
+func (h *ListaPreciosHandler) recalcularEquipos(organizacionID uuid.UUID, lista *models.ListaPrecios, codigos []string) {
+	actualizados, err := h.equipoSvc.RecalcularDependientes(&organizacionID, &lista.ID, codigos)
+	if err != nil {
+		log.Printf("⚠️ No se pudo recalcular el costo horario de equipos: %v", err)
+		return
+	}
+	if actualizados > 0 {
+		log.Printf("✅ Costo horario de %d equipos recalculado en la lista %s", actualizados, lista.Nombre)
+	}
+}
+
+// listaDeRuta valida la organización y el ID de la lista de la ruta
+func listaDeRuta(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	organizacionID, ok := autorizarOrganizacion(w, r)
+	if !ok {
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	listaID, err := uuid.Parse(mux.Vars(r)["lista_id"])
+	if err != nil {
+		http.Error(w, "ID de lista de precios inválido", http.StatusBadRequest)
+		return uuid.Nil, uuid.Nil, false
+	}
+	return organizacionID, listaID, true
+}
+
+func responderListaPrecios(w http.ResponseWriter, status int, respuesta models.ListaPreciosResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(respuesta)
+}
@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"goexcel/internal/models"
+	"goexcel/internal/services"
+)
+
+// ManoObraHandler maneja los parámetros anuales de construcción civil y el cálculo del costo
+// hora-hombre. Bajo /organizations/{organizacion_id} se gestionan los de la organización; bajo /admin,
+// los globales.
+type ManoObraHandler struct {
+	manoObraSvc     *services.ManoObraService
+	listaPreciosSvc *services.ListaPreciosService
+	equipoSvc       *services.EquipoService
+}
+
+// NewManoObraHandler crea una nueva instancia del handler de mano de obra
+func NewManoObraHandler(manoObraSvc *services.ManoObraService, listaPreciosSvc *services.ListaPreciosService, equipoSvc *services.EquipoService) *ManoObraHandler {
+	return &ManoObraHandler{
+		manoObraSvc:     manoObraSvc,
+		listaPreciosSvc: listaPreciosSvc,
+		equipoSvc:       equipoSvc,
+	}
+}
+
+// ListarParametrosManoObra devuelve los parámetros de la organización junto con los globales
+func (h *ManoObraHandler) ListarParametrosManoObra(w http.ResponseWriter, r *http.Request) {
+	organizacionID, ok := organizacionOGlobal(w, r)
+	if !ok {
+		return
+	}
+
+	lista, err := h.manoObraSvc.Listar(organizacionID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error obteniendo parámetros de mano de obra: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.ParametrosManoObraListaResponse{
+		Success: true,
+		Data:    lista,
+	})
+}
+
+// ObtenerParametrosManoObra devuelve un juego de parámetros con el cálculo por categoría
+func (h *ManoObraHandler) ObtenerParametrosManoObra(w http.ResponseWriter, r *http.Request) {
+	organizacionID, id, ok := parametrosManoObraDeRuta(w, r)
+	if !ok {
+		return
+	}
+
+	p, err := h.manoObraSvc.Obtener(id, organizacionID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	responderParametrosManoObra(w, http.StatusOK, models.ParametrosManoObraResponse{
+		Success: true,
+		Data:    p,
+		Calculo: h.manoObraSvc.Calcular(p),
+	})
+}
+
+// CrearParametrosManoObra registra los parámetros de un año y devuelve su cálculo
+func (h *ManoObraHandler) CrearParametrosManoObra(w http.ResponseWriter, r *http.Request) {
+	organizacionID, ok := organizacionOGlobal(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.ParametrosManoObraRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Error decodificando JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	p, err := h.manoObraSvc.Crear(req, organizacionID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	log.Printf("✅ Parámetros de mano de obra %d registrados (%d categorías)", p.Anio, len(p.Categorias))
+	responderParametrosManoObra(w, http.StatusCreated, models.ParametrosManoObraResponse{
+		Success: true,
+		Message: "Parámetros de mano de obra registrados exitosamente",
+		Data:    p,
+		Calculo: h.manoObraSvc.Calcular(p),
+	})
+}
+
+// ActualizarParametrosManoObra reemplaza un juego de parámetros y devuelve su cálculo
+func (h *ManoObraHandler) ActualizarParametrosManoObra(w http.ResponseWriter, r *http.Request) {
+	organizacionID, id, ok := parametrosManoObraDeRuta(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.ParametrosManoObraRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Error decodificando JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	p, err := h.manoObraSvc.Actualizar(id, organizacionID, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	responderParametrosManoObra(w, http.StatusOK, models.ParametrosManoObraResponse{
+		Success: true,
+		Message: "Parámetros de mano de obra actualizados exitosamente",
+		Data:    p,
+		Calculo: h.manoObraSvc.Calcular(p),
+	})
+}
+
+// EliminarParametrosManoObra borra un juego de parámetros
+func (h *ManoObraHandler) EliminarParametrosManoObra(w http.ResponseWriter, r *http.Request) {
+	organizacionID, id, ok := parametrosManoObraDeRuta(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.manoObraSvc.Eliminar(id, organizacionID); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	responderParametrosManoObra(w, http.StatusOK, models.ParametrosManoObraResponse{
+		Success: true,
+		Message: "Parámetros de mano de obra eliminados exitosamente",
+	})
+}
+
+// AplicarCostoManoObra guarda el costo hora-hombre de cada categoría con código de recurso: en una
+// lista de precios de la organización o, bajo /admin, como precio base del catálogo de recursos
+func (h *ManoObraHandler) AplicarCostoManoObra(w http.ResponseWriter, r *http.Request) {
+	organizacionID, id, ok := parametrosManoObraDeRuta(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.AplicarManoObraRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("Error decodificando JSON: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	p, err := h.manoObraSvc.Obtener(id, organizacionID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	calculo := h.manoObraSvc.Calcular(p)
+	precios := h.manoObraSvc.PreciosHH(calculo)
+
+	var mensaje string
+	if organizacionID == nil {
+		if err := h.manoObraSvc.AplicarACatalogo(precios); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mensaje = "Costos hora-hombre aplicados al catálogo de recursos"
+	} else {
+		if req.ListaPreciosID == nil {
+			http.Error(w, "Debe indicar lista_precios_id", http.StatusBadRequest)
+			return
+		}
+		if len(precios) == 0 {
+			http.Error(w, "Ninguna categoría tiene código de recurso", http.StatusBadRequest)
+			return
+		}
+		lista, err := h.listaPreciosSvc.GuardarPrecios(*req.ListaPreciosID, *organizacionID, precios)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mensaje = fmt.Sprintf("Costos hora-hombre aplicados a la lista de precios %s", lista.Nombre)
+	}
+
+	log.Printf("✅ %s: %d precios del año %d", mensaje, len(precios), p.Anio)
+
+	// El costo horario de los equipos incluye al operador: se recalcula con el nuevo costo hora-hombre
+	codigos := make([]string, 0, len(precios))
+	for _, precio := range precios {
+		codigos = append(codigos, precio.Codigo)
+	}
+	if actualizados, err := h.equipoSvc.RecalcularDependientes(organizacionID, req.ListaPreciosID, codigos); err != nil {
+		log.Printf("⚠️ No se pudo recalcular el costo horario de equipos: %v", err)
+	} else if actualizados > 0 {
+		log.Printf("✅ Costo horario de %d equipos recalculado", actualizados)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.AplicarManoObraResponse{
+		Success: true,
+		Message: mensaje,
+		Calculo: calculo,
+		Precios: precios,
+	})
+}
+
+// parametrosManoObraDeRuta valida la organización (nil bajo /admin) y el ID de los parámetros de la ruta
+func parametrosManoObraDeRuta(w http.ResponseWriter, r *http.Request) (*uuid.UUID, uuid.UUID, bool) {
+	organizacionID, ok := organizacionOGlobal(w, r)
+	if !ok {
+		return nil, uuid.Nil, false
+	}
+
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "ID de parámetros de mano de obra inválido", http.StatusBadRequest)
+		return nil, uuid.Nil, false
+	}
+	return organizacionID, id, true
+}
+
+func responderParametrosManoObra(w http.ResponseWriter, status int, respuesta models.ParametrosManoObraResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(respuesta)
+}
@@ -3,23 +3,34 @@ package handlers
 import (
 	"encoding/json"
 	"fmt"
+	"log"
 	"net/http"
 
 	"github.com/gorilla/mux"
 	"github.com/google/uuid"
 	"goexcel/internal/database/repositories"
 	"goexcel/internal/models"
+	"goexcel/internal/services"
 )
 
+// maxTamanoPlanilla limita el tamaño de la planilla de metrados subida
+const maxTamanoPlanilla = 10 << 20 // 10 MB
+
 // MetradoHandler maneja las peticiones HTTP relacionadas con metrados
 type MetradoHandler struct {
-	metradoRepo *repositories.MetradoRepository
+	metradoRepo  *repositories.MetradoRepository
+	proyectoRepo *repositories.ProyectoRepository
+	planillaSvc  *services.PlanillaMetradosService
+	calculoSvc   *services.CalculoService
 }
 
 // NewMetradoHandler crea una nueva instancia del handler de metrados
-func NewMetradoHandler(metradoRepo *repositories.MetradoRepository) *MetradoHandler {
+func NewMetradoHandler(metradoRepo *repositories.MetradoRepository, proyectoRepo *repositories.ProyectoRepository, planillaSvc *services.PlanillaMetradosService, calculoSvc *services.CalculoService) *MetradoHandler {
 	return &MetradoHandler{
-		metradoRepo: metradoRepo,
+		metradoRepo:  metradoRepo,
+		proyectoRepo: proyectoRepo,
+		planillaSvc:  planillaSvc,
+		calculoSvc:   calculoSvc,
 	}
 }
 
@@ -205,7 +216,8 @@ func (h *MetradoHandler) EliminarMetrado(w http.ResponseWriter, r *http.Request)
 	json.NewEncoder(w).Encode(response)
 }
 
-// ObtenerResumenProyecto obtiene el resumen financiero de un proyecto
+// ObtenerResumenProyecto obtiene el resumen financiero de un proyecto; el costo directo se calcula en
+// Go como en las exportaciones, con los tipos de cambio y el flete del proyecto
 func (h *MetradoHandler) ObtenerResumenProyecto(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	proyectoIDStr := vars["proyecto_id"]
@@ -216,7 +228,7 @@ func (h *MetradoHandler) ObtenerResumenProyecto(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	resumen, err := h.metradoRepo.ObtenerResumenProyecto(proyectoID)
+	resumen, err := h.calculoSvc.ResumenDeProyecto(proyectoID)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Error obteniendo resumen: %v", err), http.StatusInternalServerError)
 		return
@@ -232,7 +244,7 @@ func (h *MetradoHandler) ObtenerResumenProyecto(w http.ResponseWriter, r *http.R
 	json.NewEncoder(w).Encode(response)
 }
 
-// CalcularCostoTotalProyecto calcula el costo total del proyecto
+// CalcularCostoTotalProyecto calcula el costo directo del proyecto igual que las exportaciones
 func (h *MetradoHandler) CalcularCostoTotalProyecto(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	proyectoIDStr := vars["proyecto_id"]
@@ -243,7 +255,7 @@ func (h *MetradoHandler) CalcularCostoTotalProyecto(w http.ResponseWriter, r *ht
 		return
 	}
 
-	costoTotal, err := h.metradoRepo.CalcularCostoTotalProyecto(proyectoID)
+	reporte, err := h.calculoSvc.ReporteDeProyecto(proyectoID)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Error calculando costo total: %v", err), http.StatusInternalServerError)
 		return
@@ -252,9 +264,92 @@ func (h *MetradoHandler) CalcularCostoTotalProyecto(w http.ResponseWriter, r *ht
 	response := map[string]interface{}{
 		"success":     true,
 		"message":     "Costo total calculado exitosamente",
-		"costo_total": costoTotal,
+		"costo_total": reporte.Pie.CostoDirecto,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// DescargarPlanilla genera la planilla de metrados del proyecto para llenarla fuera de línea
+func (h *MetradoHandler) DescargarPlanilla(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	proyectoIDStr := vars["proyecto_id"]
+
+	proyectoID, err := uuid.Parse(proyectoIDStr)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ID de proyecto inválido: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	proyecto, err := h.proyectoRepo.GetByID(proyectoID)
+	if err != nil {
+		http.Error(w, "Proyecto no encontrado", http.StatusNotFound)
+		return
+	}
+	if !autorizarLectura(w, r, proyecto) {
+		return
+	}
+
+	f, proyecto, err := h.planillaSvc.ConstruirPlanilla(proyectoID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error generando planilla de metrados: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	downloadName := "Metrados_" + proyecto.Nombre + ".xlsx"
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", downloadName))
+	w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+
+	if err := f.Write(w); err != nil {
+		log.Printf("❌ Error enviando planilla de metrados: %v", err)
+	}
+}
+
+// CargarPlanilla aplica los metrados de una planilla llenada e informa las filas rechazadas
+func (h *MetradoHandler) CargarPlanilla(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	proyectoIDStr := vars["proyecto_id"]
+
+	proyectoID, err := uuid.Parse(proyectoIDStr)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ID de proyecto inválido: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	// Antes de leer el archivo: la planilla reemplaza los metrados del proyecto
+	if !autorizarGestion(w, r, h.proyectoRepo, proyectoID) {
+		return
+	}
+
+	if err := r.ParseMultipartForm(maxTamanoPlanilla); err != nil {
+		http.Error(w, fmt.Sprintf("Error leyendo formulario: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	archivo, _, err := r.FormFile("archivo")
+	if err != nil {
+		http.Error(w, "Debe adjuntar la planilla en el campo 'archivo'", http.StatusBadRequest)
+		return
+	}
+	defer archivo.Close()
+
+	resultado, err := h.planillaSvc.AplicarPlanilla(archivo, proyectoID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error aplicando planilla de metrados: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	log.Printf("📊 Planilla de metrados de %s: %d aplicados, %d rechazados, %d desconocidos",
+		proyectoID, resultado.Aplicados, len(resultado.Rechazadas), len(resultado.Desconocidas))
+
+	response := map[string]interface{}{
+		"success": true,
+		"message": fmt.Sprintf("%d metrados aplicados", resultado.Aplicados),
+		"data":    resultado,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
-}
\ No newline at end of file
+}
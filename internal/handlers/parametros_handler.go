@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"goexcel/internal/auth"
+	"goexcel/internal/database/repositories"
+	"goexcel/internal/models"
+	"goexcel/internal/services"
+)
+
+// ParametrosHandler maneja los parámetros de cálculo de organizaciones, proyectos y presupuestos.
+// Cada respuesta trae lo declarado en el nivel, lo heredado y los parámetros efectivos.
+type ParametrosHandler struct {
+	parametrosSvc   *services.ParametrosService
+	proyectoRepo    *repositories.ProyectoRepository
+	presupuestoRepo *repositories.PresupuestoRepository
+}
+
+// NewParametrosHandler crea una nueva instancia del handler de parámetros
+func NewParametrosHandler(parametrosSvc *services.ParametrosService, proyectoRepo *repositories.ProyectoRepository, presupuestoRepo *repositories.PresupuestoRepository) *ParametrosHandler {
+	return &ParametrosHandler{
+		parametrosSvc:   parametrosSvc,
+		proyectoRepo:    proyectoRepo,
+		presupuestoRepo: presupuestoRepo,
+	}
+}
+
+// ObtenerParametrosOrganizacion devuelve los parámetros que heredan los proyectos de la organización
+func (h *ParametrosHandler) ObtenerParametrosOrganizacion(w http.ResponseWriter, r *http.Request) {
+	organizacionID, ok := autorizarOrganizacion(w, r)
+	if !ok {
+		return
+	}
+
+	respuesta, err := h.parametrosSvc.DeOrganizacion(organizacionID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error obteniendo parámetros: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	responderParametros(w, respuesta)
+}
+
+// GuardarParametrosOrganizacion reemplaza los parámetros de la organización
+func (h *ParametrosHandler) GuardarParametrosOrganizacion(w http.ResponseWriter, r *http.Request) {
+	organizacionID, ok := autorizarOrganizacion(w, r)
+	if !ok {
+		return
+	}
+
+	parametros, ok := leerParametrosCalculo(w, r)
+	if !ok {
+		return
+	}
+	if err := h.parametrosSvc.GuardarDeOrganizacion(organizacionID, parametros); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	log.Printf("✅ Parámetros de la organización %s actualizados", organizacionID)
+	respuesta, err := h.parametrosSvc.DeOrganizacion(organizacionID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error obteniendo parámetros: %v", err), http.StatusInternalServerError)
+		return
+	}
+	respuesta.Message = "Parámetros guardados exitosamente"
+
+	responderParametros(w, respuesta)
+}
+
+// ObtenerParametrosProyecto devuelve los parámetros del proyecto y los heredados de su organización
+func (h *ParametrosHandler) ObtenerParametrosProyecto(w http.ResponseWriter, r *http.Request) {
+	proyectoID, ok := autorizarProyecto(w, r, h.proyectoRepo)
+	if !ok {
+		return
+	}
+
+	respuesta, err := h.parametrosSvc.DeProyecto(proyectoID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error obteniendo parámetros: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	responderParametros(w, respuesta)
+}
+
+// GuardarParametrosProyecto reemplaza los parámetros del proyecto; los que no se envían se heredan
+func (h *ParametrosHandler) GuardarParametrosProyecto(w http.ResponseWriter, r *http.Request) {
+	proyectoID, ok := autorizarProyecto(w, r, h.proyectoRepo)
+	if !ok {
+		return
+	}
+
+	parametros, ok := leerParametrosCalculo(w, r)
+	if !ok {
+		return
+	}
+	if err := h.parametrosSvc.GuardarDeProyecto(proyectoID, parametros); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	log.Printf("✅ Parámetros del proyecto %s actualizados", proyectoID)
+	respuesta, err := h.parametrosSvc.DeProyecto(proyectoID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error obteniendo parámetros: %v", err), http.StatusInternalServerError)
+		return
+	}
+	respuesta.Message = "Parámetros guardados exitosamente"
+
+	responderParametros(w, respuesta)
+}
+
+// ObtenerParametrosPresupuesto devuelve los parámetros del presupuesto jerárquico
+func (h *ParametrosHandler) ObtenerParametrosPresupuesto(w http.ResponseWriter, r *http.Request) {
+	presupuestoID, ok := autorizarPresupuesto(w, r, h.presupuestoRepo)
+	if !ok {
+		return
+	}
+
+	respuesta, err := h.parametrosSvc.DePresupuesto(presupuestoID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error obteniendo parámetros: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	responderParametros(w, respuesta)
+}
+
+// GuardarParametrosPresupuesto reemplaza los parámetros del presupuesto jerárquico
+func (h *ParametrosHandler) GuardarParametrosPresupuesto(w http.ResponseWriter, r *http.Request) {
+	presupuestoID, ok := autorizarPresupuesto(w, r, h.presupuestoRepo)
+	if !ok {
+		return
+	}
+
+	parametros, ok := leerParametrosCalculo(w, r)
+	if !ok {
+		return
+	}
+	if err := h.parametrosSvc.GuardarDePresupuesto(presupuestoID, parametros); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	log.Printf("✅ Parámetros del presupuesto %s actualizados", presupuestoID)
+	respuesta, err := h.parametrosSvc.DePresupuesto(presupuestoID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error obteniendo parámetros: %v", err), http.StatusInternalServerError)
+		return
+	}
+	respuesta.Message = "Parámetros guardados exitosamente"
+
+	responderParametros(w, respuesta)
+}
+
+// autorizarPresupuesto valida el ID de la ruta y que el usuario pueda gestionar el presupuesto
+// jerárquico, con las mismas reglas que un proyecto
+func autorizarPresupuesto(w http.ResponseWriter, r *http.Request, presupuestoRepo *repositories.PresupuestoRepository) (uuid.UUID, bool) {
+	presupuestoID, err := uuid.Parse(mux.Vars(r)["presupuesto_id"])
+	if err != nil {
+		http.Error(w, "ID de presupuesto inválido", http.StatusBadRequest)
+		return uuid.Nil, false
+	}
+
+	user := auth.GetUserFromContext(r.Context())
+	if user == nil {
+		http.Error(w, "Usuario no autenticado", http.StatusUnauthorized)
+		return uuid.Nil, false
+	}
+
+	presupuesto, err := presupuestoRepo.ObtenerPresupuesto(presupuestoID)
+	if err != nil {
+		http.Error(w, "Presupuesto no encontrado", http.StatusNotFound)
+		return uuid.Nil, false
+	}
+	if !puedeGestionarPresupuesto(user, presupuesto) {
+		http.Error(w, "No tiene permisos sobre este presupuesto", http.StatusForbidden)
+		return uuid.Nil, false
+	}
+
+	return presupuestoID, true
+}
+
+// puedeGestionarPresupuesto aplica a un presupuesto jerárquico las reglas de puedeGestionarProyecto:
+// administradores, miembros de su organización o su dueño
+func puedeGestionarPresupuesto(user *models.Usuario, presupuesto *models.Presupuesto) bool {
+	if user.Rol == "admin" {
+		return true
+	}
+	if user.OrganizacionID != nil && presupuesto.OrganizacionID != nil && *user.OrganizacionID == *presupuesto.OrganizacionID {
+		return true
+	}
+	return presupuesto.UsuarioID != nil && *presupuesto.UsuarioID == user.ID
+}
+
+// autorizarProyecto valida el ID de la ruta y que el usuario pueda gestionar el proyecto
+func autorizarProyecto(w http.ResponseWriter, r *http.Request, proyectoRepo *repositories.ProyectoRepository) (uuid.UUID, bool) {
+	proyectoID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "ID de proyecto inválido", http.StatusBadRequest)
+		return uuid.Nil, false
+	}
+	return proyectoID, autorizarGestion(w, r, proyectoRepo, proyectoID)
+}
+
+// autorizarGestion verifica que el usuario pueda gestionar el proyecto y, si no, responde con el error
+func autorizarGestion(w http.ResponseWriter, r *http.Request, proyectoRepo *repositories.ProyectoRepository, proyectoID uuid.UUID) bool {
+	user := auth.GetUserFromContext(r.Context())
+	if user == nil {
+		http.Error(w, "Usuario no autenticado", http.StatusUnauthorized)
+		return false
+	}
+
+	proyecto, err := proyectoRepo.GetByID(proyectoID)
+	if err != nil {
+		http.Error(w, "Proyecto no encontrado", http.StatusNotFound)
+		return false
+	}
+	if !puedeGestionarProyecto(user, proyecto) {
+		http.Error(w, "No tiene permisos sobre este proyecto", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+func leerParametrosCalculo(w http.ResponseWriter, r *http.Request) (*models.ParametrosCalculo, bool) {
+	var parametros models.ParametrosCalculo
+	if err := json.NewDecoder(r.Body).Decode(&parametros); err != nil {
+		http.Error(w, fmt.Sprintf("Error decodificando JSON: %v", err), http.StatusBadRequest)
+		return nil, false
+	}
+	return &parametros, true
+}
+
+func responderParametros(w http.ResponseWriter, respuesta *models.ParametrosResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(respuesta)
+}
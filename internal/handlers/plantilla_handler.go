@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"goexcel/internal/auth"
+	"goexcel/internal/models"
+	"goexcel/internal/services"
+)
+
+// maxTamanoReferencia limita el tamaño del libro de referencia subido
+const maxTamanoReferencia = 10 << 20 // 10 MB
+
+// PlantillaHandler maneja las plantillas Excel de las organizaciones
+type PlantillaHandler struct {
+	plantillaSvc *services.PlantillaService
+}
+
+// NewPlantillaHandler crea una nueva instancia del handler de plantillas
+func NewPlantillaHandler(plantillaSvc *services.PlantillaService) *PlantillaHandler {
+	return &PlantillaHandler{
+		plantillaSvc: plantillaSvc,
+	}
+}
+
+// ObtenerPlantilla devuelve la plantilla de la organización
+func (h *PlantillaHandler) ObtenerPlantilla(w http.ResponseWriter, r *http.Request) {
+	organizacionID, ok := autorizarOrganizacion(w, r)
+	if !ok {
+		return
+	}
+
+	plantilla, err := h.plantillaSvc.ObtenerPlantilla(organizacionID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error obteniendo plantilla: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	response := models.PlantillaExcelResponse{Success: true, Data: plantilla}
+	if plantilla == nil {
+		response.Message = "La organización usa los estilos por defecto"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GuardarPlantilla crea o reemplaza la plantilla de la organización desde JSON
+func (h *PlantillaHandler) GuardarPlantilla(w http.ResponseWriter, r *http.Request) {
+	organizacionID, ok := autorizarOrganizacion(w, r)
+	if !ok {
+		return
+	}
+
+	var plantilla models.PlantillaExcel
+	if err := json.NewDecoder(r.Body).Decode(&plantilla); err != nil {
+		http.Error(w, fmt.Sprintf("Error decodificando JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+	plantilla.OrganizacionID = organizacionID
+
+	h.guardar(w, &plantilla)
+}
+
+// ImportarPlantilla crea la plantilla de la organización a partir de un libro .xlsx de referencia
+func (h *PlantillaHandler) ImportarPlantilla(w http.ResponseWriter, r *http.Request) {
+	organizacionID, ok := autorizarOrganizacion(w, r)
+	if !ok {
+		return
+	}
+
+	if err := r.ParseMultipartForm(maxTamanoReferencia); err != nil {
+		http.Error(w, fmt.Sprintf("Error leyendo formulario: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	archivo, _, err := r.FormFile("archivo")
+	if err != nil {
+		http.Error(w, "Debe adjuntar el libro de referencia en el campo 'archivo'", http.StatusBadRequest)
+		return
+	}
+	defer archivo.Close()
+
+	plantilla, err := h.plantillaSvc.ImportarDesdeXLSX(archivo, organizacionID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.guardar(w, plantilla)
+}
+
+// EliminarPlantilla borra la plantilla y la organización vuelve a los estilos por defecto
+func (h *PlantillaHandler) EliminarPlantilla(w http.ResponseWriter, r *http.Request) {
+	organizacionID, ok := autorizarOrganizacion(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.plantillaSvc.Eliminar(organizacionID); err != nil {
+		http.Error(w, fmt.Sprintf("Error eliminando plantilla: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.PlantillaExcelResponse{
+		Success: true,
+		Message: "Plantilla eliminada exitosamente",
+	})
+}
+
+func (h *PlantillaHandler) guardar(w http.ResponseWriter, plantilla *models.PlantillaExcel) {
+	if err := h.plantillaSvc.Guardar(plantilla); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.PlantillaExcelResponse{
+		Success: true,
+		Message: "Plantilla guardada exitosamente",
+		Data:    plantilla,
+	})
+}
+
+// autorizarOrganizacion valida el ID y que el usuario pertenezca a la organización o sea admin
+func autorizarOrganizacion(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	organizacionID, err := uuid.Parse(mux.Vars(r)["organizacion_id"])
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ID de organización inválido: %v", err), http.StatusBadRequest)
+		return uuid.Nil, false
+	}
+
+	user := auth.GetUserFromContext(r.Context())
+	if user == nil {
+		http.Error(w, "Usuario no autenticado", http.StatusUnauthorized)
+		return uuid.Nil, false
+	}
+
+	if user.Rol != "admin" && (user.OrganizacionID == nil || *user.OrganizacionID != organizacionID) {
+		http.Error(w, "No tiene permisos sobre esta organización", http.StatusForbidden)
+		return uuid.Nil, false
+	}
+
+	return organizacionID, true
+}
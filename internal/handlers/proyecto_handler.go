@@ -3,17 +3,18 @@ package handlers
 import (
 	"encoding/json"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
-	"os"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"goexcel/config"
 	"goexcel/internal/auth"
+	"goexcel/internal/costing"
 	"goexcel/internal/database"
 	"goexcel/internal/database/repositories"
 	"goexcel/internal/legacy"
@@ -26,20 +27,52 @@ type ProyectoHandler struct {
 	partidaRepo      *repositories.PartidaRepository
 	normalizationSvc *services.NormalizationService
 	migrationSvc     *services.NormalizedMigrationService
-	excelSvc         *services.ExcelService
-	excelJerarquicoSvc *services.ExcelJerarquicoService
 	hierarchySvc     *services.HierarchyService
+	insumosSvc       *services.InsumosService
+	plantillaSvc     *services.PlantillaService
+	parametrosSvc    *services.ParametrosService
+	tipoCambioSvc    *services.TipoCambioService
+	manoObraSvc      *services.ManoObraService
+	equipoSvc        *services.EquipoService
+	fleteSvc         *services.FleteService
+	renderers        services.RegistroRenderers
+	metradoRepo      *repositories.MetradoRepository
+	importacionSvc   *services.ImportacionExcelService
 }
 
 func NewProyectoHandler(db *database.DB, cfg *config.Config) *ProyectoHandler {
+	parametrosSvc := services.NewParametrosService(repositories.NewParametrosRepository(db.DB))
+	tipoCambioSvc := services.NewTipoCambioService(repositories.NewTipoCambioRepository(db.DB))
+	fleteSvc := services.NewFleteService(repositories.NewFleteRepository(db.DB))
+	calculoSvc := services.NewCalculoService(db.DB, parametrosSvc, tipoCambioSvc, fleteSvc)
+	insumosSvc := services.NewInsumosService(db.DB, calculoSvc)
+	recursoRepo := repositories.NewRecursoRepository(db)
 	return &ProyectoHandler{
 		proyectoRepo:     repositories.NewProyectoRepository(db),
 		partidaRepo:      repositories.NewPartidaRepository(db),
 		normalizationSvc: services.NewNormalizationService(),
 		migrationSvc:     services.NewNormalizedMigrationService(db),
-		excelSvc:         services.NewExcelService(cfg),
-		excelJerarquicoSvc: services.NewExcelJerarquicoService(cfg),
 		hierarchySvc:     services.NewHierarchyService(db.DB),
+		insumosSvc:       insumosSvc,
+		plantillaSvc: services.NewPlantillaService(
+			repositories.NewPlantillaRepository(db.DB),
+			repositories.NewOrganizacionRepository(db),
+		),
+		parametrosSvc: parametrosSvc,
+		tipoCambioSvc: tipoCambioSvc,
+		manoObraSvc: services.NewManoObraService(
+			repositories.NewManoObraRepository(db.DB),
+			recursoRepo,
+		),
+		equipoSvc: services.NewEquipoService(
+			repositories.NewEquipoRepository(db.DB),
+			recursoRepo,
+			services.NewListaPreciosService(repositories.NewListaPreciosRepository(db.DB), calculoSvc),
+		),
+		fleteSvc:       fleteSvc,
+		metradoRepo:    repositories.NewMetradoRepository(db.DB),
+		importacionSvc: services.NewImportacionExcelService(),
+		renderers:      services.NewRegistroRenderers(insumosSvc, services.NewFormulaPolinomicaService()),
 	}
 }
 
@@ -50,7 +83,48 @@ type CreateProjectRequest struct {
 }
 
 // Almacén temporal de JSON originales por proyecto ID
-var originalJSONStore = make(map[string][]legacy.PartidaLegacy)
+var originalJSONStore = &almacenJSON{partidas: make(map[string][]legacy.PartidaLegacy)}
+
+// almacenJSON protege el mapa de JSON originales: lo leen a la vez los trabajadores de la exportación en
+// lote y lo modifican la creación, el borrado y el reprecio de proyectos
+type almacenJSON struct {
+	mu       sync.RWMutex
+	partidas map[string][]legacy.PartidaLegacy
+}
+
+// obtener devuelve las partidas guardadas del proyecto; false si no hay ninguna
+func (a *almacenJSON) obtener(projectID string) ([]legacy.PartidaLegacy, bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	partidas, existe := a.partidas[projectID]
+	return partidas, existe && len(partidas) > 0
+}
+
+func (a *almacenJSON) guardar(projectID string, partidas []legacy.PartidaLegacy) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.partidas[projectID] = partidas
+}
+
+func (a *almacenJSON) eliminar(projectID string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.partidas, projectID)
+}
+
+// ids devuelve los proyectos con JSON guardado, para los mensajes de depuración
+func (a *almacenJSON) ids() []string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	ids := make([]string, 0, len(a.partidas))
+	for id := range a.partidas {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// tiempoMaximoExportacion es el plazo para enviar un libro al cliente, mayor que el WriteTimeout del servidor
+const tiempoMaximoExportacion = 5 * time.Minute
 
 type CreateProjectResponse struct {
 	Success   bool   `json:"success"`
@@ -100,14 +174,21 @@ func (h *ProyectoHandler) CreateProject(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if req.Proyecto.Parametros != nil {
+		if err := req.Proyecto.Parametros.Validar(); err != nil {
+			http.Error(w, fmt.Sprintf("Parámetros inválidos: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
 	log.Printf("📊 Procesando proyecto: %s con %d partidas", req.Proyecto.Nombre, len(req.Partidas))
 
 	// Debug: Mostrar algunas partidas del frontend
 	for i, partida := range req.Partidas {
 		if i < 2 { // Solo las primeras 2 para no saturar logs
-			log.Printf("🔍 Partida %d: Codigo='%s', Descripcion='%s' (MO:%d, Mat:%d, Eq:%d, Sub:%d)", 
+			log.Printf("🔍 Partida %d: Codigo='%s', Descripcion='%s' (MO:%d, Mat:%d, Eq:%d, Sub:%d)",
 				i+1, partida.Codigo, partida.Descripcion,
-				len(partida.ManoObra), len(partida.Materiales), 
+				len(partida.ManoObra), len(partida.Materiales),
 				len(partida.Equipos), len(partida.Subcontratos))
 		}
 	}
@@ -127,35 +208,68 @@ func (h *ProyectoHandler) CreateProject(w http.ResponseWriter, r *http.Request)
 	partidasLegacy := h.convertToLegacyFormat(req.Partidas)
 	log.Printf("🔄 Convertidas %d partidas a formato legacy", len(partidasLegacy))
 
+	normalizedData, err := h.guardarProyecto(req.Proyecto, partidasLegacy, user)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := nuevaCreateProjectResponse(normalizedData, "Proyecto creado exitosamente")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// guardarProyecto normaliza las partidas, las migra a PostgreSQL con el usuario como dueño y
+// conserva el JSON original para la generación de Excel. Sin moneda en la solicitud, el proyecto toma
+// la de los parámetros de la organización del usuario.
+func (h *ProyectoHandler) guardarProyecto(proyectoReq models.ProyectoRequest, partidasLegacy []legacy.PartidaLegacy, user *models.Usuario) (*models.NormalizedData, error) {
 	// Normalizar datos
-	normalizedData, err := h.normalizationSvc.NormalizeFromJSONData(partidasLegacy, req.Proyecto.Nombre)
+	normalizedData, err := h.normalizationSvc.NormalizeFromJSONData(partidasLegacy, proyectoReq.Nombre)
 	if err != nil {
 		log.Printf("❌ Error normalizando datos: %v", err)
-		http.Error(w, fmt.Sprintf("Error normalizing data: %v", err), http.StatusInternalServerError)
-		return
+		return nil, fmt.Errorf("Error normalizing data: %v", err)
 	}
 
 	// Actualizar información del proyecto
-	if req.Proyecto.Descripcion != "" {
-		normalizedData.Proyecto.Descripcion = req.Proyecto.Descripcion
-	}
-	if req.Proyecto.Moneda != "" {
-		normalizedData.Proyecto.Moneda = req.Proyecto.Moneda
+	if proyectoReq.Descripcion != "" {
+		normalizedData.Proyecto.Descripcion = proyectoReq.Descripcion
+	}
+	switch {
+	case proyectoReq.Moneda != "":
+		normalizedData.Proyecto.Moneda = proyectoReq.Moneda
+	case proyectoReq.Parametros != nil && proyectoReq.Parametros.Moneda != nil:
+		normalizedData.Proyecto.Moneda = *proyectoReq.Parametros.Moneda
+	default:
+		parametros, err := h.parametrosSvc.EfectivosDeOrganizacion(user.OrganizacionID)
+		if err != nil {
+			log.Printf("⚠️ No se pudieron cargar los parámetros de la organización: %v", err)
+		}
+		normalizedData.Proyecto.Moneda = parametros.Moneda
 	}
 
 	// Migrar a PostgreSQL con usuario_id
 	if err := h.migrationSvc.MigrateNormalizedDataWithUser(normalizedData, user.ID); err != nil {
 		log.Printf("❌ Error migrando a PostgreSQL: %v", err)
-		http.Error(w, fmt.Sprintf("Error saving to database: %v", err), http.StatusInternalServerError)
-		return
+		return nil, fmt.Errorf("Error saving to database: %v", err)
 	}
 
 	log.Printf("✅ Proyecto creado exitosamente: %s", normalizedData.Proyecto.ID)
 
+	if proyectoReq.Parametros != nil {
+		proyectoUUID, err := uuid.Parse(normalizedData.Proyecto.ID)
+		if err == nil {
+			err = h.parametrosSvc.GuardarDeProyecto(proyectoUUID, proyectoReq.Parametros)
+		}
+		if err != nil {
+			log.Printf("⚠️ Proyecto %s creado, pero no se guardaron sus parámetros: %v", normalizedData.Proyecto.ID, err)
+		}
+	}
+
 	// Guardar JSON original para generación de Excel
-	originalJSONStore[normalizedData.Proyecto.ID] = partidasLegacy
+	originalJSONStore.guardar(normalizedData.Proyecto.ID, partidasLegacy)
 	log.Printf("💾 JSON original guardado para proyecto: %s (%d partidas)", normalizedData.Proyecto.ID, len(partidasLegacy))
-	
+
 	// Debug: Mostrar contenido de la primera partida legacy
 	if len(partidasLegacy) > 0 {
 		primera := partidasLegacy[0]
@@ -165,10 +279,14 @@ func (h *ProyectoHandler) CreateProject(w http.ResponseWriter, r *http.Request)
 			len(primera.Equipos), len(primera.Subcontratos))
 	}
 
-	// Respuesta
-	response := CreateProjectResponse{
+	return normalizedData, nil
+}
+
+// nuevaCreateProjectResponse arma la respuesta de creación a partir del proyecto normalizado
+func nuevaCreateProjectResponse(normalizedData *models.NormalizedData, mensaje string) CreateProjectResponse {
+	return CreateProjectResponse{
 		Success:   true,
-		Message:   "Proyecto creado exitosamente",
+		Message:   mensaje,
 		ProjectID: normalizedData.Proyecto.ID,
 		Project: models.ProyectoResponse{
 			ID:          normalizedData.Proyecto.ID,
@@ -179,9 +297,6 @@ func (h *ProyectoHandler) CreateProject(w http.ResponseWriter, r *http.Request)
 			UpdatedAt:   "",
 		},
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
 }
 
 // GetProjects returns all projects
@@ -270,12 +385,13 @@ func (h *ProyectoHandler) GetProject(w http.ResponseWriter, r *http.Request) {
 
 	// Verificar si tenemos JSON original guardado
 	log.Printf("🔍 Buscando JSON original para proyecto: %s", projectID)
-	log.Printf("🔍 Proyectos en memoria: %d", len(originalJSONStore))
-	for id := range originalJSONStore {
+	idsEnMemoria := originalJSONStore.ids()
+	log.Printf("🔍 Proyectos en memoria: %d", len(idsEnMemoria))
+	for _, id := range idsEnMemoria {
 		log.Printf("   - %s", id)
 	}
 	
-	if partidasLegacy, exists := originalJSONStore[projectID]; exists && len(partidasLegacy) > 0 {
+	if partidasLegacy, exists := originalJSONStore.obtener(projectID); exists {
 		log.Printf("📋 Usando JSON original guardado - %d partidas", len(partidasLegacy))
 		
 		// Convertir partidas legacy al formato de respuesta
@@ -388,7 +504,7 @@ func (h *ProyectoHandler) DeleteProject(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Limpiar JSON original del store si existe
-	delete(originalJSONStore, projectID)
+	originalJSONStore.eliminar(projectID)
 	log.Printf("🧹 JSON original eliminado del store para proyecto: %s", projectID)
 
 	response := map[string]interface{}{
@@ -437,69 +553,464 @@ func (h *ProyectoHandler) ExportProject(w http.ResponseWriter, r *http.Request)
 	log.Printf("📤 Exportando proyecto %s en formato: %s", projectID, format)
 
 	switch format {
-	case "excel":
-		// Validar UUID del proyecto
-		proyectoUUID, parseErr := uuid.Parse(projectID)
-		if parseErr != nil {
-			log.Printf("❌ UUID inválido: %s", projectID)
-			http.Error(w, "ID de proyecto inválido", http.StatusBadRequest)
+	case "acu":
+		// TODO: Generar .acu
+		w.Header().Set("Content-Disposition", "attachment; filename=proyecto.acu")
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("@proyecto{...}"))
+
+	default:
+		// Los formatos de reporte (excel, pdf, csv, html, json...) comparten la carga de datos
+		renderer, existe := h.renderers[format]
+		if !existe {
+			http.Error(w, "Formato no soportado", http.StatusBadRequest)
 			return
 		}
-		
-		// Obtener información del proyecto
-		proyecto, err := h.proyectoRepo.GetByID(proyectoUUID)
-		if err != nil {
-			log.Printf("❌ Error obteniendo proyecto: %v", err)
-			http.Error(w, "Proyecto no encontrado", http.StatusNotFound)
+		proyecto, opciones, ok := h.prepararExportacion(w, r, projectID)
+		if !ok || !autorizarLectura(w, r, proyecto) {
 			return
 		}
+		h.exportarReporte(w, proyecto, projectID, opciones, renderer, "attachment")
+	}
+}
 
-		log.Printf("📊 Generando Excel jerárquico profesional para proyecto: %s", proyecto.Nombre)
+// PreviewProject muestra el presupuesto como página HTML en el navegador, con el mismo cálculo que la exportación
+func (h *ProyectoHandler) PreviewProject(w http.ResponseWriter, r *http.Request) {
+	projectID := mux.Vars(r)["id"]
+	log.Printf("📊 Vista previa del proyecto %s", projectID)
 
-		// Intentar usar JSON original primero, fallback a método jerárquico desde BD
-		filename, err := h.generateExcelFromOriginalJSON(proyecto, projectID)
-		if err != nil {
-			log.Printf("❌ Error generando Excel desde JSON original: %v", err)
-			http.Error(w, fmt.Sprintf("Error generando Excel: %v", err), http.StatusInternalServerError)
+	proyecto, opciones, ok := h.prepararExportacion(w, r, projectID)
+	if !ok || !autorizarLectura(w, r, proyecto) {
+		return
+	}
+	h.exportarReporte(w, proyecto, projectID, opciones, h.renderers["html"], "inline")
+}
+
+// PreviewPublicProject muestra la vista previa sin autenticación, solo para proyectos públicos o destacados
+func (h *ProyectoHandler) PreviewPublicProject(w http.ResponseWriter, r *http.Request) {
+	projectID := mux.Vars(r)["id"]
+	log.Printf("📊 Vista previa pública del proyecto %s", projectID)
+
+	proyecto, opciones, ok := h.prepararExportacion(w, r, projectID)
+	if !ok {
+		return
+	}
+	if proyecto.Visibility != "public" && proyecto.Visibility != "featured" {
+		http.Error(w, "Proyecto no público", http.StatusForbidden)
+		return
+	}
+	h.exportarReporte(w, proyecto, projectID, opciones, h.renderers["html"], "inline")
+}
+
+// CompareProjects exporta el cuadro comparativo del proyecto (base) contra otro proyecto, por ejemplo
+// el presupuesto del expediente contra la oferta de un postor
+func (h *ProyectoHandler) CompareProjects(w http.ResponseWriter, r *http.Request) {
+	projectID := mux.Vars(r)["id"]
+	otroID := r.URL.Query().Get("con")
+	log.Printf("📊 Comparando proyecto %s con %s", projectID, otroID)
+
+	otroUUID, err := uuid.Parse(otroID)
+	if err != nil {
+		http.Error(w, "Parámetro con inválido: debe ser el ID del proyecto a comparar", http.StatusBadRequest)
+		return
+	}
+
+	umbral := services.UmbralComparativoPorDefecto
+	if valor := r.URL.Query().Get("umbral"); valor != "" {
+		umbral, err = strconv.ParseFloat(valor, 64)
+		if err != nil || umbral < 0 {
+			http.Error(w, "umbral inválido: debe ser un porcentaje mayor o igual a 0", http.StatusBadRequest)
 			return
 		}
+	}
 
-		// Enviar archivo
-		file, err := os.Open(filename)
-		if err != nil {
-			log.Printf("❌ Error abriendo archivo Excel: %v", err)
-			http.Error(w, "Error abriendo archivo Excel", http.StatusInternalServerError)
-			return
+	proyecto, opciones, ok := h.prepararExportacion(w, r, projectID)
+	if !ok || !autorizarLectura(w, r, proyecto) {
+		return
+	}
+	otro, err := h.proyectoRepo.GetByID(otroUUID)
+	if err != nil {
+		log.Printf("❌ Error obteniendo proyecto a comparar: %v", err)
+		http.Error(w, "Proyecto a comparar no encontrado", http.StatusNotFound)
+		return
+	}
+	// El comparativo muestra las partidas de ambos proyectos, así que el usuario debe poder ver los dos
+	if !autorizarLectura(w, r, otro) {
+		return
+	}
+
+	base, err := h.cargarReporte(proyecto, projectID, opciones)
+	if err != nil {
+		log.Printf("❌ Error obteniendo datos del proyecto base: %v", err)
+		http.Error(w, fmt.Sprintf("Error generando comparativo: %v", err), http.StatusInternalServerError)
+		return
+	}
+	opcionesOtro := opciones
+	opcionesOtro.Proyecto = otro.Nombre
+	opcionesOtro.Parametros = h.parametrosProyecto(otro.ID)
+	comparado, err := h.cargarReporte(otro, otroID, opcionesOtro)
+	if err != nil {
+		log.Printf("❌ Error obteniendo datos del proyecto a comparar: %v", err)
+		http.Error(w, fmt.Sprintf("Error generando comparativo: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	comparativo := services.ConstruirComparativo(base, comparado, umbral)
+	doc, err := services.GenerarExcelComparativo(comparativo)
+	if err != nil {
+		log.Printf("❌ %v", err)
+		http.Error(w, fmt.Sprintf("Error generando comparativo: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer doc.Close()
+
+	downloadName := fmt.Sprintf("Comparativo %s vs %s.xlsx", proyecto.Nombre, otro.Nombre)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", downloadName))
+	w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	if _, err := doc.WriteTo(w); err != nil {
+		log.Printf("❌ Error enviando comparativo: %v", err)
+		return
+	}
+
+	log.Printf("✅ Comparativo enviado: %d filas comparadas, %d partidas solo en base, %d solo en comparado",
+		len(comparativo.Filas), len(comparativo.SoloBase), len(comparativo.SoloComparado))
+}
+
+// SimulateProject recalcula el presupuesto con ajustes de precio por tipo de recurso o por código y de
+// rendimiento por título, sin guardar nada, y devuelve los totales antes y después. Con ?format=excel
+// descarga el resultado como libro Excel.
+func (h *ProyectoHandler) SimulateProject(w http.ResponseWriter, r *http.Request) {
+	projectID := mux.Vars(r)["id"]
+	log.Printf("📊 Simulando presupuesto del proyecto %s", projectID)
+
+	var req models.SimulacionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Error decodificando JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	proyecto, opciones, ok := h.prepararExportacion(w, r, projectID)
+	if !ok || !autorizarLectura(w, r, proyecto) {
+		return
+	}
+
+	datos, err := h.cargarDatosReporte(proyecto, projectID, opciones)
+	if err != nil {
+		log.Printf("❌ Error obteniendo datos del proyecto: %v", err)
+		http.Error(w, fmt.Sprintf("Error simulando presupuesto: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	resultado, err := services.Simular(datos, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	log.Printf("✅ Simulación de %s: %d recursos y %d partidas ajustados, costo directo %s → %s",
+		proyecto.Nombre, resultado.RecursosAjustados, resultado.PartidasAjustadas,
+		resultado.CostoDirecto.Base, resultado.CostoDirecto.Simulado)
+
+	if r.URL.Query().Get("format") != "excel" {
+		mensaje := "Simulación calculada: no se guardaron cambios"
+		if len(resultado.NoAplicados) > 0 {
+			mensaje = fmt.Sprintf("%s (%d ajustes no se aplicaron)", mensaje, len(resultado.NoAplicados))
 		}
-		defer file.Close()
-		defer os.Remove(filename) // Limpiar archivo temporal
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(models.SimulacionResponse{
+			Success: true,
+			Message: mensaje,
+			Data:    resultado,
+		})
+		return
+	}
 
-		// Usar el nombre del proyecto para el download
-		downloadName := proyecto.Nombre + ".xlsx"
-		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", downloadName))
-		w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
-		
-		_, err = io.Copy(w, file)
-		if err != nil {
-			log.Printf("❌ Error enviando archivo Excel: %v", err)
+	doc, err := services.GenerarExcelSimulacion(resultado)
+	if err != nil {
+		log.Printf("❌ %v", err)
+		http.Error(w, fmt.Sprintf("Error generando simulación: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer doc.Close()
+
+	downloadName := fmt.Sprintf("Simulación %s.xlsx", proyecto.Nombre)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", downloadName))
+	w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	if _, err := doc.WriteTo(w); err != nil {
+		log.Printf("❌ Error enviando simulación: %v", err)
+	}
+}
+
+// AnalyzeRisk corre el análisis de riesgo de costos por Monte Carlo con las distribuciones de precios,
+// rendimientos y metrados indicadas y devuelve los percentiles (P80), el histograma y el tornado. Con
+// ?format=excel descarga la hoja "Análisis de Riesgo".
+func (h *ProyectoHandler) AnalyzeRisk(w http.ResponseWriter, r *http.Request) {
+	projectID := mux.Vars(r)["id"]
+	log.Printf("📊 Analizando riesgo de costos del proyecto %s", projectID)
+
+	var req models.AnalisisRiesgoRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Error decodificando JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	proyecto, opciones, ok := h.prepararExportacion(w, r, projectID)
+	if !ok || !autorizarLectura(w, r, proyecto) {
+		return
+	}
+
+	datos, err := h.cargarDatosReporte(proyecto, projectID, opciones)
+	if err != nil {
+		log.Printf("❌ Error obteniendo datos del proyecto: %v", err)
+		http.Error(w, fmt.Sprintf("Error analizando riesgo: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	analisis, err := services.AnalizarRiesgo(r.Context(), datos, req)
+	if err != nil {
+		if r.Context().Err() != nil {
+			log.Printf("⚠️ Análisis de riesgo de %s cancelado: %v", proyecto.Nombre, err)
+			return
 		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	log.Printf("✅ Análisis de riesgo de %s: %d iteraciones (semilla %d), costo directo %s, P80 %s",
+		proyecto.Nombre, analisis.Iteraciones, analisis.Semilla, analisis.CostoDirecto, analisis.P80.CostoDirecto)
 
-		log.Printf("✅ Excel enviado exitosamente: %s", filename)
-		
-	case "acu":
-		// TODO: Generar .acu
-		w.Header().Set("Content-Disposition", "attachment; filename=proyecto.acu")
-		w.Header().Set("Content-Type", "text/plain")
-		w.Write([]byte("@proyecto{...}"))
-		
-	case "json":
-		// TODO: Generar JSON
+	if r.URL.Query().Get("format") != "excel" {
+		mensaje := "Análisis de riesgo calculado: no se guardaron cambios"
+		if len(analisis.NoAplicadas) > 0 {
+			mensaje = fmt.Sprintf("%s (%d variables no se aplicaron)", mensaje, len(analisis.NoAplicadas))
+		}
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]string{"message": "JSON export placeholder"})
-		
-	default:
-		http.Error(w, "Formato no soportado", http.StatusBadRequest)
+		json.NewEncoder(w).Encode(models.AnalisisRiesgoResponse{
+			Success: true,
+			Message: mensaje,
+			Data:    analisis,
+		})
+		return
+	}
+
+	doc, err := services.GenerarExcelRiesgo(analisis)
+	if err != nil {
+		log.Printf("❌ %v", err)
+		http.Error(w, fmt.Sprintf("Error generando análisis de riesgo: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer doc.Close()
+
+	downloadName := fmt.Sprintf("Análisis de Riesgo %s.xlsx", proyecto.Nombre)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", downloadName))
+	w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	if _, err := doc.WriteTo(w); err != nil {
+		log.Printf("❌ Error enviando análisis de riesgo: %v", err)
+	}
+}
+
+// prepararExportacion valida el proyecto y arma las opciones comunes a los formatos exportados
+func (h *ProyectoHandler) prepararExportacion(w http.ResponseWriter, r *http.Request, projectID string) (*models.Proyecto, models.OpcionesExportacion, bool) {
+	// Validar UUID del proyecto
+	proyectoUUID, parseErr := uuid.Parse(projectID)
+	if parseErr != nil {
+		log.Printf("❌ UUID inválido: %s", projectID)
+		http.Error(w, "ID de proyecto inválido", http.StatusBadRequest)
+		return nil, models.OpcionesExportacion{}, false
+	}
+
+	// Obtener información del proyecto
+	proyecto, err := h.proyectoRepo.GetByID(proyectoUUID)
+	if err != nil {
+		log.Printf("❌ Error obteniendo proyecto: %v", err)
+		http.Error(w, "Proyecto no encontrado", http.StatusNotFound)
+		return nil, models.OpcionesExportacion{}, false
+	}
+
+	opciones, err := parseOpcionesExportacion(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, models.OpcionesExportacion{}, false
+	}
+
+	opciones.Proyecto = proyecto.Nombre
+	opciones.Parametros = h.parametrosProyecto(proyectoUUID)
+
+	// Plantilla de la organización; si falla se exporta con los estilos por defecto
+	opciones.Plantilla, err = h.plantillaSvc.ObtenerParaExportar(proyecto.OrganizacionID)
+	if err != nil {
+		log.Printf("⚠️ No se pudo cargar la plantilla de la organización: %v", err)
+	}
+
+	// Los archivos grandes tardan más que el WriteTimeout del servidor en llegar al cliente
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(tiempoMaximoExportacion)); err != nil {
+		log.Printf("⚠️ No se pudo extender el plazo de escritura: %v", err)
+	}
+
+	return proyecto, opciones, true
+}
+
+// autorizarLectura verifica que el usuario pueda ver el proyecto y, si no, responde con el error. Como en
+// GetProject, los proyectos públicos o destacados los ve cualquier usuario y los privados el admin;
+// además, como en la exportación por lotes, su dueño y los usuarios de su organización.
+func autorizarLectura(w http.ResponseWriter, r *http.Request, proyecto *models.Proyecto) bool {
+	user := auth.GetUserFromContext(r.Context())
+	if user == nil {
+		http.Error(w, "Usuario no autenticado", http.StatusUnauthorized)
+		return false
+	}
+	if proyecto.Visibility != "public" && proyecto.Visibility != "featured" && !puedeGestionarProyecto(user, proyecto) {
+		log.Printf("🚫 Usuario %s sin permisos para proyecto privado %s", user.Email, proyecto.ID)
+		http.Error(w, "No tiene permisos para acceder a este proyecto", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// parametrosProyecto resuelve los parámetros de cálculo del proyecto; si fallan se calcula con los
+// valores por defecto
+func (h *ProyectoHandler) parametrosProyecto(proyectoID uuid.UUID) *models.Parametros {
+	parametros, err := h.parametrosSvc.EfectivosDeProyecto(proyectoID)
+	if err != nil {
+		log.Printf("⚠️ No se pudieron cargar los parámetros del proyecto %s: %v", proyectoID, err)
+	}
+	return &parametros
+}
+
+// exportarReporte construye el reporte del proyecto, lo genera con el renderizador del formato pedido
+// y lo envía al cliente. El documento se arma antes de escribir la respuesta para poder responder con error.
+// disposicion es "attachment" para descargar el archivo o "inline" para mostrarlo en el navegador.
+func (h *ProyectoHandler) exportarReporte(w http.ResponseWriter, proyecto *models.Proyecto, projectID string, opciones models.OpcionesExportacion, renderer services.Renderer, disposicion string) {
+	log.Printf("📊 Generando reporte %s para proyecto: %s", renderer.Extension(), proyecto.Nombre)
+
+	reporte, err := h.cargarReporte(proyecto, projectID, opciones)
+	if err != nil {
+		log.Printf("❌ Error obteniendo datos del reporte: %v", err)
+		http.Error(w, fmt.Sprintf("Error generando reporte: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	doc, err := renderer.Renderizar(reporte)
+	if err != nil {
+		log.Printf("❌ Error generando reporte %s: %v", renderer.Extension(), err)
+		http.Error(w, fmt.Sprintf("Error generando reporte: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer doc.Close()
+
+	// Usar el nombre del proyecto para el download
+	downloadName := proyecto.Nombre + renderer.Extension()
+	w.Header().Set("Content-Disposition", fmt.Sprintf("%s; filename=%s", disposicion, downloadName))
+	w.Header().Set("Content-Type", renderer.ContentType())
+
+	if _, err := doc.WriteTo(w); err != nil {
+		log.Printf("❌ Error enviando reporte: %v", err)
+		return
+	}
+
+	log.Printf("✅ Reporte enviado exitosamente: %s", downloadName)
+}
+
+// cargarReporte reúne los datos del proyecto y calcula el reporte común a todos los formatos
+func (h *ProyectoHandler) cargarReporte(proyecto *models.Proyecto, projectID string, opciones models.OpcionesExportacion) (*models.ReportePresupuesto, error) {
+	datos, err := h.cargarDatosReporte(proyecto, projectID, opciones)
+	if err != nil {
+		return nil, err
+	}
+	reporte := services.ConstruirReporte(datos)
+
+	// La relación de insumos se consolida del mismo reporte para que concilie con su costo directo; si
+	// falla, se exporta el resto del reporte
+	if reporte.Insumos, err = h.insumosSvc.RelacionDeReporte(reporte); err != nil {
+		log.Printf("⚠️ No se pudo calcular la relación de insumos: %v", err)
+		reporte.Insumos = nil
+	} else {
+		reporte.Insumos.ProyectoID = &proyecto.ID
 	}
+	return reporte, nil
+}
+
+// cargarDatosReporte reúne los datos del proyecto con los que se calcula el reporte. Solo las partidas
+// son obligatorias: sin metrados o títulos se exporta lo demás.
+func (h *ProyectoHandler) cargarDatosReporte(proyecto *models.Proyecto, projectID string, opciones models.OpcionesExportacion) (services.DatosReporte, error) {
+	partidasLegacy, err := h.obtenerPartidasLegacy(proyecto, projectID)
+	if err != nil {
+		return services.DatosReporte{}, err
+	}
+
+	datos := services.DatosReporte{
+		Partidas: partidasLegacy,
+		Titulos:  make(map[string]string),
+		Opciones: opciones,
+	}
+
+	// Los precios en otra moneda se convierten con las tasas vigentes a la fecha de referencia; si falta
+	// alguna, el reporte no se genera para no mezclar monedas en los totales
+	parametros := opciones.ParametrosCalculo()
+	if monedas := services.MonedasExtranjeras(partidasLegacy, parametros.Moneda); len(monedas) > 0 {
+		if datos.TiposCambio, err = h.tipoCambioSvc.Tasas(proyecto.ID, parametros.Moneda, parametros.FechaReferencia(), monedas); err != nil {
+			return services.DatosReporte{}, err
+		}
+	}
+
+	// Los cálculos de mano de obra y de equipos son anexos: si fallan, se exporta el resto del reporte
+	if datos.ManoObra, err = h.manoObraSvc.CalculoParaProyecto(proyecto.ID, parametros.FechaReferencia()); err != nil {
+		log.Printf("⚠️ No se pudo calcular el costo de mano de obra: %v", err)
+		datos.ManoObra = nil
+	}
+	if datos.Equipos, err = h.equipoSvc.CostosParaProyecto(proyecto.ID, codigosEquipos(partidasLegacy)); err != nil {
+		log.Printf("⚠️ No se pudo calcular el costo horario de equipos: %v", err)
+		datos.Equipos = nil
+	}
+
+	// El flete sí cambia los APU, así que si no se puede cargar el reporte no se genera
+	if datos.Flete, err = h.fleteSvc.ParaReporte(proyecto.ID); err != nil {
+		return services.DatosReporte{}, err
+	}
+
+	if datos.Metrados, err = h.metradoRepo.ObtenerMetradosSimples(proyecto.ID); err != nil {
+		log.Printf("⚠️ No se pudieron obtener los metrados: %v", err)
+	}
+
+	if titulos, err := h.hierarchySvc.ObtenerTitulosJerarquicos(proyecto.ID.String()); err != nil {
+		log.Printf("⚠️ No se pudieron obtener los títulos: %v", err)
+	} else {
+		for _, titulo := range titulos {
+			datos.Titulos[titulo.Codigo] = titulo.Descripcion
+		}
+	}
+
+	return datos, nil
+}
+
+// codigosEquipos devuelve los códigos de los equipos que usan las partidas, sin repetir
+func codigosEquipos(partidas []legacy.PartidaLegacy) []string {
+	vistos := make(map[string]bool)
+	var codigos []string
+	for _, partida := range partidas {
+		for _, recurso := range partida.Equipos {
+			if recurso.Codigo != "" && !vistos[recurso.Codigo] {
+				vistos[recurso.Codigo] = true
+				codigos = append(codigos, recurso.Codigo)
+			}
+		}
+	}
+	return codigos
+}
+
+// obtenerPartidasLegacy devuelve las partidas del JSON original o, si no está disponible, las de la BD
+func (h *ProyectoHandler) obtenerPartidasLegacy(proyecto *models.Proyecto, projectID string) ([]legacy.PartidaLegacy, error) {
+	if partidasLegacy, exists := originalJSONStore.obtener(projectID); exists {
+		return partidasLegacy, nil
+	}
+
+	partidasConRecursos, err := h.getPartidasConRecursos(proyecto.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error obteniendo partidas desde BD: %v", err)
+	}
+	if len(partidasConRecursos) == 0 {
+		return nil, fmt.Errorf("no se encontraron partidas en la base de datos para el proyecto")
+	}
+
+	return h.convertDatabaseToLegacy(partidasConRecursos), nil
 }
 
 // ValidateACU validates ACU syntax
@@ -516,7 +1027,7 @@ func (h *ProyectoHandler) ValidateACU(w http.ResponseWriter, r *http.Request) {
 	log.Printf("🔍 Validando sintaxis ACU")
 
 	// Usar el parser ACU existente
-	acuParser := services.NewACUParserService()
+	acuParser := services.NewACUParserService(models.ParametrosPorDefecto())
 	_, err := acuParser.ParseString(req.ACUContent)
 
 	response := map[string]interface{}{
@@ -557,7 +1068,7 @@ func (h *ProyectoHandler) GetProjectACU(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Verificar si tenemos JSON original guardado
-	if partidasLegacy, exists := originalJSONStore[projectID]; exists && len(partidasLegacy) > 0 {
+	if partidasLegacy, exists := originalJSONStore.obtener(projectID); exists {
 		log.Printf("📋 Generando ACU desde JSON original - %d partidas", len(partidasLegacy))
 		
 		// Generar código ACU desde el JSON original
@@ -605,29 +1116,40 @@ func (h *ProyectoHandler) GetProjectACU(w http.ResponseWriter, r *http.Request)
 		"acu_content": acuContent,
 		"source":      "database",
 	}
-	
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
+// parametrosDeclarados son los parámetros propios del proyecto, que se escriben en el bloque @proyecto
+func (h *ProyectoHandler) parametrosDeclarados(proyecto *models.Proyecto) *models.ParametrosCalculo {
+	respuesta, err := h.parametrosSvc.DeProyecto(proyecto.ID)
+	if err != nil {
+		log.Printf("⚠️ No se pudieron cargar los parámetros del proyecto %s: %v", proyecto.ID, err)
+		return nil
+	}
+	return respuesta.Parametros
+}
+
 // generateACUFromLegacy genera código ACU desde datos legacy (JSON original)
 func (h *ProyectoHandler) generateACUFromLegacy(proyecto *models.Proyecto, partidasLegacy []legacy.PartidaLegacy) string {
 	var acuContent strings.Builder
-	
+
 	// Agregar proyecto
 	acuContent.WriteString(fmt.Sprintf("@proyecto{%s,\n", strings.ToLower(strings.ReplaceAll(proyecto.Nombre, " ", "_"))))
 	acuContent.WriteString(fmt.Sprintf("  nombre = \"%s\",\n", proyecto.Nombre))
 	if proyecto.Descripcion != nil && *proyecto.Descripcion != "" {
 		acuContent.WriteString(fmt.Sprintf("  descripcion = \"%s\",\n", *proyecto.Descripcion))
 	}
+	services.EscribirParametrosACU(&acuContent, h.parametrosDeclarados(proyecto))
 	acuContent.WriteString(fmt.Sprintf("  moneda = \"%s\"\n", proyecto.Moneda))
 	acuContent.WriteString("}\n\n")
-	
+
 	// Agregar partidas
 	for _, partida := range partidasLegacy {
 		partidaID := strings.ToLower(strings.ReplaceAll(partida.Codigo, ".", "_"))
 		partidaID = strings.ReplaceAll(partidaID, " ", "_")
-		
+
 		acuContent.WriteString(fmt.Sprintf("@partida{%s,\n", partidaID))
 		acuContent.WriteString(fmt.Sprintf("  codigo = \"%s\",\n", partida.Codigo))
 		acuContent.WriteString(fmt.Sprintf("  descripcion = \"%s\",\n", partida.Descripcion))
@@ -649,21 +1171,22 @@ func (h *ProyectoHandler) generateACUFromLegacy(proyecto *models.Proyecto, parti
 // generateACUFromDB genera código ACU desde datos de la base de datos
 func (h *ProyectoHandler) generateACUFromDB(proyecto *models.Proyecto, partidasCompletas []PartidaConRecursos) string {
 	var acuContent strings.Builder
-	
+
 	// Agregar proyecto
 	acuContent.WriteString(fmt.Sprintf("@proyecto{%s,\n", strings.ToLower(strings.ReplaceAll(proyecto.Nombre, " ", "_"))))
 	acuContent.WriteString(fmt.Sprintf("  nombre = \"%s\",\n", proyecto.Nombre))
 	if proyecto.Descripcion != nil && *proyecto.Descripcion != "" {
 		acuContent.WriteString(fmt.Sprintf("  descripcion = \"%s\",\n", *proyecto.Descripcion))
 	}
+	services.EscribirParametrosACU(&acuContent, h.parametrosDeclarados(proyecto))
 	acuContent.WriteString(fmt.Sprintf("  moneda = \"%s\"\n", proyecto.Moneda))
 	acuContent.WriteString("}\n\n")
-	
+
 	// Agregar partidas
 	for _, partida := range partidasCompletas {
 		partidaID := strings.ToLower(strings.ReplaceAll(partida.Codigo, ".", "_"))
 		partidaID = strings.ReplaceAll(partidaID, " ", "_")
-		
+
 		acuContent.WriteString(fmt.Sprintf("@partida{%s,\n", partidaID))
 		acuContent.WriteString(fmt.Sprintf("  codigo = \"%s\",\n", partida.Codigo))
 		acuContent.WriteString(fmt.Sprintf("  descripcion = \"%s\",\n", partida.Descripcion))
@@ -691,15 +1214,16 @@ func (h *ProyectoHandler) generateACUFromDB(proyecto *models.Proyecto, partidasC
 // generateEmptyACU genera código ACU vacío para un proyecto sin partidas
 func (h *ProyectoHandler) generateEmptyACU(proyecto *models.Proyecto) string {
 	var acuContent strings.Builder
-	
+
 	acuContent.WriteString(fmt.Sprintf("@proyecto{%s,\n", strings.ToLower(strings.ReplaceAll(proyecto.Nombre, " ", "_"))))
 	acuContent.WriteString(fmt.Sprintf("  nombre = \"%s\",\n", proyecto.Nombre))
 	if proyecto.Descripcion != nil && *proyecto.Descripcion != "" {
 		acuContent.WriteString(fmt.Sprintf("  descripcion = \"%s\",\n", *proyecto.Descripcion))
 	}
+	services.EscribirParametrosACU(&acuContent, h.parametrosDeclarados(proyecto))
 	acuContent.WriteString(fmt.Sprintf("  moneda = \"%s\"\n", proyecto.Moneda))
 	acuContent.WriteString("}\n\n")
-	
+
 	acuContent.WriteString("// Agrega tus partidas aquí\n")
 	acuContent.WriteString("// Ejemplo:\n")
 	acuContent.WriteString("// @partida{ejemplo,\n")
@@ -727,15 +1251,21 @@ func (h *ProyectoHandler) addRecursosSectionToACU(acuContent *strings.Builder, n
 	for _, recurso := range recursos {
 		acuContent.WriteString(fmt.Sprintf("    {codigo = \"%s\", desc = \"%s\", unidad = \"%s\", cantidad = %.4g, precio = %.2f",
 			recurso.Codigo, recurso.Descripcion, recurso.Unidad, recurso.Cantidad, recurso.Precio))
-		
+
 		// Agregar cuadrilla solo para mano de obra y si es mayor que 0
 		if nombreSeccion == "mano_obra" && recurso.Cuadrilla > 0 {
 			acuContent.WriteString(fmt.Sprintf(", cuadrilla = %.4g", recurso.Cuadrilla))
 		}
-		
+		if recurso.Moneda != "" {
+			acuContent.WriteString(fmt.Sprintf(", moneda = \"%s\"", recurso.Moneda))
+		}
+		if recurso.Desperdicio != nil {
+			acuContent.WriteString(fmt.Sprintf(", desperdicio = %s", recurso.Desperdicio))
+		}
+
 		acuContent.WriteString("},\n")
 	}
-	
+
 	acuContent.WriteString("  },\n")
 }
 
@@ -762,7 +1292,7 @@ func (h *ProyectoHandler) convertToLegacyFormat(partidas []models.PartidaRequest
 
 func (h *ProyectoHandler) convertRecursosToLegacy(recursos []models.RecursoRequest) []legacy.RecursoLegacy {
 	var result []legacy.RecursoLegacy
-	
+
 	for _, r := range recursos {
 		recursoLegacy := legacy.RecursoLegacy{
 			Codigo:      r.Codigo,
@@ -770,91 +1300,70 @@ func (h *ProyectoHandler) convertRecursosToLegacy(recursos []models.RecursoReque
 			Unidad:      r.Unidad,
 			Cantidad:    r.Cantidad,
 			Precio:      r.Precio,
+			Moneda:      strings.ToUpper(strings.TrimSpace(r.Moneda)),
 		}
-		
+		if r.Desperdicio != nil && r.Desperdicio.Signo() > 0 {
+			recursoLegacy.Desperdicio = r.Desperdicio
+		}
+
 		if r.Cuadrilla != nil {
 			recursoLegacy.Cuadrilla = *r.Cuadrilla
 		}
-		
+
 		result = append(result, recursoLegacy)
 	}
 	
 	return result
 }
 
-// generateExcelLegacy genera Excel usando el nuevo servicio jerárquico con datos de la BD
-func (h *ProyectoHandler) generateExcelLegacy(proyecto *models.Proyecto, proyectoUUID uuid.UUID) (string, error) {
-	log.Printf("🔄 Generando Excel jerárquico profesional para proyecto: %s", proyecto.Nombre)
+// parseOpcionesExportacion lee las opciones de presentación del Excel desde la query
+func parseOpcionesExportacion(r *http.Request) (models.OpcionesExportacion, error) {
+	var opciones models.OpcionesExportacion
 
-	// Usar el nuevo servicio jerárquico que obtiene datos directamente de la BD
-	filename, err := h.excelJerarquicoSvc.GenerarExcelJerarquico(proyecto, h.hierarchySvc)
-	if err != nil {
-		return "", fmt.Errorf("error generando Excel jerárquico: %w", err)
+	if valor := r.URL.Query().Get("nivel_colapsado"); valor != "" {
+		nivel, err := strconv.Atoi(valor)
+		if err != nil || nivel < 0 || nivel > models.MaxNivelEsquema+1 {
+			return opciones, fmt.Errorf("nivel_colapsado inválido: debe ser un entero entre 0 y %d", models.MaxNivelEsquema+1)
+		}
+		opciones.NivelColapsado = nivel
 	}
 
-	log.Printf("✅ Excel jerárquico generado exitosamente: %s", filename)
-	return filename, nil
-}
-
-// generateExcelFromOriginalJSON genera Excel usando el JSON original del frontend o BD jerárquica
-func (h *ProyectoHandler) generateExcelFromOriginalJSON(proyecto *models.Proyecto, projectID string) (string, error) {
-	// Buscar JSON original guardado
-	partidasLegacy, exists := originalJSONStore[projectID]
-	if exists && len(partidasLegacy) > 0 {
-		log.Printf("📋 Usando JSON original con %d partidas para generar Excel", len(partidasLegacy))
-		
-		// Generar nombre único para el archivo Excel temporal
-		filename := fmt.Sprintf("output/APU_Presupuesto_%s_%s.xlsx", 
-			proyecto.Nombre, 
-			time.Now().Format("20060102_150405"))
-
-		// Usar el generador legacy para crear Excel desde JSON original
-		err := legacy.GenerarExcel(partidasLegacy, filename)
-		if err != nil {
-			log.Printf("❌ Error generando Excel desde JSON legacy: %v", err)
-		} else {
-			log.Printf("✅ Excel generado exitosamente desde JSON original: %s", filename)
-			return filename, nil
+	porcentajes := map[string]*float64{
+		"gastos_generales": &opciones.GastosGenerales,
+		"utilidad":         &opciones.Utilidad,
+	}
+	for parametro, destino := range porcentajes {
+		valor := r.URL.Query().Get(parametro)
+		if valor == "" {
+			continue
+		}
+		porcentaje, err := strconv.ParseFloat(valor, 64)
+		if err != nil || porcentaje < 0 || porcentaje > 100 {
+			return opciones, fmt.Errorf("%s inválido: debe ser un porcentaje entre 0 y 100", parametro)
 		}
+		*destino = porcentaje
 	}
 
-	// Fallback: generar desde base de datos usando estructura simple
-	log.Printf("⚠️ JSON original no disponible para proyecto %s, generando desde base de datos", projectID)
-	return h.generateExcelFromDatabase(proyecto)
-}
-
-// generateExcelFromDatabase genera Excel convirtiendo datos de BD a formato legacy
-func (h *ProyectoHandler) generateExcelFromDatabase(proyecto *models.Proyecto) (string, error) {
-	log.Printf("🔄 Generando Excel desde base de datos para proyecto: %s", proyecto.Nombre)
-	
-	// Obtener partidas con recursos desde BD
-	partidasConRecursos, err := h.getPartidasConRecursos(proyecto.ID)
-	if err != nil {
-		return "", fmt.Errorf("error obteniendo partidas desde BD: %v", err)
+	// Formato de los CSV; "tab" permite indicar el tabulador en la URL
+	switch valor := r.URL.Query().Get("delimitador"); valor {
+	case "", ",", ";", "|":
+		opciones.DelimitadorCSV = valor
+	case "tab", "\t":
+		opciones.DelimitadorCSV = "\t"
+	default:
+		return opciones, fmt.Errorf("delimitador inválido: debe ser ',', ';', '|' o 'tab'")
 	}
-	
-	if len(partidasConRecursos) == 0 {
-		return "", fmt.Errorf("no se encontraron partidas en la base de datos para el proyecto")
+	switch valor := r.URL.Query().Get("separador_decimal"); valor {
+	case "", ".", ",":
+		opciones.SeparadorDecimal = valor
+	default:
+		return opciones, fmt.Errorf("separador_decimal inválido: debe ser '.' o ','")
 	}
-	
-	log.Printf("📊 Convirtiendo %d partidas de BD a formato legacy", len(partidasConRecursos))
-	
-	// Convertir a formato legacy
-	partidasLegacy := h.convertDatabaseToLegacy(partidasConRecursos)
-	
-	// Generar nombre único para el archivo Excel temporal
-	filename := fmt.Sprintf("output/APU_Presupuesto_%s_%s.xlsx", 
-		proyecto.Nombre, 
-		time.Now().Format("20060102_150405"))
-	
-	// Generar Excel usando el generador legacy
-	err = legacy.GenerarExcel(partidasLegacy, filename)
-	if err != nil {
-		return "", fmt.Errorf("error generando Excel desde datos de BD: %v", err)
+	if opciones.DelimitadorCSV == "," && opciones.SeparadorDecimal == "," {
+		return opciones, fmt.Errorf("el delimitador y el separador decimal no pueden ser ambos ','")
 	}
-	
-	log.Printf("✅ Excel generado exitosamente desde base de datos: %s", filename)
-	return filename, nil
+
+	return opciones, nil
 }
 
 // convertDatabaseToLegacy convierte datos de BD al formato PartidaLegacy
@@ -891,7 +1400,7 @@ func (h *ProyectoHandler) convertDatabaseToLegacy(partidasConRecursos []PartidaC
 // convertRecursosCompletosToLegacy convierte []RecursoCompleto a []legacy.RecursoLegacy
 func (h *ProyectoHandler) convertRecursosCompletosToLegacy(recursos []RecursoCompleto) []legacy.RecursoLegacy {
 	var recursosLegacy []legacy.RecursoLegacy
-	
+
 	for _, recurso := range recursos {
 		recursoLegacy := legacy.RecursoLegacy{
 			Codigo:      recurso.Codigo,
@@ -899,13 +1408,15 @@ func (h *ProyectoHandler) convertRecursosCompletosToLegacy(recursos []RecursoCom
 			Unidad:      recurso.Unidad,
 			Cantidad:    recurso.Cantidad,
 			Precio:      recurso.Precio,
+			Moneda:      recurso.Moneda,
+			Desperdicio: recurso.Desperdicio,
 		}
-		
+
 		// Agregar cuadrilla si existe
 		if recurso.Cuadrilla != nil && *recurso.Cuadrilla > 0 {
 			recursoLegacy.Cuadrilla = *recurso.Cuadrilla
 		}
-		
+
 		recursosLegacy = append(recursosLegacy, recursoLegacy)
 	}
 	
@@ -926,6 +1437,8 @@ func (h *ProyectoHandler) getPartidasConRecursos(proyectoUUID uuid.UUID) ([]Part
 					'cantidad', pr.cantidad,
 					'precio', pr.precio,
 					'cuadrilla', pr.cuadrilla,
+					'moneda', pr.moneda,
+					'desperdicio', pr.desperdicio,
 					'tipo', tr.nombre
 				) ORDER BY r.codigo
 			) FILTER (WHERE r.id IS NOT NULL), '[]') as recursos
@@ -950,7 +1463,7 @@ func (h *ProyectoHandler) getPartidasConRecursos(proyectoUUID uuid.UUID) ([]Part
 		var recursosJSON string
 
 		err := rows.Scan(
-			&partida.ID, &partida.Codigo, &partida.Descripcion, 
+			&partida.ID, &partida.Codigo, &partida.Descripcion,
 			&partida.Unidad, &partida.Rendimiento, &recursosJSON,
 		)
 		if err != nil {
@@ -958,8 +1471,11 @@ func (h *ProyectoHandler) getPartidasConRecursos(proyectoUUID uuid.UUID) ([]Part
 		}
 
 		// Parsear JSON de recursos
+		// Con UseNumber cantidades y precios llegan como texto exacto y no pasan por float64
 		var recursosData []map[string]interface{}
-		if err := json.Unmarshal([]byte(recursosJSON), &recursosData); err != nil {
+		decoder := json.NewDecoder(strings.NewReader(recursosJSON))
+		decoder.UseNumber()
+		if err := decoder.Decode(&recursosData); err != nil {
 			log.Printf("⚠️  Error parseando recursos JSON para partida %s: %v", partida.Codigo, err)
 			continue
 		}
@@ -979,12 +1495,22 @@ func (h *ProyectoHandler) getPartidasConRecursos(proyectoUUID uuid.UUID) ([]Part
 				Codigo:      recursoData["codigo"].(string),
 				Descripcion: recursoData["descripcion"].(string),
 				Unidad:      recursoData["unidad"].(string),
-				Cantidad:    recursoData["cantidad"].(float64),
-				Precio:      recursoData["precio"].(float64),
+				Cantidad:    decimalJSON(recursoData["cantidad"]),
+				Precio:      decimalJSON(recursoData["precio"]),
 			}
 
-			if cuadrilla, ok := recursoData["cuadrilla"].(float64); ok && cuadrilla > 0 {
-				recurso.Cuadrilla = &cuadrilla
+			if numero, ok := recursoData["cuadrilla"].(json.Number); ok {
+				if cuadrilla, err := numero.Float64(); err == nil && cuadrilla > 0 {
+					recurso.Cuadrilla = &cuadrilla
+				}
+			}
+			if moneda, ok := recursoData["moneda"].(string); ok {
+				recurso.Moneda = moneda
+			}
+			if recursoData["desperdicio"] != nil {
+				if desperdicio := decimalJSON(recursoData["desperdicio"]); desperdicio.Signo() > 0 {
+					recurso.Desperdicio = &desperdicio
+				}
 			}
 
 			tipo := recursoData["tipo"].(string)
@@ -1045,18 +1571,27 @@ type PartidaConRecursos struct {
 }
 
 type RecursoCompleto struct {
-	Codigo      string   `json:"codigo"`
-	Descripcion string   `json:"descripcion"`
-	Unidad      string   `json:"unidad"`
-	Cantidad    float64  `json:"cantidad"`
-	Precio      float64  `json:"precio"`
-	Cuadrilla   *float64 `json:"cuadrilla,omitempty"`
+	Codigo      string           `json:"codigo"`
+	Descripcion string           `json:"descripcion"`
+	Unidad      string           `json:"unidad"`
+	Cantidad    costing.Decimal  `json:"cantidad"`
+	Precio      costing.Decimal  `json:"precio"`
+	Cuadrilla   *float64         `json:"cuadrilla,omitempty"`
+	Moneda      string           `json:"moneda,omitempty"`
+	Desperdicio *costing.Decimal `json:"desperdicio,omitempty"`
+}
+
+// decimalJSON convierte un número leído con UseNumber en Decimal; lo que no es número vale cero
+func decimalJSON(valor interface{}) costing.Decimal {
+	numero, _ := valor.(json.Number)
+	decimal, _ := costing.ParsearDecimal(numero.String())
+	return decimal
 }
 
 // Helper functions for converting legacy data to response format
 func (h *ProyectoHandler) convertLegacyToResponse(partidasLegacy []legacy.PartidaLegacy) []models.PartidaResponse {
 	var partidasResponse []models.PartidaResponse
-	
+
 	for _, partida := range partidasLegacy {
 		partidaResponse := models.PartidaResponse{
 			ID:           uuid.New().String(), // Generate temporary ID for legacy data
@@ -1072,19 +1607,14 @@ func (h *ProyectoHandler) convertLegacyToResponse(partidasLegacy []legacy.Partid
 		}
 		partidasResponse = append(partidasResponse, partidaResponse)
 	}
-	
+
 	return partidasResponse
 }
 
 func (h *ProyectoHandler) convertLegacyRecursosToResponse(recursos []legacy.RecursoLegacy) []models.RecursoResponse {
 	var recursosResponse []models.RecursoResponse
-	
+
 	for _, recurso := range recursos {
-		cantidad := recurso.Cantidad
-		if recurso.Cuadrilla > 0 {
-			cantidad = recurso.Cuadrilla
-		}
-		
 		recursoResponse := models.RecursoResponse{
 			ID:          uuid.New().String(), // Generate temporary ID for legacy data
 			Codigo:      recurso.Codigo,
@@ -1092,13 +1622,15 @@ func (h *ProyectoHandler) convertLegacyRecursosToResponse(recursos []legacy.Recu
 			Unidad:      recurso.Unidad,
 			Cantidad:    recurso.Cantidad,
 			Precio:      recurso.Precio,
-			Parcial:     cantidad * recurso.Precio,
+			Moneda:      recurso.Moneda,
+			Desperdicio: recurso.Desperdicio,
+			Parcial:     costing.ParcialRecurso(recurso.CantidadEfectiva(costing.ReglasS10), recurso.Precio),
 		}
-		
+
 		if recurso.Cuadrilla > 0 {
 			recursoResponse.Cuadrilla = &recurso.Cuadrilla
 		}
-		
+
 		recursosResponse = append(recursosResponse, recursoResponse)
 	}
 	
@@ -1124,19 +1656,14 @@ func (h *ProyectoHandler) convertDBToResponse(partidasCompletas []PartidaConRecu
 		}
 		partidasResponse = append(partidasResponse, partidaResponse)
 	}
-	
+
 	return partidasResponse
 }
 
 func (h *ProyectoHandler) convertDBRecursosToResponse(recursos []RecursoCompleto) []models.RecursoResponse {
 	var recursosResponse []models.RecursoResponse
-	
+
 	for _, recurso := range recursos {
-		cantidad := recurso.Cantidad
-		if recurso.Cuadrilla != nil && *recurso.Cuadrilla > 0 {
-			cantidad = *recurso.Cuadrilla
-		}
-		
 		recursoResponse := models.RecursoResponse{
 			ID:          uuid.New().String(), // Generate temporary ID for DB data
 			Codigo:      recurso.Codigo,
@@ -1144,13 +1671,15 @@ func (h *ProyectoHandler) convertDBRecursosToResponse(recursos []RecursoCompleto
 			Unidad:      recurso.Unidad,
 			Cantidad:    recurso.Cantidad,
 			Precio:      recurso.Precio,
-			Parcial:     cantidad * recurso.Precio,
+			Moneda:      recurso.Moneda,
+			Desperdicio: recurso.Desperdicio,
+			Parcial:     costing.Subtotal(recursosCosto([]RecursoCompleto{recurso})),
 		}
-		
+
 		if recurso.Cuadrilla != nil && *recurso.Cuadrilla > 0 {
 			recursoResponse.Cuadrilla = recurso.Cuadrilla
 		}
-		
+
 		recursosResponse = append(recursosResponse, recursoResponse)
 	}
 	
@@ -1166,54 +1695,24 @@ func (h *ProyectoHandler) countTotalRecursos(partidasLegacy []legacy.PartidaLega
 	return total
 }
 
-func (h *ProyectoHandler) calculateTotalCosto(partidasLegacy []legacy.PartidaLegacy) float64 {
-	total := 0.0
+func (h *ProyectoHandler) calculateTotalCosto(partidasLegacy []legacy.PartidaLegacy) costing.Decimal {
+	total := costing.Decimal{}
 	for _, partida := range partidasLegacy {
-		total += h.calculatePartidaCosto(partida)
+		total = costing.Sumar(total, h.calculatePartidaCosto(partida))
 	}
 	return total
 }
 
-func (h *ProyectoHandler) calculatePartidaCosto(partida legacy.PartidaLegacy) float64 {
-	total := 0.0
-	
-	// Mano de obra
-	for _, recurso := range partida.ManoObra {
-		cantidad := recurso.Cantidad
-		if recurso.Cuadrilla > 0 {
-			cantidad = recurso.Cuadrilla
-		}
-		total += cantidad * recurso.Precio * partida.Rendimiento
-	}
-	
-	// Materiales
-	for _, recurso := range partida.Materiales {
-		total += recurso.Cantidad * recurso.Precio * partida.Rendimiento
-	}
-	
-	// Equipos
-	for _, recurso := range partida.Equipos {
-		cantidad := recurso.Cantidad
-		if recurso.Cuadrilla > 0 {
-			cantidad = recurso.Cuadrilla
-		}
-		total += cantidad * recurso.Precio * partida.Rendimiento
-	}
-	
-	// Subcontratos
-	for _, recurso := range partida.Subcontratos {
-		total += recurso.Cantidad * recurso.Precio * partida.Rendimiento
-	}
-	
-	return total
+func (h *ProyectoHandler) calculatePartidaCosto(partida legacy.PartidaLegacy) costing.Decimal {
+	return legacy.CostoPartida(partida, costing.ReglasS10)
 }
 
-func (h *ProyectoHandler) calculateCostoByType(partidasLegacy []legacy.PartidaLegacy, tipoRecurso string) float64 {
-	total := 0.0
-	
+func (h *ProyectoHandler) calculateCostoByType(partidasLegacy []legacy.PartidaLegacy, tipoRecurso string) costing.Decimal {
+	total := costing.Decimal{}
+
 	for _, partida := range partidasLegacy {
 		var recursos []legacy.RecursoLegacy
-		
+
 		switch tipoRecurso {
 		case "mano_obra":
 			recursos = partida.ManoObra
@@ -1224,16 +1723,10 @@ func (h *ProyectoHandler) calculateCostoByType(partidasLegacy []legacy.PartidaLe
 		case "subcontratos":
 			recursos = partida.Subcontratos
 		}
-		
-		for _, recurso := range recursos {
-			cantidad := recurso.Cantidad
-			if (tipoRecurso == "mano_obra" || tipoRecurso == "equipos") && recurso.Cuadrilla > 0 {
-				cantidad = recurso.Cuadrilla
-			}
-			total += cantidad * recurso.Precio * partida.Rendimiento
-		}
+
+		total = costing.Sumar(total, legacy.CostoRecursos(recursos, costing.ReglasS10))
 	}
-	
+
 	return total
 }
 
@@ -1246,54 +1739,29 @@ func (h *ProyectoHandler) countTotalRecursosFromDB(partidasCompletas []PartidaCo
 	return total
 }
 
-func (h *ProyectoHandler) calculateTotalCostoDB(partidasCompletas []PartidaConRecursos) float64 {
-	total := 0.0
+func (h *ProyectoHandler) calculateTotalCostoDB(partidasCompletas []PartidaConRecursos) costing.Decimal {
+	total := costing.Decimal{}
 	for _, partida := range partidasCompletas {
-		total += h.calculatePartidaCostoDB(partida)
+		total = costing.Sumar(total, h.calculatePartidaCostoDB(partida))
 	}
 	return total
 }
 
-func (h *ProyectoHandler) calculatePartidaCostoDB(partida PartidaConRecursos) float64 {
-	total := 0.0
-	
-	// Mano de obra
-	for _, recurso := range partida.ManoObra {
-		cantidad := recurso.Cantidad
-		if recurso.Cuadrilla != nil && *recurso.Cuadrilla > 0 {
-			cantidad = *recurso.Cuadrilla
-		}
-		total += cantidad * recurso.Precio * partida.Rendimiento
-	}
-	
-	// Materiales
-	for _, recurso := range partida.Materiales {
-		total += recurso.Cantidad * recurso.Precio * partida.Rendimiento
-	}
-	
-	// Equipos
-	for _, recurso := range partida.Equipos {
-		cantidad := recurso.Cantidad
-		if recurso.Cuadrilla != nil && *recurso.Cuadrilla > 0 {
-			cantidad = *recurso.Cuadrilla
-		}
-		total += cantidad * recurso.Precio * partida.Rendimiento
-	}
-	
-	// Subcontratos
-	for _, recurso := range partida.Subcontratos {
-		total += recurso.Cantidad * recurso.Precio * partida.Rendimiento
-	}
-	
-	return total
+func (h *ProyectoHandler) calculatePartidaCostoDB(partida PartidaConRecursos) costing.Decimal {
+	return costing.Sumar(
+		costing.Subtotal(recursosCosto(partida.ManoObra)),
+		costing.Subtotal(recursosCosto(partida.Materiales)),
+		costing.Subtotal(recursosCosto(partida.Equipos)),
+		costing.Subtotal(recursosCosto(partida.Subcontratos)),
+	)
 }
 
-func (h *ProyectoHandler) calculateCostoByTypeDB(partidasCompletas []PartidaConRecursos, tipoRecurso string) float64 {
-	total := 0.0
-	
+func (h *ProyectoHandler) calculateCostoByTypeDB(partidasCompletas []PartidaConRecursos, tipoRecurso string) costing.Decimal {
+	total := costing.Decimal{}
+
 	for _, partida := range partidasCompletas {
 		var recursos []RecursoCompleto
-		
+
 		switch tipoRecurso {
 		case "mano_obra":
 			recursos = partida.ManoObra
@@ -1305,17 +1773,24 @@ func (h *ProyectoHandler) calculateCostoByTypeDB(partidasCompletas []PartidaConR
 			recursos = partida.Subcontratos
 		}
 		
-		for _, recurso := range recursos {
-			cantidad := recurso.Cantidad
-			if (tipoRecurso == "mano_obra" || tipoRecurso == "equipos") && recurso.Cuadrilla != nil && *recurso.Cuadrilla > 0 {
-				cantidad = *recurso.Cuadrilla
-			}
-			total += cantidad * recurso.Precio * partida.Rendimiento
-		}
+		total = costing.Sumar(total, costing.Subtotal(recursosCosto(recursos)))
 	}
 	
 	return total
 }
+
+// recursosCosto toma de los recursos de la BD lo que interviene en el costo
+func recursosCosto(recursos []RecursoCompleto) []costing.Recurso {
+	costos := make([]costing.Recurso, len(recursos))
+	for i, recurso := range recursos {
+		costos[i] = costing.Recurso{Cantidad: recurso.Cantidad, Precio: recurso.Precio}
+		if recurso.Desperdicio != nil {
+			costos[i].Desperdicio = *recurso.Desperdicio
+		}
+	}
+	return costos
+}
+
 // GetProjectHierarchy returns the hierarchical structure of a project
 func (h *ProyectoHandler) GetProjectHierarchy(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
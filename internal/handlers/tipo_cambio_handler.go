@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"goexcel/internal/models"
+	"goexcel/internal/services"
+)
+
+// maxTamanoTiposCambio limita el tamaño del CSV de tipos de cambio subido
+const maxTamanoTiposCambio = 2 << 20 // 2 MB
+
+// TipoCambioHandler maneja la tabla de tipos de cambio. Bajo /organizations/{organizacion_id} se
+// gestionan las tasas de la organización; bajo /admin, las globales.
+type TipoCambioHandler struct {
+	tipoCambioSvc *services.TipoCambioService
+}
+
+// NewTipoCambioHandler crea una nueva instancia del handler de tipos de cambio
+func NewTipoCambioHandler(tipoCambioSvc *services.TipoCambioService) *TipoCambioHandler {
+	return &TipoCambioHandler{
+		tipoCambioSvc: tipoCambioSvc,
+	}
+}
+
+// ListarTiposCambio devuelve las tasas de la organización junto con las globales
+func (h *TipoCambioHandler) ListarTiposCambio(w http.ResponseWriter, r *http.Request) {
+	organizacionID, ok := organizacionOGlobal(w, r)
+	if !ok {
+		return
+	}
+
+	tipos, err := h.tipoCambioSvc.Listar(organizacionID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error obteniendo tipos de cambio: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.TiposCambioResponse{
+		Success: true,
+		Data:    tipos,
+	})
+}
+
+// CrearTipoCambio registra una tasa manual; reemplaza la del mismo par de monedas y fecha
+func (h *TipoCambioHandler) CrearTipoCambio(w http.ResponseWriter, r *http.Request) {
+	organizacionID, ok := organizacionOGlobal(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.TipoCambioRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Error decodificando JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	tc, err := h.tipoCambioSvc.Crear(req, organizacionID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	log.Printf("✅ Tipo de cambio %s→%s del %s registrado: %s", tc.MonedaOrigen, tc.MonedaDestino, tc.FechaVigencia, tc.Tasa)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(models.TiposCambioResponse{
+		Success: true,
+		Message: "Tipo de cambio registrado exitosamente",
+		Data:    []models.TipoCambio{*tc},
+	})
+}
+
+// ImportarTiposCambio carga las tasas de un CSV adjunto en el campo 'archivo'; si una línea es
+// inválida no se guarda ninguna
+func (h *TipoCambioHandler) ImportarTiposCambio(w http.ResponseWriter, r *http.Request) {
+	organizacionID, ok := organizacionOGlobal(w, r)
+	if !ok {
+		return
+	}
+
+	if err := r.ParseMultipartForm(maxTamanoTiposCambio); err != nil {
+		http.Error(w, fmt.Sprintf("Error leyendo formulario: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	archivo, _, err := r.FormFile("archivo")
+	if err != nil {
+		http.Error(w, "Debe adjuntar el CSV de tipos de cambio en el campo 'archivo'", http.StatusBadRequest)
+		return
+	}
+	defer archivo.Close()
+
+	tipos, err := h.tipoCambioSvc.ImportarCSV(archivo, organizacionID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	log.Printf("📥 %d tipos de cambio importados", len(tipos))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.TiposCambioResponse{
+		Success:    true,
+		Message:    "Tipos de cambio importados exitosamente",
+		Data:       tipos,
+		Importados: len(tipos),
+	})
+}
+
+// EliminarTipoCambio borra una tasa
+func (h *TipoCambioHandler) EliminarTipoCambio(w http.ResponseWriter, r *http.Request) {
+	organizacionID, ok := organizacionOGlobal(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "ID de tipo de cambio inválido", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.tipoCambioSvc.Eliminar(id, organizacionID); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.TiposCambioResponse{
+		Success: true,
+		Message: "Tipo de cambio eliminado exitosamente",
+		Data:    []models.TipoCambio{},
+	})
+}
+
+// organizacionOGlobal devuelve la organización de la ruta, ya autorizada, o nil en las rutas de
+// administración, que gestionan los datos globales (tipos de cambio, parámetros de mano de obra)
+func organizacionOGlobal(w http.ResponseWriter, r *http.Request) (*uuid.UUID, bool) {
+	if _, enRuta := mux.Vars(r)["organizacion_id"]; !enRuta {
+		return nil, true
+	}
+	organizacionID, ok := autorizarOrganizacion(w, r)
+	if !ok {
+		return nil, false
+	}
+	return &organizacionID, true
+}
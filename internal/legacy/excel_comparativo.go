@@ -0,0 +1,288 @@
+package legacy
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/xuri/excelize/v2"
+	"goexcel/internal/costing"
+	"goexcel/internal/models"
+)
+
+// Hojas del libro comparativo
+const (
+	HojaComparativo = "Comparativo"
+	HojaNoComunes   = "No comunes"
+)
+
+// ConstruirExcelComparativo genera el libro comparativo: la hoja "Comparativo" con las partidas comunes
+// alineadas por código y los subtotales de cada título, y la hoja "No comunes" con las partidas que
+// solo están en uno de los presupuestos. La diferencia y la variación son fórmulas sobre los parciales,
+// y la variación que supera el umbral se resalta con formato condicional.
+func ConstruirExcelComparativo(comparativo *models.ComparativoPresupuesto) (*excelize.File, error) {
+	f := excelize.NewFile()
+	f.SetSheetName("Sheet1", HojaComparativo)
+	plantilla := ResolverPlantilla(comparativo.Opciones.Plantilla)
+	estilos := nuevosEstilosComparativo(f, plantilla)
+
+	if err := agregarHojaComparativo(f, comparativo, plantilla, estilos); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := agregarHojaNoComunes(f, comparativo, plantilla, estilos); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// estilosComparativo son los estilos compartidos por las dos hojas del comparativo
+type estilosComparativo struct {
+	titulo, cabecera, dato, numero, porcentaje int
+	grupo, grupoNumero, grupoPorcentaje        int
+	total, totalPorcentaje                     int
+	desviacion                                 int // relleno del formato condicional
+}
+
+func nuevosEstilosComparativo(f *excelize.File, plantilla models.PlantillaExcel) estilosComparativo {
+	tamanoDatos := TamanoDatos(plantilla, 10)
+	bordes := []excelize.Border{
+		{Type: "left", Color: "#000000", Style: 1},
+		{Type: "right", Color: "#000000", Style: 1},
+		{Type: "top", Color: "#000000", Style: 1},
+		{Type: "bottom", Color: "#000000", Style: 1},
+	}
+	estilo := func(fuente excelize.Font, relleno, horizontal string, numero bool, porcentaje bool) int {
+		fuente.Family = plantilla.Fuente
+		s := &excelize.Style{
+			Font:      &fuente,
+			Alignment: &excelize.Alignment{Horizontal: horizontal, Vertical: "center", WrapText: horizontal == "center"},
+			Border:    bordes,
+		}
+		if relleno != "" {
+			s.Fill = excelize.Fill{Type: "pattern", Color: []string{relleno}, Pattern: 1}
+		}
+		if numero {
+			s.CustomNumFmt = &plantilla.FormatoNumero
+		}
+		if porcentaje {
+			s.NumFmt = 10 // 0.00%
+		}
+		id, _ := f.NewStyle(s)
+		return id
+	}
+
+	blanca := excelize.Font{Bold: true, Size: 11, Color: "#FFFFFF"}
+	negrita := excelize.Font{Bold: true, Size: tamanoDatos}
+	normal := excelize.Font{Size: tamanoDatos}
+
+	estilos := estilosComparativo{
+		titulo:          estilo(excelize.Font{Bold: true, Size: 14, Color: "#FFFFFF"}, plantilla.ColorTitulo, "center", false, false),
+		cabecera:        estilo(blanca, plantilla.ColorCabecera, "center", false, false),
+		dato:            estilo(normal, "", "left", false, false),
+		numero:          estilo(normal, "", "right", true, false),
+		porcentaje:      estilo(normal, "", "right", false, true),
+		grupo:           estilo(negrita, plantilla.ColorSeccion, "left", false, false),
+		grupoNumero:     estilo(negrita, plantilla.ColorSeccion, "right", true, false),
+		grupoPorcentaje: estilo(negrita, plantilla.ColorSeccion, "right", false, true),
+		total:           estilo(blanca, plantilla.ColorTotal, "right", true, false),
+		totalPorcentaje: estilo(blanca, plantilla.ColorTotal, "right", false, true),
+	}
+	estilos.desviacion, _ = f.NewConditionalStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true, Color: "#9C0006"},
+		Fill: excelize.Fill{Type: "pattern", Color: []string{"#FFC7CE"}, Pattern: 1},
+	})
+	return estilos
+}
+
+func agregarHojaComparativo(f *excelize.File, comparativo *models.ComparativoPresupuesto, plantilla models.PlantillaExcel, estilos estilosComparativo) error {
+	hoja := HojaComparativo
+
+	escritor, err := NuevoEscritorHoja(f, hoja, comparativo.Opciones.NivelColapsado)
+	if err != nil {
+		return err
+	}
+	escritor.AnchoColumnas([]float64{12, 45, 7, 12, 13, 15, 12, 13, 15, 15, 10}, plantilla)
+	escritor.Congelar(3)
+
+	escritor.Combinar("A1", "K1")
+	escritor.Fila(1, 0, FilaCombinada("CUADRO COMPARATIVO DE PRESUPUESTOS", estilos.titulo, 11)...)
+
+	// Cabecera en dos filas: el nombre de cada presupuesto sobre sus tres columnas
+	grupos := []interface{}{
+		Celda("", estilos.cabecera), Celda("", estilos.cabecera), Celda("", estilos.cabecera),
+		Celda(comparativo.Base, estilos.cabecera), Celda(nil, estilos.cabecera), Celda(nil, estilos.cabecera),
+		Celda(comparativo.Comparado, estilos.cabecera), Celda(nil, estilos.cabecera), Celda(nil, estilos.cabecera),
+		Celda("Diferencia", estilos.cabecera), Celda(nil, estilos.cabecera),
+	}
+	escritor.Combinar("D2", "F2")
+	escritor.Combinar("G2", "I2")
+	escritor.Combinar("J2", "K2")
+	escritor.Fila(2, 0, grupos...)
+
+	moneda := comparativo.Opciones.ParametrosCalculo().SimboloMoneda()
+	headers := []string{"Ítem", "Descripción", "Und.", "Metrado", "Precio " + moneda, "Parcial " + moneda,
+		"Metrado", "Precio " + moneda, "Parcial " + moneda, "Parcial " + moneda, "Var. %"}
+	cabeceras := make([]interface{}, len(headers))
+	for i, header := range headers {
+		cabeceras[i] = Celda(header, estilos.cabecera)
+	}
+	escritor.Fila(3, 0, cabeceras...)
+
+	row := 4
+	for _, fila := range comparativo.Filas {
+		nivel := fila.Nivel - 1
+		if fila.EsTitulo {
+			escritor.Fila(row, nivel,
+				Celda(fila.Codigo, estilos.grupo),
+				Celda(fila.Descripcion, estilos.grupo),
+				Celda(nil, estilos.grupo),
+				Celda(nil, estilos.grupo),
+				Celda(nil, estilos.grupo),
+				Celda(fila.Base.Parcial, estilos.grupoNumero),
+				Celda(nil, estilos.grupo),
+				Celda(nil, estilos.grupo),
+				Celda(fila.Comparado.Parcial, estilos.grupoNumero),
+				celdaDiferencia(row, fila, estilos.grupoNumero),
+				celdaVariacion(row, fila, estilos.grupoPorcentaje),
+			)
+		} else {
+			escritor.Fila(row, nivel,
+				Celda(fila.Codigo, estilos.dato),
+				Celda(fila.Descripcion, estilos.dato),
+				Celda(fila.Unidad, estilos.dato),
+				Celda(fila.Base.Metrado, estilos.numero),
+				Celda(fila.Base.CostoUnitario, estilos.numero),
+				Celda(fila.Base.Parcial, estilos.numero),
+				Celda(fila.Comparado.Metrado, estilos.numero),
+				Celda(fila.Comparado.CostoUnitario, estilos.numero),
+				Celda(fila.Comparado.Parcial, estilos.numero),
+				celdaDiferencia(row, fila, estilos.numero),
+				celdaVariacion(row, fila, estilos.porcentaje),
+			)
+		}
+		row++
+	}
+	ultimaFila := row - 1
+
+	// Costo directo completo de cada presupuesto, con las partidas no comunes
+	row++
+	total := models.FilaComparativo{
+		Base:      models.ValoresComparativo{Parcial: comparativo.TotalBase},
+		Comparado: models.ValoresComparativo{Parcial: comparativo.TotalComparado},
+	}
+	escritor.Combinar(fmt.Sprintf("A%d", row), fmt.Sprintf("E%d", row))
+	celdas := FilaCombinada("COSTO DIRECTO", estilos.total, 11)
+	celdas[5] = Celda(total.Base.Parcial, estilos.total)
+	celdas[8] = Celda(total.Comparado.Parcial, estilos.total)
+	celdas[9] = celdaDiferencia(row, total, estilos.total)
+	celdas[10] = celdaVariacion(row, total, estilos.totalPorcentaje)
+	escritor.Fila(row, 0, celdas...)
+
+	if ultimaFila >= 4 {
+		umbral := strconv.FormatFloat(comparativo.Umbral/100, 'f', -1, 64)
+		err := f.SetConditionalFormat(hoja, fmt.Sprintf("J4:K%d", ultimaFila), []excelize.ConditionalFormatOptions{{
+			Type:     "formula",
+			Criteria: fmt.Sprintf("AND(ISNUMBER($K4),ABS($K4)>%s)", umbral),
+			Format:   &estilos.desviacion,
+		}})
+		if err != nil {
+			return fmt.Errorf("error aplicando formato condicional: %v", err)
+		}
+	}
+
+	return prepararHojaStream(f, hoja, escritor, plantilla, ConfigImpresion{
+		Horizontal:    true,
+		FilasTitulo:   3,
+		UltimaColumna: "K",
+		UltimaFila:    row,
+		Proyecto:      comparativo.Base + " vs " + comparativo.Comparado,
+		Fecha:         comparativo.Fecha,
+	})
+}
+
+// celdaDiferencia es la fórmula comparado - base; lleva el valor calculado para los visores que no recalculan
+func celdaDiferencia(row int, fila models.FilaComparativo, estilo int) excelize.Cell {
+	return excelize.Cell{StyleID: estilo, Formula: fmt.Sprintf("I%d-F%d", row, row), Value: fila.Diferencia()}
+}
+
+// celdaVariacion es la diferencia sobre el parcial base; vacía si el base no tiene parcial
+func celdaVariacion(row int, fila models.FilaComparativo, estilo int) excelize.Cell {
+	var valor interface{} = ""
+	if !fila.Base.Parcial.EsCero() {
+		valor = fila.Variacion() / 100
+	}
+	return excelize.Cell{StyleID: estilo, Formula: fmt.Sprintf(`IF(F%d=0,"",J%d/F%d)`, row, row, row), Value: valor}
+}
+
+func agregarHojaNoComunes(f *excelize.File, comparativo *models.ComparativoPresupuesto, plantilla models.PlantillaExcel, estilos estilosComparativo) error {
+	hoja := HojaNoComunes
+	if _, err := f.NewSheet(hoja); err != nil {
+		return fmt.Errorf("error creando hoja de partidas no comunes: %v", err)
+	}
+
+	escritor, err := NuevoEscritorHoja(f, hoja, 0)
+	if err != nil {
+		return err
+	}
+	escritor.AnchoColumnas([]float64{12, 50, 7, 12, 13, 15}, plantilla)
+
+	escritor.Combinar("A1", "F1")
+	escritor.Fila(1, 0, FilaCombinada("PARTIDAS NO COMUNES", estilos.titulo, 6)...)
+
+	parametros := comparativo.Opciones.ParametrosCalculo()
+	row := 3
+	secciones := []struct {
+		nombre   string
+		partidas []*models.PartidaReporte
+	}{
+		{"SOLO EN " + comparativo.Base, comparativo.SoloBase},
+		{"SOLO EN " + comparativo.Comparado, comparativo.SoloComparado},
+	}
+	for _, seccion := range secciones {
+		escritor.Combinar(fmt.Sprintf("A%d", row), fmt.Sprintf("F%d", row))
+		escritor.Fila(row, 0, FilaCombinada(seccion.nombre, estilos.grupo, 6)...)
+		row++
+
+		headers := []string{"Ítem", "Descripción", "Und.", "Metrado", "Precio " + parametros.SimboloMoneda(), "Parcial " + parametros.SimboloMoneda()}
+		cabeceras := make([]interface{}, len(headers))
+		for i, header := range headers {
+			cabeceras[i] = Celda(header, estilos.cabecera)
+		}
+		escritor.Fila(row, 0, cabeceras...)
+		row++
+
+		subtotal := costing.Decimal{}
+		for _, partida := range seccion.partidas {
+			escritor.Fila(row, 0,
+				Celda(partida.Codigo, estilos.dato),
+				Celda(partida.Descripcion, estilos.dato),
+				Celda(partida.Unidad, estilos.dato),
+				Celda(partida.Metrado, estilos.numero),
+				Celda(partida.CostoUnitario, estilos.numero),
+				Celda(partida.Parcial, estilos.numero),
+			)
+			subtotal = parametros.Reglas().Sumar(subtotal, partida.Parcial)
+			row++
+		}
+		if len(seccion.partidas) == 0 {
+			escritor.Combinar(fmt.Sprintf("A%d", row), fmt.Sprintf("F%d", row))
+			escritor.Fila(row, 0, FilaCombinada("Ninguna", estilos.dato, 6)...)
+			row++
+		}
+
+		escritor.Combinar(fmt.Sprintf("A%d", row), fmt.Sprintf("E%d", row))
+		celdas := FilaCombinada("SUBTOTAL", estilos.total, 6)
+		celdas[5] = Celda(subtotal, estilos.total)
+		escritor.Fila(row, 0, celdas...)
+		row += 2
+	}
+
+	return prepararHojaStream(f, hoja, escritor, plantilla, ConfigImpresion{
+		FilasTitulo:   1,
+		UltimaColumna: "F",
+		UltimaFila:    row - 2,
+		Proyecto:      comparativo.Base + " vs " + comparativo.Comparado,
+		Fecha:         comparativo.Fecha,
+	})
+}
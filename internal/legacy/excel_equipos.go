@@ -0,0 +1,182 @@
+package legacy
+
+import (
+	"fmt"
+
+	"github.com/xuri/excelize/v2"
+	"goexcel/internal/costing"
+	"goexcel/internal/models"
+)
+
+// HojaEquipos es la hoja con el análisis de costo horario de los equipos del presupuesto
+const HojaEquipos = "Costo Horario de Equipos"
+
+// AgregarHojaEquipos agrega la hoja con el costo por hora-máquina de cada equipo: un bloque por
+// equipo con el costo de posesión, el de operación y el costo horario
+func AgregarHojaEquipos(f *excelize.File, costos []models.CostoHorarioEquipo, opciones models.OpcionesExportacion) error {
+	if _, err := f.NewSheet(HojaEquipos); err != nil {
+		return fmt.Errorf("error creando hoja de equipos: %v", err)
+	}
+	plantilla := ResolverPlantilla(opciones.Plantilla)
+	tamanoDatos := TamanoDatos(plantilla, 10)
+	simbolo := opciones.ParametrosCalculo().SimboloMoneda()
+
+	bordes := []excelize.Border{
+		{Type: "left", Color: "#000000", Style: 1},
+		{Type: "right", Color: "#000000", Style: 1},
+		{Type: "top", Color: "#000000", Style: 1},
+		{Type: "bottom", Color: "#000000", Style: 1},
+	}
+
+	titleStyle, _ := f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Bold: true, Size: 14, Color: "#FFFFFF", Family: plantilla.Fuente},
+		Fill:      excelize.Fill{Type: "pattern", Color: []string{plantilla.ColorTitulo}, Pattern: 1},
+		Alignment: &excelize.Alignment{Horizontal: "center", Vertical: "center"},
+	})
+	equipoStyle, _ := f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Bold: true, Size: 11, Color: "#FFFFFF", Family: plantilla.Fuente},
+		Fill:      excelize.Fill{Type: "pattern", Color: []string{plantilla.ColorCabecera}, Pattern: 1},
+		Alignment: &excelize.Alignment{Horizontal: "left", Vertical: "center"},
+		Border:    bordes,
+	})
+	seccionStyle, _ := f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Bold: true, Size: tamanoDatos, Family: plantilla.Fuente},
+		Fill:      excelize.Fill{Type: "pattern", Color: []string{plantilla.ColorSeccion}, Pattern: 1},
+		Alignment: &excelize.Alignment{Horizontal: "left", Vertical: "center"},
+		Border:    bordes,
+	})
+	seccionNumeroStyle, _ := f.NewStyle(&excelize.Style{
+		Font:         &excelize.Font{Bold: true, Size: tamanoDatos, Family: plantilla.Fuente},
+		Fill:         excelize.Fill{Type: "pattern", Color: []string{plantilla.ColorSeccion}, Pattern: 1},
+		CustomNumFmt: &plantilla.FormatoNumero,
+		Alignment:    &excelize.Alignment{Horizontal: "right", Vertical: "center"},
+		Border:       bordes,
+	})
+	dataStyle, _ := f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Size: tamanoDatos, Family: plantilla.Fuente},
+		Alignment: &excelize.Alignment{Horizontal: "left", Vertical: "center"},
+		Border:    bordes,
+	})
+	numberStyle, _ := f.NewStyle(&excelize.Style{
+		Font:         &excelize.Font{Size: tamanoDatos, Family: plantilla.Fuente},
+		CustomNumFmt: &plantilla.FormatoNumero,
+		Alignment:    &excelize.Alignment{Horizontal: "right", Vertical: "center"},
+		Border:       bordes,
+	})
+	totalStyle, _ := f.NewStyle(&excelize.Style{
+		Font:         &excelize.Font{Bold: true, Size: 11, Color: "#FFFFFF", Family: plantilla.Fuente},
+		Fill:         excelize.Fill{Type: "pattern", Color: []string{plantilla.ColorTotal}, Pattern: 1},
+		CustomNumFmt: &plantilla.FormatoNumero,
+		Alignment:    &excelize.Alignment{Horizontal: "right", Vertical: "center"},
+		Border:       bordes,
+	})
+	totalTextoStyle, _ := f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Bold: true, Size: 11, Color: "#FFFFFF", Family: plantilla.Fuente},
+		Fill:      excelize.Fill{Type: "pattern", Color: []string{plantilla.ColorTotal}, Pattern: 1},
+		Alignment: &excelize.Alignment{Horizontal: "left", Vertical: "center"},
+		Border:    bordes,
+	})
+	notaStyle, _ := f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Italic: true, Size: 9, Family: plantilla.Fuente},
+		Alignment: &excelize.Alignment{Horizontal: "left", Vertical: "top", WrapText: true},
+	})
+
+	f.SetColWidth(HojaEquipos, "A", "A", 40)
+	f.SetColWidth(HojaEquipos, "B", "D", 14)
+	f.SetColWidth(HojaEquipos, "E", "E", 16)
+
+	f.MergeCell(HojaEquipos, "A1", "E1")
+	f.SetCellValue(HojaEquipos, "A1", "ANÁLISIS DE COSTO HORARIO DE EQUIPOS")
+	f.SetCellStyle(HojaEquipos, "A1", "E1", titleStyle)
+	f.MergeCell(HojaEquipos, "A2", "E2")
+	f.SetCellValue(HojaEquipos, "A2", fmt.Sprintf("Moneda: %s   Costos por hora-máquina (hm)", simbolo))
+
+	// concepto escribe una fila del desglose con su monto en la columna E
+	concepto := func(row int, rotulo string, monto costing.Decimal) {
+		f.SetCellValue(HojaEquipos, fmt.Sprintf("A%d", row), rotulo)
+		f.SetCellStyle(HojaEquipos, fmt.Sprintf("A%d", row), fmt.Sprintf("D%d", row), dataStyle)
+		f.SetCellValue(HojaEquipos, fmt.Sprintf("E%d", row), monto.Float64())
+		f.SetCellStyle(HojaEquipos, fmt.Sprintf("E%d", row), fmt.Sprintf("E%d", row), numberStyle)
+	}
+	// consumo escribe un insumo con su cantidad, precio y parcial
+	consumo := func(row int, rotulo, unidad string, c models.ConsumoEquipo) {
+		if c.Codigo != "" {
+			rotulo += " (" + c.Codigo + ")"
+		}
+		f.SetCellValue(HojaEquipos, fmt.Sprintf("A%d", row), rotulo)
+		f.SetCellValue(HojaEquipos, fmt.Sprintf("B%d", row), unidad)
+		f.SetCellStyle(HojaEquipos, fmt.Sprintf("A%d", row), fmt.Sprintf("B%d", row), dataStyle)
+		f.SetCellValue(HojaEquipos, fmt.Sprintf("C%d", row), c.Cantidad.Float64())
+		f.SetCellValue(HojaEquipos, fmt.Sprintf("D%d", row), c.Precio.Float64())
+		f.SetCellValue(HojaEquipos, fmt.Sprintf("E%d", row), c.Parcial.Float64())
+		f.SetCellStyle(HojaEquipos, fmt.Sprintf("C%d", row), fmt.Sprintf("E%d", row), numberStyle)
+	}
+	// subtotal escribe el rótulo de una sección con su monto
+	subtotal := func(row int, rotulo string, monto costing.Decimal) {
+		f.MergeCell(HojaEquipos, fmt.Sprintf("A%d", row), fmt.Sprintf("D%d", row))
+		f.SetCellValue(HojaEquipos, fmt.Sprintf("A%d", row), rotulo)
+		f.SetCellStyle(HojaEquipos, fmt.Sprintf("A%d", row), fmt.Sprintf("D%d", row), seccionStyle)
+		f.SetCellValue(HojaEquipos, fmt.Sprintf("E%d", row), monto.Float64())
+		f.SetCellStyle(HojaEquipos, fmt.Sprintf("E%d", row), fmt.Sprintf("E%d", row), seccionNumeroStyle)
+	}
+
+	row := 4
+	for _, costo := range costos {
+		// Cabecera del equipo y datos generales
+		f.MergeCell(HojaEquipos, fmt.Sprintf("A%d", row), fmt.Sprintf("E%d", row))
+		cabecera := fmt.Sprintf("%s - %s", costo.CodigoRecurso, costo.Descripcion)
+		if costo.PotenciaHP != nil {
+			cabecera += fmt.Sprintf(" (%s HP)", costo.PotenciaHP)
+		}
+		f.SetCellValue(HojaEquipos, fmt.Sprintf("A%d", row), cabecera)
+		f.SetCellStyle(HojaEquipos, fmt.Sprintf("A%d", row), fmt.Sprintf("E%d", row), equipoStyle)
+		row++
+
+		concepto(row, "Valor de adquisición (Va)", costo.ValorAdquisicion)
+		row++
+		concepto(row, "Valor de rescate (Vr)", costo.ValorRescate)
+		row++
+		concepto(row, fmt.Sprintf("Vida económica: %s años (horas)", costo.VidaEconomicaAnios), costo.VidaEconomicaHoras)
+		row++
+		concepto(row, "Inversión media: (Va × (N + 1) + Vr × (N - 1)) / 2N", costo.InversionMedia)
+		row++
+
+		subtotal(row, "COSTO DE POSESIÓN", costo.CostoPosesion)
+		row++
+		concepto(row, "Depreciación", costo.Depreciacion)
+		row++
+		concepto(row, "Intereses", costo.Intereses)
+		row++
+		concepto(row, "Seguros, impuestos y almacenaje", costo.Seguros)
+		row++
+
+		subtotal(row, "COSTO DE OPERACIÓN", costo.CostoOperacion)
+		row++
+		concepto(row, "Mantenimiento y reparaciones", costo.Mantenimiento)
+		row++
+		consumo(row, "Combustible", "gal", costo.Combustible)
+		row++
+		consumo(row, "Lubricante", "gal", costo.Lubricante)
+		row++
+		concepto(row, "Neumáticos", costo.Neumaticos)
+		row++
+		consumo(row, "Operador", "hh", costo.Operador)
+		row++
+
+		f.MergeCell(HojaEquipos, fmt.Sprintf("A%d", row), fmt.Sprintf("D%d", row))
+		f.SetCellValue(HojaEquipos, fmt.Sprintf("A%d", row), fmt.Sprintf("COSTO HORARIO (%s por hm)", simbolo))
+		f.SetCellStyle(HojaEquipos, fmt.Sprintf("A%d", row), fmt.Sprintf("D%d", row), totalTextoStyle)
+		f.SetCellValue(HojaEquipos, fmt.Sprintf("E%d", row), costo.CostoHorario.Float64())
+		f.SetCellStyle(HojaEquipos, fmt.Sprintf("E%d", row), fmt.Sprintf("E%d", row), totalStyle)
+		row += 2
+	}
+
+	f.MergeCell(HojaEquipos, fmt.Sprintf("A%d", row), fmt.Sprintf("E%d", row))
+	f.SetCellValue(HojaEquipos, fmt.Sprintf("A%d", row),
+		"Depreciación = (Va - Vr) / vida económica en horas; intereses y seguros se aplican a la inversión media "+
+			"por hora trabajada al año; mantenimiento como porcentaje de la depreciación.")
+	f.SetCellStyle(HojaEquipos, fmt.Sprintf("A%d", row), fmt.Sprintf("E%d", row), notaStyle)
+	f.SetRowHeight(HojaEquipos, row, 30)
+
+	return nil
+}
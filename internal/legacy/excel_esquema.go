@@ -0,0 +1,13 @@
+package legacy
+
+import (
+	"github.com/xuri/excelize/v2"
+)
+
+// ConfigurarEsquema deja las filas resumen sobre su detalle, como se lee un presupuesto
+func ConfigurarEsquema(f *excelize.File, sheet string) error {
+	resumenDebajo := false
+	return f.SetSheetProps(sheet, &excelize.SheetPropsOptions{
+		OutlineSummaryBelow: &resumenDebajo,
+	})
+}
@@ -0,0 +1,151 @@
+package legacy
+
+import (
+	"fmt"
+
+	"github.com/xuri/excelize/v2"
+	"goexcel/internal/models"
+)
+
+// HojaFlete es la hoja con el cálculo del flete de los materiales del presupuesto
+const HojaFlete = "Cálculo de Flete"
+
+// AgregarHojaFlete agrega la hoja con los datos de la ruta y, por material, la cantidad del
+// presupuesto, su peso y su flete
+func AgregarHojaFlete(f *excelize.File, calculo *models.CalculoFlete, opciones models.OpcionesExportacion) error {
+	if _, err := f.NewSheet(HojaFlete); err != nil {
+		return fmt.Errorf("error creando hoja de flete: %v", err)
+	}
+	plantilla := ResolverPlantilla(opciones.Plantilla)
+	tamanoDatos := TamanoDatos(plantilla, 10)
+	simbolo := opciones.ParametrosCalculo().SimboloMoneda()
+
+	bordes := []excelize.Border{
+		{Type: "left", Color: "#000000", Style: 1},
+		{Type: "right", Color: "#000000", Style: 1},
+		{Type: "top", Color: "#000000", Style: 1},
+		{Type: "bottom", Color: "#000000", Style: 1},
+	}
+
+	titleStyle, _ := f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Bold: true, Size: 14, Color: "#FFFFFF", Family: plantilla.Fuente},
+		Fill:      excelize.Fill{Type: "pattern", Color: []string{plantilla.ColorTitulo}, Pattern: 1},
+		Alignment: &excelize.Alignment{Horizontal: "center", Vertical: "center"},
+	})
+	headerStyle, _ := f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Bold: true, Size: 11, Color: "#FFFFFF", Family: plantilla.Fuente},
+		Fill:      excelize.Fill{Type: "pattern", Color: []string{plantilla.ColorCabecera}, Pattern: 1},
+		Alignment: &excelize.Alignment{Horizontal: "center", Vertical: "center", WrapText: true},
+		Border:    bordes,
+	})
+	dataStyle, _ := f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Size: tamanoDatos, Family: plantilla.Fuente},
+		Alignment: &excelize.Alignment{Horizontal: "left", Vertical: "center"},
+		Border:    bordes,
+	})
+	numberStyle, _ := f.NewStyle(&excelize.Style{
+		Font:         &excelize.Font{Size: tamanoDatos, Family: plantilla.Fuente},
+		CustomNumFmt: &plantilla.FormatoNumero,
+		Alignment:    &excelize.Alignment{Horizontal: "right", Vertical: "center"},
+		Border:       bordes,
+	})
+	totalStyle, _ := f.NewStyle(&excelize.Style{
+		Font:         &excelize.Font{Bold: true, Size: 11, Color: "#FFFFFF", Family: plantilla.Fuente},
+		Fill:         excelize.Fill{Type: "pattern", Color: []string{plantilla.ColorTotal}, Pattern: 1},
+		CustomNumFmt: &plantilla.FormatoNumero,
+		Alignment:    &excelize.Alignment{Horizontal: "right", Vertical: "center"},
+		Border:       bordes,
+	})
+	totalTextoStyle, _ := f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Bold: true, Size: 11, Color: "#FFFFFF", Family: plantilla.Fuente},
+		Fill:      excelize.Fill{Type: "pattern", Color: []string{plantilla.ColorTotal}, Pattern: 1},
+		Alignment: &excelize.Alignment{Horizontal: "left", Vertical: "center"},
+		Border:    bordes,
+	})
+	notaStyle, _ := f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Italic: true, Size: 9, Family: plantilla.Fuente},
+		Alignment: &excelize.Alignment{Horizontal: "left", Vertical: "top", WrapText: true},
+	})
+
+	f.SetColWidth(HojaFlete, "A", "A", 14)
+	f.SetColWidth(HojaFlete, "B", "B", 40)
+	f.SetColWidth(HojaFlete, "C", "C", 8)
+	f.SetColWidth(HojaFlete, "D", "H", 14)
+
+	f.MergeCell(HojaFlete, "A1", "H1")
+	f.SetCellValue(HojaFlete, "A1", "CÁLCULO DE FLETE TERRESTRE")
+	f.SetCellStyle(HojaFlete, "A1", "H1", titleStyle)
+
+	// Datos de la ruta
+	ruta := calculo.Ruta
+	modo := "Flete sumado al precio de cada material"
+	if calculo.Modo == models.ModoFleteSubcontrato {
+		modo = "Flete como subcontrato en el APU de cada partida"
+	}
+	datosRuta := []struct {
+		rotulo string
+		valor  interface{}
+	}{
+		{"Ruta", fmt.Sprintf("%s (%s - %s)", ruta.Nombre, ruta.Origen, ruta.Destino)},
+		{"Distancia (km)", ruta.DistanciaKm.Float64()},
+		{fmt.Sprintf("Tarifa (%s por t-km)", simbolo), ruta.TarifaToneladaKm.Float64()},
+		{fmt.Sprintf("Costo por tonelada (%s)", simbolo), calculo.CostoTonelada.Float64()},
+		{"Aplicación", modo},
+	}
+	row := 3
+	for _, dato := range datosRuta {
+		f.MergeCell(HojaFlete, fmt.Sprintf("A%d", row), fmt.Sprintf("B%d", row))
+		f.SetCellValue(HojaFlete, fmt.Sprintf("A%d", row), dato.rotulo)
+		f.SetCellStyle(HojaFlete, fmt.Sprintf("A%d", row), fmt.Sprintf("B%d", row), dataStyle)
+		f.MergeCell(HojaFlete, fmt.Sprintf("C%d", row), fmt.Sprintf("E%d", row))
+		f.SetCellValue(HojaFlete, fmt.Sprintf("C%d", row), dato.valor)
+		estilo := numberStyle
+		if _, esTexto := dato.valor.(string); esTexto {
+			estilo = dataStyle
+		}
+		f.SetCellStyle(HojaFlete, fmt.Sprintf("C%d", row), fmt.Sprintf("E%d", row), estilo)
+		row++
+	}
+	row++
+
+	headers := []string{"Código", "Material", "Und.", "Cantidad", "Peso unit. (kg)", "Peso (t)",
+		fmt.Sprintf("Flete unit. (%s)", simbolo), fmt.Sprintf("Flete (%s)", simbolo)}
+	for i, header := range headers {
+		celda, _ := excelize.CoordinatesToCellName(i+1, row)
+		f.SetCellValue(HojaFlete, celda, header)
+	}
+	f.SetCellStyle(HojaFlete, fmt.Sprintf("A%d", row), fmt.Sprintf("H%d", row), headerStyle)
+	f.SetRowHeight(HojaFlete, row, 30)
+	row++
+
+	for _, material := range calculo.Materiales {
+		f.SetCellValue(HojaFlete, fmt.Sprintf("A%d", row), material.Codigo)
+		f.SetCellValue(HojaFlete, fmt.Sprintf("B%d", row), material.Descripcion)
+		f.SetCellValue(HojaFlete, fmt.Sprintf("C%d", row), material.Unidad)
+		f.SetCellStyle(HojaFlete, fmt.Sprintf("A%d", row), fmt.Sprintf("C%d", row), dataStyle)
+		f.SetCellValue(HojaFlete, fmt.Sprintf("D%d", row), material.Cantidad.Float64())
+		f.SetCellValue(HojaFlete, fmt.Sprintf("E%d", row), material.PesoUnitario.Float64())
+		f.SetCellValue(HojaFlete, fmt.Sprintf("F%d", row), material.Peso.Float64())
+		f.SetCellValue(HojaFlete, fmt.Sprintf("G%d", row), material.FleteUnitario.Float64())
+		f.SetCellValue(HojaFlete, fmt.Sprintf("H%d", row), material.Flete.Float64())
+		f.SetCellStyle(HojaFlete, fmt.Sprintf("D%d", row), fmt.Sprintf("H%d", row), numberStyle)
+		row++
+	}
+
+	f.MergeCell(HojaFlete, fmt.Sprintf("A%d", row), fmt.Sprintf("E%d", row))
+	f.SetCellValue(HojaFlete, fmt.Sprintf("A%d", row), "TOTAL")
+	f.SetCellStyle(HojaFlete, fmt.Sprintf("A%d", row), fmt.Sprintf("E%d", row), totalTextoStyle)
+	f.SetCellValue(HojaFlete, fmt.Sprintf("F%d", row), calculo.PesoTotal.Float64())
+	f.SetCellValue(HojaFlete, fmt.Sprintf("H%d", row), calculo.Total.Float64())
+	f.SetCellStyle(HojaFlete, fmt.Sprintf("F%d", row), fmt.Sprintf("H%d", row), totalStyle)
+	row += 2
+
+	f.MergeCell(HojaFlete, fmt.Sprintf("A%d", row), fmt.Sprintf("H%d", row))
+	f.SetCellValue(HojaFlete, fmt.Sprintf("A%d", row),
+		"Cantidad = Σ metrado × cantidad del APU (con desperdicio); flete unitario = peso unitario / 1000 × costo "+
+			"por tonelada; costo por tonelada = distancia × tarifa por t-km.")
+	f.SetCellStyle(HojaFlete, fmt.Sprintf("A%d", row), fmt.Sprintf("H%d", row), notaStyle)
+	f.SetRowHeight(HojaFlete, row, 30)
+
+	return nil
+}
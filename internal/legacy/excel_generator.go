@@ -2,17 +2,43 @@ package legacy
 
 import (
 	"fmt"
+	"io"
+	"time"
+
 	"github.com/xuri/excelize/v2"
+	"goexcel/internal/costing"
+	"goexcel/internal/models"
 )
 
 // Estructuras legacy para compatibilidad
 type RecursoLegacy struct {
-	Codigo      string  `json:"codigo"`
-	Descripcion string  `json:"descripcion"`
-	Unidad      string  `json:"unidad"`
-	Cuadrilla   float64 `json:"cuadrilla,omitempty"`
-	Cantidad    float64 `json:"cantidad"`
-	Precio      float64 `json:"precio"`
+	Codigo      string          `json:"codigo"`
+	Descripcion string          `json:"descripcion"`
+	Unidad      string          `json:"unidad"`
+	Cuadrilla   float64         `json:"cuadrilla,omitempty"`
+	Cantidad    costing.Decimal `json:"cantidad"`
+	Precio      costing.Decimal `json:"precio"`
+	Moneda      string          `json:"moneda,omitempty"` // moneda del precio; vacía: la del presupuesto
+
+	// Desperdicio es el porcentaje que se agrega a la cantidad neta (p. ej. 5 para ladrillos); nil si no hay
+	Desperdicio *costing.Decimal `json:"desperdicio,omitempty"`
+
+	// Precio en Moneda antes de convertirlo; solo en los recursos convertidos, cuyo Precio ya está en la
+	// moneda del presupuesto
+	PrecioOriginal *costing.Decimal `json:"precio_original,omitempty"`
+	TipoCambio     *costing.Decimal `json:"tipo_cambio,omitempty"`
+
+	// Cantidad antes del desperdicio; solo en los recursos del reporte, cuya Cantidad ya lo incluye
+	CantidadNeta *costing.Decimal `json:"cantidad_neta,omitempty"`
+}
+
+// CantidadEfectiva es la cantidad con la que se costea el recurso: la neta más el desperdicio. En los
+// recursos del reporte Cantidad ya incluye el desperdicio y se devuelve tal cual.
+func (r RecursoLegacy) CantidadEfectiva(reglas costing.Reglas) costing.Decimal {
+	if r.Desperdicio == nil || r.CantidadNeta != nil {
+		return r.Cantidad
+	}
+	return reglas.CantidadConDesperdicio(r.Cantidad, *r.Desperdicio)
 }
 
 type PartidaLegacy struct {
@@ -26,111 +52,130 @@ type PartidaLegacy struct {
 	Subcontratos []RecursoLegacy `json:"subcontratos"`
 }
 
-func GenerarExcel(partidas []PartidaLegacy, nombreArchivo string) error {
-	f := excelize.NewFile()
+// EscribirExcel genera el libro de ACUs y lo escribe en w sin archivos intermedios; excelize arma el
+// .xlsx comprimido completo en memoria antes de escribirlo
+func EscribirExcel(w io.Writer, partidas []PartidaLegacy, opciones models.OpcionesExportacion) error {
+	f, err := ConstruirExcel(partidas, opciones)
+	if err != nil {
+		return err
+	}
 	defer f.Close()
-	
+
+	return f.Write(w)
+}
+
+// ConstruirExcel genera el libro de ACUs para que el llamador pueda agregar hojas antes de escribirlo.
+// Las hojas ACUs y Resumen se escriben en streaming, por lo que ya no admiten cambios de celdas.
+func ConstruirExcel(partidas []PartidaLegacy, opciones models.OpcionesExportacion) (*excelize.File, error) {
+	f := excelize.NewFile()
+	plantilla := ResolverPlantilla(opciones.Plantilla)
+	tamanoDatos := TamanoDatos(plantilla, 10)
+
 	sheet := "ACUs"
 	f.SetSheetName("Sheet1", sheet)
-	
+
 	// Crear hoja de resumen
 	sheetResumen := "Resumen"
 	f.NewSheet(sheetResumen)
-	
-	// Configurar columnas
-	f.SetColWidth(sheet, "A", "A", 12)
-	f.SetColWidth(sheet, "B", "B", 45)
-	f.SetColWidth(sheet, "C", "C", 10)
-	f.SetColWidth(sheet, "D", "D", 12)
-	f.SetColWidth(sheet, "E", "E", 12)
-	f.SetColWidth(sheet, "F", "F", 15)
-	f.SetColWidth(sheet, "G", "G", 15)
+
+	bordes := []excelize.Border{
+		{Type: "left", Color: "#000000", Style: 1},
+		{Type: "right", Color: "#000000", Style: 1},
+		{Type: "top", Color: "#000000", Style: 1},
+		{Type: "bottom", Color: "#000000", Style: 1},
+	}
 
 	// Estilos
 	headerStyle, _ := f.NewStyle(&excelize.Style{
-		Font: &excelize.Font{Bold: true, Size: 12, Color: "#FFFFFF"},
-		Fill: excelize.Fill{Type: "pattern", Color: []string{"#4F81BD"}, Pattern: 1},
+		Font:      &excelize.Font{Bold: true, Size: 12, Color: "#FFFFFF", Family: plantilla.Fuente},
+		Fill:      excelize.Fill{Type: "pattern", Color: []string{plantilla.ColorCabecera}, Pattern: 1},
 		Alignment: &excelize.Alignment{Horizontal: "center", Vertical: "center"},
-		Border: []excelize.Border{
-			{Type: "left", Color: "#000000", Style: 1},
-			{Type: "right", Color: "#000000", Style: 1},
-			{Type: "top", Color: "#000000", Style: 1},
-			{Type: "bottom", Color: "#000000", Style: 1},
-		},
+		Border:    bordes,
 	})
 
 	partidaStyle, _ := f.NewStyle(&excelize.Style{
-		Font: &excelize.Font{Bold: true, Size: 11, Color: "#FFFFFF"},
-		Fill: excelize.Fill{Type: "pattern", Color: []string{"#305496"}, Pattern: 1},
+		Font:      &excelize.Font{Bold: true, Size: 11, Color: "#FFFFFF", Family: plantilla.Fuente},
+		Fill:      excelize.Fill{Type: "pattern", Color: []string{plantilla.ColorPartida}, Pattern: 1},
 		Alignment: &excelize.Alignment{Horizontal: "left", Vertical: "center"},
-		Border: []excelize.Border{
-			{Type: "left", Color: "#000000", Style: 1},
-			{Type: "right", Color: "#000000", Style: 1},
-			{Type: "top", Color: "#000000", Style: 1},
-			{Type: "bottom", Color: "#000000", Style: 1},
-		},
+		Border:    bordes,
 	})
 
 	sectionStyle, _ := f.NewStyle(&excelize.Style{
-		Font: &excelize.Font{Bold: true, Size: 10},
-		Fill: excelize.Fill{Type: "pattern", Color: []string{"#D9E2F3"}, Pattern: 1},
+		Font:      &excelize.Font{Bold: true, Size: 10, Family: plantilla.Fuente},
+		Fill:      excelize.Fill{Type: "pattern", Color: []string{plantilla.ColorSeccion}, Pattern: 1},
 		Alignment: &excelize.Alignment{Horizontal: "center", Vertical: "center"},
-		Border: []excelize.Border{
-			{Type: "left", Color: "#000000", Style: 1},
-			{Type: "right", Color: "#000000", Style: 1},
-			{Type: "top", Color: "#000000", Style: 1},
-			{Type: "bottom", Color: "#000000", Style: 1},
-		},
+		Border:    bordes,
 	})
 
 	dataStyle, _ := f.NewStyle(&excelize.Style{
-		Font: &excelize.Font{Size: 10},
+		Font:      &excelize.Font{Size: tamanoDatos, Family: plantilla.Fuente},
 		Alignment: &excelize.Alignment{Horizontal: "left", Vertical: "center"},
-		Border: []excelize.Border{
-			{Type: "left", Color: "#000000", Style: 1},
-			{Type: "right", Color: "#000000", Style: 1},
-			{Type: "top", Color: "#000000", Style: 1},
-			{Type: "bottom", Color: "#000000", Style: 1},
-		},
+		Border:    bordes,
 	})
 
 	numberStyle, _ := f.NewStyle(&excelize.Style{
-		Font: &excelize.Font{Size: 10},
-		NumFmt: 4,
-		Alignment: &excelize.Alignment{Horizontal: "right", Vertical: "center"},
-		Border: []excelize.Border{
-			{Type: "left", Color: "#000000", Style: 1},
-			{Type: "right", Color: "#000000", Style: 1},
-			{Type: "top", Color: "#000000", Style: 1},
-			{Type: "bottom", Color: "#000000", Style: 1},
-		},
+		Font:         &excelize.Font{Size: tamanoDatos, Family: plantilla.Fuente},
+		CustomNumFmt: &plantilla.FormatoNumero,
+		Alignment:    &excelize.Alignment{Horizontal: "right", Vertical: "center"},
+		Border:       bordes,
 	})
 
 	totalStyle, _ := f.NewStyle(&excelize.Style{
-		Font: &excelize.Font{Bold: true, Size: 11, Color: "#FFFFFF"},
-		Fill: excelize.Fill{Type: "pattern", Color: []string{"#70AD47"}, Pattern: 1},
-		NumFmt: 4,
-		Alignment: &excelize.Alignment{Horizontal: "center", Vertical: "center"},
-		Border: []excelize.Border{
-			{Type: "left", Color: "#000000", Style: 1},
-			{Type: "right", Color: "#000000", Style: 1},
-			{Type: "top", Color: "#000000", Style: 1},
-			{Type: "bottom", Color: "#000000", Style: 1},
-		},
+		Font:         &excelize.Font{Bold: true, Size: 11, Color: "#FFFFFF", Family: plantilla.Fuente},
+		Fill:         excelize.Fill{Type: "pattern", Color: []string{plantilla.ColorTotal}, Pattern: 1},
+		CustomNumFmt: &plantilla.FormatoNumero,
+		Alignment:    &excelize.Alignment{Horizontal: "center", Vertical: "center"},
+		Border:       bordes,
 	})
 
-	// Título principal
-	f.MergeCell(sheet, "A1", "G1")
-	f.SetCellValue(sheet, "A1", "ANÁLISIS DE COSTOS UNITARIOS - CONSOLIDADO")
-	f.SetCellStyle(sheet, "A1", "G1", headerStyle)
+	escritor, err := NuevoEscritorHoja(f, sheet, opciones.NivelColapsado)
+	if err != nil {
+		return nil, err
+	}
+	// Con precios convertidos de otra moneda se agrega la columna H con el precio original
+	conPrecioOriginal := HayPreciosConvertidos(partidas)
+	anchos := []float64{12, 45, 10, 12, 12, 15, 15}
+	ultimaColumna := "G"
+	if conPrecioOriginal {
+		anchos = append(anchos, 24)
+		ultimaColumna = "H"
+	}
+	escritor.AnchoColumnas(anchos, plantilla)
+	escritor.Congelar(1)
+
+	// Título principal y parámetros con los que se calcularon los montos
+	parametros := opciones.ParametrosCalculo()
+	reglas := parametros.Reglas()
+	escritor.Combinar("A1", "G1")
+	escritor.Fila(1, 0, FilaCombinada("ANÁLISIS DE COSTOS UNITARIOS - CONSOLIDADO", headerStyle, 7)...)
+	escritor.Combinar("A2", "G2")
+	escritor.Fila(2, 0, TextoParametros(parametros))
 
 	row := 3
 	var datosResumen []map[string]interface{}
+	var bloques []BloqueFilas
+
+	// Filas de sección y subtotal de cada tipo de recurso
+	seccion := func(nombre string, recursos []RecursoLegacy, total costing.Decimal) {
+		if len(recursos) == 0 {
+			return
+		}
+
+		escritor.Combinar(fmt.Sprintf("A%d", row), fmt.Sprintf("G%d", row))
+		escritor.Fila(row, 1, FilaCombinada(nombre, sectionStyle, 7)...)
+		row++
+
+		row = agregarRecursos(escritor, recursos, reglas, row, dataStyle, numberStyle, conPrecioOriginal)
+
+		escritor.Combinar(fmt.Sprintf("A%d", row), fmt.Sprintf("F%d", row))
+		subtotal := FilaCombinada("SUBTOTAL "+nombre, sectionStyle, 7)
+		subtotal[6] = Celda(total, sectionStyle)
+		escritor.Fila(row, 1, subtotal...)
+		row++
+	}
 
 	// Procesar cada partida
 	for i, partida := range partidas {
-		fmt.Printf("Procesando partida %d/%d: %s\n", i+1, len(partidas), partida.Codigo)
-		
 		// Validar partida
 		if partida.Codigo == "" || partida.Descripcion == "" {
 			fmt.Printf("⚠️  Saltando partida %d: datos incompletos\n", i+1)
@@ -138,11 +183,11 @@ func GenerarExcel(partidas []PartidaLegacy, nombreArchivo string) error {
 		}
 
 		// Calcular totales
-		totalMO := calcularTotal(partida.ManoObra)
-		totalMat := calcularTotal(partida.Materiales)
-		totalEq := calcularTotal(partida.Equipos)
-		totalSub := calcularTotal(partida.Subcontratos)
-		costoTotal := totalMO + totalMat + totalEq + totalSub
+		totalMO := CostoRecursos(partida.ManoObra, reglas)
+		totalMat := CostoRecursos(partida.Materiales, reglas)
+		totalEq := CostoRecursos(partida.Equipos, reglas)
+		totalSub := CostoRecursos(partida.Subcontratos, reglas)
+		costoTotal := reglas.Sumar(totalMO, totalMat, totalEq, totalSub)
 
 		// Guardar para resumen
 		datosResumen = append(datosResumen, map[string]interface{}{
@@ -157,215 +202,237 @@ func GenerarExcel(partidas []PartidaLegacy, nombreArchivo string) error {
 			"costo_total": costoTotal,
 		})
 
-		// Encabezado de partida
-		f.MergeCell(sheet, fmt.Sprintf("A%d", row), fmt.Sprintf("G%d", row))
-		f.SetCellValue(sheet, fmt.Sprintf("A%d", row), 
-			fmt.Sprintf("PARTIDA %s - %s", partida.Codigo, partida.Descripcion))
-		f.SetCellStyle(sheet, fmt.Sprintf("A%d", row), fmt.Sprintf("G%d", row), partidaStyle)
+		// Encabezado de partida: resumen del grupo que contiene su detalle
+		inicioPartida := row
+		escritor.Combinar(fmt.Sprintf("A%d", row), fmt.Sprintf("G%d", row))
+		escritor.Fila(row, 0, FilaCombinada(fmt.Sprintf("PARTIDA %s - %s", partida.Codigo, partida.Descripcion), partidaStyle, 7)...)
 		row++
 
 		// Info de la partida
-		f.SetCellValue(sheet, fmt.Sprintf("A%d", row), "Unidad:")
-		f.SetCellValue(sheet, fmt.Sprintf("B%d", row), partida.Unidad)
-		f.SetCellValue(sheet, fmt.Sprintf("C%d", row), "Rendimiento:")
-		f.SetCellValue(sheet, fmt.Sprintf("D%d", row), partida.Rendimiento)
-		f.SetCellValue(sheet, fmt.Sprintf("E%d", row), "Costo Total:")
-		f.SetCellValue(sheet, fmt.Sprintf("F%d", row), costoTotal)
-		f.SetCellStyle(sheet, fmt.Sprintf("F%d", row), fmt.Sprintf("F%d", row), totalStyle)
+		escritor.Fila(row, 1, "Unidad:", partida.Unidad, "Rendimiento:", partida.Rendimiento, "Costo Total:", Celda(costoTotal, totalStyle))
 		row++
 
 		// Cabeceras de tabla
-		headers := []string{"Código", "Descripción", "Unidad", "Cuadrilla", "Cantidad", "Precio S/", "Parcial S/"}
+		headers := []string{"Código", "Descripción", "Unidad", "Cuadrilla", "Cantidad", "Precio " + parametros.SimboloMoneda(), "Parcial " + parametros.SimboloMoneda()}
+		if conPrecioOriginal {
+			headers = append(headers, "Precio original")
+		}
+		cabeceras := make([]interface{}, len(headers))
 		for j, header := range headers {
-			f.SetCellValue(sheet, fmt.Sprintf("%c%d", 'A'+j, row), header)
-			f.SetCellStyle(sheet, fmt.Sprintf("%c%d", 'A'+j, row), fmt.Sprintf("%c%d", 'A'+j, row), sectionStyle)
+			cabeceras[j] = Celda(header, sectionStyle)
 		}
+		escritor.Fila(row, 1, cabeceras...)
 		row++
 
-		// Mano de obra
-		if len(partida.ManoObra) > 0 {
-			f.MergeCell(sheet, fmt.Sprintf("A%d", row), fmt.Sprintf("G%d", row))
-			f.SetCellValue(sheet, fmt.Sprintf("A%d", row), "MANO DE OBRA")
-			f.SetCellStyle(sheet, fmt.Sprintf("A%d", row), fmt.Sprintf("G%d", row), sectionStyle)
-			row++
-			
-			row = agregarRecursos(f, sheet, partida.ManoObra, row, dataStyle, numberStyle)
-			
-			// Subtotal MO
-			f.MergeCell(sheet, fmt.Sprintf("A%d", row), fmt.Sprintf("F%d", row))
-			f.SetCellValue(sheet, fmt.Sprintf("A%d", row), "SUBTOTAL MANO DE OBRA")
-			f.SetCellValue(sheet, fmt.Sprintf("G%d", row), totalMO)
-			f.SetCellStyle(sheet, fmt.Sprintf("A%d", row), fmt.Sprintf("G%d", row), sectionStyle)
-			row++
-		}
-
-		// Materiales
-		if len(partida.Materiales) > 0 {
-			f.MergeCell(sheet, fmt.Sprintf("A%d", row), fmt.Sprintf("G%d", row))
-			f.SetCellValue(sheet, fmt.Sprintf("A%d", row), "MATERIALES")
-			f.SetCellStyle(sheet, fmt.Sprintf("A%d", row), fmt.Sprintf("G%d", row), sectionStyle)
-			row++
-			
-			row = agregarRecursos(f, sheet, partida.Materiales, row, dataStyle, numberStyle)
-			
-			// Subtotal Materiales
-			f.MergeCell(sheet, fmt.Sprintf("A%d", row), fmt.Sprintf("F%d", row))
-			f.SetCellValue(sheet, fmt.Sprintf("A%d", row), "SUBTOTAL MATERIALES")
-			f.SetCellValue(sheet, fmt.Sprintf("G%d", row), totalMat)
-			f.SetCellStyle(sheet, fmt.Sprintf("A%d", row), fmt.Sprintf("G%d", row), sectionStyle)
-			row++
-		}
-
-		// Equipos
-		if len(partida.Equipos) > 0 {
-			f.MergeCell(sheet, fmt.Sprintf("A%d", row), fmt.Sprintf("G%d", row))
-			f.SetCellValue(sheet, fmt.Sprintf("A%d", row), "EQUIPOS")
-			f.SetCellStyle(sheet, fmt.Sprintf("A%d", row), fmt.Sprintf("G%d", row), sectionStyle)
-			row++
-			
-			row = agregarRecursos(f, sheet, partida.Equipos, row, dataStyle, numberStyle)
-			
-			// Subtotal Equipos
-			f.MergeCell(sheet, fmt.Sprintf("A%d", row), fmt.Sprintf("F%d", row))
-			f.SetCellValue(sheet, fmt.Sprintf("A%d", row), "SUBTOTAL EQUIPOS")
-			f.SetCellValue(sheet, fmt.Sprintf("G%d", row), totalEq)
-			f.SetCellStyle(sheet, fmt.Sprintf("A%d", row), fmt.Sprintf("G%d", row), sectionStyle)
-			row++
-		}
-
-		// Subcontratos
-		if len(partida.Subcontratos) > 0 {
-			f.MergeCell(sheet, fmt.Sprintf("A%d", row), fmt.Sprintf("G%d", row))
-			f.SetCellValue(sheet, fmt.Sprintf("A%d", row), "SUBCONTRATOS")
-			f.SetCellStyle(sheet, fmt.Sprintf("A%d", row), fmt.Sprintf("G%d", row), sectionStyle)
-			row++
-			
-			row = agregarRecursos(f, sheet, partida.Subcontratos, row, dataStyle, numberStyle)
-			
-			// Subtotal Subcontratos
-			f.MergeCell(sheet, fmt.Sprintf("A%d", row), fmt.Sprintf("F%d", row))
-			f.SetCellValue(sheet, fmt.Sprintf("A%d", row), "SUBTOTAL SUBCONTRATOS")
-			f.SetCellValue(sheet, fmt.Sprintf("G%d", row), totalSub)
-			f.SetCellStyle(sheet, fmt.Sprintf("A%d", row), fmt.Sprintf("G%d", row), sectionStyle)
-			row++
-		}
+		seccion("MANO DE OBRA", partida.ManoObra, totalMO)
+		seccion("MATERIALES", partida.Materiales, totalMat)
+		seccion("EQUIPOS", partida.Equipos, totalEq)
+		seccion("SUBCONTRATOS", partida.Subcontratos, totalSub)
 
 		// Costo total de la partida
-		f.MergeCell(sheet, fmt.Sprintf("A%d", row), fmt.Sprintf("F%d", row))
-		f.SetCellValue(sheet, fmt.Sprintf("A%d", row), fmt.Sprintf("COSTO TOTAL - PARTIDA %s", partida.Codigo))
-		f.SetCellValue(sheet, fmt.Sprintf("G%d", row), costoTotal)
-		f.SetCellStyle(sheet, fmt.Sprintf("A%d", row), fmt.Sprintf("G%d", row), totalStyle)
+		escritor.Combinar(fmt.Sprintf("A%d", row), fmt.Sprintf("F%d", row))
+		total := FilaCombinada(fmt.Sprintf("COSTO TOTAL - PARTIDA %s", partida.Codigo), totalStyle, 7)
+		total[6] = Celda(costoTotal, totalStyle)
+		escritor.Fila(row, 1, total...)
+		bloques = append(bloques, BloqueFilas{Inicio: inicioPartida, Fin: row})
 		row += 3 // Espaciado entre partidas
 	}
 
+	// Plantilla e impresión antes de cerrar la hoja: los ACUs nunca se parten entre páginas
+	fecha := time.Now()
+	err = prepararHojaStream(f, sheet, escritor, plantilla, ConfigImpresion{
+		FilasTitulo:   1,
+		UltimaColumna: ultimaColumna,
+		UltimaFila:    row - 3,
+		Bloques:       bloques,
+		Proyecto:      opciones.Proyecto,
+		Fecha:         fecha,
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	// Crear hoja resumen
-	crearResumen(f, sheetResumen, datosResumen)
+	if err := crearResumen(f, sheetResumen, datosResumen, plantilla, opciones, fecha); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// prepararHojaStream aplica logo y configuración de página a una hoja escrita en streaming y la cierra
+func prepararHojaStream(f *excelize.File, sheet string, escritor *EscritorHoja, plantilla models.PlantillaExcel, config ConfigImpresion) error {
+	if err := AgregarLogo(f, sheet, plantilla); err != nil {
+		return err
+	}
 
-	return f.SaveAs(nombreArchivo)
+	config.Plantilla = plantilla
+	config.Anchos = escritor.Anchos()
+	if err := ConfigurarImpresion(f, sheet, config); err != nil {
+		return err
+	}
+
+	if err := escritor.Cerrar(); err != nil {
+		return fmt.Errorf("error escribiendo hoja %s: %v", sheet, err)
+	}
+	return nil
 }
 
-func agregarRecursos(f *excelize.File, sheet string, recursos []RecursoLegacy, startRow int, dataStyle, numberStyle int) int {
+// agregarRecursos escribe las filas de recursos; con conPrecioOriginal agrega la columna del precio
+// antes de convertirlo a la moneda del presupuesto, vacía en los recursos no convertidos
+func agregarRecursos(escritor *EscritorHoja, recursos []RecursoLegacy, reglas costing.Reglas, startRow int, dataStyle, numberStyle int, conPrecioOriginal bool) int {
 	row := startRow
 	for _, recurso := range recursos {
 		// Validar recurso
 		if recurso.Codigo == "" || recurso.Descripcion == "" {
 			continue
 		}
-		
-		parcial := recurso.Cantidad * recurso.Precio
-		
-		f.SetCellValue(sheet, fmt.Sprintf("A%d", row), recurso.Codigo)
-		f.SetCellValue(sheet, fmt.Sprintf("B%d", row), recurso.Descripcion)
-		f.SetCellValue(sheet, fmt.Sprintf("C%d", row), recurso.Unidad)
-		
+
+		cantidad := recurso.CantidadEfectiva(reglas)
+		parcial := reglas.ParcialRecurso(cantidad, recurso.Precio)
+
 		// Cuadrilla solo si es mayor a 0
+		var cuadrilla interface{} = "-"
 		if recurso.Cuadrilla > 0 {
-			f.SetCellValue(sheet, fmt.Sprintf("D%d", row), recurso.Cuadrilla)
-		} else {
-			f.SetCellValue(sheet, fmt.Sprintf("D%d", row), "-")
+			cuadrilla = recurso.Cuadrilla
 		}
-		
-		f.SetCellValue(sheet, fmt.Sprintf("E%d", row), recurso.Cantidad)
-		f.SetCellValue(sheet, fmt.Sprintf("F%d", row), recurso.Precio)
-		f.SetCellValue(sheet, fmt.Sprintf("G%d", row), parcial)
-		
-		// Aplicar estilos
-		f.SetCellStyle(sheet, fmt.Sprintf("A%d", row), fmt.Sprintf("C%d", row), dataStyle)
-		f.SetCellStyle(sheet, fmt.Sprintf("D%d", row), fmt.Sprintf("G%d", row), numberStyle)
+
+		celdas := []interface{}{
+			Celda(recurso.Codigo, dataStyle),
+			Celda(DescripcionConDesperdicio(recurso), dataStyle),
+			Celda(recurso.Unidad, dataStyle),
+			Celda(cuadrilla, numberStyle),
+			Celda(cantidad, numberStyle),
+			Celda(recurso.Precio, numberStyle),
+			Celda(parcial, numberStyle),
+		}
+		if conPrecioOriginal {
+			celdas = append(celdas, Celda(TextoPrecioOriginal(recurso), dataStyle))
+		}
+		escritor.Fila(row, 1, celdas...)
 		row++
 	}
 	return row
 }
 
-func crearResumen(f *excelize.File, sheet string, datos []map[string]interface{}) {
-	if len(datos) == 0 {
-		return
+// DescripcionConDesperdicio rotula los recursos con desperdicio, p. ej. "LADRILLO KK (desperdicio 5%)",
+// para que la cantidad impresa se entienda como la neta más el desperdicio
+func DescripcionConDesperdicio(recurso RecursoLegacy) string {
+	if recurso.Desperdicio == nil || recurso.Desperdicio.EsCero() {
+		return recurso.Descripcion
+	}
+	return fmt.Sprintf("%s (desperdicio %s%%)", recurso.Descripcion, recurso.Desperdicio)
+}
+
+// HayPreciosConvertidos indica si algún recurso de las partidas tiene su precio convertido de otra moneda
+func HayPreciosConvertidos(partidas []PartidaLegacy) bool {
+	for _, partida := range partidas {
+		for _, recursos := range [][]RecursoLegacy{partida.ManoObra, partida.Materiales, partida.Equipos, partida.Subcontratos} {
+			for _, recurso := range recursos {
+				if recurso.PrecioOriginal != nil {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// TextoPrecioOriginal describe la conversión de un recurso, p. ej. "USD 150 × 3.75"; vacío si
+// el precio no se convirtió
+func TextoPrecioOriginal(recurso RecursoLegacy) string {
+	if recurso.PrecioOriginal == nil || recurso.TipoCambio == nil {
+		return ""
 	}
+	return fmt.Sprintf("%s %s × %s", recurso.Moneda, recurso.PrecioOriginal, recurso.TipoCambio)
+}
 
-	// Configurar columnas para resumen
-	f.SetColWidth(sheet, "A", "A", 12)
-	f.SetColWidth(sheet, "B", "B", 45)
-	f.SetColWidth(sheet, "C", "C", 10)
-	f.SetColWidth(sheet, "D", "D", 12)
-	f.SetColWidth(sheet, "E", "E", 15)
-	f.SetColWidth(sheet, "F", "F", 15)
-	f.SetColWidth(sheet, "G", "G", 15)
-	f.SetColWidth(sheet, "H", "H", 15)
-	f.SetColWidth(sheet, "I", "I", 15)
+// filaDatosResumen es la fila de la primera partida en la hoja Resumen; la hoja de gráficos la referencia
+const filaDatosResumen = 4
 
+func crearResumen(f *excelize.File, sheet string, datos []map[string]interface{}, plantilla models.PlantillaExcel, opciones models.OpcionesExportacion, fecha time.Time) error {
 	// Estilos para resumen
 	titleStyle, _ := f.NewStyle(&excelize.Style{
-		Font: &excelize.Font{Bold: true, Size: 14, Color: "#FFFFFF"},
-		Fill: excelize.Fill{Type: "pattern", Color: []string{"#2F5597"}, Pattern: 1},
+		Font:      &excelize.Font{Bold: true, Size: 14, Color: "#FFFFFF", Family: plantilla.Fuente},
+		Fill:      excelize.Fill{Type: "pattern", Color: []string{plantilla.ColorTitulo}, Pattern: 1},
 		Alignment: &excelize.Alignment{Horizontal: "center", Vertical: "center"},
 	})
 
 	headerStyle, _ := f.NewStyle(&excelize.Style{
-		Font: &excelize.Font{Bold: true, Size: 11, Color: "#FFFFFF"},
-		Fill: excelize.Fill{Type: "pattern", Color: []string{"#4F81BD"}, Pattern: 1},
+		Font:      &excelize.Font{Bold: true, Size: 11, Color: "#FFFFFF", Family: plantilla.Fuente},
+		Fill:      excelize.Fill{Type: "pattern", Color: []string{plantilla.ColorCabecera}, Pattern: 1},
 		Alignment: &excelize.Alignment{Horizontal: "center", Vertical: "center"},
 	})
 
 	numberStyle, _ := f.NewStyle(&excelize.Style{
-		NumFmt: 4,
-		Alignment: &excelize.Alignment{Horizontal: "right", Vertical: "center"},
+		Font:         &excelize.Font{Family: plantilla.Fuente},
+		CustomNumFmt: &plantilla.FormatoNumero,
+		Alignment:    &excelize.Alignment{Horizontal: "right", Vertical: "center"},
 	})
 
-	// Título
-	f.MergeCell(sheet, "A1", "I1")
-	f.SetCellValue(sheet, "A1", "RESUMEN DE COSTOS UNITARIOS")
-	f.SetCellStyle(sheet, "A1", "I1", titleStyle)
-
-	// Cabeceras
-	headers := []string{"Código", "Descripción", "Unidad", "Rendimiento", "Mano Obra", "Materiales", "Equipos", "Subcontratos", "Costo Total"}
-	for i, header := range headers {
-		f.SetCellValue(sheet, fmt.Sprintf("%c3", 'A'+i), header)
-		f.SetCellStyle(sheet, fmt.Sprintf("%c3", 'A'+i), fmt.Sprintf("%c3", 'A'+i), headerStyle)
+	escritor, err := NuevoEscritorHoja(f, sheet, 0)
+	if err != nil {
+		return err
 	}
-
-	// Datos
-	row := 4
-	for _, dato := range datos {
-		f.SetCellValue(sheet, fmt.Sprintf("A%d", row), dato["codigo"])
-		f.SetCellValue(sheet, fmt.Sprintf("B%d", row), dato["descripcion"])
-		f.SetCellValue(sheet, fmt.Sprintf("C%d", row), dato["unidad"])
-		f.SetCellValue(sheet, fmt.Sprintf("D%d", row), dato["rendimiento"])
-		f.SetCellValue(sheet, fmt.Sprintf("E%d", row), dato["costo_mo"])
-		f.SetCellValue(sheet, fmt.Sprintf("F%d", row), dato["costo_mat"])
-		f.SetCellValue(sheet, fmt.Sprintf("G%d", row), dato["costo_eq"])
-		f.SetCellValue(sheet, fmt.Sprintf("H%d", row), dato["costo_sub"])
-		f.SetCellValue(sheet, fmt.Sprintf("I%d", row), dato["costo_total"])
-		
-		// Aplicar formato numérico a las columnas de números
-		f.SetCellStyle(sheet, fmt.Sprintf("D%d", row), fmt.Sprintf("I%d", row), numberStyle)
-		row++
+	escritor.AnchoColumnas([]float64{12, 45, 10, 12, 15, 15, 15, 15, 15}, plantilla)
+	escritor.Congelar(3)
+
+	if len(datos) > 0 {
+		// Título
+		escritor.Combinar("A1", "I1")
+		escritor.Fila(1, 0, FilaCombinada("RESUMEN DE COSTOS UNITARIOS", titleStyle, 9)...)
+
+		// Cabeceras
+		headers := []string{"Código", "Descripción", "Unidad", "Rendimiento", "Mano Obra", "Materiales", "Equipos", "Subcontratos", "Costo Total"}
+		cabeceras := make([]interface{}, len(headers))
+		for i, header := range headers {
+			cabeceras[i] = Celda(header, headerStyle)
+		}
+		escritor.Fila(3, 0, cabeceras...)
+
+		// Datos, con formato numérico en las columnas de números
+		row := filaDatosResumen
+		for _, dato := range datos {
+			escritor.Fila(row, 0,
+				dato["codigo"],
+				dato["descripcion"],
+				dato["unidad"],
+				Celda(dato["rendimiento"], numberStyle),
+				Celda(dato["costo_mo"], numberStyle),
+				Celda(dato["costo_mat"], numberStyle),
+				Celda(dato["costo_eq"], numberStyle),
+				Celda(dato["costo_sub"], numberStyle),
+				Celda(dato["costo_total"], numberStyle),
+			)
+			row++
+		}
 	}
+
+	return prepararHojaStream(f, sheet, escritor, plantilla, ConfigImpresion{
+		Horizontal:    true,
+		FilasTitulo:   3,
+		UltimaColumna: "I",
+		UltimaFila:    3 + len(datos),
+		Proyecto:      opciones.Proyecto,
+		Fecha:         fecha,
+	})
 }
 
-func calcularTotal(recursos []RecursoLegacy) float64 {
-	total := 0.0
+// CostoRecursos es el subtotal de una sección del APU con las reglas de redondeo indicadas. Los
+// recursos sin código o descripción no se listan en el libro, así que tampoco suman.
+func CostoRecursos(recursos []RecursoLegacy, reglas costing.Reglas) costing.Decimal {
+	costos := make([]costing.Recurso, 0, len(recursos))
 	for _, recurso := range recursos {
-		total += recurso.Cantidad * recurso.Precio
+		if recurso.Codigo == "" || recurso.Descripcion == "" {
+			continue
+		}
+		costos = append(costos, costing.Recurso{Cantidad: recurso.CantidadEfectiva(reglas), Precio: recurso.Precio})
 	}
-	return total
-}
\ No newline at end of file
+	return reglas.Subtotal(costos)
+}
+
+// CostoPartida es el costo unitario de la partida: la suma de sus cuatro secciones
+func CostoPartida(partida PartidaLegacy, reglas costing.Reglas) costing.Decimal {
+	return reglas.Sumar(CostoRecursos(partida.ManoObra, reglas), CostoRecursos(partida.Materiales, reglas),
+		CostoRecursos(partida.Equipos, reglas), CostoRecursos(partida.Subcontratos, reglas))
+}
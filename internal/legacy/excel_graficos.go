@@ -0,0 +1,260 @@
+package legacy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+	"goexcel/internal/costing"
+	"goexcel/internal/models"
+)
+
+// HojaGraficos es la hoja con los gráficos de distribución de costos del libro exportado
+const HojaGraficos = "Gráficos"
+
+const (
+	// maxPartidasPareto es la cantidad de partidas de mayor costo del diagrama de Pareto
+	maxPartidasPareto = 20
+	// filaBaseGraficos es la fila de cabecera de la tabla base con una fila por partida
+	filaBaseGraficos = 4
+	// columnaBaseGraficos es la primera columna de la tabla base (T), a la derecha de los gráficos
+	columnaBaseGraficos = 20
+)
+
+// DatosGraficos completa el libro con lo que no está en las partidas: metrados y títulos de primer nivel
+type DatosGraficos struct {
+	Metrados map[string]costing.Decimal // por código de partida; sin metrados se grafican los costos unitarios
+	Titulos  map[string]string          // descripción de los títulos de primer nivel por código
+	Reglas   costing.Reglas             // redondeo de los parámetros del proyecto, el mismo del reporte
+}
+
+// AgregarHojaGraficos agrega la hoja "Gráficos" con gráficos nativos de Excel: costo por tipo de recurso,
+// costo por título de primer nivel y Pareto de las partidas de mayor costo. Las tablas de la hoja son
+// fórmulas sobre las celdas de la hoja Resumen, así que los gráficos cambian si se edita el libro.
+func AgregarHojaGraficos(f *excelize.File, partidas []PartidaLegacy, datos DatosGraficos, plantillaOrg *models.PlantillaExcel) error {
+	if len(partidas) == 0 {
+		return nil
+	}
+	plantilla := ResolverPlantilla(plantillaOrg)
+
+	if _, err := f.NewSheet(HojaGraficos); err != nil {
+		return fmt.Errorf("error creando hoja de gráficos: %v", err)
+	}
+	hoja := HojaGraficos
+
+	titleStyle, _ := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true, Size: 14, Color: "#FFFFFF", Family: plantilla.Fuente},
+		Fill: excelize.Fill{Type: "pattern", Color: []string{plantilla.ColorTitulo}, Pattern: 1},
+	})
+	headerStyle, _ := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true, Color: "#FFFFFF", Family: plantilla.Fuente},
+		Fill: excelize.Fill{Type: "pattern", Color: []string{plantilla.ColorCabecera}, Pattern: 1},
+	})
+	numberStyle, _ := f.NewStyle(&excelize.Style{
+		Font:         &excelize.Font{Family: plantilla.Fuente},
+		CustomNumFmt: &plantilla.FormatoNumero,
+	})
+	porcentajeStyle, _ := f.NewStyle(&excelize.Style{
+		Font:   &excelize.Font{Family: plantilla.Fuente},
+		NumFmt: 10, // 0.00%
+	})
+
+	f.MergeCell(hoja, "A1", "D1")
+	f.SetCellValue(hoja, "A1", "DISTRIBUCIÓN DE COSTOS")
+	f.SetCellStyle(hoja, "A1", "D1", titleStyle)
+	if len(datos.Metrados) > 0 {
+		f.SetCellValue(hoja, "A2", "Costo = metrado × costo unitario de la hoja Resumen")
+	} else {
+		f.SetCellValue(hoja, "A2", "Proyecto sin metrados: se grafican los costos unitarios de la hoja Resumen")
+	}
+	f.SetColWidth(hoja, "A", "A", 30)
+	f.SetColWidth(hoja, "B", "C", 16)
+
+	// Tabla base: una fila por partida con código, metrado y costos por tipo (T:Z) referidos a su
+	// fila en Resumen (A y E:I)
+	columna := func(j int) string {
+		nombre, _ := excelize.ColumnNumberToName(columnaBaseGraficos + j)
+		return nombre
+	}
+	codigoBase, metradoBase, totalBase := columna(0), columna(1), columna(6)
+	f.SetColWidth(hoja, codigoBase, totalBase, 14)
+	f.SetSheetRow(hoja, fmt.Sprintf("%s%d", codigoBase, filaBaseGraficos),
+		&[]interface{}{"Código", "Metrado", "Mano Obra", "Materiales", "Equipos", "Subcontratos", "Costo Total"})
+	f.SetCellStyle(hoja, fmt.Sprintf("%s%d", codigoBase, filaBaseGraficos), fmt.Sprintf("%s%d", totalBase, filaBaseGraficos), headerStyle)
+
+	costos := make([]float64, len(partidas))
+	for i, partida := range partidas {
+		row := filaBaseGraficos + 1 + i
+		resumen := filaDatosResumen + i
+
+		metrado := costing.NuevoDecimal(1, 0)
+		if len(datos.Metrados) > 0 {
+			metrado = datos.Metrados[partida.Codigo]
+		}
+		costos[i] = datos.Reglas.ParcialPartida(metrado, CostoPartida(partida, datos.Reglas)).Float64()
+
+		f.SetCellFormula(hoja, fmt.Sprintf("%s%d", codigoBase, row), fmt.Sprintf("Resumen!A%d", resumen))
+		f.SetCellValue(hoja, fmt.Sprintf("%s%d", metradoBase, row), metrado.Float64())
+		for j, origen := range []string{"E", "F", "G", "H", "I"} {
+			f.SetCellFormula(hoja, fmt.Sprintf("%s%d", columna(2+j), row), fmt.Sprintf("$%s%d*Resumen!%s%d", metradoBase, row, origen, resumen))
+		}
+	}
+	ultimaBase := filaBaseGraficos + len(partidas)
+	f.SetCellStyle(hoja, fmt.Sprintf("%s%d", metradoBase, filaBaseGraficos+1), fmt.Sprintf("%s%d", totalBase, ultimaBase), numberStyle)
+
+	// Costo por tipo de recurso
+	row := 4
+	f.SetSheetRow(hoja, fmt.Sprintf("A%d", row), &[]interface{}{"Tipo de recurso", "Costo"})
+	f.SetCellStyle(hoja, fmt.Sprintf("A%d", row), fmt.Sprintf("B%d", row), headerStyle)
+	cabeceraTipos := row
+	inicioTipos := row + 1
+	for j, tipo := range []string{"Mano de Obra", "Materiales", "Equipos", "Subcontratos"} {
+		row++
+		f.SetCellValue(hoja, fmt.Sprintf("A%d", row), tipo)
+		f.SetCellFormula(hoja, fmt.Sprintf("B%d", row), fmt.Sprintf("SUM(%s%d:%s%d)", columna(2+j), filaBaseGraficos+1, columna(2+j), ultimaBase))
+	}
+	finTipos := row
+	row++
+	celdaTotal := fmt.Sprintf("$B$%d", row)
+	f.SetCellValue(hoja, fmt.Sprintf("A%d", row), "Total")
+	f.SetCellFormula(hoja, fmt.Sprintf("B%d", row), fmt.Sprintf("SUM(B%d:B%d)", inicioTipos, finTipos))
+	f.SetCellStyle(hoja, fmt.Sprintf("B%d", inicioTipos), fmt.Sprintf("B%d", row), numberStyle)
+
+	// Costo por título de primer nivel, agrupando por el primer segmento del código
+	row += 2
+	f.SetSheetRow(hoja, fmt.Sprintf("A%d", row), &[]interface{}{"Título", "Costo"})
+	f.SetCellStyle(hoja, fmt.Sprintf("A%d", row), fmt.Sprintf("B%d", row), headerStyle)
+	cabeceraTitulos := row
+	inicioTitulos := row + 1
+	// LEFT en lugar de comodines de SUMIF: "." y "*" se interpretan distinto según la hoja de cálculo
+	codigos := fmt.Sprintf("$%s$%d:$%s$%d", codigoBase, filaBaseGraficos+1, codigoBase, ultimaBase)
+	totales := fmt.Sprintf("$%s$%d:$%s$%d", totalBase, filaBaseGraficos+1, totalBase, ultimaBase)
+	for _, grupo := range gruposPrimerNivel(partidas) {
+		row++
+		nombre := grupo
+		if descripcion := datos.Titulos[grupo]; descripcion != "" {
+			nombre += " " + descripcion
+		}
+		prefijo := grupo + "."
+		f.SetCellValue(hoja, fmt.Sprintf("A%d", row), nombre)
+		f.SetCellFormula(hoja, fmt.Sprintf("B%d", row), fmt.Sprintf(`SUMPRODUCT(((LEFT(%s,%d)="%s")+(%s="%s"))*%s)`,
+			codigos, len(prefijo), prefijo, codigos, grupo, totales))
+	}
+	finTitulos := row
+	f.SetCellStyle(hoja, fmt.Sprintf("B%d", inicioTitulos), fmt.Sprintf("B%d", finTitulos), numberStyle)
+
+	// Pareto: las partidas de mayor costo al exportar, con su costo vinculado a la tabla base
+	indices := make([]int, len(partidas))
+	for i := range indices {
+		indices[i] = i
+	}
+	sort.SliceStable(indices, func(a, b int) bool { return costos[indices[a]] > costos[indices[b]] })
+	if len(indices) > maxPartidasPareto {
+		indices = indices[:maxPartidasPareto]
+	}
+
+	row += 2
+	f.SetSheetRow(hoja, fmt.Sprintf("A%d", row), &[]interface{}{"Partida", "Costo", "% acumulado"})
+	f.SetCellStyle(hoja, fmt.Sprintf("A%d", row), fmt.Sprintf("C%d", row), headerStyle)
+	cabeceraPareto := row
+	inicioPareto := row + 1
+	for _, i := range indices {
+		row++
+		base := filaBaseGraficos + 1 + i
+		f.SetCellFormula(hoja, fmt.Sprintf("A%d", row), fmt.Sprintf("%s%d", codigoBase, base))
+		f.SetCellFormula(hoja, fmt.Sprintf("B%d", row), fmt.Sprintf("%s%d", totalBase, base))
+		f.SetCellFormula(hoja, fmt.Sprintf("C%d", row), fmt.Sprintf("IF(%s=0,0,SUM($B$%d:B%d)/%s)", celdaTotal, inicioPareto, row, celdaTotal))
+	}
+	finPareto := row
+	f.SetCellStyle(hoja, fmt.Sprintf("B%d", inicioPareto), fmt.Sprintf("B%d", finPareto), numberStyle)
+	f.SetCellStyle(hoja, fmt.Sprintf("C%d", inicioPareto), fmt.Sprintf("C%d", finPareto), porcentajeStyle)
+
+	rango := func(columna string, desde, hasta int) string {
+		return fmt.Sprintf("'%s'!$%s$%d:$%s$%d", hoja, columna, desde, columna, hasta)
+	}
+	// El nombre de cada serie es la celda de cabecera de su columna
+	celda := func(columna string, fila int) string {
+		return fmt.Sprintf("'%s'!$%s$%d", hoja, columna, fila)
+	}
+	sinVariarColores := false
+	dimension := excelize.ChartDimension{Width: 640, Height: 340}
+	titulo := func(texto string) []excelize.RichTextRun {
+		return []excelize.RichTextRun{{Text: texto}}
+	}
+
+	if err := f.AddChart(hoja, "E4", &excelize.Chart{
+		Type: excelize.Pie,
+		Series: []excelize.ChartSeries{{
+			Name:       celda("B", cabeceraTipos),
+			Categories: rango("A", inicioTipos, finTipos),
+			Values:     rango("B", inicioTipos, finTipos),
+		}},
+		Title:     titulo("Costo por tipo de recurso"),
+		Legend:    excelize.ChartLegend{Position: "right"},
+		PlotArea:  excelize.ChartPlotArea{ShowPercent: true},
+		Dimension: dimension,
+	}); err != nil {
+		return fmt.Errorf("error agregando gráfico por tipo de recurso: %v", err)
+	}
+
+	if err := f.AddChart(hoja, "E22", &excelize.Chart{
+		Type: excelize.Bar,
+		Series: []excelize.ChartSeries{{
+			Name:       celda("B", cabeceraTitulos),
+			Categories: rango("A", inicioTitulos, finTitulos),
+			Values:     rango("B", inicioTitulos, finTitulos),
+		}},
+		Title:      titulo("Costo por título"),
+		VaryColors: &sinVariarColores,
+		Legend:     excelize.ChartLegend{Position: "none"},
+		XAxis:      excelize.ChartAxis{ReverseOrder: true},
+		YAxis:      excelize.ChartAxis{MajorGridLines: true, NumFmt: excelize.ChartNumFmt{CustomNumFmt: plantilla.FormatoNumero}},
+		Dimension:  dimension,
+	}); err != nil {
+		return fmt.Errorf("error agregando gráfico por título: %v", err)
+	}
+
+	maximo := 1.0
+	if err := f.AddChart(hoja, "E40", &excelize.Chart{
+		Type: excelize.Col,
+		Series: []excelize.ChartSeries{{
+			Name:       celda("B", cabeceraPareto),
+			Categories: rango("A", inicioPareto, finPareto),
+			Values:     rango("B", inicioPareto, finPareto),
+		}},
+		Title:      titulo(fmt.Sprintf("Pareto: %d partidas de mayor costo", len(indices))),
+		VaryColors: &sinVariarColores,
+		Legend:     excelize.ChartLegend{Position: "bottom"},
+		YAxis:      excelize.ChartAxis{MajorGridLines: true, NumFmt: excelize.ChartNumFmt{CustomNumFmt: plantilla.FormatoNumero}},
+		Dimension:  excelize.ChartDimension{Width: 900, Height: 400},
+	}, &excelize.Chart{
+		Type: excelize.Line,
+		Series: []excelize.ChartSeries{{
+			Name:       celda("C", cabeceraPareto),
+			Categories: rango("A", inicioPareto, finPareto),
+			Values:     rango("C", inicioPareto, finPareto),
+			Marker:     excelize.ChartMarker{Symbol: "circle", Size: 5},
+		}},
+		YAxis: excelize.ChartAxis{Secondary: true, Maximum: &maximo, NumFmt: excelize.ChartNumFmt{CustomNumFmt: "0%"}},
+	}); err != nil {
+		return fmt.Errorf("error agregando diagrama de Pareto: %v", err)
+	}
+
+	return nil
+}
+
+// gruposPrimerNivel devuelve el primer segmento de los códigos de partida ("01" de "01.02.03")
+// en el orden en que aparecen las partidas
+func gruposPrimerNivel(partidas []PartidaLegacy) []string {
+	var grupos []string
+	vistos := make(map[string]bool)
+	for _, partida := range partidas {
+		codigo, _, _ := strings.Cut(partida.Codigo, ".")
+		if !vistos[codigo] {
+			vistos[codigo] = true
+			grupos = append(grupos, codigo)
+		}
+	}
+	return grupos
+}
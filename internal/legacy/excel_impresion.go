@@ -0,0 +1,179 @@
+package legacy
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+	"goexcel/internal/models"
+)
+
+// Medidas de impresión en puntos para papel A4 con los márgenes del expediente técnico
+const (
+	altoA4Puntos           = 842.0
+	anchoA4Puntos          = 595.0
+	margenVerticalPulgadas = 0.75
+	margenLateralPulgadas  = 0.5
+	margenEncabezado       = 0.3
+	puntosPorPulgada       = 72.0
+	altoFilaPuntos         = 15.0
+	puntosPorCaracter      = 5.6 // ancho aproximado de un carácter de la fuente por defecto
+	tamanoPapelA4          = 9
+	maxLongitudProyecto    = 80
+)
+
+// BloqueFilas es un rango de filas que debe imprimirse en una misma página, como un APU completo
+type BloqueFilas struct {
+	Inicio int
+	Fin    int
+}
+
+// ConfigImpresion describe la presentación impresa de una hoja
+type ConfigImpresion struct {
+	Horizontal    bool
+	FilasTitulo   int       // filas que se repiten en cada página
+	UltimaColumna string    // última columna del área de impresión
+	Anchos        []float64 // anchos de columna de hojas escritas en streaming, que no se pueden leer del libro
+	UltimaFila    int
+	Bloques       []BloqueFilas
+	Plantilla     models.PlantillaExcel
+	Proyecto      string
+	Fecha         time.Time
+}
+
+// ConfigurarImpresion deja la hoja lista para imprimir: A4, márgenes, escala al ancho de página,
+// área y títulos de impresión, saltos de página entre bloques y encabezado/pie de página.
+// Se usa escala en lugar de "ajustar a página" porque Excel ignora los saltos manuales con esa opción.
+func ConfigurarImpresion(f *excelize.File, sheet string, config ConfigImpresion) error {
+	orientacion := "portrait"
+	anchoPagina, altoPagina := anchoA4Puntos, altoA4Puntos
+	if config.Horizontal {
+		orientacion = "landscape"
+		anchoPagina, altoPagina = altoA4Puntos, anchoA4Puntos
+	}
+
+	escala := calcularEscala(f, sheet, config.UltimaColumna, config.Anchos, anchoPagina)
+	tamano := tamanoPapelA4
+	ajuste := uint(math.Floor(escala * 100))
+	if err := f.SetPageLayout(sheet, &excelize.PageLayoutOptions{
+		Size:        &tamano,
+		Orientation: &orientacion,
+		AdjustTo:    &ajuste,
+	}); err != nil {
+		return fmt.Errorf("error configurando página: %v", err)
+	}
+
+	margenVertical, margenLateral, margenHF := margenVerticalPulgadas, margenLateralPulgadas, margenEncabezado
+	centrado := true
+	if err := f.SetPageMargins(sheet, &excelize.PageLayoutMarginsOptions{
+		Top:          &margenVertical,
+		Bottom:       &margenVertical,
+		Left:         &margenLateral,
+		Right:        &margenLateral,
+		Header:       &margenHF,
+		Footer:       &margenHF,
+		Horizontally: &centrado,
+	}); err != nil {
+		return fmt.Errorf("error configurando márgenes: %v", err)
+	}
+
+	if err := definirAreasImpresion(f, sheet, config); err != nil {
+		return err
+	}
+
+	altoUtil := altoPagina - 2*margenVertical*puntosPorPulgada
+	filasPorPagina := int(altoUtil / (altoFilaPuntos * float64(ajuste) / 100))
+	if err := insertarSaltos(f, sheet, config.Bloques, filasPorPagina, config.FilasTitulo); err != nil {
+		return err
+	}
+
+	return f.SetHeaderFooter(sheet, &excelize.HeaderFooterOptions{
+		OddHeader: TextoEncabezado(config.Plantilla, config.Proyecto),
+		OddFooter: TextoPie(config.Plantilla, config.Fecha),
+	})
+}
+
+// calcularEscala reduce la hoja lo necesario para que todas las columnas entren en el ancho de página
+func calcularEscala(f *excelize.File, sheet, ultimaColumna string, anchos []float64, anchoPagina float64) float64 {
+	ultima, err := excelize.ColumnNameToNumber(ultimaColumna)
+	if err != nil {
+		return 1
+	}
+
+	anchoTotal := 0.0
+	for col := 1; col <= ultima; col++ {
+		var ancho float64
+		if col <= len(anchos) && anchos[col-1] > 0 {
+			ancho = anchos[col-1]
+		} else {
+			nombre, _ := excelize.ColumnNumberToName(col)
+			ancho, _ = f.GetColWidth(sheet, nombre)
+		}
+		anchoTotal += ancho * puntosPorCaracter
+	}
+
+	anchoUtil := anchoPagina - 2*margenLateralPulgadas*puntosPorPulgada
+	if anchoTotal <= anchoUtil {
+		return 1
+	}
+	return math.Max(anchoUtil/anchoTotal, 0.1)
+}
+
+func definirAreasImpresion(f *excelize.File, sheet string, config ConfigImpresion) error {
+	// Volver a exportar la misma hoja reemplaza los nombres definidos previos
+	for _, nombre := range []string{"_xlnm.Print_Area", "_xlnm.Print_Titles"} {
+		f.DeleteDefinedName(&excelize.DefinedName{Name: nombre, Scope: sheet})
+	}
+
+	if config.UltimaFila > 0 {
+		err := f.SetDefinedName(&excelize.DefinedName{
+			Name:     "_xlnm.Print_Area",
+			RefersTo: fmt.Sprintf("'%s'!$A$1:$%s$%d", sheet, config.UltimaColumna, config.UltimaFila),
+			Scope:    sheet,
+		})
+		if err != nil {
+			return fmt.Errorf("error definiendo área de impresión: %v", err)
+		}
+	}
+
+	if config.FilasTitulo > 0 {
+		err := f.SetDefinedName(&excelize.DefinedName{
+			Name:     "_xlnm.Print_Titles",
+			RefersTo: fmt.Sprintf("'%s'!$1:$%d", sheet, config.FilasTitulo),
+			Scope:    sheet,
+		})
+		if err != nil {
+			return fmt.Errorf("error definiendo títulos de impresión: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// insertarSaltos agrega un salto de página antes de cada bloque que no cabe en el resto de la página
+func insertarSaltos(f *excelize.File, sheet string, bloques []BloqueFilas, filasPorPagina, filasTitulo int) error {
+	if filasPorPagina <= filasTitulo {
+		return nil
+	}
+
+	inicioPagina := 1
+	capacidad := filasPorPagina
+	for _, bloque := range bloques {
+		// Saltos automáticos de Excel en tramos largos sin bloques
+		for bloque.Inicio-inicioPagina >= capacidad {
+			inicioPagina += capacidad
+			capacidad = filasPorPagina - filasTitulo
+		}
+
+		if bloque.Fin-inicioPagina+1 > capacidad && bloque.Inicio > inicioPagina {
+			if err := f.InsertPageBreak(sheet, fmt.Sprintf("A%d", bloque.Inicio)); err != nil {
+				return fmt.Errorf("error insertando salto de página: %v", err)
+			}
+			inicioPagina = bloque.Inicio
+			capacidad = filasPorPagina - filasTitulo
+		}
+	}
+
+	return nil
+}
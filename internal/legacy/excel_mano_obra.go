@@ -0,0 +1,187 @@
+package legacy
+
+import (
+	"fmt"
+
+	"github.com/xuri/excelize/v2"
+	"goexcel/internal/costing"
+	"goexcel/internal/models"
+)
+
+// HojaManoObra es la hoja "Cálculo de Costo de Mano de Obra" del expediente; Excel limita los nombres
+// de hoja a 31 caracteres, así que el nombre completo va en el título
+const HojaManoObra = "Cálculo Costo Mano de Obra"
+
+// filaManoObra es una fila del desglose: su rótulo y el valor de cada categoría
+type filaManoObra struct {
+	rotulo     string
+	valor      func(costo models.CostoManoObra) costing.Decimal
+	porcentaje bool
+	resaltada  bool
+}
+
+var filasManoObra = []filaManoObra{
+	{rotulo: "Jornal básico", valor: func(c models.CostoManoObra) costing.Decimal { return c.JornalBasico }},
+	{rotulo: "Leyes sociales (%)", valor: func(c models.CostoManoObra) costing.Decimal { return c.PorcentajeLeyesSociales }, porcentaje: true},
+	{rotulo: "Leyes sociales", valor: func(c models.CostoManoObra) costing.Decimal { return c.LeyesSociales }},
+	{rotulo: "BUC (%)", valor: func(c models.CostoManoObra) costing.Decimal { return c.PorcentajeBUC }, porcentaje: true},
+	{rotulo: "Bonificación unificada de construcción", valor: func(c models.CostoManoObra) costing.Decimal { return c.BUC }},
+	{rotulo: "Bonificación por movilidad", valor: func(c models.CostoManoObra) costing.Decimal { return c.Movilidad }},
+	{rotulo: "Overol", valor: func(c models.CostoManoObra) costing.Decimal { return c.Overol }},
+	{rotulo: "Horas extra", valor: func(c models.CostoManoObra) costing.Decimal { return c.HorasExtra }},
+	{rotulo: "COSTO POR DÍA", valor: func(c models.CostoManoObra) costing.Decimal { return c.CostoDiario }, resaltada: true},
+	{rotulo: "Horas por día", valor: func(c models.CostoManoObra) costing.Decimal { return c.HorasDiarias }},
+}
+
+// AgregarHojaManoObra agrega la hoja con el desglose del costo diario y el costo hora-hombre de cada
+// categoría de construcción civil, una columna por categoría
+func AgregarHojaManoObra(f *excelize.File, calculo *models.CalculoManoObra, opciones models.OpcionesExportacion) error {
+	if _, err := f.NewSheet(HojaManoObra); err != nil {
+		return fmt.Errorf("error creando hoja de mano de obra: %v", err)
+	}
+	plantilla := ResolverPlantilla(opciones.Plantilla)
+	tamanoDatos := TamanoDatos(plantilla, 10)
+	simbolo := opciones.ParametrosCalculo().SimboloMoneda()
+
+	bordes := []excelize.Border{
+		{Type: "left", Color: "#000000", Style: 1},
+		{Type: "right", Color: "#000000", Style: 1},
+		{Type: "top", Color: "#000000", Style: 1},
+		{Type: "bottom", Color: "#000000", Style: 1},
+	}
+
+	titleStyle, _ := f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Bold: true, Size: 14, Color: "#FFFFFF", Family: plantilla.Fuente},
+		Fill:      excelize.Fill{Type: "pattern", Color: []string{plantilla.ColorTitulo}, Pattern: 1},
+		Alignment: &excelize.Alignment{Horizontal: "center", Vertical: "center"},
+	})
+	headerStyle, _ := f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Bold: true, Size: 11, Color: "#FFFFFF", Family: plantilla.Fuente},
+		Fill:      excelize.Fill{Type: "pattern", Color: []string{plantilla.ColorCabecera}, Pattern: 1},
+		Alignment: &excelize.Alignment{Horizontal: "center", Vertical: "center", WrapText: true},
+		Border:    bordes,
+	})
+	dataStyle, _ := f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Size: tamanoDatos, Family: plantilla.Fuente},
+		Alignment: &excelize.Alignment{Horizontal: "left", Vertical: "center"},
+		Border:    bordes,
+	})
+	numberStyle, _ := f.NewStyle(&excelize.Style{
+		Font:         &excelize.Font{Size: tamanoDatos, Family: plantilla.Fuente},
+		CustomNumFmt: &plantilla.FormatoNumero,
+		Alignment:    &excelize.Alignment{Horizontal: "right", Vertical: "center"},
+		Border:       bordes,
+	})
+	formatoPorcentaje := `0.00"%"`
+	porcentajeStyle, _ := f.NewStyle(&excelize.Style{
+		Font:         &excelize.Font{Italic: true, Size: tamanoDatos, Family: plantilla.Fuente},
+		CustomNumFmt: &formatoPorcentaje,
+		Alignment:    &excelize.Alignment{Horizontal: "right", Vertical: "center"},
+		Border:       bordes,
+	})
+	resaltadaStyle, _ := f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Bold: true, Size: tamanoDatos, Family: plantilla.Fuente},
+		Fill:      excelize.Fill{Type: "pattern", Color: []string{plantilla.ColorSeccion}, Pattern: 1},
+		Alignment: &excelize.Alignment{Horizontal: "left", Vertical: "center"},
+		Border:    bordes,
+	})
+	resaltadaNumeroStyle, _ := f.NewStyle(&excelize.Style{
+		Font:         &excelize.Font{Bold: true, Size: tamanoDatos, Family: plantilla.Fuente},
+		Fill:         excelize.Fill{Type: "pattern", Color: []string{plantilla.ColorSeccion}, Pattern: 1},
+		CustomNumFmt: &plantilla.FormatoNumero,
+		Alignment:    &excelize.Alignment{Horizontal: "right", Vertical: "center"},
+		Border:       bordes,
+	})
+	totalStyle, _ := f.NewStyle(&excelize.Style{
+		Font:         &excelize.Font{Bold: true, Size: 11, Color: "#FFFFFF", Family: plantilla.Fuente},
+		Fill:         excelize.Fill{Type: "pattern", Color: []string{plantilla.ColorTotal}, Pattern: 1},
+		CustomNumFmt: &plantilla.FormatoNumero,
+		Alignment:    &excelize.Alignment{Horizontal: "right", Vertical: "center"},
+		Border:       bordes,
+	})
+	totalTextoStyle, _ := f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Bold: true, Size: 11, Color: "#FFFFFF", Family: plantilla.Fuente},
+		Fill:      excelize.Fill{Type: "pattern", Color: []string{plantilla.ColorTotal}, Pattern: 1},
+		Alignment: &excelize.Alignment{Horizontal: "left", Vertical: "center"},
+		Border:    bordes,
+	})
+	notaStyle, _ := f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Italic: true, Size: 9, Family: plantilla.Fuente},
+		Alignment: &excelize.Alignment{Horizontal: "left", Vertical: "top", WrapText: true},
+	})
+
+	ultima, _ := excelize.ColumnNumberToName(len(calculo.Categorias) + 1)
+	f.SetColWidth(HojaManoObra, "A", "A", 40)
+	if len(calculo.Categorias) > 0 {
+		f.SetColWidth(HojaManoObra, "B", ultima, 16)
+	}
+
+	// Título y parámetros del convenio
+	f.MergeCell(HojaManoObra, "A1", ultima+"1")
+	f.SetCellValue(HojaManoObra, "A1", "CÁLCULO DE COSTO DE MANO DE OBRA")
+	f.SetCellStyle(HojaManoObra, "A1", ultima+"1", titleStyle)
+
+	convenio := fmt.Sprintf("Régimen de construcción civil %d", calculo.Anio)
+	if calculo.Descripcion != "" {
+		convenio += " - " + calculo.Descripcion
+	}
+	f.MergeCell(HojaManoObra, "A2", ultima+"2")
+	f.SetCellValue(HojaManoObra, "A2", fmt.Sprintf("%s   Vigente desde: %s", convenio, calculo.VigenteDesde))
+	f.MergeCell(HojaManoObra, "A3", ultima+"3")
+	f.SetCellValue(HojaManoObra, "A3", fmt.Sprintf("Jornada: %s h   Horas extra por día: %s h   Moneda: %s",
+		calculo.HorasJornada, calculo.HorasExtraDiarias, simbolo))
+
+	// Una columna por categoría
+	row := 5
+	f.SetCellValue(HojaManoObra, fmt.Sprintf("A%d", row), "Concepto")
+	for i, costo := range calculo.Categorias {
+		columna, _ := excelize.ColumnNumberToName(i + 2)
+		cabecera := costo.Categoria
+		if costo.CodigoRecurso != "" {
+			cabecera += "\n" + costo.CodigoRecurso
+		}
+		f.SetCellValue(HojaManoObra, fmt.Sprintf("%s%d", columna, row), cabecera)
+	}
+	f.SetCellStyle(HojaManoObra, fmt.Sprintf("A%d", row), fmt.Sprintf("%s%d", ultima, row), headerStyle)
+	f.SetRowHeight(HojaManoObra, row, 30)
+	row++
+
+	for _, fila := range filasManoObra {
+		texto, numero := dataStyle, numberStyle
+		if fila.porcentaje {
+			numero = porcentajeStyle
+		}
+		if fila.resaltada {
+			texto, numero = resaltadaStyle, resaltadaNumeroStyle
+		}
+
+		f.SetCellValue(HojaManoObra, fmt.Sprintf("A%d", row), fila.rotulo)
+		f.SetCellStyle(HojaManoObra, fmt.Sprintf("A%d", row), fmt.Sprintf("A%d", row), texto)
+		for i, costo := range calculo.Categorias {
+			columna, _ := excelize.ColumnNumberToName(i + 2)
+			celda := fmt.Sprintf("%s%d", columna, row)
+			f.SetCellValue(HojaManoObra, celda, fila.valor(costo).Float64())
+			f.SetCellStyle(HojaManoObra, celda, celda, numero)
+		}
+		row++
+	}
+
+	f.SetCellValue(HojaManoObra, fmt.Sprintf("A%d", row), fmt.Sprintf("COSTO HORA-HOMBRE (%s por hh)", simbolo))
+	f.SetCellStyle(HojaManoObra, fmt.Sprintf("A%d", row), fmt.Sprintf("A%d", row), totalTextoStyle)
+	for i, costo := range calculo.Categorias {
+		columna, _ := excelize.ColumnNumberToName(i + 2)
+		celda := fmt.Sprintf("%s%d", columna, row)
+		f.SetCellValue(HojaManoObra, celda, costo.CostoHH.Float64())
+		f.SetCellStyle(HojaManoObra, celda, celda, totalStyle)
+	}
+	row += 2
+
+	f.MergeCell(HojaManoObra, fmt.Sprintf("A%d", row), fmt.Sprintf("%s%d", ultima, row))
+	f.SetCellValue(HojaManoObra, fmt.Sprintf("A%d", row),
+		"Leyes sociales y BUC se calculan sobre el jornal básico; cada hora extra vale la hora básica más la sobretasa. "+
+			"Costo hora-hombre = costo por día / horas por día.")
+	f.SetCellStyle(HojaManoObra, fmt.Sprintf("A%d", row), fmt.Sprintf("%s%d", ultima, row), notaStyle)
+	f.SetRowHeight(HojaManoObra, row, 30)
+
+	return nil
+}
@@ -0,0 +1,152 @@
+package legacy
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+	"goexcel/internal/models"
+)
+
+// PlantillaPorDefecto devuelve los estilos institucionales usados cuando la organización no tiene plantilla
+func PlantillaPorDefecto() models.PlantillaExcel {
+	return models.PlantillaExcel{
+		ColorTitulo:   "#2F5597",
+		ColorCabecera: "#4F81BD",
+		ColorPartida:  "#305496",
+		ColorSeccion:  "#D9E2F3",
+		ColorTotal:    "#70AD47",
+		Fuente:        "Calibri",
+		FormatoNumero: "#,##0.00",
+	}
+}
+
+// ResolverPlantilla completa los campos vacíos de la plantilla con los valores por defecto
+func ResolverPlantilla(plantilla *models.PlantillaExcel) models.PlantillaExcel {
+	base := PlantillaPorDefecto()
+	if plantilla == nil {
+		return base
+	}
+
+	resultado := *plantilla
+	if resultado.ColorTitulo == "" {
+		resultado.ColorTitulo = base.ColorTitulo
+	}
+	if resultado.ColorCabecera == "" {
+		resultado.ColorCabecera = base.ColorCabecera
+	}
+	if resultado.ColorPartida == "" {
+		resultado.ColorPartida = base.ColorPartida
+	}
+	if resultado.ColorSeccion == "" {
+		resultado.ColorSeccion = base.ColorSeccion
+	}
+	if resultado.ColorTotal == "" {
+		resultado.ColorTotal = base.ColorTotal
+	}
+	if resultado.Fuente == "" {
+		resultado.Fuente = base.Fuente
+	}
+	if resultado.FormatoNumero == "" {
+		resultado.FormatoNumero = base.FormatoNumero
+	}
+
+	return resultado
+}
+
+// TamanoDatos devuelve el tamaño de fuente de las celdas de datos, respetando el del generador si la plantilla no lo define
+func TamanoDatos(plantilla models.PlantillaExcel, porDefecto float64) float64 {
+	if plantilla.TamanoFuente > 0 {
+		return plantilla.TamanoFuente
+	}
+	return porDefecto
+}
+
+// AplicarPlantilla aplica a la hoja los anchos de columna y registra el logo que usa el encabezado de página
+func AplicarPlantilla(f *excelize.File, sheet string, plantilla models.PlantillaExcel) error {
+	for columna, ancho := range plantilla.AnchosColumna {
+		if err := f.SetColWidth(sheet, columna, columna, ancho); err != nil {
+			return fmt.Errorf("ancho de columna inválido %s: %v", columna, err)
+		}
+	}
+
+	return AgregarLogo(f, sheet, plantilla)
+}
+
+// AgregarLogo registra el logo de la plantilla para el encabezado de página de la hoja
+func AgregarLogo(f *excelize.File, sheet string, plantilla models.PlantillaExcel) error {
+	if !TieneLogo(plantilla) {
+		return nil
+	}
+
+	err := f.AddHeaderFooterImage(sheet, &excelize.HeaderFooterImageOptions{
+		Position:  excelize.HeaderFooterImagePositionLeft,
+		File:      plantilla.Logo,
+		Extension: plantilla.LogoExtension,
+		Height:    "36pt",
+	})
+	if err != nil {
+		return fmt.Errorf("error agregando logo: %v", err)
+	}
+
+	return nil
+}
+
+// TieneLogo indica si la plantilla trae una imagen utilizable en el encabezado
+func TieneLogo(plantilla models.PlantillaExcel) bool {
+	return len(plantilla.Logo) > 0 && plantilla.LogoExtension != ""
+}
+
+// TextoEncabezado arma el encabezado de página: logo a la izquierda, empresa y proyecto al centro y RUC a la derecha
+func TextoEncabezado(plantilla models.PlantillaExcel, proyecto string) string {
+	var texto strings.Builder
+	if TieneLogo(plantilla) {
+		texto.WriteString("&L&G")
+	}
+
+	centro := ""
+	if plantilla.Empresa != "" {
+		centro = "&B" + escaparEncabezado(plantilla.Empresa) + "&B"
+	}
+	if proyecto != "" {
+		if len([]rune(proyecto)) > maxLongitudProyecto {
+			proyecto = string([]rune(proyecto)[:maxLongitudProyecto]) + "…"
+		}
+		if centro != "" {
+			centro += "\n"
+		}
+		centro += escaparEncabezado(proyecto)
+	}
+	if centro != "" {
+		texto.WriteString("&C" + centro)
+	}
+
+	if plantilla.RUC != "" {
+		texto.WriteString("&RRUC: " + escaparEncabezado(plantilla.RUC))
+	}
+	return texto.String()
+}
+
+// TextoPie arma el pie de página: ingeniero responsable con su CIP, numeración "Página X de Y" y fecha
+func TextoPie(plantilla models.PlantillaExcel, fecha time.Time) string {
+	var texto strings.Builder
+	if plantilla.Ingeniero != "" {
+		responsable := "Ing. " + plantilla.Ingeniero
+		if plantilla.CIP != "" {
+			responsable += " - CIP " + plantilla.CIP
+		}
+		texto.WriteString("&L" + escaparEncabezado(responsable))
+	}
+
+	texto.WriteString("&CPágina &P de &N")
+	if !fecha.IsZero() {
+		texto.WriteString("&R" + fecha.Format("02/01/2006"))
+	}
+	return texto.String()
+}
+
+// escaparEncabezado duplica los "&" para que Excel no los interprete como códigos de formato
+func escaparEncabezado(texto string) string {
+	return strings.ReplaceAll(texto, "&", "&&")
+}
@@ -0,0 +1,205 @@
+package legacy
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/xuri/excelize/v2"
+	"goexcel/internal/costing"
+	"goexcel/internal/models"
+)
+
+// HojaPresupuesto es la hoja con el árbol del presupuesto, sus subtotales y el pie
+const HojaPresupuesto = "Presupuesto"
+
+// coloresNivelPresupuesto rellena los títulos a partir del segundo nivel; el primero usa el color de cabecera
+var coloresNivelPresupuesto = []string{"#8DB4E2", "#C5D9F1", "#E7E6E6"}
+
+// AgregarHojaPresupuesto agrega la hoja "Presupuesto" con los títulos y partidas del reporte. Cada título
+// agrupa (esquema de Excel) a sus hijos y muestra su subtotal, de modo que sigue visible al colapsar.
+func AgregarHojaPresupuesto(f *excelize.File, reporte *models.ReportePresupuesto) error {
+	if _, err := f.NewSheet(HojaPresupuesto); err != nil {
+		return fmt.Errorf("error creando hoja de presupuesto: %v", err)
+	}
+	plantilla := ResolverPlantilla(reporte.Opciones.Plantilla)
+	tamanoDatos := TamanoDatos(plantilla, 10)
+
+	bordes := []excelize.Border{
+		{Type: "left", Color: "#000000", Style: 1},
+		{Type: "right", Color: "#000000", Style: 1},
+		{Type: "top", Color: "#000000", Style: 1},
+		{Type: "bottom", Color: "#000000", Style: 1},
+	}
+
+	titleStyle, _ := f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Bold: true, Size: 14, Color: "#FFFFFF", Family: plantilla.Fuente},
+		Fill:      excelize.Fill{Type: "pattern", Color: []string{plantilla.ColorTitulo}, Pattern: 1},
+		Alignment: &excelize.Alignment{Horizontal: "center", Vertical: "center"},
+	})
+	headerStyle, _ := f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Bold: true, Size: 11, Color: "#FFFFFF", Family: plantilla.Fuente},
+		Fill:      excelize.Fill{Type: "pattern", Color: []string{plantilla.ColorCabecera}, Pattern: 1},
+		Alignment: &excelize.Alignment{Horizontal: "center", Vertical: "center"},
+		Border:    bordes,
+	})
+	dataStyle, _ := f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Size: tamanoDatos, Family: plantilla.Fuente},
+		Alignment: &excelize.Alignment{Horizontal: "left", Vertical: "center"},
+		Border:    bordes,
+	})
+	numberStyle, _ := f.NewStyle(&excelize.Style{
+		Font:         &excelize.Font{Size: tamanoDatos, Family: plantilla.Fuente},
+		CustomNumFmt: &plantilla.FormatoNumero,
+		Alignment:    &excelize.Alignment{Horizontal: "right", Vertical: "center"},
+		Border:       bordes,
+	})
+	pieStyle, _ := f.NewStyle(&excelize.Style{
+		Font:         &excelize.Font{Bold: true, Size: tamanoDatos, Family: plantilla.Fuente},
+		Fill:         excelize.Fill{Type: "pattern", Color: []string{plantilla.ColorSeccion}, Pattern: 1},
+		CustomNumFmt: &plantilla.FormatoNumero,
+		Alignment:    &excelize.Alignment{Horizontal: "right", Vertical: "center"},
+		Border:       bordes,
+	})
+	totalStyle, _ := f.NewStyle(&excelize.Style{
+		Font:         &excelize.Font{Bold: true, Size: 11, Color: "#FFFFFF", Family: plantilla.Fuente},
+		Fill:         excelize.Fill{Type: "pattern", Color: []string{plantilla.ColorTotal}, Pattern: 1},
+		CustomNumFmt: &plantilla.FormatoNumero,
+		Alignment:    &excelize.Alignment{Horizontal: "right", Vertical: "center"},
+		Border:       bordes,
+	})
+
+	// Un estilo de texto y otro numérico por nivel de título
+	estilosNivel := make([][2]int, len(coloresNivelPresupuesto)+1)
+	for i := range estilosNivel {
+		fuente := &excelize.Font{Bold: true, Size: tamanoDatos, Family: plantilla.Fuente}
+		relleno := plantilla.ColorCabecera
+		if i == 0 {
+			fuente.Color = "#FFFFFF"
+		} else {
+			relleno = coloresNivelPresupuesto[i-1]
+		}
+		texto, _ := f.NewStyle(&excelize.Style{
+			Font:      fuente,
+			Fill:      excelize.Fill{Type: "pattern", Color: []string{relleno}, Pattern: 1},
+			Alignment: &excelize.Alignment{Horizontal: "left", Vertical: "center"},
+			Border:    bordes,
+		})
+		numero, _ := f.NewStyle(&excelize.Style{
+			Font:         fuente,
+			Fill:         excelize.Fill{Type: "pattern", Color: []string{relleno}, Pattern: 1},
+			CustomNumFmt: &plantilla.FormatoNumero,
+			Alignment:    &excelize.Alignment{Horizontal: "right", Vertical: "center"},
+			Border:       bordes,
+		})
+		estilosNivel[i] = [2]int{texto, numero}
+	}
+
+	escritor, err := NuevoEscritorHoja(f, HojaPresupuesto, reporte.Opciones.NivelColapsado)
+	if err != nil {
+		return err
+	}
+	escritor.AnchoColumnas([]float64{14, 50, 8, 12, 15, 18}, plantilla)
+	escritor.Congelar(3)
+
+	escritor.Combinar("A1", "F1")
+	escritor.Fila(1, 0, FilaCombinada("PRESUPUESTO", titleStyle, 6)...)
+	parametros := reporte.Opciones.ParametrosCalculo()
+	escritor.Combinar("A2", "F2")
+	escritor.Fila(2, 0, TextoParametros(parametros))
+
+	headers := []string{"Ítem", "Descripción", "Und.", "Metrado", "Precio " + parametros.SimboloMoneda(), "Parcial " + parametros.SimboloMoneda()}
+	cabeceras := make([]interface{}, len(headers))
+	for i, header := range headers {
+		cabeceras[i] = Celda(header, headerStyle)
+	}
+	escritor.Fila(3, 0, cabeceras...)
+
+	// El nivel de esquema de cada fila es la profundidad del título que la contiene
+	row := 4
+	reporte.Recorrer(func(nodo *models.NodoReporte) {
+		if nodo.EsTitulo() {
+			estilo := estilosNivel[min(nodo.Nivel, len(estilosNivel))-1]
+			escritor.Fila(row, nodo.Nivel-1,
+				Celda(nodo.Codigo, estilo[0]),
+				Celda(nodo.Descripcion, estilo[0]),
+				Celda(nil, estilo[0]),
+				Celda(nil, estilo[0]),
+				Celda(nil, estilo[0]),
+				Celda(nodo.Subtotal, estilo[1]),
+			)
+		} else {
+			partida := nodo.Partida
+			escritor.Fila(row, nodo.Nivel-1,
+				Celda(partida.Codigo, dataStyle),
+				Celda(partida.Descripcion, dataStyle),
+				Celda(partida.Unidad, dataStyle),
+				Celda(partida.Metrado, numberStyle),
+				Celda(partida.CostoUnitario, numberStyle),
+				Celda(partida.Parcial, numberStyle),
+			)
+		}
+		row++
+	})
+
+	// Pie del presupuesto, siempre visible
+	row++
+	for _, linea := range LineasPie(reporte.Pie) {
+		estilo := pieStyle
+		if linea.Total {
+			estilo = totalStyle
+		}
+		escritor.Combinar(fmt.Sprintf("A%d", row), fmt.Sprintf("E%d", row))
+		celdas := FilaCombinada(linea.Etiqueta, estilo, 6)
+		celdas[5] = Celda(linea.Monto, estilo)
+		escritor.Fila(row, 0, celdas...)
+		row++
+	}
+
+	return prepararHojaStream(f, HojaPresupuesto, escritor, plantilla, ConfigImpresion{
+		FilasTitulo:   3,
+		UltimaColumna: "F",
+		UltimaFila:    row - 1,
+		Proyecto:      reporte.Opciones.Proyecto,
+		Fecha:         reporte.Fecha,
+	})
+}
+
+// LineaPie es una línea del pie del presupuesto tal como se presenta en los reportes
+type LineaPie struct {
+	Etiqueta string
+	Monto    costing.Decimal
+	Total    bool
+}
+
+// LineasPie devuelve las líneas del pie desde el costo directo hasta el total; gastos generales y
+// utilidad solo aparecen si tienen porcentaje
+func LineasPie(pie models.PiePresupuesto) []LineaPie {
+	lineas := []LineaPie{{Etiqueta: "COSTO DIRECTO", Monto: pie.CostoDirecto}}
+	if pie.PorcentajeGastosGenerales > 0 {
+		lineas = append(lineas, LineaPie{Etiqueta: fmt.Sprintf("GASTOS GENERALES (%s%%)", formatearPorcentaje(pie.PorcentajeGastosGenerales)), Monto: pie.GastosGenerales})
+	}
+	if pie.PorcentajeUtilidad > 0 {
+		lineas = append(lineas, LineaPie{Etiqueta: fmt.Sprintf("UTILIDAD (%s%%)", formatearPorcentaje(pie.PorcentajeUtilidad)), Monto: pie.Utilidad})
+	}
+	return append(lineas,
+		LineaPie{Etiqueta: "SUBTOTAL", Monto: pie.Subtotal},
+		LineaPie{Etiqueta: fmt.Sprintf("IGV (%s%%)", formatearPorcentaje(pie.PorcentajeIGV)), Monto: pie.IGV},
+		LineaPie{Etiqueta: "TOTAL PRESUPUESTO", Monto: pie.Total, Total: true},
+	)
+}
+
+// formatearPorcentaje escribe el porcentaje con dos decimales: 18.00
+func formatearPorcentaje(porcentaje float64) string {
+	return fmt.Sprintf("%.2f", porcentaje)
+}
+
+// TextoParametros es la línea bajo el título de las hojas que indica con qué parámetros se calcularon
+// los montos
+func TextoParametros(parametros models.Parametros) string {
+	texto := fmt.Sprintf("Moneda: %s (%s)   Jornada: %s h   IGV: %s%%", parametros.Moneda, parametros.SimboloMoneda(),
+		strconv.FormatFloat(parametros.HorasJornada, 'f', -1, 64), formatearPorcentaje(parametros.PorcentajeIGV))
+	if parametros.CuadrillaDefineCantidad {
+		texto += "   Cantidad según cuadrilla"
+	}
+	return texto
+}
@@ -0,0 +1,310 @@
+package legacy
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/xuri/excelize/v2"
+	"goexcel/internal/models"
+)
+
+// HojaRiesgo es la hoja con el resultado del análisis de riesgo de costos por Monte Carlo
+const HojaRiesgo = "Análisis de Riesgo"
+
+// ConstruirExcelRiesgo genera el libro del análisis de riesgo: el resumen con el P80 y la contingencia,
+// los percentiles, las variables, el histograma del costo directo y el tornado de sensibilidades, con
+// gráficos nativos de Excel para los dos últimos
+func ConstruirExcelRiesgo(analisis *models.AnalisisRiesgo) (*excelize.File, error) {
+	f := excelize.NewFile()
+	f.SetSheetName("Sheet1", HojaRiesgo)
+
+	if err := agregarHojaRiesgo(f, analisis); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+func agregarHojaRiesgo(f *excelize.File, analisis *models.AnalisisRiesgo) error {
+	hoja := HojaRiesgo
+	plantilla := ResolverPlantilla(analisis.Opciones.Plantilla)
+	tamanoDatos := TamanoDatos(plantilla, 10)
+	simbolo := analisis.Opciones.ParametrosCalculo().SimboloMoneda()
+
+	bordes := []excelize.Border{
+		{Type: "left", Color: "#000000", Style: 1},
+		{Type: "right", Color: "#000000", Style: 1},
+		{Type: "top", Color: "#000000", Style: 1},
+		{Type: "bottom", Color: "#000000", Style: 1},
+	}
+
+	titleStyle, _ := f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Bold: true, Size: 14, Color: "#FFFFFF", Family: plantilla.Fuente},
+		Fill:      excelize.Fill{Type: "pattern", Color: []string{plantilla.ColorTitulo}, Pattern: 1},
+		Alignment: &excelize.Alignment{Horizontal: "center", Vertical: "center"},
+	})
+	seccionStyle, _ := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true, Size: 11, Family: plantilla.Fuente},
+		Fill: excelize.Fill{Type: "pattern", Color: []string{plantilla.ColorSeccion}, Pattern: 1},
+	})
+	headerStyle, _ := f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Bold: true, Size: 11, Color: "#FFFFFF", Family: plantilla.Fuente},
+		Fill:      excelize.Fill{Type: "pattern", Color: []string{plantilla.ColorCabecera}, Pattern: 1},
+		Alignment: &excelize.Alignment{Horizontal: "center", Vertical: "center", WrapText: true},
+		Border:    bordes,
+	})
+	dataStyle, _ := f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Size: tamanoDatos, Family: plantilla.Fuente},
+		Alignment: &excelize.Alignment{Horizontal: "left", Vertical: "center"},
+		Border:    bordes,
+	})
+	numberStyle, _ := f.NewStyle(&excelize.Style{
+		Font:         &excelize.Font{Size: tamanoDatos, Family: plantilla.Fuente},
+		CustomNumFmt: &plantilla.FormatoNumero,
+		Alignment:    &excelize.Alignment{Horizontal: "right", Vertical: "center"},
+		Border:       bordes,
+	})
+	enteroStyle, _ := f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Size: tamanoDatos, Family: plantilla.Fuente},
+		NumFmt:    3, // #,##0
+		Alignment: &excelize.Alignment{Horizontal: "right", Vertical: "center"},
+		Border:    bordes,
+	})
+	porcentajeStyle, _ := f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Size: tamanoDatos, Family: plantilla.Fuente},
+		NumFmt:    10, // 0.00%
+		Alignment: &excelize.Alignment{Horizontal: "right", Vertical: "center"},
+		Border:    bordes,
+	})
+	destacadoStyle, _ := f.NewStyle(&excelize.Style{
+		Font:         &excelize.Font{Bold: true, Size: 11, Color: "#FFFFFF", Family: plantilla.Fuente},
+		Fill:         excelize.Fill{Type: "pattern", Color: []string{plantilla.ColorTotal}, Pattern: 1},
+		CustomNumFmt: &plantilla.FormatoNumero,
+		Alignment:    &excelize.Alignment{Horizontal: "right", Vertical: "center"},
+		Border:       bordes,
+	})
+	destacadoTextoStyle, _ := f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Bold: true, Size: 11, Color: "#FFFFFF", Family: plantilla.Fuente},
+		Fill:      excelize.Fill{Type: "pattern", Color: []string{plantilla.ColorTotal}, Pattern: 1},
+		Alignment: &excelize.Alignment{Horizontal: "left", Vertical: "center"},
+		Border:    bordes,
+	})
+	notaStyle, _ := f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Italic: true, Size: 9, Family: plantilla.Fuente},
+		Alignment: &excelize.Alignment{Horizontal: "left", Vertical: "top", WrapText: true},
+	})
+
+	f.SetColWidth(hoja, "A", "A", 40)
+	f.SetColWidth(hoja, "B", "G", 15)
+
+	f.MergeCell(hoja, "A1", "G1")
+	f.SetCellValue(hoja, "A1", "ANÁLISIS DE RIESGO DE COSTOS")
+	f.SetCellStyle(hoja, "A1", "G1", titleStyle)
+
+	row := 3
+	seccion := func(titulo string) {
+		f.MergeCell(hoja, fmt.Sprintf("A%d", row), fmt.Sprintf("G%d", row))
+		f.SetCellValue(hoja, fmt.Sprintf("A%d", row), titulo)
+		f.SetCellStyle(hoja, fmt.Sprintf("A%d", row), fmt.Sprintf("G%d", row), seccionStyle)
+		row++
+	}
+	cabecera := func(headers ...string) {
+		for i, header := range headers {
+			celda, _ := excelize.CoordinatesToCellName(i+1, row)
+			f.SetCellValue(hoja, celda, header)
+			f.SetCellStyle(hoja, celda, celda, headerStyle)
+		}
+		f.SetRowHeight(hoja, row, 30)
+		row++
+	}
+
+	// Resumen; el costo directo del presupuesto queda en una celda fija para las fórmulas del tornado
+	seccion("RESUMEN")
+	resumen := []struct {
+		rotulo    string
+		valor     interface{}
+		estilo    int
+		destacado bool
+	}{
+		{"Iteraciones", analisis.Iteraciones, enteroStyle, false},
+		{"Semilla", strconv.FormatUint(analisis.Semilla, 10), dataStyle, false},
+		{fmt.Sprintf("Costo directo del presupuesto (%s)", simbolo), analisis.CostoDirecto.Float64(), numberStyle, false},
+		{"Probabilidad de no superarlo", analisis.ProbabilidadCostoDirecto / 100, porcentajeStyle, false},
+		{fmt.Sprintf("Costo directo P80 (%s)", simbolo), analisis.P80.CostoDirecto.Float64(), destacadoStyle, true},
+		{fmt.Sprintf("Contingencia P80 (%s)", simbolo), analisis.Contingencia.Float64(), destacadoStyle, true},
+		{fmt.Sprintf("Total P80 con gastos generales, utilidad e IGV (%s)", simbolo), analisis.P80.Total.Float64(), numberStyle, false},
+		{fmt.Sprintf("Media (%s)", simbolo), analisis.Media.Float64(), numberStyle, false},
+		{fmt.Sprintf("Desviación estándar (%s)", simbolo), analisis.DesviacionEstandar.Float64(), numberStyle, false},
+		{fmt.Sprintf("Mínimo (%s)", simbolo), analisis.Minimo.Float64(), numberStyle, false},
+		{fmt.Sprintf("Máximo (%s)", simbolo), analisis.Maximo.Float64(), numberStyle, false},
+	}
+	celdaCostoDirecto := ""
+	for i, dato := range resumen {
+		estiloRotulo := dataStyle
+		if dato.destacado {
+			estiloRotulo = destacadoTextoStyle
+		}
+		f.SetCellValue(hoja, fmt.Sprintf("A%d", row), dato.rotulo)
+		f.SetCellStyle(hoja, fmt.Sprintf("A%d", row), fmt.Sprintf("A%d", row), estiloRotulo)
+		f.SetCellValue(hoja, fmt.Sprintf("B%d", row), dato.valor)
+		f.SetCellStyle(hoja, fmt.Sprintf("B%d", row), fmt.Sprintf("B%d", row), dato.estilo)
+		if i == 2 {
+			celdaCostoDirecto = fmt.Sprintf("$B$%d", row)
+		}
+		row++
+	}
+	row++
+
+	seccion("PERCENTILES")
+	cabecera("Percentil", fmt.Sprintf("Costo directo (%s)", simbolo), fmt.Sprintf("Total (%s)", simbolo))
+	for _, p := range analisis.Percentiles {
+		f.SetCellValue(hoja, fmt.Sprintf("A%d", row), fmt.Sprintf("P%s", strconv.FormatFloat(p.Percentil, 'f', -1, 64)))
+		f.SetCellStyle(hoja, fmt.Sprintf("A%d", row), fmt.Sprintf("A%d", row), dataStyle)
+		f.SetCellValue(hoja, fmt.Sprintf("B%d", row), p.CostoDirecto.Float64())
+		f.SetCellValue(hoja, fmt.Sprintf("C%d", row), p.Total.Float64())
+		f.SetCellStyle(hoja, fmt.Sprintf("B%d", row), fmt.Sprintf("C%d", row), numberStyle)
+		row++
+	}
+	row++
+
+	seccion("VARIABLES (variación en % sobre el valor del presupuesto)")
+	cabecera("Variable", "Objetivo", "Distribución", "Mínimo %", "Moda %", "Máximo %")
+	for _, variable := range analisis.Variables {
+		f.SetCellValue(hoja, fmt.Sprintf("A%d", row), variable.Nombre)
+		f.SetCellValue(hoja, fmt.Sprintf("B%d", row), variable.Objetivo)
+		f.SetCellValue(hoja, fmt.Sprintf("C%d", row), variable.Distribucion.Tipo)
+		f.SetCellStyle(hoja, fmt.Sprintf("A%d", row), fmt.Sprintf("C%d", row), dataStyle)
+		f.SetCellValue(hoja, fmt.Sprintf("D%d", row), variable.Distribucion.Minimo)
+		if variable.Distribucion.Tipo != models.DistribucionUniforme {
+			f.SetCellValue(hoja, fmt.Sprintf("E%d", row), variable.Distribucion.Moda)
+		}
+		f.SetCellValue(hoja, fmt.Sprintf("F%d", row), variable.Distribucion.Maximo)
+		f.SetCellStyle(hoja, fmt.Sprintf("D%d", row), fmt.Sprintf("F%d", row), numberStyle)
+		row++
+	}
+	for _, nota := range analisis.NoAplicadas {
+		f.MergeCell(hoja, fmt.Sprintf("A%d", row), fmt.Sprintf("F%d", row))
+		f.SetCellValue(hoja, fmt.Sprintf("A%d", row), "No aplicada: "+nota)
+		f.SetCellStyle(hoja, fmt.Sprintf("A%d", row), fmt.Sprintf("F%d", row), notaStyle)
+		row++
+	}
+	row++
+
+	// Histograma: la primera columna es la etiqueta de cada barra del gráfico
+	seccion("HISTOGRAMA DEL COSTO DIRECTO")
+	cabecera("Intervalo", fmt.Sprintf("Desde (%s)", simbolo), fmt.Sprintf("Hasta (%s)", simbolo), "Frecuencia", "% iteraciones", "% acumulado")
+	cabeceraHistograma := row - 1
+	inicioHistograma := row
+	for _, intervalo := range analisis.Histograma {
+		f.SetCellValue(hoja, fmt.Sprintf("A%d", row), fmt.Sprintf("%.2f - %.2f", intervalo.Desde.Float64(), intervalo.Hasta.Float64()))
+		f.SetCellStyle(hoja, fmt.Sprintf("A%d", row), fmt.Sprintf("A%d", row), dataStyle)
+		f.SetCellValue(hoja, fmt.Sprintf("B%d", row), intervalo.Desde.Float64())
+		f.SetCellValue(hoja, fmt.Sprintf("C%d", row), intervalo.Hasta.Float64())
+		f.SetCellStyle(hoja, fmt.Sprintf("B%d", row), fmt.Sprintf("C%d", row), numberStyle)
+		f.SetCellValue(hoja, fmt.Sprintf("D%d", row), intervalo.Frecuencia)
+		f.SetCellStyle(hoja, fmt.Sprintf("D%d", row), fmt.Sprintf("D%d", row), enteroStyle)
+		f.SetCellValue(hoja, fmt.Sprintf("E%d", row), intervalo.Porcentaje/100)
+		f.SetCellValue(hoja, fmt.Sprintf("F%d", row), intervalo.Acumulado/100)
+		f.SetCellStyle(hoja, fmt.Sprintf("E%d", row), fmt.Sprintf("F%d", row), porcentajeStyle)
+		row++
+	}
+	finHistograma := row - 1
+	row++
+
+	// Tornado: las diferencias contra el costo directo son fórmulas y son las barras del gráfico
+	seccion("SENSIBILIDAD (TORNADO)")
+	cabecera("Variable", fmt.Sprintf("Con P10 (%s)", simbolo), fmt.Sprintf("Con P90 (%s)", simbolo), fmt.Sprintf("Rango (%s)", simbolo),
+		fmt.Sprintf("Δ con P10 (%s)", simbolo), fmt.Sprintf("Δ con P90 (%s)", simbolo), "Correlación")
+	cabeceraTornado := row - 1
+	inicioTornado := row
+	for _, sensibilidad := range analisis.Tornado {
+		f.SetCellValue(hoja, fmt.Sprintf("A%d", row), sensibilidad.Variable)
+		f.SetCellStyle(hoja, fmt.Sprintf("A%d", row), fmt.Sprintf("A%d", row), dataStyle)
+		f.SetCellValue(hoja, fmt.Sprintf("B%d", row), sensibilidad.Bajo.Float64())
+		f.SetCellValue(hoja, fmt.Sprintf("C%d", row), sensibilidad.Alto.Float64())
+		f.SetCellValue(hoja, fmt.Sprintf("D%d", row), sensibilidad.Rango.Float64())
+		// El valor calculado queda junto a la fórmula para los visores que no recalculan
+		f.SetCellValue(hoja, fmt.Sprintf("E%d", row), sensibilidad.Bajo.Restar(analisis.CostoDirecto).Float64())
+		f.SetCellFormula(hoja, fmt.Sprintf("E%d", row), fmt.Sprintf("B%d-%s", row, celdaCostoDirecto))
+		f.SetCellValue(hoja, fmt.Sprintf("F%d", row), sensibilidad.Alto.Restar(analisis.CostoDirecto).Float64())
+		f.SetCellFormula(hoja, fmt.Sprintf("F%d", row), fmt.Sprintf("C%d-%s", row, celdaCostoDirecto))
+		f.SetCellStyle(hoja, fmt.Sprintf("B%d", row), fmt.Sprintf("F%d", row), numberStyle)
+		f.SetCellValue(hoja, fmt.Sprintf("G%d", row), sensibilidad.Correlacion)
+		f.SetCellStyle(hoja, fmt.Sprintf("G%d", row), fmt.Sprintf("G%d", row), numberStyle)
+		row++
+	}
+	finTornado := row - 1
+	row++
+
+	f.MergeCell(hoja, fmt.Sprintf("A%d", row), fmt.Sprintf("G%d", row))
+	f.SetCellValue(hoja, fmt.Sprintf("A%d", row),
+		"Cada iteración varía precios, rendimientos y metrados según su distribución y recalcula Σ metrado × "+
+			"(Σ cantidad × precio) de todas las partidas. Más rendimiento reduce la mano de obra y los equipos por "+
+			"unidad. El tornado mueve una variable a su percentil 10 y 90 con las demás en el valor del presupuesto.")
+	f.SetCellStyle(hoja, fmt.Sprintf("A%d", row), fmt.Sprintf("G%d", row), notaStyle)
+	f.SetRowHeight(hoja, row, 45)
+
+	rango := func(columna string, desde, hasta int) string {
+		return fmt.Sprintf("'%s'!$%s$%d:$%s$%d", hoja, columna, desde, columna, hasta)
+	}
+	celda := func(columna string, fila int) string {
+		return fmt.Sprintf("'%s'!$%s$%d", hoja, columna, fila)
+	}
+	titulo := func(texto string) []excelize.RichTextRun {
+		return []excelize.RichTextRun{{Text: texto}}
+	}
+	sinVariarColores := false
+
+	if finHistograma >= inicioHistograma {
+		if err := f.AddChart(hoja, "I3", &excelize.Chart{
+			Type: excelize.Col,
+			Series: []excelize.ChartSeries{{
+				Name:       celda("D", cabeceraHistograma),
+				Categories: rango("A", inicioHistograma, finHistograma),
+				Values:     rango("D", inicioHistograma, finHistograma),
+			}},
+			Title:      titulo("Distribución del costo directo"),
+			VaryColors: &sinVariarColores,
+			Legend:     excelize.ChartLegend{Position: "none"},
+			YAxis:      excelize.ChartAxis{MajorGridLines: true},
+			Dimension:  excelize.ChartDimension{Width: 720, Height: 360},
+		}); err != nil {
+			return fmt.Errorf("error agregando histograma: %v", err)
+		}
+	}
+
+	if finTornado >= inicioTornado {
+		if err := f.AddChart(hoja, "I23", &excelize.Chart{
+			Type: excelize.Bar,
+			Series: []excelize.ChartSeries{
+				{
+					Name:       celda("E", cabeceraTornado),
+					Categories: rango("A", inicioTornado, finTornado),
+					Values:     rango("E", inicioTornado, finTornado),
+				},
+				{
+					Name:       celda("F", cabeceraTornado),
+					Categories: rango("A", inicioTornado, finTornado),
+					Values:     rango("F", inicioTornado, finTornado),
+				},
+			},
+			Title:     titulo("Tornado: sensibilidad del costo directo"),
+			Legend:    excelize.ChartLegend{Position: "bottom"},
+			XAxis:     excelize.ChartAxis{ReverseOrder: true},
+			YAxis:     excelize.ChartAxis{MajorGridLines: true, NumFmt: excelize.ChartNumFmt{CustomNumFmt: plantilla.FormatoNumero}},
+			Dimension: excelize.ChartDimension{Width: 720, Height: 400},
+		}); err != nil {
+			return fmt.Errorf("error agregando tornado: %v", err)
+		}
+	}
+
+	return ConfigurarImpresion(f, hoja, ConfigImpresion{
+		Horizontal:    true,
+		FilasTitulo:   1,
+		UltimaColumna: "G",
+		UltimaFila:    row,
+		Plantilla:     plantilla,
+		Proyecto:      analisis.Proyecto,
+		Fecha:         analisis.Fecha,
+	})
+}
@@ -0,0 +1,134 @@
+package legacy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+	"goexcel/internal/models"
+)
+
+// HojaSimulacion es la hoja con el resultado de una simulación "qué pasa si"
+const HojaSimulacion = "Simulación"
+
+// ConstruirExcelSimulacion genera el libro de la simulación: los ajustes aplicados y, antes y después
+// de aplicarlos, el subtotal de cada título, el costo de cada tipo de recurso y los totales. La
+// diferencia y la variación son fórmulas sobre las columnas base y simulado.
+func ConstruirExcelSimulacion(resultado *models.ResultadoSimulacion) (*excelize.File, error) {
+	f := excelize.NewFile()
+	f.SetSheetName("Sheet1", HojaSimulacion)
+	plantilla := ResolverPlantilla(resultado.Opciones.Plantilla)
+	estilos := nuevosEstilosComparativo(f, plantilla)
+
+	if err := agregarHojaSimulacion(f, resultado, plantilla, estilos); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+func agregarHojaSimulacion(f *excelize.File, resultado *models.ResultadoSimulacion, plantilla models.PlantillaExcel, estilos estilosComparativo) error {
+	hoja := HojaSimulacion
+
+	escritor, err := NuevoEscritorHoja(f, hoja, resultado.Opciones.NivelColapsado)
+	if err != nil {
+		return err
+	}
+	escritor.AnchoColumnas([]float64{12, 45, 15, 15, 15, 10}, plantilla)
+
+	escritor.Combinar("A1", "F1")
+	escritor.Fila(1, 0, FilaCombinada("SIMULACIÓN DE PRESUPUESTO", estilos.titulo, 6)...)
+
+	// Ajustes aplicados; los tipos de recurso se nombran como en el resultado
+	row := 3
+	escritor.Combinar(fmt.Sprintf("A%d", row), fmt.Sprintf("F%d", row))
+	escritor.Fila(row, 0, FilaCombinada("AJUSTES", estilos.grupo, 6)...)
+	row++
+	ajuste := func(codigo, descripcion, valor string) {
+		escritor.Combinar(fmt.Sprintf("C%d", row), fmt.Sprintf("F%d", row))
+		escritor.Fila(row, 0,
+			Celda(codigo, estilos.dato),
+			Celda(descripcion, estilos.dato),
+			Celda(valor, estilos.dato), Celda(nil, estilos.dato), Celda(nil, estilos.dato), Celda(nil, estilos.dato),
+		)
+		row++
+	}
+	for _, tipo := range resultado.TiposRecurso {
+		if porcentaje, existe := resultado.Ajustes.PorTipo[tipo.Codigo]; existe {
+			ajuste("", "Precio de "+strings.ToLower(tipo.Descripcion), fmt.Sprintf("%s%%", porcentaje))
+		}
+	}
+	for _, precio := range resultado.Ajustes.PorCodigo {
+		ajuste(precio.Codigo, "Precio del recurso", fmt.Sprintf("%s%%", precio.Porcentaje))
+	}
+	for _, rendimiento := range resultado.Ajustes.Rendimientos {
+		ajuste(rendimiento.Titulo, "Rendimiento de las partidas del título", fmt.Sprintf("× %s", rendimiento.Factor))
+	}
+	for _, nota := range resultado.NoAplicados {
+		ajuste("", "No aplicado", nota)
+	}
+	row++
+
+	moneda := resultado.Opciones.ParametrosCalculo().SimboloMoneda()
+	cabecera := func(primera string) {
+		headers := []string{primera, "Descripción", "Base " + moneda, "Simulado " + moneda, "Diferencia " + moneda, "Var. %"}
+		cabeceras := make([]interface{}, len(headers))
+		for i, header := range headers {
+			cabeceras[i] = Celda(header, estilos.cabecera)
+		}
+		escritor.Fila(row, 0, cabeceras...)
+		row++
+	}
+	total := func(nivel int, codigo, descripcion string, valores models.TotalSimulacion, texto, numero, porcentaje int) {
+		escritor.Fila(row, nivel,
+			Celda(codigo, texto),
+			Celda(descripcion, texto),
+			Celda(valores.Base, numero),
+			Celda(valores.Simulado, numero),
+			excelize.Cell{StyleID: numero, Formula: fmt.Sprintf("D%d-C%d", row, row), Value: valores.Diferencia},
+			celdaVariacionSimulacion(row, valores, porcentaje),
+		)
+		row++
+	}
+
+	// Subtotales de los títulos, agrupados con el esquema de Excel
+	cabecera("Ítem")
+	for _, titulo := range resultado.Titulos {
+		texto, numero, porcentaje := estilos.dato, estilos.numero, estilos.porcentaje
+		if titulo.Nivel == 1 {
+			texto, numero, porcentaje = estilos.grupo, estilos.grupoNumero, estilos.grupoPorcentaje
+		}
+		total(titulo.Nivel-1, titulo.Codigo, titulo.Descripcion, titulo, texto, numero, porcentaje)
+	}
+	total(0, "", "COSTO DIRECTO", resultado.CostoDirecto, estilos.total, estilos.total, estilos.totalPorcentaje)
+	total(0, "", "TOTAL PRESUPUESTO", resultado.Total, estilos.total, estilos.total, estilos.totalPorcentaje)
+	row++
+
+	cabecera("Tipo")
+	for _, tipo := range resultado.TiposRecurso {
+		total(0, "", tipo.Descripcion, tipo, estilos.dato, estilos.numero, estilos.porcentaje)
+	}
+	row++
+
+	escritor.Combinar(fmt.Sprintf("A%d", row), fmt.Sprintf("F%d", row))
+	escritor.Fila(row, 0, FilaCombinada(
+		"Costo por tipo = Σ metrado × subtotal del tipo en el APU; por el redondeo de cada parcial puede diferir en "+
+			"céntimos del costo directo. La simulación no modifica el proyecto.", estilos.dato, 6)...)
+
+	return prepararHojaStream(f, hoja, escritor, plantilla, ConfigImpresion{
+		FilasTitulo:   1,
+		UltimaColumna: "F",
+		UltimaFila:    row,
+		Proyecto:      resultado.Proyecto,
+		Fecha:         resultado.Fecha,
+	})
+}
+
+// celdaVariacionSimulacion es la diferencia sobre el valor base; vacía si el base es cero
+func celdaVariacionSimulacion(row int, valores models.TotalSimulacion, estilo int) excelize.Cell {
+	var valor interface{} = ""
+	if valores.VariacionPorcentaje != nil {
+		valor = valores.VariacionPorcentaje.Float64() / 100
+	}
+	return excelize.Cell{StyleID: estilo, Formula: fmt.Sprintf(`IF(C%d=0,"",E%d/C%d)`, row, row, row), Value: valor}
+}
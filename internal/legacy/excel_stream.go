@@ -0,0 +1,150 @@
+package legacy
+
+import (
+	"fmt"
+
+	"github.com/xuri/excelize/v2"
+	"goexcel/internal/costing"
+	"goexcel/internal/models"
+)
+
+// EscritorHoja escribe una hoja grande fila por fila con el StreamWriter de excelize, sin mantener
+// las celdas en memoria. Las filas deben escribirse en orden ascendente; el nivel de esquema y la
+// visibilidad se fijan al escribir cada fila porque no pueden modificarse después.
+// El primer error se conserva y lo devuelve Cerrar, como en bufio.Writer.
+type EscritorHoja struct {
+	sw             *excelize.StreamWriter
+	nivelColapsado int
+	anchos         []float64
+	err            error
+}
+
+// NuevoEscritorHoja prepara la hoja para escritura en streaming. Las propiedades que Excel
+// guarda antes de los datos (resumen del esquema sobre el detalle) se fijan aquí.
+func NuevoEscritorHoja(f *excelize.File, sheet string, nivelColapsado int) (*EscritorHoja, error) {
+	if err := ConfigurarEsquema(f, sheet); err != nil {
+		return nil, fmt.Errorf("error configurando esquema de %s: %v", sheet, err)
+	}
+
+	sw, err := f.NewStreamWriter(sheet)
+	if err != nil {
+		return nil, fmt.Errorf("error creando escritor de %s: %v", sheet, err)
+	}
+
+	return &EscritorHoja{sw: sw, nivelColapsado: nivelColapsado}, nil
+}
+
+// AnchoColumnas fija los anchos de columna, con prioridad para los de la plantilla de la organización.
+// Debe llamarse antes de escribir filas.
+func (e *EscritorHoja) AnchoColumnas(anchos []float64, plantilla models.PlantillaExcel) {
+	e.anchos = append([]float64(nil), anchos...)
+	for columna, ancho := range plantilla.AnchosColumna {
+		col, err := excelize.ColumnNameToNumber(columna)
+		if err != nil {
+			e.registrar(fmt.Errorf("ancho de columna inválido %s: %v", columna, err))
+			return
+		}
+		for len(e.anchos) < col {
+			e.anchos = append(e.anchos, 0)
+		}
+		e.anchos[col-1] = ancho
+	}
+
+	for i, ancho := range e.anchos {
+		if ancho > 0 {
+			e.registrar(e.sw.SetColWidth(i+1, i+1, ancho))
+		}
+	}
+}
+
+// Anchos devuelve los anchos aplicados, necesarios para calcular la escala de impresión
+func (e *EscritorHoja) Anchos() []float64 {
+	return e.anchos
+}
+
+// Congelar fija las filas superiores; debe llamarse antes de escribir filas
+func (e *EscritorHoja) Congelar(filas int) {
+	e.registrar(e.sw.SetPanes(&excelize.Panes{
+		Freeze:      true,
+		YSplit:      filas,
+		TopLeftCell: fmt.Sprintf("A%d", filas+1),
+		ActivePane:  "bottomLeft",
+	}))
+}
+
+// Fila escribe una fila con su nivel de esquema; se oculta si queda dentro del nivel colapsado.
+// Los montos Decimal se escriben como números de Excel.
+func (e *EscritorHoja) Fila(row, nivel int, celdas ...interface{}) {
+	if e.err != nil {
+		return
+	}
+	for i, celda := range celdas {
+		celdas[i] = ValorCelda(celda)
+	}
+	if nivel > models.MaxNivelEsquema {
+		nivel = models.MaxNivelEsquema
+	}
+
+	opts := excelize.RowOpts{
+		OutlineLevel: nivel,
+		Hidden:       e.nivelColapsado > 0 && nivel >= e.nivelColapsado,
+	}
+	e.registrar(e.sw.SetRow(fmt.Sprintf("A%d", row), celdas, opts))
+}
+
+// Vacia escribe una fila en blanco dentro de un grupo para que el esquema no se corte
+func (e *EscritorHoja) Vacia(row, nivel int) {
+	if nivel > 0 {
+		e.Fila(row, nivel)
+	}
+}
+
+// Combinar combina un rango de celdas de la hoja
+func (e *EscritorHoja) Combinar(desde, hasta string) {
+	if e.err == nil {
+		e.registrar(e.sw.MergeCell(desde, hasta))
+	}
+}
+
+// Cerrar termina la escritura de la hoja. La configuración de página debe aplicarse antes de cerrar.
+func (e *EscritorHoja) Cerrar() error {
+	if e.err != nil {
+		return e.err
+	}
+	return e.sw.Flush()
+}
+
+func (e *EscritorHoja) registrar(err error) {
+	if err != nil && e.err == nil {
+		e.err = err
+	}
+}
+
+// ValorCelda convierte los Decimal, sueltos o dentro de una celda, en el número que guarda Excel; los
+// demás valores pasan igual. excelize escribiría un Decimal como texto.
+func ValorCelda(valor interface{}) interface{} {
+	switch v := valor.(type) {
+	case costing.Decimal:
+		return v.Float64()
+	case excelize.Cell:
+		v.Value = ValorCelda(v.Value)
+		return v
+	}
+	return valor
+}
+
+// Celda crea una celda con estilo para el escritor
+func Celda(valor interface{}, estilo int) excelize.Cell {
+	return excelize.Cell{Value: valor, StyleID: estilo}
+}
+
+// FilaCombinada devuelve las celdas de una fila cuyo texto ocupa las primeras columnas con un mismo
+// estilo; las celdas vacías llevan el estilo para que el rango combinado conserve relleno y bordes
+func FilaCombinada(valor interface{}, estilo int, columnas int) []interface{} {
+	celdas := make([]interface{}, columnas)
+	celdas[0] = Celda(valor, estilo)
+	for i := 1; i < columnas; i++ {
+		celdas[i] = Celda(nil, estilo)
+	}
+	return celdas
+}
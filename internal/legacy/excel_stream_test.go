@@ -0,0 +1,59 @@
+package legacy
+
+import (
+	"fmt"
+	"testing"
+
+	"goexcel/internal/costing"
+	"goexcel/internal/models"
+)
+
+// partidasBenchmark arma n partidas de cuatro títulos con mano de obra, materiales y equipos
+func partidasBenchmark(n int) []PartidaLegacy {
+	partidas := make([]PartidaLegacy, n)
+	for i := range partidas {
+		partidas[i] = PartidaLegacy{
+			Codigo:      fmt.Sprintf("%02d.%02d.%04d", i%4+1, i%25+1, i+1),
+			Descripcion: fmt.Sprintf("Partida de prueba %d", i+1),
+			Unidad:      "m3",
+			Rendimiento: 25,
+			ManoObra: []RecursoLegacy{
+				{Codigo: "0147010002", Descripcion: "OPERARIO", Unidad: "hh", Cuadrilla: 1, Cantidad: costing.NuevoDecimal(3200, 4), Precio: costing.NuevoDecimal(2382, 2)},
+				{Codigo: "0147010004", Descripcion: "PEON", Unidad: "hh", Cuadrilla: 2, Cantidad: costing.NuevoDecimal(6400, 4), Precio: costing.NuevoDecimal(1721, 2)},
+			},
+			Materiales: []RecursoLegacy{
+				{Codigo: "0221000001", Descripcion: "CEMENTO PORTLAND TIPO I", Unidad: "bls", Cantidad: costing.NuevoDecimal(97000, 4), Precio: costing.NuevoDecimal(2850, 2)},
+				{Codigo: "0238000000", Descripcion: "HORMIGON", Unidad: "m3", Cantidad: costing.NuevoDecimal(12000, 4), Precio: costing.NuevoDecimal(6000, 2)},
+			},
+			Equipos: []RecursoLegacy{
+				{Codigo: "0337010001", Descripcion: "HERRAMIENTAS MANUALES", Unidad: "%mo", Cantidad: costing.NuevoDecimal(300, 4), Precio: costing.NuevoDecimal(1863, 2)},
+			},
+		}
+	}
+	return partidas
+}
+
+func BenchmarkEscribirExcel10k(b *testing.B) {
+	partidas := partidasBenchmark(10000)
+	opciones := models.OpcionesExportacion{Proyecto: "Benchmark", NivelColapsado: 2}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	var libro contadorBytes
+	for i := 0; i < b.N; i++ {
+		libro = 0
+		if err := EscribirExcel(&libro, partidas, opciones); err != nil {
+			b.Fatal(err)
+		}
+	}
+	// excelize retiene el libro comprimido completo antes de escribirlo: es el piso de memoria por descarga
+	b.ReportMetric(float64(libro), "bytes/libro")
+}
+
+// contadorBytes descarta lo escrito y cuenta cuántos bytes recibió
+type contadorBytes int64
+
+func (c *contadorBytes) Write(p []byte) (int, error) {
+	*c += contadorBytes(len(p))
+	return len(p), nil
+}
@@ -1,33 +1,39 @@
 package models
 
+import "goexcel/internal/costing"
+
 // Estructuras para formato .acu
 type ACUProject struct {
-	ID          string      `json:"id"`
-	Nombre      string      `json:"nombre"`
-	Descripcion string      `json:"descripcion"`
-	Moneda      string      `json:"moneda"`
+	ID          string       `json:"id"`
+	Nombre      string       `json:"nombre"`
+	Descripcion string       `json:"descripcion"`
+	Moneda      string       `json:"moneda"`
 	Partidas    []ACUPartida `json:"partidas"`
+
+	Parametros *ParametrosCalculo `json:"parametros,omitempty"` // declarados en el bloque @proyecto
 }
 
 type ACUPartida struct {
-	ID           string        `json:"id"`
-	Codigo       string        `json:"codigo"`
-	Descripcion  string        `json:"descripcion"`
-	Unidad       string        `json:"unidad"`
-	Rendimiento  float64       `json:"rendimiento"`
-	ManoObra     []ACURecurso  `json:"mano_obra,omitempty"`
-	Materiales   []ACURecurso  `json:"materiales,omitempty"`
-	Equipos      []ACURecurso  `json:"equipos,omitempty"`
-	Subcontratos []ACURecurso  `json:"subcontratos,omitempty"`
+	ID           string       `json:"id"`
+	Codigo       string       `json:"codigo"`
+	Descripcion  string       `json:"descripcion"`
+	Unidad       string       `json:"unidad"`
+	Rendimiento  float64      `json:"rendimiento"`
+	ManoObra     []ACURecurso `json:"mano_obra,omitempty"`
+	Materiales   []ACURecurso `json:"materiales,omitempty"`
+	Equipos      []ACURecurso `json:"equipos,omitempty"`
+	Subcontratos []ACURecurso `json:"subcontratos,omitempty"`
 }
 
 type ACURecurso struct {
-	Codigo      string   `json:"codigo"`
-	Descripcion string   `json:"descripcion"`
-	Unidad      string   `json:"unidad"`
-	Cantidad    float64  `json:"cantidad"`
-	Precio      float64  `json:"precio"`
-	Cuadrilla   *float64 `json:"cuadrilla,omitempty"`
+	Codigo      string           `json:"codigo"`
+	Descripcion string           `json:"descripcion"`
+	Unidad      string           `json:"unidad"`
+	Cantidad    costing.Decimal  `json:"cantidad"`
+	Precio      costing.Decimal  `json:"precio"`
+	Cuadrilla   *float64         `json:"cuadrilla,omitempty"`
+	Moneda      string           `json:"moneda,omitempty"`
+	Desperdicio *costing.Decimal `json:"desperdicio,omitempty"`
 }
 
 // Token types para el parser
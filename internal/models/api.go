@@ -1,10 +1,14 @@
 package models
 
+import "goexcel/internal/costing"
+
 // Request structures for API
 type ProyectoRequest struct {
 	Nombre      string `json:"nombre" validate:"required"`
 	Descripcion string `json:"descripcion"`
 	Moneda      string `json:"moneda"`
+
+	Parametros *ParametrosCalculo `json:"parametros,omitempty"` // sin declarar se heredan de la organización
 }
 
 type PartidaRequest struct {
@@ -19,12 +23,14 @@ type PartidaRequest struct {
 }
 
 type RecursoRequest struct {
-	Codigo      string   `json:"codigo" validate:"required"`
-	Descripcion string   `json:"descripcion" validate:"required"`
-	Unidad      string   `json:"unidad" validate:"required"`
-	Cantidad    float64  `json:"cantidad" validate:"min=0"`
-	Precio      float64  `json:"precio" validate:"min=0"`
-	Cuadrilla   *float64 `json:"cuadrilla,omitempty"`
+	Codigo      string           `json:"codigo" validate:"required"`
+	Descripcion string           `json:"descripcion" validate:"required"`
+	Unidad      string           `json:"unidad" validate:"required"`
+	Cantidad    costing.Decimal  `json:"cantidad" validate:"min=0"`
+	Precio      costing.Decimal  `json:"precio" validate:"min=0"`
+	Cuadrilla   *float64         `json:"cuadrilla,omitempty"`
+	Moneda      string           `json:"moneda,omitempty"`      // moneda del precio; vacía: la del proyecto
+	Desperdicio *costing.Decimal `json:"desperdicio,omitempty"` // porcentaje sobre la cantidad neta
 }
 
 // Response structures for API
@@ -55,7 +61,7 @@ type PartidaResponse struct {
 	Descripcion  string            `json:"descripcion"`
 	Unidad       string            `json:"unidad"`
 	Rendimiento  float64           `json:"rendimiento"`
-	CostoTotal   float64           `json:"costo_total"`
+	CostoTotal   costing.Decimal   `json:"costo_total"`
 	ManoObra     []RecursoResponse `json:"mano_obra"`
 	Materiales   []RecursoResponse `json:"materiales"`
 	Equipos      []RecursoResponse `json:"equipos"`
@@ -63,24 +69,26 @@ type PartidaResponse struct {
 }
 
 type RecursoResponse struct {
-	ID          string   `json:"id"`
-	Codigo      string   `json:"codigo"`
-	Descripcion string   `json:"descripcion"`
-	Unidad      string   `json:"unidad"`
-	Cantidad    float64  `json:"cantidad"`
-	Precio      float64  `json:"precio"`
-	Cuadrilla   *float64 `json:"cuadrilla,omitempty"`
-	Parcial     float64  `json:"parcial"`
+	ID          string           `json:"id"`
+	Codigo      string           `json:"codigo"`
+	Descripcion string           `json:"descripcion"`
+	Unidad      string           `json:"unidad"`
+	Cantidad    costing.Decimal  `json:"cantidad"`
+	Precio      costing.Decimal  `json:"precio"`
+	Cuadrilla   *float64         `json:"cuadrilla,omitempty"`
+	Moneda      string           `json:"moneda,omitempty"`
+	Desperdicio *costing.Decimal `json:"desperdicio,omitempty"`
+	Parcial     costing.Decimal  `json:"parcial"`
 }
 
 type ProjectStats struct {
-	TotalPartidas     int     `json:"total_partidas"`
-	TotalRecursos     int     `json:"total_recursos"`
-	CostoTotal        float64 `json:"costo_total"`
-	CostoManoObra     float64 `json:"costo_mano_obra"`
-	CostoMateriales   float64 `json:"costo_materiales"`
-	CostoEquipos      float64 `json:"costo_equipos"`
-	CostoSubcontratos float64 `json:"costo_subcontratos"`
+	TotalPartidas     int             `json:"total_partidas"`
+	TotalRecursos     int             `json:"total_recursos"`
+	CostoTotal        costing.Decimal `json:"costo_total"`
+	CostoManoObra     costing.Decimal `json:"costo_mano_obra"`
+	CostoMateriales   costing.Decimal `json:"costo_materiales"`
+	CostoEquipos      costing.Decimal `json:"costo_equipos"`
+	CostoSubcontratos costing.Decimal `json:"costo_subcontratos"`
 }
 
 // Error response structure
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+
+	"goexcel/internal/costing"
+)
+
+// ComparativoPresupuesto alinea por código las partidas de dos presupuestos, por ejemplo el del
+// expediente técnico (base) y la oferta de un postor (comparado)
+type ComparativoPresupuesto struct {
+	Base           string              `json:"base"`
+	Comparado      string              `json:"comparado"`
+	Umbral         float64             `json:"umbral"` // % de desviación a partir del cual se resalta una fila
+	Filas          []FilaComparativo   `json:"filas"`  // títulos y partidas comunes, en el orden del presupuesto base
+	SoloBase       []*PartidaReporte   `json:"solo_base"`
+	SoloComparado  []*PartidaReporte   `json:"solo_comparado"`
+	TotalBase      costing.Decimal     `json:"total_base"` // costos directos completos, con las partidas no comunes
+	TotalComparado costing.Decimal     `json:"total_comparado"`
+	Fecha          time.Time           `json:"fecha"`
+	Opciones       OpcionesExportacion `json:"-"`
+}
+
+// FilaComparativo es un título, con los subtotales de ambos presupuestos, o una partida común
+type FilaComparativo struct {
+	Codigo      string             `json:"codigo"`
+	Descripcion string             `json:"descripcion"`
+	Unidad      string             `json:"unidad,omitempty"`
+	Nivel       int                `json:"nivel"`
+	EsTitulo    bool               `json:"es_titulo"`
+	Base        ValoresComparativo `json:"base"`
+	Comparado   ValoresComparativo `json:"comparado"`
+}
+
+// ValoresComparativo son los valores de un lado de la comparación; los títulos solo tienen parcial
+type ValoresComparativo struct {
+	Metrado       costing.Decimal `json:"metrado"`
+	CostoUnitario costing.Decimal `json:"costo_unitario"`
+	Parcial       costing.Decimal `json:"parcial"`
+}
+
+// decimalesVariacion es la precisión de la variación como fracción del parcial base
+var decimalesVariacion = costing.Redondeo{Decimales: 8, Modo: costing.MitadArriba}
+
+// Diferencia es el parcial comparado menos el base
+func (f FilaComparativo) Diferencia() costing.Decimal {
+	return f.Comparado.Parcial.Restar(f.Base.Parcial)
+}
+
+// Variacion es la diferencia en % del parcial base; 0 si el base no tiene parcial
+func (f FilaComparativo) Variacion() float64 {
+	if f.Base.Parcial.EsCero() {
+		return 0
+	}
+	return f.Diferencia().Dividir(f.Base.Parcial, decimalesVariacion).Float64() * 100
+}
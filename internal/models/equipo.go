@@ -0,0 +1,122 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"goexcel/internal/costing"
+)
+
+// AnalisisEquipo son los datos con los que se calcula el costo por hora-máquina (hm) de un equipo
+// del catálogo
+type AnalisisEquipo struct {
+	ID                      uuid.UUID        `json:"id"`
+	OrganizacionID          *uuid.UUID       `json:"organizacion_id,omitempty"` // nil: análisis global
+	CodigoRecurso           string           `json:"codigo_recurso"`            // recurso de equipo que recibe el costo horario
+	Descripcion             string           `json:"descripcion"`
+	PotenciaHP              *costing.Decimal `json:"potencia_hp,omitempty"`
+	ValorAdquisicion        costing.Decimal  `json:"valor_adquisicion"`
+	VidaEconomicaAnios      costing.Decimal  `json:"vida_economica_anios"`
+	HorasAnuales            costing.Decimal  `json:"horas_anuales"`
+	PorcentajeRescate       costing.Decimal  `json:"porcentaje_rescate"` // del valor de adquisición
+	TasaInteresAnual        costing.Decimal  `json:"tasa_interes_anual"`
+	PorcentajeSegurosAnual  costing.Decimal  `json:"porcentaje_seguros_anual"` // seguros, impuestos y almacenaje
+	PorcentajeMantenimiento costing.Decimal  `json:"porcentaje_mantenimiento"` // de la depreciación
+	ValorNeumaticos         costing.Decimal  `json:"valor_neumaticos"`
+	VidaNeumaticosHoras     *costing.Decimal `json:"vida_neumaticos_horas,omitempty"`
+	Combustible             InsumoEquipo     `json:"combustible"` // cantidad en gal/h
+	Lubricante              InsumoEquipo     `json:"lubricante"`  // cantidad en gal/h
+	Operador                InsumoEquipo     `json:"operador"`    // cantidad en hh por hm
+	CreatedAt               time.Time        `json:"created_at"`
+	UpdatedAt               time.Time        `json:"updated_at"`
+}
+
+// InsumoEquipo es un insumo que consume el equipo por hora-máquina. Con código, su precio se toma de la
+// lista de precios o del catálogo y Precio solo se usa si el recurso no tiene uno.
+type InsumoEquipo struct {
+	Codigo   string          `json:"codigo,omitempty"`
+	Cantidad costing.Decimal `json:"cantidad"`
+	Precio   costing.Decimal `json:"precio"`
+}
+
+// AnalisisEquipoRequest crea o reemplaza un análisis; sin horas_anuales se usan 2000 y sin
+// porcentaje_rescate, 20 %
+type AnalisisEquipoRequest struct {
+	CodigoRecurso           string           `json:"codigo_recurso"`
+	Descripcion             string           `json:"descripcion"`
+	PotenciaHP              *costing.Decimal `json:"potencia_hp,omitempty"`
+	ValorAdquisicion        costing.Decimal  `json:"valor_adquisicion"`
+	VidaEconomicaAnios      costing.Decimal  `json:"vida_economica_anios"`
+	HorasAnuales            costing.Decimal  `json:"horas_anuales"`
+	PorcentajeRescate       *costing.Decimal `json:"porcentaje_rescate,omitempty"`
+	TasaInteresAnual        costing.Decimal  `json:"tasa_interes_anual"`
+	PorcentajeSegurosAnual  costing.Decimal  `json:"porcentaje_seguros_anual"`
+	PorcentajeMantenimiento costing.Decimal  `json:"porcentaje_mantenimiento"`
+	ValorNeumaticos         costing.Decimal  `json:"valor_neumaticos"`
+	VidaNeumaticosHoras     *costing.Decimal `json:"vida_neumaticos_horas,omitempty"`
+	Combustible             InsumoEquipo     `json:"combustible"`
+	Lubricante              InsumoEquipo     `json:"lubricante"`
+	Operador                InsumoEquipo     `json:"operador"`
+}
+
+// CostoHorarioEquipo es el desglose del costo por hora-máquina de un equipo. Los montos se
+// redondean a céntimos.
+type CostoHorarioEquipo struct {
+	AnalisisID         uuid.UUID        `json:"analisis_id"`
+	CodigoRecurso      string           `json:"codigo_recurso"`
+	Descripcion        string           `json:"descripcion"`
+	PotenciaHP         *costing.Decimal `json:"potencia_hp,omitempty"`
+	ValorAdquisicion   costing.Decimal  `json:"valor_adquisicion"`
+	ValorRescate       costing.Decimal  `json:"valor_rescate"`
+	VidaEconomicaAnios costing.Decimal  `json:"vida_economica_anios"`
+	VidaEconomicaHoras costing.Decimal  `json:"vida_economica_horas"`
+	InversionMedia     costing.Decimal  `json:"inversion_media"` // (Va × (N + 1) + Vr × (N - 1)) / 2N
+	Depreciacion       costing.Decimal  `json:"depreciacion"`
+	Intereses          costing.Decimal  `json:"intereses"`
+	Seguros            costing.Decimal  `json:"seguros"`
+	CostoPosesion      costing.Decimal  `json:"costo_posesion"`
+	Mantenimiento      costing.Decimal  `json:"mantenimiento"`
+	Combustible        ConsumoEquipo    `json:"combustible"`
+	Lubricante         ConsumoEquipo    `json:"lubricante"`
+	Neumaticos         costing.Decimal  `json:"neumaticos"`
+	Operador           ConsumoEquipo    `json:"operador"`
+	CostoOperacion     costing.Decimal  `json:"costo_operacion"`
+	CostoHorario       costing.Decimal  `json:"costo_horario"` // por hora-máquina
+}
+
+// ConsumoEquipo es el costo por hora-máquina de un insumo con el precio que se usó
+type ConsumoEquipo struct {
+	Codigo       string          `json:"codigo,omitempty"`
+	Cantidad     costing.Decimal `json:"cantidad"`
+	Precio       costing.Decimal `json:"precio"`
+	FuentePrecio string          `json:"fuente_precio"` // "lista", "catalogo" o "analisis"
+	Parcial      costing.Decimal `json:"parcial"`
+}
+
+// AnalisisEquipoResponse representa la respuesta de la API con un análisis y su costo horario
+type AnalisisEquipoResponse struct {
+	Success bool                `json:"success"`
+	Message string              `json:"message,omitempty"`
+	Data    *AnalisisEquipo     `json:"data,omitempty"`
+	Costo   *CostoHorarioEquipo `json:"costo,omitempty"`
+}
+
+// AnalisisEquiposResponse representa la respuesta de la API con los análisis de equipos
+type AnalisisEquiposResponse struct {
+	Success bool             `json:"success"`
+	Data    []AnalisisEquipo `json:"data"`
+}
+
+// AplicarEquiposRequest indica la lista de precios de la organización donde se guardan los costos
+// horarios; bajo /admin se omite y se actualiza el catálogo
+type AplicarEquiposRequest struct {
+	ListaPreciosID *uuid.UUID `json:"lista_precios_id,omitempty"`
+}
+
+// AplicarEquiposResponse representa la respuesta de la API con los costos horarios aplicados
+type AplicarEquiposResponse struct {
+	Success bool                 `json:"success"`
+	Message string               `json:"message,omitempty"`
+	Costos  []CostoHorarioEquipo `json:"costos"`
+	Precios []PrecioListaRequest `json:"precios"`
+}
@@ -0,0 +1,74 @@
+package models
+
+import "goexcel/internal/costing"
+
+// MaxNivelEsquema es la profundidad máxima de esquema (outline) que admite Excel
+const MaxNivelEsquema = 7
+
+// PorcentajeIGV es la tasa por defecto del Impuesto General a las Ventas aplicada en el pie del
+// presupuesto; los parámetros del proyecto pueden cambiarla
+const PorcentajeIGV = 18.0
+
+// OpcionesExportacion agrupa las opciones de presentación del libro Excel solicitadas al exportar
+type OpcionesExportacion struct {
+	// NivelColapsado indica el botón de esquema activo al abrir el libro: solo se muestran
+	// las filas con nivel de esquema menor a este valor. 0 deja todo expandido.
+	NivelColapsado int `json:"nivel_colapsado"`
+
+	// Porcentajes de gastos generales y utilidad sobre el costo directo para el pie del presupuesto
+	GastosGenerales float64 `json:"gastos_generales"`
+	Utilidad        float64 `json:"utilidad"`
+
+	// Formato de los CSV exportados: separador de campos y separador decimal ("." o ","). Vacíos
+	// usan la coma y el punto.
+	DelimitadorCSV   string `json:"delimitador_csv,omitempty"`
+	SeparadorDecimal string `json:"separador_decimal,omitempty"`
+
+	// Nombre del proyecto para el encabezado de página
+	Proyecto string `json:"-"`
+
+	// Plantilla de la organización; nil usa los estilos por defecto
+	Plantilla *PlantillaExcel `json:"-"`
+
+	// Parámetros de cálculo del proyecto; nil usa los parámetros por defecto
+	Parametros *Parametros `json:"-"`
+}
+
+// ParametrosCalculo devuelve los parámetros del proyecto o, si no se cargaron, los por defecto
+func (o OpcionesExportacion) ParametrosCalculo() Parametros {
+	if o.Parametros == nil {
+		return ParametrosPorDefecto()
+	}
+	return *o.Parametros
+}
+
+// PiePresupuesto es el cierre del presupuesto desde el costo directo hasta el total con IGV
+type PiePresupuesto struct {
+	CostoDirecto              costing.Decimal `json:"costo_directo"`
+	PorcentajeGastosGenerales float64         `json:"porcentaje_gastos_generales"`
+	GastosGenerales           costing.Decimal `json:"gastos_generales"`
+	PorcentajeUtilidad        float64         `json:"porcentaje_utilidad"`
+	Utilidad                  costing.Decimal `json:"utilidad"`
+	Subtotal                  costing.Decimal `json:"subtotal"`
+	PorcentajeIGV             float64         `json:"porcentaje_igv"`
+	IGV                       costing.Decimal `json:"igv"`
+	Total                     costing.Decimal `json:"total"`
+}
+
+// NuevoPiePresupuesto calcula el pie a partir del costo directo y los porcentajes indicados, con el
+// IGV y el redondeo de los parámetros del proyecto
+func NuevoPiePresupuesto(costoDirecto costing.Decimal, gastosGenerales, utilidad float64, parametros Parametros) PiePresupuesto {
+	reglas := parametros.Reglas()
+	pie := PiePresupuesto{
+		CostoDirecto:              costoDirecto,
+		PorcentajeGastosGenerales: gastosGenerales,
+		GastosGenerales:           reglas.Porcentaje(costoDirecto, gastosGenerales),
+		PorcentajeUtilidad:        utilidad,
+		Utilidad:                  reglas.Porcentaje(costoDirecto, utilidad),
+		PorcentajeIGV:             parametros.PorcentajeIGV,
+	}
+	pie.Subtotal = reglas.Sumar(pie.CostoDirecto, pie.GastosGenerales, pie.Utilidad)
+	pie.IGV = reglas.Porcentaje(pie.Subtotal, parametros.PorcentajeIGV)
+	pie.Total = reglas.Sumar(pie.Subtotal, pie.IGV)
+	return pie
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"goexcel/internal/costing"
+)
+
+// SolicitudExportacionLote pide exportar varios proyectos en un solo ZIP: por lista de IDs o por filtro
+type SolicitudExportacionLote struct {
+	ProyectoIDs []uuid.UUID      `json:"project_ids,omitempty"`
+	Filtro      *FiltroProyectos `json:"filtro,omitempty"`
+	Formatos    []string         `json:"formatos"` // los mismos valores que el parámetro format de la exportación
+}
+
+// FiltroProyectos selecciona proyectos; los campos vacíos no filtran
+type FiltroProyectos struct {
+	OrganizacionID *uuid.UUID `json:"organizacion_id,omitempty"`
+	UsuarioID      *uuid.UUID `json:"usuario_id,omitempty"`
+	Visibility     string     `json:"visibility,omitempty"`
+	Desde          *time.Time `json:"desde,omitempty"` // fecha de creación
+	Hasta          *time.Time `json:"hasta,omitempty"`
+}
+
+// ManifiestoLote es el manifest.json del ZIP: qué se exportó de cada proyecto y qué falló
+type ManifiestoLote struct {
+	Generado  time.Time      `json:"generado"`
+	Formatos  []string       `json:"formatos"`
+	Proyectos []ProyectoLote `json:"proyectos"` // en el orden de la solicitud
+	Exitosos  int            `json:"exitosos"`
+	Fallidos  int            `json:"fallidos"`
+}
+
+// ProyectoLote es el resultado de un proyecto; Error indica por qué no se generaron algunos archivos
+type ProyectoLote struct {
+	ID           string          `json:"id"`
+	Nombre       string          `json:"nombre,omitempty"`
+	CostoDirecto costing.Decimal `json:"costo_directo"`
+	Total        costing.Decimal `json:"total"`
+	Archivos     []ArchivoLote   `json:"archivos"`
+	Errores      []string        `json:"errores,omitempty"`
+}
+
+// ArchivoLote es un archivo del ZIP con su huella para verificar la copia archivada
+type ArchivoLote struct {
+	Ruta    string `json:"ruta"`
+	Formato string `json:"formato"`
+	Bytes   int64  `json:"bytes"`
+	SHA256  string `json:"sha256"`
+}
@@ -0,0 +1,126 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"goexcel/internal/costing"
+)
+
+// Modos de aplicar el flete en el presupuesto del proyecto
+const (
+	ModoFletePrecio      = "precio"      // el flete unitario se suma al precio de cada material
+	ModoFleteSubcontrato = "subcontrato" // cada partida recibe una línea de flete terrestre en subcontratos
+)
+
+// RutaFlete es el transporte de materiales desde un origen hasta la obra
+type RutaFlete struct {
+	ID               uuid.UUID       `json:"id"`
+	OrganizacionID   *uuid.UUID      `json:"organizacion_id,omitempty"` // nil: ruta global
+	Nombre           string          `json:"nombre"`
+	Origen           string          `json:"origen"`
+	Destino          string          `json:"destino"`
+	DistanciaKm      costing.Decimal `json:"distancia_km"`
+	TarifaToneladaKm costing.Decimal `json:"tarifa_tonelada_km"` // costo de llevar una tonelada un km
+	CreatedAt        time.Time       `json:"created_at"`
+	UpdatedAt        time.Time       `json:"updated_at"`
+}
+
+// CostoTonelada es el costo de llevar una tonelada por toda la ruta, redondeado como precio
+func (r RutaFlete) CostoTonelada(reglas costing.Reglas) costing.Decimal {
+	return r.DistanciaKm.Multiplicar(r.TarifaToneladaKm).Redondear(reglas.Precio)
+}
+
+// RutaFleteRequest crea o reemplaza una ruta
+type RutaFleteRequest struct {
+	Nombre           string          `json:"nombre"`
+	Origen           string          `json:"origen"`
+	Destino          string          `json:"destino"`
+	DistanciaKm      costing.Decimal `json:"distancia_km"`
+	TarifaToneladaKm costing.Decimal `json:"tarifa_tonelada_km"`
+}
+
+// PesoUnitario es el peso en kg de una unidad de un material del catálogo
+type PesoUnitario struct {
+	ID             uuid.UUID       `json:"id"`
+	OrganizacionID *uuid.UUID      `json:"organizacion_id,omitempty"` // nil: peso global
+	CodigoRecurso  string          `json:"codigo_recurso"`
+	PesoKg         costing.Decimal `json:"peso_kg"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
+
+// PesoUnitarioRequest es el peso de un material en una carga de pesos
+type PesoUnitarioRequest struct {
+	CodigoRecurso string          `json:"codigo_recurso"`
+	PesoKg        costing.Decimal `json:"peso_kg"`
+}
+
+// GuardarPesosRequest agrega o reemplaza pesos unitarios; los materiales que no vienen no cambian
+type GuardarPesosRequest struct {
+	Pesos []PesoUnitarioRequest `json:"pesos"`
+}
+
+// FleteProyecto es la ruta y el modo de flete asignados a un proyecto. Pesos son los pesos unitarios
+// efectivos para su organización por código de recurso; se cargan solo para calcular el reporte.
+type FleteProyecto struct {
+	Ruta  *RutaFlete                 `json:"ruta,omitempty"`
+	Modo  string                     `json:"modo"`
+	Pesos map[string]costing.Decimal `json:"-"`
+}
+
+// AsignarFleteRequest asigna al proyecto una ruta (o la quita con null) y el modo de aplicar el flete;
+// sin modo se usa "precio"
+type AsignarFleteRequest struct {
+	RutaFleteID *uuid.UUID `json:"ruta_flete_id"`
+	Modo        string     `json:"modo,omitempty"`
+}
+
+// CalculoFlete es el flete del presupuesto por material, tal como se aplicó en los APU
+type CalculoFlete struct {
+	Ruta          RutaFlete       `json:"ruta"`
+	Modo          string          `json:"modo"`
+	CostoTonelada costing.Decimal `json:"costo_tonelada"`
+	Materiales    []FleteMaterial `json:"materiales"`
+	PesoTotal     costing.Decimal `json:"peso_total"` // toneladas
+	Total         costing.Decimal `json:"total"`
+}
+
+// FleteMaterial es el flete de un material en todo el presupuesto
+type FleteMaterial struct {
+	Codigo        string          `json:"codigo"`
+	Descripcion   string          `json:"descripcion"`
+	Unidad        string          `json:"unidad"`
+	PesoUnitario  costing.Decimal `json:"peso_unitario"`  // kg por unidad
+	FleteUnitario costing.Decimal `json:"flete_unitario"` // por unidad
+	Cantidad      costing.Decimal `json:"cantidad"`       // Σ metrado × cantidad del APU, con desperdicio
+	Peso          costing.Decimal `json:"peso"`           // toneladas
+	Flete         costing.Decimal `json:"flete"`
+}
+
+// RutaFleteResponse representa la respuesta de la API con una ruta de flete
+type RutaFleteResponse struct {
+	Success bool       `json:"success"`
+	Message string     `json:"message,omitempty"`
+	Data    *RutaFlete `json:"data,omitempty"`
+}
+
+// RutasFleteResponse representa la respuesta de la API con las rutas de flete
+type RutasFleteResponse struct {
+	Success bool        `json:"success"`
+	Data    []RutaFlete `json:"data"`
+}
+
+// PesosUnitariosResponse representa la respuesta de la API con los pesos unitarios
+type PesosUnitariosResponse struct {
+	Success bool           `json:"success"`
+	Message string         `json:"message,omitempty"`
+	Data    []PesoUnitario `json:"data"`
+}
+
+// FleteProyectoResponse representa la respuesta de la API con el flete asignado a un proyecto
+type FleteProyectoResponse struct {
+	Success bool           `json:"success"`
+	Message string         `json:"message,omitempty"`
+	Data    *FleteProyecto `json:"data,omitempty"`
+}
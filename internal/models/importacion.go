@@ -0,0 +1,54 @@
+package models
+
+// MapeoColumnas indica en qué columna (letra de Excel) está cada dato de una tabla importada.
+// Los campos vacíos se detectan a partir de la fila de cabeceras de la hoja.
+type MapeoColumnas struct {
+	Codigo      string `json:"codigo,omitempty"`
+	Descripcion string `json:"descripcion,omitempty"`
+	Unidad      string `json:"unidad,omitempty"`
+	Cuadrilla   string `json:"cuadrilla,omitempty"`
+	Cantidad    string `json:"cantidad,omitempty"`
+	Precio      string `json:"precio,omitempty"`
+	Parcial     string `json:"parcial,omitempty"`
+	Metrado     string `json:"metrado,omitempty"`
+}
+
+// OpcionesImportacion configura la lectura de un libro de APU/Presupuesto
+type OpcionesImportacion struct {
+	Hoja             string         `json:"hoja,omitempty"` // vacío: todas las hojas
+	MapeoAPU         *MapeoColumnas `json:"mapeo_apu,omitempty"`
+	MapeoPresupuesto *MapeoColumnas `json:"mapeo_presupuesto,omitempty"`
+
+	Parametros *Parametros `json:"-"` // de la organización del usuario; nil usa los valores por defecto
+}
+
+// HojaImportada describe cómo se interpretó cada hoja del libro
+type HojaImportada struct {
+	Nombre   string        `json:"nombre"`
+	Tipo     string        `json:"tipo"` // "apu", "presupuesto" o "ignorada"
+	Mapeo    MapeoColumnas `json:"mapeo"`
+	Partidas int           `json:"partidas"`
+	Titulos  int           `json:"titulos"`
+}
+
+// TituloImportado es un título del presupuesto detectado en el libro
+type TituloImportado struct {
+	Codigo      string `json:"codigo"`
+	Descripcion string `json:"descripcion"`
+	Nivel       int    `json:"nivel"`
+}
+
+// AdvertenciaImportacion señala una fila que no se pudo interpretar o que se completó por heurística
+type AdvertenciaImportacion struct {
+	Hoja    string `json:"hoja"`
+	Fila    int    `json:"fila,omitempty"`
+	Mensaje string `json:"mensaje"`
+}
+
+// ConfirmarImportacionRequest es la vista previa, corregida por el usuario, que se guarda como proyecto
+type ConfirmarImportacionRequest struct {
+	Proyecto ProyectoRequest   `json:"proyecto"`
+	Partidas []PartidaRequest  `json:"partidas"`
+	Titulos  []TituloImportado `json:"titulos,omitempty"`
+	Metrados []MetradoRequest  `json:"metrados,omitempty"`
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"goexcel/internal/costing"
+)
+
+// InsumoConsolidado representa la cantidad total de un recurso en todo el presupuesto
+type InsumoConsolidado struct {
+	RecursoID       uuid.UUID       `json:"recurso_id" db:"recurso_id"`
+	Codigo          string          `json:"codigo" db:"codigo"`
+	Descripcion     string          `json:"descripcion" db:"descripcion"`
+	Unidad          string          `json:"unidad" db:"unidad"`
+	TipoRecurso     string          `json:"tipo_recurso" db:"tipo_recurso"`
+	IndiceUnificado *string         `json:"indice_unificado,omitempty" db:"indice_unificado"`
+	Cantidad        costing.Decimal `json:"cantidad" db:"cantidad"`
+	Precio          costing.Decimal `json:"precio" db:"precio"`
+	CostoTotal      costing.Decimal `json:"costo_total" db:"costo_total"`
+}
+
+// GrupoInsumos agrupa los insumos de un mismo tipo de recurso
+type GrupoInsumos struct {
+	TipoRecurso string              `json:"tipo_recurso"`
+	Nombre      string              `json:"nombre"`
+	Insumos     []InsumoConsolidado `json:"insumos"`
+	Subtotal    costing.Decimal     `json:"subtotal"`
+}
+
+// RelacionInsumos representa la relación de insumos de un proyecto o presupuesto
+type RelacionInsumos struct {
+	ProyectoID    *uuid.UUID      `json:"proyecto_id,omitempty"`
+	PresupuestoID *uuid.UUID      `json:"presupuesto_id,omitempty"`
+	Grupos        []GrupoInsumos  `json:"grupos"`
+	TotalInsumos  costing.Decimal `json:"total_insumos"`
+	CostoDirecto  costing.Decimal `json:"costo_directo"`
+	Diferencia    costing.Decimal `json:"diferencia"`
+	Conciliado    bool            `json:"conciliado"`
+}
+
+// RelacionInsumosResponse representa la respuesta de la API para la relación de insumos
+type RelacionInsumosResponse struct {
+	Success bool             `json:"success"`
+	Message string           `json:"message,omitempty"`
+	Data    *RelacionInsumos `json:"data,omitempty"`
+}
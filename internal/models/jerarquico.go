@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"goexcel/internal/costing"
 )
 
 // Presupuesto representa un presupuesto principal
@@ -53,53 +54,53 @@ type Titulo struct {
 // PartidaJerarquica representa una partida con información jerárquica
 type PartidaJerarquica struct {
 	// Campos de la partida
-	PartidaID          uuid.UUID  `json:"partida_id" db:"partida_id"`
-	PartidaCodigo      string     `json:"partida_codigo" db:"partida_codigo"`
-	PartidaDescripcion string     `json:"partida_descripcion" db:"partida_descripcion"`
-	Unidad             string     `json:"unidad" db:"unidad"`
-	Rendimiento        float64    `json:"rendimiento" db:"rendimiento"`
-	CostoTotal         float64    `json:"costo_total" db:"costo_total"`
-	PartidaNumero      int        `json:"partida_numero" db:"partida_numero"`
-	PartidaOrden       int        `json:"partida_orden" db:"partida_orden"`
-	
+	PartidaID          uuid.UUID       `json:"partida_id" db:"partida_id"`
+	PartidaCodigo      string          `json:"partida_codigo" db:"partida_codigo"`
+	PartidaDescripcion string          `json:"partida_descripcion" db:"partida_descripcion"`
+	Unidad             string          `json:"unidad" db:"unidad"`
+	Rendimiento        float64         `json:"rendimiento" db:"rendimiento"`
+	CostoTotal         costing.Decimal `json:"costo_total" db:"costo_total"`
+	PartidaNumero      int             `json:"partida_numero" db:"partida_numero"`
+	PartidaOrden       int             `json:"partida_orden" db:"partida_orden"`
+
 	// Información jerárquica
-	PresupuestoID         uuid.UUID  `json:"presupuesto_id" db:"presupuesto_id"`
-	PresupuestoCodigo     string     `json:"presupuesto_codigo" db:"presupuesto_codigo"`
-	PresupuestoNombre     string     `json:"presupuesto_nombre" db:"presupuesto_nombre"`
-	SubpresupuestoID      *uuid.UUID `json:"subpresupuesto_id,omitempty" db:"subpresupuesto_id"`
-	SubpresupuestoCodigo  *string    `json:"subpresupuesto_codigo,omitempty" db:"subpresupuesto_codigo"`
-	SubpresupuestoNombre  *string    `json:"subpresupuesto_nombre,omitempty" db:"subpresupuesto_nombre"`
-	TituloID              *uuid.UUID `json:"titulo_id,omitempty" db:"titulo_id"`
-	TituloCodigo          *string    `json:"titulo_codigo,omitempty" db:"titulo_codigo"`
-	TituloNombre          *string    `json:"titulo_nombre,omitempty" db:"titulo_nombre"`
-	TituloNivel           *int       `json:"titulo_nivel,omitempty" db:"titulo_nivel"`
+	PresupuestoID        uuid.UUID  `json:"presupuesto_id" db:"presupuesto_id"`
+	PresupuestoCodigo    string     `json:"presupuesto_codigo" db:"presupuesto_codigo"`
+	PresupuestoNombre    string     `json:"presupuesto_nombre" db:"presupuesto_nombre"`
+	SubpresupuestoID     *uuid.UUID `json:"subpresupuesto_id,omitempty" db:"subpresupuesto_id"`
+	SubpresupuestoCodigo *string    `json:"subpresupuesto_codigo,omitempty" db:"subpresupuesto_codigo"`
+	SubpresupuestoNombre *string    `json:"subpresupuesto_nombre,omitempty" db:"subpresupuesto_nombre"`
+	TituloID             *uuid.UUID `json:"titulo_id,omitempty" db:"titulo_id"`
+	TituloCodigo         *string    `json:"titulo_codigo,omitempty" db:"titulo_codigo"`
+	TituloNombre         *string    `json:"titulo_nombre,omitempty" db:"titulo_nombre"`
+	TituloNivel          *int       `json:"titulo_nivel,omitempty" db:"titulo_nivel"`
 }
 
 // EstructuraJerarquica representa la vista completa de la jerarquía
 type EstructuraJerarquica struct {
-	PresupuestoID         uuid.UUID  `json:"presupuesto_id" db:"presupuesto_id"`
-	PresupuestoCodigo     string     `json:"presupuesto_codigo" db:"presupuesto_codigo"`
-	PresupuestoNombre     string     `json:"presupuesto_nombre" db:"presupuesto_nombre"`
-	SubpresupuestoID      *uuid.UUID `json:"subpresupuesto_id,omitempty" db:"subpresupuesto_id"`
-	SubpresupuestoCodigo  *string    `json:"subpresupuesto_codigo,omitempty" db:"subpresupuesto_codigo"`
-	SubpresupuestoNombre  *string    `json:"subpresupuesto_nombre,omitempty" db:"subpresupuesto_nombre"`
-	TituloID              *uuid.UUID `json:"titulo_id,omitempty" db:"titulo_id"`
-	Nivel                 *int       `json:"nivel,omitempty" db:"nivel"`
-	TituloCodigo          *string    `json:"titulo_codigo,omitempty" db:"titulo_codigo"`
-	TituloNombre          *string    `json:"titulo_nombre,omitempty" db:"titulo_nombre"`
-	Depth                 *int       `json:"depth,omitempty" db:"depth"`
-	PathOrden             *string    `json:"path_orden,omitempty" db:"path_orden"`
-	TotalPartidas         int64      `json:"total_partidas" db:"total_partidas"`
-	CostoTotalTitulos     float64    `json:"costo_total_titulos" db:"costo_total_titulos"`
+	PresupuestoID        uuid.UUID       `json:"presupuesto_id" db:"presupuesto_id"`
+	PresupuestoCodigo    string          `json:"presupuesto_codigo" db:"presupuesto_codigo"`
+	PresupuestoNombre    string          `json:"presupuesto_nombre" db:"presupuesto_nombre"`
+	SubpresupuestoID     *uuid.UUID      `json:"subpresupuesto_id,omitempty" db:"subpresupuesto_id"`
+	SubpresupuestoCodigo *string         `json:"subpresupuesto_codigo,omitempty" db:"subpresupuesto_codigo"`
+	SubpresupuestoNombre *string         `json:"subpresupuesto_nombre,omitempty" db:"subpresupuesto_nombre"`
+	TituloID             *uuid.UUID      `json:"titulo_id,omitempty" db:"titulo_id"`
+	Nivel                *int            `json:"nivel,omitempty" db:"nivel"`
+	TituloCodigo         *string         `json:"titulo_codigo,omitempty" db:"titulo_codigo"`
+	TituloNombre         *string         `json:"titulo_nombre,omitempty" db:"titulo_nombre"`
+	Depth                *int            `json:"depth,omitempty" db:"depth"`
+	PathOrden            *string         `json:"path_orden,omitempty" db:"path_orden"`
+	TotalPartidas        int64           `json:"total_partidas" db:"total_partidas"`
+	CostoTotalTitulos    costing.Decimal `json:"costo_total_titulos" db:"costo_total_titulos"`
 }
 
 // ResumenJerarquico representa las estadísticas de un presupuesto
 type ResumenJerarquico struct {
-	TotalSubpresupuestos int64   `json:"total_subpresupuestos" db:"total_subpresupuestos"`
-	TotalTitulos         int64   `json:"total_titulos" db:"total_titulos"`
-	TotalPartidas        int64   `json:"total_partidas" db:"total_partidas"`
-	CostoTotal           float64 `json:"costo_total" db:"costo_total"`
-	NivelesMaximos       int     `json:"niveles_maximos" db:"niveles_maximos"`
+	TotalSubpresupuestos int64           `json:"total_subpresupuestos" db:"total_subpresupuestos"`
+	TotalTitulos         int64           `json:"total_titulos" db:"total_titulos"`
+	TotalPartidas        int64           `json:"total_partidas" db:"total_partidas"`
+	CostoTotal           costing.Decimal `json:"costo_total" db:"costo_total"`
+	NivelesMaximos       int             `json:"niveles_maximos" db:"niveles_maximos"`
 }
 
 // Requests para API
@@ -147,6 +148,8 @@ type PresupuestoData struct {
 	Cliente *string `json:"cliente,omitempty"`
 	Lugar   *string `json:"lugar,omitempty"`
 	Moneda  string  `json:"moneda"`
+
+	Parametros *ParametrosCalculo `json:"parametros,omitempty"` // declarados en el bloque @presupuesto
 }
 
 type SubpresupuestoData struct {
@@ -174,22 +177,24 @@ type PartidaData struct {
 }
 
 type RecursoData struct {
-	Codigo      string   `json:"codigo"`
-	Descripcion string   `json:"descripcion"`
-	Unidad      string   `json:"unidad"`
-	Cantidad    float64  `json:"cantidad"`
-	Precio      float64  `json:"precio"`
-	Cuadrilla   *float64 `json:"cuadrilla,omitempty"`
+	Codigo      string           `json:"codigo"`
+	Descripcion string           `json:"descripcion"`
+	Unidad      string           `json:"unidad"`
+	Cantidad    costing.Decimal  `json:"cantidad"`
+	Precio      costing.Decimal  `json:"precio"`
+	Cuadrilla   *float64         `json:"cuadrilla,omitempty"`
+	Moneda      string           `json:"moneda,omitempty"`
+	Desperdicio *costing.Decimal `json:"desperdicio,omitempty"`
 }
 
 // Responses para API
 type PresupuestoResponse struct {
-	Success     bool                    `json:"success"`
-	Message     string                  `json:"message,omitempty"`
-	Data        *Presupuesto            `json:"data,omitempty"`
-	Presupuesto *Presupuesto            `json:"presupuesto,omitempty"`
-	Estructura  []EstructuraJerarquica  `json:"estructura,omitempty"`
-	Resumen     *ResumenJerarquico      `json:"resumen,omitempty"`
+	Success     bool                   `json:"success"`
+	Message     string                 `json:"message,omitempty"`
+	Data        *Presupuesto           `json:"data,omitempty"`
+	Presupuesto *Presupuesto           `json:"presupuesto,omitempty"`
+	Estructura  []EstructuraJerarquica `json:"estructura,omitempty"`
+	Resumen     *ResumenJerarquico     `json:"resumen,omitempty"`
 }
 
 type TituloResponse struct {
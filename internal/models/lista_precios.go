@@ -0,0 +1,146 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"goexcel/internal/costing"
+)
+
+// ListaPrecios es una lista de precios de recursos de una organización para una región y un período
+type ListaPrecios struct {
+	ID              uuid.UUID     `json:"id"`
+	OrganizacionID  uuid.UUID     `json:"organizacion_id"`
+	Nombre          string        `json:"nombre"`
+	Region          string        `json:"region"`
+	VigenteDesde    string        `json:"vigente_desde"`
+	VigenteHasta    *string       `json:"vigente_hasta,omitempty"` // nil: sin fecha de término
+	CantidadPrecios int           `json:"cantidad_precios"`
+	Precios         []PrecioLista `json:"precios,omitempty"` // solo al consultar una lista
+	CreatedAt       time.Time     `json:"created_at"`
+	UpdatedAt       time.Time     `json:"updated_at"`
+}
+
+// VigenteEn indica si la lista está vigente en la fecha dada
+func (l *ListaPrecios) VigenteEn(fecha time.Time) bool {
+	dia := fecha.Format(FormatoFecha)
+	return l.VigenteDesde <= dia && (l.VigenteHasta == nil || dia <= *l.VigenteHasta)
+}
+
+// PrecioLista es el precio de un recurso en una lista
+type PrecioLista struct {
+	RecursoID   uuid.UUID       `json:"recurso_id"`
+	Codigo      string          `json:"codigo"`
+	Descripcion string          `json:"descripcion"`
+	Unidad      string          `json:"unidad"`
+	Precio      costing.Decimal `json:"precio"`
+	Moneda      string          `json:"moneda,omitempty"` // vacía: la moneda del proyecto
+}
+
+// ListaPreciosRequest crea o modifica una lista; las fechas tienen el formato AAAA-MM-DD
+type ListaPreciosRequest struct {
+	Nombre       string               `json:"nombre"`
+	Region       string               `json:"region"`
+	VigenteDesde string               `json:"vigente_desde"`
+	VigenteHasta *string              `json:"vigente_hasta,omitempty"`
+	Precios      []PrecioListaRequest `json:"precios,omitempty"` // al crear, precios iniciales
+}
+
+// PrecioListaRequest es el precio de un recurso identificado por su código
+type PrecioListaRequest struct {
+	Codigo string          `json:"codigo"`
+	Precio costing.Decimal `json:"precio"`
+	Moneda string          `json:"moneda,omitempty"`
+}
+
+// ListaPreciosResponse representa la respuesta de la API con una lista de precios
+type ListaPreciosResponse struct {
+	Success    bool          `json:"success"`
+	Message    string        `json:"message,omitempty"`
+	Data       *ListaPrecios `json:"data,omitempty"`
+	Importados int           `json:"importados,omitempty"`
+}
+
+// ListasPreciosResponse representa la respuesta de la API con las listas de una organización
+type ListasPreciosResponse struct {
+	Success bool           `json:"success"`
+	Data    []ListaPrecios `json:"data"`
+}
+
+// AsignarListaPreciosRequest asigna una lista al proyecto; nil la quita
+type AsignarListaPreciosRequest struct {
+	ListaPreciosID *uuid.UUID `json:"lista_precios_id"`
+}
+
+// UsoRecurso es un recurso usado en una partida del proyecto, con su precio en la lista asignada
+type UsoRecurso struct {
+	PartidaRecursoID   uuid.UUID
+	PartidaID          uuid.UUID
+	PartidaCodigo      string
+	PartidaDescripcion string
+	RecursoCodigo      string
+	Descripcion        string
+	Unidad             string
+	Cantidad           costing.Decimal // incluye el desperdicio
+	Precio             costing.Decimal
+	Moneda             string
+	PrecioLista        *costing.Decimal // nil si el recurso no está en la lista
+	MonedaLista        string
+}
+
+// ActualizacionPrecio es el nuevo precio de un recurso en una partida
+type ActualizacionPrecio struct {
+	PartidaRecursoID uuid.UUID
+	PartidaID        uuid.UUID
+	Precio           costing.Decimal
+	Moneda           string
+}
+
+// ReprecioProyecto es el informe de actualizar los precios del proyecto con su lista de precios
+type ReprecioProyecto struct {
+	ProyectoID           uuid.UUID          `json:"proyecto_id"`
+	ListaPrecios         ListaPrecios       `json:"lista_precios"`
+	Fecha                time.Time          `json:"fecha"`
+	Simulado             bool               `json:"simulado"` // true: no se guardó ningún cambio
+	ListaVigente         bool               `json:"lista_vigente"`
+	RecursosActualizados int                `json:"recursos_actualizados"`
+	PartidasAfectadas    int                `json:"partidas_afectadas"`
+	CostoDirectoAnterior costing.Decimal    `json:"costo_directo_anterior"`
+	CostoDirectoNuevo    costing.Decimal    `json:"costo_directo_nuevo"`
+	Diferencia           costing.Decimal    `json:"diferencia"`
+	Cambios              []CambioPrecio     `json:"cambios"`
+	SinPrecio            []RecursoSinPrecio `json:"sin_precio"` // recursos del proyecto que no están en la lista
+}
+
+// CambioPrecio es el cambio de precio de un recurso en una partida. Si cambia la moneda, la
+// diferencia, la variación y el impacto no se calculan.
+type CambioPrecio struct {
+	PartidaCodigo       string           `json:"partida_codigo"`
+	PartidaDescripcion  string           `json:"partida_descripcion"`
+	RecursoCodigo       string           `json:"recurso_codigo"`
+	Descripcion         string           `json:"descripcion"`
+	Unidad              string           `json:"unidad"`
+	Cantidad            costing.Decimal  `json:"cantidad"`
+	PrecioAnterior      costing.Decimal  `json:"precio_anterior"`
+	PrecioNuevo         costing.Decimal  `json:"precio_nuevo"`
+	MonedaAnterior      string           `json:"moneda_anterior,omitempty"`
+	MonedaNueva         string           `json:"moneda_nueva,omitempty"`
+	Diferencia          *costing.Decimal `json:"diferencia,omitempty"`           // precio nuevo - anterior
+	VariacionPorcentaje *costing.Decimal `json:"variacion_porcentaje,omitempty"` // nil si el precio anterior era cero
+	ImpactoUnitario     *costing.Decimal `json:"impacto_unitario,omitempty"`     // cambio del costo unitario de la partida
+}
+
+// RecursoSinPrecio es un recurso del proyecto que no está en la lista y conserva su precio
+type RecursoSinPrecio struct {
+	Codigo      string `json:"codigo"`
+	Descripcion string `json:"descripcion"`
+	Unidad      string `json:"unidad"`
+	Partidas    int    `json:"partidas"` // partidas en las que se usa
+}
+
+// ReprecioResponse representa la respuesta de la API al repreciar un proyecto
+type ReprecioResponse struct {
+	Success bool              `json:"success"`
+	Message string            `json:"message,omitempty"`
+	Data    *ReprecioProyecto `json:"data"`
+}
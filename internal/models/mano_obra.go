@@ -0,0 +1,106 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"goexcel/internal/costing"
+)
+
+// ParametrosManoObra son los parámetros de un año del régimen de construcción civil (convenio
+// CAPECO-FTCCP) con los que se calcula el costo hora-hombre de cada categoría
+type ParametrosManoObra struct {
+	ID                       uuid.UUID           `json:"id"`
+	OrganizacionID           *uuid.UUID          `json:"organizacion_id,omitempty"` // nil: parámetros globales
+	Anio                     int                 `json:"anio"`
+	Descripcion              string              `json:"descripcion,omitempty"` // p. ej. "Acta CAPECO-FTCCP 2024-2025"
+	VigenteDesde             string              `json:"vigente_desde"`
+	HorasJornada             costing.Decimal     `json:"horas_jornada"`
+	MovilidadDiaria          costing.Decimal     `json:"movilidad_diaria"` // bonificación por movilidad acumulada
+	OverolDiario             costing.Decimal     `json:"overol_diario"`    // costo de los overoles prorrateado por día
+	HorasExtraDiarias        costing.Decimal     `json:"horas_extra_diarias"`
+	PorcentajeSobretasaExtra costing.Decimal     `json:"porcentaje_sobretasa_extra"` // sobre el valor de la hora básica
+	Categorias               []CategoriaManoObra `json:"categorias"`
+	CreatedAt                time.Time           `json:"created_at"`
+	UpdatedAt                time.Time           `json:"updated_at"`
+}
+
+// CategoriaManoObra es el jornal y los porcentajes de una categoría
+type CategoriaManoObra struct {
+	Categoria               string          `json:"categoria"`                // OPERARIO, OFICIAL, PEÓN...
+	CodigoRecurso           string          `json:"codigo_recurso,omitempty"` // recurso hh que recibe el costo
+	JornalBasico            costing.Decimal `json:"jornal_basico"`
+	PorcentajeBUC           costing.Decimal `json:"porcentaje_buc"` // bonificación unificada de construcción
+	PorcentajeLeyesSociales costing.Decimal `json:"porcentaje_leyes_sociales"`
+}
+
+// ParametrosManoObraRequest crea o reemplaza un juego de parámetros. Sin vigente_desde rige desde el
+// 1 de junio del año, inicio del convenio; sin horas_jornada, 8 horas.
+type ParametrosManoObraRequest struct {
+	Anio                     int                 `json:"anio"`
+	Descripcion              string              `json:"descripcion,omitempty"`
+	VigenteDesde             string              `json:"vigente_desde,omitempty"`
+	HorasJornada             costing.Decimal     `json:"horas_jornada"`
+	MovilidadDiaria          costing.Decimal     `json:"movilidad_diaria"`
+	OverolDiario             costing.Decimal     `json:"overol_diario"`
+	HorasExtraDiarias        costing.Decimal     `json:"horas_extra_diarias"`
+	PorcentajeSobretasaExtra *costing.Decimal    `json:"porcentaje_sobretasa_extra,omitempty"` // nil: 25 %
+	Categorias               []CategoriaManoObra `json:"categorias"`
+}
+
+// CalculoManoObra es el desglose del costo de mano de obra de cada categoría
+type CalculoManoObra struct {
+	ParametrosID      uuid.UUID       `json:"parametros_id"`
+	Anio              int             `json:"anio"`
+	Descripcion       string          `json:"descripcion,omitempty"`
+	VigenteDesde      string          `json:"vigente_desde"`
+	HorasJornada      costing.Decimal `json:"horas_jornada"`
+	HorasExtraDiarias costing.Decimal `json:"horas_extra_diarias"`
+	Categorias        []CostoManoObra `json:"categorias"`
+}
+
+// CostoManoObra es el desglose del costo diario de una categoría y su costo hora-hombre. Los montos
+// son por día y se redondean a céntimos.
+type CostoManoObra struct {
+	Categoria               string          `json:"categoria"`
+	CodigoRecurso           string          `json:"codigo_recurso,omitempty"`
+	JornalBasico            costing.Decimal `json:"jornal_basico"`
+	PorcentajeLeyesSociales costing.Decimal `json:"porcentaje_leyes_sociales"`
+	LeyesSociales           costing.Decimal `json:"leyes_sociales"`
+	PorcentajeBUC           costing.Decimal `json:"porcentaje_buc"`
+	BUC                     costing.Decimal `json:"buc"`
+	Movilidad               costing.Decimal `json:"movilidad"`
+	Overol                  costing.Decimal `json:"overol"`
+	HorasExtra              costing.Decimal `json:"horas_extra"`
+	CostoDiario             costing.Decimal `json:"costo_diario"`
+	HorasDiarias            costing.Decimal `json:"horas_diarias"` // jornada más horas extra
+	CostoHH                 costing.Decimal `json:"costo_hh"`
+}
+
+// ParametrosManoObraResponse representa la respuesta de la API con un juego de parámetros y su cálculo
+type ParametrosManoObraResponse struct {
+	Success bool                `json:"success"`
+	Message string              `json:"message,omitempty"`
+	Data    *ParametrosManoObra `json:"data,omitempty"`
+	Calculo *CalculoManoObra    `json:"calculo,omitempty"`
+}
+
+// ParametrosManoObraListaResponse representa la respuesta de la API con los juegos de parámetros
+type ParametrosManoObraListaResponse struct {
+	Success bool                 `json:"success"`
+	Data    []ParametrosManoObra `json:"data"`
+}
+
+// AplicarManoObraRequest indica la lista de precios que recibe los costos hora-hombre; en las rutas de
+// administración se omite y se actualiza el catálogo de recursos
+type AplicarManoObraRequest struct {
+	ListaPreciosID *uuid.UUID `json:"lista_precios_id,omitempty"`
+}
+
+// AplicarManoObraResponse representa la respuesta de la API con los precios aplicados
+type AplicarManoObraResponse struct {
+	Success bool                 `json:"success"`
+	Message string               `json:"message,omitempty"`
+	Calculo *CalculoManoObra     `json:"calculo"`
+	Precios []PrecioListaRequest `json:"precios"`
+}
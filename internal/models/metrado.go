@@ -4,51 +4,52 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"goexcel/internal/costing"
 )
 
 // MetradoPartida representa un metrado específico de una partida en un proyecto
 type MetradoPartida struct {
-	ID            uuid.UUID  `json:"id" db:"id"`
-	ProyectoID    uuid.UUID  `json:"proyecto_id" db:"proyecto_id"`
-	PartidaCodigo string     `json:"partida_codigo" db:"partida_codigo"`
-	Metrado       float64    `json:"metrado" db:"metrado"`
-	Unidad        *string    `json:"unidad,omitempty" db:"unidad"`
-	Observaciones *string    `json:"observaciones,omitempty" db:"observaciones"`
-	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
-	UpdatedAt     time.Time  `json:"updated_at" db:"updated_at"`
+	ID            uuid.UUID       `json:"id" db:"id"`
+	ProyectoID    uuid.UUID       `json:"proyecto_id" db:"proyecto_id"`
+	PartidaCodigo string          `json:"partida_codigo" db:"partida_codigo"`
+	Metrado       costing.Decimal `json:"metrado" db:"metrado"`
+	Unidad        *string         `json:"unidad,omitempty" db:"unidad"`
+	Observaciones *string         `json:"observaciones,omitempty" db:"observaciones"`
+	CreatedAt     time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at" db:"updated_at"`
 }
 
 // MetradoCompleto representa un metrado con información completa de la partida
 type MetradoCompleto struct {
-	ID                   uuid.UUID  `json:"id" db:"id"`
-	ProyectoID           uuid.UUID  `json:"proyecto_id" db:"proyecto_id"`
-	PartidaCodigo        string     `json:"partida_codigo" db:"partida_codigo"`
-	Metrado              float64    `json:"metrado" db:"metrado"`
-	MetradoUnidad        *string    `json:"metrado_unidad,omitempty" db:"metrado_unidad"`
-	Observaciones        *string    `json:"observaciones,omitempty" db:"observaciones"`
-	PartidaDescripcion   *string    `json:"partida_descripcion,omitempty" db:"partida_descripcion"`
-	PartidaUnidad        *string    `json:"partida_unidad,omitempty" db:"partida_unidad"`
-	CostoUnitario        *float64   `json:"costo_unitario,omitempty" db:"costo_unitario"`
-	CostoTotalPartida    *float64   `json:"costo_total_partida,omitempty" db:"costo_total_partida"`
-	ProyectoNombre       *string    `json:"proyecto_nombre,omitempty" db:"proyecto_nombre"`
-	CreatedAt            time.Time  `json:"created_at" db:"created_at"`
-	UpdatedAt            time.Time  `json:"updated_at" db:"updated_at"`
+	ID                 uuid.UUID        `json:"id" db:"id"`
+	ProyectoID         uuid.UUID        `json:"proyecto_id" db:"proyecto_id"`
+	PartidaCodigo      string           `json:"partida_codigo" db:"partida_codigo"`
+	Metrado            costing.Decimal  `json:"metrado" db:"metrado"`
+	MetradoUnidad      *string          `json:"metrado_unidad,omitempty" db:"metrado_unidad"`
+	Observaciones      *string          `json:"observaciones,omitempty" db:"observaciones"`
+	PartidaDescripcion *string          `json:"partida_descripcion,omitempty" db:"partida_descripcion"`
+	PartidaUnidad      *string          `json:"partida_unidad,omitempty" db:"partida_unidad"`
+	CostoUnitario      *costing.Decimal `json:"costo_unitario,omitempty" db:"costo_unitario"`
+	CostoTotalPartida  *costing.Decimal `json:"costo_total_partida,omitempty" db:"costo_total_partida"`
+	ProyectoNombre     *string          `json:"proyecto_nombre,omitempty" db:"proyecto_nombre"`
+	CreatedAt          time.Time        `json:"created_at" db:"created_at"`
+	UpdatedAt          time.Time        `json:"updated_at" db:"updated_at"`
 }
 
 // ResumenProyecto representa el resumen financiero de un proyecto
 type ResumenProyecto struct {
-	TotalPartidas       int64   `json:"total_partidas" db:"total_partidas"`
-	CostoDirecto        float64 `json:"costo_directo" db:"costo_directo"`
-	PartidasConMetrado  int64   `json:"partidas_con_metrado" db:"partidas_con_metrado"`
-	PartidasSinMetrado  int64   `json:"partidas_sin_metrado" db:"partidas_sin_metrado"`
+	TotalPartidas      int64           `json:"total_partidas" db:"total_partidas"`
+	CostoDirecto       costing.Decimal `json:"costo_directo" db:"costo_directo"`
+	PartidasConMetrado int64           `json:"partidas_con_metrado" db:"partidas_con_metrado"`
+	PartidasSinMetrado int64           `json:"partidas_sin_metrado" db:"partidas_sin_metrado"`
 }
 
 // MetradoRequest representa la estructura para crear/actualizar metrados
 type MetradoRequest struct {
-	PartidaCodigo string  `json:"partida_codigo" validate:"required"`
-	Metrado       float64 `json:"metrado" validate:"min=0"`
-	Unidad        *string `json:"unidad,omitempty"`
-	Observaciones *string `json:"observaciones,omitempty"`
+	PartidaCodigo string          `json:"partida_codigo" validate:"required"`
+	Metrado       costing.Decimal `json:"metrado" validate:"min=0"`
+	Unidad        *string         `json:"unidad,omitempty"`
+	Observaciones *string         `json:"observaciones,omitempty"`
 }
 
 // MetradosLoteRequest representa una solicitud para actualizar múltiples metrados
@@ -69,4 +70,18 @@ type ResumenProyectoResponse struct {
 	Success bool             `json:"success"`
 	Message string           `json:"message,omitempty"`
 	Data    *ResumenProyecto `json:"data,omitempty"`
-}
\ No newline at end of file
+}
+// FilaPlanillaRechazada es una fila de la planilla de metrados que no se aplicó
+type FilaPlanillaRechazada struct {
+	Fila   int    `json:"fila"`
+	Codigo string `json:"codigo"`
+	Motivo string `json:"motivo"`
+}
+
+// ResultadoPlanillaMetrados resume la carga de una planilla de metrados llenada fuera de línea
+type ResultadoPlanillaMetrados struct {
+	Aplicados    int                     `json:"aplicados"`
+	SinMetrado   int                     `json:"sin_metrado"`
+	Rechazadas   []FilaPlanillaRechazada `json:"rechazadas"`
+	Desconocidas []FilaPlanillaRechazada `json:"desconocidas"`
+}
@@ -1,10 +1,12 @@
 package models
 
+import "goexcel/internal/costing"
+
 // Estructuras para datos normalizados
 type NormalizedData struct {
-	Proyecto  ProyectoNormalizado  `json:"proyecto"`
-	Recursos  []RecursoNormalizado `json:"recursos"`
-	Partidas  []PartidaNormalizada `json:"partidas"`
+	Proyecto   ProyectoNormalizado   `json:"proyecto"`
+	Recursos   []RecursoNormalizado  `json:"recursos"`
+	Partidas   []PartidaNormalizada  `json:"partidas"`
 	Relaciones []RelacionNormalizada `json:"relaciones"`
 }
 
@@ -16,12 +18,12 @@ type ProyectoNormalizado struct {
 }
 
 type RecursoNormalizado struct {
-	ID            string  `json:"id"`
-	Codigo        string  `json:"codigo"`
-	Descripcion   string  `json:"descripcion"`
-	Unidad        string  `json:"unidad"`
-	PrecioBase    float64 `json:"precio_base"`
-	TipoRecurso   string  `json:"tipo_recurso"` // mano_obra, materiales, equipos, subcontratos
+	ID          string          `json:"id"`
+	Codigo      string          `json:"codigo"`
+	Descripcion string          `json:"descripcion"`
+	Unidad      string          `json:"unidad"`
+	PrecioBase  costing.Decimal `json:"precio_base"`
+	TipoRecurso string          `json:"tipo_recurso"` // mano_obra, materiales, equipos, subcontratos
 }
 
 type PartidaNormalizada struct {
@@ -34,10 +36,12 @@ type PartidaNormalizada struct {
 }
 
 type RelacionNormalizada struct {
-	ID        string   `json:"id"`
-	PartidaID string   `json:"partida_id"`
-	RecursoID string   `json:"recurso_id"`
-	Cantidad  float64  `json:"cantidad"`
-	Precio    float64  `json:"precio"`
-	Cuadrilla *float64 `json:"cuadrilla,omitempty"`
-}
\ No newline at end of file
+	ID          string           `json:"id"`
+	PartidaID   string           `json:"partida_id"`
+	RecursoID   string           `json:"recurso_id"`
+	Cantidad    costing.Decimal  `json:"cantidad"`
+	Precio      costing.Decimal  `json:"precio"`
+	Cuadrilla   *float64         `json:"cuadrilla,omitempty"`
+	Moneda      string           `json:"moneda,omitempty"`      // moneda del precio; vacía: la del proyecto
+	Desperdicio *costing.Decimal `json:"desperdicio,omitempty"` // porcentaje sobre la cantidad neta
+}
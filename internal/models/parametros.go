@@ -0,0 +1,153 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"goexcel/internal/costing"
+)
+
+// Valores por defecto de los parámetros de cálculo, los usuales en presupuestos peruanos (S10)
+const (
+	HorasJornadaPorDefecto = 8.0
+	MonedaPorDefecto       = "PEN"
+)
+
+// FormatoFecha es el formato de las fechas de los parámetros y del tipo de cambio: 2006-01-02
+const FormatoFecha = "2006-01-02"
+
+// maxDecimalesParametro limita los decimales configurables de cantidades y parciales
+const maxDecimalesParametro = 8
+
+// Parametros son los parámetros de cálculo efectivos de un proyecto o presupuesto, ya resueltos
+// con la herencia organización → proyecto/presupuesto
+type Parametros struct {
+	HorasJornada            float64 `json:"horas_jornada"`
+	DecimalesCantidad       int32   `json:"decimales_cantidad"`
+	DecimalesParcial        int32   `json:"decimales_parcial"`
+	PorcentajeIGV           float64 `json:"porcentaje_igv"`
+	Moneda                  string  `json:"moneda"`
+	CuadrillaDefineCantidad bool    `json:"cuadrilla_define_cantidad"`   // cantidad = cuadrilla × jornada / rendimiento
+	FechaTipoCambio         string  `json:"fecha_tipo_cambio,omitempty"` // vacío: la fecha del cálculo
+}
+
+// ParametrosCalculo son los parámetros declarados en un nivel (organización, proyecto, presupuesto o
+// archivo .acu); los campos nil se heredan del nivel superior
+type ParametrosCalculo struct {
+	HorasJornada            *float64 `json:"horas_jornada,omitempty"`
+	DecimalesCantidad       *int32   `json:"decimales_cantidad,omitempty"`
+	DecimalesParcial        *int32   `json:"decimales_parcial,omitempty"`
+	PorcentajeIGV           *float64 `json:"porcentaje_igv,omitempty"`
+	Moneda                  *string  `json:"moneda,omitempty"`
+	CuadrillaDefineCantidad *bool    `json:"cuadrilla_define_cantidad,omitempty"`
+	FechaTipoCambio         *string  `json:"fecha_tipo_cambio,omitempty"`
+}
+
+// ParametrosResponse representa la respuesta de la API: lo declarado en el nivel, lo heredado del
+// nivel superior y el resultado que se aplica en los cálculos
+type ParametrosResponse struct {
+	Success    bool               `json:"success"`
+	Message    string             `json:"message,omitempty"`
+	Parametros *ParametrosCalculo `json:"parametros,omitempty"`
+	Heredados  *Parametros        `json:"heredados,omitempty"`
+	Efectivos  *Parametros        `json:"efectivos,omitempty"`
+}
+
+// ParametrosPorDefecto son los parámetros cuando ni la organización ni el proyecto declaran ninguno
+func ParametrosPorDefecto() Parametros {
+	return Parametros{
+		HorasJornada:      HorasJornadaPorDefecto,
+		DecimalesCantidad: costing.DecimalesCantidad,
+		DecimalesParcial:  costing.DecimalesParcial,
+		PorcentajeIGV:     PorcentajeIGV,
+		Moneda:            MonedaPorDefecto,
+	}
+}
+
+// Aplicar devuelve los parámetros con lo declarado en el nivel inferior encima; nil no cambia nada
+func (p Parametros) Aplicar(declarados *ParametrosCalculo) Parametros {
+	if declarados == nil {
+		return p
+	}
+	if declarados.HorasJornada != nil {
+		p.HorasJornada = *declarados.HorasJornada
+	}
+	if declarados.DecimalesCantidad != nil {
+		p.DecimalesCantidad = *declarados.DecimalesCantidad
+	}
+	if declarados.DecimalesParcial != nil {
+		p.DecimalesParcial = *declarados.DecimalesParcial
+	}
+	if declarados.PorcentajeIGV != nil {
+		p.PorcentajeIGV = *declarados.PorcentajeIGV
+	}
+	if declarados.Moneda != nil {
+		p.Moneda = *declarados.Moneda
+	}
+	if declarados.CuadrillaDefineCantidad != nil {
+		p.CuadrillaDefineCantidad = *declarados.CuadrillaDefineCantidad
+	}
+	if declarados.FechaTipoCambio != nil {
+		p.FechaTipoCambio = *declarados.FechaTipoCambio
+	}
+	return p
+}
+
+// Reglas son las reglas de redondeo del motor de costos con los decimales de los parámetros
+func (p Parametros) Reglas() costing.Reglas {
+	reglas := costing.ReglasS10
+	reglas.Cantidad.Decimales = p.DecimalesCantidad
+	reglas.Parcial.Decimales = p.DecimalesParcial
+	return reglas
+}
+
+// FechaReferencia es la fecha con la que se buscan los tipos de cambio vigentes
+func (p Parametros) FechaReferencia() time.Time {
+	if fecha, err := time.Parse(FormatoFecha, p.FechaTipoCambio); err == nil {
+		return fecha
+	}
+	return time.Now()
+}
+
+// SimboloMoneda es el símbolo con el que se rotulan los montos en las cabeceras de los reportes
+func (p Parametros) SimboloMoneda() string {
+	switch p.Moneda {
+	case "PEN":
+		return "S/"
+	case "USD":
+		return "US$"
+	case "EUR":
+		return "€"
+	default:
+		return p.Moneda
+	}
+}
+
+// Validar revisa que los parámetros declarados tengan valores utilizables y normaliza la moneda
+func (c *ParametrosCalculo) Validar() error {
+	if c.HorasJornada != nil && (*c.HorasJornada <= 0 || *c.HorasJornada > 24) {
+		return fmt.Errorf("horas_jornada debe estar entre 0 y 24")
+	}
+	for nombre, decimales := range map[string]*int32{"decimales_cantidad": c.DecimalesCantidad, "decimales_parcial": c.DecimalesParcial} {
+		if decimales != nil && (*decimales < 0 || *decimales > maxDecimalesParametro) {
+			return fmt.Errorf("%s debe estar entre 0 y %d", nombre, maxDecimalesParametro)
+		}
+	}
+	if c.PorcentajeIGV != nil && (*c.PorcentajeIGV < 0 || *c.PorcentajeIGV >= 100) {
+		return fmt.Errorf("porcentaje_igv debe estar entre 0 y 100")
+	}
+	if c.Moneda != nil {
+		moneda := strings.ToUpper(strings.TrimSpace(*c.Moneda))
+		if len(moneda) != 3 {
+			return fmt.Errorf("moneda debe ser un código ISO 4217 de 3 letras")
+		}
+		c.Moneda = &moneda
+	}
+	if c.FechaTipoCambio != nil {
+		if _, err := time.Parse(FormatoFecha, *c.FechaTipoCambio); err != nil {
+			return fmt.Errorf("fecha_tipo_cambio debe tener el formato AAAA-MM-DD")
+		}
+	}
+	return nil
+}
@@ -4,32 +4,33 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"goexcel/internal/costing"
 )
 
 type Partida struct {
-	ID          uuid.UUID `json:"id" db:"id"`
-	ProyectoID  uuid.UUID `json:"proyecto_id" db:"proyecto_id"`
-	Codigo      string    `json:"codigo" db:"codigo"`
-	Descripcion string    `json:"descripcion" db:"descripcion"`
-	Unidad      string    `json:"unidad" db:"unidad"`
-	Rendimiento float64   `json:"rendimiento" db:"rendimiento"`
-	CostoTotal  float64   `json:"costo_total" db:"costo_total"`
-	Activo      bool      `json:"activo" db:"activo"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	ID          uuid.UUID       `json:"id" db:"id"`
+	ProyectoID  uuid.UUID       `json:"proyecto_id" db:"proyecto_id"`
+	Codigo      string          `json:"codigo" db:"codigo"`
+	Descripcion string          `json:"descripcion" db:"descripcion"`
+	Unidad      string          `json:"unidad" db:"unidad"`
+	Rendimiento float64         `json:"rendimiento" db:"rendimiento"`
+	CostoTotal  costing.Decimal `json:"costo_total" db:"costo_total"`
+	Activo      bool            `json:"activo" db:"activo"`
+	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at" db:"updated_at"`
 
 	// Relaciones
-	Proyecto  *Proyecto        `json:"proyecto,omitempty"`
-	Recursos  []PartidaRecurso `json:"recursos,omitempty"`
+	Proyecto *Proyecto        `json:"proyecto,omitempty"`
+	Recursos []PartidaRecurso `json:"recursos,omitempty"`
 }
 
 type PartidaCompleta struct {
 	Partida
-	CostoManoObra     float64 `json:"costo_mano_obra" db:"costo_mano_obra"`
-	CostoMateriales   float64 `json:"costo_materiales" db:"costo_materiales"`
-	CostoEquipos      float64 `json:"costo_equipos" db:"costo_equipos"`
-	CostoSubcontratos float64 `json:"costo_subcontratos" db:"costo_subcontratos"`
-	ProyectoNombre    string  `json:"proyecto_nombre" db:"proyecto_nombre"`
+	CostoManoObra     costing.Decimal `json:"costo_mano_obra" db:"costo_mano_obra"`
+	CostoMateriales   costing.Decimal `json:"costo_materiales" db:"costo_materiales"`
+	CostoEquipos      costing.Decimal `json:"costo_equipos" db:"costo_equipos"`
+	CostoSubcontratos costing.Decimal `json:"costo_subcontratos" db:"costo_subcontratos"`
+	ProyectoNombre    string          `json:"proyecto_nombre" db:"proyecto_nombre"`
 }
 
 type PartidaCreateRequest struct {
@@ -61,10 +62,10 @@ type PartidaJSON struct {
 }
 
 type RecursoJSON struct {
-	Codigo      string  `json:"codigo"`
-	Descripcion string  `json:"descripcion"`
-	Unidad      string  `json:"unidad"`
-	Cuadrilla   float64 `json:"cuadrilla,omitempty"`
-	Cantidad    float64 `json:"cantidad"`
-	Precio      float64 `json:"precio"`
-}
\ No newline at end of file
+	Codigo      string          `json:"codigo"`
+	Descripcion string          `json:"descripcion"`
+	Unidad      string          `json:"unidad"`
+	Cuadrilla   float64         `json:"cuadrilla,omitempty"`
+	Cantidad    costing.Decimal `json:"cantidad"`
+	Precio      costing.Decimal `json:"precio"`
+}
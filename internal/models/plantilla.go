@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PlantillaExcel representa la plantilla de reportes Excel de una organización
+type PlantillaExcel struct {
+	ID             uuid.UUID          `json:"id" db:"id"`
+	OrganizacionID uuid.UUID          `json:"organizacion_id" db:"organizacion_id"`
+	ColorTitulo    string             `json:"color_titulo" db:"color_titulo"`
+	ColorCabecera  string             `json:"color_cabecera" db:"color_cabecera"`
+	ColorPartida   string             `json:"color_partida" db:"color_partida"`
+	ColorSeccion   string             `json:"color_seccion" db:"color_seccion"`
+	ColorTotal     string             `json:"color_total" db:"color_total"`
+	Fuente         string             `json:"fuente" db:"fuente"`
+	TamanoFuente   float64            `json:"tamano_fuente" db:"tamano_fuente"`
+	FormatoNumero  string             `json:"formato_numero" db:"formato_numero"`
+	Empresa        string             `json:"empresa" db:"empresa"`
+	RUC            string             `json:"ruc" db:"ruc"`
+	Ingeniero      string             `json:"ingeniero" db:"ingeniero"`
+	CIP            string             `json:"cip" db:"cip"`
+	Logo           []byte             `json:"logo,omitempty" db:"logo"`
+	LogoExtension  string             `json:"logo_extension,omitempty" db:"logo_extension"`
+	AnchosColumna  map[string]float64 `json:"anchos_columna,omitempty" db:"anchos_columna"`
+	CreatedAt      time.Time          `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time          `json:"updated_at" db:"updated_at"`
+}
+
+// PlantillaExcelResponse representa la respuesta de la API para la plantilla de una organización
+type PlantillaExcelResponse struct {
+	Success bool            `json:"success"`
+	Message string          `json:"message,omitempty"`
+	Data    *PlantillaExcel `json:"data,omitempty"`
+}
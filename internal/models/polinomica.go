@@ -0,0 +1,39 @@
+package models
+
+import "goexcel/internal/costing"
+
+// ElementoMonomio representa un índice unificado dentro de un monomio de la fórmula polinómica
+type ElementoMonomio struct {
+	IndiceCodigo      string          `json:"indice_codigo"`
+	IndiceDescripcion string          `json:"indice_descripcion"`
+	Costo             costing.Decimal `json:"costo"`
+	Incidencia        float64         `json:"incidencia"`
+	Coeficiente       float64         `json:"coeficiente"`
+}
+
+// Monomio representa un término de la fórmula polinómica, simple o agrupado
+type Monomio struct {
+	Simbolo     string            `json:"simbolo"`
+	Coeficiente float64           `json:"coeficiente"`
+	Elementos   []ElementoMonomio `json:"elementos"`
+}
+
+// FormulaPolinomica representa la fórmula de reajuste K y su tabla de agrupamiento
+type FormulaPolinomica struct {
+	Formula      string            `json:"formula"`
+	Monomios     []Monomio         `json:"monomios"`
+	Incidencias  []ElementoMonomio `json:"incidencias"`
+	CostoDirecto costing.Decimal   `json:"costo_directo"`
+}
+
+// FormulaPolinomicaResponse representa la respuesta de la API para la fórmula polinómica
+type FormulaPolinomicaResponse struct {
+	Success bool               `json:"success"`
+	Message string             `json:"message,omitempty"`
+	Data    *FormulaPolinomica `json:"data,omitempty"`
+}
+
+// IndicesUnificadosRequest asigna índices unificados a recursos identificados por código
+type IndicesUnificadosRequest struct {
+	Asignaciones map[string]string `json:"asignaciones" validate:"required"`
+}
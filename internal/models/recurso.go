@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"goexcel/internal/costing"
 )
 
 type TipoRecurso struct {
@@ -14,30 +15,32 @@ type TipoRecurso struct {
 }
 
 type Recurso struct {
-	ID            uuid.UUID   `json:"id" db:"id"`
-	Codigo        string      `json:"codigo" db:"codigo"`
-	Descripcion   string      `json:"descripcion" db:"descripcion"`
-	Unidad        string      `json:"unidad" db:"unidad"`
-	PrecioBase    float64     `json:"precio_base" db:"precio_base"`
-	TipoRecursoID uuid.UUID   `json:"tipo_recurso_id" db:"tipo_recurso_id"`
-	Activo        bool        `json:"activo" db:"activo"`
-	CreatedAt     time.Time   `json:"created_at" db:"created_at"`
-	UpdatedAt     time.Time   `json:"updated_at" db:"updated_at"`
+	ID              uuid.UUID       `json:"id" db:"id"`
+	Codigo          string          `json:"codigo" db:"codigo"`
+	Descripcion     string          `json:"descripcion" db:"descripcion"`
+	Unidad          string          `json:"unidad" db:"unidad"`
+	PrecioBase      costing.Decimal `json:"precio_base" db:"precio_base"`
+	TipoRecursoID   uuid.UUID       `json:"tipo_recurso_id" db:"tipo_recurso_id"`
+	IndiceUnificado *string         `json:"indice_unificado" db:"indice_unificado"`
+	Activo          bool            `json:"activo" db:"activo"`
+	CreatedAt       time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at" db:"updated_at"`
 
 	// Relaciones
 	TipoRecurso *TipoRecurso `json:"tipo_recurso,omitempty"`
 }
 
 type PartidaRecurso struct {
-	ID         uuid.UUID `json:"id" db:"id"`
-	PartidaID  uuid.UUID `json:"partida_id" db:"partida_id"`
-	RecursoID  uuid.UUID `json:"recurso_id" db:"recurso_id"`
-	Cantidad   float64   `json:"cantidad" db:"cantidad"`
-	Precio     float64   `json:"precio" db:"precio"`
-	Cuadrilla  *float64  `json:"cuadrilla" db:"cuadrilla"`
-	Parcial    float64   `json:"parcial" db:"parcial"`
-	CreatedAt  time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+	ID          uuid.UUID        `json:"id" db:"id"`
+	PartidaID   uuid.UUID        `json:"partida_id" db:"partida_id"`
+	RecursoID   uuid.UUID        `json:"recurso_id" db:"recurso_id"`
+	Cantidad    costing.Decimal  `json:"cantidad" db:"cantidad"`
+	Precio      costing.Decimal  `json:"precio" db:"precio"`
+	Cuadrilla   *float64         `json:"cuadrilla" db:"cuadrilla"`
+	Desperdicio *costing.Decimal `json:"desperdicio,omitempty" db:"desperdicio"` // porcentaje sobre la cantidad neta
+	Parcial     costing.Decimal  `json:"parcial" db:"parcial"`                   // incluye el desperdicio
+	CreatedAt   time.Time        `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at" db:"updated_at"`
 
 	// Relaciones
 	Partida *Partida `json:"partida,omitempty"`
@@ -53,31 +56,33 @@ type PartidaRecursoDetalle struct {
 }
 
 type RecursoCreateRequest struct {
-	Codigo        string    `json:"codigo" validate:"required,min=1,max=50"`
-	Descripcion   string    `json:"descripcion" validate:"required"`
-	Unidad        string    `json:"unidad" validate:"required,max=20"`
-	PrecioBase    float64   `json:"precio_base" validate:"min=0"`
-	TipoRecursoID uuid.UUID `json:"tipo_recurso_id" validate:"required"`
+	Codigo        string          `json:"codigo" validate:"required,min=1,max=50"`
+	Descripcion   string          `json:"descripcion" validate:"required"`
+	Unidad        string          `json:"unidad" validate:"required,max=20"`
+	PrecioBase    costing.Decimal `json:"precio_base" validate:"min=0"`
+	TipoRecursoID uuid.UUID       `json:"tipo_recurso_id" validate:"required"`
 }
 
 type RecursoUpdateRequest struct {
-	Codigo      *string  `json:"codigo,omitempty"`
-	Descripcion *string  `json:"descripcion,omitempty"`
-	Unidad      *string  `json:"unidad,omitempty"`
-	PrecioBase  *float64 `json:"precio_base,omitempty"`
-	Activo      *bool    `json:"activo,omitempty"`
+	Codigo          *string          `json:"codigo,omitempty"`
+	Descripcion     *string          `json:"descripcion,omitempty"`
+	Unidad          *string          `json:"unidad,omitempty"`
+	PrecioBase      *costing.Decimal `json:"precio_base,omitempty"`
+	IndiceUnificado *string          `json:"indice_unificado,omitempty"`
+	Activo          *bool            `json:"activo,omitempty"`
 }
 
 type PartidaRecursoCreateRequest struct {
-	PartidaID uuid.UUID `json:"partida_id" validate:"required"`
-	RecursoID uuid.UUID `json:"recurso_id" validate:"required"`
-	Cantidad  float64   `json:"cantidad" validate:"min=0"`
-	Precio    float64   `json:"precio" validate:"min=0"`
-	Cuadrilla *float64  `json:"cuadrilla,omitempty"`
+	PartidaID   uuid.UUID        `json:"partida_id" validate:"required"`
+	RecursoID   uuid.UUID        `json:"recurso_id" validate:"required"`
+	Cantidad    costing.Decimal  `json:"cantidad" validate:"min=0"`
+	Precio      costing.Decimal  `json:"precio" validate:"min=0"`
+	Cuadrilla   *float64         `json:"cuadrilla,omitempty"`
+	Desperdicio *costing.Decimal `json:"desperdicio,omitempty"`
 }
 
 type PartidaRecursoUpdateRequest struct {
-	Cantidad  *float64 `json:"cantidad,omitempty"`
-	Precio    *float64 `json:"precio,omitempty"`
-	Cuadrilla *float64 `json:"cuadrilla,omitempty"`
-}
\ No newline at end of file
+	Cantidad  *costing.Decimal `json:"cantidad,omitempty"`
+	Precio    *costing.Decimal `json:"precio,omitempty"`
+	Cuadrilla *float64         `json:"cuadrilla,omitempty"`
+}
@@ -0,0 +1,116 @@
+package models
+
+import (
+	"time"
+
+	"goexcel/internal/costing"
+)
+
+// ReportePresupuesto es el modelo único del que se generan todos los formatos de exportación:
+// el árbol del presupuesto con sus subtotales, el APU de cada partida, la relación de insumos y el pie.
+// Los renderizadores solo presentan estos datos; los cálculos se hacen al construir el reporte.
+type ReportePresupuesto struct {
+	Proyecto string              `json:"proyecto"`
+	Fecha    time.Time           `json:"fecha"`
+	Arbol    []*NodoReporte      `json:"arbol"`
+	Partidas []*PartidaReporte   `json:"partidas"` // en el orden del presupuesto
+	Insumos  *RelacionInsumos    `json:"insumos,omitempty"`
+	Pie      PiePresupuesto      `json:"pie"`
+	Opciones OpcionesExportacion `json:"-"`
+
+	TiposCambio *TasasCambio         `json:"tipos_cambio,omitempty"` // tasas usadas si hay precios en otra moneda
+	ManoObra    *CalculoManoObra     `json:"mano_obra,omitempty"`    // costo hora-hombre de construcción civil
+	Equipos     []CostoHorarioEquipo `json:"equipos,omitempty"`      // costo horario de los equipos con análisis
+	Flete       *CalculoFlete        `json:"flete,omitempty"`        // flete de los materiales si el proyecto tiene ruta
+}
+
+// NodoReporte es un título o una partida del árbol del presupuesto
+type NodoReporte struct {
+	Codigo      string          `json:"codigo"`
+	Descripcion string          `json:"descripcion"`
+	Nivel       int             `json:"nivel"`             // 1 para el primer nivel del árbol
+	Partida     *PartidaReporte `json:"partida,omitempty"` // nil en los títulos
+	Hijos       []*NodoReporte  `json:"hijos,omitempty"`
+	Subtotal    costing.Decimal `json:"subtotal"` // parcial de la partida o suma de sus hijos
+}
+
+// EsTitulo indica si el nodo agrupa otras partidas
+func (n *NodoReporte) EsTitulo() bool {
+	return n.Partida == nil
+}
+
+// PartidaReporte es una partida con su metrado y su análisis de precio unitario
+type PartidaReporte struct {
+	Codigo        string           `json:"codigo"`
+	Descripcion   string           `json:"descripcion"`
+	Unidad        string           `json:"unidad"`
+	Rendimiento   float64          `json:"rendimiento"`
+	Metrado       costing.Decimal  `json:"metrado"`
+	CostoUnitario costing.Decimal  `json:"costo_unitario"`
+	Parcial       costing.Decimal  `json:"parcial"`
+	Secciones     []SeccionReporte `json:"secciones"` // siempre los cuatro tipos de recurso, en orden
+}
+
+// CostoSeccion devuelve el subtotal del tipo de recurso indicado ("mano_obra", "materiales"...)
+func (p *PartidaReporte) CostoSeccion(tipo string) costing.Decimal {
+	for _, seccion := range p.Secciones {
+		if seccion.Tipo == tipo {
+			return seccion.Subtotal
+		}
+	}
+	return costing.Decimal{}
+}
+
+// SeccionReporte agrupa los recursos de un mismo tipo dentro del APU
+type SeccionReporte struct {
+	Tipo     string           `json:"tipo"`
+	Nombre   string           `json:"nombre"`
+	Recursos []RecursoReporte `json:"recursos"`
+	Subtotal costing.Decimal  `json:"subtotal"`
+}
+
+// RecursoReporte es una línea del APU
+type RecursoReporte struct {
+	Codigo      string          `json:"codigo"`
+	Descripcion string          `json:"descripcion"`
+	Unidad      string          `json:"unidad"`
+	Cuadrilla   float64         `json:"cuadrilla,omitempty"`
+	Cantidad    costing.Decimal `json:"cantidad"` // incluye el desperdicio
+	Precio      costing.Decimal `json:"precio"`   // en la moneda del presupuesto
+	Parcial     costing.Decimal `json:"parcial"`
+
+	// Porcentaje de desperdicio y cantidad neta antes de aplicarlo; vacíos si el recurso no tiene
+	Desperdicio  *costing.Decimal `json:"desperdicio,omitempty"`
+	CantidadNeta *costing.Decimal `json:"cantidad_neta,omitempty"`
+
+	// Flete por unidad ya sumado a Precio; vacío si el material no lleva flete
+	Flete *costing.Decimal `json:"flete,omitempty"`
+
+	// Precio cotizado en otra moneda y tipo de cambio con el que se convirtió; vacíos si no hubo conversión
+	MonedaOriginal string           `json:"moneda_original,omitempty"`
+	PrecioOriginal *costing.Decimal `json:"precio_original,omitempty"`
+	TipoCambio     *costing.Decimal `json:"tipo_cambio,omitempty"`
+}
+
+// Metrados devuelve los metrados registrados por código de partida; las partidas sin metrado no se incluyen
+func (r *ReportePresupuesto) Metrados() map[string]costing.Decimal {
+	metrados := make(map[string]costing.Decimal, len(r.Partidas))
+	for _, partida := range r.Partidas {
+		if !partida.Metrado.EsCero() {
+			metrados[partida.Codigo] = partida.Metrado
+		}
+	}
+	return metrados
+}
+
+// Recorrer visita los nodos del árbol en orden de presentación, cada título antes que sus hijos
+func (r *ReportePresupuesto) Recorrer(visitar func(nodo *NodoReporte)) {
+	var recorrer func(nodos []*NodoReporte)
+	recorrer = func(nodos []*NodoReporte) {
+		for _, nodo := range nodos {
+			visitar(nodo)
+			recorrer(nodo.Hijos)
+		}
+	}
+	recorrer(r.Arbol)
+}
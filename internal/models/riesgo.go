@@ -0,0 +1,110 @@
+package models
+
+import (
+	"time"
+
+	"goexcel/internal/costing"
+)
+
+// Datos del presupuesto a los que se asigna una variable de riesgo
+const (
+	VariablePrecio      = "precio"      // precio de un recurso o de todos los de un tipo
+	VariableRendimiento = "rendimiento" // rendimiento de una partida o de las partidas de un título
+	VariableMetrado     = "metrado"     // metrado de una partida o de las partidas de un título
+)
+
+// Distribuciones de probabilidad de las variables de riesgo
+const (
+	DistribucionTriangular = "triangular"
+	DistribucionPERT       = "pert"
+	DistribucionUniforme   = "uniforme"
+)
+
+// AnalisisRiesgoRequest configura un análisis de riesgo de costos por Monte Carlo. Las variables se
+// aplican en memoria sobre el presupuesto calculado del proyecto; el proyecto no cambia.
+type AnalisisRiesgoRequest struct {
+	Iteraciones int              `json:"iteraciones,omitempty"` // 5000 si no se indica
+	Semilla     *uint64          `json:"semilla,omitempty"`     // sin semilla se genera una, que se devuelve para repetir el análisis
+	Percentiles []float64        `json:"percentiles,omitempty"` // 10, 50, 80 y 90 si no se indican
+	Intervalos  int              `json:"intervalos,omitempty"`  // barras del histograma; 20 si no se indica
+	Variables   []VariableRiesgo `json:"variables"`
+}
+
+// VariableRiesgo es un dato incierto del presupuesto. Cada variable toma un solo valor por iteración,
+// así que una regla por tipo de recurso mueve juntos todos sus recursos. Si varias variables alcanzan
+// un mismo dato, vale la más específica: el código de recurso antes que el tipo, y la partida antes que
+// su título.
+type VariableRiesgo struct {
+	Nombre       string             `json:"nombre,omitempty"`       // etiqueta en el tornado; se arma con el objetivo y el código si falta
+	Objetivo     string             `json:"objetivo"`               // "precio", "rendimiento" o "metrado"
+	Codigo       string             `json:"codigo,omitempty"`       // recurso (precio) o partida o título; sin código, todo el proyecto
+	TipoRecurso  string             `json:"tipo_recurso,omitempty"` // solo para precios: regla para todos los recursos del tipo
+	Distribucion DistribucionRiesgo `json:"distribucion"`
+}
+
+// DistribucionRiesgo es la distribución de la variación en % sobre el valor guardado: {-5, 0, 15}
+// significa entre 5% menos y 15% más, con el valor guardado como el más probable. La moda no se usa en
+// la uniforme.
+type DistribucionRiesgo struct {
+	Tipo   string  `json:"tipo"` // "triangular", "pert" o "uniforme"
+	Minimo float64 `json:"minimo"`
+	Moda   float64 `json:"moda"`
+	Maximo float64 `json:"maximo"`
+}
+
+// AnalisisRiesgo es el resultado del Monte Carlo sobre el costo directo del presupuesto
+type AnalisisRiesgo struct {
+	Proyecto    string    `json:"proyecto"`
+	Fecha       time.Time `json:"fecha"`
+	Iteraciones int       `json:"iteraciones"`
+	Semilla     uint64    `json:"semilla"`
+
+	CostoDirecto             costing.Decimal `json:"costo_directo"`              // el del presupuesto, sin variaciones
+	ProbabilidadCostoDirecto float64         `json:"probabilidad_costo_directo"` // % de iteraciones que no lo superan
+	P80                      PercentilRiesgo `json:"p80"`
+	Contingencia             costing.Decimal `json:"contingencia"` // P80 - costo directo
+	Media                    costing.Decimal `json:"media"`
+	DesviacionEstandar       costing.Decimal `json:"desviacion_estandar"`
+	Minimo                   costing.Decimal `json:"minimo"`
+	Maximo                   costing.Decimal `json:"maximo"`
+
+	Percentiles []PercentilRiesgo     `json:"percentiles"`
+	Histograma  []IntervaloHistograma `json:"histograma"`
+	Tornado     []SensibilidadRiesgo  `json:"tornado"` // de mayor a menor rango
+	Variables   []VariableRiesgo      `json:"variables"`
+	NoAplicadas []string              `json:"no_aplicadas,omitempty"` // variables que no alcanzan ningún dato
+	Opciones    OpcionesExportacion   `json:"-"`
+}
+
+// PercentilRiesgo es el costo que no se supera con la probabilidad indicada, sin y con el pie del presupuesto
+type PercentilRiesgo struct {
+	Percentil    float64         `json:"percentil"`
+	CostoDirecto costing.Decimal `json:"costo_directo"`
+	Total        costing.Decimal `json:"total"` // con gastos generales, utilidad e IGV
+}
+
+// IntervaloHistograma es una barra del histograma del costo directo
+type IntervaloHistograma struct {
+	Desde      costing.Decimal `json:"desde"`
+	Hasta      costing.Decimal `json:"hasta"`
+	Frecuencia int             `json:"frecuencia"`
+	Porcentaje float64         `json:"porcentaje"` // % de las iteraciones
+	Acumulado  float64         `json:"acumulado"`  // % de las iteraciones hasta este intervalo
+}
+
+// SensibilidadRiesgo es una barra del tornado: el costo directo con la variable en su percentil 10 y
+// en su percentil 90 y las demás en el valor guardado
+type SensibilidadRiesgo struct {
+	Variable    string          `json:"variable"`
+	Bajo        costing.Decimal `json:"bajo"`
+	Alto        costing.Decimal `json:"alto"`
+	Rango       costing.Decimal `json:"rango"`       // |alto - bajo|
+	Correlacion float64         `json:"correlacion"` // entre la variable y el costo directo en las iteraciones
+}
+
+// AnalisisRiesgoResponse representa la respuesta de la API con un análisis de riesgo
+type AnalisisRiesgoResponse struct {
+	Success bool            `json:"success"`
+	Message string          `json:"message,omitempty"`
+	Data    *AnalisisRiesgo `json:"data"`
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+
+	"goexcel/internal/costing"
+)
+
+// SimulacionRequest son los ajustes de una simulación "qué pasa si". Se aplican en memoria sobre las
+// partidas, recursos y metrados guardados del proyecto; el proyecto no cambia.
+type SimulacionRequest struct {
+	// PorTipo es el % de variación del precio por tipo de recurso ("mano_obra", "materiales", "equipos",
+	// "subcontratos"); p. ej. {"materiales": 8} sube 8% todos los materiales
+	PorTipo map[string]costing.Decimal `json:"por_tipo,omitempty"`
+
+	// PorCodigo es el % de variación del precio de recursos puntuales; prevalece sobre el de su tipo
+	PorCodigo []AjustePrecioRecurso `json:"por_codigo,omitempty"`
+
+	// Rendimientos multiplica el rendimiento de las partidas de un título; si hay títulos anidados,
+	// vale el más específico
+	Rendimientos []AjusteRendimiento `json:"rendimientos,omitempty"`
+}
+
+// AjustePrecioRecurso es la variación del precio de un recurso en todas las partidas que lo usan
+type AjustePrecioRecurso struct {
+	Codigo     string          `json:"codigo"`
+	Porcentaje costing.Decimal `json:"porcentaje"` // -10 baja el precio 10%
+}
+
+// AjusteRendimiento es el factor que multiplica el rendimiento de las partidas de un título: 1.2 rinde
+// 20% más, por lo que la mano de obra y los equipos por unidad bajan en la misma proporción
+type AjusteRendimiento struct {
+	Titulo string          `json:"titulo"` // código del título, p. ej. "02.01"
+	Factor costing.Decimal `json:"factor"`
+}
+
+// ResultadoSimulacion compara el presupuesto del proyecto (base) con el recalculado con los ajustes
+type ResultadoSimulacion struct {
+	Proyecto          string              `json:"proyecto"`
+	Fecha             time.Time           `json:"fecha"`
+	Ajustes           SimulacionRequest   `json:"ajustes"`
+	RecursosAjustados int                 `json:"recursos_ajustados"` // líneas de APU con precio ajustado
+	PartidasAjustadas int                 `json:"partidas_ajustadas"` // partidas con rendimiento ajustado
+	Titulos           []TotalSimulacion   `json:"titulos"`            // todos los títulos, en el orden del presupuesto
+	TiposRecurso      []TotalSimulacion   `json:"tipos_recurso"`      // Σ metrado × subtotal de cada tipo en el APU
+	CostoDirecto      TotalSimulacion     `json:"costo_directo"`
+	Total             TotalSimulacion     `json:"total"`                  // con gastos generales, utilidad e IGV
+	NoAplicados       []string            `json:"no_aplicados,omitempty"` // ajustes que no coinciden con ningún recurso o título
+	Opciones          OpcionesExportacion `json:"-"`
+}
+
+// TotalSimulacion es un total antes y después de aplicar los ajustes
+type TotalSimulacion struct {
+	Codigo              string           `json:"codigo,omitempty"` // código del título o tipo de recurso
+	Descripcion         string           `json:"descripcion,omitempty"`
+	Nivel               int              `json:"nivel,omitempty"` // solo en los títulos
+	Base                costing.Decimal  `json:"base"`
+	Simulado            costing.Decimal  `json:"simulado"`
+	Diferencia          costing.Decimal  `json:"diferencia"`                     // simulado - base
+	VariacionPorcentaje *costing.Decimal `json:"variacion_porcentaje,omitempty"` // nil si el base es cero
+}
+
+// SimulacionResponse representa la respuesta de la API con el resultado de una simulación
+type SimulacionResponse struct {
+	Success bool                 `json:"success"`
+	Message string               `json:"message,omitempty"`
+	Data    *ResultadoSimulacion `json:"data"`
+}
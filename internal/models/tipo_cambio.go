@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"goexcel/internal/costing"
+)
+
+// TipoCambio es una entrada de la tabla de tipos de cambio, vigente desde su fecha hasta la siguiente
+// entrada del mismo par de monedas
+type TipoCambio struct {
+	ID             uuid.UUID       `json:"id"`
+	OrganizacionID *uuid.UUID      `json:"organizacion_id,omitempty"` // nil: tasa global, para todas las organizaciones
+	MonedaOrigen   string          `json:"moneda_origen"`
+	MonedaDestino  string          `json:"moneda_destino"`
+	Tasa           costing.Decimal `json:"tasa"` // unidades de la moneda destino por unidad de la de origen
+	FechaVigencia  string          `json:"fecha_vigencia"`
+	Fuente         string          `json:"fuente,omitempty"` // p. ej. "SBS" o "BCRP"
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+// TipoCambioRequest es una entrada manual de la tabla; la fecha tiene el formato AAAA-MM-DD
+type TipoCambioRequest struct {
+	MonedaOrigen  string          `json:"moneda_origen"`
+	MonedaDestino string          `json:"moneda_destino"`
+	Tasa          costing.Decimal `json:"tasa"`
+	FechaVigencia string          `json:"fecha_vigencia"`
+	Fuente        string          `json:"fuente,omitempty"`
+}
+
+// TiposCambioResponse representa la respuesta de la API con la tabla de tipos de cambio
+type TiposCambioResponse struct {
+	Success    bool         `json:"success"`
+	Message    string       `json:"message,omitempty"`
+	Data       []TipoCambio `json:"data"`
+	Importados int          `json:"importados,omitempty"`
+}
+
+// TasasCambio son las tasas para llevar los precios a la moneda del presupuesto, vigentes a una fecha
+type TasasCambio struct {
+	Moneda string                     `json:"moneda"` // moneda del presupuesto
+	Fecha  string                     `json:"fecha"`
+	Tasas  map[string]costing.Decimal `json:"tasas"` // por moneda de origen
+}
+
+// Tasa devuelve la tasa de una moneda de origen; la moneda del presupuesto (o vacía) vale 1
+func (t *TasasCambio) Tasa(moneda string) (costing.Decimal, bool) {
+	if moneda == "" || moneda == t.Moneda {
+		return costing.NuevoDecimal(1, 0), true
+	}
+	tasa, existe := t.Tasas[moneda]
+	return tasa, existe
+}
@@ -13,6 +13,7 @@ import (
 	"goexcel/internal/database"
 	"goexcel/internal/database/repositories"
 	apiHandlers "goexcel/internal/handlers"
+	"goexcel/internal/services"
 )
 
 type Server struct {
@@ -25,6 +26,14 @@ type Server struct {
 	multiTenantHandler      *apiHandlers.ProyectoMultiTenantHandler
 	metradoHandler          *apiHandlers.MetradoHandler
 	presupuestoJerarquicoHandler *apiHandlers.PresupuestoJerarquicoHandler
+	insumosHandler          *apiHandlers.InsumosHandler
+	plantillaHandler        *apiHandlers.PlantillaHandler
+	parametrosHandler       *apiHandlers.ParametrosHandler
+	tipoCambioHandler       *apiHandlers.TipoCambioHandler
+	listaPreciosHandler     *apiHandlers.ListaPreciosHandler
+	manoObraHandler         *apiHandlers.ManoObraHandler
+	equipoHandler           *apiHandlers.EquipoHandler
+	fleteHandler            *apiHandlers.FleteHandler
 	jwtService              *auth.JWTService
 	authMiddleware          *auth.AuthMiddleware
 }
@@ -36,6 +45,27 @@ func NewServer(cfg *config.Config, db *database.DB) *Server {
 	proyectoRepo := repositories.NewProyectoRepository(db)
 	metradoRepo := repositories.NewMetradoRepository(db.DB)
 	presupuestoRepo := repositories.NewPresupuestoRepository(db.DB)
+	recursoRepo := repositories.NewRecursoRepository(db)
+	plantillaRepo := repositories.NewPlantillaRepository(db.DB)
+	parametrosRepo := repositories.NewParametrosRepository(db.DB)
+	tipoCambioRepo := repositories.NewTipoCambioRepository(db.DB)
+	listaPreciosRepo := repositories.NewListaPreciosRepository(db.DB)
+	manoObraRepo := repositories.NewManoObraRepository(db.DB)
+	equipoRepo := repositories.NewEquipoRepository(db.DB)
+	fleteRepo := repositories.NewFleteRepository(db.DB)
+
+	// Inicializar servicios de cálculo
+	parametrosSvc := services.NewParametrosService(parametrosRepo)
+	tipoCambioSvc := services.NewTipoCambioService(tipoCambioRepo)
+	fleteSvc := services.NewFleteService(fleteRepo)
+	calculoSvc := services.NewCalculoService(db.DB, parametrosSvc, tipoCambioSvc, fleteSvc)
+	insumosSvc := services.NewInsumosService(db.DB, calculoSvc)
+	formulaSvc := services.NewFormulaPolinomicaService()
+	plantillaSvc := services.NewPlantillaService(plantillaRepo, organizacionRepo)
+	listaPreciosSvc := services.NewListaPreciosService(listaPreciosRepo, calculoSvc)
+	manoObraSvc := services.NewManoObraService(manoObraRepo, recursoRepo)
+	equipoSvc := services.NewEquipoService(equipoRepo, recursoRepo, listaPreciosSvc)
+	planillaMetradosSvc := services.NewPlanillaMetradosService(proyectoRepo, metradoRepo, services.NewHierarchyService(db.DB))
 
 	// Inicializar servicios de auth
 	jwtService := auth.NewJWTService(cfg.JWT.Secret, "PresupuestosAI")
@@ -50,8 +80,16 @@ func NewServer(cfg *config.Config, db *database.DB) *Server {
 		authHandler:                  apiHandlers.NewAuthHandler(usuarioRepo, jwtService),
 		adminHandler:                 apiHandlers.NewAdminHandler(usuarioRepo, organizacionRepo, proyectoRepo),
 		multiTenantHandler:           apiHandlers.NewProyectoMultiTenantHandler(proyectoRepo),
-		metradoHandler:               apiHandlers.NewMetradoHandler(metradoRepo),
+		metradoHandler:               apiHandlers.NewMetradoHandler(metradoRepo, proyectoRepo, planillaMetradosSvc, calculoSvc),
 		presupuestoJerarquicoHandler: apiHandlers.NewPresupuestoJerarquicoHandler(presupuestoRepo),
+		insumosHandler:               apiHandlers.NewInsumosHandler(insumosSvc, formulaSvc, recursoRepo, proyectoRepo, presupuestoRepo),
+		plantillaHandler:             apiHandlers.NewPlantillaHandler(plantillaSvc),
+		parametrosHandler:            apiHandlers.NewParametrosHandler(parametrosSvc, proyectoRepo, presupuestoRepo),
+		tipoCambioHandler:            apiHandlers.NewTipoCambioHandler(tipoCambioSvc),
+		listaPreciosHandler:          apiHandlers.NewListaPreciosHandler(listaPreciosSvc, equipoSvc, proyectoRepo),
+		manoObraHandler:              apiHandlers.NewManoObraHandler(manoObraSvc, listaPreciosSvc, equipoSvc),
+		equipoHandler:                apiHandlers.NewEquipoHandler(equipoSvc),
+		fleteHandler:                 apiHandlers.NewFleteHandler(fleteSvc, proyectoRepo),
 		jwtService:                   jwtService,
 		authMiddleware:               authMiddleware,
 	}
@@ -90,6 +128,7 @@ func (s *Server) setupRoutes() {
 	publicProjects.HandleFunc("/projects/featured", s.multiTenantHandler.GetProyectosDestacados).Methods("GET")
 	publicProjects.HandleFunc("/projects/{id}", s.multiTenantHandler.GetProjectWithLikeStatus).Methods("GET")
 	publicProjects.HandleFunc("/projects/{id}/details", s.multiTenantHandler.GetPublicProjectDetails).Methods("GET")
+	publicProjects.HandleFunc("/projects/{id}/preview", s.proyectoHandler.PreviewPublicProject).Methods("GET")
 
 	// User project routes (require auth)
 	userProjects := api.PathPrefix("/my").Subrouter()
@@ -104,25 +143,107 @@ func (s *Server) setupRoutes() {
 	projects.Use(s.middlewareAdapter(s.authMiddleware.RequireAuth))
 	projects.HandleFunc("", s.proyectoHandler.GetProjects).Methods("GET")
 	projects.HandleFunc("", s.proyectoHandler.CreateProject).Methods("POST")
+	projects.HandleFunc("/import/preview", s.proyectoHandler.PreviewImport).Methods("POST")
+	projects.HandleFunc("/import/confirm", s.proyectoHandler.ConfirmImport).Methods("POST")
 	projects.HandleFunc("/{id}", s.proyectoHandler.GetProject).Methods("GET")
 	projects.HandleFunc("/{id}", s.proyectoHandler.UpdateProject).Methods("PUT")
 	projects.HandleFunc("/{id}", s.proyectoHandler.DeleteProject).Methods("DELETE")
 	projects.HandleFunc("/{id}/export", s.proyectoHandler.ExportProject).Methods("GET")
+	projects.HandleFunc("/{id}/preview", s.proyectoHandler.PreviewProject).Methods("GET")
+	projects.HandleFunc("/{id}/compare", s.proyectoHandler.CompareProjects).Methods("GET")
+	projects.HandleFunc("/{id}/simulate", s.proyectoHandler.SimulateProject).Methods("POST")
+	projects.HandleFunc("/{id}/risk", s.proyectoHandler.AnalyzeRisk).Methods("POST")
 	projects.HandleFunc("/{id}/acu", s.proyectoHandler.GetProjectACU).Methods("GET")
 	projects.HandleFunc("/{id}/hierarchy", s.proyectoHandler.GetProjectHierarchy).Methods("GET")
 	projects.HandleFunc("/{id}/titles", s.proyectoHandler.GetProjectTitles).Methods("GET")
 	projects.HandleFunc("/{id}/titles", s.proyectoHandler.UpdateProjectTitles).Methods("PUT")
-	
+	projects.HandleFunc("/{id}/parametros", s.parametrosHandler.ObtenerParametrosProyecto).Methods("GET")
+	projects.HandleFunc("/{id}/parametros", s.parametrosHandler.GuardarParametrosProyecto).Methods("PUT")
+	projects.HandleFunc("/{id}/lista-precios", s.listaPreciosHandler.ObtenerListaPreciosProyecto).Methods("GET")
+	projects.HandleFunc("/{id}/lista-precios", s.listaPreciosHandler.AsignarListaPreciosProyecto).Methods("PUT")
+	projects.HandleFunc("/{id}/repreciar", s.listaPreciosHandler.RepreciarProyecto).Methods("POST")
+	projects.HandleFunc("/{id}/flete", s.fleteHandler.ObtenerFleteProyecto).Methods("GET")
+	projects.HandleFunc("/{id}/flete", s.fleteHandler.AsignarFleteProyecto).Methods("PUT")
+
 	// Metrado routes (protected)
 	projects.HandleFunc("/{proyecto_id}/metrados", s.metradoHandler.ObtenerMetradosPorProyecto).Methods("GET")
 	projects.HandleFunc("/{proyecto_id}/metrados", s.metradoHandler.CrearMetrado).Methods("POST")
 	projects.HandleFunc("/{proyecto_id}/metrados/batch", s.metradoHandler.ActualizarMetradosLote).Methods("PUT")
 	projects.HandleFunc("/{proyecto_id}/metrados/simple", s.metradoHandler.ObtenerMetradoSimple).Methods("GET")
+	projects.HandleFunc("/{proyecto_id}/metrados/planilla", s.metradoHandler.DescargarPlanilla).Methods("GET")
+	projects.HandleFunc("/{proyecto_id}/metrados/planilla", s.metradoHandler.CargarPlanilla).Methods("POST")
 	projects.HandleFunc("/{proyecto_id}/metrados/{partida_codigo}", s.metradoHandler.ObtenerMetradoPorPartida).Methods("GET")
 	projects.HandleFunc("/{proyecto_id}/metrados/{partida_codigo}", s.metradoHandler.EliminarMetrado).Methods("DELETE")
 	projects.HandleFunc("/{proyecto_id}/resumen", s.metradoHandler.ObtenerResumenProyecto).Methods("GET")
 	projects.HandleFunc("/{proyecto_id}/costo-total", s.metradoHandler.CalcularCostoTotalProyecto).Methods("GET")
 
+	// Relación de insumos (protected)
+	projects.HandleFunc("/{proyecto_id}/insumos", s.insumosHandler.ObtenerInsumosProyecto).Methods("GET")
+	projects.HandleFunc("/{proyecto_id}/formula-polinomica", s.insumosHandler.ObtenerFormulaProyecto).Methods("GET")
+
+	// Índices unificados de recursos (admin): el catálogo de recursos es compartido por todas las organizaciones
+	recursos := api.PathPrefix("/recursos").Subrouter()
+	recursos.Use(s.middlewareAdapter(s.authMiddleware.RequireRole("admin")))
+	recursos.HandleFunc("/indices-unificados", s.insumosHandler.ActualizarIndicesUnificados).Methods("PUT")
+
+	// Exportación por lotes
+	exports := api.PathPrefix("/exports").Subrouter()
+	exports.Use(s.middlewareAdapter(s.authMiddleware.RequireAuth))
+	exports.HandleFunc("/batch", s.proyectoHandler.ExportBatch).Methods("POST")
+
+	// Plantillas Excel por organización (protected)
+	organizations := api.PathPrefix("/organizations").Subrouter()
+	organizations.Use(s.middlewareAdapter(s.authMiddleware.RequireAuth))
+	organizations.HandleFunc("/{organizacion_id}/plantilla-excel", s.plantillaHandler.ObtenerPlantilla).Methods("GET")
+	organizations.HandleFunc("/{organizacion_id}/plantilla-excel", s.plantillaHandler.GuardarPlantilla).Methods("PUT")
+	organizations.HandleFunc("/{organizacion_id}/plantilla-excel", s.plantillaHandler.EliminarPlantilla).Methods("DELETE")
+	organizations.HandleFunc("/{organizacion_id}/plantilla-excel/referencia", s.plantillaHandler.ImportarPlantilla).Methods("POST")
+
+	// Parámetros de cálculo por organización (protected); los heredan sus proyectos
+	organizations.HandleFunc("/{organizacion_id}/parametros", s.parametrosHandler.ObtenerParametrosOrganizacion).Methods("GET")
+	organizations.HandleFunc("/{organizacion_id}/parametros", s.parametrosHandler.GuardarParametrosOrganizacion).Methods("PUT")
+
+	// Tipos de cambio de la organización (protected); prevalecen sobre los globales
+	organizations.HandleFunc("/{organizacion_id}/tipos-cambio", s.tipoCambioHandler.ListarTiposCambio).Methods("GET")
+	organizations.HandleFunc("/{organizacion_id}/tipos-cambio", s.tipoCambioHandler.CrearTipoCambio).Methods("POST")
+	organizations.HandleFunc("/{organizacion_id}/tipos-cambio/importar", s.tipoCambioHandler.ImportarTiposCambio).Methods("POST")
+	organizations.HandleFunc("/{organizacion_id}/tipos-cambio/{id}", s.tipoCambioHandler.EliminarTipoCambio).Methods("DELETE")
+
+	// Listas de precios por región y vigencia (protected); se asignan a proyectos para repreciarlos
+	organizations.HandleFunc("/{organizacion_id}/listas-precios", s.listaPreciosHandler.ListarListasPrecios).Methods("GET")
+	organizations.HandleFunc("/{organizacion_id}/listas-precios", s.listaPreciosHandler.CrearListaPrecios).Methods("POST")
+	organizations.HandleFunc("/{organizacion_id}/listas-precios/{lista_id}", s.listaPreciosHandler.ObtenerListaPrecios).Methods("GET")
+	organizations.HandleFunc("/{organizacion_id}/listas-precios/{lista_id}", s.listaPreciosHandler.ActualizarListaPrecios).Methods("PUT")
+	organizations.HandleFunc("/{organizacion_id}/listas-precios/{lista_id}", s.listaPreciosHandler.EliminarListaPrecios).Methods("DELETE")
+	organizations.HandleFunc("/{organizacion_id}/listas-precios/{lista_id}/precios", s.listaPreciosHandler.GuardarPreciosLista).Methods("PUT")
+	organizations.HandleFunc("/{organizacion_id}/listas-precios/{lista_id}/precios/importar", s.listaPreciosHandler.ImportarPreciosLista).Methods("POST")
+
+	// Costo de mano de obra de construcción civil por año (protected)
+	organizations.HandleFunc("/{organizacion_id}/mano-obra", s.manoObraHandler.ListarParametrosManoObra).Methods("GET")
+	organizations.HandleFunc("/{organizacion_id}/mano-obra", s.manoObraHandler.CrearParametrosManoObra).Methods("POST")
+	organizations.HandleFunc("/{organizacion_id}/mano-obra/{id}", s.manoObraHandler.ObtenerParametrosManoObra).Methods("GET")
+	organizations.HandleFunc("/{organizacion_id}/mano-obra/{id}", s.manoObraHandler.ActualizarParametrosManoObra).Methods("PUT")
+	organizations.HandleFunc("/{organizacion_id}/mano-obra/{id}", s.manoObraHandler.EliminarParametrosManoObra).Methods("DELETE")
+	organizations.HandleFunc("/{organizacion_id}/mano-obra/{id}/aplicar", s.manoObraHandler.AplicarCostoManoObra).Methods("POST")
+
+	// Análisis de costo horario de equipos (protected)
+	organizations.HandleFunc("/{organizacion_id}/equipos", s.equipoHandler.ListarAnalisisEquipos).Methods("GET")
+	organizations.HandleFunc("/{organizacion_id}/equipos", s.equipoHandler.CrearAnalisisEquipo).Methods("POST")
+	organizations.HandleFunc("/{organizacion_id}/equipos/aplicar", s.equipoHandler.AplicarCostosEquipos).Methods("POST")
+	organizations.HandleFunc("/{organizacion_id}/equipos/{id}", s.equipoHandler.ObtenerAnalisisEquipo).Methods("GET")
+	organizations.HandleFunc("/{organizacion_id}/equipos/{id}", s.equipoHandler.ActualizarAnalisisEquipo).Methods("PUT")
+	organizations.HandleFunc("/{organizacion_id}/equipos/{id}", s.equipoHandler.EliminarAnalisisEquipo).Methods("DELETE")
+
+	// Rutas de flete y pesos unitarios de materiales (protected)
+	organizations.HandleFunc("/{organizacion_id}/rutas-flete", s.fleteHandler.ListarRutasFlete).Methods("GET")
+	organizations.HandleFunc("/{organizacion_id}/rutas-flete", s.fleteHandler.CrearRutaFlete).Methods("POST")
+	organizations.HandleFunc("/{organizacion_id}/rutas-flete/{id}", s.fleteHandler.ObtenerRutaFlete).Methods("GET")
+	organizations.HandleFunc("/{organizacion_id}/rutas-flete/{id}", s.fleteHandler.ActualizarRutaFlete).Methods("PUT")
+	organizations.HandleFunc("/{organizacion_id}/rutas-flete/{id}", s.fleteHandler.EliminarRutaFlete).Methods("DELETE")
+	organizations.HandleFunc("/{organizacion_id}/pesos-unitarios", s.fleteHandler.ListarPesosUnitarios).Methods("GET")
+	organizations.HandleFunc("/{organizacion_id}/pesos-unitarios", s.fleteHandler.GuardarPesosUnitarios).Methods("PUT")
+	organizations.HandleFunc("/{organizacion_id}/pesos-unitarios/{codigo}", s.fleteHandler.EliminarPesoUnitario).Methods("DELETE")
+
 	// Admin routes (require admin role)
 	admin := api.PathPrefix("/admin").Subrouter()
 	admin.Use(s.middlewareAdapter(s.authMiddleware.RequireRole("admin")))
@@ -134,6 +255,30 @@ func (s *Server) setupRoutes() {
 	admin.HandleFunc("/projects/{id}/visibility", s.adminHandler.UpdateProyectoVisibility).Methods("PUT")
 	admin.HandleFunc("/projects/featured", s.adminHandler.GetFeaturedProyectos).Methods("GET")
 	admin.HandleFunc("/organizations", s.adminHandler.GetAllOrganizaciones).Methods("GET")
+	admin.HandleFunc("/tipos-cambio", s.tipoCambioHandler.ListarTiposCambio).Methods("GET")
+	admin.HandleFunc("/tipos-cambio", s.tipoCambioHandler.CrearTipoCambio).Methods("POST")
+	admin.HandleFunc("/tipos-cambio/importar", s.tipoCambioHandler.ImportarTiposCambio).Methods("POST")
+	admin.HandleFunc("/tipos-cambio/{id}", s.tipoCambioHandler.EliminarTipoCambio).Methods("DELETE")
+	admin.HandleFunc("/mano-obra", s.manoObraHandler.ListarParametrosManoObra).Methods("GET")
+	admin.HandleFunc("/mano-obra", s.manoObraHandler.CrearParametrosManoObra).Methods("POST")
+	admin.HandleFunc("/mano-obra/{id}", s.manoObraHandler.ObtenerParametrosManoObra).Methods("GET")
+	admin.HandleFunc("/mano-obra/{id}", s.manoObraHandler.ActualizarParametrosManoObra).Methods("PUT")
+	admin.HandleFunc("/mano-obra/{id}", s.manoObraHandler.EliminarParametrosManoObra).Methods("DELETE")
+	admin.HandleFunc("/mano-obra/{id}/aplicar", s.manoObraHandler.AplicarCostoManoObra).Methods("POST")
+	admin.HandleFunc("/equipos", s.equipoHandler.ListarAnalisisEquipos).Methods("GET")
+	admin.HandleFunc("/equipos", s.equipoHandler.CrearAnalisisEquipo).Methods("POST")
+	admin.HandleFunc("/equipos/aplicar", s.equipoHandler.AplicarCostosEquipos).Methods("POST")
+	admin.HandleFunc("/equipos/{id}", s.equipoHandler.ObtenerAnalisisEquipo).Methods("GET")
+	admin.HandleFunc("/equipos/{id}", s.equipoHandler.ActualizarAnalisisEquipo).Methods("PUT")
+	admin.HandleFunc("/equipos/{id}", s.equipoHandler.EliminarAnalisisEquipo).Methods("DELETE")
+	admin.HandleFunc("/rutas-flete", s.fleteHandler.ListarRutasFlete).Methods("GET")
+	admin.HandleFunc("/rutas-flete", s.fleteHandler.CrearRutaFlete).Methods("POST")
+	admin.HandleFunc("/rutas-flete/{id}", s.fleteHandler.ObtenerRutaFlete).Methods("GET")
+	admin.HandleFunc("/rutas-flete/{id}", s.fleteHandler.ActualizarRutaFlete).Methods("PUT")
+	admin.HandleFunc("/rutas-flete/{id}", s.fleteHandler.EliminarRutaFlete).Methods("DELETE")
+	admin.HandleFunc("/pesos-unitarios", s.fleteHandler.ListarPesosUnitarios).Methods("GET")
+	admin.HandleFunc("/pesos-unitarios", s.fleteHandler.GuardarPesosUnitarios).Methods("PUT")
+	admin.HandleFunc("/pesos-unitarios/{codigo}", s.fleteHandler.EliminarPesoUnitario).Methods("DELETE")
 
 	// ACU validation (public)
 	api.HandleFunc("/validate-acu", s.proyectoHandler.ValidateACU).Methods("POST")
@@ -141,6 +286,14 @@ func (s *Server) setupRoutes() {
 	// Hierarchical Budget routes (Presupuestos Jerárquicos) - public for testing
 	apiHandlers.SetupPresupuestoJerarquicoRoutes(s.router, s.presupuestoJerarquicoHandler)
 
+	// Costos de presupuestos jerárquicos (protected): muestran precios y cambian los totales
+	presupuestos := api.PathPrefix("/presupuestos/{presupuesto_id}").Subrouter()
+	presupuestos.Use(s.middlewareAdapter(s.authMiddleware.RequireAuth))
+	presupuestos.HandleFunc("/insumos", s.insumosHandler.ObtenerInsumosPresupuesto).Methods("GET")
+	presupuestos.HandleFunc("/formula-polinomica", s.insumosHandler.ObtenerFormulaPresupuesto).Methods("GET")
+	presupuestos.HandleFunc("/parametros", s.parametrosHandler.ObtenerParametrosPresupuesto).Methods("GET")
+	presupuestos.HandleFunc("/parametros", s.parametrosHandler.GuardarParametrosPresupuesto).Methods("PUT")
+
 	// Static files and React app (for production)
 	s.router.PathPrefix("/").Handler(http.FileServer(http.Dir("./web/build/")))
 }
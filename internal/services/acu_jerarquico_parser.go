@@ -6,15 +6,19 @@ import (
 	"strconv"
 	"strings"
 
+	"goexcel/internal/costing"
 	"goexcel/internal/models"
 )
 
 // ACUJerarquicoParser parsea el nuevo formato ACU jerárquico
-type ACUJerarquicoParser struct{}
+type ACUJerarquicoParser struct {
+	parametros models.Parametros // heredados; el bloque @presupuesto puede declarar otros
+}
 
-// NewACUJerarquicoParser crea una nueva instancia del parser jerárquico
-func NewACUJerarquicoParser() *ACUJerarquicoParser {
-	return &ACUJerarquicoParser{}
+// NewACUJerarquicoParser crea una nueva instancia del parser jerárquico con los parámetros de cálculo
+// heredados de la organización
+func NewACUJerarquicoParser(parametros models.Parametros) *ACUJerarquicoParser {
+	return &ACUJerarquicoParser{parametros: parametros}
 }
 
 // ParseACUJerarquico parsea contenido ACU en formato jerárquico
@@ -83,14 +87,33 @@ func (p *ACUJerarquicoParser) ParseACUJerarquico(content string) (*models.ACUJer
 		}
 	}
 
+	p.aplicarCuadrillas(result)
 	return result, nil
 }
 
+// aplicarCuadrillas deduce la cantidad de los recursos con cuadrilla si los parámetros efectivos del
+// presupuesto lo indican
+func (p *ACUJerarquicoParser) aplicarCuadrillas(result *models.ACUJerarquico) {
+	parametros := p.parametros.Aplicar(result.Presupuesto.Parametros)
+	if !parametros.CuadrillaDefineCantidad {
+		return
+	}
+	for i := range result.Partidas {
+		partida := &result.Partidas[i]
+		for _, recursos := range [][]models.RecursoData{partida.ManoObra, partida.Equipos} {
+			for j := range recursos {
+				recursos[j].Cantidad = cantidadSegunCuadrilla(parametros, recursos[j].Cantidad, recursos[j].Cuadrilla, partida.Rendimiento)
+			}
+		}
+	}
+}
+
 // parsePresupuesto parsea un bloque @presupuesto{}
 func (p *ACUJerarquicoParser) parsePresupuesto(lines []string, index *int) (*models.PresupuestoData, error) {
 	presupuesto := &models.PresupuestoData{
-		Moneda: "PEN", // Default
+		Moneda: p.parametros.Moneda, // Default
 	}
+	declarados := &models.ParametrosCalculo{}
 
 	// Extraer código del presupuesto de la declaración
 	line := lines[*index]
@@ -125,11 +148,18 @@ func (p *ACUJerarquicoParser) parsePresupuesto(lines []string, index *int) (*mod
 					presupuesto.Lugar = &value
 				case "moneda":
 					presupuesto.Moneda = value
+				default:
+					if _, err := parametroACU(declarados, key, value); err != nil {
+						return nil, err
+					}
 				}
 			}
 		}
 	}
 
+	if *declarados != (models.ParametrosCalculo{}) {
+		presupuesto.Parametros = declarados
+	}
 	return presupuesto, nil
 }
 
@@ -349,19 +379,23 @@ func (p *ACUJerarquicoParser) parseRecurso(lines []string, index *int, seccion s
 			case "unidad":
 				recurso.Unidad = value
 			case "cantidad":
-				if cantidad, err := strconv.ParseFloat(value, 64); err == nil {
+				if cantidad, err := costing.ParsearDecimal(value); err == nil {
 					recurso.Cantidad = cantidad
 				}
 			case "precio":
-				if precio, err := strconv.ParseFloat(value, 64); err == nil {
+				if precio, err := costing.ParsearDecimal(value); err == nil {
 					recurso.Precio = precio
 				}
 			case "cuadrilla":
-				if seccion == "mano_obra" {
+				if seccion == "mano_obra" || seccion == "equipos" {
 					if cuadrilla, err := strconv.ParseFloat(value, 64); err == nil {
 						recurso.Cuadrilla = &cuadrilla
 					}
 				}
+			case "moneda":
+				recurso.Moneda = strings.ToUpper(value)
+			case "desperdicio":
+				recurso.Desperdicio = parsearDesperdicio(value)
 			}
 		}
 	}
@@ -456,6 +490,7 @@ func (p *ACUJerarquicoParser) ConvertToACUJerarquico(data *models.ACUJerarquico)
 	if data.Presupuesto.Lugar != nil {
 		acuContent.WriteString(fmt.Sprintf("  lugar = \"%s\",\n", *data.Presupuesto.Lugar))
 	}
+	EscribirParametrosACU(&acuContent, data.Presupuesto.Parametros)
 	acuContent.WriteString(fmt.Sprintf("  moneda = \"%s\"\n", data.Presupuesto.Moneda))
 	acuContent.WriteString("}\n\n")
 
@@ -489,6 +524,8 @@ func (p *ACUJerarquicoParser) ConvertToACUJerarquico(data *models.ACUJerarquico)
 				if recurso.Cuadrilla != nil {
 					acuContent.WriteString(fmt.Sprintf(", cuadrilla = %.4f", *recurso.Cuadrilla))
 				}
+				escribirMonedaACU(&acuContent, recurso.Moneda)
+				escribirDesperdicioACU(&acuContent, recurso.Desperdicio)
 				acuContent.WriteString("},\n")
 			}
 			acuContent.WriteString("  },\n")
@@ -497,8 +534,11 @@ func (p *ACUJerarquicoParser) ConvertToACUJerarquico(data *models.ACUJerarquico)
 		if len(partida.Materiales) > 0 {
 			acuContent.WriteString("  \n  materiales = {\n")
 			for _, recurso := range partida.Materiales {
-				acuContent.WriteString(fmt.Sprintf("    {codigo = \"%s\", desc = \"%s\", unidad = \"%s\", cantidad = %.4f, precio = %.2f},\n",
+				acuContent.WriteString(fmt.Sprintf("    {codigo = \"%s\", desc = \"%s\", unidad = \"%s\", cantidad = %.4f, precio = %.2f",
 					recurso.Codigo, recurso.Descripcion, recurso.Unidad, recurso.Cantidad, recurso.Precio))
+				escribirMonedaACU(&acuContent, recurso.Moneda)
+				escribirDesperdicioACU(&acuContent, recurso.Desperdicio)
+				acuContent.WriteString("},\n")
 			}
 			acuContent.WriteString("  },\n")
 		}
@@ -506,8 +546,11 @@ func (p *ACUJerarquicoParser) ConvertToACUJerarquico(data *models.ACUJerarquico)
 		if len(partida.Equipos) > 0 {
 			acuContent.WriteString("  \n  equipos = {\n")
 			for _, recurso := range partida.Equipos {
-				acuContent.WriteString(fmt.Sprintf("    {codigo = \"%s\", desc = \"%s\", unidad = \"%s\", cantidad = %.4f, precio = %.2f},\n",
+				acuContent.WriteString(fmt.Sprintf("    {codigo = \"%s\", desc = \"%s\", unidad = \"%s\", cantidad = %.4f, precio = %.2f",
 					recurso.Codigo, recurso.Descripcion, recurso.Unidad, recurso.Cantidad, recurso.Precio))
+				escribirMonedaACU(&acuContent, recurso.Moneda)
+				escribirDesperdicioACU(&acuContent, recurso.Desperdicio)
+				acuContent.WriteString("},\n")
 			}
 			acuContent.WriteString("  },\n")
 		}
@@ -515,8 +558,11 @@ func (p *ACUJerarquicoParser) ConvertToACUJerarquico(data *models.ACUJerarquico)
 		if len(partida.Subcontratos) > 0 {
 			acuContent.WriteString("  \n  subcontratos = {\n")
 			for _, recurso := range partida.Subcontratos {
-				acuContent.WriteString(fmt.Sprintf("    {codigo = \"%s\", desc = \"%s\", unidad = \"%s\", cantidad = %.4f, precio = %.2f},\n",
+				acuContent.WriteString(fmt.Sprintf("    {codigo = \"%s\", desc = \"%s\", unidad = \"%s\", cantidad = %.4f, precio = %.2f",
 					recurso.Codigo, recurso.Descripcion, recurso.Unidad, recurso.Cantidad, recurso.Precio))
+				escribirMonedaACU(&acuContent, recurso.Moneda)
+				escribirDesperdicioACU(&acuContent, recurso.Desperdicio)
+				acuContent.WriteString("},\n")
 			}
 			acuContent.WriteString("  },\n")
 		}
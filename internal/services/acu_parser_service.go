@@ -9,14 +9,17 @@ import (
 	"strings"
 
 	"github.com/google/uuid"
+	"goexcel/internal/costing"
 	"goexcel/internal/legacy"
 	"goexcel/internal/models"
 )
 
-type ACUParserService struct{}
+type ACUParserService struct {
+	parametros models.Parametros // heredados; el bloque @proyecto puede declarar otros
+}
 
-func NewACUParserService() *ACUParserService {
-	return &ACUParserService{}
+func NewACUParserService(parametros models.Parametros) *ACUParserService {
+	return &ACUParserService{parametros: parametros}
 }
 
 // ParseFile parsea un archivo .acu y devuelve el proyecto
@@ -40,15 +43,16 @@ func (s *ACUParserService) ParseString(content string) (*models.ACUProject, erro
 	if err := s.parseProject(content, project); err != nil {
 		return nil, fmt.Errorf("error parseando proyecto: %w", err)
 	}
-	
+
 	// Parsear partidas
 	partidas, err := s.parsePartidas(content)
 	if err != nil {
 		return nil, fmt.Errorf("error parseando partidas: %w", err)
 	}
-	
+
 	project.Partidas = partidas
-	
+	s.aplicarCuadrillas(project)
+
 	return project, nil
 }
 
@@ -72,23 +76,50 @@ func (s *ACUParserService) parseProject(content string, project *models.ACUProje
 		if moneda, ok := fields["moneda"]; ok {
 			project.Moneda = s.cleanQuotes(moneda)
 		}
+
+		declarados := &models.ParametrosCalculo{}
+		for clave, valor := range fields {
+			if _, err := parametroACU(declarados, clave, s.cleanQuotes(valor)); err != nil {
+				return err
+			}
+		}
+		if *declarados != (models.ParametrosCalculo{}) {
+			project.Parametros = declarados
+		}
 	}
-	
+
 	// Valores por defecto
 	if project.Nombre == "" {
 		project.Nombre = "Proyecto ACU"
 	}
 	if project.Moneda == "" {
-		project.Moneda = "PEN"
+		project.Moneda = s.parametros.Moneda
 	}
-	
+
 	return nil
 }
 
+// aplicarCuadrillas deduce la cantidad de los recursos con cuadrilla si los parámetros efectivos del
+// proyecto lo indican
+func (s *ACUParserService) aplicarCuadrillas(project *models.ACUProject) {
+	parametros := s.parametros.Aplicar(project.Parametros)
+	if !parametros.CuadrillaDefineCantidad {
+		return
+	}
+	for i := range project.Partidas {
+		partida := &project.Partidas[i]
+		for _, recursos := range [][]models.ACURecurso{partida.ManoObra, partida.Equipos} {
+			for j := range recursos {
+				recursos[j].Cantidad = cantidadSegunCuadrilla(parametros, recursos[j].Cantidad, recursos[j].Cuadrilla, partida.Rendimiento)
+			}
+		}
+	}
+}
+
 // parsePartidas extrae todas las partidas del contenido
 func (s *ACUParserService) parsePartidas(content string) ([]models.ACUPartida, error) {
 	var partidas []models.ACUPartida
-	
+
 	// Regex mejorada para capturar bloques @partida completos
 	partidaRegex := regexp.MustCompile(`@partida\s*\{\s*([^,]+),\s*((?:[^{}]*\{[^{}]*\}[^{}]*)*[^}]*)\}`)
 	matches := partidaRegex.FindAllStringSubmatch(content, -1)
@@ -200,12 +231,12 @@ func (s *ACUParserService) parseRecurso(content string) models.ACURecurso {
 		recurso.Unidad = s.cleanQuotes(unidad)
 	}
 	if cantStr, ok := fields["cantidad"]; ok {
-		if cant, err := strconv.ParseFloat(cantStr, 64); err == nil {
+		if cant, err := costing.ParsearDecimal(cantStr); err == nil {
 			recurso.Cantidad = cant
 		}
 	}
 	if precioStr, ok := fields["precio"]; ok {
-		if precio, err := strconv.ParseFloat(precioStr, 64); err == nil {
+		if precio, err := costing.ParsearDecimal(precioStr); err == nil {
 			recurso.Precio = precio
 		}
 	}
@@ -214,14 +245,20 @@ func (s *ACUParserService) parseRecurso(content string) models.ACURecurso {
 			recurso.Cuadrilla = &cuadrilla
 		}
 	}
-	
+	if moneda, ok := fields["moneda"]; ok {
+		recurso.Moneda = strings.ToUpper(s.cleanQuotes(moneda))
+	}
+	if desperdicio, ok := fields["desperdicio"]; ok {
+		recurso.Desperdicio = parsearDesperdicio(desperdicio)
+	}
+
 	return recurso
 }
 
 // parseFields extrae campos clave=valor del contenido
 func (s *ACUParserService) parseFields(content string) map[string]string {
 	fields := make(map[string]string)
-	
+
 	// Regex mejorada para extraer pares clave=valor, excluyendo bloques
 	fieldRegex := regexp.MustCompile(`(\w+)\s*=\s*("([^"]*)"|([^,}{\s]+))`)
 	matches := fieldRegex.FindAllStringSubmatch(content, -1)
@@ -286,7 +323,7 @@ func (s *ACUParserService) ConvertToJSON(project *models.ACUProject) ([]legacy.P
 // convertRecursos convierte recursos ACU a legacy
 func (s *ACUParserService) convertRecursos(recursosACU []models.ACURecurso) []legacy.RecursoLegacy {
 	var recursos []legacy.RecursoLegacy
-	
+
 	for _, recursoACU := range recursosACU {
 		recurso := legacy.RecursoLegacy{
 			Codigo:      recursoACU.Codigo,
@@ -294,12 +331,14 @@ func (s *ACUParserService) convertRecursos(recursosACU []models.ACURecurso) []le
 			Unidad:      recursoACU.Unidad,
 			Cantidad:    recursoACU.Cantidad,
 			Precio:      recursoACU.Precio,
+			Moneda:      recursoACU.Moneda,
+			Desperdicio: recursoACU.Desperdicio,
 		}
-		
+
 		if recursoACU.Cuadrilla != nil {
 			recurso.Cuadrilla = *recursoACU.Cuadrilla
 		}
-		
+
 		recursos = append(recursos, recurso)
 	}
 	
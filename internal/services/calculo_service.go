@@ -0,0 +1,252 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"goexcel/internal/costing"
+	"goexcel/internal/legacy"
+	"goexcel/internal/models"
+)
+
+// ErrSinMetrados indica que el presupuesto tiene partidas pero ninguna con metrado, así que no hay nada
+// que consolidar. Los presupuestos jerárquicos no guardan metrados: metrados_partidas es por proyecto.
+var ErrSinMetrados = errors.New("el presupuesto no tiene metrados: la relación de insumos y la fórmula polinómica se calculan sobre las partidas con metrado de un proyecto")
+
+// CalculoService calcula el presupuesto guardado en la BD con ConstruirReporte, el mismo cálculo de
+// las exportaciones, para que los totales de la API cuadren al céntimo con los reportes
+type CalculoService struct {
+	db            *sql.DB
+	parametrosSvc *ParametrosService
+	tipoCambioSvc *TipoCambioService
+	fleteSvc      *FleteService
+}
+
+func NewCalculoService(db *sql.DB, parametrosSvc *ParametrosService, tipoCambioSvc *TipoCambioService, fleteSvc *FleteService) *CalculoService {
+	return &CalculoService{
+		db:            db,
+		parametrosSvc: parametrosSvc,
+		tipoCambioSvc: tipoCambioSvc,
+		fleteSvc:      fleteSvc,
+	}
+}
+
+// ReporteDeProyecto calcula el presupuesto del proyecto con sus parámetros, sus tipos de cambio, su
+// flete y sus metrados
+func (s *CalculoService) ReporteDeProyecto(proyectoID uuid.UUID) (*models.ReportePresupuesto, error) {
+	datos, err := s.DatosDeProyecto(proyectoID)
+	if err != nil {
+		return nil, err
+	}
+	return ConstruirReporte(datos), nil
+}
+
+// DatosDeProyecto carga lo necesario para calcular el presupuesto guardado del proyecto. Como al
+// exportar, si falta el tipo de cambio de alguna moneda no se calcula, para no mezclar monedas.
+func (s *CalculoService) DatosDeProyecto(proyectoID uuid.UUID) (DatosReporte, error) {
+	parametros, err := s.parametrosSvc.EfectivosDeProyecto(proyectoID)
+	if err != nil {
+		return DatosReporte{}, err
+	}
+
+	datos, err := s.datosGuardados("p.proyecto_id", proyectoID, parametros, true)
+	if err != nil {
+		return DatosReporte{}, err
+	}
+	if err := s.TasasDeProyecto(proyectoID, &datos); err != nil {
+		return DatosReporte{}, err
+	}
+	if datos.Flete, err = s.fleteSvc.ParaReporte(proyectoID); err != nil {
+		return DatosReporte{}, err
+	}
+
+	return datos, nil
+}
+
+// TasasDeProyecto carga en datos los tipos de cambio del proyecto para las monedas extranjeras de sus
+// partidas. Sirve también después de cambiar precios en memoria, que pueden traer monedas nuevas.
+func (s *CalculoService) TasasDeProyecto(proyectoID uuid.UUID, datos *DatosReporte) error {
+	parametros := datos.Opciones.ParametrosCalculo()
+	monedas := MonedasExtranjeras(datos.Partidas, parametros.Moneda)
+	if len(monedas) == 0 {
+		datos.TiposCambio = nil
+		return nil
+	}
+	tasas, err := s.tipoCambioSvc.Tasas(proyectoID, parametros.Moneda, parametros.FechaReferencia(), monedas)
+	if err != nil {
+		return err
+	}
+	datos.TiposCambio = tasas
+	return nil
+}
+
+// ReporteDePresupuesto calcula un presupuesto jerárquico con sus parámetros y sus tipos de cambio. Sus
+// partidas no tienen metrados, así que devuelve ErrSinMetrados en lugar de una relación vacía.
+func (s *CalculoService) ReporteDePresupuesto(presupuestoID uuid.UUID) (*models.ReportePresupuesto, error) {
+	respuesta, err := s.parametrosSvc.DePresupuesto(presupuestoID)
+	if err != nil {
+		return nil, err
+	}
+	parametros := *respuesta.Efectivos
+
+	datos, err := s.datosGuardados("p.presupuesto_id", presupuestoID, parametros, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := exigirMetrados(datos); err != nil {
+		return nil, err
+	}
+	if monedas := MonedasExtranjeras(datos.Partidas, parametros.Moneda); len(monedas) > 0 {
+		if datos.TiposCambio, err = s.tipoCambioSvc.TasasDePresupuesto(presupuestoID, parametros.Moneda, parametros.FechaReferencia(), monedas); err != nil {
+			return nil, err
+		}
+	}
+
+	return ConstruirReporte(datos), nil
+}
+
+// ResumenDeProyecto cuenta las partidas del proyecto y su costo directo, calculado como en el reporte
+func (s *CalculoService) ResumenDeProyecto(proyectoID uuid.UUID) (*models.ResumenProyecto, error) {
+	reporte, err := s.ReporteDeProyecto(proyectoID)
+	if err != nil {
+		return nil, err
+	}
+
+	resumen := &models.ResumenProyecto{
+		TotalPartidas: int64(len(reporte.Partidas)),
+		CostoDirecto:  reporte.Pie.CostoDirecto,
+	}
+	for _, partida := range reporte.Partidas {
+		if partida.Metrado.EsCero() {
+			resumen.PartidasSinMetrado++
+		} else {
+			resumen.PartidasConMetrado++
+		}
+	}
+	return resumen, nil
+}
+
+// MonedasExtranjeras devuelve las monedas de los precios de recursos distintas de la del presupuesto
+func MonedasExtranjeras(partidas []legacy.PartidaLegacy, moneda string) []string {
+	vistas := make(map[string]bool)
+	var monedas []string
+	for _, partida := range partidas {
+		for _, recursos := range [][]legacy.RecursoLegacy{partida.ManoObra, partida.Materiales, partida.Equipos, partida.Subcontratos} {
+			for _, recurso := range recursos {
+				if recurso.Moneda != "" && recurso.Moneda != moneda && !vistas[recurso.Moneda] {
+					vistas[recurso.Moneda] = true
+					monedas = append(monedas, recurso.Moneda)
+				}
+			}
+		}
+	}
+	return monedas
+}
+
+// exigirMetrados rechaza los datos con partidas y sin ningún metrado
+func exigirMetrados(datos DatosReporte) error {
+	if len(datos.Partidas) > 0 && len(datos.Metrados) == 0 {
+		return ErrSinMetrados
+	}
+	return nil
+}
+
+// datosGuardados lee las partidas con sus recursos y, con conMetrados, el metrado de cada una en
+// metrados_partidas (solo las partidas de proyectos lo tienen). Cantidades, precios y metrados se leen
+// como Decimal, sin pasar por float64.
+func (s *CalculoService) datosGuardados(columnaFiltro string, id uuid.UUID, parametros models.Parametros, conMetrados bool) (DatosReporte, error) {
+	metrado, joinMetrados := "NULL::DECIMAL", ""
+	if conMetrados {
+		metrado = "mp.metrado"
+		joinMetrados = "LEFT JOIN metrados_partidas mp ON mp.partida_codigo = p.codigo AND mp.proyecto_id = p.proyecto_id"
+	}
+	query := fmt.Sprintf(`
+		SELECT
+			p.id, p.codigo, p.descripcion, p.unidad, p.rendimiento, %s,
+			r.codigo, r.descripcion, r.unidad, tr.nombre,
+			pr.cantidad, pr.precio, pr.cuadrilla, pr.moneda, pr.desperdicio
+		FROM partidas p
+		%s
+		LEFT JOIN partida_recursos pr ON pr.partida_id = p.id
+		LEFT JOIN recursos r ON pr.recurso_id = r.id
+		LEFT JOIN tipos_recurso tr ON r.tipo_recurso_id = tr.id
+		WHERE %s = $1
+		ORDER BY p.codigo, p.id, r.codigo
+	`, metrado, joinMetrados, columnaFiltro)
+
+	rows, err := s.db.Query(query, id)
+	if err != nil {
+		return DatosReporte{}, fmt.Errorf("error consultando partidas: %v", err)
+	}
+	defer rows.Close()
+
+	datos := DatosReporte{
+		Metrados: make(map[string]costing.Decimal),
+		Opciones: models.OpcionesExportacion{Parametros: &parametros},
+	}
+	var partidaID uuid.UUID
+	for rows.Next() {
+		var (
+			fila                              legacy.PartidaLegacy
+			filaID                            uuid.UUID
+			metrado                           costing.Decimal
+			codigo, descripcion, unidad, tipo sql.NullString
+			cantidad, precio, desperdicio     costing.Decimal
+			cuadrilla                         sql.NullFloat64
+			moneda                            sql.NullString
+		)
+		err := rows.Scan(
+			&filaID, &fila.Codigo, &fila.Descripcion, &fila.Unidad, &fila.Rendimiento, &metrado,
+			&codigo, &descripcion, &unidad, &tipo,
+			&cantidad, &precio, &cuadrilla, &moneda, &desperdicio,
+		)
+		if err != nil {
+			return DatosReporte{}, fmt.Errorf("error escaneando partida: %v", err)
+		}
+
+		if len(datos.Partidas) == 0 || filaID != partidaID {
+			partidaID = filaID
+			datos.Partidas = append(datos.Partidas, fila)
+			if !metrado.EsCero() {
+				datos.Metrados[fila.Codigo] = metrado
+			}
+		}
+		if !codigo.Valid {
+			continue
+		}
+
+		recurso := legacy.RecursoLegacy{
+			Codigo:      codigo.String,
+			Descripcion: descripcion.String,
+			Unidad:      unidad.String,
+			Cantidad:    cantidad,
+			Precio:      precio,
+			Moneda:      moneda.String,
+		}
+		if cuadrilla.Valid && cuadrilla.Float64 > 0 {
+			recurso.Cuadrilla = cuadrilla.Float64
+		}
+		if desperdicio.Signo() > 0 {
+			recurso.Desperdicio = &desperdicio
+		}
+
+		partida := &datos.Partidas[len(datos.Partidas)-1]
+		switch tipo.String {
+		case "mano_obra":
+			partida.ManoObra = append(partida.ManoObra, recurso)
+		case "materiales":
+			partida.Materiales = append(partida.Materiales, recurso)
+		case "equipos":
+			partida.Equipos = append(partida.Equipos, recurso)
+		case "subcontratos":
+			partida.Subcontratos = append(partida.Subcontratos, recurso)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return DatosReporte{}, fmt.Errorf("error leyendo partidas: %v", err)
+	}
+
+	return datos, nil
+}
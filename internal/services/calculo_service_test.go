@@ -0,0 +1,29 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"goexcel/internal/costing"
+	"goexcel/internal/legacy"
+)
+
+func TestExigirMetrados(t *testing.T) {
+	partidas := []legacy.PartidaLegacy{{Codigo: "01.01"}, {Codigo: "01.02"}}
+	casos := []struct {
+		nombre   string
+		datos    DatosReporte
+		esperado error
+	}{
+		{"sin partidas", DatosReporte{}, nil},
+		{"presupuesto jerárquico sin metrados", DatosReporte{Partidas: partidas, Metrados: map[string]costing.Decimal{}}, ErrSinMetrados},
+		{"con un metrado", DatosReporte{Partidas: partidas, Metrados: map[string]costing.Decimal{"01.02": costing.DebeParsear("3.5")}}, nil},
+	}
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			if err := exigirMetrados(caso.datos); !errors.Is(err, caso.esperado) {
+				t.Errorf("exigirMetrados = %v, se esperaba %v", err, caso.esperado)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"goexcel/internal/legacy"
+	"goexcel/internal/models"
+)
+
+// UmbralComparativoPorDefecto es el % de desviación que se resalta si no se indica otro
+const UmbralComparativoPorDefecto = 10.0
+
+// ConstruirComparativo alinea por código las partidas de dos reportes ya calculados. Las filas siguen
+// el árbol del presupuesto base: cada título lleva el subtotal de ese título en ambos presupuestos y
+// cada partida común sus valores en ambos. Las partidas que solo están en uno se listan aparte, pero
+// sí suman en los subtotales de sus títulos.
+func ConstruirComparativo(base, comparado *models.ReportePresupuesto, umbral float64) *models.ComparativoPresupuesto {
+	comparativo := &models.ComparativoPresupuesto{
+		Base:           base.Proyecto,
+		Comparado:      comparado.Proyecto,
+		Umbral:         umbral,
+		TotalBase:      base.Pie.CostoDirecto,
+		TotalComparado: comparado.Pie.CostoDirecto,
+		Fecha:          time.Now(),
+		Opciones:       base.Opciones,
+	}
+
+	nodosComparado := make(map[string]*models.NodoReporte)
+	comparado.Recorrer(func(nodo *models.NodoReporte) {
+		nodosComparado[nodo.Codigo] = nodo
+	})
+
+	partidasBase := make(map[string]bool)
+	base.Recorrer(func(nodo *models.NodoReporte) {
+		otro := nodosComparado[nodo.Codigo]
+
+		if nodo.EsTitulo() {
+			fila := models.FilaComparativo{
+				Codigo:      nodo.Codigo,
+				Descripcion: nodo.Descripcion,
+				Nivel:       nodo.Nivel,
+				EsTitulo:    true,
+				Base:        models.ValoresComparativo{Parcial: nodo.Subtotal},
+			}
+			if otro != nil && otro.EsTitulo() {
+				fila.Comparado.Parcial = otro.Subtotal
+			}
+			comparativo.Filas = append(comparativo.Filas, fila)
+			return
+		}
+
+		partidasBase[nodo.Codigo] = true
+		if otro == nil || otro.EsTitulo() {
+			comparativo.SoloBase = append(comparativo.SoloBase, nodo.Partida)
+			return
+		}
+		comparativo.Filas = append(comparativo.Filas, models.FilaComparativo{
+			Codigo:      nodo.Codigo,
+			Descripcion: nodo.Descripcion,
+			Unidad:      nodo.Partida.Unidad,
+			Nivel:       nodo.Nivel,
+			Base:        valoresComparativo(nodo.Partida),
+			Comparado:   valoresComparativo(otro.Partida),
+		})
+	})
+
+	for _, partida := range comparado.Partidas {
+		if !partidasBase[partida.Codigo] {
+			comparativo.SoloComparado = append(comparativo.SoloComparado, partida)
+		}
+	}
+
+	return comparativo
+}
+
+func valoresComparativo(partida *models.PartidaReporte) models.ValoresComparativo {
+	return models.ValoresComparativo{
+		Metrado:       partida.Metrado,
+		CostoUnitario: partida.CostoUnitario,
+		Parcial:       partida.Parcial,
+	}
+}
+
+// GenerarExcelComparativo genera el libro con la hoja comparativa y la de partidas no comunes
+func GenerarExcelComparativo(comparativo *models.ComparativoPresupuesto) (Documento, error) {
+	f, err := legacy.ConstruirExcelComparativo(comparativo)
+	if err != nil {
+		return nil, fmt.Errorf("error generando comparativo: %v", err)
+	}
+	return documentoExcel{f: f}, nil
+}
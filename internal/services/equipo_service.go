@@ -0,0 +1,427 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"goexcel/internal/costing"
+	"goexcel/internal/database/repositories"
+	"goexcel/internal/models"
+)
+
+// Valores por defecto del análisis de equipos, los usuales en el método CAPECO
+var (
+	horasAnualesPorDefecto      = costing.NuevoDecimal(2000, 0)
+	porcentajeRescatePorDefecto = costing.NuevoDecimal(20, 0)
+)
+
+// Fuentes del precio de un insumo del equipo
+const (
+	FuentePrecioLista    = "lista"
+	FuentePrecioCatalogo = "catalogo"
+	FuentePrecioAnalisis = "analisis"
+)
+
+// EquipoService administra los análisis de costo horario de equipos y calcula el costo por
+// hora-máquina con los precios vigentes de combustible, lubricante y operador
+type EquipoService struct {
+	equipoRepo      *repositories.EquipoRepository
+	recursoRepo     *repositories.RecursoRepository
+	listaPreciosSvc *ListaPreciosService
+}
+
+func NewEquipoService(equipoRepo *repositories.EquipoRepository, recursoRepo *repositories.RecursoRepository, listaPreciosSvc *ListaPreciosService) *EquipoService {
+	return &EquipoService{
+		equipoRepo:      equipoRepo,
+		recursoRepo:     recursoRepo,
+		listaPreciosSvc: listaPreciosSvc,
+	}
+}
+
+// preciosInsumos son los precios con los que se valoran los insumos de los equipos: los de la lista
+// prevalecen sobre los del catálogo
+type preciosInsumos struct {
+	lista    map[string]costing.Decimal
+	catalogo map[string]costing.Decimal
+}
+
+// Listar devuelve los análisis de la organización y los globales; sin organización, solo los globales
+func (s *EquipoService) Listar(organizacionID *uuid.UUID) ([]models.AnalisisEquipo, error) {
+	return s.equipoRepo.Listar(organizacionID)
+}
+
+// Obtener devuelve un análisis de la organización o global
+func (s *EquipoService) Obtener(id uuid.UUID, organizacionID *uuid.UUID) (*models.AnalisisEquipo, error) {
+	a, err := s.equipoRepo.ObtenerPorID(id)
+	if err != nil {
+		return nil, err
+	}
+	if a == nil || (a.OrganizacionID != nil && (organizacionID == nil || *a.OrganizacionID != *organizacionID)) {
+		return nil, fmt.Errorf("análisis de equipo no encontrado")
+	}
+	return a, nil
+}
+
+// Crear valida y guarda el análisis de un equipo; falla si el equipo ya tiene uno
+func (s *EquipoService) Crear(req models.AnalisisEquipoRequest, organizacionID *uuid.UUID) (*models.AnalisisEquipo, error) {
+	a, err := nuevoAnalisisEquipo(req)
+	if err != nil {
+		return nil, err
+	}
+	a.OrganizacionID = organizacionID
+
+	existente, err := s.equipoRepo.ObtenerPorCodigo(organizacionID, a.CodigoRecurso)
+	if err != nil {
+		return nil, err
+	}
+	if existente != nil {
+		return nil, fmt.Errorf("el equipo %s ya tiene un análisis", a.CodigoRecurso)
+	}
+
+	if err := s.equipoRepo.Crear(a); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Actualizar reemplaza un análisis propio de la organización; los globales solo bajo /admin
+func (s *EquipoService) Actualizar(id uuid.UUID, organizacionID *uuid.UUID, req models.AnalisisEquipoRequest) (*models.AnalisisEquipo, error) {
+	actual, err := s.equipoRepo.ObtenerPorID(id)
+	if err != nil {
+		return nil, err
+	}
+	if actual == nil || !mismaOrganizacion(actual.OrganizacionID, organizacionID) {
+		return nil, fmt.Errorf("análisis de equipo no encontrado")
+	}
+
+	a, err := nuevoAnalisisEquipo(req)
+	if err != nil {
+		return nil, err
+	}
+	if a.CodigoRecurso != actual.CodigoRecurso {
+		existente, err := s.equipoRepo.ObtenerPorCodigo(organizacionID, a.CodigoRecurso)
+		if err != nil {
+			return nil, err
+		}
+		if existente != nil {
+			return nil, fmt.Errorf("el equipo %s ya tiene un análisis", a.CodigoRecurso)
+		}
+	}
+	a.ID = actual.ID
+	a.OrganizacionID = actual.OrganizacionID
+	a.CreatedAt = actual.CreatedAt
+
+	if err := s.equipoRepo.Actualizar(a); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Eliminar borra un análisis de la organización; sin organización, uno global
+func (s *EquipoService) Eliminar(id uuid.UUID, organizacionID *uuid.UUID) error {
+	return s.equipoRepo.Eliminar(id, organizacionID)
+}
+
+// CostoHorario calcula el costo por hora-máquina del análisis con los precios de la lista indicada
+// (de la organización) o, sin lista, con los del catálogo
+func (s *EquipoService) CostoHorario(a *models.AnalisisEquipo, organizacionID, listaID *uuid.UUID) (*models.CostoHorarioEquipo, error) {
+	precios, err := s.precios([]models.AnalisisEquipo{*a}, organizacionID, listaID)
+	if err != nil {
+		return nil, err
+	}
+	return s.Calcular(a, precios), nil
+}
+
+// Aplicar recalcula los equipos de la organización (los suyos y los globales que no reemplaza) y
+// guarda su costo horario en la lista indicada; sin organización, los globales en el catálogo
+func (s *EquipoService) Aplicar(organizacionID, listaID *uuid.UUID) ([]models.CostoHorarioEquipo, []models.PrecioListaRequest, error) {
+	analisis, err := s.equipoRepo.Efectivos(organizacionID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(analisis) == 0 {
+		return nil, nil, fmt.Errorf("no hay análisis de equipos")
+	}
+	return s.aplicar(analisis, organizacionID, listaID)
+}
+
+// RecalcularDependientes actualiza el costo horario de los equipos que consumen alguno de los recursos
+// cuyo precio cambió (combustible, lubricante u operador), en la lista indicada o, sin organización,
+// en el catálogo. Devuelve cuántos equipos se actualizaron.
+func (s *EquipoService) RecalcularDependientes(organizacionID, listaID *uuid.UUID, codigos []string) (int, error) {
+	cambiados := make(map[string]bool, len(codigos))
+	for _, codigo := range codigos {
+		cambiados[codigo] = true
+	}
+
+	analisis, err := s.equipoRepo.Efectivos(organizacionID)
+	if err != nil {
+		return 0, err
+	}
+	var dependientes []models.AnalisisEquipo
+	for _, a := range analisis {
+		for _, insumo := range []models.InsumoEquipo{a.Combustible, a.Lubricante, a.Operador} {
+			if insumo.Codigo != "" && cambiados[insumo.Codigo] {
+				dependientes = append(dependientes, a)
+				break
+			}
+		}
+	}
+	if len(dependientes) == 0 {
+		return 0, nil
+	}
+
+	_, precios, err := s.aplicar(dependientes, organizacionID, listaID)
+	if err != nil {
+		return 0, err
+	}
+	return len(precios), nil
+}
+
+func (s *EquipoService) aplicar(analisis []models.AnalisisEquipo, organizacionID, listaID *uuid.UUID) ([]models.CostoHorarioEquipo, []models.PrecioListaRequest, error) {
+	precios, err := s.precios(analisis, organizacionID, listaID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	costos := make([]models.CostoHorarioEquipo, 0, len(analisis))
+	nuevos := make([]models.PrecioListaRequest, 0, len(analisis))
+	for i := range analisis {
+		costo := s.Calcular(&analisis[i], precios)
+		costos = append(costos, *costo)
+		nuevos = append(nuevos, models.PrecioListaRequest{Codigo: costo.CodigoRecurso, Precio: costo.CostoHorario})
+	}
+
+	if organizacionID == nil {
+		if _, err := s.recursoRepo.ActualizarPreciosBase(nuevos); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		if listaID == nil {
+			return nil, nil, fmt.Errorf("debe indicar la lista de precios")
+		}
+		if _, err := s.listaPreciosSvc.GuardarPrecios(*listaID, *organizacionID, nuevos); err != nil {
+			return nil, nil, err
+		}
+	}
+	return costos, nuevos, nil
+}
+
+// CostosParaProyecto calcula el costo horario de los equipos indicados que tienen análisis para la
+// organización del proyecto, con los precios de su lista asignada o del catálogo
+func (s *EquipoService) CostosParaProyecto(proyectoID uuid.UUID, codigos []string) ([]models.CostoHorarioEquipo, error) {
+	usados := make(map[string]bool, len(codigos))
+	for _, codigo := range codigos {
+		usados[codigo] = true
+	}
+
+	efectivos, err := s.equipoRepo.EfectivosParaProyecto(proyectoID)
+	if err != nil {
+		return nil, err
+	}
+	var analisis []models.AnalisisEquipo
+	for _, a := range efectivos {
+		if usados[a.CodigoRecurso] {
+			analisis = append(analisis, a)
+		}
+	}
+	if len(analisis) == 0 {
+		return nil, nil
+	}
+
+	precios := preciosInsumos{}
+	lista, err := s.listaPreciosSvc.ListaDeProyecto(proyectoID)
+	if err != nil {
+		return nil, err
+	}
+	if lista != nil {
+		if precios.lista, err = s.listaPreciosSvc.PreciosPorCodigo(lista.ID); err != nil {
+			return nil, err
+		}
+	}
+	if precios.catalogo, err = s.recursoRepo.PreciosBase(codigosInsumos(analisis)); err != nil {
+		return nil, err
+	}
+
+	costos := make([]models.CostoHorarioEquipo, 0, len(analisis))
+	for i := range analisis {
+		costos = append(costos, *s.Calcular(&analisis[i], precios))
+	}
+	return costos, nil
+}
+
+// precios obtiene los precios de los insumos de los análisis de la lista (si se indica) y del catálogo
+func (s *EquipoService) precios(analisis []models.AnalisisEquipo, organizacionID, listaID *uuid.UUID) (preciosInsumos, error) {
+	var precios preciosInsumos
+	var err error
+	if listaID != nil {
+		if organizacionID == nil {
+			return precios, fmt.Errorf("las listas de precios solo se usan bajo una organización")
+		}
+		if _, err := s.listaPreciosSvc.Obtener(*listaID, *organizacionID); err != nil {
+			return precios, err
+		}
+		if precios.lista, err = s.listaPreciosSvc.PreciosPorCodigo(*listaID); err != nil {
+			return precios, err
+		}
+	}
+	precios.catalogo, err = s.recursoRepo.PreciosBase(codigosInsumos(analisis))
+	return precios, err
+}
+
+// Calcular desglosa el costo por hora-máquina (método CAPECO), con Va el valor de adquisición, Vr el
+// de rescate, N la vida económica en años y Ha las horas trabajadas por año:
+//
+//	depreciación    = (Va - Vr) / (N × Ha)
+//	inversión media = (Va × (N + 1) + Vr × (N - 1)) / 2N
+//	intereses       = inversión media × tasa anual / Ha
+//	seguros         = inversión media × % seguros, impuestos y almacenaje / Ha
+//	mantenimiento   = depreciación × % mantenimiento
+//	neumáticos      = valor de los neumáticos / vida en horas
+//
+// El costo de posesión suma depreciación, intereses y seguros; el de operación, mantenimiento,
+// combustible, lubricante, neumáticos y operador. Cada monto se redondea a céntimos.
+func (s *EquipoService) Calcular(a *models.AnalisisEquipo, precios preciosInsumos) *models.CostoHorarioEquipo {
+	cien := costing.NuevoDecimal(100, 0)
+	dos := costing.NuevoDecimal(2, 0)
+	uno := costing.NuevoDecimal(1, 0)
+
+	costo := &models.CostoHorarioEquipo{
+		AnalisisID:         a.ID,
+		CodigoRecurso:      a.CodigoRecurso,
+		Descripcion:        a.Descripcion,
+		PotenciaHP:         a.PotenciaHP,
+		ValorAdquisicion:   a.ValorAdquisicion,
+		ValorRescate:       a.ValorAdquisicion.Multiplicar(a.PorcentajeRescate).Dividir(cien, redondeoCentimos),
+		VidaEconomicaAnios: a.VidaEconomicaAnios,
+		VidaEconomicaHoras: a.VidaEconomicaAnios.Multiplicar(a.HorasAnuales),
+	}
+
+	// Costo de posesión
+	costo.Depreciacion = a.ValorAdquisicion.Restar(costo.ValorRescate).Dividir(costo.VidaEconomicaHoras, redondeoCentimos)
+	costo.InversionMedia = a.ValorAdquisicion.Multiplicar(a.VidaEconomicaAnios.Sumar(uno)).
+		Sumar(costo.ValorRescate.Multiplicar(a.VidaEconomicaAnios.Restar(uno))).
+		Dividir(dos.Multiplicar(a.VidaEconomicaAnios), redondeoCentimos)
+	porHoraAnual := cien.Multiplicar(a.HorasAnuales)
+	costo.Intereses = costo.InversionMedia.Multiplicar(a.TasaInteresAnual).Dividir(porHoraAnual, redondeoCentimos)
+	costo.Seguros = costo.InversionMedia.Multiplicar(a.PorcentajeSegurosAnual).Dividir(porHoraAnual, redondeoCentimos)
+	costo.CostoPosesion = costing.Sumar(costo.Depreciacion, costo.Intereses, costo.Seguros)
+
+	// Costo de operación
+	costo.Mantenimiento = costo.Depreciacion.Multiplicar(a.PorcentajeMantenimiento).Dividir(cien, redondeoCentimos)
+	costo.Combustible = consumoEquipo(a.Combustible, precios)
+	costo.Lubricante = consumoEquipo(a.Lubricante, precios)
+	costo.Operador = consumoEquipo(a.Operador, precios)
+	if a.VidaNeumaticosHoras != nil && a.VidaNeumaticosHoras.Signo() > 0 {
+		costo.Neumaticos = a.ValorNeumaticos.Dividir(*a.VidaNeumaticosHoras, redondeoCentimos)
+	}
+	costo.CostoOperacion = costing.Sumar(
+		costo.Mantenimiento, costo.Combustible.Parcial, costo.Lubricante.Parcial, costo.Neumaticos, costo.Operador.Parcial,
+	)
+
+	costo.CostoHorario = costo.CostoPosesion.Sumar(costo.CostoOperacion)
+	return costo
+}
+
+// consumoEquipo valora un insumo con el precio de la lista, el del catálogo o, si el recurso no tiene,
+// el registrado en el análisis
+func consumoEquipo(insumo models.InsumoEquipo, precios preciosInsumos) models.ConsumoEquipo {
+	consumo := models.ConsumoEquipo{
+		Codigo:       insumo.Codigo,
+		Cantidad:     insumo.Cantidad,
+		Precio:       insumo.Precio,
+		FuentePrecio: FuentePrecioAnalisis,
+	}
+	if insumo.Codigo != "" {
+		if precio, existe := precios.lista[insumo.Codigo]; existe {
+			consumo.Precio, consumo.FuentePrecio = precio, FuentePrecioLista
+		} else if precio, existe := precios.catalogo[insumo.Codigo]; existe {
+			consumo.Precio, consumo.FuentePrecio = precio, FuentePrecioCatalogo
+		}
+	}
+	consumo.Parcial = costing.ParcialRecurso(consumo.Cantidad, consumo.Precio)
+	return consumo
+}
+
+// codigosInsumos devuelve los códigos de combustible, lubricante y operador de los análisis, sin repetir
+func codigosInsumos(analisis []models.AnalisisEquipo) []string {
+	vistos := make(map[string]bool)
+	var codigos []string
+	for _, a := range analisis {
+		for _, insumo := range []models.InsumoEquipo{a.Combustible, a.Lubricante, a.Operador} {
+			if insumo.Codigo != "" && !vistos[insumo.Codigo] {
+				vistos[insumo.Codigo] = true
+				codigos = append(codigos, insumo.Codigo)
+			}
+		}
+	}
+	return codigos
+}
+
+// nuevoAnalisisEquipo valida un análisis y completa los valores por defecto
+func nuevoAnalisisEquipo(req models.AnalisisEquipoRequest) (*models.AnalisisEquipo, error) {
+	codigo := strings.TrimSpace(req.CodigoRecurso)
+	descripcion := strings.TrimSpace(req.Descripcion)
+	if codigo == "" || descripcion == "" {
+		return nil, fmt.Errorf("codigo_recurso y descripcion son requeridos")
+	}
+	if req.ValorAdquisicion.Signo() <= 0 {
+		return nil, fmt.Errorf("valor_adquisicion debe ser mayor que cero")
+	}
+	if req.VidaEconomicaAnios.Signo() <= 0 {
+		return nil, fmt.Errorf("vida_economica_anios debe ser mayor que cero")
+	}
+
+	horasAnuales := req.HorasAnuales
+	if horasAnuales.EsCero() {
+		horasAnuales = horasAnualesPorDefecto
+	}
+	rescate := porcentajeRescatePorDefecto
+	if req.PorcentajeRescate != nil {
+		rescate = *req.PorcentajeRescate
+	}
+	if horasAnuales.Signo() < 0 {
+		return nil, fmt.Errorf("horas_anuales debe ser mayor que cero")
+	}
+	if rescate.Signo() < 0 || rescate.Cmp(costing.NuevoDecimal(100, 0)) > 0 {
+		return nil, fmt.Errorf("porcentaje_rescate debe estar entre 0 y 100")
+	}
+	if req.TasaInteresAnual.Signo() < 0 || req.PorcentajeSegurosAnual.Signo() < 0 ||
+		req.PorcentajeMantenimiento.Signo() < 0 || req.ValorNeumaticos.Signo() < 0 {
+		return nil, fmt.Errorf("los porcentajes y montos no pueden ser negativos")
+	}
+	if req.ValorNeumaticos.Signo() > 0 && (req.VidaNeumaticosHoras == nil || req.VidaNeumaticosHoras.Signo() <= 0) {
+		return nil, fmt.Errorf("vida_neumaticos_horas es requerida si hay valor_neumaticos")
+	}
+
+	insumos := map[string]*models.InsumoEquipo{
+		"combustible": &req.Combustible,
+		"lubricante":  &req.Lubricante,
+		"operador":    &req.Operador,
+	}
+	for nombre, insumo := range insumos {
+		insumo.Codigo = strings.TrimSpace(insumo.Codigo)
+		if insumo.Cantidad.Signo() < 0 || insumo.Precio.Signo() < 0 {
+			return nil, fmt.Errorf("%s: la cantidad y el precio no pueden ser negativos", nombre)
+		}
+	}
+
+	return &models.AnalisisEquipo{
+		CodigoRecurso:           codigo,
+		Descripcion:             descripcion,
+		PotenciaHP:              req.PotenciaHP,
+		ValorAdquisicion:        req.ValorAdquisicion,
+		VidaEconomicaAnios:      req.VidaEconomicaAnios,
+		HorasAnuales:            horasAnuales,
+		PorcentajeRescate:       rescate,
+		TasaInteresAnual:        req.TasaInteresAnual,
+		PorcentajeSegurosAnual:  req.PorcentajeSegurosAnual,
+		PorcentajeMantenimiento: req.PorcentajeMantenimiento,
+		ValorNeumaticos:         req.ValorNeumaticos,
+		VidaNeumaticosHoras:     req.VidaNeumaticosHoras,
+		Combustible:             req.Combustible,
+		Lubricante:              req.Lubricante,
+		Operador:                req.Operador,
+	}, nil
+}
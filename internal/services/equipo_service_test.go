@@ -0,0 +1,93 @@
+package services
+
+import (
+	"testing"
+
+	"goexcel/internal/costing"
+	"goexcel/internal/models"
+)
+
+func TestCalcularCostoHorarioEquipo(t *testing.T) {
+	d := costing.DebeParsear
+	vidaNeumaticos := d("2000")
+	casos := []struct {
+		nombre   string
+		analisis models.AnalisisEquipo
+		precios  preciosInsumos
+		esperado map[string]string
+		fuentes  [3]string // combustible, lubricante y operador
+	}{
+		{
+			// Ejemplo de la documentación: con Vr la inversión media es (500000 × 6 + 100000 × 4) / 10
+			nombre: "cargador con valor de rescate",
+			analisis: models.AnalisisEquipo{
+				ValorAdquisicion: d("500000"), VidaEconomicaAnios: d("5"), HorasAnuales: d("2000"),
+				PorcentajeRescate: d("20"), TasaInteresAnual: d("12"), PorcentajeSegurosAnual: d("3"),
+				PorcentajeMantenimiento: d("80"),
+				Combustible:             models.InsumoEquipo{Codigo: "340101", Cantidad: d("5"), Precio: d("14")},
+				Lubricante:              models.InsumoEquipo{Cantidad: d("0.1"), Precio: d("40")},
+				Operador:                models.InsumoEquipo{Codigo: "470101", Cantidad: d("1"), Precio: d("20")},
+			},
+			precios: preciosInsumos{
+				lista:    map[string]costing.Decimal{"470101": d("25.5")},
+				catalogo: map[string]costing.Decimal{"340101": d("15"), "470101": d("24")},
+			},
+			esperado: map[string]string{
+				"valor rescate": "100000", "inversión media": "340000", "depreciación": "40", "intereses": "20.40",
+				"seguros": "5.10", "posesión": "65.50", "mantenimiento": "32", "operación": "136.50", "horario": "202",
+			},
+			fuentes: [3]string{FuentePrecioCatalogo, FuentePrecioAnalisis, FuentePrecioLista},
+		},
+		{
+			// Sin rescate la inversión media se reduce a Va × (N + 1) / 2N; los neumáticos se deprecian aparte
+			nombre: "sin valor de rescate y con neumáticos",
+			analisis: models.AnalisisEquipo{
+				ValorAdquisicion: d("120000"), VidaEconomicaAnios: d("4"), HorasAnuales: d("1500"),
+				TasaInteresAnual: d("10"), PorcentajeSegurosAnual: d("2"), PorcentajeMantenimiento: d("50"),
+				ValorNeumaticos: d("6000"), VidaNeumaticosHoras: &vidaNeumaticos,
+				Combustible: models.InsumoEquipo{Cantidad: d("2"), Precio: d("14")},
+			},
+			esperado: map[string]string{
+				"valor rescate": "0", "inversión media": "75000", "depreciación": "20", "intereses": "5",
+				"seguros": "1", "posesión": "26", "mantenimiento": "10", "operación": "41", "horario": "67",
+			},
+			fuentes: [3]string{FuentePrecioAnalisis, FuentePrecioAnalisis, FuentePrecioAnalisis},
+		},
+		{
+			// (85000 × 4 + 12750 × 2) / 6 = 60916.666…; intereses y seguros parten de la inversión redondeada
+			nombre: "montos que se redondean a céntimos",
+			analisis: models.AnalisisEquipo{
+				ValorAdquisicion: d("85000"), VidaEconomicaAnios: d("3"), HorasAnuales: d("1800"),
+				PorcentajeRescate: d("15"), TasaInteresAnual: d("11"), PorcentajeSegurosAnual: d("2.5"),
+				PorcentajeMantenimiento: d("70"),
+			},
+			esperado: map[string]string{
+				"valor rescate": "12750", "inversión media": "60916.67", "depreciación": "13.38", "intereses": "3.72",
+				"seguros": "0.85", "posesión": "17.95", "mantenimiento": "9.37", "operación": "9.37", "horario": "27.32",
+			},
+			fuentes: [3]string{FuentePrecioAnalisis, FuentePrecioAnalisis, FuentePrecioAnalisis},
+		},
+	}
+
+	s := &EquipoService{}
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			costo := s.Calcular(&caso.analisis, caso.precios)
+			obtenidos := map[string]costing.Decimal{
+				"valor rescate": costo.ValorRescate, "inversión media": costo.InversionMedia,
+				"depreciación": costo.Depreciacion, "intereses": costo.Intereses, "seguros": costo.Seguros,
+				"posesión": costo.CostoPosesion, "mantenimiento": costo.Mantenimiento,
+				"operación": costo.CostoOperacion, "horario": costo.CostoHorario,
+			}
+			for concepto, esperado := range caso.esperado {
+				if !obtenidos[concepto].Igual(d(esperado)) {
+					t.Errorf("%s = %s, se esperaba %s", concepto, obtenidos[concepto], esperado)
+				}
+			}
+			fuentes := [3]string{costo.Combustible.FuentePrecio, costo.Lubricante.FuentePrecio, costo.Operador.FuentePrecio}
+			if fuentes != caso.fuentes {
+				t.Errorf("fuentes de precio = %v, se esperaba %v", fuentes, caso.fuentes)
+			}
+		})
+	}
+}
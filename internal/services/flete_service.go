@@ -0,0 +1,275 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"goexcel/internal/costing"
+	"goexcel/internal/database/repositories"
+	"goexcel/internal/models"
+)
+
+// CodigoFleteTerrestre es el código de la línea de flete que se agrega a los subcontratos del APU
+const CodigoFleteTerrestre = "FLETE"
+
+// FleteService administra las rutas de flete y los pesos unitarios de materiales, y prepara el flete
+// de cada proyecto para el reporte
+type FleteService struct {
+	fleteRepo *repositories.FleteRepository
+}
+
+func NewFleteService(fleteRepo *repositories.FleteRepository) *FleteService {
+	return &FleteService{fleteRepo: fleteRepo}
+}
+
+// ListarRutas devuelve las rutas de la organización y las globales; sin organización, solo las globales
+func (s *FleteService) ListarRutas(organizacionID *uuid.UUID) ([]models.RutaFlete, error) {
+	return s.fleteRepo.ListarRutas(organizacionID)
+}
+
+// ObtenerRuta devuelve una ruta de la organización o global
+func (s *FleteService) ObtenerRuta(id uuid.UUID, organizacionID *uuid.UUID) (*models.RutaFlete, error) {
+	ruta, err := s.fleteRepo.ObtenerRuta(id)
+	if err != nil {
+		return nil, err
+	}
+	if ruta == nil || (ruta.OrganizacionID != nil && (organizacionID == nil || *ruta.OrganizacionID != *organizacionID)) {
+		return nil, fmt.Errorf("ruta de flete no encontrada")
+	}
+	return ruta, nil
+}
+
+// CrearRuta valida y guarda una ruta de la organización; sin organización, una global
+func (s *FleteService) CrearRuta(req models.RutaFleteRequest, organizacionID *uuid.UUID) (*models.RutaFlete, error) {
+	ruta, err := nuevaRutaFlete(req)
+	if err != nil {
+		return nil, err
+	}
+	ruta.OrganizacionID = organizacionID
+
+	if err := s.fleteRepo.CrearRuta(ruta); err != nil {
+		return nil, err
+	}
+	return ruta, nil
+}
+
+// ActualizarRuta reemplaza una ruta propia de la organización; las globales solo bajo /admin
+func (s *FleteService) ActualizarRuta(id uuid.UUID, organizacionID *uuid.UUID, req models.RutaFleteRequest) (*models.RutaFlete, error) {
+	actual, err := s.fleteRepo.ObtenerRuta(id)
+	if err != nil {
+		return nil, err
+	}
+	if actual == nil || !mismaOrganizacion(actual.OrganizacionID, organizacionID) {
+		return nil, fmt.Errorf("ruta de flete no encontrada")
+	}
+
+	ruta, err := nuevaRutaFlete(req)
+	if err != nil {
+		return nil, err
+	}
+	ruta.ID = actual.ID
+	ruta.OrganizacionID = actual.OrganizacionID
+	ruta.CreatedAt = actual.CreatedAt
+
+	if err := s.fleteRepo.ActualizarRuta(ruta); err != nil {
+		return nil, err
+	}
+	return ruta, nil
+}
+
+// EliminarRuta borra una ruta de la organización; sin organización, una global
+func (s *FleteService) EliminarRuta(id uuid.UUID, organizacionID *uuid.UUID) error {
+	return s.fleteRepo.EliminarRuta(id, organizacionID)
+}
+
+// ListarPesos devuelve los pesos unitarios de la organización y los globales
+func (s *FleteService) ListarPesos(organizacionID *uuid.UUID) ([]models.PesoUnitario, error) {
+	return s.fleteRepo.ListarPesos(organizacionID)
+}
+
+// GuardarPesos valida y guarda los pesos unitarios de la organización; sin organización, los globales
+func (s *FleteService) GuardarPesos(organizacionID *uuid.UUID, req models.GuardarPesosRequest) ([]models.PesoUnitario, error) {
+	if len(req.Pesos) == 0 {
+		return nil, fmt.Errorf("debe indicar al menos un peso")
+	}
+	pesos := make([]models.PesoUnitarioRequest, 0, len(req.Pesos))
+	for _, peso := range req.Pesos {
+		codigo := strings.TrimSpace(peso.CodigoRecurso)
+		if codigo == "" {
+			return nil, fmt.Errorf("codigo_recurso es requerido")
+		}
+		if peso.PesoKg.Signo() <= 0 {
+			return nil, fmt.Errorf("peso_kg de %s debe ser mayor que cero", codigo)
+		}
+		pesos = append(pesos, models.PesoUnitarioRequest{CodigoRecurso: codigo, PesoKg: peso.PesoKg})
+	}
+
+	if err := s.fleteRepo.GuardarPesos(organizacionID, pesos); err != nil {
+		return nil, err
+	}
+	return s.fleteRepo.ListarPesos(organizacionID)
+}
+
+// EliminarPeso borra el peso de un material de la organización; sin organización, el global
+func (s *FleteService) EliminarPeso(organizacionID *uuid.UUID, codigo string) error {
+	return s.fleteRepo.EliminarPeso(organizacionID, codigo)
+}
+
+// FleteDeProyecto devuelve la ruta y el modo de flete del proyecto; la ruta es nil si no tiene
+func (s *FleteService) FleteDeProyecto(proyectoID uuid.UUID) (*models.FleteProyecto, error) {
+	_, rutaID, modo, err := s.fleteRepo.FleteDeProyecto(proyectoID)
+	if err != nil {
+		return nil, err
+	}
+
+	flete := &models.FleteProyecto{Modo: modo}
+	if rutaID != nil {
+		if flete.Ruta, err = s.fleteRepo.ObtenerRuta(*rutaID); err != nil {
+			return nil, err
+		}
+	}
+	return flete, nil
+}
+
+// AsignarAProyecto asigna al proyecto una ruta de su organización o global, o la quita con nil, y el
+// modo de aplicar el flete
+func (s *FleteService) AsignarAProyecto(proyectoID uuid.UUID, req models.AsignarFleteRequest) (*models.FleteProyecto, error) {
+	modo := req.Modo
+	if modo == "" {
+		modo = models.ModoFletePrecio
+	}
+	if modo != models.ModoFletePrecio && modo != models.ModoFleteSubcontrato {
+		return nil, fmt.Errorf("modo de flete inválido: %s (use %q o %q)", modo, models.ModoFletePrecio, models.ModoFleteSubcontrato)
+	}
+
+	flete := &models.FleteProyecto{Modo: modo}
+	if req.RutaFleteID != nil {
+		organizacionID, _, _, err := s.fleteRepo.FleteDeProyecto(proyectoID)
+		if err != nil {
+			return nil, err
+		}
+		if flete.Ruta, err = s.ObtenerRuta(*req.RutaFleteID, organizacionID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.fleteRepo.AsignarAProyecto(proyectoID, req.RutaFleteID, modo); err != nil {
+		return nil, err
+	}
+	return flete, nil
+}
+
+// ParaReporte devuelve el flete del proyecto con los pesos unitarios efectivos de su organización;
+// nil si el proyecto no tiene ruta asignada
+func (s *FleteService) ParaReporte(proyectoID uuid.UUID) (*models.FleteProyecto, error) {
+	flete, err := s.FleteDeProyecto(proyectoID)
+	if err != nil || flete.Ruta == nil {
+		return nil, err
+	}
+	if flete.Pesos, err = s.fleteRepo.PesosParaProyecto(proyectoID); err != nil {
+		return nil, err
+	}
+	return flete, nil
+}
+
+func nuevaRutaFlete(req models.RutaFleteRequest) (*models.RutaFlete, error) {
+	ruta := &models.RutaFlete{
+		Nombre:           strings.TrimSpace(req.Nombre),
+		Origen:           strings.TrimSpace(req.Origen),
+		Destino:          strings.TrimSpace(req.Destino),
+		DistanciaKm:      req.DistanciaKm,
+		TarifaToneladaKm: req.TarifaToneladaKm,
+	}
+	if ruta.Nombre == "" || ruta.Origen == "" || ruta.Destino == "" {
+		return nil, fmt.Errorf("nombre, origen y destino son requeridos")
+	}
+	if ruta.DistanciaKm.Signo() <= 0 {
+		return nil, fmt.Errorf("distancia_km debe ser mayor que cero")
+	}
+	if ruta.TarifaToneladaKm.Signo() < 0 {
+		return nil, fmt.Errorf("tarifa_tonelada_km no puede ser negativa")
+	}
+	return ruta, nil
+}
+
+// lineaFleteTerrestre es la línea de subcontrato con el flete de los materiales de un APU: las
+// toneladas que se transportan por unidad de partida al costo por tonelada de la ruta. ok es false si
+// ningún material de la partida tiene peso.
+func lineaFleteTerrestre(secciones []models.SeccionReporte, flete *models.FleteProyecto, reglas costing.Reglas) (models.RecursoReporte, bool) {
+	kilos := costing.Decimal{}
+	for _, seccion := range secciones {
+		if seccion.Tipo != "materiales" {
+			continue
+		}
+		for _, material := range seccion.Recursos {
+			if peso, existe := flete.Pesos[material.Codigo]; existe {
+				kilos = kilos.Sumar(material.Cantidad.Multiplicar(peso))
+			}
+		}
+	}
+	if kilos.EsCero() {
+		return models.RecursoReporte{}, false
+	}
+
+	linea := models.RecursoReporte{
+		Codigo:      CodigoFleteTerrestre,
+		Descripcion: fmt.Sprintf("FLETE TERRESTRE %s - %s", flete.Ruta.Origen, flete.Ruta.Destino),
+		Unidad:      "t",
+		Cantidad:    kilos.Dividir(costing.NuevoDecimal(1000, 0), reglas.Cantidad),
+		Precio:      flete.Ruta.CostoTonelada(reglas),
+	}
+	linea.Parcial = reglas.ParcialRecurso(linea.Cantidad, linea.Precio)
+	return linea, true
+}
+
+// calcularFlete resume el flete de cada material con peso en todo el presupuesto: Σ metrado × cantidad
+// del APU, su peso y su flete con el costo por tonelada de la ruta
+func calcularFlete(partidas []*models.PartidaReporte, flete *models.FleteProyecto, reglas costing.Reglas) *models.CalculoFlete {
+	calculo := &models.CalculoFlete{
+		Ruta:          *flete.Ruta,
+		Modo:          flete.Modo,
+		CostoTonelada: flete.Ruta.CostoTonelada(reglas),
+	}
+
+	materiales := make(map[string]*models.FleteMaterial)
+	for _, partida := range partidas {
+		for _, seccion := range partida.Secciones {
+			if seccion.Tipo != "materiales" {
+				continue
+			}
+			for _, recurso := range seccion.Recursos {
+				peso, existe := flete.Pesos[recurso.Codigo]
+				if !existe {
+					continue
+				}
+				material, visto := materiales[recurso.Codigo]
+				if !visto {
+					material = &models.FleteMaterial{
+						Codigo:        recurso.Codigo,
+						Descripcion:   recurso.Descripcion,
+						Unidad:        recurso.Unidad,
+						PesoUnitario:  peso,
+						FleteUnitario: reglas.FleteUnitario(peso, calculo.CostoTonelada),
+					}
+					materiales[recurso.Codigo] = material
+				}
+				material.Cantidad = material.Cantidad.Sumar(partida.Metrado.Multiplicar(recurso.Cantidad))
+			}
+		}
+	}
+
+	for _, material := range materiales {
+		material.Cantidad = material.Cantidad.Redondear(reglas.Cantidad)
+		material.Peso = reglas.Toneladas(material.Cantidad, material.PesoUnitario)
+		material.Flete = reglas.ParcialRecurso(material.Cantidad, material.FleteUnitario)
+		calculo.Materiales = append(calculo.Materiales, *material)
+		calculo.PesoTotal = calculo.PesoTotal.Sumar(material.Peso)
+		calculo.Total = reglas.Sumar(calculo.Total, material.Flete)
+	}
+	sort.Slice(calculo.Materiales, func(i, j int) bool {
+		return calculo.Materiales[i].Codigo < calculo.Materiales[j].Codigo
+	})
+	return calculo
+}
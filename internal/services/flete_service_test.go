@@ -0,0 +1,105 @@
+package services
+
+import (
+	"testing"
+
+	"goexcel/internal/costing"
+	"goexcel/internal/models"
+)
+
+func TestCostoToneladaRuta(t *testing.T) {
+	casos := []struct {
+		distancia, tarifa, esperado string
+	}{
+		{"300", "0.45", "135"},
+		// 87.5 km × 0.3333 por t-km = 29.16375 → 29.1638, redondeado como precio
+		{"87.5", "0.3333", "29.1638"},
+		{"12", "0", "0"},
+	}
+	for _, caso := range casos {
+		ruta := models.RutaFlete{DistanciaKm: costing.DebeParsear(caso.distancia), TarifaToneladaKm: costing.DebeParsear(caso.tarifa)}
+		if got := ruta.CostoTonelada(costing.ReglasS10); !got.Igual(costing.DebeParsear(caso.esperado)) {
+			t.Errorf("%s km × %s = %s, se esperaba %s", caso.distancia, caso.tarifa, got, caso.esperado)
+		}
+	}
+}
+
+// fleteLimaHuancayo son 300 km a 0.45 por t-km (135 por tonelada) con el peso del cemento y del ladrillo
+func fleteLimaHuancayo() *models.FleteProyecto {
+	return &models.FleteProyecto{
+		Ruta: &models.RutaFlete{Origen: "LIMA", Destino: "HUANCAYO", DistanciaKm: costing.DebeParsear("300"), TarifaToneladaKm: costing.DebeParsear("0.45")},
+		Modo: "precio",
+		Pesos: map[string]costing.Decimal{
+			"CEMENTO":  costing.DebeParsear("42.5"),
+			"LADRILLO": costing.DebeParsear("2.9"),
+		},
+	}
+}
+
+func materialesPrueba(cantidades map[string]string) []models.SeccionReporte {
+	seccion := models.SeccionReporte{Tipo: "materiales"}
+	for _, codigo := range []string{"CEMENTO", "LADRILLO", "CLAVOS"} {
+		if cantidad, existe := cantidades[codigo]; existe {
+			seccion.Recursos = append(seccion.Recursos, models.RecursoReporte{Codigo: codigo, Cantidad: costing.DebeParsear(cantidad)})
+		}
+	}
+	return []models.SeccionReporte{seccion}
+}
+
+func TestLineaFleteTerrestre(t *testing.T) {
+	casos := []struct {
+		nombre     string
+		cantidades map[string]string
+		ok         bool
+		toneladas  string
+		parcial    string
+	}{
+		// 0.2156 × 42.5 + 40.425 × 2.9 = 126.3955 kg → 0.1264 t × 135 = 17.064 → 17.06
+		{"cemento y ladrillo", map[string]string{"CEMENTO": "0.2156", "LADRILLO": "40.425", "CLAVOS": "0.02"}, true, "0.1264", "17.06"},
+		{"sin materiales con peso", map[string]string{"CLAVOS": "0.02"}, false, "0", "0"},
+	}
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			linea, ok := lineaFleteTerrestre(materialesPrueba(caso.cantidades), fleteLimaHuancayo(), costing.ReglasS10)
+			if ok != caso.ok {
+				t.Fatalf("ok = %v, se esperaba %v", ok, caso.ok)
+			}
+			if !linea.Cantidad.Igual(costing.DebeParsear(caso.toneladas)) || !linea.Parcial.Igual(costing.DebeParsear(caso.parcial)) {
+				t.Errorf("línea = %s t, parcial %s; se esperaba %s t, parcial %s", linea.Cantidad, linea.Parcial, caso.toneladas, caso.parcial)
+			}
+		})
+	}
+}
+
+func TestCalcularFletePorMaterial(t *testing.T) {
+	d := costing.DebeParsear
+	partidas := []*models.PartidaReporte{
+		{Codigo: "01.01", Metrado: d("45.67"), Secciones: materialesPrueba(map[string]string{"CEMENTO": "0.2156", "LADRILLO": "40.425"})},
+		{Codigo: "01.02", Metrado: d("12.5"), Secciones: materialesPrueba(map[string]string{"CEMENTO": "9.73", "CLAVOS": "0.5"})},
+	}
+
+	calculo := calcularFlete(partidas, fleteLimaHuancayo(), costing.ReglasS10)
+
+	// Cemento: 45.67 × 0.2156 + 12.5 × 9.73 = 131.471452 → 131.4715 bol a 42.5 × 135 / 1000 = 5.7375 por bolsa
+	esperados := []struct {
+		codigo, fleteUnitario, cantidad, peso, flete string
+	}{
+		{"CEMENTO", "5.7375", "131.4715", "5.5875", "754.32"},
+		{"LADRILLO", "0.3915", "1846.2098", "5.3540", "722.79"},
+	}
+	if len(calculo.Materiales) != len(esperados) {
+		t.Fatalf("materiales = %d, se esperaban %d", len(calculo.Materiales), len(esperados))
+	}
+	for i, esperado := range esperados {
+		material := calculo.Materiales[i]
+		if material.Codigo != esperado.codigo || !material.FleteUnitario.Igual(d(esperado.fleteUnitario)) ||
+			!material.Cantidad.Igual(d(esperado.cantidad)) || !material.Peso.Igual(d(esperado.peso)) || !material.Flete.Igual(d(esperado.flete)) {
+			t.Errorf("material %d = %s: unitario %s, cantidad %s, peso %s, flete %s; se esperaba %+v",
+				i, material.Codigo, material.FleteUnitario, material.Cantidad, material.Peso, material.Flete, esperado)
+		}
+	}
+	if !calculo.CostoTonelada.Igual(d("135")) || !calculo.PesoTotal.Igual(d("10.9415")) || !calculo.Total.Igual(d("1477.11")) {
+		t.Errorf("costo por tonelada %s, peso total %s, total %s; se esperaba 135, 10.9415 y 1477.11",
+			calculo.CostoTonelada, calculo.PesoTotal, calculo.Total)
+	}
+}
@@ -0,0 +1,682 @@
+package services
+
+import (
+	"fmt"
+	"hash/fnv"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+	"goexcel/internal/costing"
+	"goexcel/internal/legacy"
+	"goexcel/internal/models"
+)
+
+// toleranciaSubtotal es la diferencia admitida entre el subtotal impreso en el libro y la suma de sus recursos
+var toleranciaSubtotal = costing.NuevoDecimal(5, 2)
+
+var (
+	// "PARTIDA 01.02 - Descripción", "Partida: 01.02 Descripción" o solo "Partida" con el código en la celda siguiente
+	patronPartida = regexp.MustCompile(`(?i)^partida\b\s*:?\s*(.*)$`)
+	patronCodigo  = regexp.MustCompile(`^(\S+)\s+(?:[-–:]\s*)?(.+)$`)
+	// Títulos del presupuesto: "01 ESTRUCTURAS", "01.02. CONCRETO SIMPLE"
+	patronTitulo = regexp.MustCompile(`^(\d{1,3}(?:\.\d{1,3})*)\.?\s+(.+)$`)
+	patronItem   = regexp.MustCompile(`^\d{1,3}(?:\.\d{1,3})*\.?$`)
+
+	normalizadorTexto = strings.NewReplacer("á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u", "ü", "u", "ñ", "n")
+)
+
+// ImportacionExcelService lee libros de APU y Presupuesto exportados por este sistema, S10 u hojas
+// propias y los convierte en partidas legacy, títulos y metrados para revisión antes de guardarlos
+type ImportacionExcelService struct{}
+
+// NewImportacionExcelService crea una nueva instancia del servicio de importación
+func NewImportacionExcelService() *ImportacionExcelService {
+	return &ImportacionExcelService{}
+}
+
+// VistaPreviaImportacion es el resultado de leer el libro, sin persistir nada
+type VistaPreviaImportacion struct {
+	Hojas        []models.HojaImportada          `json:"hojas"`
+	Partidas     []legacy.PartidaLegacy          `json:"partidas"`
+	Titulos      []models.TituloImportado        `json:"titulos"`
+	Metrados     []models.MetradoRequest         `json:"metrados"`
+	Advertencias []models.AdvertenciaImportacion `json:"advertencias"`
+}
+
+// columnasTabla guarda los índices (base 0) de cada dato en la tabla; -1 si no existe
+type columnasTabla struct {
+	codigo, descripcion, unidad, cuadrilla, cantidad, precio, parcial, metrado int
+}
+
+// Importar lee el libro y detecta bloques de partida, secciones de recursos y el presupuesto
+func (s *ImportacionExcelService) Importar(r io.Reader, opciones models.OpcionesImportacion) (*VistaPreviaImportacion, error) {
+	f, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("error abriendo libro: %v", err)
+	}
+	defer f.Close()
+
+	mapeoAPU, err := columnasDesdeMapeo(opciones.MapeoAPU)
+	if err != nil {
+		return nil, fmt.Errorf("mapeo de APU inválido: %v", err)
+	}
+	mapeoPresupuesto, err := columnasDesdeMapeo(opciones.MapeoPresupuesto)
+	if err != nil {
+		return nil, fmt.Errorf("mapeo de presupuesto inválido: %v", err)
+	}
+
+	hojas := f.GetSheetList()
+	if opciones.Hoja != "" {
+		if idx, _ := f.GetSheetIndex(opciones.Hoja); idx < 0 {
+			return nil, fmt.Errorf("la hoja %q no existe en el libro", opciones.Hoja)
+		}
+		hojas = []string{opciones.Hoja}
+	}
+
+	vista := &VistaPreviaImportacion{
+		Partidas:     []legacy.PartidaLegacy{},
+		Titulos:      []models.TituloImportado{},
+		Metrados:     []models.MetradoRequest{},
+		Advertencias: []models.AdvertenciaImportacion{},
+	}
+	parametros := models.ParametrosPorDefecto()
+	if opciones.Parametros != nil {
+		parametros = *opciones.Parametros
+	}
+	lector := &lectorImportacion{vista: vista, parametros: parametros, titulos: make(map[string]bool), metrados: make(map[string]bool)}
+
+	for _, hoja := range hojas {
+		filas, err := f.GetRows(hoja, excelize.Options{RawCellValue: true})
+		if err != nil {
+			return nil, fmt.Errorf("error leyendo hoja %s: %v", hoja, err)
+		}
+
+		info := models.HojaImportada{Nombre: hoja, Tipo: "ignorada"}
+		switch clasificarHoja(filas) {
+		case "apu":
+			info.Tipo = "apu"
+			lector.leerAPU(hoja, filas, mapeoAPU, &info)
+		case "presupuesto":
+			info.Tipo = "presupuesto"
+			lector.leerPresupuesto(hoja, filas, mapeoPresupuesto, &info)
+		}
+		vista.Hojas = append(vista.Hojas, info)
+	}
+
+	if len(vista.Partidas) == 0 {
+		return nil, fmt.Errorf("no se encontraron partidas: el libro no tiene bloques \"Partida <código> - <descripción>\"")
+	}
+
+	lector.validar()
+	return vista, nil
+}
+
+// clasificarHoja decide si la hoja contiene análisis unitarios, un presupuesto o nada reconocible
+func clasificarHoja(filas [][]string) string {
+	presupuesto := false
+	for _, fila := range filas {
+		primero, _ := primeraCelda(fila)
+		if patronPartida.MatchString(primero) && !esFilaTotal(normalizar(primero)) {
+			return "apu"
+		}
+		if cols, ok := detectarCabecera(fila); ok && cols.metrado >= 0 {
+			presupuesto = true
+		}
+	}
+	if presupuesto {
+		return "presupuesto"
+	}
+	return ""
+}
+
+// lectorImportacion acumula lo leído de todas las hojas
+type lectorImportacion struct {
+	vista *VistaPreviaImportacion
+	// parametros dan la jornada y el redondeo con que se deduce la cantidad de mano de obra y equipo a
+	// partir de la cuadrilla, cuando el libro no la trae o cuando la cuadrilla define la cantidad
+	parametros models.Parametros
+	titulos    map[string]bool
+	metrados   map[string]bool
+}
+
+func (l *lectorImportacion) advertir(hoja string, fila int, formato string, args ...interface{}) {
+	l.vista.Advertencias = append(l.vista.Advertencias, models.AdvertenciaImportacion{
+		Hoja:    hoja,
+		Fila:    fila,
+		Mensaje: fmt.Sprintf(formato, args...),
+	})
+}
+
+func (l *lectorImportacion) agregarTitulo(info *models.HojaImportada, codigo, descripcion string) {
+	codigo = strings.TrimSuffix(codigo, ".")
+	if l.titulos[codigo] {
+		return
+	}
+	l.titulos[codigo] = true
+	l.vista.Titulos = append(l.vista.Titulos, models.TituloImportado{
+		Codigo:      codigo,
+		Descripcion: descripcion,
+		Nivel:       strings.Count(codigo, ".") + 1,
+	})
+	info.Titulos++
+}
+
+// leerAPU recorre la hoja como una secuencia de bloques: encabezado de partida, datos de unidad y
+// rendimiento, cabecera de la tabla de recursos y secciones con sus recursos y subtotales
+func (l *lectorImportacion) leerAPU(hoja string, filas [][]string, mapeo columnasTabla, info *models.HojaImportada) {
+	cols, tieneColumnas := mapeo, mapeo.descripcion >= 0
+	if tieneColumnas {
+		info.Mapeo = mapeoDesdeColumnas(mapeo)
+	}
+	var actual *legacy.PartidaLegacy
+	seccion := ""
+	sumaSeccion := costing.Decimal{}
+
+	cerrar := func() {
+		if actual != nil {
+			l.vista.Partidas = append(l.vista.Partidas, *actual)
+			info.Partidas++
+			actual = nil
+		}
+	}
+
+	for i, fila := range filas {
+		numFila := i + 1
+		primero, idx := primeraCelda(fila)
+		if idx < 0 {
+			continue
+		}
+		texto := normalizar(primero)
+
+		// Encabezado de partida
+		if m := patronPartida.FindStringSubmatch(primero); m != nil && !esFilaTotal(texto) {
+			cerrar()
+			codigo, descripcion := separarCodigo(m[1], fila[idx+1:])
+			if codigo == "" {
+				l.advertir(hoja, numFila, "encabezado de partida sin código: %q", primero)
+			}
+			actual = &legacy.PartidaLegacy{Codigo: codigo, Descripcion: descripcion}
+			seccion, sumaSeccion = "", costing.Decimal{}
+			continue
+		}
+
+		// Cabecera de la tabla de recursos; el mapeo indicado por el usuario tiene prioridad
+		if detectadas, ok := detectarCabecera(fila); ok && detectadas.metrado < 0 {
+			if mapeo.descripcion < 0 {
+				cols = detectadas
+			}
+			tieneColumnas = true
+			if info.Mapeo == (models.MapeoColumnas{}) {
+				info.Mapeo = mapeoDesdeColumnas(cols)
+			}
+			continue
+		}
+
+		// Fila de título del presupuesto entre partidas
+		if m := patronTitulo.FindStringSubmatch(primero); m != nil && celdasConTexto(fila) == 1 {
+			cerrar()
+			l.agregarTitulo(info, m[1], strings.TrimSpace(m[2]))
+			continue
+		}
+
+		if actual == nil {
+			continue
+		}
+
+		// Subtotales y totales: solo se usan para verificar la lectura
+		if esFilaTotal(texto) {
+			if strings.HasPrefix(texto, "subtotal") || strings.HasPrefix(texto, "sub total") {
+				if leido, ok := ultimoNumero(fila); ok && seccion != "" && leido.Restar(sumaSeccion).Abs().Cmp(toleranciaSubtotal) > 0 {
+					l.advertir(hoja, numFila, "el subtotal del libro (%.2f) no coincide con la suma de los recursos leídos (%.2f) en la partida %s",
+						leido, sumaSeccion, actual.Codigo)
+				}
+			}
+			seccion, sumaSeccion = "", costing.Decimal{}
+			continue
+		}
+
+		if nueva := seccionDesdeTexto(texto); nueva != "" && celdasConTexto(fila) <= 2 {
+			seccion, sumaSeccion = nueva, costing.Decimal{}
+			continue
+		}
+
+		// Datos de la partida: "Unidad: m3", "Rendimiento: 25 m3/DIA", "Costo unitario directo por: m3"
+		if l.leerDatosPartida(actual, fila) {
+			continue
+		}
+
+		if !tieneColumnas {
+			continue
+		}
+
+		recurso, ok := l.leerRecurso(hoja, numFila, fila, cols)
+		if !ok {
+			continue
+		}
+		tipo := seccion
+		if tipo == "" {
+			tipo = seccionPorUnidad(recurso.Unidad)
+			l.advertir(hoja, numFila, "recurso %q fuera de una sección; se asignó a %s por su unidad", recurso.Descripcion, tipo)
+		}
+		deducir := recurso.Cantidad.EsCero() || l.parametros.CuadrillaDefineCantidad
+		if deducir && recurso.Cuadrilla > 0 && actual.Rendimiento > 0 && (tipo == "mano_obra" || tipo == "equipos") {
+			recurso.Cantidad = l.parametros.Reglas().CantidadCuadrilla(recurso.Cuadrilla, l.parametros.HorasJornada, actual.Rendimiento)
+			l.advertir(hoja, numFila, "cantidad de %q calculada desde la cuadrilla y el rendimiento", recurso.Descripcion)
+		}
+		sumaSeccion = costing.Sumar(sumaSeccion, costing.ParcialRecurso(recurso.Cantidad, recurso.Precio))
+		agregarRecursoPorTipo(actual, tipo, recurso)
+	}
+	cerrar()
+}
+
+// leerDatosPartida toma unidad y rendimiento de las filas de información; devuelve true si la fila lo era
+func (l *lectorImportacion) leerDatosPartida(partida *legacy.PartidaLegacy, fila []string) bool {
+	encontrado := false
+	for j, celda := range fila {
+		etiqueta := strings.TrimSuffix(normalizar(celda), ":")
+		switch {
+		case etiqueta == "unidad" || etiqueta == "und" || etiqueta == "und.":
+			if valor := siguienteTexto(fila, j); valor != "" {
+				partida.Unidad = valor
+			}
+			encontrado = true
+		case strings.HasPrefix(etiqueta, "costo unitario directo por"):
+			if partes := strings.SplitN(celda, ":", 2); len(partes) == 2 && strings.TrimSpace(partes[1]) != "" {
+				partida.Unidad = strings.TrimSpace(partes[1])
+			} else if valor := siguienteTexto(fila, j); valor != "" {
+				partida.Unidad = valor
+			}
+			encontrado = true
+		case strings.HasPrefix(etiqueta, "rendimiento"):
+			for _, resto := range fila[j+1:] {
+				if valor, ok := parsearNumero(resto); ok {
+					partida.Rendimiento = valor
+					break
+				}
+			}
+			encontrado = true
+		}
+	}
+	return encontrado
+}
+
+// leerRecurso interpreta una fila de la tabla; sin descripción o sin cantidad ni parcial no es un recurso
+func (l *lectorImportacion) leerRecurso(hoja string, numFila int, fila []string, cols columnasTabla) (legacy.RecursoLegacy, bool) {
+	descripcion := celda(fila, cols.descripcion)
+	if descripcion == "" {
+		return legacy.RecursoLegacy{}, false
+	}
+
+	cantidad, tieneCantidad := parsearDecimal(celda(fila, cols.cantidad))
+	parcial, tieneParcial := parsearDecimal(celda(fila, cols.parcial))
+	cuadrilla, tieneCuadrilla := parsearNumero(celda(fila, cols.cuadrilla))
+	if !tieneCantidad && !tieneParcial && !tieneCuadrilla {
+		return legacy.RecursoLegacy{}, false
+	}
+
+	recurso := legacy.RecursoLegacy{
+		Codigo:      celda(fila, cols.codigo),
+		Descripcion: descripcion,
+		Unidad:      celda(fila, cols.unidad),
+		Cantidad:    cantidad,
+		Cuadrilla:   cuadrilla,
+	}
+
+	precio, tienePrecio := parsearDecimal(celda(fila, cols.precio))
+	switch {
+	case tienePrecio:
+		recurso.Precio = precio
+	case tieneParcial && cantidad.Signo() > 0:
+		recurso.Precio = parcial.Dividir(cantidad, costing.Redondeo{Decimales: 4, Modo: costing.MitadArriba})
+		l.advertir(hoja, numFila, "precio de %q calculado como parcial / cantidad", descripcion)
+	}
+
+	if recurso.Codigo == "" {
+		recurso.Codigo = codigoGenerado(recurso)
+		l.advertir(hoja, numFila, "recurso %q sin código; se le asignó %s", descripcion, recurso.Codigo)
+	}
+
+	return recurso, true
+}
+
+// leerPresupuesto toma títulos y metrados de una hoja con columnas Ítem, Descripción, Und. y Metrado
+func (l *lectorImportacion) leerPresupuesto(hoja string, filas [][]string, mapeo columnasTabla, info *models.HojaImportada) {
+	cols, tieneColumnas := mapeo, mapeo.metrado >= 0
+	if tieneColumnas {
+		info.Mapeo = mapeoDesdeColumnas(mapeo)
+	}
+
+	for i, fila := range filas {
+		numFila := i + 1
+		if !tieneColumnas {
+			if detectadas, ok := detectarCabecera(fila); ok && detectadas.metrado >= 0 {
+				cols, tieneColumnas = detectadas, true
+				info.Mapeo = mapeoDesdeColumnas(cols)
+			}
+			continue
+		}
+
+		item := celda(fila, cols.codigo)
+		descripcion := celda(fila, cols.descripcion)
+		if item == "" || descripcion == "" || esFilaTotal(normalizar(item)) {
+			continue
+		}
+
+		metrado, tieneMetrado := parsearDecimal(celda(fila, cols.metrado))
+		if !tieneMetrado {
+			if patronItem.MatchString(item) {
+				l.agregarTitulo(info, item, descripcion)
+			}
+			continue
+		}
+
+		if l.metrados[item] {
+			l.advertir(hoja, numFila, "metrado repetido para la partida %s; se conserva el primero", item)
+			continue
+		}
+		l.metrados[item] = true
+
+		metradoReq := models.MetradoRequest{PartidaCodigo: item, Metrado: metrado}
+		if unidad := celda(fila, cols.unidad); unidad != "" {
+			metradoReq.Unidad = &unidad
+		}
+		l.vista.Metrados = append(l.vista.Metrados, metradoReq)
+		info.Partidas++
+	}
+}
+
+// validar revisa la consistencia entre hojas para que el usuario corrija antes de confirmar
+func (l *lectorImportacion) validar() {
+	codigos := make(map[string]bool)
+	for _, partida := range l.vista.Partidas {
+		if codigos[partida.Codigo] {
+			l.advertir("", 0, "la partida %s aparece más de una vez", partida.Codigo)
+		}
+		codigos[partida.Codigo] = true
+
+		if len(partida.ManoObra)+len(partida.Materiales)+len(partida.Equipos)+len(partida.Subcontratos) == 0 {
+			l.advertir("", 0, "la partida %s no tiene recursos", partida.Codigo)
+		}
+		if partida.Unidad == "" {
+			l.advertir("", 0, "la partida %s no tiene unidad", partida.Codigo)
+		}
+		if partida.Rendimiento == 0 && len(partida.ManoObra) > 0 {
+			l.advertir("", 0, "la partida %s tiene mano de obra pero no rendimiento", partida.Codigo)
+		}
+	}
+
+	for _, metrado := range l.vista.Metrados {
+		if !codigos[metrado.PartidaCodigo] {
+			l.advertir("", 0, "el presupuesto tiene metrado para %s pero no hay análisis unitario de esa partida", metrado.PartidaCodigo)
+		}
+	}
+}
+
+// detectarCabecera reconoce la fila de cabeceras de una tabla por los nombres de sus columnas
+func detectarCabecera(fila []string) (columnasTabla, bool) {
+	cols := columnasTabla{-1, -1, -1, -1, -1, -1, -1, -1}
+	for j, valor := range fila {
+		texto := strings.TrimSuffix(normalizar(valor), ":")
+		switch {
+		case texto == "":
+		case texto == "codigo" || texto == "cod" || texto == "cod." || texto == "item" || texto == "indice":
+			asignarColumna(&cols.codigo, j)
+		case strings.HasPrefix(texto, "descripcion") || texto == "recurso" || texto == "insumo":
+			asignarColumna(&cols.descripcion, j)
+		case texto == "unidad" || texto == "und" || texto == "und." || texto == "unid." || texto == "u.m.":
+			asignarColumna(&cols.unidad, j)
+		case strings.HasPrefix(texto, "cuadrilla") || texto == "cuad.":
+			asignarColumna(&cols.cuadrilla, j)
+		case strings.HasPrefix(texto, "cantidad") || texto == "cant.":
+			asignarColumna(&cols.cantidad, j)
+		case strings.HasPrefix(texto, "precio") || strings.HasPrefix(texto, "p.u") || strings.HasPrefix(texto, "costo unitario"):
+			asignarColumna(&cols.precio, j)
+		case strings.HasPrefix(texto, "parcial") || strings.HasPrefix(texto, "importe"):
+			asignarColumna(&cols.parcial, j)
+		case strings.HasPrefix(texto, "metrado"):
+			asignarColumna(&cols.metrado, j)
+		}
+	}
+	return cols, cols.descripcion >= 0 && (cols.cantidad >= 0 || cols.metrado >= 0 || cols.parcial >= 0)
+}
+
+func asignarColumna(destino *int, j int) {
+	if *destino < 0 {
+		*destino = j
+	}
+}
+
+// columnasDesdeMapeo convierte las letras indicadas por el usuario en índices de columna
+func columnasDesdeMapeo(mapeo *models.MapeoColumnas) (columnasTabla, error) {
+	cols := columnasTabla{-1, -1, -1, -1, -1, -1, -1, -1}
+	if mapeo == nil {
+		return cols, nil
+	}
+
+	campos := []struct {
+		letra   string
+		destino *int
+	}{
+		{mapeo.Codigo, &cols.codigo},
+		{mapeo.Descripcion, &cols.descripcion},
+		{mapeo.Unidad, &cols.unidad},
+		{mapeo.Cuadrilla, &cols.cuadrilla},
+		{mapeo.Cantidad, &cols.cantidad},
+		{mapeo.Precio, &cols.precio},
+		{mapeo.Parcial, &cols.parcial},
+		{mapeo.Metrado, &cols.metrado},
+	}
+	for _, campo := range campos {
+		if campo.letra == "" {
+			continue
+		}
+		numero, err := excelize.ColumnNameToNumber(strings.ToUpper(strings.TrimSpace(campo.letra)))
+		if err != nil {
+			return cols, fmt.Errorf("columna %q: %v", campo.letra, err)
+		}
+		*campo.destino = numero - 1
+	}
+	if cols.descripcion < 0 {
+		return cols, fmt.Errorf("debe indicar al menos la columna de descripción")
+	}
+	return cols, nil
+}
+
+// mapeoDesdeColumnas devuelve el mapeo usado, en letras, para mostrarlo en la vista previa
+func mapeoDesdeColumnas(cols columnasTabla) models.MapeoColumnas {
+	letra := func(j int) string {
+		if j < 0 {
+			return ""
+		}
+		nombre, _ := excelize.ColumnNumberToName(j + 1)
+		return nombre
+	}
+	return models.MapeoColumnas{
+		Codigo:      letra(cols.codigo),
+		Descripcion: letra(cols.descripcion),
+		Unidad:      letra(cols.unidad),
+		Cuadrilla:   letra(cols.cuadrilla),
+		Cantidad:    letra(cols.cantidad),
+		Precio:      letra(cols.precio),
+		Parcial:     letra(cols.parcial),
+		Metrado:     letra(cols.metrado),
+	}
+}
+
+// seccionDesdeTexto reconoce los encabezados de sección de recursos
+func seccionDesdeTexto(texto string) string {
+	switch {
+	case strings.HasPrefix(texto, "mano de obra"):
+		return "mano_obra"
+	case texto == "materiales" || texto == "material" || strings.HasPrefix(texto, "materiales "):
+		return "materiales"
+	case texto == "equipos" || texto == "equipo" || strings.HasPrefix(texto, "equipos ") || strings.HasPrefix(texto, "herramientas"):
+		return "equipos"
+	case strings.HasPrefix(texto, "subcontrato") || strings.HasPrefix(texto, "sub-contrato") || strings.HasPrefix(texto, "sub contrato"):
+		return "subcontratos"
+	}
+	return ""
+}
+
+// seccionPorUnidad asigna un recurso sin sección según la convención de unidades de los APU
+func seccionPorUnidad(unidad string) string {
+	switch strings.ToLower(strings.TrimSpace(unidad)) {
+	case "hh":
+		return "mano_obra"
+	case "hm", "%mo", "% mo":
+		return "equipos"
+	}
+	return "materiales"
+}
+
+func agregarRecursoPorTipo(partida *legacy.PartidaLegacy, tipo string, recurso legacy.RecursoLegacy) {
+	switch tipo {
+	case "mano_obra":
+		partida.ManoObra = append(partida.ManoObra, recurso)
+	case "equipos":
+		partida.Equipos = append(partida.Equipos, recurso)
+	case "subcontratos":
+		partida.Subcontratos = append(partida.Subcontratos, recurso)
+	default:
+		partida.Materiales = append(partida.Materiales, recurso)
+	}
+}
+
+// esFilaTotal reconoce subtotales, costos totales y el pie del presupuesto
+func esFilaTotal(texto string) bool {
+	for _, prefijo := range []string{"subtotal", "sub total", "total", "costo total", "costo directo", "gastos generales", "utilidad", "igv"} {
+		if strings.HasPrefix(texto, prefijo) {
+			return true
+		}
+	}
+	return false
+}
+
+// separarCodigo obtiene código y descripción del texto del encabezado o, si está vacío, de las celdas siguientes
+func separarCodigo(texto string, resto []string) (string, string) {
+	texto = strings.TrimSpace(texto)
+	if texto == "" {
+		var valores []string
+		for _, valor := range resto {
+			if v := strings.TrimSpace(valor); v != "" {
+				valores = append(valores, v)
+			}
+		}
+		if len(valores) == 0 {
+			return "", ""
+		}
+		return valores[0], strings.Join(valores[1:], " ")
+	}
+
+	if m := patronCodigo.FindStringSubmatch(texto); m != nil {
+		return m[1], strings.TrimSpace(m[2])
+	}
+	return texto, siguienteTexto(resto, -1)
+}
+
+// codigoGenerado asigna el mismo código a recursos iguales para que se normalicen como uno solo
+func codigoGenerado(recurso legacy.RecursoLegacy) string {
+	h := fnv.New32a()
+	h.Write([]byte(strings.ToLower(recurso.Descripcion + "|" + recurso.Unidad)))
+	return fmt.Sprintf("IMP%08X", h.Sum32())
+}
+
+func primeraCelda(fila []string) (string, int) {
+	for j, valor := range fila {
+		if v := strings.TrimSpace(valor); v != "" {
+			return v, j
+		}
+	}
+	return "", -1
+}
+
+func siguienteTexto(fila []string, j int) string {
+	for _, valor := range fila[j+1:] {
+		if v := strings.TrimSpace(valor); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func celdasConTexto(fila []string) int {
+	total := 0
+	for _, valor := range fila {
+		if strings.TrimSpace(valor) != "" {
+			total++
+		}
+	}
+	return total
+}
+
+func celda(fila []string, j int) string {
+	if j < 0 || j >= len(fila) {
+		return ""
+	}
+	return strings.TrimSpace(fila[j])
+}
+
+func ultimoNumero(fila []string) (costing.Decimal, bool) {
+	for j := len(fila) - 1; j >= 0; j-- {
+		if valor, ok := parsearDecimal(fila[j]); ok {
+			return valor, true
+		}
+	}
+	return costing.Decimal{}, false
+}
+
+// normalizar pasa a minúsculas, quita tildes y espacios repetidos para comparar etiquetas
+func normalizar(texto string) string {
+	return strings.Join(strings.Fields(normalizadorTexto.Replace(strings.ToLower(texto))), " ")
+}
+
+// parsearNumero acepta valores crudos de Excel y textos con separador de miles "1,234.50" o "1.234,50"
+func parsearNumero(texto string) (float64, bool) {
+	texto, ok := textoNumerico(texto)
+	if !ok {
+		return 0, false
+	}
+	valor, err := strconv.ParseFloat(texto, 64)
+	if err != nil {
+		return 0, false
+	}
+	return valor, true
+}
+
+// parsearDecimal es parsearNumero para precios, cantidades y parciales, sin pasar por float64
+func parsearDecimal(texto string) (costing.Decimal, bool) {
+	texto, ok := textoNumerico(texto)
+	if !ok {
+		return costing.Decimal{}, false
+	}
+	valor, err := costing.ParsearDecimal(texto)
+	if err != nil {
+		return costing.Decimal{}, false
+	}
+	return valor, true
+}
+
+// textoNumerico quita moneda y separador de miles y deja el punto como separador decimal
+func textoNumerico(texto string) (string, bool) {
+	texto = strings.TrimSpace(strings.NewReplacer("S/.", "", "S/", "", " ", "").Replace(texto))
+	if texto == "" || texto == "-" {
+		return "", false
+	}
+
+	coma, punto := strings.LastIndex(texto, ","), strings.LastIndex(texto, ".")
+	switch {
+	case coma >= 0 && punto >= 0 && coma > punto:
+		texto = strings.ReplaceAll(texto, ".", "")
+		texto = strings.Replace(texto, ",", ".", 1)
+	case coma >= 0 && punto >= 0:
+		texto = strings.ReplaceAll(texto, ",", "")
+	case coma >= 0 && len(texto)-coma-1 != 3:
+		texto = strings.Replace(texto, ",", ".", 1)
+	case coma >= 0:
+		texto = strings.ReplaceAll(texto, ",", "")
+	}
+	return texto, true
+}
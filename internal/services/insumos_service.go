@@ -0,0 +1,377 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/xuri/excelize/v2"
+	"goexcel/internal/costing"
+	"goexcel/internal/legacy"
+	"goexcel/internal/models"
+)
+
+// tiposRecursoOrden define el orden de presentación de los grupos de insumos
+var tiposRecursoOrden = []string{"mano_obra", "materiales", "equipos", "subcontratos"}
+
+// nombresTipoRecurso define el título de cada grupo de insumos
+var nombresTipoRecurso = map[string]string{
+	"mano_obra":    "MANO DE OBRA",
+	"materiales":   "MATERIALES",
+	"equipos":      "EQUIPOS",
+	"subcontratos": "SUBCONTRATOS",
+}
+
+type InsumosService struct {
+	db         *sql.DB
+	calculoSvc *CalculoService
+}
+
+func NewInsumosService(db *sql.DB, calculoSvc *CalculoService) *InsumosService {
+	return &InsumosService{db: db, calculoSvc: calculoSvc}
+}
+
+// ObtenerRelacionPorProyecto consolida los insumos de todas las partidas con metrado de un proyecto
+func (s *InsumosService) ObtenerRelacionPorProyecto(proyectoID uuid.UUID) (*models.RelacionInsumos, error) {
+	reporte, err := s.calculoSvc.ReporteDeProyecto(proyectoID)
+	if err != nil {
+		return nil, err
+	}
+	relacion, err := s.RelacionDeReporte(reporte)
+	if err != nil {
+		return nil, err
+	}
+	relacion.ProyectoID = &proyectoID
+	return relacion, nil
+}
+
+// ObtenerRelacionPorPresupuesto consolida los insumos de todas las partidas con metrado de un presupuesto
+func (s *InsumosService) ObtenerRelacionPorPresupuesto(presupuestoID uuid.UUID) (*models.RelacionInsumos, error) {
+	reporte, err := s.calculoSvc.ReporteDePresupuesto(presupuestoID)
+	if err != nil {
+		return nil, err
+	}
+	relacion, err := s.RelacionDeReporte(reporte)
+	if err != nil {
+		return nil, err
+	}
+	relacion.PresupuestoID = &presupuestoID
+	return relacion, nil
+}
+
+// RelacionDeReporte consolida los insumos del reporte y completa el id y el índice unificado de los
+// recursos que están en el catálogo
+func (s *InsumosService) RelacionDeReporte(reporte *models.ReportePresupuesto) (*models.RelacionInsumos, error) {
+	relacion := ConsolidarInsumos(reporte)
+
+	var codigos []string
+	for _, grupo := range relacion.Grupos {
+		for _, insumo := range grupo.Insumos {
+			codigos = append(codigos, insumo.Codigo)
+		}
+	}
+	if len(codigos) == 0 {
+		return relacion, nil
+	}
+
+	rows, err := s.db.Query(`SELECT id, codigo, indice_unificado FROM recursos WHERE codigo = ANY($1)`, pq.Array(codigos))
+	if err != nil {
+		return nil, fmt.Errorf("error consultando recursos de los insumos: %v", err)
+	}
+	defer rows.Close()
+
+	type datosRecurso struct {
+		id     uuid.UUID
+		indice *string
+	}
+	recursos := make(map[string]datosRecurso)
+	for rows.Next() {
+		var codigo string
+		var recurso datosRecurso
+		if err := rows.Scan(&recurso.id, &codigo, &recurso.indice); err != nil {
+			return nil, fmt.Errorf("error escaneando recurso: %v", err)
+		}
+		recursos[codigo] = recurso
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error leyendo recursos: %v", err)
+	}
+
+	for i := range relacion.Grupos {
+		for j := range relacion.Grupos[i].Insumos {
+			insumo := &relacion.Grupos[i].Insumos[j]
+			if recurso, existe := recursos[insumo.Codigo]; existe {
+				insumo.RecursoID = recurso.id
+				insumo.IndiceUnificado = recurso.indice
+			}
+		}
+	}
+
+	return relacion, nil
+}
+
+// ConsolidarInsumos calcula Σ metrado × cantidad de cada recurso en las partidas con metrado del reporte.
+// El costo de cada insumo es Σ metrado × parcial del recurso en el APU, así que lleva los mismos precios
+// convertidos, desperdicios y flete que el reporte.
+//
+// La conciliación compara, antes de redondear, la suma de los costos de los insumos con Σ metrado × costo
+// unitario de las partidas. Ninguno de los dos lados redondea, así que deben ser iguales: cualquier
+// diferencia es un recurso perdido o contado dos veces, no un redondeo. Diferencia informa además cuánto
+// se separa el total de insumos mostrado del costo directo por redondear cada insumo y cada partida.
+func ConsolidarInsumos(reporte *models.ReportePresupuesto) *models.RelacionInsumos {
+	reglas := reporte.Opciones.ParametrosCalculo().Reglas()
+
+	porTipo := make(map[string][]*models.InsumoConsolidado)
+	porCodigo := make(map[string]*models.InsumoConsolidado)
+	costoPartidas := costing.Decimal{} // Σ metrado × costo unitario, sin redondear
+	for _, partida := range reporte.Partidas {
+		if partida.Metrado.EsCero() {
+			continue
+		}
+		costoPartidas = costoPartidas.Sumar(partida.Metrado.Multiplicar(partida.CostoUnitario))
+		for _, seccion := range partida.Secciones {
+			for _, recurso := range seccion.Recursos {
+				clave := seccion.Tipo + "|" + recurso.Codigo
+				insumo, existe := porCodigo[clave]
+				if !existe {
+					insumo = &models.InsumoConsolidado{
+						Codigo:      recurso.Codigo,
+						Descripcion: recurso.Descripcion,
+						Unidad:      recurso.Unidad,
+						TipoRecurso: seccion.Tipo,
+					}
+					porCodigo[clave] = insumo
+					porTipo[seccion.Tipo] = append(porTipo[seccion.Tipo], insumo)
+				}
+				insumo.Cantidad = insumo.Cantidad.Sumar(partida.Metrado.Multiplicar(recurso.Cantidad.Redondear(reglas.Cantidad)))
+				insumo.CostoTotal = insumo.CostoTotal.Sumar(partida.Metrado.Multiplicar(recurso.Parcial))
+			}
+		}
+	}
+
+	relacion := &models.RelacionInsumos{Grupos: []models.GrupoInsumos{}}
+	costoInsumos := costing.Decimal{} // Σ costos de los insumos, sin redondear
+	for _, tipo := range tiposRecursoOrden {
+		insumos, existe := porTipo[tipo]
+		if !existe {
+			continue
+		}
+		sort.SliceStable(insumos, func(i, j int) bool {
+			if insumos[i].Descripcion != insumos[j].Descripcion {
+				return insumos[i].Descripcion < insumos[j].Descripcion
+			}
+			return insumos[i].Codigo < insumos[j].Codigo
+		})
+
+		grupo := models.GrupoInsumos{
+			TipoRecurso: tipo,
+			Nombre:      nombresTipoRecurso[tipo],
+		}
+		for _, insumo := range insumos {
+			costoInsumos = costoInsumos.Sumar(insumo.CostoTotal)
+			insumo.Cantidad = insumo.Cantidad.Redondear(reglas.Cantidad)
+			insumo.CostoTotal = insumo.CostoTotal.Redondear(reglas.Parcial)
+
+			// El precio de uso puede variar entre partidas: se reporta el precio promedio ponderado
+			if !insumo.Cantidad.EsCero() {
+				insumo.Precio = insumo.CostoTotal.Dividir(insumo.Cantidad, reglas.Precio)
+			}
+
+			grupo.Insumos = append(grupo.Insumos, *insumo)
+			grupo.Subtotal = reglas.Sumar(grupo.Subtotal, insumo.CostoTotal)
+		}
+
+		relacion.Grupos = append(relacion.Grupos, grupo)
+		relacion.TotalInsumos = reglas.Sumar(relacion.TotalInsumos, grupo.Subtotal)
+	}
+
+	relacion.CostoDirecto = reporte.Pie.CostoDirecto
+	relacion.Diferencia = relacion.TotalInsumos.Restar(relacion.CostoDirecto)
+	relacion.Conciliado = costoInsumos.Igual(costoPartidas)
+
+	return relacion
+}
+
+// EscribirCSV escribe la relación de insumos en formato CSV
+func (s *InsumosService) EscribirCSV(w io.Writer, relacion *models.RelacionInsumos) error {
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write([]string{"tipo_recurso", "codigo", "descripcion", "unidad", "cantidad", "precio", "parcial"}); err != nil {
+		return fmt.Errorf("error escribiendo cabecera CSV: %v", err)
+	}
+
+	for _, grupo := range relacion.Grupos {
+		for _, insumo := range grupo.Insumos {
+			registro := []string{
+				grupo.TipoRecurso,
+				insumo.Codigo,
+				insumo.Descripcion,
+				insumo.Unidad,
+				insumo.Cantidad.StringFijo(4),
+				insumo.Precio.StringFijo(2),
+				insumo.CostoTotal.StringFijo(2),
+			}
+			if err := writer.Write(registro); err != nil {
+				return fmt.Errorf("error escribiendo insumo %s: %v", insumo.Codigo, err)
+			}
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// AgregarHojaInsumos agrega la hoja "Insumos" agrupada por tipo de recurso a un libro existente; los
+// parámetros del proyecto dan la moneda de las cabeceras y la plantilla resuelta, los colores y la fuente
+func (s *InsumosService) AgregarHojaInsumos(f *excelize.File, relacion *models.RelacionInsumos, parametros models.Parametros, plantilla models.PlantillaExcel) error {
+	sheet := "Insumos"
+	if _, err := f.NewSheet(sheet); err != nil {
+		return fmt.Errorf("error creando hoja de insumos: %v", err)
+	}
+	tamanoDatos := legacy.TamanoDatos(plantilla, 9)
+
+	// Configurar columnas
+	f.SetColWidth(sheet, "A", "A", 12)
+	f.SetColWidth(sheet, "B", "B", 50)
+	f.SetColWidth(sheet, "C", "C", 8)
+	f.SetColWidth(sheet, "D", "D", 15)
+	f.SetColWidth(sheet, "E", "E", 12)
+	f.SetColWidth(sheet, "F", "F", 18)
+
+	bordes := []excelize.Border{
+		{Type: "left", Color: "#000000", Style: 1},
+		{Type: "right", Color: "#000000", Style: 1},
+		{Type: "top", Color: "#000000", Style: 1},
+		{Type: "bottom", Color: "#000000", Style: 1},
+	}
+
+	tituloStyle, _ := f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Bold: true, Size: 14, Color: "#FFFFFF", Family: plantilla.Fuente},
+		Fill:      excelize.Fill{Type: "pattern", Color: []string{plantilla.ColorTitulo}, Pattern: 1},
+		Alignment: &excelize.Alignment{Horizontal: "center", Vertical: "center"},
+	})
+
+	cabeceraStyle, _ := f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Bold: true, Size: 10, Color: "#FFFFFF", Family: plantilla.Fuente},
+		Fill:      excelize.Fill{Type: "pattern", Color: []string{plantilla.ColorCabecera}, Pattern: 1},
+		Alignment: &excelize.Alignment{Horizontal: "center", Vertical: "center"},
+		Border:    bordes,
+	})
+
+	grupoStyle, _ := f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Bold: true, Size: 10, Family: plantilla.Fuente},
+		Fill:      excelize.Fill{Type: "pattern", Color: []string{plantilla.ColorSeccion}, Pattern: 1},
+		Alignment: &excelize.Alignment{Horizontal: "left", Vertical: "center"},
+		Border:    bordes,
+	})
+
+	datosStyle, _ := f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Size: tamanoDatos, Family: plantilla.Fuente},
+		Alignment: &excelize.Alignment{Horizontal: "left", Vertical: "center"},
+		Border:    bordes,
+	})
+
+	numeroStyle, _ := f.NewStyle(&excelize.Style{
+		Font:         &excelize.Font{Size: tamanoDatos, Family: plantilla.Fuente},
+		CustomNumFmt: &plantilla.FormatoNumero,
+		Alignment:    &excelize.Alignment{Horizontal: "right", Vertical: "center"},
+		Border:       bordes,
+	})
+
+	subtotalStyle, _ := f.NewStyle(&excelize.Style{
+		Font:         &excelize.Font{Bold: true, Size: 10, Family: plantilla.Fuente},
+		Fill:         excelize.Fill{Type: "pattern", Color: []string{plantilla.ColorSeccion}, Pattern: 1},
+		CustomNumFmt: &plantilla.FormatoNumero,
+		Alignment:    &excelize.Alignment{Horizontal: "right", Vertical: "center"},
+		Border:       bordes,
+	})
+
+	totalStyle, _ := f.NewStyle(&excelize.Style{
+		Font:         &excelize.Font{Bold: true, Size: 11, Color: "#FFFFFF", Family: plantilla.Fuente},
+		Fill:         excelize.Fill{Type: "pattern", Color: []string{plantilla.ColorTotal}, Pattern: 1},
+		CustomNumFmt: &plantilla.FormatoNumero,
+		Alignment:    &excelize.Alignment{Horizontal: "right", Vertical: "center"},
+		Border:       bordes,
+	})
+
+	alertaStyle, _ := f.NewStyle(&excelize.Style{
+		Font:         &excelize.Font{Bold: true, Size: 11, Color: "#FFFFFF", Family: plantilla.Fuente},
+		Fill:         excelize.Fill{Type: "pattern", Color: []string{"#C00000"}, Pattern: 1},
+		CustomNumFmt: &plantilla.FormatoNumero,
+		Alignment:    &excelize.Alignment{Horizontal: "right", Vertical: "center"},
+		Border:       bordes,
+	})
+
+	// Título principal
+	f.MergeCell(sheet, "A1", "F1")
+	f.SetCellValue(sheet, "A1", "RELACIÓN DE INSUMOS")
+	f.SetCellStyle(sheet, "A1", "F1", tituloStyle)
+	f.MergeCell(sheet, "A2", "F2")
+	f.SetCellValue(sheet, "A2", legacy.TextoParametros(parametros))
+
+	// Cabeceras
+	row := 3
+	headers := []string{"Código", "Descripción", "Und.", "Cantidad", "Precio " + parametros.SimboloMoneda(), "Parcial " + parametros.SimboloMoneda()}
+	for i, header := range headers {
+		f.SetCellValue(sheet, fmt.Sprintf("%c%d", 'A'+i, row), header)
+		f.SetCellStyle(sheet, fmt.Sprintf("%c%d", 'A'+i, row), fmt.Sprintf("%c%d", 'A'+i, row), cabeceraStyle)
+	}
+	row++
+
+	for _, grupo := range relacion.Grupos {
+		// Encabezado del grupo
+		f.MergeCell(sheet, fmt.Sprintf("A%d", row), fmt.Sprintf("F%d", row))
+		f.SetCellValue(sheet, fmt.Sprintf("A%d", row), grupo.Nombre)
+		f.SetCellStyle(sheet, fmt.Sprintf("A%d", row), fmt.Sprintf("F%d", row), grupoStyle)
+		row++
+
+		for _, insumo := range grupo.Insumos {
+			f.SetCellValue(sheet, fmt.Sprintf("A%d", row), insumo.Codigo)
+			f.SetCellValue(sheet, fmt.Sprintf("B%d", row), insumo.Descripcion)
+			f.SetCellValue(sheet, fmt.Sprintf("C%d", row), insumo.Unidad)
+			f.SetCellValue(sheet, fmt.Sprintf("D%d", row), insumo.Cantidad.Float64())
+			f.SetCellValue(sheet, fmt.Sprintf("E%d", row), insumo.Precio.Float64())
+			f.SetCellValue(sheet, fmt.Sprintf("F%d", row), insumo.CostoTotal.Float64())
+
+			f.SetCellStyle(sheet, fmt.Sprintf("A%d", row), fmt.Sprintf("C%d", row), datosStyle)
+			f.SetCellStyle(sheet, fmt.Sprintf("D%d", row), fmt.Sprintf("F%d", row), numeroStyle)
+			row++
+		}
+
+		// Subtotal del grupo
+		f.MergeCell(sheet, fmt.Sprintf("A%d", row), fmt.Sprintf("E%d", row))
+		f.SetCellValue(sheet, fmt.Sprintf("A%d", row), fmt.Sprintf("SUBTOTAL %s", grupo.Nombre))
+		f.SetCellValue(sheet, fmt.Sprintf("F%d", row), grupo.Subtotal.Float64())
+		f.SetCellStyle(sheet, fmt.Sprintf("A%d", row), fmt.Sprintf("F%d", row), subtotalStyle)
+		row++
+	}
+
+	// Totales y conciliación
+	row++
+	f.MergeCell(sheet, fmt.Sprintf("A%d", row), fmt.Sprintf("E%d", row))
+	f.SetCellValue(sheet, fmt.Sprintf("A%d", row), "TOTAL INSUMOS")
+	f.SetCellValue(sheet, fmt.Sprintf("F%d", row), relacion.TotalInsumos.Float64())
+	f.SetCellStyle(sheet, fmt.Sprintf("A%d", row), fmt.Sprintf("F%d", row), totalStyle)
+	row++
+
+	f.MergeCell(sheet, fmt.Sprintf("A%d", row), fmt.Sprintf("E%d", row))
+	f.SetCellValue(sheet, fmt.Sprintf("A%d", row), "COSTO DIRECTO")
+	f.SetCellValue(sheet, fmt.Sprintf("F%d", row), relacion.CostoDirecto.Float64())
+	f.SetCellStyle(sheet, fmt.Sprintf("A%d", row), fmt.Sprintf("F%d", row), totalStyle)
+	row++
+
+	estiloDiferencia := totalStyle
+	if !relacion.Conciliado {
+		estiloDiferencia = alertaStyle
+	}
+	f.MergeCell(sheet, fmt.Sprintf("A%d", row), fmt.Sprintf("E%d", row))
+	f.SetCellValue(sheet, fmt.Sprintf("A%d", row), "DIFERENCIA (INSUMOS - COSTO DIRECTO)")
+	f.SetCellValue(sheet, fmt.Sprintf("F%d", row), relacion.Diferencia.Float64())
+	f.SetCellStyle(sheet, fmt.Sprintf("A%d", row), fmt.Sprintf("F%d", row), estiloDiferencia)
+
+	return nil
+}
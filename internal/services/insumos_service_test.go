@@ -0,0 +1,54 @@
+package services
+
+import (
+	"testing"
+
+	"goexcel/internal/costing"
+	"goexcel/internal/models"
+)
+
+// reporteInsumos arma un reporte de una partida con dos materiales cuyos parciales suman el costo
+// unitario indicado
+func reporteInsumos(metrado, costoUnitario string) *models.ReportePresupuesto {
+	recursos := []models.RecursoReporte{
+		{Codigo: "M1", Descripcion: "CEMENTO", Unidad: "bol", Cantidad: costing.DebeParsear("0.2156"), Precio: costing.DebeParsear("28.70"), Parcial: costing.DebeParsear("6.19")},
+		{Codigo: "M2", Descripcion: "ARENA", Unidad: "m3", Cantidad: costing.DebeParsear("0.0333"), Precio: costing.DebeParsear("45.00"), Parcial: costing.DebeParsear("1.50")},
+	}
+	partida := &models.PartidaReporte{
+		Codigo:        "01.01",
+		Metrado:       costing.DebeParsear(metrado),
+		CostoUnitario: costing.DebeParsear(costoUnitario),
+		Secciones:     []models.SeccionReporte{{Tipo: "materiales", Recursos: recursos}},
+	}
+	partida.Parcial = costing.ParcialPartida(partida.Metrado, partida.CostoUnitario)
+	return &models.ReportePresupuesto{
+		Partidas: []*models.PartidaReporte{partida},
+		Pie:      models.PiePresupuesto{CostoDirecto: partida.Parcial},
+	}
+}
+
+func TestConsolidarInsumosConciliacion(t *testing.T) {
+	casos := []struct {
+		nombre        string
+		costoUnitario string
+		conciliado    bool
+		diferencia    string
+	}{
+		// 123.456 × 6.19 = 764.19264 y 123.456 × 1.50 = 185.184: los insumos redondeados suman
+		// 764.19 + 185.18 = 949.37 y la partida 123.456 × 7.69 = 949.37664 → 949.38
+		{"los redondeos no rompen la conciliación", "7.69", true, "-0.01"},
+		// El costo unitario incluye un recurso de 0.50 que no aparece en el APU
+		{"recurso perdido", "8.19", false, "-61.73"},
+	}
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			relacion := ConsolidarInsumos(reporteInsumos("123.456", caso.costoUnitario))
+			if relacion.Conciliado != caso.conciliado {
+				t.Errorf("conciliado = %v, se esperaba %v", relacion.Conciliado, caso.conciliado)
+			}
+			if esperada := costing.DebeParsear(caso.diferencia); !relacion.Diferencia.Igual(esperada) {
+				t.Errorf("diferencia = %s, se esperaba %s", relacion.Diferencia, esperada)
+			}
+		})
+	}
+}
@@ -0,0 +1,410 @@
+package services
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"goexcel/internal/costing"
+	"goexcel/internal/database/repositories"
+	"goexcel/internal/legacy"
+	"goexcel/internal/models"
+)
+
+// columnasPreciosCSV es la cabecera esperada del CSV de precios de una lista; moneda es opcional
+var columnasPreciosCSV = []string{"codigo", "precio", "moneda"}
+
+// redondeoVariacion redondea la variación porcentual de un precio en el informe de reprecio
+var redondeoVariacion = costing.Redondeo{Decimales: 2, Modo: costing.MitadArriba}
+
+// ListaPreciosService gestiona las listas de precios regionales y el reprecio de proyectos con ellas
+type ListaPreciosService struct {
+	listaPreciosRepo *repositories.ListaPreciosRepository
+	calculoSvc       *CalculoService
+}
+
+func NewListaPreciosService(listaPreciosRepo *repositories.ListaPreciosRepository, calculoSvc *CalculoService) *ListaPreciosService {
+	return &ListaPreciosService{listaPreciosRepo: listaPreciosRepo, calculoSvc: calculoSvc}
+}
+
+// Listar devuelve las listas de la organización, sin sus precios
+func (s *ListaPreciosService) Listar(organizacionID uuid.UUID) ([]models.ListaPrecios, error) {
+	return s.listaPreciosRepo.Listar(organizacionID)
+}
+
+// Obtener devuelve la lista con sus precios si pertenece a la organización
+func (s *ListaPreciosService) Obtener(id, organizacionID uuid.UUID) (*models.ListaPrecios, error) {
+	lista, err := s.deOrganizacion(id, organizacionID)
+	if err != nil {
+		return nil, err
+	}
+	if lista.Precios, err = s.listaPreciosRepo.ObtenerPrecios(id); err != nil {
+		return nil, err
+	}
+	return lista, nil
+}
+
+// Crear valida y guarda una lista nueva con sus precios iniciales
+func (s *ListaPreciosService) Crear(req models.ListaPreciosRequest, organizacionID uuid.UUID) (*models.ListaPrecios, error) {
+	lista := &models.ListaPrecios{OrganizacionID: organizacionID}
+	if err := aplicarListaPreciosRequest(lista, req); err != nil {
+		return nil, err
+	}
+	precios, err := validarPreciosLista(req.Precios)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.listaPreciosRepo.Crear(lista, precios); err != nil {
+		return nil, err
+	}
+	return lista, nil
+}
+
+// Actualizar modifica nombre, región y vigencia de una lista de la organización; los precios se
+// modifican con GuardarPrecios
+func (s *ListaPreciosService) Actualizar(id, organizacionID uuid.UUID, req models.ListaPreciosRequest) (*models.ListaPrecios, error) {
+	lista, err := s.deOrganizacion(id, organizacionID)
+	if err != nil {
+		return nil, err
+	}
+	if err := aplicarListaPreciosRequest(lista, req); err != nil {
+		return nil, err
+	}
+
+	if err := s.listaPreciosRepo.Actualizar(lista); err != nil {
+		return nil, err
+	}
+	return lista, nil
+}
+
+// Eliminar borra una lista de la organización
+func (s *ListaPreciosService) Eliminar(id, organizacionID uuid.UUID) error {
+	if _, err := s.deOrganizacion(id, organizacionID); err != nil {
+		return err
+	}
+	return s.listaPreciosRepo.Eliminar(id)
+}
+
+// GuardarPrecios crea o reemplaza precios de la lista; los recursos se identifican por código
+func (s *ListaPreciosService) GuardarPrecios(id, organizacionID uuid.UUID, precios []models.PrecioListaRequest) (*models.ListaPrecios, error) {
+	if _, err := s.deOrganizacion(id, organizacionID); err != nil {
+		return nil, err
+	}
+	validos, err := validarPreciosLista(precios)
+	if err != nil {
+		return nil, err
+	}
+	if len(validos) == 0 {
+		return nil, fmt.Errorf("no se enviaron precios")
+	}
+
+	if err := s.listaPreciosRepo.GuardarPrecios(id, validos); err != nil {
+		return nil, err
+	}
+	return s.Obtener(id, organizacionID)
+}
+
+// ImportarCSV lee precios de un CSV con cabecera codigo,precio y moneda opcional y los guarda en la
+// lista. Si una línea es inválida no se guarda ninguna.
+func (s *ListaPreciosService) ImportarCSV(r io.Reader, id, organizacionID uuid.UUID) (*models.ListaPrecios, int, error) {
+	lector := csv.NewReader(r)
+	lector.FieldsPerRecord = -1
+	lector.TrimLeadingSpace = true
+
+	registros, err := lector.ReadAll()
+	if err != nil {
+		return nil, 0, fmt.Errorf("error leyendo CSV: %v", err)
+	}
+	if len(registros) < 2 {
+		return nil, 0, fmt.Errorf("el CSV no tiene precios")
+	}
+
+	cabecera := registros[0]
+	if len(cabecera) < 2 {
+		return nil, 0, fmt.Errorf("cabecera inválida: se esperaba %s", strings.Join(columnasPreciosCSV, ","))
+	}
+	for i, columna := range cabecera {
+		if i >= len(columnasPreciosCSV) || !strings.EqualFold(strings.TrimSpace(columna), columnasPreciosCSV[i]) {
+			return nil, 0, fmt.Errorf("cabecera inválida: se esperaba %s", strings.Join(columnasPreciosCSV, ","))
+		}
+	}
+
+	var precios []models.PrecioListaRequest
+	for i, registro := range registros[1:] {
+		linea := i + 2
+		if len(registro) == 1 && strings.TrimSpace(registro[0]) == "" {
+			continue
+		}
+		if len(registro) < 2 {
+			return nil, 0, fmt.Errorf("línea %d: se esperaban al menos 2 columnas", linea)
+		}
+
+		precio, err := costing.ParsearDecimal(strings.TrimSpace(registro[1]))
+		if err != nil {
+			return nil, 0, fmt.Errorf("línea %d: precio inválido: %s", linea, registro[1])
+		}
+		req := models.PrecioListaRequest{Codigo: registro[0], Precio: precio}
+		if len(registro) > 2 {
+			req.Moneda = registro[2]
+		}
+		if _, err := validarPreciosLista([]models.PrecioListaRequest{req}); err != nil {
+			return nil, 0, fmt.Errorf("línea %d: %v", linea, err)
+		}
+		precios = append(precios, req)
+	}
+
+	lista, err := s.GuardarPrecios(id, organizacionID, precios)
+	if err != nil {
+		return nil, 0, err
+	}
+	return lista, len(precios), nil
+}
+
+// PreciosPorCodigo devuelve los precios de la lista por código de recurso
+func (s *ListaPreciosService) PreciosPorCodigo(id uuid.UUID) (map[string]costing.Decimal, error) {
+	precios, err := s.listaPreciosRepo.ObtenerPrecios(id)
+	if err != nil {
+		return nil, err
+	}
+	porCodigo := make(map[string]costing.Decimal, len(precios))
+	for _, precio := range precios {
+		porCodigo[precio.Codigo] = precio.Precio
+	}
+	return porCodigo, nil
+}
+
+// ListaDeProyecto devuelve la lista asignada al proyecto, sin sus precios, o nil si no tiene
+func (s *ListaPreciosService) ListaDeProyecto(proyectoID uuid.UUID) (*models.ListaPrecios, error) {
+	_, listaID, err := s.listaPreciosRepo.OrganizacionYListaDeProyecto(proyectoID)
+	if err != nil || listaID == nil {
+		return nil, err
+	}
+	return s.listaPreciosRepo.ObtenerPorID(*listaID)
+}
+
+// AsignarAProyecto asigna al proyecto una lista de su organización; nil quita la asignación
+func (s *ListaPreciosService) AsignarAProyecto(proyectoID uuid.UUID, listaID *uuid.UUID) (*models.ListaPrecios, error) {
+	if listaID == nil {
+		return nil, s.listaPreciosRepo.AsignarAProyecto(proyectoID, nil)
+	}
+
+	organizacionID, _, err := s.listaPreciosRepo.OrganizacionYListaDeProyecto(proyectoID)
+	if err != nil {
+		return nil, err
+	}
+	if organizacionID == nil {
+		return nil, fmt.Errorf("el proyecto no pertenece a ninguna organización")
+	}
+	lista, err := s.deOrganizacion(*listaID, *organizacionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.listaPreciosRepo.AsignarAProyecto(proyectoID, listaID); err != nil {
+		return nil, err
+	}
+	return lista, nil
+}
+
+// Repreciar copia a cada recurso de las partidas del proyecto su precio en la lista asignada y
+// devuelve qué cambió y cuánto. Los recursos que no están en la lista conservan su precio. Con
+// simular en true se calcula el informe sin guardar los cambios. El costo directo antes y después se
+// calcula como en los reportes, con los parámetros, tipos de cambio y flete del proyecto.
+func (s *ListaPreciosService) Repreciar(proyectoID uuid.UUID, simular bool) (*models.ReprecioProyecto, error) {
+	lista, err := s.ListaDeProyecto(proyectoID)
+	if err != nil {
+		return nil, err
+	}
+	if lista == nil {
+		return nil, fmt.Errorf("el proyecto no tiene una lista de precios asignada")
+	}
+
+	usos, err := s.listaPreciosRepo.UsosEnProyecto(proyectoID, lista.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	informe := &models.ReprecioProyecto{
+		ProyectoID:   proyectoID,
+		ListaPrecios: *lista,
+		Fecha:        time.Now(),
+		Simulado:     simular,
+		ListaVigente: lista.VigenteEn(time.Now()),
+		Cambios:      []models.CambioPrecio{},
+		SinPrecio:    []models.RecursoSinPrecio{},
+	}
+
+	var actualizaciones []models.ActualizacionPrecio
+	nuevos := make(map[string]models.ActualizacionPrecio) // por código de partida y de recurso
+	partidas := make(map[uuid.UUID]bool)
+	sinPrecio := make(map[string]int) // posición en informe.SinPrecio por código
+	for _, uso := range usos {
+		if uso.PrecioLista == nil {
+			if i, existe := sinPrecio[uso.RecursoCodigo]; existe {
+				informe.SinPrecio[i].Partidas++
+				continue
+			}
+			sinPrecio[uso.RecursoCodigo] = len(informe.SinPrecio)
+			informe.SinPrecio = append(informe.SinPrecio, models.RecursoSinPrecio{
+				Codigo:      uso.RecursoCodigo,
+				Descripcion: uso.Descripcion,
+				Unidad:      uso.Unidad,
+				Partidas:    1,
+			})
+			continue
+		}
+		if uso.PrecioLista.Igual(uso.Precio) && uso.MonedaLista == uso.Moneda {
+			continue
+		}
+
+		actualizacion := models.ActualizacionPrecio{
+			PartidaRecursoID: uso.PartidaRecursoID,
+			PartidaID:        uso.PartidaID,
+			Precio:           *uso.PrecioLista,
+			Moneda:           uso.MonedaLista,
+		}
+		actualizaciones = append(actualizaciones, actualizacion)
+		nuevos[uso.PartidaCodigo+"|"+uso.RecursoCodigo] = actualizacion
+		partidas[uso.PartidaID] = true
+		informe.Cambios = append(informe.Cambios, cambioPrecio(uso))
+	}
+	informe.RecursosActualizados = len(actualizaciones)
+	informe.PartidasAfectadas = len(partidas)
+
+	datos, err := s.calculoSvc.DatosDeProyecto(proyectoID)
+	if err != nil {
+		return nil, err
+	}
+	informe.CostoDirectoAnterior = ConstruirReporte(datos).Pie.CostoDirecto
+
+	datos.Partidas = conPreciosNuevos(datos.Partidas, nuevos)
+	if err := s.calculoSvc.TasasDeProyecto(proyectoID, &datos); err != nil {
+		return nil, err
+	}
+	informe.CostoDirectoNuevo = ConstruirReporte(datos).Pie.CostoDirecto
+	informe.Diferencia = informe.CostoDirectoNuevo.Restar(informe.CostoDirectoAnterior)
+
+	if !simular {
+		if err := s.listaPreciosRepo.AplicarPrecios(actualizaciones); err != nil {
+			return nil, err
+		}
+	}
+	return informe, nil
+}
+
+// conPreciosNuevos copia las partidas con el precio y la moneda de la lista en los recursos que cambian,
+// sin tocar las partidas originales
+func conPreciosNuevos(partidas []legacy.PartidaLegacy, nuevos map[string]models.ActualizacionPrecio) []legacy.PartidaLegacy {
+	copia := make([]legacy.PartidaLegacy, len(partidas))
+	for i, partida := range partidas {
+		copia[i] = partida
+		for _, recursos := range []*[]legacy.RecursoLegacy{&copia[i].ManoObra, &copia[i].Materiales, &copia[i].Equipos, &copia[i].Subcontratos} {
+			repreciados := append([]legacy.RecursoLegacy(nil), (*recursos)...)
+			for j := range repreciados {
+				if nuevo, existe := nuevos[partida.Codigo+"|"+repreciados[j].Codigo]; existe {
+					repreciados[j].Precio = nuevo.Precio
+					repreciados[j].Moneda = nuevo.Moneda
+				}
+			}
+			*recursos = repreciados
+		}
+	}
+	return copia
+}
+
+// deOrganizacion obtiene la cabecera de la lista y comprueba que sea de la organización
+func (s *ListaPreciosService) deOrganizacion(id, organizacionID uuid.UUID) (*models.ListaPrecios, error) {
+	lista, err := s.listaPreciosRepo.ObtenerPorID(id)
+	if err != nil {
+		return nil, err
+	}
+	if lista == nil || lista.OrganizacionID != organizacionID {
+		return nil, fmt.Errorf("lista de precios no encontrada")
+	}
+	return lista, nil
+}
+
+// cambioPrecio describe el cambio de precio de un recurso en una partida; la diferencia solo se
+// calcula si el precio sigue en la misma moneda
+func cambioPrecio(uso models.UsoRecurso) models.CambioPrecio {
+	cambio := models.CambioPrecio{
+		PartidaCodigo:      uso.PartidaCodigo,
+		PartidaDescripcion: uso.PartidaDescripcion,
+		RecursoCodigo:      uso.RecursoCodigo,
+		Descripcion:        uso.Descripcion,
+		Unidad:             uso.Unidad,
+		Cantidad:           uso.Cantidad,
+		PrecioAnterior:     uso.Precio,
+		PrecioNuevo:        *uso.PrecioLista,
+		MonedaAnterior:     uso.Moneda,
+		MonedaNueva:        uso.MonedaLista,
+	}
+	if uso.Moneda != uso.MonedaLista {
+		return cambio
+	}
+
+	diferencia := cambio.PrecioNuevo.Restar(cambio.PrecioAnterior)
+	impacto := costing.ParcialRecurso(uso.Cantidad, cambio.PrecioNuevo).Restar(costing.ParcialRecurso(uso.Cantidad, cambio.PrecioAnterior))
+	cambio.Diferencia = &diferencia
+	cambio.ImpactoUnitario = &impacto
+	if !cambio.PrecioAnterior.EsCero() {
+		variacion := diferencia.Multiplicar(costing.NuevoDecimal(100, 0)).Dividir(cambio.PrecioAnterior, redondeoVariacion)
+		cambio.VariacionPorcentaje = &variacion
+	}
+	return cambio
+}
+
+// aplicarListaPreciosRequest valida la cabecera de una lista y la copia
+func aplicarListaPreciosRequest(lista *models.ListaPrecios, req models.ListaPreciosRequest) error {
+	nombre := strings.TrimSpace(req.Nombre)
+	region := strings.TrimSpace(req.Region)
+	if nombre == "" || region == "" {
+		return fmt.Errorf("nombre y región son obligatorios")
+	}
+	if _, err := time.Parse(models.FormatoFecha, req.VigenteDesde); err != nil {
+		return fmt.Errorf("vigente_desde debe tener el formato AAAA-MM-DD")
+	}
+	if req.VigenteHasta != nil {
+		if _, err := time.Parse(models.FormatoFecha, *req.VigenteHasta); err != nil {
+			return fmt.Errorf("vigente_hasta debe tener el formato AAAA-MM-DD")
+		}
+		if *req.VigenteHasta < req.VigenteDesde {
+			return fmt.Errorf("vigente_hasta no puede ser anterior a vigente_desde")
+		}
+	}
+
+	lista.Nombre = nombre
+	lista.Region = region
+	lista.VigenteDesde = req.VigenteDesde
+	lista.VigenteHasta = req.VigenteHasta
+	return nil
+}
+
+// validarPreciosLista revisa código, precio y moneda de cada precio y normaliza código y moneda
+func validarPreciosLista(precios []models.PrecioListaRequest) ([]models.PrecioListaRequest, error) {
+	validos := make([]models.PrecioListaRequest, 0, len(precios))
+	vistos := make(map[string]bool)
+	for _, precio := range precios {
+		precio.Codigo = strings.TrimSpace(precio.Codigo)
+		precio.Moneda = strings.ToUpper(strings.TrimSpace(precio.Moneda))
+		if precio.Codigo == "" {
+			return nil, fmt.Errorf("cada precio debe indicar el código del recurso")
+		}
+		if precio.Precio.Signo() < 0 {
+			return nil, fmt.Errorf("el precio de %s no puede ser negativo", precio.Codigo)
+		}
+		if precio.Moneda != "" && !esCodigoMoneda(precio.Moneda) {
+			return nil, fmt.Errorf("la moneda de %s debe ser un código ISO 4217 de 3 letras", precio.Codigo)
+		}
+		if vistos[precio.Codigo] {
+			return nil, fmt.Errorf("el recurso %s está repetido", precio.Codigo)
+		}
+		vistos[precio.Codigo] = true
+		validos = append(validos, precio)
+	}
+	return validos, nil
+}
@@ -0,0 +1,269 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"goexcel/internal/costing"
+	"goexcel/internal/database/repositories"
+	"goexcel/internal/models"
+)
+
+// sobretasaExtraPorDefecto es la sobretasa de las dos primeras horas extra (D.S. 007-2002-TR)
+var sobretasaExtraPorDefecto = costing.NuevoDecimal(25, 0)
+
+// redondeoCentimos redondea los montos del cálculo de mano de obra, como en la planilla
+var redondeoCentimos = costing.Redondeo{Decimales: 2, Modo: costing.MitadArriba}
+
+// ManoObraService administra los parámetros anuales de construcción civil y calcula con ellos el
+// costo hora-hombre de cada categoría
+type ManoObraService struct {
+	manoObraRepo *repositories.ManoObraRepository
+	recursoRepo  *repositories.RecursoRepository
+}
+
+func NewManoObraService(manoObraRepo *repositories.ManoObraRepository, recursoRepo *repositories.RecursoRepository) *ManoObraService {
+	return &ManoObraService{
+		manoObraRepo: manoObraRepo,
+		recursoRepo:  recursoRepo,
+	}
+}
+
+// Listar devuelve los parámetros de la organización y los globales; sin organización, solo los globales
+func (s *ManoObraService) Listar(organizacionID *uuid.UUID) ([]models.ParametrosManoObra, error) {
+	return s.manoObraRepo.Listar(organizacionID)
+}
+
+// Obtener devuelve un juego de parámetros de la organización o global
+func (s *ManoObraService) Obtener(id uuid.UUID, organizacionID *uuid.UUID) (*models.ParametrosManoObra, error) {
+	p, err := s.manoObraRepo.ObtenerPorID(id)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil || (p.OrganizacionID != nil && (organizacionID == nil || *p.OrganizacionID != *organizacionID)) {
+		return nil, fmt.Errorf("parámetros de mano de obra no encontrados")
+	}
+	return p, nil
+}
+
+// Crear valida y guarda los parámetros de un año; falla si el año ya tiene parámetros
+func (s *ManoObraService) Crear(req models.ParametrosManoObraRequest, organizacionID *uuid.UUID) (*models.ParametrosManoObra, error) {
+	p, err := nuevosParametrosManoObra(req)
+	if err != nil {
+		return nil, err
+	}
+	p.OrganizacionID = organizacionID
+
+	existente, err := s.manoObraRepo.ObtenerPorAnio(organizacionID, p.Anio)
+	if err != nil {
+		return nil, err
+	}
+	if existente != nil {
+		return nil, fmt.Errorf("ya existen parámetros de mano de obra para el año %d", p.Anio)
+	}
+
+	if err := s.manoObraRepo.Crear(p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Actualizar reemplaza los parámetros propios de la organización; los globales solo bajo /admin
+func (s *ManoObraService) Actualizar(id uuid.UUID, organizacionID *uuid.UUID, req models.ParametrosManoObraRequest) (*models.ParametrosManoObra, error) {
+	actual, err := s.manoObraRepo.ObtenerPorID(id)
+	if err != nil {
+		return nil, err
+	}
+	if actual == nil || !mismaOrganizacion(actual.OrganizacionID, organizacionID) {
+		return nil, fmt.Errorf("parámetros de mano de obra no encontrados")
+	}
+
+	p, err := nuevosParametrosManoObra(req)
+	if err != nil {
+		return nil, err
+	}
+	if p.Anio != actual.Anio {
+		existente, err := s.manoObraRepo.ObtenerPorAnio(organizacionID, p.Anio)
+		if err != nil {
+			return nil, err
+		}
+		if existente != nil {
+			return nil, fmt.Errorf("ya existen parámetros de mano de obra para el año %d", p.Anio)
+		}
+	}
+	p.ID = actual.ID
+	p.OrganizacionID = actual.OrganizacionID
+	p.CreatedAt = actual.CreatedAt
+
+	if err := s.manoObraRepo.Actualizar(p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Eliminar borra los parámetros de la organización; sin organización, unos globales
+func (s *ManoObraService) Eliminar(id uuid.UUID, organizacionID *uuid.UUID) error {
+	return s.manoObraRepo.Eliminar(id, organizacionID)
+}
+
+// Calcular desglosa el costo diario de cada categoría y lo divide entre las horas trabajadas:
+//
+//	costo diario = jornal básico + leyes sociales + BUC + movilidad + overol + horas extra
+//	costo hh     = costo diario / (horas de la jornada + horas extra)
+//
+// Leyes sociales y BUC son porcentajes del jornal básico, y cada hora extra vale la hora básica más
+// la sobretasa. Cada monto se redondea a céntimos.
+func (s *ManoObraService) Calcular(p *models.ParametrosManoObra) *models.CalculoManoObra {
+	cien := costing.NuevoDecimal(100, 0)
+	horasDiarias := p.HorasJornada.Sumar(p.HorasExtraDiarias)
+
+	calculo := &models.CalculoManoObra{
+		ParametrosID:      p.ID,
+		Anio:              p.Anio,
+		Descripcion:       p.Descripcion,
+		VigenteDesde:      p.VigenteDesde,
+		HorasJornada:      p.HorasJornada,
+		HorasExtraDiarias: p.HorasExtraDiarias,
+		Categorias:        make([]models.CostoManoObra, 0, len(p.Categorias)),
+	}
+	for _, categoria := range p.Categorias {
+		costo := models.CostoManoObra{
+			Categoria:               categoria.Categoria,
+			CodigoRecurso:           categoria.CodigoRecurso,
+			JornalBasico:            categoria.JornalBasico,
+			PorcentajeLeyesSociales: categoria.PorcentajeLeyesSociales,
+			LeyesSociales:           categoria.JornalBasico.Multiplicar(categoria.PorcentajeLeyesSociales).Dividir(cien, redondeoCentimos),
+			PorcentajeBUC:           categoria.PorcentajeBUC,
+			BUC:                     categoria.JornalBasico.Multiplicar(categoria.PorcentajeBUC).Dividir(cien, redondeoCentimos),
+			Movilidad:               p.MovilidadDiaria,
+			Overol:                  p.OverolDiario,
+			HorasDiarias:            horasDiarias,
+		}
+		costo.HorasExtra = categoria.JornalBasico.
+			Multiplicar(p.HorasExtraDiarias).
+			Multiplicar(cien.Sumar(p.PorcentajeSobretasaExtra)).
+			Dividir(p.HorasJornada.Multiplicar(cien), redondeoCentimos)
+
+		costo.CostoDiario = costo.JornalBasico.
+			Sumar(costo.LeyesSociales).
+			Sumar(costo.BUC).
+			Sumar(costo.Movilidad).
+			Sumar(costo.Overol).
+			Sumar(costo.HorasExtra)
+		costo.CostoHH = costo.CostoDiario.Dividir(horasDiarias, redondeoCentimos)
+
+		calculo.Categorias = append(calculo.Categorias, costo)
+	}
+	return calculo
+}
+
+// PreciosHH son los costos hora-hombre de las categorías que tienen recurso asociado, listos para
+// guardarse en el catálogo o en una lista de precios
+func (s *ManoObraService) PreciosHH(calculo *models.CalculoManoObra) []models.PrecioListaRequest {
+	precios := []models.PrecioListaRequest{}
+	for _, costo := range calculo.Categorias {
+		if costo.CodigoRecurso != "" {
+			precios = append(precios, models.PrecioListaRequest{Codigo: costo.CodigoRecurso, Precio: costo.CostoHH})
+		}
+	}
+	return precios
+}
+
+// AplicarACatalogo guarda los costos hora-hombre como precio base de los recursos del catálogo
+func (s *ManoObraService) AplicarACatalogo(precios []models.PrecioListaRequest) error {
+	if len(precios) == 0 {
+		return fmt.Errorf("ninguna categoría tiene código de recurso")
+	}
+	_, err := s.recursoRepo.ActualizarPreciosBase(precios)
+	return err
+}
+
+// CalculoParaProyecto calcula el costo de mano de obra con los parámetros vigentes a la fecha para el
+// proyecto; devuelve nil si no hay parámetros
+func (s *ManoObraService) CalculoParaProyecto(proyectoID uuid.UUID, fecha time.Time) (*models.CalculoManoObra, error) {
+	p, err := s.manoObraRepo.VigentesParaProyecto(proyectoID, fecha)
+	if err != nil || p == nil {
+		return nil, err
+	}
+	return s.Calcular(p), nil
+}
+
+// nuevosParametrosManoObra valida un juego de parámetros y completa los valores por defecto
+func nuevosParametrosManoObra(req models.ParametrosManoObraRequest) (*models.ParametrosManoObra, error) {
+	if req.Anio < 2000 || req.Anio > 2100 {
+		return nil, fmt.Errorf("anio debe estar entre 2000 y 2100")
+	}
+
+	vigenteDesde := strings.TrimSpace(req.VigenteDesde)
+	if vigenteDesde == "" {
+		vigenteDesde = fmt.Sprintf("%d-06-01", req.Anio)
+	} else if _, err := time.Parse(models.FormatoFecha, vigenteDesde); err != nil {
+		return nil, fmt.Errorf("vigente_desde debe tener el formato AAAA-MM-DD")
+	}
+
+	horasJornada := req.HorasJornada
+	if horasJornada.EsCero() {
+		horasJornada = costing.DecimalDesdeFloat(models.HorasJornadaPorDefecto)
+	}
+	sobretasa := sobretasaExtraPorDefecto
+	if req.PorcentajeSobretasaExtra != nil {
+		sobretasa = *req.PorcentajeSobretasaExtra
+	}
+
+	if horasJornada.Signo() < 0 || req.MovilidadDiaria.Signo() < 0 || req.OverolDiario.Signo() < 0 ||
+		req.HorasExtraDiarias.Signo() < 0 || sobretasa.Signo() < 0 {
+		return nil, fmt.Errorf("los montos, horas y porcentajes no pueden ser negativos")
+	}
+	if len(req.Categorias) == 0 {
+		return nil, fmt.Errorf("debe indicar al menos una categoría")
+	}
+
+	vistas := make(map[string]bool)
+	categorias := make([]models.CategoriaManoObra, 0, len(req.Categorias))
+	for _, c := range req.Categorias {
+		nombre := strings.ToUpper(strings.TrimSpace(c.Categoria))
+		if nombre == "" {
+			return nil, fmt.Errorf("cada categoría debe tener nombre")
+		}
+		if vistas[nombre] {
+			return nil, fmt.Errorf("categoría repetida: %s", nombre)
+		}
+		vistas[nombre] = true
+		if c.JornalBasico.Signo() <= 0 {
+			return nil, fmt.Errorf("%s: el jornal básico debe ser mayor que cero", nombre)
+		}
+		if c.PorcentajeBUC.Signo() < 0 || c.PorcentajeLeyesSociales.Signo() < 0 {
+			return nil, fmt.Errorf("%s: los porcentajes no pueden ser negativos", nombre)
+		}
+
+		categorias = append(categorias, models.CategoriaManoObra{
+			Categoria:               nombre,
+			CodigoRecurso:           strings.TrimSpace(c.CodigoRecurso),
+			JornalBasico:            c.JornalBasico,
+			PorcentajeBUC:           c.PorcentajeBUC,
+			PorcentajeLeyesSociales: c.PorcentajeLeyesSociales,
+		})
+	}
+
+	return &models.ParametrosManoObra{
+		Anio:                     req.Anio,
+		Descripcion:              strings.TrimSpace(req.Descripcion),
+		VigenteDesde:             vigenteDesde,
+		HorasJornada:             horasJornada,
+		MovilidadDiaria:          req.MovilidadDiaria,
+		OverolDiario:             req.OverolDiario,
+		HorasExtraDiarias:        req.HorasExtraDiarias,
+		PorcentajeSobretasaExtra: sobretasa,
+		Categorias:               categorias,
+	}, nil
+}
+
+// mismaOrganizacion indica si dos organizaciones opcionales son la misma; dos nil son lo global
+func mismaOrganizacion(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"testing"
+
+	"goexcel/internal/costing"
+	"goexcel/internal/models"
+)
+
+func TestCalcularCostoHoraHombre(t *testing.T) {
+	d := costing.DebeParsear
+	casos := []struct {
+		nombre     string
+		parametros models.ParametrosManoObra
+		categoria  models.CategoriaManoObra
+		esperado   map[string]string
+	}{
+		{
+			// 83.10 × 111.37 % = 92.54847 → 92.55; 211.47 / 8 = 26.43375 → 26.43
+			nombre: "operario sin horas extra",
+			parametros: models.ParametrosManoObra{
+				HorasJornada: d("8"), MovilidadDiaria: d("8"), OverolDiario: d("1.23"), PorcentajeSobretasaExtra: d("25"),
+			},
+			categoria: models.CategoriaManoObra{Categoria: "OPERARIO", JornalBasico: d("83.10"), PorcentajeBUC: d("32"), PorcentajeLeyesSociales: d("111.37")},
+			esperado: map[string]string{
+				"leyes sociales": "92.55", "buc": "26.59", "horas extra": "0", "costo diario": "211.47", "costo hh": "26.43",
+			},
+		},
+		{
+			// Dos horas extra al 25 %: 62 × 2 × 1.25 / 8 = 19.375 → 19.38, y el día rinde 10 horas
+			nombre: "peón con horas extra",
+			parametros: models.ParametrosManoObra{
+				HorasJornada: d("8"), MovilidadDiaria: d("8"), OverolDiario: d("1.23"),
+				HorasExtraDiarias: d("2"), PorcentajeSobretasaExtra: d("25"),
+			},
+			categoria: models.CategoriaManoObra{Categoria: "PEÓN", JornalBasico: d("62.00"), PorcentajeBUC: d("30"), PorcentajeLeyesSociales: d("111.37")},
+			esperado: map[string]string{
+				"leyes sociales": "69.05", "buc": "18.60", "horas extra": "19.38", "costo diario": "178.26", "costo hh": "17.83",
+			},
+		},
+		{
+			// Sobretasa pactada de 35 %: 68.70 × 1.35 / 8 = 11.593125 → 11.59; 100.90 / 9 = 11.2111… → 11.21
+			nombre: "oficial con sobretasa propia y sin leyes sociales",
+			parametros: models.ParametrosManoObra{
+				HorasJornada: d("8"), HorasExtraDiarias: d("1"), PorcentajeSobretasaExtra: d("35"),
+			},
+			categoria: models.CategoriaManoObra{Categoria: "OFICIAL", JornalBasico: d("68.70"), PorcentajeBUC: d("30")},
+			esperado: map[string]string{
+				"leyes sociales": "0", "buc": "20.61", "horas extra": "11.59", "costo diario": "100.90", "costo hh": "11.21",
+			},
+		},
+	}
+
+	s := &ManoObraService{}
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			caso.parametros.Categorias = []models.CategoriaManoObra{caso.categoria}
+			costo := s.Calcular(&caso.parametros).Categorias[0]
+			obtenidos := map[string]costing.Decimal{
+				"leyes sociales": costo.LeyesSociales, "buc": costo.BUC, "horas extra": costo.HorasExtra,
+				"costo diario": costo.CostoDiario, "costo hh": costo.CostoHH,
+			}
+			for concepto, esperado := range caso.esperado {
+				if !obtenidos[concepto].Igual(d(esperado)) {
+					t.Errorf("%s = %s, se esperaba %s", concepto, obtenidos[concepto], esperado)
+				}
+			}
+		})
+	}
+}
+
+func TestPreciosHHSoloCategoriasConRecurso(t *testing.T) {
+	d := costing.DebeParsear
+	s := &ManoObraService{}
+	calculo := s.Calcular(&models.ParametrosManoObra{
+		HorasJornada: d("8"),
+		Categorias: []models.CategoriaManoObra{
+			{Categoria: "OPERARIO", CodigoRecurso: "470101", JornalBasico: d("80")},
+			{Categoria: "PEÓN", JornalBasico: d("60")},
+		},
+	})
+
+	precios := s.PreciosHH(calculo)
+	if len(precios) != 1 || precios[0].Codigo != "470101" || !precios[0].Precio.Igual(d("10")) {
+		t.Errorf("precios = %+v, se esperaba solo 470101 a 10.00", precios)
+	}
+}
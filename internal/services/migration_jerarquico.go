@@ -12,12 +12,14 @@ import (
 // MigrationJerarquicoService maneja la migración de datos ACU jerárquicos a PostgreSQL
 type MigrationJerarquicoService struct {
 	presupuestoRepo *repositories.PresupuestoRepository
+	parametrosRepo  *repositories.ParametrosRepository
 }
 
 // NewMigrationJerarquicoService crea una nueva instancia del servicio de migración jerárquica
 func NewMigrationJerarquicoService(db *sql.DB) *MigrationJerarquicoService {
 	return &MigrationJerarquicoService{
 		presupuestoRepo: repositories.NewPresupuestoRepository(db),
+		parametrosRepo:  repositories.NewParametrosRepository(db),
 	}
 }
 
@@ -39,6 +41,12 @@ func (s *MigrationJerarquicoService) MigrarACUJerarquico(acuData *models.ACUJera
 
 	fmt.Printf("✅ Presupuesto creado: %s (ID: %s)\n", presupuesto.Nombre, presupuesto.ID.String()[:8])
 
+	if acuData.Presupuesto.Parametros != nil {
+		if err := s.parametrosRepo.GuardarDePresupuesto(presupuesto.ID, acuData.Presupuesto.Parametros); err != nil {
+			return nil, fmt.Errorf("error guardando parámetros del presupuesto: %v", err)
+		}
+	}
+
 	// 2. Crear subpresupuestos
 	subpresupuestoMap := make(map[string]uuid.UUID)
 	for _, subData := range acuData.Subpresupuestos {
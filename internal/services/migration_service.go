@@ -32,9 +32,9 @@ func (s *MigrationService) MigrarDesdeJSON(partidasJSON []legacy.PartidaLegacy,
 	// 1. Crear proyecto
 	proyectoReq := &models.ProyectoCreateRequest{
 		Nombre: fmt.Sprintf("Proyecto Migrado - %s", nombreArchivo),
-		Moneda: "PEN",
+		Moneda: models.MonedaPorDefecto,
 	}
-	
+
 	// Para migración, usar usuario admin por defecto
 	adminUserID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
 	proyecto, err := s.proyectoRepo.Create(proyectoReq, adminUserID)
@@ -135,11 +135,12 @@ func (s *MigrationService) procesarRecursos(partidaID uuid.UUID, recursos []lega
 		}
 
 		partidaRecursoReq := &models.PartidaRecursoCreateRequest{
-			PartidaID: partidaID,
-			RecursoID: recurso.ID,
-			Cantidad:  recursoJSON.Cantidad,
-			Precio:    recursoJSON.Precio,
-			Cuadrilla: cuadrilla,
+			PartidaID:   partidaID,
+			RecursoID:   recurso.ID,
+			Cantidad:    recursoJSON.Cantidad,
+			Precio:      recursoJSON.Precio,
+			Cuadrilla:   cuadrilla,
+			Desperdicio: recursoJSON.Desperdicio,
 		}
 
 		_, err = s.partidaRepo.AddRecurso(partidaRecursoReq)
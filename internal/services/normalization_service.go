@@ -39,7 +39,7 @@ func (s *NormalizationService) NormalizeFromJSON(archivoJSON string) (*models.No
 		ID:          proyectoID,
 		Nombre:      fmt.Sprintf("Proyecto ACU - %s", archivoJSON),
 		Descripcion: fmt.Sprintf("Proyecto normalizado desde %s", archivoJSON),
-		Moneda:      "PEN",
+		Moneda:      models.MonedaPorDefecto,
 	}
 
 	// 2. Mapas para evitar duplicados
@@ -135,12 +135,14 @@ func (s *NormalizationService) procesarRecursos(
 		}
 
 		relacion := models.RelacionNormalizada{
-			ID:        uuid.New().String(),
-			PartidaID: partidaID,
-			RecursoID: recursosMap[claveRecurso].ID,
-			Cantidad:  recursoJSON.Cantidad,
-			Precio:    recursoJSON.Precio,
-			Cuadrilla: cuadrilla,
+			ID:          uuid.New().String(),
+			PartidaID:   partidaID,
+			RecursoID:   recursosMap[claveRecurso].ID,
+			Cantidad:    recursoJSON.Cantidad,
+			Precio:      recursoJSON.Precio,
+			Cuadrilla:   cuadrilla,
+			Moneda:      recursoJSON.Moneda,
+			Desperdicio: recursoJSON.Desperdicio,
 		}
 
 		*relaciones = append(*relaciones, relacion)
@@ -189,7 +191,7 @@ func (s *NormalizationService) NormalizeFromJSONData(partidasJSON []legacy.Parti
 		ID:          proyectoID,
 		Nombre:      nombreProyecto,
 		Descripcion: fmt.Sprintf("Proyecto desde datos ACU: %s", nombreProyecto),
-		Moneda:      "PEN",
+		Moneda:      models.MonedaPorDefecto,
 	}
 
 	// 2. Mapas para evitar duplicados
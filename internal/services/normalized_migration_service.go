@@ -325,16 +325,19 @@ func (s *NormalizedMigrationService) insertPartidaAndGetID(id uuid.UUID, partida
 
 func (s *NormalizedMigrationService) insertRelacion(id uuid.UUID, relacion models.RelacionNormalizada, partidaID, recursoID uuid.UUID) error {
 	query := `
-		INSERT INTO partida_recursos (id, partida_id, recurso_id, cantidad, precio, cuadrilla)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO partida_recursos (id, partida_id, recurso_id, cantidad, precio, cuadrilla, moneda, desperdicio)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8)
 		ON CONFLICT (partida_id, recurso_id) DO UPDATE SET
 			cantidad = EXCLUDED.cantidad,
 			precio = EXCLUDED.precio,
 			cuadrilla = EXCLUDED.cuadrilla,
+			moneda = EXCLUDED.moneda,
+			desperdicio = EXCLUDED.desperdicio,
 			updated_at = CURRENT_TIMESTAMP
 	`
 
-	_, err := s.db.Exec(query, id, partidaID, recursoID, relacion.Cantidad, relacion.Precio, relacion.Cuadrilla)
+	_, err := s.db.Exec(query, id, partidaID, recursoID, relacion.Cantidad, relacion.Precio, relacion.Cuadrilla, relacion.Moneda,
+		relacion.Desperdicio)
 	return err
 }
 
@@ -442,22 +445,25 @@ func (s *NormalizedMigrationService) insertPartidaAndGetIDTx(tx *sql.Tx, id uuid
 
 func (s *NormalizedMigrationService) insertRelacionTx(tx *sql.Tx, id uuid.UUID, relacion models.RelacionNormalizada, partidaID, recursoID uuid.UUID) error {
 	query := `
-		INSERT INTO partida_recursos (id, partida_id, recurso_id, cantidad, precio, cuadrilla)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO partida_recursos (id, partida_id, recurso_id, cantidad, precio, cuadrilla, moneda, desperdicio)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8)
 		ON CONFLICT (partida_id, recurso_id) DO UPDATE SET
 			cantidad = EXCLUDED.cantidad,
 			precio = EXCLUDED.precio,
 			cuadrilla = EXCLUDED.cuadrilla,
+			moneda = EXCLUDED.moneda,
+			desperdicio = EXCLUDED.desperdicio,
 			updated_at = CURRENT_TIMESTAMP
 	`
 
-	_, err := tx.Exec(query, id, partidaID, recursoID, relacion.Cantidad, relacion.Precio, relacion.Cuadrilla)
+	_, err := tx.Exec(query, id, partidaID, recursoID, relacion.Cantidad, relacion.Precio, relacion.Cuadrilla, relacion.Moneda,
+		relacion.Desperdicio)
 	return err
 }
 
 func (s *NormalizedMigrationService) MigrateNormalizedDataWithUser(data *models.NormalizedData, usuarioID uuid.UUID) error {
 	log.Printf("🚀 Iniciando migración de datos normalizados con usuario: %s", usuarioID.String())
-	
+
 	// Iniciar transacción explícita
 	tx, err := s.db.Begin()
 	if err != nil {
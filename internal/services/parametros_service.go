@@ -0,0 +1,210 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"goexcel/internal/costing"
+	"goexcel/internal/database/repositories"
+	"goexcel/internal/models"
+)
+
+// ParametrosService resuelve los parámetros de cálculo con su herencia:
+// valores por defecto → organización → proyecto o presupuesto
+type ParametrosService struct {
+	parametrosRepo *repositories.ParametrosRepository
+}
+
+func NewParametrosService(parametrosRepo *repositories.ParametrosRepository) *ParametrosService {
+	return &ParametrosService{parametrosRepo: parametrosRepo}
+}
+
+// DeOrganizacion devuelve lo declarado por la organización sobre los valores por defecto
+func (s *ParametrosService) DeOrganizacion(organizacionID uuid.UUID) (*models.ParametrosResponse, error) {
+	declarados, err := s.parametrosRepo.ObtenerDeOrganizacion(organizacionID)
+	if err != nil {
+		return nil, err
+	}
+	return respuestaParametros(declarados, models.ParametrosPorDefecto()), nil
+}
+
+// DeProyecto devuelve lo declarado por el proyecto sobre lo heredado de su organización
+func (s *ParametrosService) DeProyecto(proyectoID uuid.UUID) (*models.ParametrosResponse, error) {
+	declarados, deOrganizacion, err := s.parametrosRepo.ObtenerDeProyecto(proyectoID)
+	if err != nil {
+		return nil, err
+	}
+	return respuestaParametros(declarados, models.ParametrosPorDefecto().Aplicar(deOrganizacion)), nil
+}
+
+// DePresupuesto devuelve lo declarado por el presupuesto sobre lo heredado de su organización
+func (s *ParametrosService) DePresupuesto(presupuestoID uuid.UUID) (*models.ParametrosResponse, error) {
+	declarados, deOrganizacion, err := s.parametrosRepo.ObtenerDePresupuesto(presupuestoID)
+	if err != nil {
+		return nil, err
+	}
+	return respuestaParametros(declarados, models.ParametrosPorDefecto().Aplicar(deOrganizacion)), nil
+}
+
+// EfectivosDeOrganizacion son los parámetros que heredan los proyectos nuevos de la organización;
+// sin organización son los valores por defecto
+func (s *ParametrosService) EfectivosDeOrganizacion(organizacionID *uuid.UUID) (models.Parametros, error) {
+	if organizacionID == nil {
+		return models.ParametrosPorDefecto(), nil
+	}
+	respuesta, err := s.DeOrganizacion(*organizacionID)
+	if err != nil {
+		return models.ParametrosPorDefecto(), err
+	}
+	return *respuesta.Efectivos, nil
+}
+
+// EfectivosDeProyecto son los parámetros con los que se calculan los reportes del proyecto
+func (s *ParametrosService) EfectivosDeProyecto(proyectoID uuid.UUID) (models.Parametros, error) {
+	respuesta, err := s.DeProyecto(proyectoID)
+	if err != nil {
+		return models.ParametrosPorDefecto(), err
+	}
+	return *respuesta.Efectivos, nil
+}
+
+// GuardarDeOrganizacion valida y reemplaza los parámetros de la organización
+func (s *ParametrosService) GuardarDeOrganizacion(organizacionID uuid.UUID, parametros *models.ParametrosCalculo) error {
+	if err := parametros.Validar(); err != nil {
+		return err
+	}
+	return s.parametrosRepo.GuardarDeOrganizacion(organizacionID, parametros)
+}
+
+// GuardarDeProyecto valida y reemplaza los parámetros del proyecto
+func (s *ParametrosService) GuardarDeProyecto(proyectoID uuid.UUID, parametros *models.ParametrosCalculo) error {
+	if err := parametros.Validar(); err != nil {
+		return err
+	}
+	return s.parametrosRepo.GuardarDeProyecto(proyectoID, parametros)
+}
+
+// GuardarDePresupuesto valida y reemplaza los parámetros del presupuesto
+func (s *ParametrosService) GuardarDePresupuesto(presupuestoID uuid.UUID, parametros *models.ParametrosCalculo) error {
+	if err := parametros.Validar(); err != nil {
+		return err
+	}
+	return s.parametrosRepo.GuardarDePresupuesto(presupuestoID, parametros)
+}
+
+func respuestaParametros(declarados *models.ParametrosCalculo, heredados models.Parametros) *models.ParametrosResponse {
+	efectivos := heredados.Aplicar(declarados)
+	return &models.ParametrosResponse{
+		Success:    true,
+		Parametros: declarados,
+		Heredados:  &heredados,
+		Efectivos:  &efectivos,
+	}
+}
+
+// parametroACU interpreta una clave de los bloques @presupuesto/@proyecto de un archivo .acu que
+// declara un parámetro de cálculo; devuelve false si la clave no es un parámetro
+func parametroACU(declarados *models.ParametrosCalculo, clave, valor string) (bool, error) {
+	switch clave {
+	case "jornada":
+		horas, err := strconv.ParseFloat(valor, 64)
+		if err != nil {
+			return true, fmt.Errorf("jornada inválida: %s", valor)
+		}
+		declarados.HorasJornada = &horas
+	case "decimales_cantidad", "decimales_parcial":
+		decimales, err := strconv.ParseInt(valor, 10, 32)
+		if err != nil {
+			return true, fmt.Errorf("%s inválido: %s", clave, valor)
+		}
+		d := int32(decimales)
+		if clave == "decimales_cantidad" {
+			declarados.DecimalesCantidad = &d
+		} else {
+			declarados.DecimalesParcial = &d
+		}
+	case "igv":
+		igv, err := strconv.ParseFloat(strings.TrimSuffix(valor, "%"), 64)
+		if err != nil {
+			return true, fmt.Errorf("igv inválido: %s", valor)
+		}
+		declarados.PorcentajeIGV = &igv
+	case "cuadrilla_define_cantidad":
+		var activo bool
+		switch strings.ToLower(valor) {
+		case "true", "si", "sí", "1":
+			activo = true
+		case "false", "no", "0":
+			activo = false
+		default:
+			return true, fmt.Errorf("cuadrilla_define_cantidad inválido: %s", valor)
+		}
+		declarados.CuadrillaDefineCantidad = &activo
+	case "fecha_tipo_cambio":
+		declarados.FechaTipoCambio = &valor
+	default:
+		return false, nil
+	}
+	return true, declarados.Validar()
+}
+
+// cantidadSegunCuadrilla deduce la cantidad de un recurso de mano de obra o equipo de su cuadrilla
+// cuando los parámetros lo indican; si no, o si faltan datos, deja la cantidad como está
+func cantidadSegunCuadrilla(parametros models.Parametros, cantidad costing.Decimal, cuadrilla *float64, rendimiento float64) costing.Decimal {
+	if !parametros.CuadrillaDefineCantidad || cuadrilla == nil || *cuadrilla <= 0 || rendimiento <= 0 {
+		return cantidad
+	}
+	return parametros.Reglas().CantidadCuadrilla(*cuadrilla, parametros.HorasJornada, rendimiento)
+}
+
+// EscribirParametrosACU escribe los parámetros declarados como claves de un bloque @presupuesto o
+// @proyecto, cada una con su coma final
+func EscribirParametrosACU(acuContent *strings.Builder, parametros *models.ParametrosCalculo) {
+	if parametros == nil {
+		return
+	}
+	if parametros.HorasJornada != nil {
+		acuContent.WriteString(fmt.Sprintf("  jornada = %s,\n", strconv.FormatFloat(*parametros.HorasJornada, 'f', -1, 64)))
+	}
+	if parametros.DecimalesCantidad != nil {
+		acuContent.WriteString(fmt.Sprintf("  decimales_cantidad = %d,\n", *parametros.DecimalesCantidad))
+	}
+	if parametros.DecimalesParcial != nil {
+		acuContent.WriteString(fmt.Sprintf("  decimales_parcial = %d,\n", *parametros.DecimalesParcial))
+	}
+	if parametros.PorcentajeIGV != nil {
+		acuContent.WriteString(fmt.Sprintf("  igv = %s,\n", strconv.FormatFloat(*parametros.PorcentajeIGV, 'f', -1, 64)))
+	}
+	if parametros.CuadrillaDefineCantidad != nil {
+		acuContent.WriteString(fmt.Sprintf("  cuadrilla_define_cantidad = %t,\n", *parametros.CuadrillaDefineCantidad))
+	}
+	if parametros.FechaTipoCambio != nil {
+		acuContent.WriteString(fmt.Sprintf("  fecha_tipo_cambio = \"%s\",\n", *parametros.FechaTipoCambio))
+	}
+}
+
+// escribirMonedaACU agrega el campo moneda a un recurso de un archivo .acu si su precio está en otra
+// moneda que la del presupuesto
+func escribirMonedaACU(acuContent *strings.Builder, moneda string) {
+	if moneda != "" {
+		acuContent.WriteString(fmt.Sprintf(", moneda = \"%s\"", moneda))
+	}
+}
+
+// escribirDesperdicioACU agrega el campo desperdicio a un recurso de un archivo .acu si lo tiene
+func escribirDesperdicioACU(acuContent *strings.Builder, desperdicio *costing.Decimal) {
+	if desperdicio != nil && !desperdicio.EsCero() {
+		acuContent.WriteString(fmt.Sprintf(", desperdicio = %s", desperdicio))
+	}
+}
+
+// parsearDesperdicio lee el porcentaje de desperdicio de un recurso; nil si no es un número positivo
+func parsearDesperdicio(valor string) *costing.Decimal {
+	desperdicio, err := costing.ParsearDecimal(strings.TrimSpace(valor))
+	if err != nil || desperdicio.Signo() <= 0 {
+		return nil
+	}
+	return &desperdicio
+}
@@ -0,0 +1,385 @@
+package services
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/xuri/excelize/v2"
+	"goexcel/internal/database/repositories"
+	"goexcel/internal/models"
+)
+
+const (
+	hojaPlanillaMetrados = "Metrados"
+	// hojaIDsPlanilla queda muy oculta: Excel no la muestra en "Mostrar hojas"
+	hojaIDsPlanilla         = "_ids"
+	filaCabeceraPlanilla    = 5
+	filaInicioPlanilla      = filaCabeceraPlanilla + 1
+	versionPlanillaMetrados = 1
+	filaInicioIDsPlanilla   = 6
+)
+
+// PlanillaMetradosService genera la planilla de metrados que se llena fuera de línea y aplica la
+// planilla devuelta. Solo las celdas de metrado y observaciones quedan desbloqueadas; la hoja oculta
+// guarda el ID del proyecto y de cada partida para validar la carga.
+type PlanillaMetradosService struct {
+	proyectoRepo *repositories.ProyectoRepository
+	metradoRepo  *repositories.MetradoRepository
+	hierarchySvc *HierarchyService
+}
+
+// NewPlanillaMetradosService crea una nueva instancia del servicio de planillas de metrados
+func NewPlanillaMetradosService(proyectoRepo *repositories.ProyectoRepository, metradoRepo *repositories.MetradoRepository, hierarchySvc *HierarchyService) *PlanillaMetradosService {
+	return &PlanillaMetradosService{
+		proyectoRepo: proyectoRepo,
+		metradoRepo:  metradoRepo,
+		hierarchySvc: hierarchySvc,
+	}
+}
+
+// filaIDPlanilla relaciona una fila de la hoja de metrados con la partida que representa
+type filaIDPlanilla struct {
+	partidaID string
+	codigo    string
+}
+
+// ConstruirPlanilla genera la planilla del proyecto con la jerarquía de títulos y partidas y los
+// metrados ya registrados. El llamador debe cerrar el libro.
+func (s *PlanillaMetradosService) ConstruirPlanilla(proyectoID uuid.UUID) (*excelize.File, *models.Proyecto, error) {
+	proyecto, err := s.proyectoRepo.GetByID(proyectoID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	jerarquia, err := s.hierarchySvc.ObtenerJerarquiaCompleta(proyectoID.String())
+	if err != nil {
+		return nil, nil, err
+	}
+	partidas, err := s.hierarchySvc.ObtenerPartidasConJerarquia(proyectoID.String())
+	if err != nil {
+		return nil, nil, err
+	}
+	metrados, err := s.metradoRepo.ObtenerMetradosPorProyecto(proyectoID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	partidasMap := make(map[string]models.PartidaCompleta, len(partidas))
+	for _, partida := range partidas {
+		partidasMap[partida.Codigo] = partida
+	}
+	metradosMap := make(map[string]models.MetradoCompleto, len(metrados))
+	for _, metrado := range metrados {
+		metradosMap[metrado.PartidaCodigo] = metrado
+	}
+
+	// Sin elementos jerárquicos se listan las partidas en orden
+	if len(jerarquia) == 0 {
+		for _, partida := range partidas {
+			jerarquia = append(jerarquia, ElementoJerarquico{
+				Codigo:       partida.Codigo,
+				Descripcion:  partida.Descripcion,
+				TipoElemento: "partida",
+			})
+		}
+	}
+
+	f := excelize.NewFile()
+	if err := s.escribirPlanilla(f, proyecto, jerarquia, partidasMap, metradosMap); err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return f, proyecto, nil
+}
+
+func (s *PlanillaMetradosService) escribirPlanilla(f *excelize.File, proyecto *models.Proyecto, jerarquia []ElementoJerarquico, partidasMap map[string]models.PartidaCompleta, metradosMap map[string]models.MetradoCompleto) error {
+	if err := f.SetSheetName("Sheet1", hojaPlanillaMetrados); err != nil {
+		return fmt.Errorf("error creando hoja de metrados: %v", err)
+	}
+	if _, err := f.NewSheet(hojaIDsPlanilla); err != nil {
+		return fmt.Errorf("error creando hoja de IDs: %v", err)
+	}
+
+	estilos, err := crearEstilosPlanilla(f)
+	if err != nil {
+		return err
+	}
+
+	// Encabezado de la planilla
+	f.MergeCell(hojaPlanillaMetrados, "A1", "E1")
+	f.SetCellValue(hojaPlanillaMetrados, "A1", "PLANILLA DE METRADOS")
+	f.SetCellStyle(hojaPlanillaMetrados, "A1", "E1", estilos["titulo"])
+	f.SetCellValue(hojaPlanillaMetrados, "A2", "Proyecto:")
+	f.SetCellValue(hojaPlanillaMetrados, "B2", proyecto.Nombre)
+	f.SetCellValue(hojaPlanillaMetrados, "A3", "Complete solo las columnas Metrado y Observaciones. No agregue, mueva ni elimine filas.")
+	f.SetSheetRow(hojaPlanillaMetrados, fmt.Sprintf("A%d", filaCabeceraPlanilla),
+		&[]interface{}{"Ítem", "Descripción", "Und.", "Metrado", "Observaciones"})
+	f.SetCellStyle(hojaPlanillaMetrados, fmt.Sprintf("A%d", filaCabeceraPlanilla), fmt.Sprintf("E%d", filaCabeceraPlanilla), estilos["cabecera"])
+	for columna, ancho := range map[string]float64{"A": 14, "B": 60, "C": 8, "D": 14, "E": 40} {
+		f.SetColWidth(hojaPlanillaMetrados, columna, columna, ancho)
+	}
+
+	// Filas de títulos y partidas, con su ID en la hoja oculta
+	partidasEscritas := 0
+	row := filaInicioPlanilla
+	var escribir func(elementos []ElementoJerarquico)
+	escribir = func(elementos []ElementoJerarquico) {
+		for _, elem := range elementos {
+			celdaA := fmt.Sprintf("A%d", row)
+			celdaE := fmt.Sprintf("E%d", row)
+
+			if elem.TipoElemento == "titulo" {
+				f.SetSheetRow(hojaPlanillaMetrados, celdaA, &[]interface{}{elem.Codigo, elem.Descripcion})
+				f.SetCellStyle(hojaPlanillaMetrados, celdaA, celdaE, estilos["titulo_nivel"])
+				row++
+				escribir(elem.Hijos)
+				continue
+			}
+
+			partida, existe := partidasMap[elem.Codigo]
+			if !existe {
+				continue
+			}
+			valores := []interface{}{partida.Codigo, partida.Descripcion, partida.Unidad, nil, nil}
+			if metrado, ok := metradosMap[partida.Codigo]; ok {
+				valores[3] = metrado.Metrado.Float64()
+				if metrado.Observaciones != nil {
+					valores[4] = *metrado.Observaciones
+				}
+			}
+			f.SetSheetRow(hojaPlanillaMetrados, celdaA, &valores)
+			f.SetCellStyle(hojaPlanillaMetrados, celdaA, fmt.Sprintf("C%d", row), estilos["bloqueada"])
+			f.SetCellStyle(hojaPlanillaMetrados, fmt.Sprintf("D%d", row), fmt.Sprintf("D%d", row), estilos["metrado"])
+			f.SetCellStyle(hojaPlanillaMetrados, celdaE, celdaE, estilos["observaciones"])
+
+			f.SetSheetRow(hojaIDsPlanilla, fmt.Sprintf("A%d", filaInicioIDsPlanilla+partidasEscritas),
+				&[]interface{}{row, partida.ID.String(), partida.Codigo})
+			partidasEscritas++
+			row++
+		}
+	}
+	escribir(jerarquia)
+
+	if partidasEscritas == 0 {
+		return fmt.Errorf("el proyecto no tiene partidas")
+	}
+	ultimaFila := row - 1
+
+	// Metrado: número mayor o igual a cero
+	validacion := excelize.NewDataValidation(true)
+	validacion.Sqref = fmt.Sprintf("D%d:D%d", filaInicioPlanilla, ultimaFila)
+	if err := validacion.SetRange(0, "", excelize.DataValidationTypeDecimal, excelize.DataValidationOperatorGreaterThanOrEqual); err != nil {
+		return fmt.Errorf("error configurando validación: %v", err)
+	}
+	validacion.SetError(excelize.DataValidationErrorStyleStop, "Metrado inválido", "El metrado debe ser un número mayor o igual a cero.")
+	if err := f.AddDataValidation(hojaPlanillaMetrados, validacion); err != nil {
+		return fmt.Errorf("error agregando validación: %v", err)
+	}
+
+	if err := f.SetPanes(hojaPlanillaMetrados, &excelize.Panes{
+		Freeze:      true,
+		YSplit:      filaCabeceraPlanilla,
+		TopLeftCell: fmt.Sprintf("A%d", filaInicioPlanilla),
+		ActivePane:  "bottomLeft",
+	}); err != nil {
+		return fmt.Errorf("error fijando cabecera: %v", err)
+	}
+
+	if err := f.ProtectSheet(hojaPlanillaMetrados, &excelize.SheetProtectionOptions{
+		SelectLockedCells:   true,
+		SelectUnlockedCells: true,
+		FormatColumns:       true,
+	}); err != nil {
+		return fmt.Errorf("error protegiendo hoja: %v", err)
+	}
+
+	// Hoja oculta con los datos para validar la planilla devuelta
+	f.SetSheetRow(hojaIDsPlanilla, "A1", &[]interface{}{"proyecto_id", proyecto.ID.String()})
+	f.SetSheetRow(hojaIDsPlanilla, "A2", &[]interface{}{"version", versionPlanillaMetrados})
+	f.SetSheetRow(hojaIDsPlanilla, "A3", &[]interface{}{"generado", time.Now().Format(time.RFC3339)})
+	f.SetSheetRow(hojaIDsPlanilla, fmt.Sprintf("A%d", filaInicioIDsPlanilla-1), &[]interface{}{"fila", "partida_id", "codigo"})
+	if err := f.SetSheetVisible(hojaIDsPlanilla, false, true); err != nil {
+		return fmt.Errorf("error ocultando hoja de IDs: %v", err)
+	}
+
+	return nil
+}
+
+// AplicarPlanilla valida la planilla llenada contra el proyecto y guarda los metrados válidos.
+// Las filas con metrado inválido o de partidas que ya no existen se devuelven sin aplicarse.
+func (s *PlanillaMetradosService) AplicarPlanilla(r io.Reader, proyectoID uuid.UUID) (*models.ResultadoPlanillaMetrados, error) {
+	f, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("error abriendo libro: %v", err)
+	}
+	defer f.Close()
+
+	filasIDs, err := f.GetRows(hojaIDsPlanilla, excelize.Options{RawCellValue: true})
+	if err != nil || len(filasIDs) < filaInicioIDsPlanilla-1 {
+		return nil, fmt.Errorf("el libro no es una planilla de metrados generada por el sistema")
+	}
+	if celda(filasIDs[0], 1) != proyectoID.String() {
+		return nil, fmt.Errorf("la planilla pertenece a otro proyecto (%s)", celda(filasIDs[0], 1))
+	}
+
+	porFila := make(map[int]filaIDPlanilla)
+	for _, fila := range filasIDs[filaInicioIDsPlanilla-1:] {
+		numero, err := strconv.Atoi(celda(fila, 0))
+		if err != nil {
+			continue
+		}
+		porFila[numero] = filaIDPlanilla{partidaID: celda(fila, 1), codigo: celda(fila, 2)}
+	}
+
+	partidas, err := s.hierarchySvc.ObtenerPartidasConJerarquia(proyectoID.String())
+	if err != nil {
+		return nil, err
+	}
+	porID := make(map[string]models.PartidaCompleta, len(partidas))
+	porCodigo := make(map[string]models.PartidaCompleta, len(partidas))
+	for _, partida := range partidas {
+		porID[partida.ID.String()] = partida
+		porCodigo[partida.Codigo] = partida
+	}
+
+	filas, err := f.GetRows(hojaPlanillaMetrados, excelize.Options{RawCellValue: true})
+	if err != nil {
+		return nil, fmt.Errorf("error leyendo hoja %s: %v", hojaPlanillaMetrados, err)
+	}
+
+	resultado := &models.ResultadoPlanillaMetrados{
+		Rechazadas:   []models.FilaPlanillaRechazada{},
+		Desconocidas: []models.FilaPlanillaRechazada{},
+	}
+	rechazar := func(destino *[]models.FilaPlanillaRechazada, fila int, codigo, formato string, args ...interface{}) {
+		*destino = append(*destino, models.FilaPlanillaRechazada{Fila: fila, Codigo: codigo, Motivo: fmt.Sprintf(formato, args...)})
+	}
+
+	var metrados []models.MetradoRequest
+	aplicadas := make(map[string]bool)
+	for i := filaInicioPlanilla - 1; i < len(filas); i++ {
+		fila := filas[i]
+		numFila := i + 1
+		codigo := celda(fila, 0)
+		valor := celda(fila, 3)
+		if codigo == "" {
+			if valor != "" {
+				rechazar(&resultado.Rechazadas, numFila, "", "metrado sin código de partida")
+			}
+			continue
+		}
+
+		// Las filas generadas se validan contra la hoja oculta; las demás, solo por código
+		var partida models.PartidaCompleta
+		if id, ok := porFila[numFila]; ok {
+			if id.codigo != codigo {
+				rechazar(&resultado.Rechazadas, numFila, codigo, "el código fue modificado; se esperaba %s", id.codigo)
+				continue
+			}
+			if partida, ok = porID[id.partidaID]; !ok {
+				rechazar(&resultado.Desconocidas, numFila, codigo, "la partida ya no existe en el proyecto")
+				continue
+			}
+		} else {
+			var existe bool
+			if partida, existe = porCodigo[codigo]; !existe {
+				// Filas de título o texto sin metrado no son errores
+				if valor != "" {
+					rechazar(&resultado.Desconocidas, numFila, codigo, "la partida no existe en el proyecto")
+				}
+				continue
+			}
+		}
+
+		if valor == "" {
+			resultado.SinMetrado++
+			continue
+		}
+		metrado, ok := parsearDecimal(valor)
+		if !ok {
+			rechazar(&resultado.Rechazadas, numFila, codigo, "metrado no numérico: %q", valor)
+			continue
+		}
+		if metrado.Signo() < 0 {
+			rechazar(&resultado.Rechazadas, numFila, codigo, "metrado negativo: %v", metrado)
+			continue
+		}
+		if aplicadas[partida.Codigo] {
+			rechazar(&resultado.Rechazadas, numFila, codigo, "la partida aparece más de una vez; se conserva la primera fila")
+			continue
+		}
+		aplicadas[partida.Codigo] = true
+
+		metradoReq := models.MetradoRequest{PartidaCodigo: partida.Codigo, Metrado: metrado}
+		unidad := partida.Unidad
+		metradoReq.Unidad = &unidad
+		if observaciones := strings.TrimSpace(celda(fila, 4)); observaciones != "" {
+			metradoReq.Observaciones = &observaciones
+		}
+		metrados = append(metrados, metradoReq)
+	}
+
+	if len(metrados) > 0 {
+		if err := s.metradoRepo.ActualizarMetrados(proyectoID, metrados); err != nil {
+			return nil, err
+		}
+	}
+	resultado.Aplicados = len(metrados)
+
+	return resultado, nil
+}
+
+// crearEstilosPlanilla define los estilos; por defecto las celdas quedan bloqueadas al proteger la hoja
+func crearEstilosPlanilla(f *excelize.File) (map[string]int, error) {
+	bordes := []excelize.Border{
+		{Type: "left", Color: "BFBFBF", Style: 1},
+		{Type: "top", Color: "BFBFBF", Style: 1},
+		{Type: "bottom", Color: "BFBFBF", Style: 1},
+		{Type: "right", Color: "BFBFBF", Style: 1},
+	}
+	definiciones := map[string]*excelize.Style{
+		"titulo": {
+			Font:      &excelize.Font{Bold: true, Size: 14},
+			Alignment: &excelize.Alignment{Horizontal: "center"},
+		},
+		"cabecera": {
+			Font:      &excelize.Font{Bold: true, Color: "FFFFFF"},
+			Fill:      excelize.Fill{Type: "pattern", Color: []string{"4F81BD"}, Pattern: 1},
+			Alignment: &excelize.Alignment{Horizontal: "center"},
+			Border:    bordes,
+		},
+		"titulo_nivel": {
+			Font:   &excelize.Font{Bold: true},
+			Fill:   excelize.Fill{Type: "pattern", Color: []string{"D9E2F3"}, Pattern: 1},
+			Border: bordes,
+		},
+		"bloqueada": {
+			Fill:   excelize.Fill{Type: "pattern", Color: []string{"F2F2F2"}, Pattern: 1},
+			Border: bordes,
+		},
+		"metrado": {
+			Border:       bordes,
+			CustomNumFmt: &[]string{"#,##0.00"}[0],
+			Fill:         excelize.Fill{Type: "pattern", Color: []string{"FFF2CC"}, Pattern: 1},
+			Protection:   &excelize.Protection{Locked: false},
+		},
+		"observaciones": {
+			Border:     bordes,
+			Protection: &excelize.Protection{Locked: false},
+		},
+	}
+
+	estilos := make(map[string]int, len(definiciones))
+	for nombre, definicion := range definiciones {
+		id, err := f.NewStyle(definicion)
+		if err != nil {
+			return nil, fmt.Errorf("error creando estilo %s: %v", nombre, err)
+		}
+		estilos[nombre] = id
+	}
+	return estilos, nil
+}
@@ -0,0 +1,395 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/xuri/excelize/v2"
+	"goexcel/internal/database/repositories"
+	"goexcel/internal/models"
+)
+
+const (
+	maxTamanoLogo      = 1 << 20 // 1 MB
+	anchoColumnaMaximo = 255
+	// vigenciaLogo es cuánto se reutiliza un logo descargado antes de volver a pedirlo; evita esperar
+	// la descarga en cada exportación. Un error se recuerda menos tiempo para reintentar antes.
+	vigenciaLogo      = time.Hour
+	vigenciaErrorLogo = 5 * time.Minute
+)
+
+var (
+	colorHexRegex = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
+	rucRegex      = regexp.MustCompile(`^\d{11}$`)
+	columnaRegex  = regexp.MustCompile(`^[A-Z]{1,3}$`)
+)
+
+// extensionesLogo son los formatos de imagen aceptados para el logo del encabezado
+var extensionesLogo = map[string]bool{".png": true, ".jpg": true, ".jpeg": true, ".gif": true}
+
+// Celdas de la hoja de referencia de las que se toman los estilos
+var celdasReferencia = map[string]string{
+	"A1": "titulo",
+	"A2": "cabecera",
+	"A3": "partida",
+	"A4": "seccion",
+	"A5": "total",
+}
+
+type PlantillaService struct {
+	plantillaRepo    *repositories.PlantillaRepository
+	organizacionRepo *repositories.OrganizacionRepository
+	httpClient       *http.Client
+
+	mu    sync.Mutex
+	logos map[string]logoDescargado
+}
+
+// logoDescargado es el resultado de descargar el logo de una URL, guardado hasta que vence
+type logoDescargado struct {
+	logo      []byte
+	extension string
+	err       error
+	vence     time.Time
+}
+
+func NewPlantillaService(plantillaRepo *repositories.PlantillaRepository, organizacionRepo *repositories.OrganizacionRepository) *PlantillaService {
+	return &PlantillaService{
+		plantillaRepo:    plantillaRepo,
+		organizacionRepo: organizacionRepo,
+		httpClient:       nuevoClienteLogo(),
+		logos:            make(map[string]logoDescargado),
+	}
+}
+
+// nuevoClienteLogo crea el cliente HTTP de descarga de logos. Las conexiones se validan después de
+// resolver el nombre, también en las redirecciones, para que logo_url no sirva para alcanzar
+// servicios internos; por eso no se usa el proxy del entorno.
+func nuevoClienteLogo() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || !direccionPublica(ip) {
+				return fmt.Errorf("dirección de logo no permitida: %s", host)
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout: 5 * time.Second,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, address)
+			},
+			TLSHandshakeTimeout: 5 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 3 {
+				return fmt.Errorf("demasiadas redirecciones al descargar logo")
+			}
+			return validarURLLogo(req.URL)
+		},
+	}
+}
+
+// direccionPublica indica si la IP no es de loopback, red privada, enlace local, multicast ni la no especificada
+func direccionPublica(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast())
+}
+
+// validarURLLogo acepta solo URLs http(s) con host
+func validarURLLogo(u *url.URL) error {
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return fmt.Errorf("URL de logo no soportada: %s", u.Redacted())
+	}
+	return nil
+}
+
+// ObtenerPlantilla devuelve la plantilla guardada de la organización, o nil si no tiene una
+func (s *PlantillaService) ObtenerPlantilla(organizacionID uuid.UUID) (*models.PlantillaExcel, error) {
+	return s.plantillaRepo.ObtenerPorOrganizacion(organizacionID)
+}
+
+// ObtenerParaExportar arma la plantilla a aplicar en una exportación: la guardada o una basada en
+// los datos de la organización, con el logo de la organización si la plantilla no trae uno
+func (s *PlantillaService) ObtenerParaExportar(organizacionID *uuid.UUID) (*models.PlantillaExcel, error) {
+	if organizacionID == nil {
+		return nil, nil
+	}
+
+	organizacion, err := s.organizacionRepo.GetByID(*organizacionID)
+	if err != nil {
+		return nil, err
+	}
+
+	plantilla, err := s.plantillaRepo.ObtenerPorOrganizacion(*organizacionID)
+	if err != nil {
+		return nil, err
+	}
+	if plantilla == nil {
+		plantilla = &models.PlantillaExcel{OrganizacionID: organizacion.ID}
+	}
+	if plantilla.Empresa == "" {
+		plantilla.Empresa = organizacion.Nombre
+	}
+
+	if len(plantilla.Logo) == 0 && organizacion.LogoURL != nil && *organizacion.LogoURL != "" {
+		logo, extension, err := s.logoDeURL(*organizacion.LogoURL)
+		if err != nil {
+			log.Printf("⚠️ No se pudo descargar el logo de la organización: %v", err)
+		} else {
+			plantilla.Logo = logo
+			plantilla.LogoExtension = extension
+		}
+	}
+
+	return plantilla, nil
+}
+
+// Guardar valida y guarda la plantilla de la organización
+func (s *PlantillaService) Guardar(plantilla *models.PlantillaExcel) error {
+	if err := s.Validar(plantilla); err != nil {
+		return err
+	}
+	return s.plantillaRepo.Guardar(plantilla)
+}
+
+// Eliminar borra la plantilla de la organización
+func (s *PlantillaService) Eliminar(organizacionID uuid.UUID) error {
+	return s.plantillaRepo.Eliminar(organizacionID)
+}
+
+// Validar verifica colores, RUC, columnas y logo de la plantilla
+func (s *PlantillaService) Validar(plantilla *models.PlantillaExcel) error {
+	colores := map[string]string{
+		"color_titulo":   plantilla.ColorTitulo,
+		"color_cabecera": plantilla.ColorCabecera,
+		"color_partida":  plantilla.ColorPartida,
+		"color_seccion":  plantilla.ColorSeccion,
+		"color_total":    plantilla.ColorTotal,
+	}
+	for campo, color := range colores {
+		if color != "" && !colorHexRegex.MatchString(color) {
+			return fmt.Errorf("%s inválido: %s (se espera #RRGGBB)", campo, color)
+		}
+	}
+
+	if plantilla.RUC != "" && !rucRegex.MatchString(plantilla.RUC) {
+		return fmt.Errorf("RUC inválido: debe tener 11 dígitos")
+	}
+
+	if plantilla.TamanoFuente < 0 || plantilla.TamanoFuente > 72 {
+		return fmt.Errorf("tamaño de fuente inválido: %.1f", plantilla.TamanoFuente)
+	}
+
+	for columna, ancho := range plantilla.AnchosColumna {
+		if !columnaRegex.MatchString(columna) {
+			return fmt.Errorf("columna inválida en anchos_columna: %s", columna)
+		}
+		if ancho <= 0 || ancho > anchoColumnaMaximo {
+			return fmt.Errorf("ancho inválido para la columna %s: %.2f", columna, ancho)
+		}
+	}
+
+	if len(plantilla.Logo) > 0 {
+		if len(plantilla.Logo) > maxTamanoLogo {
+			return fmt.Errorf("el logo excede el tamaño máximo de 1 MB")
+		}
+		if !extensionesLogo[strings.ToLower(plantilla.LogoExtension)] {
+			return fmt.Errorf("extensión de logo no soportada: %s", plantilla.LogoExtension)
+		}
+	}
+
+	return nil
+}
+
+// ImportarDesdeXLSX lee una plantilla desde un libro de referencia. En su primera hoja se toman los
+// estilos de A1 (título), A2 (cabecera), A3 (partida), A4 (sección) y A5 (total, con su formato
+// numérico); las etiquetas Empresa, RUC, Ingeniero y CIP de la columna A con su valor en la columna B;
+// los anchos de columna personalizados y la primera imagen como logo.
+func (s *PlantillaService) ImportarDesdeXLSX(r io.Reader, organizacionID uuid.UUID) (*models.PlantillaExcel, error) {
+	f, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("archivo de referencia inválido: %v", err)
+	}
+	defer f.Close()
+
+	sheet := f.GetSheetName(0)
+	plantilla := &models.PlantillaExcel{
+		OrganizacionID: organizacionID,
+		AnchosColumna:  make(map[string]float64),
+	}
+
+	for celda, rol := range celdasReferencia {
+		estilo, err := s.estiloDeCelda(f, sheet, celda)
+		if err != nil || estilo == nil {
+			continue
+		}
+
+		color := ""
+		if len(estilo.Fill.Color) > 0 {
+			color = "#" + strings.TrimPrefix(strings.ToUpper(estilo.Fill.Color[0]), "#")
+			if len(color) == 9 { // ARGB
+				color = "#" + color[3:]
+			}
+		}
+
+		switch rol {
+		case "titulo":
+			plantilla.ColorTitulo = color
+			if estilo.Font != nil {
+				plantilla.Fuente = estilo.Font.Family
+			}
+		case "cabecera":
+			plantilla.ColorCabecera = color
+		case "partida":
+			plantilla.ColorPartida = color
+		case "seccion":
+			plantilla.ColorSeccion = color
+		case "total":
+			plantilla.ColorTotal = color
+			if estilo.CustomNumFmt != nil {
+				plantilla.FormatoNumero = *estilo.CustomNumFmt
+			}
+		}
+	}
+
+	rows, err := f.GetRows(sheet)
+	if err != nil {
+		return nil, fmt.Errorf("error leyendo hoja de referencia: %v", err)
+	}
+	for _, row := range rows {
+		if len(row) < 2 {
+			continue
+		}
+		valor := strings.TrimSpace(row[1])
+		switch strings.ToLower(strings.TrimSuffix(strings.TrimSpace(row[0]), ":")) {
+		case "empresa":
+			plantilla.Empresa = valor
+		case "ruc":
+			plantilla.RUC = valor
+		case "ingeniero":
+			plantilla.Ingeniero = valor
+		case "cip":
+			plantilla.CIP = valor
+		}
+	}
+
+	// Solo se conservan los anchos distintos al ancho por defecto de la hoja
+	anchoPorDefecto, _ := f.GetColWidth(sheet, "XFD")
+	for col := 1; col <= 9; col++ {
+		nombre, _ := excelize.ColumnNumberToName(col)
+		ancho, err := f.GetColWidth(sheet, nombre)
+		if err == nil && ancho != anchoPorDefecto {
+			plantilla.AnchosColumna[nombre] = ancho
+		}
+	}
+
+	celdas, err := f.GetPictureCells(sheet)
+	if err == nil && len(celdas) > 0 {
+		imagenes, err := f.GetPictures(sheet, celdas[0])
+		if err == nil && len(imagenes) > 0 {
+			plantilla.Logo = imagenes[0].File
+			plantilla.LogoExtension = strings.ToLower(imagenes[0].Extension)
+		}
+	}
+
+	if err := s.Validar(plantilla); err != nil {
+		return nil, err
+	}
+
+	return plantilla, nil
+}
+
+func (s *PlantillaService) estiloDeCelda(f *excelize.File, sheet, celda string) (*excelize.Style, error) {
+	id, err := f.GetCellStyle(sheet, celda)
+	if err != nil || id == 0 {
+		return nil, err
+	}
+	return f.GetStyle(id)
+}
+
+// logoDeURL devuelve el logo de la URL desde la caché, descargándolo si no está o ya venció
+func (s *PlantillaService) logoDeURL(direccion string) ([]byte, string, error) {
+	s.mu.Lock()
+	guardado, ok := s.logos[direccion]
+	s.mu.Unlock()
+	if ok && time.Now().Before(guardado.vence) {
+		return guardado.logo, guardado.extension, guardado.err
+	}
+
+	logo, extension, err := s.descargarLogo(direccion)
+
+	vigencia := vigenciaLogo
+	if err != nil {
+		vigencia = vigenciaErrorLogo
+	}
+	s.mu.Lock()
+	s.logos[direccion] = logoDescargado{logo: logo, extension: extension, err: err, vence: time.Now().Add(vigencia)}
+	s.mu.Unlock()
+	return logo, extension, err
+}
+
+// descargarLogo obtiene la imagen del logo de la organización desde su URL
+func (s *PlantillaService) descargarLogo(direccion string) ([]byte, string, error) {
+	u, err := url.Parse(direccion)
+	if err != nil {
+		return nil, "", fmt.Errorf("URL de logo inválida: %v", err)
+	}
+	if err := validarURLLogo(u); err != nil {
+		return nil, "", err
+	}
+
+	resp, err := s.httpClient.Get(u.String())
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("respuesta %d al descargar logo", resp.StatusCode)
+	}
+
+	logo, err := io.ReadAll(io.LimitReader(resp.Body, maxTamanoLogo+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(logo) > maxTamanoLogo {
+		return nil, "", fmt.Errorf("el logo excede el tamaño máximo de 1 MB")
+	}
+
+	extension := ""
+	switch http.DetectContentType(logo) {
+	case "image/png":
+		extension = ".png"
+	case "image/jpeg":
+		extension = ".jpg"
+	case "image/gif":
+		extension = ".gif"
+	default:
+		extension = strings.ToLower(filepath.Ext(u.Path))
+	}
+	if !extensionesLogo[extension] {
+		return nil, "", fmt.Errorf("formato de logo no soportado")
+	}
+
+	return logo, extension, nil
+}
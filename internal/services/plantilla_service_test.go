@@ -0,0 +1,75 @@
+package services
+
+import (
+	"bytes"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDireccionPublica(t *testing.T) {
+	casos := []struct {
+		ip      string
+		publica bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.0.0.5", false},
+		{"172.16.3.1", false},
+		{"192.168.1.10", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"0.0.0.0", false},
+		{"224.0.0.1", false},
+	}
+	for _, c := range casos {
+		if got := direccionPublica(net.ParseIP(c.ip)); got != c.publica {
+			t.Errorf("direccionPublica(%s) = %v, se esperaba %v", c.ip, got, c.publica)
+		}
+	}
+}
+
+func TestDescargarLogoRechazaDireccionesInternas(t *testing.T) {
+	servidor := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("no se debió conectar a una dirección de loopback")
+	}))
+	defer servidor.Close()
+
+	s := NewPlantillaService(nil, nil)
+	for _, direccion := range []string{servidor.URL + "/logo.png", "file:///etc/passwd", "ftp://example.com/logo.png"} {
+		if _, _, err := s.descargarLogo(direccion); err == nil {
+			t.Errorf("descargarLogo(%s) no devolvió error", direccion)
+		}
+	}
+}
+
+func TestLogoDeURLUsaCache(t *testing.T) {
+	png := append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 16)...)
+	pedidos := 0
+	servidor := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pedidos++
+		w.Write(png)
+	}))
+	defer servidor.Close()
+
+	// El cliente del servidor de prueba evita la restricción de direcciones para probar solo la caché
+	s := NewPlantillaService(nil, nil)
+	s.httpClient = servidor.Client()
+
+	for i := 0; i < 3; i++ {
+		logo, extension, err := s.logoDeURL(servidor.URL + "/logo")
+		if err != nil {
+			t.Fatalf("logoDeURL: %v", err)
+		}
+		if extension != ".png" || !bytes.Equal(logo, png) {
+			t.Fatalf("logo inesperado: extensión %q, %d bytes", extension, len(logo))
+		}
+	}
+	if pedidos != 1 {
+		t.Errorf("se descargó el logo %d veces, se esperaba 1", pedidos)
+	}
+}
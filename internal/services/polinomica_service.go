@@ -0,0 +1,369 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+	"goexcel/internal/costing"
+	"goexcel/internal/legacy"
+	"goexcel/internal/models"
+)
+
+// Reglas de agrupamiento de la fórmula polinómica (D.S. 011-79-VC y modificatorias)
+const (
+	maxMonomios            = 8
+	maxElementosPorMonomio = 3
+	coeficienteMinimo      = 0.05
+	indiceManoObra         = "47"
+)
+
+// indicesPorTipoRecurso es el índice que se usa cuando el recurso no tiene uno asignado
+var indicesPorTipoRecurso = map[string]string{
+	"mano_obra":    indiceManoObra,
+	"materiales":   "39",
+	"equipos":      "48",
+	"subcontratos": "39",
+}
+
+// IndicesUnificados contiene la descripción de los índices unificados INEI de uso más frecuente
+var IndicesUnificados = map[string]string{
+	"02": "Acero de construcción liso",
+	"03": "Acero de construcción corrugado",
+	"04": "Agregado fino",
+	"05": "Agregado grueso",
+	"13": "Asfalto",
+	"17": "Bloque y ladrillo",
+	"21": "Cemento Portland tipo I",
+	"30": "Dólar (general ponderado)",
+	"37": "Herramienta manual",
+	"39": "Índice general de precios al consumidor",
+	"43": "Madera nacional para encofrado y carpintería",
+	"47": "Mano de obra (incluido leyes sociales)",
+	"48": "Maquinaria y equipo nacional",
+	"49": "Maquinaria y equipo importado",
+	"53": "Petróleo diesel",
+}
+
+type FormulaPolinomicaService struct{}
+
+func NewFormulaPolinomicaService() *FormulaPolinomicaService {
+	return &FormulaPolinomicaService{}
+}
+
+// ValidarIndiceUnificado verifica que el código corresponda a un índice unificado (01 a 80)
+func ValidarIndiceUnificado(codigo string) error {
+	numero, err := strconv.Atoi(codigo)
+	if err != nil || len(codigo) != 2 || numero < 1 || numero > 80 {
+		return fmt.Errorf("índice unificado inválido: %s (se espera un código de 01 a 80)", codigo)
+	}
+	return nil
+}
+
+// DescripcionIndice devuelve la descripción de un índice unificado
+func DescripcionIndice(codigo string) string {
+	if descripcion, existe := IndicesUnificados[codigo]; existe {
+		return descripcion
+	}
+	return fmt.Sprintf("Índice unificado %s", codigo)
+}
+
+// grupoMonomio es un monomio en construcción durante el agrupamiento
+type grupoMonomio struct {
+	elementos []models.ElementoMonomio
+	costo     float64
+}
+
+func (g grupoMonomio) contieneIndice(codigo string) bool {
+	for _, elem := range g.elementos {
+		if elem.IndiceCodigo == codigo {
+			return true
+		}
+	}
+	return false
+}
+
+// Calcular construye la fórmula polinómica a partir de la incidencia de cada índice en la relación de insumos
+func (s *FormulaPolinomicaService) Calcular(relacion *models.RelacionInsumos) (*models.FormulaPolinomica, error) {
+	if relacion.TotalInsumos.Signo() <= 0 {
+		return nil, fmt.Errorf("la relación de insumos está vacía: registre metrados antes de calcular la fórmula")
+	}
+
+	// Acumular costo por índice unificado
+	costos := make(map[string]costing.Decimal)
+	for _, grupo := range relacion.Grupos {
+		for _, insumo := range grupo.Insumos {
+			indice := indicesPorTipoRecurso[grupo.TipoRecurso]
+			if insumo.IndiceUnificado != nil && *insumo.IndiceUnificado != "" {
+				indice = *insumo.IndiceUnificado
+			}
+			costos[indice] = costos[indice].Sumar(insumo.CostoTotal)
+		}
+	}
+
+	// Los coeficientes son proporciones que se redondean a tres decimales: se calculan en float64
+	total := relacion.TotalInsumos.Float64()
+	formula := &models.FormulaPolinomica{CostoDirecto: relacion.TotalInsumos}
+	var grupos []grupoMonomio
+	for codigo, costo := range costos {
+		elem := models.ElementoMonomio{
+			IndiceCodigo:      codigo,
+			IndiceDescripcion: DescripcionIndice(codigo),
+			Costo:             costo,
+			Incidencia:        costo.Float64() / total,
+		}
+		formula.Incidencias = append(formula.Incidencias, elem)
+		grupos = append(grupos, grupoMonomio{elementos: []models.ElementoMonomio{elem}, costo: costo.Float64()})
+	}
+
+	sort.Slice(formula.Incidencias, func(i, j int) bool {
+		if formula.Incidencias[i].Incidencia != formula.Incidencias[j].Incidencia {
+			return formula.Incidencias[i].Incidencia > formula.Incidencias[j].Incidencia
+		}
+		return formula.Incidencias[i].IndiceCodigo < formula.Incidencias[j].IndiceCodigo
+	})
+
+	grupos = s.agrupar(grupos, total)
+	formula.Monomios = s.redondear(grupos, total)
+	formula.Formula = s.construirExpresion(formula.Monomios)
+
+	return formula, nil
+}
+
+// agrupar fusiona los monomios menores hasta cumplir el máximo de monomios y el coeficiente mínimo. La
+// mano de obra no se agrupa: su monomio no se fusiona con otro ni recibe elementos, aunque su coeficiente
+// sea menor que el mínimo.
+func (s *FormulaPolinomicaService) agrupar(grupos []grupoMonomio, total float64) []grupoMonomio {
+	ordenarGrupos(grupos)
+
+	for {
+		// El monomio a fusionar es el menor que no sea de mano de obra
+		origen := -1
+		for i := len(grupos) - 1; i >= 0; i-- {
+			if !grupos[i].contieneIndice(indiceManoObra) {
+				origen = i
+				break
+			}
+		}
+		if origen == -1 || (len(grupos) <= maxMonomios && grupos[origen].costo/total >= coeficienteMinimo) {
+			break
+		}
+		menor := grupos[origen]
+
+		// Buscar el monomio más pequeño que admita los elementos; sin uno que los admita se relaja el
+		// límite de elementos antes de dejar un monomio fuera de norma
+		destino, relajado := -1, -1
+		for i := len(grupos) - 1; i >= 0; i-- {
+			if i == origen || grupos[i].contieneIndice(indiceManoObra) {
+				continue
+			}
+			if relajado == -1 {
+				relajado = i
+			}
+			if len(grupos[i].elementos)+len(menor.elementos) <= maxElementosPorMonomio {
+				destino = i
+				break
+			}
+		}
+		if destino == -1 {
+			destino = relajado
+		}
+		if destino == -1 {
+			break
+		}
+
+		grupos[destino].elementos = append(grupos[destino].elementos, menor.elementos...)
+		grupos[destino].costo += menor.costo
+		grupos = append(grupos[:origen], grupos[origen+1:]...)
+		ordenarGrupos(grupos)
+	}
+
+	return grupos
+}
+
+// ordenarGrupos ordena los monomios de mayor a menor costo; a igual costo decide el código del primer
+// índice, para que la fórmula no dependa del orden en que se recorrió el mapa de costos
+func ordenarGrupos(grupos []grupoMonomio) {
+	sort.Slice(grupos, func(i, j int) bool {
+		if grupos[i].costo != grupos[j].costo {
+			return grupos[i].costo > grupos[j].costo
+		}
+		return grupos[i].elementos[0].IndiceCodigo < grupos[j].elementos[0].IndiceCodigo
+	})
+}
+
+// redondear expresa los coeficientes con tres decimales asegurando que sumen 1.000
+func (s *FormulaPolinomicaService) redondear(grupos []grupoMonomio, total float64) []models.Monomio {
+	monomios := make([]models.Monomio, len(grupos))
+	suma := 0.0
+	for i, grupo := range grupos {
+		monomios[i] = models.Monomio{
+			Simbolo:     string(rune('a' + i)),
+			Coeficiente: redondear3(grupo.costo / total),
+			Elementos:   grupo.elementos,
+		}
+		suma += monomios[i].Coeficiente
+	}
+
+	// El residuo del redondeo se asigna al monomio de mayor incidencia
+	if len(monomios) > 0 {
+		monomios[0].Coeficiente = redondear3(monomios[0].Coeficiente + 1.0 - suma)
+	}
+
+	for i := range monomios {
+		monomio := &monomios[i]
+		costoMonomio := grupos[i].costo
+		sumaElementos := 0.0
+		for j := range monomio.Elementos {
+			elem := &monomio.Elementos[j]
+			if j == len(monomio.Elementos)-1 {
+				elem.Coeficiente = redondear3(monomio.Coeficiente - sumaElementos)
+				continue
+			}
+			elem.Coeficiente = redondear3(monomio.Coeficiente * elem.Costo.Float64() / costoMonomio)
+			sumaElementos += elem.Coeficiente
+		}
+	}
+
+	return monomios
+}
+
+// construirExpresion arma el texto K = a·(Ir/Io) + ... de la fórmula
+func (s *FormulaPolinomicaService) construirExpresion(monomios []models.Monomio) string {
+	var terminos []string
+	for _, monomio := range monomios {
+		if len(monomio.Elementos) == 1 {
+			codigo := monomio.Elementos[0].IndiceCodigo
+			terminos = append(terminos, fmt.Sprintf("%.3f(I%sr/I%so)", monomio.Coeficiente, codigo, codigo))
+			continue
+		}
+
+		var partes []string
+		for _, elem := range monomio.Elementos {
+			peso := 0.0
+			if monomio.Coeficiente > 0 {
+				peso = elem.Coeficiente / monomio.Coeficiente
+			}
+			partes = append(partes, fmt.Sprintf("%.3f·I%sr/I%so", peso, elem.IndiceCodigo, elem.IndiceCodigo))
+		}
+		terminos = append(terminos, fmt.Sprintf("%.3f(%s)", monomio.Coeficiente, strings.Join(partes, " + ")))
+	}
+
+	return "K = " + strings.Join(terminos, " + ")
+}
+
+func redondear3(valor float64) float64 {
+	return math.Round(valor*1000) / 1000
+}
+
+// AgregarHojaFormula agrega la hoja "Fórmula Polinómica" con la expresión y su tabla de agrupamiento,
+// con los colores y la fuente de la plantilla resuelta
+func (s *FormulaPolinomicaService) AgregarHojaFormula(f *excelize.File, formula *models.FormulaPolinomica, plantilla models.PlantillaExcel) error {
+	sheet := "Fórmula Polinómica"
+	if _, err := f.NewSheet(sheet); err != nil {
+		return fmt.Errorf("error creando hoja de fórmula polinómica: %v", err)
+	}
+	tamanoDatos := legacy.TamanoDatos(plantilla, 9)
+
+	f.SetColWidth(sheet, "A", "A", 10)
+	f.SetColWidth(sheet, "B", "B", 14)
+	f.SetColWidth(sheet, "C", "C", 10)
+	f.SetColWidth(sheet, "D", "D", 50)
+	f.SetColWidth(sheet, "E", "E", 14)
+	f.SetColWidth(sheet, "F", "F", 14)
+
+	bordes := []excelize.Border{
+		{Type: "left", Color: "#000000", Style: 1},
+		{Type: "right", Color: "#000000", Style: 1},
+		{Type: "top", Color: "#000000", Style: 1},
+		{Type: "bottom", Color: "#000000", Style: 1},
+	}
+
+	tituloStyle, _ := f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Bold: true, Size: 14, Color: "#FFFFFF", Family: plantilla.Fuente},
+		Fill:      excelize.Fill{Type: "pattern", Color: []string{plantilla.ColorTitulo}, Pattern: 1},
+		Alignment: &excelize.Alignment{Horizontal: "center", Vertical: "center"},
+	})
+
+	formulaStyle, _ := f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Bold: true, Size: 11, Family: plantilla.Fuente},
+		Alignment: &excelize.Alignment{Horizontal: "left", Vertical: "center", WrapText: true},
+		Border:    bordes,
+	})
+
+	cabeceraStyle, _ := f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Bold: true, Size: 10, Color: "#FFFFFF", Family: plantilla.Fuente},
+		Fill:      excelize.Fill{Type: "pattern", Color: []string{plantilla.ColorCabecera}, Pattern: 1},
+		Alignment: &excelize.Alignment{Horizontal: "center", Vertical: "center"},
+		Border:    bordes,
+	})
+
+	datosStyle, _ := f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Size: tamanoDatos, Family: plantilla.Fuente},
+		Alignment: &excelize.Alignment{Horizontal: "left", Vertical: "center"},
+		Border:    bordes,
+	})
+
+	coeficienteFmt := "0.000"
+	coeficienteStyle, _ := f.NewStyle(&excelize.Style{
+		Font:         &excelize.Font{Size: tamanoDatos, Family: plantilla.Fuente},
+		CustomNumFmt: &coeficienteFmt,
+		Alignment:    &excelize.Alignment{Horizontal: "right", Vertical: "center"},
+		Border:       bordes,
+	})
+
+	incidenciaStyle, _ := f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Size: tamanoDatos, Family: plantilla.Fuente},
+		NumFmt:    10,
+		Alignment: &excelize.Alignment{Horizontal: "right", Vertical: "center"},
+		Border:    bordes,
+	})
+
+	// Título y fórmula
+	f.MergeCell(sheet, "A1", "F1")
+	f.SetCellValue(sheet, "A1", "FÓRMULA POLINÓMICA DE REAJUSTE")
+	f.SetCellStyle(sheet, "A1", "F1", tituloStyle)
+
+	f.MergeCell(sheet, "A3", "F3")
+	f.SetCellValue(sheet, "A3", formula.Formula)
+	f.SetCellStyle(sheet, "A3", "F3", formulaStyle)
+	f.SetRowHeight(sheet, 3, 45)
+
+	// Tabla de agrupamiento
+	row := 5
+	headers := []string{"Monomio", "Coeficiente", "Índice", "Descripción", "Coef. índice", "Incidencia"}
+	for i, header := range headers {
+		f.SetCellValue(sheet, fmt.Sprintf("%c%d", 'A'+i, row), header)
+		f.SetCellStyle(sheet, fmt.Sprintf("%c%d", 'A'+i, row), fmt.Sprintf("%c%d", 'A'+i, row), cabeceraStyle)
+	}
+	row++
+
+	for _, monomio := range formula.Monomios {
+		inicio := row
+		for _, elem := range monomio.Elementos {
+			f.SetCellValue(sheet, fmt.Sprintf("C%d", row), elem.IndiceCodigo)
+			f.SetCellValue(sheet, fmt.Sprintf("D%d", row), elem.IndiceDescripcion)
+			f.SetCellValue(sheet, fmt.Sprintf("E%d", row), elem.Coeficiente)
+			f.SetCellValue(sheet, fmt.Sprintf("F%d", row), elem.Incidencia)
+			f.SetCellStyle(sheet, fmt.Sprintf("C%d", row), fmt.Sprintf("D%d", row), datosStyle)
+			f.SetCellStyle(sheet, fmt.Sprintf("E%d", row), fmt.Sprintf("E%d", row), coeficienteStyle)
+			f.SetCellStyle(sheet, fmt.Sprintf("F%d", row), fmt.Sprintf("F%d", row), incidenciaStyle)
+			row++
+		}
+
+		// El símbolo y coeficiente del monomio abarcan todos sus índices
+		if row-1 > inicio {
+			f.MergeCell(sheet, fmt.Sprintf("A%d", inicio), fmt.Sprintf("A%d", row-1))
+			f.MergeCell(sheet, fmt.Sprintf("B%d", inicio), fmt.Sprintf("B%d", row-1))
+		}
+		f.SetCellValue(sheet, fmt.Sprintf("A%d", inicio), monomio.Simbolo)
+		f.SetCellValue(sheet, fmt.Sprintf("B%d", inicio), monomio.Coeficiente)
+		f.SetCellStyle(sheet, fmt.Sprintf("A%d", inicio), fmt.Sprintf("A%d", row-1), datosStyle)
+		f.SetCellStyle(sheet, fmt.Sprintf("B%d", inicio), fmt.Sprintf("B%d", row-1), coeficienteStyle)
+	}
+
+	return nil
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"goexcel/internal/costing"
+	"goexcel/internal/models"
+)
+
+// grupoPrueba arma un monomio con elementos de igual costo que suman el costo indicado
+func grupoPrueba(costo float64, codigos ...string) grupoMonomio {
+	grupo := grupoMonomio{costo: costo}
+	for _, codigo := range codigos {
+		grupo.elementos = append(grupo.elementos, models.ElementoMonomio{
+			IndiceCodigo: codigo,
+			Costo:        costing.DecimalDesdeFloat(costo / float64(len(codigos))),
+		})
+	}
+	return grupo
+}
+
+func codigosGrupos(grupos []grupoMonomio) [][]string {
+	var codigos [][]string
+	for _, grupo := range grupos {
+		var monomio []string
+		for _, elem := range grupo.elementos {
+			monomio = append(monomio, elem.IndiceCodigo)
+		}
+		codigos = append(codigos, monomio)
+	}
+	return codigos
+}
+
+func TestAgruparMonomios(t *testing.T) {
+	casos := []struct {
+		nombre   string
+		grupos   []grupoMonomio
+		esperado [][]string
+	}{
+		{
+			// 53 pesa 4 %: se fusiona con el menor monomio que lo admite y el empate se ordena por código
+			nombre:   "monomio bajo el 5 % se fusiona",
+			grupos:   []grupoMonomio{grupoPrueba(40, "47"), grupoPrueba(30, "21"), grupoPrueba(26, "03"), grupoPrueba(4, "53")},
+			esperado: [][]string{{"47"}, {"03", "53"}, {"21"}},
+		},
+		{
+			nombre:   "la mano de obra no se fusiona aunque pese menos del 5 %",
+			grupos:   []grupoMonomio{grupoPrueba(60, "21"), grupoPrueba(37, "03"), grupoPrueba(3, "47")},
+			esperado: [][]string{{"21"}, {"03"}, {"47"}},
+		},
+		{
+			// Diez índices de 10 %: se fusionan los dos pares menores hasta quedar ocho monomios
+			nombre: "máximo de ocho monomios",
+			grupos: []grupoMonomio{
+				grupoPrueba(10, "02"), grupoPrueba(10, "03"), grupoPrueba(10, "04"), grupoPrueba(10, "05"), grupoPrueba(10, "13"),
+				grupoPrueba(10, "17"), grupoPrueba(10, "21"), grupoPrueba(10, "30"), grupoPrueba(10, "37"), grupoPrueba(10, "39"),
+			},
+			esperado: [][]string{{"21", "30"}, {"37", "39"}, {"02"}, {"03"}, {"04"}, {"05"}, {"13"}, {"17"}},
+		},
+		{
+			// El menor monomio ya tiene tres índices, así que 53 pasa al siguiente
+			nombre:   "máximo de tres índices por monomio",
+			grupos:   []grupoMonomio{grupoPrueba(50, "47"), grupoPrueba(25, "21"), grupoPrueba(21, "02", "03", "04"), grupoPrueba(4, "53")},
+			esperado: [][]string{{"47"}, {"21", "53"}, {"02", "03", "04"}},
+		},
+	}
+
+	s := NewFormulaPolinomicaService()
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			total := 0.0
+			for _, grupo := range caso.grupos {
+				total += grupo.costo
+			}
+			if got := codigosGrupos(s.agrupar(caso.grupos, total)); !reflect.DeepEqual(got, caso.esperado) {
+				t.Errorf("monomios = %v, se esperaba %v", got, caso.esperado)
+			}
+		})
+	}
+}
+
+func TestRedondearCoeficientes(t *testing.T) {
+	casos := []struct {
+		nombre     string
+		grupos     []grupoMonomio
+		esperados  []float64
+		elementos0 []float64 // coeficientes de los elementos del primer monomio
+	}{
+		{
+			// 0.333 × 3 = 0.999: el milésimo que falta va al monomio de mayor incidencia
+			nombre:     "residuo positivo",
+			grupos:     []grupoMonomio{grupoPrueba(1, "21"), grupoPrueba(1, "03"), grupoPrueba(1, "47")},
+			esperados:  []float64{0.334, 0.333, 0.333},
+			elementos0: []float64{0.334},
+		},
+		{
+			// 0.445 + 0.334 + 0.222 = 1.001: el milésimo que sobra se descuenta del primero
+			nombre:     "residuo negativo",
+			grupos:     []grupoMonomio{grupoPrueba(44.46, "47"), grupoPrueba(33.36, "21"), grupoPrueba(22.18, "03")},
+			esperados:  []float64{0.444, 0.334, 0.222},
+			elementos0: []float64{0.444},
+		},
+		{
+			// Los elementos del monomio reparten su coeficiente y el último absorbe el redondeo
+			nombre: "elementos de un monomio agrupado",
+			grupos: []grupoMonomio{
+				{costo: 6, elementos: []models.ElementoMonomio{
+					{IndiceCodigo: "03", Costo: costing.DebeParsear("4")},
+					{IndiceCodigo: "21", Costo: costing.DebeParsear("2")},
+				}},
+				grupoPrueba(3, "47"),
+			},
+			esperados:  []float64{0.667, 0.333},
+			elementos0: []float64{0.445, 0.222},
+		},
+	}
+
+	s := NewFormulaPolinomicaService()
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			total := 0.0
+			for _, grupo := range caso.grupos {
+				total += grupo.costo
+			}
+			monomios := s.redondear(caso.grupos, total)
+
+			suma := 0.0
+			for i, monomio := range monomios {
+				if math.Abs(monomio.Coeficiente-caso.esperados[i]) > 1e-9 {
+					t.Errorf("coeficiente %s = %.3f, se esperaba %.3f", monomio.Simbolo, monomio.Coeficiente, caso.esperados[i])
+				}
+				suma += monomio.Coeficiente
+			}
+			if math.Abs(suma-1) > 1e-9 {
+				t.Errorf("los coeficientes suman %.6f, se esperaba 1.000", suma)
+			}
+
+			for j, elem := range monomios[0].Elementos {
+				if math.Abs(elem.Coeficiente-caso.elementos0[j]) > 1e-9 {
+					t.Errorf("elemento %s = %.3f, se esperaba %.3f", elem.IndiceCodigo, elem.Coeficiente, caso.elementos0[j])
+				}
+			}
+		})
+	}
+}
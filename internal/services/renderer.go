@@ -0,0 +1,65 @@
+package services
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/xuri/excelize/v2"
+	"goexcel/internal/models"
+)
+
+// Renderer genera un formato de exportación a partir del reporte del proyecto. Los datos se cargan
+// y calculan una sola vez en ConstruirReporte; un formato nuevo solo implementa esta interfaz y se
+// registra en NewRegistroRenderers.
+type Renderer interface {
+	// Renderizar genera el documento completo antes de enviarlo, para poder responder con error si falla
+	Renderizar(reporte *models.ReportePresupuesto) (Documento, error)
+	ContentType() string
+	Extension() string // con punto: ".xlsx"
+}
+
+// Documento es un reporte ya generado, listo para escribirse en la respuesta
+type Documento interface {
+	io.WriterTo
+	io.Closer
+}
+
+// RegistroRenderers asocia cada valor del parámetro format con su renderizador
+type RegistroRenderers map[string]Renderer
+
+func NewRegistroRenderers(insumosSvc *InsumosService, formulaSvc *FormulaPolinomicaService) RegistroRenderers {
+	xlsx := NewRendererXLSX(insumosSvc, formulaSvc)
+	return RegistroRenderers{
+		"excel": xlsx,
+		"xlsx":  xlsx,
+		"csv":   NewRendererCSV(),
+		"ods":   NewRendererODS(),
+		"pdf":   NewRendererPDF(),
+		"html":  NewRendererHTML(),
+		"json":  NewRendererJSON(),
+	}
+}
+
+// documentoMemoria es un documento generado en un buffer
+type documentoMemoria struct {
+	bytes.Buffer
+}
+
+func (d *documentoMemoria) Close() error {
+	return nil
+}
+
+// documentoExcel escribe el libro en la respuesta. excelize arma el zip completo en memoria antes de
+// enviarlo (File.WriteTo usa WriteToBuffer), así que mientras se envía se retiene el .xlsx comprimido
+// entero: unos 5.5 MB con 10 000 partidas. Las filas de las hojas grandes no se retienen como celdas.
+type documentoExcel struct {
+	f *excelize.File
+}
+
+func (d documentoExcel) WriteTo(w io.Writer) (int64, error) {
+	return d.f.WriteTo(w)
+}
+
+func (d documentoExcel) Close() error {
+	return d.f.Close()
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"goexcel/internal/models"
+)
+
+// bomUTF8 hace que Excel abra el CSV como UTF-8 y muestre bien tildes y eñes
+const bomUTF8 = "\ufeff"
+
+// RendererCSV genera un ZIP con un CSV por reporte (presupuesto, APU e insumos), con las columnas
+// de las hojas del libro Excel. El separador de campos y el decimal se toman de las opciones, porque
+// en Perú las hojas de cálculo suelen usar coma decimal y punto y coma entre campos.
+type RendererCSV struct{}
+
+func NewRendererCSV() *RendererCSV {
+	return &RendererCSV{}
+}
+
+func (r *RendererCSV) ContentType() string {
+	return "application/zip"
+}
+
+func (r *RendererCSV) Extension() string {
+	return ".zip"
+}
+
+func (r *RendererCSV) Renderizar(reporte *models.ReportePresupuesto) (Documento, error) {
+	delimitador, separadorDecimal := formatoCSV(reporte.Opciones)
+
+	doc := &documentoMemoria{}
+	archivo := zip.NewWriter(doc)
+	for _, tabla := range tablasReporte(reporte) {
+		nombre := strings.ToLower(tabla.nombre) + ".csv"
+		entrada, err := archivo.CreateHeader(&zip.FileHeader{Name: nombre, Method: zip.Deflate, Modified: reporte.Fecha})
+		if err != nil {
+			return nil, fmt.Errorf("error generando %s: %v", nombre, err)
+		}
+		if _, err := entrada.Write([]byte(bomUTF8)); err != nil {
+			return nil, fmt.Errorf("error generando %s: %v", nombre, err)
+		}
+
+		escritor := csv.NewWriter(entrada)
+		escritor.Comma = delimitador
+		escritor.Write(tabla.columnas)
+		for _, fila := range tabla.filas {
+			valores := make([]string, len(fila))
+			for i, celda := range fila {
+				valores[i] = celda.formatear(separadorDecimal)
+			}
+			escritor.Write(valores)
+		}
+		escritor.Flush()
+		if err := escritor.Error(); err != nil {
+			return nil, fmt.Errorf("error generando %s: %v", nombre, err)
+		}
+	}
+
+	if err := archivo.Close(); err != nil {
+		return nil, fmt.Errorf("error generando ZIP de CSV: %v", err)
+	}
+	return doc, nil
+}
+
+// formatoCSV devuelve el separador de campos y el decimal de las opciones. Con coma decimal y sin
+// separador de campos indicado se usa punto y coma, como en la configuración regional de Perú.
+func formatoCSV(opciones models.OpcionesExportacion) (rune, string) {
+	separadorDecimal := opciones.SeparadorDecimal
+	if separadorDecimal == "" {
+		separadorDecimal = "."
+	}
+
+	if opciones.DelimitadorCSV == "" {
+		if separadorDecimal == "," {
+			return ';', separadorDecimal
+		}
+		return ',', separadorDecimal
+	}
+	delimitador, _ := utf8.DecodeRuneInString(opciones.DelimitadorCSV)
+	return delimitador, separadorDecimal
+}
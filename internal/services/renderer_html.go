@@ -0,0 +1,90 @@
+package services
+
+import (
+	"embed"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+
+	"goexcel/internal/costing"
+	"goexcel/internal/legacy"
+	"goexcel/internal/models"
+)
+
+//go:embed templates/reporte.html
+var plantillasHTML embed.FS
+
+// plantillaReporteHTML se compila una sola vez; un error en la plantilla detiene el arranque
+var plantillaReporteHTML = template.Must(template.New("reporte.html").Funcs(template.FuncMap{
+	"monto":    func(valor interface{}) string { return formatearNumero(decimalPlantilla(valor), 2) },
+	"cantidad": func(valor interface{}) string { return formatearNumero(decimalPlantilla(valor), decimalesCantidad) },
+	"fecha":    func(fecha time.Time) string { return fecha.Format("02/01/2006") },
+	"sangria":  func(nivel int) int { return nivel - 1 },
+}).ParseFS(plantillasHTML, "templates/reporte.html"))
+
+// decimalPlantilla acepta los montos Decimal del reporte y los float64 de rendimiento y cuadrilla
+func decimalPlantilla(valor interface{}) costing.Decimal {
+	if numero, ok := valor.(float64); ok {
+		return costing.DecimalDesdeFloat(numero)
+	}
+	decimal, _ := valor.(costing.Decimal)
+	return decimal
+}
+
+// datosPlantillaHTML es lo que recibe la plantilla además del reporte
+type datosPlantillaHTML struct {
+	Reporte   *models.ReportePresupuesto
+	Plantilla models.PlantillaExcel
+	Pie       []legacy.LineaPie
+	Logo      template.URL
+	Moneda    string
+}
+
+// RendererHTML genera una página autocontenida e imprimible: presupuesto con jerarquía plegable,
+// un ancla por partida hacia su APU y la relación de insumos
+type RendererHTML struct{}
+
+func NewRendererHTML() *RendererHTML {
+	return &RendererHTML{}
+}
+
+func (r *RendererHTML) ContentType() string {
+	return "text/html; charset=utf-8"
+}
+
+func (r *RendererHTML) Extension() string {
+	return ".html"
+}
+
+func (r *RendererHTML) Renderizar(reporte *models.ReportePresupuesto) (Documento, error) {
+	plantilla := legacy.ResolverPlantilla(reporte.Opciones.Plantilla)
+	datos := datosPlantillaHTML{
+		Reporte:   reporte,
+		Plantilla: plantilla,
+		Pie:       legacy.LineasPie(reporte.Pie),
+		Logo:      logoHTML(plantilla),
+		Moneda:    reporte.Opciones.ParametrosCalculo().SimboloMoneda(),
+	}
+
+	doc := &documentoMemoria{}
+	if err := plantillaReporteHTML.Execute(doc, datos); err != nil {
+		return nil, fmt.Errorf("error generando HTML: %v", err)
+	}
+	return doc, nil
+}
+
+// logoHTML incrusta el logo de la plantilla como data URI para que la página no dependa de otros archivos
+func logoHTML(plantilla models.PlantillaExcel) template.URL {
+	if !legacy.TieneLogo(plantilla) {
+		return ""
+	}
+
+	tipos := map[string]string{".png": "image/png", ".jpg": "image/jpeg", ".jpeg": "image/jpeg", ".gif": "image/gif"}
+	tipo, existe := tipos[strings.ToLower(plantilla.LogoExtension)]
+	if !existe {
+		return ""
+	}
+	return template.URL("data:" + tipo + ";base64," + base64.StdEncoding.EncodeToString(plantilla.Logo))
+}
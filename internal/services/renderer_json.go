@@ -0,0 +1,34 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"goexcel/internal/models"
+)
+
+// RendererJSON entrega el reporte calculado tal cual, para integraciones que consumen los montos sin
+// abrir un libro; incluye el precio original y el tipo de cambio de los recursos convertidos
+type RendererJSON struct{}
+
+func NewRendererJSON() *RendererJSON {
+	return &RendererJSON{}
+}
+
+func (r *RendererJSON) ContentType() string {
+	return "application/json"
+}
+
+func (r *RendererJSON) Extension() string {
+	return ".json"
+}
+
+func (r *RendererJSON) Renderizar(reporte *models.ReportePresupuesto) (Documento, error) {
+	doc := &documentoMemoria{}
+	codificador := json.NewEncoder(doc)
+	codificador.SetIndent("", "  ")
+	if err := codificador.Encode(reporte); err != nil {
+		return nil, fmt.Errorf("error generando JSON: %v", err)
+	}
+	return doc, nil
+}
@@ -0,0 +1,149 @@
+package services
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"goexcel/internal/models"
+)
+
+const tipoMIMEODS = "application/vnd.oasis.opendocument.spreadsheet"
+
+// manifiestoODS declara el contenido del paquete OpenDocument
+const manifiestoODS = `<?xml version="1.0" encoding="UTF-8"?>
+<manifest:manifest xmlns:manifest="urn:oasis:names:tc:opendocument:xmlns:manifest:1.0" manifest:version="1.2">
+ <manifest:file-entry manifest:full-path="/" manifest:version="1.2" manifest:media-type="` + tipoMIMEODS + `"/>
+ <manifest:file-entry manifest:full-path="content.xml" manifest:media-type="text/xml"/>
+</manifest:manifest>
+`
+
+// estilosODS define la cabecera en negrita y los formatos numéricos con 2 y 4 decimales
+const estilosODS = `<office:automatic-styles>
+<number:number-style style:name="N2"><number:number number:decimal-places="2" number:min-decimal-places="2" number:min-integer-digits="1" number:grouping="true"/></number:number-style>
+<number:number-style style:name="N4"><number:number number:decimal-places="4" number:min-decimal-places="4" number:min-integer-digits="1" number:grouping="true"/></number:number-style>
+<style:style style:name="cabecera" style:family="table-cell"><style:text-properties fo:font-weight="bold"/></style:style>
+<style:style style:name="numero2" style:family="table-cell" style:data-style-name="N2"/>
+<style:style style:name="numero4" style:family="table-cell" style:data-style-name="N4"/>
+`
+
+// RendererODS genera una hoja de cálculo OpenDocument con una hoja por reporte (presupuesto, APU e
+// insumos) y las mismas columnas que los CSV. Se escribe el XML directamente, sin dependencias.
+type RendererODS struct{}
+
+func NewRendererODS() *RendererODS {
+	return &RendererODS{}
+}
+
+func (r *RendererODS) ContentType() string {
+	return tipoMIMEODS
+}
+
+func (r *RendererODS) Extension() string {
+	return ".ods"
+}
+
+func (r *RendererODS) Renderizar(reporte *models.ReportePresupuesto) (Documento, error) {
+	doc := &documentoMemoria{}
+	archivo := zip.NewWriter(doc)
+
+	// El tipo MIME debe ser la primera entrada y sin comprimir para que se reconozca el formato
+	mimetype, err := archivo.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store, Modified: reporte.Fecha})
+	if err == nil {
+		_, err = io.WriteString(mimetype, tipoMIMEODS)
+	}
+	if err == nil {
+		var manifiesto io.Writer
+		if manifiesto, err = archivo.CreateHeader(&zip.FileHeader{Name: "META-INF/manifest.xml", Method: zip.Deflate, Modified: reporte.Fecha}); err == nil {
+			_, err = io.WriteString(manifiesto, manifiestoODS)
+		}
+	}
+	if err == nil {
+		var contenido io.Writer
+		if contenido, err = archivo.CreateHeader(&zip.FileHeader{Name: "content.xml", Method: zip.Deflate, Modified: reporte.Fecha}); err == nil {
+			err = escribirContenidoODS(contenido, tablasReporte(reporte))
+		}
+	}
+	if err == nil {
+		err = archivo.Close()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error generando ODS: %v", err)
+	}
+	return doc, nil
+}
+
+// escribirContenidoODS escribe content.xml con una tabla por reporte
+func escribirContenidoODS(w io.Writer, tablas []tablaReporte) error {
+	b := bufio.NewWriter(w)
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<office:document-content xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0" xmlns:style="urn:oasis:names:tc:opendocument:xmlns:style:1.0" xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0" xmlns:table="urn:oasis:names:tc:opendocument:xmlns:table:1.0" xmlns:number="urn:oasis:names:tc:opendocument:xmlns:datastyle:1.0" xmlns:fo="urn:oasis:names:tc:opendocument:xmlns:xsl-fo-compatible:1.0" office:version="1.2">
+`)
+
+	// Un estilo de columna por cada ancho usado, en caracteres como en Excel
+	b.WriteString(estilosODS)
+	estilosColumna := make(map[float64]string)
+	for _, tabla := range tablas {
+		for _, ancho := range tabla.anchos {
+			if _, existe := estilosColumna[ancho]; existe {
+				continue
+			}
+			nombre := fmt.Sprintf("co%d", len(estilosColumna)+1)
+			estilosColumna[ancho] = nombre
+			fmt.Fprintf(b, `<style:style style:name="%s" style:family="table-column"><style:table-column-properties style:column-width="%scm"/></style:style>`+"\n",
+				nombre, strconv.FormatFloat(ancho*0.2, 'f', 2, 64))
+		}
+	}
+	b.WriteString("</office:automatic-styles>\n<office:body><office:spreadsheet>\n")
+
+	for _, tabla := range tablas {
+		fmt.Fprintf(b, `<table:table table:name="%s">`+"\n", escaparXML(tabla.nombre))
+		for _, ancho := range tabla.anchos {
+			fmt.Fprintf(b, `<table:table-column table:style-name="%s"/>`, estilosColumna[ancho])
+		}
+		b.WriteString("\n<table:table-row>")
+		for _, columna := range tabla.columnas {
+			fmt.Fprintf(b, `<table:table-cell table:style-name="cabecera" office:value-type="string"><text:p>%s</text:p></table:table-cell>`, escaparXML(columna))
+		}
+		b.WriteString("</table:table-row>\n")
+
+		for _, fila := range tabla.filas {
+			b.WriteString("<table:table-row>")
+			for _, celda := range fila {
+				escribirCeldaODS(b, celda)
+			}
+			b.WriteString("</table:table-row>\n")
+		}
+		b.WriteString("</table:table>\n")
+	}
+
+	b.WriteString("</office:spreadsheet></office:body></office:document-content>\n")
+	return b.Flush()
+}
+
+// escribirCeldaODS escribe los números con su valor exacto y el formato de sus decimales
+func escribirCeldaODS(b *bufio.Writer, celda celdaTabla) {
+	switch {
+	case celda.esNumero:
+		estilo := "numero2"
+		if celda.decimales > 2 {
+			estilo = "numero4"
+		}
+		fmt.Fprintf(b, `<table:table-cell table:style-name="%s" office:value-type="float" office:value="%s"><text:p>%s</text:p></table:table-cell>`,
+			estilo, celda.numero.String(), celda.formatear("."))
+	case celda.texto == "":
+		b.WriteString("<table:table-cell/>")
+	default:
+		fmt.Fprintf(b, `<table:table-cell office:value-type="string"><text:p>%s</text:p></table:table-cell>`, escaparXML(celda.texto))
+	}
+}
+
+func escaparXML(texto string) string {
+	var escapado strings.Builder
+	xml.EscapeText(&escapado, []byte(texto))
+	return escapado.String()
+}
@@ -0,0 +1,485 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+	"goexcel/internal/costing"
+	"goexcel/internal/legacy"
+	"goexcel/internal/models"
+)
+
+// Medidas del reporte PDF en milímetros sobre papel A4 vertical
+const (
+	margenPDF         = 15.0
+	margenSuperiorPDF = 32.0 // deja espacio al encabezado con logo
+	margenInferiorPDF = 18.0
+	anchoUtilPDF      = 180.0
+	altoLineaPDF      = 4.5
+	altoLogoPDF       = 14.0
+	tamanoFuentePDF   = 8.0
+	fuentePDF         = "Helvetica" // fuente estándar del PDF: no requiere archivos de fuentes
+	decimalesCantidad = 4
+)
+
+// columnaPDF describe una columna de las tablas del reporte
+type columnaPDF struct {
+	titulo  string
+	ancho   float64
+	alinear string
+}
+
+// columnasPresupuesto, columnasAPU y columnasInsumos son las columnas de cada tabla con los montos
+// rotulados en la moneda del proyecto; los anchos suman el ancho útil de la página
+func columnasPresupuesto(moneda string) []columnaPDF {
+	return []columnaPDF{
+		{"Ítem", 20, "L"},
+		{"Descripción", 78, "L"},
+		{"Und.", 12, "C"},
+		{"Metrado", 20, "R"},
+		{"Precio " + moneda, 24, "R"},
+		{"Parcial " + moneda, 26, "R"},
+	}
+}
+
+func columnasAPU(moneda string) []columnaPDF {
+	return []columnaPDF{
+		{"Código", 18, "L"},
+		{"Descripción", 62, "L"},
+		{"Und.", 12, "C"},
+		{"Cuadrilla", 18, "R"},
+		{"Cantidad", 20, "R"},
+		{"Precio " + moneda, 24, "R"},
+		{"Parcial " + moneda, 26, "R"},
+	}
+}
+
+func columnasInsumos(moneda string) []columnaPDF {
+	return []columnaPDF{
+		{"Código", 20, "L"},
+		{"Descripción", 76, "L"},
+		{"Und.", 12, "C"},
+		{"Cantidad", 22, "R"},
+		{"Precio " + moneda, 24, "R"},
+		{"Parcial " + moneda, 26, "R"},
+	}
+}
+
+// RendererPDF genera el reporte del proyecto (presupuesto con su pie, APU y relación de insumos).
+// Se genera en Go puro con las fuentes estándar de PDF, sin dependencias externas.
+type RendererPDF struct{}
+
+func NewRendererPDF() *RendererPDF {
+	return &RendererPDF{}
+}
+
+func (r *RendererPDF) ContentType() string {
+	return "application/pdf"
+}
+
+func (r *RendererPDF) Extension() string {
+	return ".pdf"
+}
+
+// Renderizar arma el PDF en memoria: fpdf solo informa los errores al terminar el documento
+func (r *RendererPDF) Renderizar(reporte *models.ReportePresupuesto) (Documento, error) {
+	g := nuevoGeneradorPDF(legacy.ResolverPlantilla(reporte.Opciones.Plantilla), reporte.Proyecto, reporte.Fecha)
+	g.moneda = reporte.Opciones.ParametrosCalculo().SimboloMoneda()
+
+	g.presupuesto(reporte)
+	g.apus(reporte.Partidas)
+	if reporte.Insumos != nil && len(reporte.Insumos.Grupos) > 0 {
+		g.insumos(reporte.Insumos)
+	}
+
+	if err := g.pdf.Error(); err != nil {
+		return nil, fmt.Errorf("error generando PDF: %v", err)
+	}
+	doc := &documentoMemoria{}
+	if err := g.pdf.Output(doc); err != nil {
+		return nil, fmt.Errorf("error generando PDF: %v", err)
+	}
+	return doc, nil
+}
+
+// generadorPDF mantiene el documento y la tabla en curso para repetir sus cabeceras al cambiar de página
+type generadorPDF struct {
+	pdf       *fpdf.Fpdf
+	tr        func(string) string
+	plantilla models.PlantillaExcel
+	moneda    string // símbolo de la moneda del proyecto
+	columnas  []columnaPDF
+	conLogo   bool
+}
+
+func nuevoGeneradorPDF(plantilla models.PlantillaExcel, proyecto string, fecha time.Time) *generadorPDF {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	g := &generadorPDF{
+		pdf: pdf,
+		// cp1252 cubre tildes, ñ, ° y ² ³ con las fuentes estándar
+		tr:        pdf.UnicodeTranslatorFromDescriptor("cp1252"),
+		plantilla: plantilla,
+	}
+
+	pdf.SetMargins(margenPDF, margenSuperiorPDF, margenPDF)
+	pdf.SetAutoPageBreak(false, margenInferiorPDF)
+	pdf.SetTitle(proyecto, true)
+	pdf.SetCreator(plantilla.Empresa, true)
+	pdf.AliasNbPages("")
+	g.registrarLogo()
+
+	pdf.SetHeaderFunc(func() { g.encabezado(proyecto) })
+	pdf.SetFooterFunc(func() { g.piePagina(fecha) })
+	return g
+}
+
+// registrarLogo carga el logo de la plantilla; si la imagen no es válida el reporte sale sin logo
+func (g *generadorPDF) registrarLogo() {
+	if !legacy.TieneLogo(g.plantilla) {
+		return
+	}
+
+	tipo := ""
+	switch strings.ToLower(g.plantilla.LogoExtension) {
+	case ".png":
+		tipo = "PNG"
+	case ".jpg", ".jpeg":
+		tipo = "JPG"
+	case ".gif":
+		tipo = "GIF"
+	default:
+		return
+	}
+
+	g.pdf.RegisterImageOptionsReader("logo", fpdf.ImageOptions{ImageType: tipo}, bytes.NewReader(g.plantilla.Logo))
+	if g.pdf.Err() {
+		g.pdf.ClearError()
+		return
+	}
+	g.conLogo = true
+}
+
+// encabezado dibuja logo, empresa, proyecto y RUC en cada página
+func (g *generadorPDF) encabezado(proyecto string) {
+	pdf := g.pdf
+	if g.conLogo {
+		pdf.ImageOptions("logo", margenPDF, 10, 0, altoLogoPDF, false, fpdf.ImageOptions{}, 0, "")
+	}
+
+	pdf.SetTextColor(0, 0, 0)
+	pdf.SetXY(margenPDF, 10)
+	if g.plantilla.Empresa != "" {
+		pdf.SetFont(fuentePDF, "B", 11)
+		pdf.CellFormat(anchoUtilPDF, 6, g.tr(g.plantilla.Empresa), "", 2, "C", false, 0, "")
+	}
+	if proyecto != "" {
+		pdf.SetFont(fuentePDF, "", 9)
+		pdf.SetX(margenPDF + 35)
+		pdf.MultiCell(anchoUtilPDF-70, 4.5, g.tr(proyecto), "", "C", false)
+	}
+	if g.plantilla.RUC != "" {
+		pdf.SetFont(fuentePDF, "", 8)
+		pdf.SetXY(margenPDF, 10)
+		pdf.CellFormat(anchoUtilPDF, 5, g.tr("RUC: "+g.plantilla.RUC), "", 0, "R", false, 0, "")
+	}
+
+	g.colorDibujo(g.plantilla.ColorTitulo)
+	pdf.SetLineWidth(0.4)
+	pdf.Line(margenPDF, margenSuperiorPDF-4, margenPDF+anchoUtilPDF, margenSuperiorPDF-4)
+	pdf.SetLineWidth(0.2)
+	pdf.SetXY(margenPDF, margenSuperiorPDF)
+}
+
+// piePagina dibuja el responsable con su CIP, la numeración "Página X de Y" y la fecha
+func (g *generadorPDF) piePagina(fecha time.Time) {
+	pdf := g.pdf
+	_, alto := pdf.GetPageSize()
+	y := alto - margenInferiorPDF + 6
+
+	pdf.SetTextColor(0, 0, 0)
+	pdf.SetFont(fuentePDF, "", 7)
+	if g.plantilla.Ingeniero != "" {
+		responsable := "Ing. " + g.plantilla.Ingeniero
+		if g.plantilla.CIP != "" {
+			responsable += " - CIP " + g.plantilla.CIP
+		}
+		pdf.SetXY(margenPDF, y)
+		pdf.CellFormat(anchoUtilPDF, 4, g.tr(responsable), "", 0, "L", false, 0, "")
+	}
+
+	pdf.SetXY(margenPDF, y)
+	pdf.CellFormat(anchoUtilPDF, 4, g.tr(fmt.Sprintf("Página %d de {nb}", pdf.PageNo())), "", 0, "C", false, 0, "")
+	pdf.SetXY(margenPDF, y)
+	pdf.CellFormat(anchoUtilPDF, 4, fecha.Format("02/01/2006"), "", 0, "R", false, 0, "")
+}
+
+// presupuesto imprime el árbol del presupuesto, con el subtotal de cada título, y el pie
+func (g *generadorPDF) presupuesto(reporte *models.ReportePresupuesto) {
+	g.nuevaSeccion("PRESUPUESTO")
+	g.iniciarTabla(columnasPresupuesto(g.moneda))
+
+	reporte.Recorrer(func(nodo *models.NodoReporte) {
+		if nodo.EsTitulo() {
+			g.filaCombinada(strings.TrimSpace(nodo.Codigo+" "+nodo.Descripcion), formatearNumero(nodo.Subtotal, 2), g.plantilla.ColorSeccion)
+			return
+		}
+
+		partida := nodo.Partida
+		g.fila([]string{
+			partida.Codigo,
+			partida.Descripcion,
+			partida.Unidad,
+			formatearNumero(partida.Metrado, 2),
+			formatearNumero(partida.CostoUnitario, 2),
+			formatearNumero(partida.Parcial, 2),
+		})
+	})
+
+	g.piePresupuesto(reporte.Pie)
+}
+
+// piePresupuesto imprime el cierre del presupuesto, siempre en un solo bloque
+func (g *generadorPDF) piePresupuesto(pie models.PiePresupuesto) {
+	lineas := legacy.LineasPie(pie)
+
+	g.columnas = nil
+	g.reservar(float64(len(lineas)+1) * altoLineaPDF)
+	g.pdf.Ln(altoLineaPDF)
+
+	anchoEtiqueta := anchoUtilPDF - 50
+	for _, linea := range lineas {
+		color := g.plantilla.ColorSeccion
+		if linea.Total {
+			color = g.plantilla.ColorTotal
+		}
+		g.colorRelleno(color)
+		g.pdf.SetFont(fuentePDF, "B", tamanoFuentePDF)
+		g.pdf.CellFormat(anchoEtiqueta, altoLineaPDF+1, g.tr(linea.Etiqueta), "1", 0, "R", true, 0, "")
+		g.pdf.CellFormat(50, altoLineaPDF+1, g.tr(g.moneda+" "+formatearNumero(linea.Monto, 2)), "1", 1, "R", true, 0, "")
+	}
+}
+
+// apus imprime el análisis de precios unitarios de cada partida sin partirlo entre páginas cuando cabe en una
+func (g *generadorPDF) apus(partidas []*models.PartidaReporte) {
+	g.nuevaSeccion("ANÁLISIS DE PRECIOS UNITARIOS")
+
+	for _, partida := range partidas {
+		filas := 4 // encabezado, datos, cabecera de tabla y total
+		for _, seccion := range partida.Secciones {
+			if len(seccion.Recursos) > 0 {
+				filas += len(seccion.Recursos) + 2
+			}
+		}
+
+		g.columnas = nil
+		g.reservar(float64(filas)*altoLineaPDF + altoLineaPDF)
+		if g.pdf.GetY() > margenSuperiorPDF {
+			g.pdf.Ln(altoLineaPDF)
+		}
+
+		g.barra(fmt.Sprintf("Partida %s - %s", partida.Codigo, partida.Descripcion), g.plantilla.ColorPartida)
+
+		g.pdf.SetFont(fuentePDF, "", tamanoFuentePDF)
+		g.pdf.SetTextColor(0, 0, 0)
+		datos := fmt.Sprintf("Unidad: %s      Rendimiento: %s %s/día      Costo unitario: %s %s",
+			partida.Unidad, formatearNumero(costing.DecimalDesdeFloat(partida.Rendimiento), 2), partida.Unidad,
+			g.moneda, formatearNumero(partida.CostoUnitario, 2))
+		g.pdf.CellFormat(anchoUtilPDF, altoLineaPDF+1, g.tr(datos), "", 1, "L", false, 0, "")
+
+		g.iniciarTabla(columnasAPU(g.moneda))
+		for _, seccion := range partida.Secciones {
+			if len(seccion.Recursos) == 0 {
+				continue
+			}
+
+			g.filaCombinada(seccion.Nombre, "", g.plantilla.ColorSeccion)
+			for _, recurso := range seccion.Recursos {
+				cuadrilla := "-"
+				if recurso.Cuadrilla > 0 {
+					cuadrilla = formatearNumero(costing.DecimalDesdeFloat(recurso.Cuadrilla), decimalesCantidad)
+				}
+
+				g.fila([]string{
+					recurso.Codigo,
+					recurso.Descripcion,
+					recurso.Unidad,
+					cuadrilla,
+					formatearNumero(recurso.Cantidad, decimalesCantidad),
+					formatearNumero(recurso.Precio, 2),
+					formatearNumero(recurso.Parcial, 2),
+				})
+			}
+			g.filaCombinada("SUBTOTAL "+seccion.Nombre, formatearNumero(seccion.Subtotal, 2), g.plantilla.ColorSeccion)
+		}
+		g.filaCombinada(fmt.Sprintf("COSTO UNITARIO - PARTIDA %s", partida.Codigo), formatearNumero(partida.CostoUnitario, 2), g.plantilla.ColorTotal)
+	}
+}
+
+// insumos imprime la relación de insumos agrupada por tipo de recurso
+func (g *generadorPDF) insumos(relacion *models.RelacionInsumos) {
+	g.nuevaSeccion("RELACIÓN DE INSUMOS")
+	g.iniciarTabla(columnasInsumos(g.moneda))
+
+	for _, grupo := range relacion.Grupos {
+		g.filaCombinada(grupo.Nombre, "", g.plantilla.ColorSeccion)
+		for _, insumo := range grupo.Insumos {
+			g.fila([]string{
+				insumo.Codigo,
+				insumo.Descripcion,
+				insumo.Unidad,
+				formatearNumero(insumo.Cantidad, decimalesCantidad),
+				formatearNumero(insumo.Precio, 2),
+				formatearNumero(insumo.CostoTotal, 2),
+			})
+		}
+		g.filaCombinada("SUBTOTAL "+grupo.Nombre, formatearNumero(grupo.Subtotal, 2), g.plantilla.ColorSeccion)
+	}
+	g.filaCombinada("TOTAL INSUMOS", formatearNumero(relacion.TotalInsumos, 2), g.plantilla.ColorTotal)
+}
+
+// nuevaSeccion empieza cada reporte en una página nueva con su título
+func (g *generadorPDF) nuevaSeccion(titulo string) {
+	g.columnas = nil
+	g.pdf.AddPage()
+	g.barra(titulo, g.plantilla.ColorTitulo)
+	g.pdf.Ln(2)
+}
+
+// barra dibuja una franja de ancho completo con texto blanco
+func (g *generadorPDF) barra(texto, color string) {
+	g.colorRelleno(color)
+	g.pdf.SetTextColor(255, 255, 255)
+	g.pdf.SetFont(fuentePDF, "B", tamanoFuentePDF+2)
+	g.pdf.MultiCell(anchoUtilPDF, altoLineaPDF+2, g.tr(texto), "1", "L", true)
+	g.pdf.SetTextColor(0, 0, 0)
+}
+
+// iniciarTabla dibuja la cabecera de la tabla y la recuerda para repetirla en cada página
+func (g *generadorPDF) iniciarTabla(columnas []columnaPDF) {
+	g.columnas = nil
+	g.reservar(2 * altoLineaPDF)
+	g.columnas = columnas
+	g.cabeceraTabla()
+}
+
+func (g *generadorPDF) cabeceraTabla() {
+	g.colorRelleno(g.plantilla.ColorCabecera)
+	g.pdf.SetTextColor(255, 255, 255)
+	g.pdf.SetFont(fuentePDF, "B", tamanoFuentePDF)
+	for _, columna := range g.columnas {
+		g.pdf.CellFormat(columna.ancho, altoLineaPDF+1, g.tr(columna.titulo), "1", 0, "C", true, 0, "")
+	}
+	g.pdf.Ln(-1)
+	g.pdf.SetTextColor(0, 0, 0)
+}
+
+// fila imprime una fila de la tabla en curso; las celdas largas se ajustan en varias líneas
+func (g *generadorPDF) fila(valores []string) {
+	pdf := g.pdf
+	pdf.SetFont(fuentePDF, "", tamanoFuentePDF)
+
+	lineas := make([][]string, len(valores))
+	maxLineas := 1
+	for i, valor := range valores {
+		for _, linea := range pdf.SplitLines([]byte(g.tr(valor)), g.columnas[i].ancho-2) {
+			lineas[i] = append(lineas[i], string(linea))
+		}
+		if len(lineas[i]) > maxLineas {
+			maxLineas = len(lineas[i])
+		}
+	}
+	alto := float64(maxLineas) * altoLineaPDF
+
+	g.reservar(alto)
+
+	x, y := pdf.GetXY()
+	for i, columna := range g.columnas {
+		pdf.Rect(x, y, columna.ancho, alto, "D")
+		for j, linea := range lineas[i] {
+			pdf.SetXY(x, y+float64(j)*altoLineaPDF)
+			pdf.CellFormat(columna.ancho, altoLineaPDF, linea, "", 0, columna.alinear, false, 0, "")
+		}
+		x += columna.ancho
+	}
+	pdf.SetXY(margenPDF, y+alto)
+}
+
+// filaCombinada imprime una fila de sección o subtotal: texto en las primeras columnas y monto en la última
+func (g *generadorPDF) filaCombinada(texto, monto, color string) {
+	g.reservar(altoLineaPDF + 1)
+
+	ultima := g.columnas[len(g.columnas)-1]
+	g.colorRelleno(color)
+	g.pdf.SetFont(fuentePDF, "B", tamanoFuentePDF)
+	g.pdf.SetTextColor(0, 0, 0)
+	if color == g.plantilla.ColorTotal {
+		g.pdf.SetTextColor(255, 255, 255)
+	}
+	g.pdf.CellFormat(anchoUtilPDF-ultima.ancho, altoLineaPDF+1, g.tr(texto), "1", 0, "L", true, 0, "")
+	g.pdf.CellFormat(ultima.ancho, altoLineaPDF+1, monto, "1", 1, "R", true, 0, "")
+	g.pdf.SetTextColor(0, 0, 0)
+}
+
+// reservar pasa a una página nueva si el alto indicado no entra en la actual (y sí en una página
+// vacía), repitiendo la cabecera de la tabla en curso
+func (g *generadorPDF) reservar(alto float64) {
+	_, altoPagina := g.pdf.GetPageSize()
+	limite := altoPagina - margenInferiorPDF
+	if g.pdf.GetY()+alto <= limite || g.pdf.GetY() <= margenSuperiorPDF {
+		return
+	}
+	if alto > limite-margenSuperiorPDF && g.columnas == nil {
+		// Bloques más altos que una página se parten de todas formas
+		return
+	}
+
+	g.pdf.AddPage()
+	if g.columnas != nil {
+		g.cabeceraTabla()
+	}
+}
+
+func (g *generadorPDF) colorRelleno(hex string) {
+	r, v, a := colorRGB(hex)
+	g.pdf.SetFillColor(r, v, a)
+}
+
+func (g *generadorPDF) colorDibujo(hex string) {
+	r, v, a := colorRGB(hex)
+	g.pdf.SetDrawColor(r, v, a)
+}
+
+// colorRGB convierte un color #RRGGBB de la plantilla a sus componentes
+func colorRGB(hex string) (int, int, int) {
+	valor, err := strconv.ParseUint(strings.TrimPrefix(hex, "#"), 16, 32)
+	if err != nil || len(hex) != 7 {
+		return 0, 0, 0
+	}
+	return int(valor >> 16 & 0xFF), int(valor >> 8 & 0xFF), int(valor & 0xFF)
+}
+
+// formatearNumero escribe un número con separador de miles y los decimales indicados: 1,234.50
+func formatearNumero(valor costing.Decimal, decimales int) string {
+	texto := valor.Abs().StringFijo(int32(decimales))
+	entero, fraccion := texto, ""
+	if punto := strings.IndexByte(texto, '.'); punto >= 0 {
+		entero, fraccion = texto[:punto], texto[punto:]
+	}
+
+	var resultado strings.Builder
+	if valor.Signo() < 0 && strings.Trim(texto, "0.") != "" {
+		resultado.WriteByte('-')
+	}
+	for i, digito := range entero {
+		if i > 0 && (len(entero)-i)%3 == 0 {
+			resultado.WriteByte(',')
+		}
+		resultado.WriteRune(digito)
+	}
+	resultado.WriteString(fraccion)
+	return resultado.String()
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"log"
+
+	"github.com/xuri/excelize/v2"
+	"goexcel/internal/legacy"
+	"goexcel/internal/models"
+)
+
+// RendererXLSX genera el libro Excel: ACUs y Resumen del generador legacy, el presupuesto con su pie
+// y, como anexos, la relación de insumos, la fórmula polinómica, el cálculo de mano de obra y los gráficos
+type RendererXLSX struct {
+	insumosSvc *InsumosService
+	formulaSvc *FormulaPolinomicaService
+}
+
+func NewRendererXLSX(insumosSvc *InsumosService, formulaSvc *FormulaPolinomicaService) *RendererXLSX {
+	return &RendererXLSX{
+		insumosSvc: insumosSvc,
+		formulaSvc: formulaSvc,
+	}
+}
+
+func (r *RendererXLSX) ContentType() string {
+	return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+}
+
+func (r *RendererXLSX) Extension() string {
+	return ".xlsx"
+}
+
+// Renderizar arma el libro en memoria; se escribe en la respuesta sin archivo temporal
+func (r *RendererXLSX) Renderizar(reporte *models.ReportePresupuesto) (Documento, error) {
+	f, err := r.Construir(reporte)
+	if err != nil {
+		return nil, err
+	}
+	return documentoExcel{f: f}, nil
+}
+
+// Construir genera el libro para que el llamador pueda agregarle hojas antes de escribirlo.
+// Los anexos son opcionales: si uno falla se registra y se exporta el resto del libro.
+func (r *RendererXLSX) Construir(reporte *models.ReportePresupuesto) (*excelize.File, error) {
+	partidas := partidasLegacyDesdeReporte(reporte)
+	f, err := legacy.ConstruirExcel(partidas, reporte.Opciones)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := legacy.AgregarHojaPresupuesto(f, reporte); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	if relacion := reporte.Insumos; relacion != nil {
+		plantilla := legacy.ResolverPlantilla(reporte.Opciones.Plantilla)
+		if !relacion.Conciliado {
+			log.Printf("⚠️ Relación de insumos no concilia con el costo directo (diferencia: %.4f)", relacion.Diferencia)
+		}
+		if err := r.insumosSvc.AgregarHojaInsumos(f, relacion, reporte.Opciones.ParametrosCalculo(), plantilla); err != nil {
+			log.Printf("⚠️ Error agregando hoja de insumos: %v", err)
+		}
+
+		formula, err := r.formulaSvc.Calcular(relacion)
+		if err != nil {
+			log.Printf("⚠️ No se pudo calcular la fórmula polinómica: %v", err)
+		} else if err := r.formulaSvc.AgregarHojaFormula(f, formula, plantilla); err != nil {
+			log.Printf("⚠️ Error agregando hoja de fórmula polinómica: %v", err)
+		}
+	}
+
+	if reporte.ManoObra != nil {
+		if err := legacy.AgregarHojaManoObra(f, reporte.ManoObra, reporte.Opciones); err != nil {
+			log.Printf("⚠️ Error agregando hoja de mano de obra: %v", err)
+		}
+	}
+
+	if len(reporte.Equipos) > 0 {
+		if err := legacy.AgregarHojaEquipos(f, reporte.Equipos, reporte.Opciones); err != nil {
+			log.Printf("⚠️ Error agregando hoja de equipos: %v", err)
+		}
+	}
+
+	if reporte.Flete != nil && len(reporte.Flete.Materiales) > 0 {
+		if err := legacy.AgregarHojaFlete(f, reporte.Flete, reporte.Opciones); err != nil {
+			log.Printf("⚠️ Error agregando hoja de flete: %v", err)
+		}
+	}
+
+	// Gráficos de distribución de costos; sin metrados se grafican los costos unitarios
+	datosGraficos := legacy.DatosGraficos{
+		Metrados: reporte.Metrados(),
+		Titulos:  titulosPrimerNivel(reporte),
+		Reglas:   reporte.Opciones.ParametrosCalculo().Reglas(),
+	}
+	if err := legacy.AgregarHojaGraficos(f, partidas, datosGraficos, reporte.Opciones.Plantilla); err != nil {
+		log.Printf("⚠️ Error agregando hoja de gráficos: %v", err)
+	}
+
+	return f, nil
+}
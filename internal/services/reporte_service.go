@@ -0,0 +1,247 @@
+package services
+
+import (
+	"strings"
+	"time"
+
+	"goexcel/internal/costing"
+	"goexcel/internal/legacy"
+	"goexcel/internal/models"
+)
+
+// DatosReporte son los datos del proyecto con los que se construye el reporte; Metrados, Titulos,
+// TiposCambio, ManoObra, Equipos y Flete son opcionales
+type DatosReporte struct {
+	Partidas []legacy.PartidaLegacy
+	Metrados map[string]costing.Decimal // por código de partida
+	Titulos  map[string]string          // descripción de cada título por código
+	Opciones models.OpcionesExportacion
+
+	// TiposCambio convierte los precios de recursos cotizados en otra moneda; sin tasa, el precio se
+	// usa tal cual
+	TiposCambio *models.TasasCambio
+
+	// ManoObra es el cálculo del costo hora-hombre con los parámetros de construcción civil vigentes;
+	// se presenta como anexo y no cambia los precios del APU
+	ManoObra *models.CalculoManoObra
+
+	// Equipos es el costo horario de los equipos del presupuesto que tienen análisis; como la mano de
+	// obra, es un anexo
+	Equipos []models.CostoHorarioEquipo
+
+	// Flete es la ruta y los pesos unitarios del proyecto: según su modo, el flete de cada material se
+	// suma a su precio o se agrega como línea de subcontrato en el APU
+	Flete *models.FleteProyecto
+}
+
+// ConstruirReporte calcula el reporte del proyecto que comparten todos los formatos de exportación.
+// Los títulos del árbol se deducen de los códigos de partida ("01.02" agrupa a "01.02.03") y su
+// subtotal es la suma de los parciales que contienen. Los montos siguen las reglas de redondeo de costing
+// con los decimales de los parámetros del proyecto.
+func ConstruirReporte(datos DatosReporte) *models.ReportePresupuesto {
+	parametros := datos.Opciones.ParametrosCalculo()
+	reglas := parametros.Reglas()
+	reporte := &models.ReportePresupuesto{
+		Proyecto: datos.Opciones.Proyecto,
+		Fecha:    time.Now(),
+		Opciones: datos.Opciones,
+
+		TiposCambio: datos.TiposCambio,
+		ManoObra:    datos.ManoObra,
+		Equipos:     datos.Equipos,
+	}
+
+	titulos := make(map[string]*models.NodoReporte)
+	var nodoTitulo func(codigo string) *models.NodoReporte
+	nodoTitulo = func(codigo string) *models.NodoReporte {
+		if nodo, existe := titulos[codigo]; existe {
+			return nodo
+		}
+		nodo := &models.NodoReporte{
+			Codigo:      codigo,
+			Descripcion: datos.Titulos[codigo],
+			Nivel:       strings.Count(codigo, ".") + 1,
+		}
+		titulos[codigo] = nodo
+		agregarNodo(reporte, reglas, nodoTitulo, nodo)
+		return nodo
+	}
+
+	costoDirecto := costing.Decimal{}
+	for _, partidaLegacy := range datos.Partidas {
+		// Igual que en el libro legacy, las partidas incompletas no se exportan
+		if partidaLegacy.Codigo == "" || partidaLegacy.Descripcion == "" {
+			continue
+		}
+
+		partida := nuevaPartidaReporte(partidaLegacy, datos.Metrados[partidaLegacy.Codigo], parametros, datos.TiposCambio, datos.Flete)
+		reporte.Partidas = append(reporte.Partidas, partida)
+		costoDirecto = reglas.Sumar(costoDirecto, partida.Parcial)
+
+		agregarNodo(reporte, reglas, nodoTitulo, &models.NodoReporte{
+			Codigo:      partida.Codigo,
+			Descripcion: partida.Descripcion,
+			Nivel:       strings.Count(partida.Codigo, ".") + 1,
+			Partida:     partida,
+			Subtotal:    partida.Parcial,
+		})
+	}
+
+	if datos.Flete != nil {
+		reporte.Flete = calcularFlete(reporte.Partidas, datos.Flete, reglas)
+	}
+
+	reporte.Pie = models.NuevoPiePresupuesto(costoDirecto, datos.Opciones.GastosGenerales, datos.Opciones.Utilidad, parametros)
+	return reporte
+}
+
+// agregarNodo cuelga el nodo de su título padre, creándolo si hace falta, y suma su subtotal a
+// todos los títulos que lo contienen
+func agregarNodo(reporte *models.ReportePresupuesto, reglas costing.Reglas, nodoTitulo func(string) *models.NodoReporte, nodo *models.NodoReporte) {
+	ultimoPunto := strings.LastIndex(nodo.Codigo, ".")
+	if ultimoPunto <= 0 {
+		reporte.Arbol = append(reporte.Arbol, nodo)
+		return
+	}
+
+	padre := nodoTitulo(nodo.Codigo[:ultimoPunto])
+	padre.Hijos = append(padre.Hijos, nodo)
+	for codigo := padre.Codigo; !nodo.Subtotal.EsCero(); {
+		titulo := nodoTitulo(codigo)
+		titulo.Subtotal = reglas.Sumar(titulo.Subtotal, nodo.Subtotal)
+		punto := strings.LastIndex(codigo, ".")
+		if punto <= 0 {
+			break
+		}
+		codigo = codigo[:punto]
+	}
+}
+
+// nuevaPartidaReporte calcula el APU de una partida y su parcial en el presupuesto. Si los parámetros lo
+// indican, la cantidad de mano de obra y equipos con cuadrilla se deduce de la cuadrilla y la jornada.
+// Los precios en otra moneda se convierten a la del presupuesto antes de calcular el parcial, y después
+// se aplica el flete de los materiales con peso.
+func nuevaPartidaReporte(partida legacy.PartidaLegacy, metrado costing.Decimal, parametros models.Parametros, tiposCambio *models.TasasCambio, flete *models.FleteProyecto) *models.PartidaReporte {
+	reglas := parametros.Reglas()
+	reporte := &models.PartidaReporte{
+		Codigo:      partida.Codigo,
+		Descripcion: partida.Descripcion,
+		Unidad:      partida.Unidad,
+		Rendimiento: partida.Rendimiento,
+		Metrado:     metrado,
+	}
+
+	recursosPorTipo := map[string][]legacy.RecursoLegacy{
+		"mano_obra":    partida.ManoObra,
+		"materiales":   partida.Materiales,
+		"equipos":      partida.Equipos,
+		"subcontratos": partida.Subcontratos,
+	}
+	for _, tipo := range tiposRecursoOrden {
+		seccion := models.SeccionReporte{Tipo: tipo, Nombre: nombresTipoRecurso[tipo]}
+		for _, recurso := range recursosPorTipo[tipo] {
+			// Los recursos incompletos no se listan ni suman, para que el subtotal cuadre con lo impreso
+			if recurso.Codigo == "" || recurso.Descripcion == "" {
+				continue
+			}
+			cantidad := recurso.Cantidad
+			if parametros.CuadrillaDefineCantidad && recurso.Cuadrilla > 0 && partida.Rendimiento > 0 && (tipo == "mano_obra" || tipo == "equipos") {
+				cantidad = reglas.CantidadCuadrilla(recurso.Cuadrilla, parametros.HorasJornada, partida.Rendimiento)
+			}
+			recursoReporte := models.RecursoReporte{
+				Codigo:      recurso.Codigo,
+				Descripcion: recurso.Descripcion,
+				Unidad:      recurso.Unidad,
+				Cuadrilla:   recurso.Cuadrilla,
+				Cantidad:    cantidad,
+				Precio:      recurso.Precio,
+			}
+			if recurso.Desperdicio != nil && !recurso.Desperdicio.EsCero() {
+				neta, desperdicio := cantidad, *recurso.Desperdicio
+				recursoReporte.CantidadNeta = &neta
+				recursoReporte.Desperdicio = &desperdicio
+				recursoReporte.Cantidad = reglas.CantidadConDesperdicio(cantidad, desperdicio)
+			}
+			if recurso.Moneda != "" && recurso.Moneda != parametros.Moneda && tiposCambio != nil {
+				if tasa, existe := tiposCambio.Tasa(recurso.Moneda); existe {
+					precioOriginal := recurso.Precio
+					recursoReporte.MonedaOriginal = recurso.Moneda
+					recursoReporte.PrecioOriginal = &precioOriginal
+					recursoReporte.TipoCambio = &tasa
+					recursoReporte.Precio = reglas.Convertir(recurso.Precio, tasa)
+				}
+			}
+			if flete != nil && flete.Modo == models.ModoFletePrecio && tipo == "materiales" {
+				if peso, existe := flete.Pesos[recurso.Codigo]; existe {
+					fleteUnitario := reglas.FleteUnitario(peso, flete.Ruta.CostoTonelada(reglas))
+					recursoReporte.Flete = &fleteUnitario
+					recursoReporte.Precio = recursoReporte.Precio.Sumar(fleteUnitario)
+				}
+			}
+			recursoReporte.Parcial = reglas.ParcialRecurso(recursoReporte.Cantidad, recursoReporte.Precio)
+			seccion.Subtotal = reglas.Sumar(seccion.Subtotal, recursoReporte.Parcial)
+			seccion.Recursos = append(seccion.Recursos, recursoReporte)
+		}
+		if flete != nil && flete.Modo == models.ModoFleteSubcontrato && tipo == "subcontratos" {
+			if linea, ok := lineaFleteTerrestre(reporte.Secciones, flete, reglas); ok {
+				seccion.Subtotal = reglas.Sumar(seccion.Subtotal, linea.Parcial)
+				seccion.Recursos = append(seccion.Recursos, linea)
+			}
+		}
+		reporte.CostoUnitario = reglas.Sumar(reporte.CostoUnitario, seccion.Subtotal)
+		reporte.Secciones = append(reporte.Secciones, seccion)
+	}
+
+	reporte.Parcial = reglas.ParcialPartida(reporte.Metrado, reporte.CostoUnitario)
+	return reporte
+}
+
+// partidasLegacyDesdeReporte devuelve las partidas del reporte en el formato del generador legacy
+func partidasLegacyDesdeReporte(reporte *models.ReportePresupuesto) []legacy.PartidaLegacy {
+	partidas := make([]legacy.PartidaLegacy, 0, len(reporte.Partidas))
+	for _, partida := range reporte.Partidas {
+		partidaLegacy := legacy.PartidaLegacy{
+			Codigo:      partida.Codigo,
+			Descripcion: partida.Descripcion,
+			Unidad:      partida.Unidad,
+			Rendimiento: partida.Rendimiento,
+		}
+		destinos := map[string]*[]legacy.RecursoLegacy{
+			"mano_obra":    &partidaLegacy.ManoObra,
+			"materiales":   &partidaLegacy.Materiales,
+			"equipos":      &partidaLegacy.Equipos,
+			"subcontratos": &partidaLegacy.Subcontratos,
+		}
+		for _, seccion := range partida.Secciones {
+			for _, recurso := range seccion.Recursos {
+				*destinos[seccion.Tipo] = append(*destinos[seccion.Tipo], legacy.RecursoLegacy{
+					Codigo:      recurso.Codigo,
+					Descripcion: recurso.Descripcion,
+					Unidad:      recurso.Unidad,
+					Cuadrilla:   recurso.Cuadrilla,
+					Cantidad:    recurso.Cantidad,
+					Precio:      recurso.Precio,
+					Moneda:      recurso.MonedaOriginal,
+
+					PrecioOriginal: recurso.PrecioOriginal,
+					TipoCambio:     recurso.TipoCambio,
+					Desperdicio:    recurso.Desperdicio,
+					CantidadNeta:   recurso.CantidadNeta,
+				})
+			}
+		}
+		partidas = append(partidas, partidaLegacy)
+	}
+	return partidas
+}
+
+// titulosPrimerNivel devuelve la descripción de los títulos de primer nivel del árbol por código
+func titulosPrimerNivel(reporte *models.ReportePresupuesto) map[string]string {
+	titulos := make(map[string]string)
+	for _, nodo := range reporte.Arbol {
+		if nodo.EsTitulo() && nodo.Descripcion != "" {
+			titulos[nodo.Codigo] = nodo.Descripcion
+		}
+	}
+	return titulos
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"strings"
+
+	"goexcel/internal/costing"
+	"goexcel/internal/legacy"
+	"goexcel/internal/models"
+)
+
+// celdaTabla es un valor de las tablas planas del reporte; los números conservan su valor y los
+// decimales con que se presentan
+type celdaTabla struct {
+	texto     string
+	numero    costing.Decimal
+	decimales int
+	esNumero  bool
+}
+
+func textoTabla(texto string) celdaTabla {
+	return celdaTabla{texto: texto}
+}
+
+func numeroTabla(numero costing.Decimal, decimales int) celdaTabla {
+	return celdaTabla{numero: numero, decimales: decimales, esNumero: true}
+}
+
+// formatear escribe la celda para un archivo de texto: los números sin separador de miles y con el
+// separador decimal indicado
+func (c celdaTabla) formatear(separadorDecimal string) string {
+	if !c.esNumero {
+		return c.texto
+	}
+	texto := c.numero.StringFijo(int32(c.decimales))
+	if separadorDecimal != "" && separadorDecimal != "." {
+		texto = strings.Replace(texto, ".", separadorDecimal, 1)
+	}
+	return texto
+}
+
+// tablaReporte es un reporte en forma de tabla plana: una fila de cabeceras y una fila por dato
+type tablaReporte struct {
+	nombre   string // nombre de la hoja; en minúsculas, del archivo CSV
+	columnas []string
+	anchos   []float64 // en caracteres, como en las hojas de Excel
+	filas    [][]celdaTabla
+}
+
+// tablasReporte devuelve el presupuesto, el APU y la relación de insumos como tablas planas. Las
+// columnas son las de las hojas del libro Excel; el APU y los insumos anteponen la partida y el tipo
+// de recurso que en el libro se leen de las filas de agrupación. Las columnas no cambian aunque el
+// reporte no tenga datos, para que los sistemas que las importan puedan depender de ellas. Metrados
+// y cantidades se escriben con los decimales de la regla de cantidad, precios y costos unitarios con
+// los de la regla de precio, y parciales y totales con los de la regla de parcial, para que el
+// archivo no pierda precisión respecto del cálculo.
+func tablasReporte(reporte *models.ReportePresupuesto) []tablaReporte {
+	parametros := reporte.Opciones.ParametrosCalculo()
+	moneda := parametros.SimboloMoneda()
+	reglas := parametros.Reglas()
+	digitosCantidad := int(reglas.Cantidad.Decimales)
+	digitosPrecio := int(reglas.Precio.Decimales)
+	digitosParcial := int(reglas.Parcial.Decimales)
+	presupuesto := tablaReporte{
+		nombre:   "Presupuesto",
+		columnas: []string{"Ítem", "Descripción", "Und.", "Metrado", "Precio " + moneda, "Parcial " + moneda},
+		anchos:   []float64{14, 50, 8, 12, 15, 18},
+	}
+	reporte.Recorrer(func(nodo *models.NodoReporte) {
+		if nodo.EsTitulo() {
+			presupuesto.filas = append(presupuesto.filas, []celdaTabla{
+				textoTabla(nodo.Codigo), textoTabla(nodo.Descripcion), textoTabla(""), textoTabla(""), textoTabla(""),
+				numeroTabla(nodo.Subtotal, digitosParcial),
+			})
+			return
+		}
+		partida := nodo.Partida
+		presupuesto.filas = append(presupuesto.filas, []celdaTabla{
+			textoTabla(partida.Codigo),
+			textoTabla(partida.Descripcion),
+			textoTabla(partida.Unidad),
+			numeroTabla(partida.Metrado, digitosCantidad),
+			numeroTabla(partida.CostoUnitario, digitosPrecio),
+			numeroTabla(partida.Parcial, digitosParcial),
+		})
+	})
+	for _, linea := range legacy.LineasPie(reporte.Pie) {
+		presupuesto.filas = append(presupuesto.filas, []celdaTabla{
+			textoTabla(""), textoTabla(linea.Etiqueta), textoTabla(""), textoTabla(""), textoTabla(""),
+			numeroTabla(linea.Monto, digitosParcial),
+		})
+	}
+
+	apu := tablaReporte{
+		nombre:   "APU",
+		columnas: []string{"Partida", "Tipo", "Código", "Descripción", "Unidad", "Cuadrilla", "Cantidad", "Precio " + moneda, "Parcial " + moneda},
+		anchos:   []float64{14, 14, 12, 45, 10, 12, 12, 15, 15},
+	}
+	for _, partida := range reporte.Partidas {
+		for _, seccion := range partida.Secciones {
+			for _, recurso := range seccion.Recursos {
+				cuadrilla := textoTabla("")
+				if recurso.Cuadrilla > 0 {
+					cuadrilla = numeroTabla(costing.DecimalDesdeFloat(recurso.Cuadrilla), decimalesCantidad)
+				}
+				apu.filas = append(apu.filas, []celdaTabla{
+					textoTabla(partida.Codigo),
+					textoTabla(seccion.Tipo),
+					textoTabla(recurso.Codigo),
+					textoTabla(recurso.Descripcion),
+					textoTabla(recurso.Unidad),
+					cuadrilla,
+					numeroTabla(recurso.Cantidad, digitosCantidad),
+					numeroTabla(recurso.Precio, digitosPrecio),
+					numeroTabla(recurso.Parcial, digitosParcial),
+				})
+			}
+		}
+	}
+
+	insumos := tablaReporte{
+		nombre:   "Insumos",
+		columnas: []string{"Tipo", "Código", "Descripción", "Und.", "Cantidad", "Precio " + moneda, "Parcial " + moneda},
+		anchos:   []float64{14, 12, 50, 8, 15, 12, 18},
+	}
+	if reporte.Insumos != nil {
+		for _, grupo := range reporte.Insumos.Grupos {
+			for _, insumo := range grupo.Insumos {
+				insumos.filas = append(insumos.filas, []celdaTabla{
+					textoTabla(grupo.TipoRecurso),
+					textoTabla(insumo.Codigo),
+					textoTabla(insumo.Descripcion),
+					textoTabla(insumo.Unidad),
+					numeroTabla(insumo.Cantidad, digitosCantidad),
+					numeroTabla(insumo.Precio, digitosPrecio),
+					numeroTabla(insumo.CostoTotal, digitosParcial),
+				})
+			}
+		}
+	}
+
+	return []tablaReporte{presupuesto, apu, insumos}
+}
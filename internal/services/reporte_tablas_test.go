@@ -0,0 +1,54 @@
+package services
+
+import (
+	"testing"
+
+	"goexcel/internal/models"
+)
+
+func TestTablasReporteDecimalesDeLasReglas(t *testing.T) {
+	reporte := reporteInsumos("123.4567", "7.69")
+	reporte.Arbol = []*models.NodoReporte{{Codigo: "01.01", Nivel: 1, Partida: reporte.Partidas[0], Subtotal: reporte.Partidas[0].Parcial}}
+	parametros := models.ParametrosPorDefecto()
+	parametros.DecimalesCantidad = 3
+	reporte.Opciones.Parametros = &parametros
+
+	tablas := tablasReporte(reporte)
+	casos := []struct {
+		tabla, columna string
+		fila           int
+		esperado       string
+	}{
+		// El metrado se redondea a los 3 decimales de la regla de cantidad, no a los 2 de los montos
+		{"Presupuesto", "Metrado", 0, "123.457"},
+		{"Presupuesto", "Precio S/", 0, "7.6900"},
+		{"Presupuesto", "Parcial S/", 0, "949.38"},
+		{"APU", "Cantidad", 0, "0.216"},
+		// El precio del recurso conserva los 4 decimales de la regla de precio
+		{"APU", "Precio S/", 0, "28.7000"},
+		{"APU", "Parcial S/", 0, "6.19"},
+	}
+	for _, caso := range casos {
+		var tabla *tablaReporte
+		for i := range tablas {
+			if tablas[i].nombre == caso.tabla {
+				tabla = &tablas[i]
+			}
+		}
+		if tabla == nil {
+			t.Fatalf("no se generó la tabla %s", caso.tabla)
+		}
+		columna := -1
+		for i, nombre := range tabla.columnas {
+			if nombre == caso.columna {
+				columna = i
+			}
+		}
+		if columna < 0 {
+			t.Fatalf("la tabla %s no tiene la columna %s", caso.tabla, caso.columna)
+		}
+		if got := tabla.filas[caso.fila][columna].formatear("."); got != caso.esperado {
+			t.Errorf("%s/%s = %s, se esperaba %s", caso.tabla, caso.columna, got, caso.esperado)
+		}
+	}
+}
@@ -0,0 +1,596 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"goexcel/internal/costing"
+	"goexcel/internal/legacy"
+	"goexcel/internal/models"
+)
+
+const (
+	// IteracionesRiesgoPorDefecto son las iteraciones del Monte Carlo si no se indican otras
+	IteracionesRiesgoPorDefecto = 5000
+	minIteracionesRiesgo        = 100
+	maxIteracionesRiesgo        = 100000
+
+	// maxVariablesRiesgo limita la memoria del análisis: se guarda la muestra de cada variable en cada
+	// iteración para el tornado, hasta 50 × 100000 valores
+	maxVariablesRiesgo = 50
+
+	intervalosRiesgoPorDefecto = 20
+	maxIntervalosRiesgo        = 100
+
+	// tamanoBloqueRiesgo son las iteraciones de cada bloque de trabajo. Cada bloque usa su propio generador
+	// con la semilla y su número, así el resultado no depende de cuántos trabajadores lo calculen.
+	tamanoBloqueRiesgo = 500
+)
+
+// percentilesRiesgoPorDefecto son los percentiles que se informan si no se indican otros
+var percentilesRiesgoPorDefecto = []float64{10, 50, 80, 90}
+
+// AnalizarRiesgo simula el costo directo del presupuesto variando precios, rendimientos y metrados con
+// las distribuciones indicadas. Cada iteración recalcula Σ metrado × (Σ cantidad × precio) de todas las
+// partidas a partir del presupuesto calculado, con los precios ya convertidos y con flete; los montos de
+// las iteraciones no se redondean línea por línea, así que sin variación difieren en céntimos del
+// costo directo del presupuesto. Los datos del proyecto no se modifican. Si ctx se cancela, la simulación
+// se detiene y se devuelve su error.
+func AnalizarRiesgo(ctx context.Context, datos DatosReporte, req models.AnalisisRiesgoRequest) (*models.AnalisisRiesgo, error) {
+	if err := validarAnalisisRiesgo(&req); err != nil {
+		return nil, err
+	}
+	parametros := datos.Opciones.ParametrosCalculo()
+	reglas := parametros.Reglas()
+	monto := func(valor float64) costing.Decimal {
+		return costing.DecimalDesdeFloat(valor).Redondear(reglas.Parcial)
+	}
+
+	semilla := rand.Uint64()
+	if req.Semilla != nil {
+		semilla = *req.Semilla
+	}
+
+	reporte := ConstruirReporte(datos)
+	analisis := &models.AnalisisRiesgo{
+		Proyecto:     datos.Opciones.Proyecto,
+		Fecha:        time.Now(),
+		Iteraciones:  req.Iteraciones,
+		Semilla:      semilla,
+		CostoDirecto: reporte.Pie.CostoDirecto,
+		Opciones:     datos.Opciones,
+	}
+
+	modelo, alcanzadas := nuevoModeloRiesgo(reporte, req.Variables)
+	analisis.Variables = nombrarVariablesRiesgo(reporte, req.Variables)
+	for v, variable := range analisis.Variables {
+		if !alcanzadas[v] {
+			analisis.NoAplicadas = append(analisis.NoAplicadas, fmt.Sprintf("%s: no alcanza ningún dato del presupuesto", variable.Nombre))
+		}
+	}
+
+	costos, muestras, err := modelo.simular(ctx, req.Variables, req.Iteraciones, semilla)
+	if err != nil {
+		return nil, err
+	}
+	ordenados := append([]float64(nil), costos...)
+	sort.Float64s(ordenados)
+
+	// Resumen de la distribución del costo directo
+	costoDirecto := reporte.Pie.CostoDirecto.Float64()
+	suma, noSuperan := 0.0, 0
+	for _, costo := range costos {
+		suma += costo
+		if costo <= costoDirecto {
+			noSuperan++
+		}
+	}
+	media := suma / float64(len(costos))
+	varianza := 0.0
+	for _, costo := range costos {
+		varianza += (costo - media) * (costo - media)
+	}
+	if len(costos) > 1 {
+		varianza /= float64(len(costos) - 1)
+	}
+	analisis.Media = monto(media)
+	analisis.DesviacionEstandar = monto(math.Sqrt(varianza))
+	analisis.Minimo = monto(ordenados[0])
+	analisis.Maximo = monto(ordenados[len(ordenados)-1])
+	analisis.ProbabilidadCostoDirecto = float64(noSuperan) * 100 / float64(len(costos))
+
+	percentilRiesgo := func(p float64) models.PercentilRiesgo {
+		costo := monto(percentil(ordenados, p))
+		pie := models.NuevoPiePresupuesto(costo, datos.Opciones.GastosGenerales, datos.Opciones.Utilidad, parametros)
+		return models.PercentilRiesgo{Percentil: p, CostoDirecto: costo, Total: pie.Total}
+	}
+	for _, p := range req.Percentiles {
+		analisis.Percentiles = append(analisis.Percentiles, percentilRiesgo(p))
+	}
+	analisis.P80 = percentilRiesgo(80)
+	analisis.Contingencia = analisis.P80.CostoDirecto.Restar(analisis.CostoDirecto)
+
+	analisis.Histograma = histogramaRiesgo(ordenados, req.Intervalos, monto)
+
+	// Tornado: cada variable en su percentil 10 y 90 con las demás en el valor guardado
+	variaciones := make([]float64, len(req.Variables))
+	for v, variable := range analisis.Variables {
+		if !alcanzadas[v] {
+			continue
+		}
+		muestrasOrdenadas := append([]float64(nil), muestras[v]...)
+		sort.Float64s(muestrasOrdenadas)
+		variaciones[v] = percentil(muestrasOrdenadas, 10)
+		bajo := modelo.costoDirecto(variaciones)
+		variaciones[v] = percentil(muestrasOrdenadas, 90)
+		alto := modelo.costoDirecto(variaciones)
+		variaciones[v] = 0
+
+		analisis.Tornado = append(analisis.Tornado, models.SensibilidadRiesgo{
+			Variable:    variable.Nombre,
+			Bajo:        monto(bajo),
+			Alto:        monto(alto),
+			Rango:       monto(math.Abs(alto - bajo)),
+			Correlacion: correlacion(muestras[v], costos),
+		})
+	}
+	sort.SliceStable(analisis.Tornado, func(i, j int) bool {
+		return analisis.Tornado[i].Rango.Cmp(analisis.Tornado[j].Rango) > 0
+	})
+
+	return analisis, nil
+}
+
+// validarAnalisisRiesgo completa los valores por defecto y rechaza las variables mal definidas
+func validarAnalisisRiesgo(req *models.AnalisisRiesgoRequest) error {
+	if len(req.Variables) == 0 {
+		return fmt.Errorf("debe indicar al menos una variable")
+	}
+	if len(req.Variables) > maxVariablesRiesgo {
+		return fmt.Errorf("se admiten hasta %d variables", maxVariablesRiesgo)
+	}
+	if req.Iteraciones == 0 {
+		req.Iteraciones = IteracionesRiesgoPorDefecto
+	}
+	if req.Iteraciones < minIteracionesRiesgo || req.Iteraciones > maxIteracionesRiesgo {
+		return fmt.Errorf("iteraciones debe estar entre %d y %d", minIteracionesRiesgo, maxIteracionesRiesgo)
+	}
+	if req.Intervalos == 0 {
+		req.Intervalos = intervalosRiesgoPorDefecto
+	}
+	if req.Intervalos < 1 || req.Intervalos > maxIntervalosRiesgo {
+		return fmt.Errorf("intervalos debe estar entre 1 y %d", maxIntervalosRiesgo)
+	}
+	if len(req.Percentiles) == 0 {
+		req.Percentiles = percentilesRiesgoPorDefecto
+	}
+	for _, p := range req.Percentiles {
+		if p <= 0 || p >= 100 {
+			return fmt.Errorf("percentil inválido: %v (debe estar entre 0 y 100)", p)
+		}
+	}
+
+	vistas := make(map[string]bool)
+	variables := make([]models.VariableRiesgo, len(req.Variables))
+	for i, variable := range req.Variables {
+		variable.Codigo = strings.TrimSpace(variable.Codigo)
+		variable.TipoRecurso = strings.TrimSpace(variable.TipoRecurso)
+		switch variable.Objetivo {
+		case models.VariablePrecio:
+			if variable.TipoRecurso != "" {
+				if _, existe := nombresTipoRecurso[variable.TipoRecurso]; !existe {
+					return fmt.Errorf("tipo de recurso inválido: %s (use %s)", variable.TipoRecurso, strings.Join(tiposRecursoOrden, ", "))
+				}
+				if variable.Codigo != "" {
+					return fmt.Errorf("indique codigo o tipo_recurso en la variable de precio, pero no ambos")
+				}
+			}
+		case models.VariableRendimiento, models.VariableMetrado:
+			if variable.TipoRecurso != "" {
+				return fmt.Errorf("tipo_recurso solo se usa en las variables de precio")
+			}
+		default:
+			return fmt.Errorf("objetivo inválido: %q (use %q, %q o %q)", variable.Objetivo,
+				models.VariablePrecio, models.VariableRendimiento, models.VariableMetrado)
+		}
+
+		clave := variable.Objetivo + "|" + variable.Codigo + "|" + variable.TipoRecurso
+		if vistas[clave] {
+			return fmt.Errorf("variable repetida: %s %s%s", variable.Objetivo, variable.Codigo, variable.TipoRecurso)
+		}
+		vistas[clave] = true
+
+		if err := validarDistribucionRiesgo(variable.Distribucion); err != nil {
+			return fmt.Errorf("variable %d (%s): %v", i+1, variable.Objetivo, err)
+		}
+		variables[i] = variable
+	}
+	req.Variables = variables
+	return nil
+}
+
+func validarDistribucionRiesgo(distribucion models.DistribucionRiesgo) error {
+	switch distribucion.Tipo {
+	case models.DistribucionTriangular, models.DistribucionPERT:
+		if distribucion.Moda < distribucion.Minimo || distribucion.Moda > distribucion.Maximo {
+			return fmt.Errorf("la moda debe estar entre el mínimo y el máximo")
+		}
+	case models.DistribucionUniforme:
+	default:
+		return fmt.Errorf("distribución inválida: %q (use %q, %q o %q)", distribucion.Tipo,
+			models.DistribucionTriangular, models.DistribucionPERT, models.DistribucionUniforme)
+	}
+	if distribucion.Minimo <= -100 {
+		return fmt.Errorf("el mínimo debe ser mayor que -100%%")
+	}
+	if distribucion.Minimo >= distribucion.Maximo {
+		return fmt.Errorf("el mínimo debe ser menor que el máximo")
+	}
+	return nil
+}
+
+// nombrarVariablesRiesgo completa el nombre de las variables que no lo traen con el objetivo, el código
+// y la descripción del recurso, la partida o el título
+func nombrarVariablesRiesgo(reporte *models.ReportePresupuesto, variables []models.VariableRiesgo) []models.VariableRiesgo {
+	recursos := make(map[string]string)
+	for _, partida := range reporte.Partidas {
+		for _, seccion := range partida.Secciones {
+			for _, recurso := range seccion.Recursos {
+				recursos[recurso.Codigo] = recurso.Descripcion
+			}
+		}
+	}
+	nodos := make(map[string]string)
+	reporte.Recorrer(func(nodo *models.NodoReporte) {
+		nodos[nodo.Codigo] = nodo.Descripcion
+	})
+
+	nombradas := make([]models.VariableRiesgo, len(variables))
+	for i, variable := range variables {
+		if strings.TrimSpace(variable.Nombre) == "" {
+			objetivo := strings.ToUpper(variable.Objetivo[:1]) + variable.Objetivo[1:]
+			switch {
+			case variable.TipoRecurso != "":
+				variable.Nombre = fmt.Sprintf("%s de %s", objetivo, strings.ToLower(nombresTipoRecurso[variable.TipoRecurso]))
+			case variable.Codigo == "":
+				variable.Nombre = fmt.Sprintf("%s de todo el proyecto", objetivo)
+			case variable.Objetivo == models.VariablePrecio && recursos[variable.Codigo] != "":
+				variable.Nombre = fmt.Sprintf("%s %s %s", objetivo, variable.Codigo, recursos[variable.Codigo])
+			case variable.Objetivo != models.VariablePrecio && nodos[variable.Codigo] != "":
+				variable.Nombre = fmt.Sprintf("%s %s %s", objetivo, variable.Codigo, nodos[variable.Codigo])
+			default:
+				variable.Nombre = fmt.Sprintf("%s %s", objetivo, variable.Codigo)
+			}
+		}
+		nombradas[i] = variable
+	}
+	return nombradas
+}
+
+// modeloRiesgo es el presupuesto reducido a lo que cambia en cada iteración: por partida su metrado y
+// sus parciales unitarios agrupados por la variable de precio que los mueve
+type modeloRiesgo struct {
+	partidas []partidaRiesgo
+}
+
+// partidaRiesgo guarda el índice de la variable de metrado y de rendimiento que alcanza la partida;
+// -1 si ninguna
+type partidaRiesgo struct {
+	metrado        float64
+	varMetrado     int
+	varRendimiento int
+	grupos         []grupoRiesgo
+}
+
+// grupoRiesgo suma los parciales unitarios de los recursos de una partida que dependen de la misma
+// variable de precio; los de mano de obra y equipos dependen además del rendimiento
+type grupoRiesgo struct {
+	costo          float64
+	varPrecio      int
+	porRendimiento bool
+}
+
+// nuevoModeloRiesgo asigna a cada dato del presupuesto la variable más específica que lo alcanza y
+// devuelve qué variables alcanzaron algún dato
+func nuevoModeloRiesgo(reporte *models.ReportePresupuesto, variables []models.VariableRiesgo) (*modeloRiesgo, []bool) {
+	alcanzadas := make([]bool, len(variables))
+
+	// variablePartida elige entre las variables del objetivo la de código más largo que contiene la partida
+	variablePartida := func(objetivo, codigo string) int {
+		elegida, largo := -1, -1
+		for v, variable := range variables {
+			if variable.Objetivo != objetivo {
+				continue
+			}
+			contiene := variable.Codigo == "" || codigo == variable.Codigo || strings.HasPrefix(codigo, variable.Codigo+".")
+			if contiene && len(variable.Codigo) > largo {
+				elegida, largo = v, len(variable.Codigo)
+			}
+		}
+		if elegida >= 0 {
+			alcanzadas[elegida] = true
+		}
+		return elegida
+	}
+	// variablePrecio prefiere el código del recurso, luego su tipo y luego la regla de todo el proyecto
+	variablePrecio := func(codigo, tipo string) int {
+		elegida, prioridad := -1, -1
+		for v, variable := range variables {
+			if variable.Objetivo != models.VariablePrecio {
+				continue
+			}
+			p := -1
+			switch {
+			case variable.Codigo != "":
+				if variable.Codigo == codigo {
+					p = 2
+				}
+			case variable.TipoRecurso != "":
+				if variable.TipoRecurso == tipo {
+					p = 1
+				}
+			default:
+				p = 0
+			}
+			if p > prioridad {
+				elegida, prioridad = v, p
+			}
+		}
+		if elegida >= 0 {
+			alcanzadas[elegida] = true
+		}
+		return elegida
+	}
+
+	modelo := &modeloRiesgo{}
+	for _, partida := range reporte.Partidas {
+		partidaRiesgo := partidaRiesgo{
+			metrado:        partida.Metrado.Float64(),
+			varMetrado:     variablePartida(models.VariableMetrado, partida.Codigo),
+			varRendimiento: variablePartida(models.VariableRendimiento, partida.Codigo),
+		}
+		for _, seccion := range partida.Secciones {
+			porRendimiento := seccion.Tipo == "mano_obra" || seccion.Tipo == "equipos"
+			for _, recurso := range seccion.Recursos {
+				varPrecio := variablePrecio(recurso.Codigo, seccion.Tipo)
+				costo := recurso.Cantidad.Float64() * recurso.Precio.Float64()
+
+				agrupado := false
+				for g := range partidaRiesgo.grupos {
+					grupo := &partidaRiesgo.grupos[g]
+					if grupo.varPrecio == varPrecio && grupo.porRendimiento == porRendimiento {
+						grupo.costo += costo
+						agrupado = true
+						break
+					}
+				}
+				if !agrupado {
+					partidaRiesgo.grupos = append(partidaRiesgo.grupos, grupoRiesgo{costo: costo, varPrecio: varPrecio, porRendimiento: porRendimiento})
+				}
+			}
+		}
+		modelo.partidas = append(modelo.partidas, partidaRiesgo)
+	}
+
+	// Una variable de rendimiento sobre partidas sin mano de obra ni equipos no cambia nada
+	for v, variable := range variables {
+		if variable.Objetivo != models.VariableRendimiento || !alcanzadas[v] {
+			continue
+		}
+		alcanzadas[v] = false
+		for _, partida := range modelo.partidas {
+			for _, grupo := range partida.grupos {
+				if partida.varRendimiento == v && grupo.porRendimiento {
+					alcanzadas[v] = true
+				}
+			}
+		}
+	}
+	return modelo, alcanzadas
+}
+
+// costoDirecto calcula el costo directo con la variación en % de cada variable. Más rendimiento es menos
+// horas de cuadrilla por unidad, así que la mano de obra y los equipos se dividen por su factor.
+func (m *modeloRiesgo) costoDirecto(variaciones []float64) float64 {
+	factor := func(v int) float64 {
+		if v < 0 {
+			return 1
+		}
+		return 1 + variaciones[v]/100
+	}
+
+	total := 0.0
+	for _, partida := range m.partidas {
+		unitario := 0.0
+		for _, grupo := range partida.grupos {
+			costo := grupo.costo * factor(grupo.varPrecio)
+			if grupo.porRendimiento {
+				costo /= factor(partida.varRendimiento)
+			}
+			unitario += costo
+		}
+		total += partida.metrado * factor(partida.varMetrado) * unitario
+	}
+	return total
+}
+
+// simular reparte las iteraciones en bloques entre los trabajadores y devuelve el costo directo de cada
+// iteración y la variación que tomó cada variable en ella. Los trabajadores dejan de iterar en cuanto
+// se cancela ctx.
+func (m *modeloRiesgo) simular(ctx context.Context, variables []models.VariableRiesgo, iteraciones int, semilla uint64) ([]float64, [][]float64, error) {
+	costos := make([]float64, iteraciones)
+	muestras := make([][]float64, len(variables))
+	for v := range muestras {
+		muestras[v] = make([]float64, iteraciones)
+	}
+
+	bloques := (iteraciones + tamanoBloqueRiesgo - 1) / tamanoBloqueRiesgo
+	trabajos := make(chan int)
+	var wg sync.WaitGroup
+	for t := 0; t < min(runtime.NumCPU(), bloques); t++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			variaciones := make([]float64, len(variables))
+			for bloque := range trabajos {
+				rng := rand.New(rand.NewPCG(semilla, uint64(bloque)))
+				for i := bloque * tamanoBloqueRiesgo; i < min((bloque+1)*tamanoBloqueRiesgo, iteraciones); i++ {
+					select {
+					case <-ctx.Done():
+						return
+					default:
+					}
+					for v, variable := range variables {
+						variaciones[v] = muestrear(variable.Distribucion, rng)
+						muestras[v][i] = variaciones[v]
+					}
+					costos[i] = m.costoDirecto(variaciones)
+				}
+			}
+		}()
+	}
+
+	// Al cancelar, los trabajadores ya no reciben bloques y el reparto se abandona
+repartir:
+	for bloque := 0; bloque < bloques; bloque++ {
+		select {
+		case trabajos <- bloque:
+		case <-ctx.Done():
+			break repartir
+		}
+	}
+	close(trabajos)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, nil, fmt.Errorf("análisis de riesgo cancelado: %v", err)
+	}
+	return costos, muestras, nil
+}
+
+// muestrear toma un valor de la distribución
+func muestrear(distribucion models.DistribucionRiesgo, rng *rand.Rand) float64 {
+	a, c, b := distribucion.Minimo, distribucion.Moda, distribucion.Maximo
+	switch distribucion.Tipo {
+	case models.DistribucionTriangular:
+		// Inversa de la función de distribución acumulada
+		u := rng.Float64()
+		if u < (c-a)/(b-a) {
+			return a + math.Sqrt(u*(b-a)*(c-a))
+		}
+		return b - math.Sqrt((1-u)*(b-a)*(b-c))
+	case models.DistribucionPERT:
+		// Beta escalada al rango, con el peso habitual de 4 para la moda
+		alfa := 1 + 4*(c-a)/(b-a)
+		beta := 1 + 4*(b-c)/(b-a)
+		x := muestrearGamma(alfa, rng)
+		y := muestrearGamma(beta, rng)
+		return a + x/(x+y)*(b-a)
+	default:
+		return a + rng.Float64()*(b-a)
+	}
+}
+
+// muestrearGamma toma un valor de una Gamma de forma mayor o igual a 1 con el método de Marsaglia y Tsang
+func muestrearGamma(forma float64, rng *rand.Rand) float64 {
+	d := forma - 1.0/3
+	c := 1 / math.Sqrt(9*d)
+	for {
+		x := rng.NormFloat64()
+		v := 1 + c*x
+		if v <= 0 {
+			continue
+		}
+		v = v * v * v
+		u := rng.Float64()
+		if u < 1-0.0331*x*x*x*x || math.Log(u) < 0.5*x*x+d*(1-v+math.Log(v)) {
+			return d * v
+		}
+	}
+}
+
+// percentil interpola linealmente entre los valores ordenados que rodean la posición del percentil
+func percentil(ordenados []float64, p float64) float64 {
+	posicion := p / 100 * float64(len(ordenados)-1)
+	inferior := int(math.Floor(posicion))
+	if inferior >= len(ordenados)-1 {
+		return ordenados[len(ordenados)-1]
+	}
+	fraccion := posicion - float64(inferior)
+	return ordenados[inferior] + fraccion*(ordenados[inferior+1]-ordenados[inferior])
+}
+
+// histogramaRiesgo reparte los costos ordenados en intervalos iguales entre el mínimo y el máximo
+func histogramaRiesgo(ordenados []float64, intervalos int, monto func(float64) costing.Decimal) []models.IntervaloHistograma {
+	minimo, maximo := ordenados[0], ordenados[len(ordenados)-1]
+	if maximo == minimo {
+		intervalos = 1
+	}
+	ancho := (maximo - minimo) / float64(intervalos)
+
+	frecuencias := make([]int, intervalos)
+	for _, costo := range ordenados {
+		i := intervalos - 1
+		if ancho > 0 {
+			i = min(int((costo-minimo)/ancho), intervalos-1)
+		}
+		frecuencias[i]++
+	}
+
+	histograma := make([]models.IntervaloHistograma, intervalos)
+	acumuladas := 0
+	for i, frecuencia := range frecuencias {
+		acumuladas += frecuencia
+		hasta := minimo + float64(i+1)*ancho
+		if i == intervalos-1 {
+			hasta = maximo
+		}
+		histograma[i] = models.IntervaloHistograma{
+			Desde:      monto(minimo + float64(i)*ancho),
+			Hasta:      monto(hasta),
+			Frecuencia: frecuencia,
+			Porcentaje: float64(frecuencia) * 100 / float64(len(ordenados)),
+			Acumulado:  float64(acumuladas) * 100 / float64(len(ordenados)),
+		}
+	}
+	return histograma
+}
+
+// correlacion es el coeficiente de Pearson entre dos series; 0 si alguna no varía
+func correlacion(x, y []float64) float64 {
+	n := float64(len(x))
+	mediaX, mediaY := 0.0, 0.0
+	for i := range x {
+		mediaX += x[i]
+		mediaY += y[i]
+	}
+	mediaX /= n
+	mediaY /= n
+
+	covarianza, varianzaX, varianzaY := 0.0, 0.0, 0.0
+	for i := range x {
+		dx, dy := x[i]-mediaX, y[i]-mediaY
+		covarianza += dx * dy
+		varianzaX += dx * dx
+		varianzaY += dy * dy
+	}
+	if varianzaX == 0 || varianzaY == 0 {
+		return 0
+	}
+	return covarianza / math.Sqrt(varianzaX*varianzaY)
+}
+
+// GenerarExcelRiesgo genera el libro con la hoja "Análisis de Riesgo"
+func GenerarExcelRiesgo(analisis *models.AnalisisRiesgo) (Documento, error) {
+	f, err := legacy.ConstruirExcelRiesgo(analisis)
+	if err != nil {
+		return nil, fmt.Errorf("error generando análisis de riesgo: %v", err)
+	}
+	return documentoExcel{f: f}, nil
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"goexcel/internal/models"
+)
+
+// variablesRiesgoPrueba mueven el precio de los materiales y el rendimiento del título 02 de datosSimulacion
+func variablesRiesgoPrueba() []models.VariableRiesgo {
+	return []models.VariableRiesgo{
+		{Objetivo: "precio", TipoRecurso: "materiales", Distribucion: models.DistribucionRiesgo{Tipo: "triangular", Minimo: -5, Moda: 0, Maximo: 15}},
+		{Objetivo: "rendimiento", Codigo: "02", Distribucion: models.DistribucionRiesgo{Tipo: "pert", Minimo: -10, Moda: 0, Maximo: 10}},
+	}
+}
+
+func TestAnalizarRiesgoReproducibleConSemilla(t *testing.T) {
+	casos := []struct {
+		nombre      string
+		semilla     uint64
+		iteraciones int
+	}{
+		{"un solo bloque", 42, 100},
+		// Varios bloques, el último incompleto, repartidos entre los trabajadores que haya
+		{"varios bloques", 42, 2345},
+		{"otra semilla", 20261018, 5000},
+	}
+
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			analizar := func(semilla uint64) *models.AnalisisRiesgo {
+				t.Helper()
+				analisis, err := AnalizarRiesgo(context.Background(), datosSimulacion(), models.AnalisisRiesgoRequest{
+					Iteraciones: caso.iteraciones,
+					Semilla:     &semilla,
+					Variables:   variablesRiesgoPrueba(),
+				})
+				if err != nil {
+					t.Fatalf("AnalizarRiesgo: %v", err)
+				}
+				return analisis
+			}
+
+			primero, segundo := analizar(caso.semilla), analizar(caso.semilla)
+			if !primero.P80.CostoDirecto.Igual(segundo.P80.CostoDirecto) || !primero.P80.Total.Igual(segundo.P80.Total) {
+				t.Errorf("P80 con la misma semilla: %s y %s", primero.P80.CostoDirecto, segundo.P80.CostoDirecto)
+			}
+			if !primero.Media.Igual(segundo.Media) || !primero.DesviacionEstandar.Igual(segundo.DesviacionEstandar) {
+				t.Errorf("media y desviación con la misma semilla: %s ± %s y %s ± %s",
+					primero.Media, primero.DesviacionEstandar, segundo.Media, segundo.DesviacionEstandar)
+			}
+			if len(primero.Histograma) != len(segundo.Histograma) {
+				t.Fatalf("histogramas de %d y %d intervalos", len(primero.Histograma), len(segundo.Histograma))
+			}
+			frecuencias := 0
+			for i, intervalo := range primero.Histograma {
+				otro := segundo.Histograma[i]
+				if !intervalo.Desde.Igual(otro.Desde) || !intervalo.Hasta.Igual(otro.Hasta) || intervalo.Frecuencia != otro.Frecuencia {
+					t.Errorf("intervalo %d: [%s, %s) × %d y [%s, %s) × %d", i,
+						intervalo.Desde, intervalo.Hasta, intervalo.Frecuencia, otro.Desde, otro.Hasta, otro.Frecuencia)
+				}
+				frecuencias += intervalo.Frecuencia
+			}
+			if frecuencias != caso.iteraciones {
+				t.Errorf("el histograma cuenta %d iteraciones, se esperaban %d", frecuencias, caso.iteraciones)
+			}
+
+			// Otra semilla da otra muestra
+			if distinto := analizar(caso.semilla + 1); distinto.Media.Igual(primero.Media) && distinto.P80.CostoDirecto.Igual(primero.P80.CostoDirecto) {
+				t.Errorf("las semillas %d y %d dieron la misma media y el mismo P80", caso.semilla, caso.semilla+1)
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"goexcel/internal/costing"
+	"goexcel/internal/legacy"
+	"goexcel/internal/models"
+)
+
+// Simular recalcula en memoria el presupuesto con los ajustes de precio y rendimiento y lo compara con
+// el presupuesto sin ajustar, por título y por tipo de recurso. Los datos del proyecto no se modifican.
+// El precio ajustado es precio × (1 + porcentaje / 100), en la moneda en que está cotizado; el flete se
+// calcula igual que en el reporte, sobre los pesos y no sobre el precio.
+func Simular(datos DatosReporte, ajustes models.SimulacionRequest) (*models.ResultadoSimulacion, error) {
+	if err := validarSimulacion(ajustes); err != nil {
+		return nil, err
+	}
+	reglas := datos.Opciones.ParametrosCalculo().Reglas()
+
+	resultado := &models.ResultadoSimulacion{
+		Proyecto: datos.Opciones.Proyecto,
+		Fecha:    time.Now(),
+		Ajustes:  ajustes,
+		Opciones: datos.Opciones,
+	}
+
+	base := ConstruirReporte(datos)
+	ajustados := datos
+	ajustados.Partidas = ajustarPartidas(datos.Partidas, ajustes, reglas, resultado)
+	simulado := ConstruirReporte(ajustados)
+
+	nodosSimulados := make(map[string]*models.NodoReporte)
+	simulado.Recorrer(func(nodo *models.NodoReporte) {
+		nodosSimulados[nodo.Codigo] = nodo
+	})
+	base.Recorrer(func(nodo *models.NodoReporte) {
+		if !nodo.EsTitulo() {
+			return
+		}
+		total := nuevoTotalSimulacion(nodo.Subtotal, nodosSimulados[nodo.Codigo].Subtotal)
+		total.Codigo = nodo.Codigo
+		total.Descripcion = nodo.Descripcion
+		total.Nivel = nodo.Nivel
+		resultado.Titulos = append(resultado.Titulos, total)
+	})
+
+	for _, tipo := range tiposRecursoOrden {
+		total := nuevoTotalSimulacion(costoPorTipo(base, tipo, reglas), costoPorTipo(simulado, tipo, reglas))
+		total.Codigo = tipo
+		total.Descripcion = nombresTipoRecurso[tipo]
+		resultado.TiposRecurso = append(resultado.TiposRecurso, total)
+	}
+
+	resultado.CostoDirecto = nuevoTotalSimulacion(base.Pie.CostoDirecto, simulado.Pie.CostoDirecto)
+	resultado.Total = nuevoTotalSimulacion(base.Pie.Total, simulado.Pie.Total)
+	return resultado, nil
+}
+
+// validarSimulacion rechaza los tipos desconocidos, las bajas de precio de 100% o más y los factores
+// de rendimiento que no son positivos
+func validarSimulacion(ajustes models.SimulacionRequest) error {
+	if len(ajustes.PorTipo) == 0 && len(ajustes.PorCodigo) == 0 && len(ajustes.Rendimientos) == 0 {
+		return fmt.Errorf("debe indicar al menos un ajuste")
+	}
+	menosCien := costing.NuevoDecimal(-100, 0)
+	for tipo, porcentaje := range ajustes.PorTipo {
+		if _, existe := nombresTipoRecurso[tipo]; !existe {
+			return fmt.Errorf("tipo de recurso inválido: %s (use %s)", tipo, strings.Join(tiposRecursoOrden, ", "))
+		}
+		if porcentaje.Cmp(menosCien) <= 0 {
+			return fmt.Errorf("el porcentaje de %s debe ser mayor que -100", tipo)
+		}
+	}
+	for _, ajuste := range ajustes.PorCodigo {
+		if strings.TrimSpace(ajuste.Codigo) == "" {
+			return fmt.Errorf("codigo es requerido en los ajustes por código")
+		}
+		if ajuste.Porcentaje.Cmp(menosCien) <= 0 {
+			return fmt.Errorf("el porcentaje de %s debe ser mayor que -100", ajuste.Codigo)
+		}
+	}
+	for _, ajuste := range ajustes.Rendimientos {
+		if strings.TrimSpace(ajuste.Titulo) == "" {
+			return fmt.Errorf("titulo es requerido en los ajustes de rendimiento")
+		}
+		if ajuste.Factor.Signo() <= 0 {
+			return fmt.Errorf("el factor de rendimiento de %s debe ser mayor que cero", ajuste.Titulo)
+		}
+	}
+	return nil
+}
+
+// ajustarPartidas devuelve una copia de las partidas con los ajustes aplicados y anota en el resultado
+// cuántos recursos y partidas cambiaron y qué ajustes no coincidieron con nada
+func ajustarPartidas(partidas []legacy.PartidaLegacy, ajustes models.SimulacionRequest, reglas costing.Reglas, resultado *models.ResultadoSimulacion) []legacy.PartidaLegacy {
+	porCodigo := make(map[string]costing.Decimal, len(ajustes.PorCodigo))
+	for _, ajuste := range ajustes.PorCodigo {
+		porCodigo[strings.TrimSpace(ajuste.Codigo)] = ajuste.Porcentaje
+	}
+	// Los títulos más largos primero, para que el más específico prevalezca
+	rendimientos := append([]models.AjusteRendimiento(nil), ajustes.Rendimientos...)
+	for i := range rendimientos {
+		rendimientos[i].Titulo = strings.TrimSpace(rendimientos[i].Titulo)
+	}
+	sort.SliceStable(rendimientos, func(i, j int) bool {
+		return len(rendimientos[i].Titulo) > len(rendimientos[j].Titulo)
+	})
+
+	codigosUsados := make(map[string]bool)
+	titulosUsados := make(map[string]bool)
+	ajustadas := make([]legacy.PartidaLegacy, 0, len(partidas))
+	for _, partida := range partidas {
+		var factor *costing.Decimal
+		for i, ajuste := range rendimientos {
+			if partida.Codigo == ajuste.Titulo || strings.HasPrefix(partida.Codigo, ajuste.Titulo+".") {
+				factor = &rendimientos[i].Factor
+				titulosUsados[ajuste.Titulo] = true
+				break
+			}
+		}
+		if factor != nil {
+			partida.Rendimiento *= factor.Float64()
+			resultado.PartidasAjustadas++
+		}
+
+		ajustarRecursos := func(tipo string, recursos []legacy.RecursoLegacy) []legacy.RecursoLegacy {
+			copia := make([]legacy.RecursoLegacy, len(recursos))
+			for i, recurso := range recursos {
+				porcentaje, existe := porCodigo[recurso.Codigo]
+				if existe {
+					codigosUsados[recurso.Codigo] = true
+				} else {
+					porcentaje, existe = ajustes.PorTipo[tipo]
+				}
+				if existe && !porcentaje.EsCero() {
+					recurso.Precio = ajustarPrecio(recurso.Precio, porcentaje, reglas)
+					resultado.RecursosAjustados++
+				}
+				// Más rendimiento es menos horas de cuadrilla por unidad de partida
+				if factor != nil && (tipo == "mano_obra" || tipo == "equipos") {
+					recurso.Cantidad = recurso.Cantidad.Dividir(*factor, reglas.Cantidad)
+				}
+				copia[i] = recurso
+			}
+			return copia
+		}
+		partida.ManoObra = ajustarRecursos("mano_obra", partida.ManoObra)
+		partida.Materiales = ajustarRecursos("materiales", partida.Materiales)
+		partida.Equipos = ajustarRecursos("equipos", partida.Equipos)
+		partida.Subcontratos = ajustarRecursos("subcontratos", partida.Subcontratos)
+		ajustadas = append(ajustadas, partida)
+	}
+
+	for _, ajuste := range ajustes.PorCodigo {
+		if codigo := strings.TrimSpace(ajuste.Codigo); !codigosUsados[codigo] {
+			resultado.NoAplicados = append(resultado.NoAplicados, fmt.Sprintf("recurso %s: no se usa en el proyecto", codigo))
+		}
+	}
+	for _, ajuste := range ajustes.Rendimientos {
+		if titulo := strings.TrimSpace(ajuste.Titulo); !titulosUsados[titulo] {
+			resultado.NoAplicados = append(resultado.NoAplicados, fmt.Sprintf("título %s: no tiene partidas", titulo))
+		}
+	}
+	return ajustadas
+}
+
+// ajustarPrecio aplica un porcentaje de variación al precio, redondeado como precio
+func ajustarPrecio(precio, porcentaje costing.Decimal, reglas costing.Reglas) costing.Decimal {
+	factor := costing.NuevoDecimal(100, 0).Sumar(porcentaje)
+	return precio.Multiplicar(factor).Dividir(costing.NuevoDecimal(100, 0), reglas.Precio)
+}
+
+// costoPorTipo es el costo de un tipo de recurso en todo el presupuesto: Σ metrado × subtotal del tipo
+// en el APU. Por el redondeo de cada parcial, la suma de los tipos puede diferir en céntimos del costo directo.
+func costoPorTipo(reporte *models.ReportePresupuesto, tipo string, reglas costing.Reglas) costing.Decimal {
+	total := costing.Decimal{}
+	for _, partida := range reporte.Partidas {
+		total = reglas.Sumar(total, reglas.ParcialPartida(partida.Metrado, partida.CostoSeccion(tipo)))
+	}
+	return total
+}
+
+func nuevoTotalSimulacion(base, simulado costing.Decimal) models.TotalSimulacion {
+	total := models.TotalSimulacion{
+		Base:       base,
+		Simulado:   simulado,
+		Diferencia: simulado.Restar(base),
+	}
+	if !base.EsCero() {
+		variacion := total.Diferencia.Multiplicar(costing.NuevoDecimal(100, 0)).Dividir(base, redondeoVariacion)
+		total.VariacionPorcentaje = &variacion
+	}
+	return total
+}
+
+// GenerarExcelSimulacion genera el libro con la hoja de la simulación
+func GenerarExcelSimulacion(resultado *models.ResultadoSimulacion) (Documento, error) {
+	f, err := legacy.ConstruirExcelSimulacion(resultado)
+	if err != nil {
+		return nil, fmt.Errorf("error generando simulación: %v", err)
+	}
+	return documentoExcel{f: f}, nil
+}
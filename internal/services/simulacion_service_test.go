@@ -0,0 +1,116 @@
+package services
+
+import (
+	"testing"
+
+	"goexcel/internal/costing"
+	"goexcel/internal/legacy"
+	"goexcel/internal/models"
+)
+
+// datosSimulacion son dos partidas de títulos distintos: 01.01 (10 m3 a 80) y 02.01 (5 m2 a 100), con
+// un costo directo de 1300
+func datosSimulacion() DatosReporte {
+	d := costing.DebeParsear
+	recurso := func(codigo, cantidad, precio string) legacy.RecursoLegacy {
+		return legacy.RecursoLegacy{Codigo: codigo, Descripcion: codigo, Unidad: "und", Cantidad: d(cantidad), Precio: d(precio)}
+	}
+	parametros := models.ParametrosPorDefecto()
+	return DatosReporte{
+		Partidas: []legacy.PartidaLegacy{
+			{Codigo: "01.01", Descripcion: "CONCRETO", Unidad: "m3",
+				ManoObra: []legacy.RecursoLegacy{recurso("OPERARIO", "2", "25")}, Materiales: []legacy.RecursoLegacy{recurso("CEMENTO", "1", "30")}},
+			{Codigo: "02.01", Descripcion: "TARRAJEO", Unidad: "m2",
+				ManoObra: []legacy.RecursoLegacy{recurso("PEON", "4", "20")}, Equipos: []legacy.RecursoLegacy{recurso("MEZCLADORA", "0.5", "40")}},
+		},
+		Metrados: map[string]costing.Decimal{"01.01": d("10"), "02.01": d("5")},
+		Titulos:  map[string]string{"01": "ESTRUCTURAS", "02": "ARQUITECTURA"},
+		Opciones: models.OpcionesExportacion{Parametros: &parametros},
+	}
+}
+
+func TestSimularTotales(t *testing.T) {
+	d := costing.DebeParsear
+	casos := []struct {
+		nombre    string
+		ajustes   models.SimulacionRequest
+		directo   string // costo directo simulado; el base es 1300
+		variacion string
+		titulos   map[string]string // subtotal simulado por título
+		tipos     map[string]string // costo simulado por tipo de recurso
+	}{
+		{
+			// El cemento pasa de 30 a 33: 01.01 sube a 83 × 10
+			nombre:    "precio por tipo",
+			ajustes:   models.SimulacionRequest{PorTipo: map[string]costing.Decimal{"materiales": d("10")}},
+			directo:   "1330",
+			variacion: "2.31",
+			titulos:   map[string]string{"01": "830", "02": "500"},
+			tipos:     map[string]string{"mano_obra": "900", "materiales": "330", "equipos": "100", "subcontratos": "0"},
+		},
+		{
+			// El peón baja 5 % aunque la mano de obra suba 10 %: 27.50 × 2 y 19 × 4
+			nombre: "el ajuste por código prevalece sobre el del tipo",
+			ajustes: models.SimulacionRequest{
+				PorTipo:   map[string]costing.Decimal{"mano_obra": d("10")},
+				PorCodigo: []models.AjustePrecioRecurso{{Codigo: "PEON", Porcentaje: d("-5")}},
+			},
+			directo:   "1330",
+			variacion: "2.31",
+			titulos:   map[string]string{"01": "850", "02": "480"},
+			tipos:     map[string]string{"mano_obra": "930", "materiales": "300", "equipos": "100", "subcontratos": "0"},
+		},
+		{
+			// Rendir 25 % más en 02 divide las horas de peón y mezcladora entre 1.25
+			nombre:    "rendimiento de un título",
+			ajustes:   models.SimulacionRequest{Rendimientos: []models.AjusteRendimiento{{Titulo: "02", Factor: d("1.25")}}},
+			directo:   "1200",
+			variacion: "-7.69",
+			titulos:   map[string]string{"01": "800", "02": "400"},
+			tipos:     map[string]string{"mano_obra": "820", "materiales": "300", "equipos": "80", "subcontratos": "0"},
+		},
+	}
+
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			resultado, err := Simular(datosSimulacion(), caso.ajustes)
+			if err != nil {
+				t.Fatalf("Simular: %v", err)
+			}
+
+			directo := resultado.CostoDirecto
+			if !directo.Base.Igual(d("1300")) || !directo.Simulado.Igual(d(caso.directo)) ||
+				!directo.Diferencia.Igual(d(caso.directo).Restar(d("1300"))) || !directo.VariacionPorcentaje.Igual(d(caso.variacion)) {
+				t.Errorf("costo directo = %s → %s (%s %%), se esperaba 1300 → %s (%s %%)",
+					directo.Base, directo.Simulado, directo.VariacionPorcentaje, caso.directo, caso.variacion)
+			}
+			if len(resultado.Titulos) != len(caso.titulos) {
+				t.Fatalf("títulos = %d, se esperaban %d", len(resultado.Titulos), len(caso.titulos))
+			}
+			for _, titulo := range resultado.Titulos {
+				if esperado := d(caso.titulos[titulo.Codigo]); !titulo.Simulado.Igual(esperado) {
+					t.Errorf("título %s simulado = %s, se esperaba %s", titulo.Codigo, titulo.Simulado, esperado)
+				}
+			}
+			for _, tipo := range resultado.TiposRecurso {
+				if esperado := d(caso.tipos[tipo.Codigo]); !tipo.Simulado.Igual(esperado) {
+					t.Errorf("%s simulado = %s, se esperaba %s", tipo.Codigo, tipo.Simulado, esperado)
+				}
+			}
+		})
+	}
+}
+
+func TestSimularNoModificaLosDatos(t *testing.T) {
+	datos := datosSimulacion()
+	ajustes := models.SimulacionRequest{
+		PorTipo:      map[string]costing.Decimal{"materiales": costing.DebeParsear("10")},
+		Rendimientos: []models.AjusteRendimiento{{Titulo: "02", Factor: costing.DebeParsear("1.25")}},
+	}
+	if _, err := Simular(datos, ajustes); err != nil {
+		t.Fatalf("Simular: %v", err)
+	}
+	if !ConstruirReporte(datos).Pie.CostoDirecto.Igual(costing.DebeParsear("1300")) {
+		t.Errorf("la simulación cambió las partidas del proyecto")
+	}
+}
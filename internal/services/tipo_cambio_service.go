@@ -0,0 +1,208 @@
+package services
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"goexcel/internal/costing"
+	"goexcel/internal/database/repositories"
+	"goexcel/internal/models"
+)
+
+// decimalesTasaInversa son los decimales de una tasa deducida de la del par inverso, los mismos que
+// guarda la columna tasa
+const decimalesTasaInversa = 6
+
+// columnasTiposCambioCSV es la cabecera esperada del CSV de tipos de cambio; fuente es opcional
+var columnasTiposCambioCSV = []string{"moneda_origen", "moneda_destino", "tasa", "fecha_vigencia", "fuente"}
+
+// TipoCambioService valida y consulta la tabla de tipos de cambio
+type TipoCambioService struct {
+	tipoCambioRepo *repositories.TipoCambioRepository
+}
+
+func NewTipoCambioService(tipoCambioRepo *repositories.TipoCambioRepository) *TipoCambioService {
+	return &TipoCambioService{tipoCambioRepo: tipoCambioRepo}
+}
+
+// Listar devuelve las tasas de la organización y las globales; sin organización, solo las globales
+func (s *TipoCambioService) Listar(organizacionID *uuid.UUID) ([]models.TipoCambio, error) {
+	return s.tipoCambioRepo.Listar(organizacionID)
+}
+
+// Crear valida y guarda una tasa; si ya había una del mismo par y fecha, la reemplaza
+func (s *TipoCambioService) Crear(req models.TipoCambioRequest, organizacionID *uuid.UUID) (*models.TipoCambio, error) {
+	tc, err := nuevoTipoCambio(req, organizacionID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.tipoCambioRepo.Guardar(tc); err != nil {
+		return nil, err
+	}
+	return tc, nil
+}
+
+// ImportarCSV carga tasas desde un CSV con cabecera moneda_origen,moneda_destino,tasa,fecha_vigencia
+// y fuente opcional. Si una línea es inválida no se guarda ninguna.
+func (s *TipoCambioService) ImportarCSV(r io.Reader, organizacionID *uuid.UUID) ([]models.TipoCambio, error) {
+	lector := csv.NewReader(r)
+	lector.FieldsPerRecord = -1
+	lector.TrimLeadingSpace = true
+
+	registros, err := lector.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("error leyendo CSV: %v", err)
+	}
+	if len(registros) < 2 {
+		return nil, fmt.Errorf("el CSV no tiene tipos de cambio")
+	}
+
+	cabecera := registros[0]
+	if len(cabecera) < 4 {
+		return nil, fmt.Errorf("cabecera inválida: se esperaba %s", strings.Join(columnasTiposCambioCSV, ","))
+	}
+	for i, columna := range cabecera {
+		if i >= len(columnasTiposCambioCSV) || !strings.EqualFold(strings.TrimSpace(columna), columnasTiposCambioCSV[i]) {
+			return nil, fmt.Errorf("cabecera inválida: se esperaba %s", strings.Join(columnasTiposCambioCSV, ","))
+		}
+	}
+
+	var tipos []models.TipoCambio
+	for i, registro := range registros[1:] {
+		linea := i + 2
+		if len(registro) == 1 && strings.TrimSpace(registro[0]) == "" {
+			continue
+		}
+		if len(registro) < 4 {
+			return nil, fmt.Errorf("línea %d: se esperaban al menos 4 columnas", linea)
+		}
+
+		tasa, err := costing.ParsearDecimal(strings.TrimSpace(registro[2]))
+		if err != nil {
+			return nil, fmt.Errorf("línea %d: tasa inválida: %s", linea, registro[2])
+		}
+		req := models.TipoCambioRequest{
+			MonedaOrigen:  registro[0],
+			MonedaDestino: registro[1],
+			Tasa:          tasa,
+			FechaVigencia: strings.TrimSpace(registro[3]),
+		}
+		if len(registro) > 4 {
+			req.Fuente = strings.TrimSpace(registro[4])
+		}
+
+		tc, err := nuevoTipoCambio(req, organizacionID)
+		if err != nil {
+			return nil, fmt.Errorf("línea %d: %v", linea, err)
+		}
+		tipos = append(tipos, *tc)
+	}
+
+	if err := s.tipoCambioRepo.GuardarLote(tipos); err != nil {
+		return nil, err
+	}
+	return tipos, nil
+}
+
+// Eliminar borra una tasa de la organización; sin organización, una global
+func (s *TipoCambioService) Eliminar(id uuid.UUID, organizacionID *uuid.UUID) error {
+	return s.tipoCambioRepo.Eliminar(id, organizacionID)
+}
+
+// Tasas obtiene las tasas vigentes a la fecha para llevar cada moneda a la del presupuesto. Si solo
+// existe el par inverso se usa su recíproco. Falla si alguna moneda no tiene tasa.
+func (s *TipoCambioService) Tasas(proyectoID uuid.UUID, destino string, fecha time.Time, monedas []string) (*models.TasasCambio, error) {
+	vigentes, err := s.tipoCambioRepo.VigentesParaProyecto(proyectoID, fecha)
+	if err != nil {
+		return nil, err
+	}
+	return tasasHacia(vigentes, destino, fecha, monedas)
+}
+
+// TasasDePresupuesto es Tasas con las tasas de la organización de un presupuesto jerárquico
+func (s *TipoCambioService) TasasDePresupuesto(presupuestoID uuid.UUID, destino string, fecha time.Time, monedas []string) (*models.TasasCambio, error) {
+	vigentes, err := s.tipoCambioRepo.VigentesParaPresupuesto(presupuestoID, fecha)
+	if err != nil {
+		return nil, err
+	}
+	return tasasHacia(vigentes, destino, fecha, monedas)
+}
+
+// tasasHacia elige entre las tasas vigentes la que lleva cada moneda a destino
+func tasasHacia(vigentes []models.TipoCambio, destino string, fecha time.Time, monedas []string) (*models.TasasCambio, error) {
+	directas := make(map[string]costing.Decimal)
+	inversas := make(map[string]costing.Decimal)
+	for _, tc := range vigentes {
+		if tc.MonedaDestino == destino {
+			directas[tc.MonedaOrigen] = tc.Tasa
+		} else if tc.MonedaOrigen == destino {
+			inversas[tc.MonedaDestino] = tc.Tasa
+		}
+	}
+
+	tasas := &models.TasasCambio{
+		Moneda: destino,
+		Fecha:  fecha.Format(models.FormatoFecha),
+		Tasas:  make(map[string]costing.Decimal),
+	}
+	for _, moneda := range monedas {
+		if moneda == "" || moneda == destino {
+			continue
+		}
+		if tasa, existe := directas[moneda]; existe {
+			tasas.Tasas[moneda] = tasa
+			continue
+		}
+		if tasa, existe := inversas[moneda]; existe {
+			redondeo := costing.Redondeo{Decimales: decimalesTasaInversa, Modo: costing.MitadArriba}
+			tasas.Tasas[moneda] = costing.NuevoDecimal(1, 0).Dividir(tasa, redondeo)
+			continue
+		}
+		return nil, fmt.Errorf("no hay tipo de cambio de %s a %s vigente al %s", moneda, destino, tasas.Fecha)
+	}
+
+	return tasas, nil
+}
+
+// nuevoTipoCambio valida una entrada de la tabla y normaliza sus monedas
+func nuevoTipoCambio(req models.TipoCambioRequest, organizacionID *uuid.UUID) (*models.TipoCambio, error) {
+	origen := strings.ToUpper(strings.TrimSpace(req.MonedaOrigen))
+	destino := strings.ToUpper(strings.TrimSpace(req.MonedaDestino))
+	if !esCodigoMoneda(origen) || !esCodigoMoneda(destino) {
+		return nil, fmt.Errorf("las monedas deben ser códigos ISO 4217 de 3 letras")
+	}
+	if origen == destino {
+		return nil, fmt.Errorf("la moneda de origen y la de destino deben ser distintas")
+	}
+	if req.Tasa.Signo() <= 0 {
+		return nil, fmt.Errorf("la tasa debe ser mayor que cero")
+	}
+	if _, err := time.Parse(models.FormatoFecha, req.FechaVigencia); err != nil {
+		return nil, fmt.Errorf("fecha_vigencia debe tener el formato AAAA-MM-DD")
+	}
+
+	return &models.TipoCambio{
+		OrganizacionID: organizacionID,
+		MonedaOrigen:   origen,
+		MonedaDestino:  destino,
+		Tasa:           req.Tasa,
+		FechaVigencia:  req.FechaVigencia,
+		Fuente:         strings.TrimSpace(req.Fuente),
+	}, nil
+}
+
+func esCodigoMoneda(moneda string) bool {
+	if len(moneda) != 3 {
+		return false
+	}
+	for _, c := range moneda {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,230 @@
+package services_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+	"goexcel/internal/costing"
+	"goexcel/internal/database/repositories"
+	"goexcel/internal/legacy"
+	"goexcel/internal/models"
+	"goexcel/internal/services"
+)
+
+// Presupuesto de prueba con cantidades de más de 4 decimales, desperdicios y parciales que caen en la
+// mitad, para que cualquier diferencia de redondeo entre los cálculos cambie el total
+type recursoFixture struct {
+	tipo, codigo, descripcion, unidad string
+	cantidad, precio, desperdicio     string
+}
+
+type partidaFixture struct {
+	codigo, descripcion, unidad, metrado string
+	recursos                             []recursoFixture
+}
+
+var fixture = []partidaFixture{
+	{"01.01", "EXCAVACIÓN MANUAL", "m3", "123.456", []recursoFixture{
+		{"mano_obra", "TEST-470101", "OPERARIO", "hh", "0.114286", "25.37", "0"},
+		{"mano_obra", "TEST-470103", "PEÓN", "hh", "1.142857", "19.13", "0"},
+		{"equipos", "TEST-370101", "HERRAMIENTAS MANUALES", "%mo", "0.03", "2.0711", "0"},
+	}},
+	{"01.02", "MURO DE LADRILLO", "m2", "45.67", []recursoFixture{
+		{"materiales", "TEST-020101", "LADRILLO KK", "und", "38.5", "0.85", "5"},
+		{"materiales", "TEST-210000", "CEMENTO PORTLAND", "bol", "0.2156", "28.7", "3"},
+		{"mano_obra", "TEST-470102", "OFICIAL", "hh", "0.457143", "21.46", "0"},
+	}},
+	{"02.01", "INSTALACIONES PROVISIONALES", "glb", "7.005", []recursoFixture{
+		{"subcontratos", "TEST-900101", "SC INSTALACIONES", "glb", "1", "350.555", "0"},
+	}},
+}
+
+// totalFixture es el costo directo del presupuesto de prueba calculado a mano con las reglas S10
+const totalFixture = "7828.01"
+
+// TestTotalesCoinciden calcula el mismo presupuesto con costing, con ConstruirReporte, con la relación
+// de insumos y con calcular_costo_total_proyecto, y exige el mismo total. Cubre las dos fuentes de
+// datos de un proyecto: las exportaciones calculan con las partidas del JSON importado mientras está
+// en memoria (originalJSONStore) y /costo-total y /insumos con las guardadas en la BD (CalculoService).
+// La parte SQL necesita TEST_DATABASE_URL con el esquema y las migraciones aplicados.
+func TestTotalesCoinciden(t *testing.T) {
+	esperado := costing.DebeParsear(totalFixture)
+
+	if total := totalCosting(); !total.Igual(esperado) {
+		t.Errorf("costing: total = %s, se esperaba %s", total, esperado)
+	}
+
+	t.Run("json", func(t *testing.T) {
+		reporte := services.ConstruirReporte(datosJSONFixture(t))
+		if !reporte.Pie.CostoDirecto.Igual(esperado) {
+			t.Errorf("exportación: costo directo = %s, se esperaba %s", reporte.Pie.CostoDirecto, esperado)
+		}
+		exigirInsumosConciliados(t, "exportación", reporte, esperado)
+	})
+
+	t.Run("sql", func(t *testing.T) {
+		url := os.Getenv("TEST_DATABASE_URL")
+		if url == "" {
+			t.Skip("TEST_DATABASE_URL no está definida")
+		}
+		db, err := sql.Open("postgres", url)
+		if err != nil {
+			t.Fatalf("error conectando a la BD de pruebas: %v", err)
+		}
+		defer db.Close()
+
+		proyectoID := guardarFixture(t, db)
+
+		var total costing.Decimal
+		if err := db.QueryRow(`SELECT calcular_costo_total_proyecto($1)`, proyectoID).Scan(&total); err != nil {
+			t.Fatalf("error calculando el costo total: %v", err)
+		}
+		if !total.Igual(esperado) {
+			t.Errorf("calcular_costo_total_proyecto = %s, se esperaba %s", total, esperado)
+		}
+
+		calculoSvc := services.NewCalculoService(db,
+			services.NewParametrosService(repositories.NewParametrosRepository(db)),
+			services.NewTipoCambioService(repositories.NewTipoCambioRepository(db)),
+			services.NewFleteService(repositories.NewFleteRepository(db)))
+		resumen, err := calculoSvc.ResumenDeProyecto(proyectoID)
+		if err != nil {
+			t.Fatalf("error calculando el resumen del proyecto: %v", err)
+		}
+		if !resumen.CostoDirecto.Igual(esperado) {
+			t.Errorf("/costo-total: costo directo = %s, se esperaba %s", resumen.CostoDirecto, esperado)
+		}
+		relacion, err := services.NewInsumosService(db, calculoSvc).ObtenerRelacionPorProyecto(proyectoID)
+		if err != nil {
+			t.Fatalf("error consolidando los insumos del proyecto: %v", err)
+		}
+		if !relacion.CostoDirecto.Igual(esperado) || !relacion.Conciliado {
+			t.Errorf("/insumos: costo directo = %s, conciliado = %v; se esperaba %s conciliado",
+				relacion.CostoDirecto, relacion.Conciliado, esperado)
+		}
+	})
+}
+
+func exigirInsumosConciliados(t *testing.T, origen string, reporte *models.ReportePresupuesto, esperado costing.Decimal) {
+	t.Helper()
+	relacion := services.ConsolidarInsumos(reporte)
+	if !relacion.CostoDirecto.Igual(esperado) || !relacion.Conciliado {
+		t.Errorf("%s: insumos con costo directo = %s, total insumos = %s, conciliado = %v; se esperaba %s conciliado",
+			origen, relacion.CostoDirecto, relacion.TotalInsumos, relacion.Conciliado, esperado)
+	}
+}
+
+func totalCosting() costing.Decimal {
+	var parciales []costing.Decimal
+	for _, partida := range fixture {
+		var recursos []costing.Recurso
+		for _, recurso := range partida.recursos {
+			recursos = append(recursos, costing.Recurso{
+				Cantidad:    costing.DebeParsear(recurso.cantidad),
+				Precio:      costing.DebeParsear(recurso.precio),
+				Desperdicio: costing.DebeParsear(recurso.desperdicio),
+			})
+		}
+		costoUnitario := costing.Subtotal(recursos)
+		parciales = append(parciales, costing.ParcialPartida(costing.DebeParsear(partida.metrado), costoUnitario))
+	}
+	return costing.Sumar(parciales...)
+}
+
+// datosJSONFixture arma las partidas del presupuesto de prueba como el JSON que recibe la importación
+// y las decodifica en las PartidaLegacy que guarda originalJSONStore, con los metrados del proyecto
+func datosJSONFixture(t *testing.T) services.DatosReporte {
+	t.Helper()
+	var importadas []map[string]interface{}
+	metrados := make(map[string]costing.Decimal)
+	for _, partida := range fixture {
+		importada := map[string]interface{}{
+			"codigo": partida.codigo, "descripcion": partida.descripcion, "unidad": partida.unidad,
+		}
+		for _, recurso := range partida.recursos {
+			item := map[string]interface{}{
+				"codigo": recurso.codigo, "descripcion": recurso.descripcion, "unidad": recurso.unidad,
+				"cantidad": json.Number(recurso.cantidad), "precio": json.Number(recurso.precio),
+			}
+			if recurso.desperdicio != "0" {
+				item["desperdicio"] = json.Number(recurso.desperdicio)
+			}
+			lista, _ := importada[recurso.tipo].([]interface{})
+			importada[recurso.tipo] = append(lista, item)
+		}
+		importadas = append(importadas, importada)
+		metrados[partida.codigo] = costing.DebeParsear(partida.metrado)
+	}
+
+	contenido, err := json.Marshal(importadas)
+	if err != nil {
+		t.Fatalf("error armando el JSON de prueba: %v", err)
+	}
+	var partidas []legacy.PartidaLegacy
+	if err := json.Unmarshal(contenido, &partidas); err != nil {
+		t.Fatalf("error decodificando el JSON de prueba: %v", err)
+	}
+
+	parametros := models.ParametrosPorDefecto()
+	return services.DatosReporte{
+		Partidas: partidas,
+		Metrados: metrados,
+		Opciones: models.OpcionesExportacion{Parametros: &parametros},
+	}
+}
+
+// guardarFixture guarda el presupuesto de prueba en un proyecto nuevo, recalcula el costo unitario de
+// las partidas con calcular_costo_partida y lo borra al terminar la prueba. Se confirma la transacción
+// porque CalculoService lee con su propia conexión.
+func guardarFixture(t *testing.T, db *sql.DB) uuid.UUID {
+	t.Helper()
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("error iniciando transacción: %v", err)
+	}
+	defer tx.Rollback()
+
+	ejecutar := func(query string, args ...interface{}) {
+		t.Helper()
+		if _, err := tx.Exec(query, args...); err != nil {
+			t.Fatalf("error ejecutando %q: %v", query, err)
+		}
+	}
+
+	proyectoID := uuid.New()
+	ejecutar(`INSERT INTO proyectos (id, nombre) VALUES ($1, 'Prueba de totales')`, proyectoID)
+	for _, partida := range fixture {
+		partidaID := uuid.New()
+		ejecutar(`INSERT INTO partidas (id, proyecto_id, codigo, descripcion, unidad) VALUES ($1, $2, $3, $4, $5)`,
+			partidaID, proyectoID, partida.codigo, partida.descripcion, partida.unidad)
+		for _, recurso := range partida.recursos {
+			recursoID := uuid.New()
+			ejecutar(`
+				INSERT INTO recursos (id, codigo, descripcion, unidad, tipo_recurso_id)
+				SELECT $1, $2, $3, $4, id FROM tipos_recurso WHERE nombre = $5
+				ON CONFLICT (codigo) DO NOTHING`,
+				recursoID, recurso.codigo, recurso.descripcion, recurso.unidad, recurso.tipo)
+			ejecutar(`
+				INSERT INTO partida_recursos (partida_id, recurso_id, cantidad, precio, desperdicio)
+				SELECT $1, id, $3, $4, NULLIF($5::DECIMAL, 0) FROM recursos WHERE codigo = $2`,
+				partidaID, recurso.codigo, recurso.cantidad, recurso.precio, recurso.desperdicio)
+		}
+		ejecutar(`INSERT INTO metrados_partidas (proyecto_id, partida_codigo, metrado) VALUES ($1, $2, $3)`,
+			proyectoID, partida.codigo, partida.metrado)
+	}
+	ejecutar(`UPDATE partidas SET costo_total = calcular_costo_partida(id) WHERE proyecto_id = $1`, proyectoID)
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("error guardando el presupuesto de prueba: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if _, err := db.Exec(`DELETE FROM proyectos WHERE id = $1`, proyectoID); err != nil {
+			t.Errorf("error borrando el proyecto de prueba: %v", err)
+		}
+	})
+	return proyectoID
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 
+	"goexcel/internal/models"
 	"goexcel/internal/services"
 )
 
@@ -24,8 +25,8 @@ func main() {
 	}
 
 	// Crear parser
-	parser := services.NewACUJerarquicoParser()
-	
+	parser := services.NewACUJerarquicoParser(models.ParametrosPorDefecto())
+
 	// Parsear contenido
 	result, err := parser.ParseACUJerarquico(string(content))
 	if err != nil {